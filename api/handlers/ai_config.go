@@ -16,9 +16,9 @@ type AIConfigHandler struct {
 	log       *logger.Logger
 }
 
-func NewAIConfigHandler(db *gorm.DB, cfg *config.Config, log *logger.Logger) *AIConfigHandler {
+func NewAIConfigHandler(db *gorm.DB, cfg *config.Config, log *logger.Logger, aiService *services.AIService) *AIConfigHandler {
 	return &AIConfigHandler{
-		aiService: services.NewAIService(db, log),
+		aiService: aiService,
 		log:       log,
 	}
 }
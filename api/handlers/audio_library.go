@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/drama-generator/backend/application/services"
+	"github.com/drama-generator/backend/domain/models"
+	"github.com/drama-generator/backend/pkg/audio"
+	"github.com/drama-generator/backend/pkg/logger"
+	"github.com/drama-generator/backend/pkg/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type AudioLibraryHandler struct {
+	audioLibraryService *services.AudioLibraryService
+	log                 *logger.Logger
+}
+
+func NewAudioLibraryHandler(audioLibraryService *services.AudioLibraryService, log *logger.Logger) *AudioLibraryHandler {
+	return &AudioLibraryHandler{
+		audioLibraryService: audioLibraryService,
+		log:                 log,
+	}
+}
+
+// ListAudioLibrary 检索音频库，支持 category、keyword、tags（逗号分隔）和 drama_id 过滤
+func (h *AudioLibraryHandler) ListAudioLibrary(c *gin.Context) {
+	category := c.Query("category")
+	if category != "" && category != models.AssetCategoryBGM && category != models.AssetCategorySFX {
+		response.BadRequest(c, "category 只能为 bgm 或 sfx")
+		return
+	}
+
+	var dramaID *uint
+	if dramaIDStr := c.Query("drama_id"); dramaIDStr != "" {
+		id, err := strconv.ParseUint(dramaIDStr, 10, 32)
+		if err != nil {
+			response.BadRequest(c, "无效的剧本ID")
+			return
+		}
+		uid := uint(id)
+		dramaID = &uid
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	assets, total, err := h.audioLibraryService.ListAudioLibrary(&services.ListAudioLibraryRequest{
+		Category: category,
+		Keyword:  strings.TrimSpace(c.Query("keyword")),
+		Tags:     audio.ParseTags(c.Query("tags")),
+		DramaID:  dramaID,
+		Page:     page,
+		PageSize: pageSize,
+	})
+	if err != nil {
+		h.log.Errorw("Failed to list audio library", "error", err)
+		response.InternalError(c, "获取音频库失败")
+		return
+	}
+
+	response.SuccessWithPagination(c, assets, total, page, pageSize)
+}
+
+// CreateAudioLibraryItem 将已上传的背景音乐或音效加入音频库
+func (h *AudioLibraryHandler) CreateAudioLibraryItem(c *gin.Context) {
+	var req services.CreateAudioLibraryItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	asset, err := h.audioLibraryService.CreateAudioLibraryItem(&req)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	response.Created(c, asset)
+}
+
+// UpdateAudioLibraryItem 修改音频库素材的名称、分类、描述和标签
+func (h *AudioLibraryHandler) UpdateAudioLibraryItem(c *gin.Context) {
+	id, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req services.UpdateAudioLibraryItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	asset, err := h.audioLibraryService.UpdateAudioLibraryItem(id, &req)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	response.Success(c, asset)
+}
+
+// GenerateMusic 异步按提示词生成配乐并加入音频库
+func (h *AudioLibraryHandler) GenerateMusic(c *gin.Context) {
+	var req services.GenerateMusicRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	task, err := h.audioLibraryService.GenerateMusic(&req)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	response.Success(c, gin.H{
+		"task_id": task.ID,
+		"status":  "pending",
+		"message": "配乐生成任务已创建",
+	})
+}
+
+// MatchEpisodeAudio 为章节分镜匹配音频库中的配乐和音效
+func (h *AudioLibraryHandler) MatchEpisodeAudio(c *gin.Context) {
+	episodeID, ok := parseUintParam(c, "episode_id")
+	if !ok {
+		return
+	}
+
+	var req services.MatchEpisodeAudioRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, err.Error())
+			return
+		}
+	}
+
+	result, err := h.audioLibraryService.MatchEpisodeAudio(episodeID, &req)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// SetStoryboardAudio 手动指定分镜的配乐和音效
+func (h *AudioLibraryHandler) SetStoryboardAudio(c *gin.Context) {
+	id, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req services.SetStoryboardAudioRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	storyboard, err := h.audioLibraryService.SetStoryboardAudio(id, &req)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	response.Success(c, storyboard)
+}
+
+func (h *AudioLibraryHandler) respondError(c *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) || strings.HasSuffix(err.Error(), "not found") {
+		response.NotFound(c, err.Error())
+		return
+	}
+	response.BadRequest(c, err.Error())
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/drama-generator/backend/application/services"
+	"github.com/drama-generator/backend/pkg/config"
+	"github.com/drama-generator/backend/pkg/logger"
+	"github.com/drama-generator/backend/pkg/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type BrandingHandler struct {
+	brandingService *services.BrandingService
+	log             *logger.Logger
+}
+
+func NewBrandingHandler(db *gorm.DB, cfg *config.Config, log *logger.Logger) *BrandingHandler {
+	return &BrandingHandler{
+		brandingService: services.NewBrandingService(db, log),
+		log:             log,
+	}
+}
+
+// GetBranding 获取剧本的水印、片头片尾和标题卡设置
+func (h *BrandingHandler) GetBranding(c *gin.Context) {
+	dramaID, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	settings, err := h.brandingService.GetDramaBranding(dramaID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	response.Success(c, settings)
+}
+
+// UpdateBranding 设置剧本的品牌包装，合成成片时自动添加；提交空对象清除设置
+func (h *BrandingHandler) UpdateBranding(c *gin.Context) {
+	dramaID, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var settings services.BrandingSettings
+	if err := c.ShouldBindJSON(&settings); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	updated, err := h.brandingService.UpdateDramaBranding(dramaID, settings)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	response.Success(c, updated)
+}
+
+func (h *BrandingHandler) respondError(c *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) || strings.HasSuffix(err.Error(), "drama not found") {
+		response.NotFound(c, err.Error())
+		return
+	}
+	response.BadRequest(c, err.Error())
+}
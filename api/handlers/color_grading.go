@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/drama-generator/backend/application/services"
+	"github.com/drama-generator/backend/pkg/config"
+	"github.com/drama-generator/backend/pkg/logger"
+	"github.com/drama-generator/backend/pkg/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ColorGradingHandler struct {
+	colorGradingService *services.ColorGradingService
+	log                 *logger.Logger
+}
+
+func NewColorGradingHandler(db *gorm.DB, cfg *config.Config, log *logger.Logger) *ColorGradingHandler {
+	return &ColorGradingHandler{
+		colorGradingService: services.NewColorGradingService(db, cfg.Storage.LocalPath, log),
+		log:                 log,
+	}
+}
+
+// GetColorGrading 获取剧本的镜头配色匹配和 LUT 设置
+func (h *ColorGradingHandler) GetColorGrading(c *gin.Context) {
+	dramaID, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	settings, err := h.colorGradingService.GetDramaColorGrading(dramaID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	response.Success(c, settings)
+}
+
+// UpdateColorGrading 设置合成成片时是否默认配色匹配及使用的 LUT；lut 为空时不套用 LUT
+func (h *ColorGradingHandler) UpdateColorGrading(c *gin.Context) {
+	dramaID, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var settings services.ColorGradingSettings
+	if err := c.ShouldBindJSON(&settings); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	updated, err := h.colorGradingService.UpdateDramaColorGrading(dramaID, settings)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	response.Success(c, updated)
+}
+
+// UploadLUT 上传 .cube 或 .3dl 文件作为剧本的 LUT
+func (h *ColorGradingHandler) UploadLUT(c *gin.Context) {
+	dramaID, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		response.BadRequest(c, "请选择文件")
+		return
+	}
+	defer file.Close()
+
+	settings, err := h.colorGradingService.UploadDramaLUT(dramaID, header.Filename, file)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	response.Success(c, settings)
+}
+
+func (h *ColorGradingHandler) respondError(c *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) || strings.HasSuffix(err.Error(), "drama not found") {
+		response.NotFound(c, err.Error())
+		return
+	}
+	response.BadRequest(c, err.Error())
+}
@@ -16,14 +16,16 @@ type DramaHandler struct {
 	db                *gorm.DB
 	dramaService      *services.DramaService
 	videoMergeService *services.VideoMergeService
+	subtitleService   *services.SubtitleService
 	log               *logger.Logger
 }
 
-func NewDramaHandler(db *gorm.DB, cfg *config.Config, log *logger.Logger, transferService *services.ResourceTransferService) *DramaHandler {
+func NewDramaHandler(db *gorm.DB, cfg *config.Config, log *logger.Logger, videoMergeService *services.VideoMergeService, subtitleService *services.SubtitleService) *DramaHandler {
 	return &DramaHandler{
 		db:                db,
 		dramaService:      services.NewDramaService(db, cfg, log),
-		videoMergeService: services.NewVideoMergeService(db, transferService, cfg.Storage.LocalPath, cfg.Storage.BaseURL, log),
+		videoMergeService: videoMergeService,
+		subtitleService:   subtitleService,
 		log:               log,
 	}
 }
@@ -341,10 +343,45 @@ func (h *DramaHandler) DownloadEpisodeVideo(c *gin.Context) {
 		return
 	}
 
-	// 返回视频URL，让前端重定向下载
-	c.JSON(200, gin.H{
+	// subtitles=soft|burn 时下载字幕版视频，尚未生成时创建字幕视频任务
+	subtitleMode := c.Query("subtitles")
+	if subtitleMode != "" && subtitleMode != services.SubtitleModeSoft && subtitleMode != services.SubtitleModeBurn {
+		response.BadRequest(c, "subtitles 只支持 soft 或 burn")
+		return
+	}
+
+	result := gin.H{
 		"video_url":      *episode.VideoURL,
 		"title":          episode.Title,
 		"episode_number": episode.EpisodeNum,
-	})
+	}
+
+	// 字幕文件随视频一起返回，生成失败不影响视频下载
+	subtitleFiles, err := h.subtitleService.SaveEpisodeSubtitles(episode.ID)
+	if err != nil {
+		h.log.Warnw("Failed to generate episode subtitles", "error", err, "episode_id", episode.ID)
+	} else if len(subtitleFiles) > 0 {
+		result["subtitles"] = subtitleFiles
+	}
+
+	subtitledVideos := h.subtitleService.SubtitledVideos(&episode)
+	if len(subtitledVideos) > 0 {
+		result["subtitled_videos"] = subtitledVideos
+	}
+	if subtitleMode != "" {
+		if videoURL, ok := subtitledVideos[subtitleMode]; ok {
+			result["video_url"] = videoURL
+		} else if len(subtitleFiles) > 0 {
+			task, err := h.subtitleService.EmbedEpisodeSubtitles(episode.ID, subtitleMode)
+			if err != nil {
+				result["subtitle_message"] = err.Error()
+			} else {
+				result["subtitle_task_id"] = task.ID
+				result["subtitle_message"] = "字幕视频生成中，完成后可重新下载"
+			}
+		}
+	}
+
+	// 返回视频URL，让前端重定向下载
+	c.JSON(200, result)
 }
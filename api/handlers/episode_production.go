@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"github.com/drama-generator/backend/application/services"
+	"github.com/drama-generator/backend/pkg/logger"
+	"github.com/drama-generator/backend/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+type EpisodeProductionHandler struct {
+	productionService *services.EpisodeProductionService
+	log               *logger.Logger
+}
+
+func NewEpisodeProductionHandler(productionService *services.EpisodeProductionService, log *logger.Logger) *EpisodeProductionHandler {
+	return &EpisodeProductionHandler{
+		productionService: productionService,
+		log:               log,
+	}
+}
+
+// ProduceEpisode 一键制作剧集，剧集已有失败或等待审核的制作时从中断的步骤继续
+func (h *EpisodeProductionHandler) ProduceEpisode(c *gin.Context) {
+	episodeID := c.Param("episode_id")
+
+	var req services.ProduceEpisodeRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, err.Error())
+			return
+		}
+	}
+
+	production, err := h.productionService.ProduceEpisode(episodeID, &req)
+	if err != nil {
+		if err.Error() == "episode not found" {
+			response.NotFound(c, "剧集不存在")
+			return
+		}
+		h.log.Errorw("Failed to start episode production", "error", err, "episode_id", episodeID)
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, production)
+}
+
+// GetProduction 获取剧集最近一次一键制作的进度
+func (h *EpisodeProductionHandler) GetProduction(c *gin.Context) {
+	episodeID := c.Param("episode_id")
+
+	production, err := h.productionService.GetProduction(episodeID)
+	if err != nil {
+		if err.Error() == "production not found" {
+			response.NotFound(c, "该剧集没有制作任务")
+			return
+		}
+		response.InternalError(c, err.Error())
+		return
+	}
+
+	response.Success(c, production)
+}
+
+// ApproveStep 审核通过制作步骤，流水线正在等待该步骤审核时继续执行
+func (h *EpisodeProductionHandler) ApproveStep(c *gin.Context) {
+	episodeID := c.Param("episode_id")
+
+	var req struct {
+		Step string `json:"step" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	production, err := h.productionService.ApproveStep(episodeID, req.Step)
+	if err != nil {
+		if err.Error() == "production not found" {
+			response.NotFound(c, "该剧集没有进行中的制作任务")
+			return
+		}
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, production)
+}
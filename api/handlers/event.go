@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/drama-generator/backend/application/services"
+	"github.com/drama-generator/backend/pkg/logger"
+	"github.com/drama-generator/backend/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+// SSE 心跳间隔，避免代理因连接空闲而断开
+const sseHeartbeatInterval = 15 * time.Second
+
+type EventHandler struct {
+	eventBus *services.EventBus
+	log      *logger.Logger
+}
+
+func NewEventHandler(eventBus *services.EventBus, log *logger.Logger) *EventHandler {
+	return &EventHandler{
+		eventBus: eventBus,
+		log:      log,
+	}
+}
+
+// StreamEvents 通过 Server-Sent Events 推送任务进度、图片/视频生成和视频合成状态变更
+// 支持 drama_id 过滤；断线重连时浏览器会带上 Last-Event-ID，服务端补发期间错过的事件
+func (h *EventHandler) StreamEvents(c *gin.Context) {
+	var dramaID uint
+	if dramaIDStr := c.Query("drama_id"); dramaIDStr != "" {
+		id, err := strconv.ParseUint(dramaIDStr, 10, 32)
+		if err != nil {
+			response.BadRequest(c, "无效的drama_id")
+			return
+		}
+		dramaID = uint(id)
+	}
+
+	lastEventIDStr := c.GetHeader("Last-Event-ID")
+	if lastEventIDStr == "" {
+		lastEventIDStr = c.Query("last_event_id")
+	}
+	lastEventID, _ := strconv.ParseInt(lastEventIDStr, 10, 64)
+
+	flusher, ok := c.Writer.(http.Flusher)
+	if !ok {
+		response.InternalError(c, "streaming not supported")
+		return
+	}
+
+	replay, events, unsubscribe := h.eventBus.Subscribe(dramaID, lastEventID)
+	defer unsubscribe()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	// 告诉浏览器断线后 3 秒重连（服务端 WriteTimeout 到期也会断开，由重连+补发衔接）
+	fmt.Fprint(c.Writer, "retry: 3000\n\n")
+	for _, event := range replay {
+		h.writeEvent(c, event)
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				// 订阅被总线断开（消费过慢），客户端重连后补发
+				return
+			}
+			h.writeEvent(c, event)
+			flusher.Flush()
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": ping\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+func (h *EventHandler) writeEvent(c *gin.Context, event services.Event) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		h.log.Warnw("Failed to marshal event", "error", err, "event_id", event.ID, "type", event.Type)
+		return
+	}
+	fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+}
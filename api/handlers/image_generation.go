@@ -5,7 +5,6 @@ import (
 
 	"github.com/drama-generator/backend/application/services"
 	"github.com/drama-generator/backend/domain/models"
-	"github.com/drama-generator/backend/infrastructure/storage"
 	"github.com/drama-generator/backend/pkg/config"
 	"github.com/drama-generator/backend/pkg/logger"
 	"github.com/drama-generator/backend/pkg/response"
@@ -21,10 +20,10 @@ type ImageGenerationHandler struct {
 	db           *gorm.DB
 }
 
-func NewImageGenerationHandler(db *gorm.DB, cfg *config.Config, log *logger.Logger, transferService *services.ResourceTransferService, localStorage *storage.LocalStorage) *ImageGenerationHandler {
+func NewImageGenerationHandler(db *gorm.DB, cfg *config.Config, log *logger.Logger, imageService *services.ImageGenerationService, taskService *services.TaskService) *ImageGenerationHandler {
 	return &ImageGenerationHandler{
-		imageService: services.NewImageGenerationService(db, cfg, transferService, localStorage, log),
-		taskService:  services.NewTaskService(db, log),
+		imageService: imageService,
+		taskService:  taskService,
 		log:          log,
 		config:       cfg,
 		db:           db,
@@ -242,3 +241,49 @@ func (h *ImageGenerationHandler) UploadImage(c *gin.Context) {
 
 	response.Success(c, imageGen)
 }
+
+// CancelImageGeneration 取消图片生成
+func (h *ImageGenerationHandler) CancelImageGeneration(c *gin.Context) {
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	if err := h.imageService.CancelImageGeneration(uint(id)); err != nil {
+		h.log.Warnw("Failed to cancel image generation", "error", err, "id", id)
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, gin.H{"id": id, "status": "cancelled"})
+}
+
+// PauseEpisodeBatch 暂停剧集的批量图片生成
+func (h *ImageGenerationHandler) PauseEpisodeBatch(c *gin.Context) {
+
+	episodeID := c.Param("episode_id")
+
+	batch, err := h.imageService.PauseEpisodeBatch(episodeID)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, batch)
+}
+
+// ResumeEpisodeBatch 恢复剧集的批量图片生成
+func (h *ImageGenerationHandler) ResumeEpisodeBatch(c *gin.Context) {
+
+	episodeID := c.Param("episode_id")
+
+	batch, err := h.imageService.ResumeEpisodeBatch(episodeID)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, batch)
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/drama-generator/backend/application/services"
+	"github.com/drama-generator/backend/pkg/logger"
+	"github.com/drama-generator/backend/pkg/response"
+	"github.com/gin-gonic/gin"
+)
+
+type LipSyncHandler struct {
+	lipSyncService *services.LipSyncService
+	log            *logger.Logger
+}
+
+func NewLipSyncHandler(lipSyncService *services.LipSyncService, log *logger.Logger) *LipSyncHandler {
+	return &LipSyncHandler{
+		lipSyncService: lipSyncService,
+		log:            log,
+	}
+}
+
+// LipSyncStoryboard 按配音为分镜当前采用的视频同步口型
+func (h *LipSyncHandler) LipSyncStoryboard(c *gin.Context) {
+	id, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	videoGen, err := h.lipSyncService.LipSyncStoryboard(id)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	response.Success(c, gin.H{
+		"video_gen_id":    videoGen.ID,
+		"lip_sync_status": videoGen.LipSyncStatus,
+		"message":         "口型同步任务已创建",
+	})
+}
+
+// LipSyncEpisode 为章节所有有对白且已配音的分镜同步口型
+func (h *LipSyncHandler) LipSyncEpisode(c *gin.Context) {
+	episodeID, ok := parseUintParam(c, "episode_id")
+	if !ok {
+		return
+	}
+
+	result, err := h.lipSyncService.LipSyncEpisode(episodeID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+func (h *LipSyncHandler) respondError(c *gin.Context, err error) {
+	if strings.HasSuffix(err.Error(), "not found") {
+		response.NotFound(c, err.Error())
+		return
+	}
+	h.log.Warnw("Lip sync request rejected", "error", err)
+	response.BadRequest(c, err.Error())
+}
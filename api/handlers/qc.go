@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/drama-generator/backend/application/services"
+	"github.com/drama-generator/backend/pkg/logger"
+	"github.com/drama-generator/backend/pkg/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type QCHandler struct {
+	qcService *services.QCService
+	log       *logger.Logger
+}
+
+func NewQCHandler(qcService *services.QCService, log *logger.Logger) *QCHandler {
+	return &QCHandler{
+		qcService: qcService,
+		log:       log,
+	}
+}
+
+// GetEpisodeReadiness 获取章节各分镜的生成和质检情况，全部分镜都有可用视频时可以合成成片
+func (h *QCHandler) GetEpisodeReadiness(c *gin.Context) {
+	episodeID, ok := parseUintParam(c, "episode_id")
+	if !ok {
+		return
+	}
+
+	readiness, err := h.qcService.EpisodeReadiness(episodeID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	response.Success(c, readiness)
+}
+
+// CheckVideo 重新质检视频
+func (h *QCHandler) CheckVideo(c *gin.Context) {
+	videoGenID, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	task, err := h.qcService.CheckVideo(videoGenID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	response.Success(c, gin.H{
+		"task_id": task.ID,
+		"status":  "pending",
+		"message": "视频质检任务已创建",
+	})
+}
+
+// CheckImage 重新质检图片
+func (h *QCHandler) CheckImage(c *gin.Context) {
+	imageGenID, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	task, err := h.qcService.CheckImage(imageGenID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	response.Success(c, gin.H{
+		"task_id": task.ID,
+		"status":  "pending",
+		"message": "图片质检任务已创建",
+	})
+}
+
+func (h *QCHandler) respondError(c *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) || strings.HasSuffix(err.Error(), "not found") {
+		response.NotFound(c, err.Error())
+		return
+	}
+	response.BadRequest(c, err.Error())
+}
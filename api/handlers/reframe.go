@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/drama-generator/backend/application/services"
+	"github.com/drama-generator/backend/pkg/logger"
+	"github.com/drama-generator/backend/pkg/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ReframeHandler struct {
+	reframeService *services.ReframeService
+	log            *logger.Logger
+}
+
+func NewReframeHandler(reframeService *services.ReframeService, log *logger.Logger) *ReframeHandler {
+	return &ReframeHandler{
+		reframeService: reframeService,
+		log:            log,
+	}
+}
+
+// CreateReframe 将视频、图片或成片转换到其他宽高比，智能裁剪时先分析画面主体生成裁剪关键帧
+func (h *ReframeHandler) CreateReframe(c *gin.Context) {
+	var req services.ReframeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	record, err := h.reframeService.CreateReframe(&req)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	response.Success(c, record)
+}
+
+// ListReframes 获取来源的各宽高比转换记录，需要 source_type 和 source_id 参数
+func (h *ReframeHandler) ListReframes(c *gin.Context) {
+	sourceType := c.Query("source_type")
+	sourceID, err := strconv.ParseUint(c.Query("source_id"), 10, 32)
+	if sourceType == "" || err != nil {
+		response.BadRequest(c, "source_type and source_id are required")
+		return
+	}
+
+	records, err := h.reframeService.ListReframes(sourceType, uint(sourceID))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	response.Success(c, records)
+}
+
+// GetReframe 获取转换记录和裁剪关键帧
+func (h *ReframeHandler) GetReframe(c *gin.Context) {
+	id, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	record, err := h.reframeService.GetReframe(id)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	response.Success(c, record)
+}
+
+// UpdateKeyframes 手动调整裁剪关键帧，已生成的文件按新关键帧重新输出
+func (h *ReframeHandler) UpdateKeyframes(c *gin.Context) {
+	id, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req services.UpdateReframeKeyframesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	record, err := h.reframeService.UpdateKeyframes(id, req.Keyframes)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	response.Success(c, record)
+}
+
+func (h *ReframeHandler) respondError(c *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) || strings.HasSuffix(err.Error(), "not found") {
+		response.NotFound(c, err.Error())
+		return
+	}
+	response.BadRequest(c, err.Error())
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/drama-generator/backend/application/services"
+	"github.com/drama-generator/backend/pkg/logger"
+	"github.com/drama-generator/backend/pkg/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type RenditionHandler struct {
+	renditionService *services.RenditionService
+	log              *logger.Logger
+}
+
+func NewRenditionHandler(renditionService *services.RenditionService, log *logger.Logger) *RenditionHandler {
+	return &RenditionHandler{
+		renditionService: renditionService,
+		log:              log,
+	}
+}
+
+// GetOutputSettings 获取剧本的成片输出规格和 HLS 设置
+func (h *RenditionHandler) GetOutputSettings(c *gin.Context) {
+	dramaID, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	settings, err := h.renditionService.GetDramaOutputSettings(dramaID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	response.Success(c, settings)
+}
+
+// UpdateOutputSettings 设置剧本的成片输出规格，规格只填写名称时使用同名的内置规格
+func (h *RenditionHandler) UpdateOutputSettings(c *gin.Context) {
+	dramaID, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var settings services.OutputSettings
+	if err := c.ShouldBindJSON(&settings); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	updated, err := h.renditionService.UpdateDramaOutputSettings(dramaID, settings)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	response.Success(c, updated)
+}
+
+// ListEpisodeRenditions 获取章节当前成片的各规格输出和 HLS 播放地址
+func (h *RenditionHandler) ListEpisodeRenditions(c *gin.Context) {
+	episodeID, ok := parseUintParam(c, "episode_id")
+	if !ok {
+		return
+	}
+
+	renditions, err := h.renditionService.ListEpisodeRenditions(episodeID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	response.Success(c, renditions)
+}
+
+// RenderEpisodeRenditions 异步为章节当前成片重新生成输出规格
+func (h *RenditionHandler) RenderEpisodeRenditions(c *gin.Context) {
+	episodeID, ok := parseUintParam(c, "episode_id")
+	if !ok {
+		return
+	}
+
+	// 请求体可选，未指定时使用剧本的输出设置
+	var selection services.OutputSelection
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&selection); err != nil {
+			response.BadRequest(c, err.Error())
+			return
+		}
+	}
+
+	task, err := h.renditionService.RenderEpisodeOutputs(episodeID, selection)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	response.Success(c, gin.H{
+		"task_id": task.ID,
+		"status":  "pending",
+		"message": "输出规格转码任务已创建",
+	})
+}
+
+func (h *RenditionHandler) respondError(c *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) || strings.HasSuffix(err.Error(), "not found") {
+		response.NotFound(c, err.Error())
+		return
+	}
+	response.BadRequest(c, err.Error())
+}
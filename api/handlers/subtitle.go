@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/drama-generator/backend/application/services"
+	"github.com/drama-generator/backend/pkg/logger"
+	"github.com/drama-generator/backend/pkg/response"
+	"github.com/drama-generator/backend/pkg/subtitle"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type SubtitleHandler struct {
+	subtitleService *services.SubtitleService
+	log             *logger.Logger
+}
+
+func NewSubtitleHandler(subtitleService *services.SubtitleService, log *logger.Logger) *SubtitleHandler {
+	return &SubtitleHandler{
+		subtitleService: subtitleService,
+		log:             log,
+	}
+}
+
+// GetSubtitleStyle 获取剧本的字幕样式
+func (h *SubtitleHandler) GetSubtitleStyle(c *gin.Context) {
+	dramaID, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	style, err := h.subtitleService.GetDramaSubtitleStyle(dramaID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	response.Success(c, style)
+}
+
+// UpdateSubtitleStyle 设置剧本的字幕样式，未提供的字段使用默认值
+func (h *SubtitleHandler) UpdateSubtitleStyle(c *gin.Context) {
+	dramaID, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	style := subtitle.DefaultStyle()
+	if err := c.ShouldBindJSON(&style); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	updated, err := h.subtitleService.UpdateDramaSubtitleStyle(dramaID, style)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	response.Success(c, updated)
+}
+
+// ExportEpisodeSubtitles 下载章节字幕文件，format 支持 srt、vtt、ass，默认 srt
+func (h *SubtitleHandler) ExportEpisodeSubtitles(c *gin.Context) {
+	episodeID, ok := parseUintParam(c, "episode_id")
+	if !ok {
+		return
+	}
+
+	format, err := subtitle.ParseFormat(c.DefaultQuery("format", string(subtitle.FormatSRT)))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	data, fileName, err := h.subtitleService.ExportEpisodeSubtitles(episodeID, format)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+	c.Data(http.StatusOK, format.ContentType(), data)
+}
+
+// EmbedEpisodeSubtitles 异步为章节成片添加软字幕或烧录字幕
+func (h *SubtitleHandler) EmbedEpisodeSubtitles(c *gin.Context) {
+	episodeID, ok := parseUintParam(c, "episode_id")
+	if !ok {
+		return
+	}
+
+	var req services.EmbedSubtitlesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	task, err := h.subtitleService.EmbedEpisodeSubtitles(episodeID, req.Mode)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	response.Success(c, gin.H{
+		"task_id": task.ID,
+		"status":  "pending",
+		"message": "字幕视频任务已创建",
+	})
+}
+
+func (h *SubtitleHandler) respondError(c *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) || strings.HasSuffix(err.Error(), "not found") {
+		response.NotFound(c, err.Error())
+		return
+	}
+	response.BadRequest(c, err.Error())
+}
@@ -10,12 +10,14 @@ import (
 
 type TaskHandler struct {
 	taskService *services.TaskService
+	jobQueue    *services.JobQueue
 	log         *logger.Logger
 }
 
-func NewTaskHandler(db *gorm.DB, log *logger.Logger) *TaskHandler {
+func NewTaskHandler(taskService *services.TaskService, jobQueue *services.JobQueue, log *logger.Logger) *TaskHandler {
 	return &TaskHandler{
-		taskService: services.NewTaskService(db, log),
+		taskService: taskService,
+		jobQueue:    jobQueue,
 		log:         log,
 	}
 }
@@ -55,3 +57,40 @@ func (h *TaskHandler) GetResourceTasks(c *gin.Context) {
 
 	response.Success(c, tasks)
 }
+
+// CancelTask 取消任务（批量任务会级联取消子任务）
+func (h *TaskHandler) CancelTask(c *gin.Context) {
+	h.controlTask(c, "cancel", h.jobQueue.Cancel)
+}
+
+// PauseTask 暂停排队中的任务或整个批量任务
+func (h *TaskHandler) PauseTask(c *gin.Context) {
+	h.controlTask(c, "pause", h.jobQueue.Pause)
+}
+
+// ResumeTask 恢复已暂停的任务
+func (h *TaskHandler) ResumeTask(c *gin.Context) {
+	h.controlTask(c, "resume", h.jobQueue.Resume)
+}
+
+func (h *TaskHandler) controlTask(c *gin.Context, action string, fn func(taskID string) error) {
+	taskID := c.Param("task_id")
+
+	if err := fn(taskID); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response.NotFound(c, "任务不存在")
+			return
+		}
+		h.log.Warnw("Failed to control task", "action", action, "error", err, "task_id", taskID)
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	task, err := h.taskService.GetTask(taskID)
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+
+	response.Success(c, task)
+}
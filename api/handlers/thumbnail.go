@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/drama-generator/backend/application/services"
+	"github.com/drama-generator/backend/pkg/logger"
+	"github.com/drama-generator/backend/pkg/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ThumbnailHandler struct {
+	thumbnailService *services.ThumbnailService
+	log              *logger.Logger
+}
+
+func NewThumbnailHandler(thumbnailService *services.ThumbnailService, log *logger.Logger) *ThumbnailHandler {
+	return &ThumbnailHandler{
+		thumbnailService: thumbnailService,
+		log:              log,
+	}
+}
+
+// SetEpisodePoster 截取章节成片指定时间点的画面作为封面
+func (h *ThumbnailHandler) SetEpisodePoster(c *gin.Context) {
+	episodeID, ok := parseUintParam(c, "episode_id")
+	if !ok {
+		return
+	}
+
+	var req services.SetPosterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	result, err := h.thumbnailService.SetEpisodePoster(episodeID, *req.Timestamp)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// SetAssetPoster 截取视频素材指定时间点的画面作为封面
+func (h *ThumbnailHandler) SetAssetPoster(c *gin.Context) {
+	assetID, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req services.SetPosterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	result, err := h.thumbnailService.SetAssetPoster(assetID, *req.Timestamp)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	response.Success(c, result)
+}
+
+func (h *ThumbnailHandler) respondError(c *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) || strings.HasSuffix(err.Error(), "not found") {
+		response.NotFound(c, err.Error())
+		return
+	}
+	h.log.Warnw("Poster request rejected", "error", err)
+	response.BadRequest(c, err.Error())
+}
@@ -0,0 +1,526 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/drama-generator/backend/application/services"
+	"github.com/drama-generator/backend/pkg/config"
+	"github.com/drama-generator/backend/pkg/interchange"
+	"github.com/drama-generator/backend/pkg/logger"
+	"github.com/drama-generator/backend/pkg/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type TimelineHandler struct {
+	timelineService *services.TimelineService
+	renderService   *services.TimelineRenderService
+	exportService   *services.TimelineExportService
+	log             *logger.Logger
+}
+
+func NewTimelineHandler(db *gorm.DB, cfg *config.Config, log *logger.Logger, renderService *services.TimelineRenderService) *TimelineHandler {
+	return &TimelineHandler{
+		timelineService: services.NewTimelineService(db, log),
+		renderService:   renderService,
+		exportService:   services.NewTimelineExportService(db, cfg.Storage.LocalPath, log),
+		log:             log,
+	}
+}
+
+// CreateTimeline 创建时间线
+func (h *TimelineHandler) CreateTimeline(c *gin.Context) {
+	var req services.CreateTimelineRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	timeline, err := h.timelineService.CreateTimeline(&req)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	response.Created(c, timeline)
+}
+
+// BuildEpisodeTimeline 根据章节分镜和已生成的视频、配音自动生成时间线
+func (h *TimelineHandler) BuildEpisodeTimeline(c *gin.Context) {
+	episodeID, ok := parseTimelineID(c, "episode_id")
+	if !ok {
+		return
+	}
+
+	var req services.BuildTimelineRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, err.Error())
+			return
+		}
+	}
+
+	timeline, err := h.timelineService.BuildEpisodeTimeline(episodeID, &req)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	response.Created(c, timeline)
+}
+
+// ListTimelines 获取时间线列表，支持 drama_id、episode_id 过滤
+func (h *TimelineHandler) ListTimelines(c *gin.Context) {
+	var dramaID, episodeID *uint
+	if v := c.Query("drama_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			response.BadRequest(c, "无效的drama_id")
+			return
+		}
+		uid := uint(id)
+		dramaID = &uid
+	}
+	if v := c.Query("episode_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			response.BadRequest(c, "无效的episode_id")
+			return
+		}
+		uid := uint(id)
+		episodeID = &uid
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	timelines, total, err := h.timelineService.ListTimelines(dramaID, episodeID, page, pageSize)
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+
+	response.SuccessWithPagination(c, timelines, total, page, pageSize)
+}
+
+// GetTimeline 获取完整时间线
+func (h *TimelineHandler) GetTimeline(c *gin.Context) {
+	id, ok := parseTimelineID(c, "id")
+	if !ok {
+		return
+	}
+
+	timeline, err := h.timelineService.GetTimeline(id)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	response.Success(c, timeline)
+}
+
+// UpdateTimeline 更新时间线
+func (h *TimelineHandler) UpdateTimeline(c *gin.Context) {
+	id, ok := parseTimelineID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req services.UpdateTimelineRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	timeline, err := h.timelineService.UpdateTimeline(id, &req)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	response.Success(c, timeline)
+}
+
+// DeleteTimeline 删除时间线
+func (h *TimelineHandler) DeleteTimeline(c *gin.Context) {
+	id, ok := parseTimelineID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.timelineService.DeleteTimeline(id); err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	response.Success(c, gin.H{"message": "删除成功"})
+}
+
+// RenderTimeline 异步渲染时间线，进度通过任务查询，完成后结果中返回新素材
+func (h *TimelineHandler) RenderTimeline(c *gin.Context) {
+	id, ok := parseTimelineID(c, "id")
+	if !ok {
+		return
+	}
+
+	task, err := h.renderService.RenderTimeline(id)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	response.Success(c, gin.H{
+		"task_id": task.ID,
+		"status":  "pending",
+		"message": "时间线渲染任务已创建",
+	})
+}
+
+// ExportTimeline 导出时间线为 EDL/FCPXML/OTIO 文件
+func (h *TimelineHandler) ExportTimeline(c *gin.Context) {
+	id, ok := parseTimelineID(c, "id")
+	if !ok {
+		return
+	}
+	format, ok := parseExportFormat(c)
+	if !ok {
+		return
+	}
+
+	data, err := h.exportService.ExportTimeline(id, format)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	sendExportFile(c, fmt.Sprintf("timeline_%d%s", id, format.Extension()), format, data)
+}
+
+// ExportEpisode 按分镜顺序导出章节的剪辑序列
+func (h *TimelineHandler) ExportEpisode(c *gin.Context) {
+	episodeID, ok := parseTimelineID(c, "episode_id")
+	if !ok {
+		return
+	}
+	format, ok := parseExportFormat(c)
+	if !ok {
+		return
+	}
+
+	data, err := h.exportService.ExportEpisode(episodeID, format)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	sendExportFile(c, fmt.Sprintf("episode_%d%s", episodeID, format.Extension()), format, data)
+}
+
+// AddTrack 添加轨道
+func (h *TimelineHandler) AddTrack(c *gin.Context) {
+	id, ok := parseTimelineID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req services.CreateTrackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	track, err := h.timelineService.AddTrack(id, &req)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	response.Created(c, track)
+}
+
+// UpdateTrack 更新轨道
+func (h *TimelineHandler) UpdateTrack(c *gin.Context) {
+	id, ok := parseTimelineID(c, "track_id")
+	if !ok {
+		return
+	}
+
+	var req services.UpdateTrackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	track, err := h.timelineService.UpdateTrack(id, &req)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	response.Success(c, track)
+}
+
+// DeleteTrack 删除轨道及其片段
+func (h *TimelineHandler) DeleteTrack(c *gin.Context) {
+	id, ok := parseTimelineID(c, "track_id")
+	if !ok {
+		return
+	}
+
+	if err := h.timelineService.DeleteTrack(id); err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	response.Success(c, gin.H{"message": "删除成功"})
+}
+
+// AddClip 在轨道上添加片段
+func (h *TimelineHandler) AddClip(c *gin.Context) {
+	id, ok := parseTimelineID(c, "track_id")
+	if !ok {
+		return
+	}
+
+	var req services.CreateClipRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	clip, err := h.timelineService.AddClip(id, &req)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	response.Created(c, clip)
+}
+
+// UpdateClip 更新片段
+func (h *TimelineHandler) UpdateClip(c *gin.Context) {
+	id, ok := parseTimelineID(c, "clip_id")
+	if !ok {
+		return
+	}
+
+	var req services.UpdateClipRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	clip, err := h.timelineService.UpdateClip(id, &req)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	response.Success(c, clip)
+}
+
+// DeleteClip 删除片段
+func (h *TimelineHandler) DeleteClip(c *gin.Context) {
+	h.deleteClip(c, false)
+}
+
+// RippleDeleteClip 删除片段，同一轨道上后续片段左移填补空隙
+func (h *TimelineHandler) RippleDeleteClip(c *gin.Context) {
+	h.deleteClip(c, true)
+}
+
+func (h *TimelineHandler) deleteClip(c *gin.Context, ripple bool) {
+	id, ok := parseTimelineID(c, "clip_id")
+	if !ok {
+		return
+	}
+
+	if err := h.timelineService.DeleteClip(id, ripple); err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	response.Success(c, gin.H{"message": "删除成功"})
+}
+
+// MoveClip 移动片段
+func (h *TimelineHandler) MoveClip(c *gin.Context) {
+	id, ok := parseTimelineID(c, "clip_id")
+	if !ok {
+		return
+	}
+
+	var req services.MoveClipRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	clip, err := h.timelineService.MoveClip(id, &req)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	response.Success(c, clip)
+}
+
+// SplitClip 切分片段
+func (h *TimelineHandler) SplitClip(c *gin.Context) {
+	id, ok := parseTimelineID(c, "clip_id")
+	if !ok {
+		return
+	}
+
+	var req services.SplitClipRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	clips, err := h.timelineService.SplitClip(id, req.At)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	response.Success(c, clips)
+}
+
+// SetTransition 设置片段入场（in）或出场（out）转场
+func (h *TimelineHandler) SetTransition(c *gin.Context) {
+	id, ok := parseTimelineID(c, "clip_id")
+	if !ok {
+		return
+	}
+
+	var req services.TransitionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	clip, err := h.timelineService.SetTransition(id, c.Param("position"), &req)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	response.Success(c, clip)
+}
+
+// RemoveTransition 移除片段转场
+func (h *TimelineHandler) RemoveTransition(c *gin.Context) {
+	id, ok := parseTimelineID(c, "clip_id")
+	if !ok {
+		return
+	}
+
+	clip, err := h.timelineService.RemoveTransition(id, c.Param("position"))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	response.Success(c, clip)
+}
+
+// AddEffect 为片段添加特效
+func (h *TimelineHandler) AddEffect(c *gin.Context) {
+	id, ok := parseTimelineID(c, "clip_id")
+	if !ok {
+		return
+	}
+
+	var req services.CreateEffectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	effect, err := h.timelineService.AddEffect(id, &req)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	response.Created(c, effect)
+}
+
+// UpdateEffect 更新特效
+func (h *TimelineHandler) UpdateEffect(c *gin.Context) {
+	id, ok := parseTimelineID(c, "effect_id")
+	if !ok {
+		return
+	}
+
+	var req services.UpdateEffectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	effect, err := h.timelineService.UpdateEffect(id, &req)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	response.Success(c, effect)
+}
+
+// DeleteEffect 删除特效
+func (h *TimelineHandler) DeleteEffect(c *gin.Context) {
+	id, ok := parseTimelineID(c, "effect_id")
+	if !ok {
+		return
+	}
+
+	if err := h.timelineService.DeleteEffect(id); err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	response.Success(c, gin.H{"message": "删除成功"})
+}
+
+// respondError 记录不存在返回 404，其余为校验错误
+func (h *TimelineHandler) respondError(c *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) || strings.HasSuffix(err.Error(), "not found") {
+		response.NotFound(c, err.Error())
+		return
+	}
+	response.BadRequest(c, err.Error())
+}
+
+// parseExportFormat 解析导出格式，默认 fcpxml
+func parseExportFormat(c *gin.Context) (interchange.Format, bool) {
+	format, err := interchange.ParseFormat(c.DefaultQuery("format", string(interchange.FormatFCPXML)))
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return "", false
+	}
+	return format, true
+}
+
+func sendExportFile(c *gin.Context, fileName string, format interchange.Format, data []byte) {
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+	c.Data(http.StatusOK, format.ContentType(), data)
+}
+
+func parseTimelineID(c *gin.Context, param string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(param), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -1,10 +1,12 @@
 package handlers
 
 import (
+	"errors"
+	"io"
+	"net/http"
 	"strconv"
 
 	"github.com/drama-generator/backend/application/services"
-	"github.com/drama-generator/backend/infrastructure/storage"
 	"github.com/drama-generator/backend/pkg/logger"
 	"github.com/drama-generator/backend/pkg/response"
 	"github.com/gin-gonic/gin"
@@ -16,9 +18,9 @@ type VideoGenerationHandler struct {
 	log          *logger.Logger
 }
 
-func NewVideoGenerationHandler(db *gorm.DB, transferService *services.ResourceTransferService, localStorage *storage.LocalStorage, aiService *services.AIService, log *logger.Logger, promptI18n *services.PromptI18n) *VideoGenerationHandler {
+func NewVideoGenerationHandler(videoService *services.VideoGenerationService, log *logger.Logger) *VideoGenerationHandler {
 	return &VideoGenerationHandler{
-		videoService: services.NewVideoGenerationService(db, transferService, localStorage, aiService, log, promptI18n),
+		videoService: videoService,
 		log:          log,
 	}
 }
@@ -59,11 +61,27 @@ func (h *VideoGenerationHandler) GenerateVideoFromImage(c *gin.Context) {
 	response.Success(c, videoGen)
 }
 
+// BatchGenerateVideosRequest 整集批量生成视频参数
+type BatchGenerateVideosRequest struct {
+	Chained bool `json:"chained"` // 按分镜顺序以上一镜头的尾帧作为首帧、本分镜图片作为尾帧依次生成
+}
+
+// BatchGenerateForEpisode 为整集分镜批量生成视频，chained 为 true 时按分镜顺序以上一镜头的尾帧作为首帧、
+// 本分镜图片作为尾帧依次生成（首尾帧模式，需厂商支持尾帧参考）
 func (h *VideoGenerationHandler) BatchGenerateForEpisode(c *gin.Context) {
 
 	episodeID := c.Param("episode_id")
 
-	videos, err := h.videoService.BatchGenerateVideosForEpisode(episodeID)
+	// 请求体可选
+	var req BatchGenerateVideosRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, err.Error())
+			return
+		}
+	}
+
+	_, videos, err := h.videoService.BatchGenerateVideosForEpisodeWithOptions(episodeID, services.EpisodeBatchOptions{Chained: req.Chained})
 	if err != nil {
 		h.log.Errorw("Failed to batch generate videos", "error", err)
 		response.InternalError(c, err.Error())
@@ -147,3 +165,82 @@ func (h *VideoGenerationHandler) DeleteVideoGeneration(c *gin.Context) {
 
 	response.Success(c, nil)
 }
+
+// CancelVideoGeneration 取消视频生成
+func (h *VideoGenerationHandler) CancelVideoGeneration(c *gin.Context) {
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	if err := h.videoService.CancelVideoGeneration(uint(id)); err != nil {
+		h.log.Warnw("Failed to cancel video generation", "error", err, "id", id)
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, gin.H{"id": id, "status": "cancelled"})
+}
+
+// PauseEpisodeBatch 暂停剧集的批量视频生成
+func (h *VideoGenerationHandler) PauseEpisodeBatch(c *gin.Context) {
+
+	episodeID := c.Param("episode_id")
+
+	batch, err := h.videoService.PauseEpisodeBatch(episodeID)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, batch)
+}
+
+// ResumeEpisodeBatch 恢复剧集的批量视频生成
+func (h *VideoGenerationHandler) ResumeEpisodeBatch(c *gin.Context) {
+
+	episodeID := c.Param("episode_id")
+
+	batch, err := h.videoService.ResumeEpisodeBatch(episodeID)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Success(c, batch)
+}
+
+// HandleProviderCallback 接收厂商推送的视频任务状态，回调地址带有签名参数 video_gen_id 和 token
+func (h *VideoGenerationHandler) HandleProviderCallback(c *gin.Context) {
+	provider := c.Param("provider")
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	challenge, err := h.videoService.HandleProviderCallback(provider, c.Query("video_gen_id"), c.Query("token"), body)
+	if err != nil {
+		h.log.Warnw("Rejected video provider callback", "error", err, "provider", provider, "video_gen_id", c.Query("video_gen_id"))
+		switch {
+		case errors.Is(err, services.ErrInvalidCallbackToken):
+			response.Unauthorized(c, "回调签名无效")
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			response.NotFound(c, "视频生成记录不存在")
+		default:
+			response.BadRequest(c, err.Error())
+		}
+		return
+	}
+
+	// MiniMax 校验回调地址时要求原样返回 challenge
+	if challenge != "" {
+		c.JSON(http.StatusOK, gin.H{"challenge": challenge})
+		return
+	}
+
+	response.Success(c, gin.H{"received": true})
+}
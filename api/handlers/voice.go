@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/drama-generator/backend/application/services"
+	"github.com/drama-generator/backend/pkg/logger"
+	"github.com/drama-generator/backend/pkg/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type VoiceHandler struct {
+	voiceService *services.VoiceService
+	log          *logger.Logger
+}
+
+func NewVoiceHandler(voiceService *services.VoiceService, log *logger.Logger) *VoiceHandler {
+	return &VoiceHandler{
+		voiceService: voiceService,
+		log:          log,
+	}
+}
+
+// SetCharacterVoice 设置角色的配音音色和语速
+func (h *VoiceHandler) SetCharacterVoice(c *gin.Context) {
+	id, ok := parseUintParam(c, "id")
+	if !ok {
+		return
+	}
+
+	var req services.SetCharacterVoiceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	character, err := h.voiceService.SetCharacterVoice(id, &req)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	response.Success(c, character)
+}
+
+// VoiceEpisode 异步为章节对白配音，每句台词生成一个音频素材
+func (h *VoiceHandler) VoiceEpisode(c *gin.Context) {
+	episodeID, ok := parseUintParam(c, "episode_id")
+	if !ok {
+		return
+	}
+
+	var req services.VoiceEpisodeRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, err.Error())
+			return
+		}
+	}
+
+	task, err := h.voiceService.VoiceEpisode(episodeID, &req)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	response.Success(c, gin.H{
+		"task_id": task.ID,
+		"status":  "pending",
+		"message": "配音任务已创建",
+	})
+}
+
+// ListDialogueLines 获取章节的台词及配音结果
+func (h *VoiceHandler) ListDialogueLines(c *gin.Context) {
+	episodeID, ok := parseUintParam(c, "episode_id")
+	if !ok {
+		return
+	}
+
+	lines, err := h.voiceService.ListDialogueLines(episodeID)
+	if err != nil {
+		h.log.Errorw("Failed to list dialogue lines", "error", err, "episode_id", episodeID)
+		response.InternalError(c, "获取台词失败")
+		return
+	}
+
+	response.Success(c, lines)
+}
+
+func (h *VoiceHandler) respondError(c *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) || strings.HasSuffix(err.Error(), "not found") {
+		response.NotFound(c, err.Error())
+		return
+	}
+	response.BadRequest(c, err.Error())
+}
+
+func parseUintParam(c *gin.Context, param string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(param), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return 0, false
+	}
+	return uint(id), true
+}
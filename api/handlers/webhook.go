@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/drama-generator/backend/application/services"
+	"github.com/drama-generator/backend/pkg/logger"
+	"github.com/drama-generator/backend/pkg/response"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type WebhookHandler struct {
+	webhookService *services.WebhookService
+	log            *logger.Logger
+}
+
+func NewWebhookHandler(webhookService *services.WebhookService, log *logger.Logger) *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: webhookService,
+		log:            log,
+	}
+}
+
+// CreateWebhook 创建 webhook 订阅，响应中包含签名密钥（之后不再返回）
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	var req services.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	subscription, secret, err := h.webhookService.CreateSubscription(&req)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	response.Created(c, gin.H{
+		"webhook": subscription,
+		"secret":  secret,
+	})
+}
+
+// ListWebhooks 获取 webhook 订阅列表
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	subscriptions, err := h.webhookService.ListSubscriptions()
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+
+	response.Success(c, gin.H{
+		"webhooks": subscriptions,
+		"events":   services.WebhookEvents,
+	})
+}
+
+// GetWebhook 获取 webhook 订阅
+func (h *WebhookHandler) GetWebhook(c *gin.Context) {
+	id, ok := parseWebhookID(c, "id")
+	if !ok {
+		return
+	}
+
+	subscription, err := h.webhookService.GetSubscription(id)
+	if err != nil {
+		h.respondError(c, err, "webhook 不存在")
+		return
+	}
+
+	response.Success(c, subscription)
+}
+
+// UpdateWebhook 更新 webhook 订阅
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	id, ok := parseWebhookID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req services.UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	subscription, err := h.webhookService.UpdateSubscription(id, &req)
+	if err != nil {
+		h.respondError(c, err, "webhook 不存在")
+		return
+	}
+
+	response.Success(c, subscription)
+}
+
+// DeleteWebhook 删除 webhook 订阅
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	id, ok := parseWebhookID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.webhookService.DeleteSubscription(id); err != nil {
+		h.respondError(c, err, "webhook 不存在")
+		return
+	}
+
+	response.Success(c, gin.H{"message": "删除成功"})
+}
+
+// ListDeliveries 分页获取 webhook 的投递记录，支持 status 过滤
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	id, ok := parseWebhookID(c, "id")
+	if !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	deliveries, total, err := h.webhookService.ListDeliveries(id, c.Query("status"), page, pageSize)
+	if err != nil {
+		response.InternalError(c, err.Error())
+		return
+	}
+
+	response.SuccessWithPagination(c, deliveries, total, page, pageSize)
+}
+
+// GetDelivery 获取投递记录详情
+func (h *WebhookHandler) GetDelivery(c *gin.Context) {
+	id, ok := parseWebhookID(c, "delivery_id")
+	if !ok {
+		return
+	}
+
+	delivery, err := h.webhookService.GetDelivery(id)
+	if err != nil {
+		h.respondError(c, err, "投递记录不存在")
+		return
+	}
+
+	response.Success(c, delivery)
+}
+
+// RedeliverDelivery 重新投递事件，生成新的投递记录
+func (h *WebhookHandler) RedeliverDelivery(c *gin.Context) {
+	id, ok := parseWebhookID(c, "delivery_id")
+	if !ok {
+		return
+	}
+
+	delivery, err := h.webhookService.Redeliver(id)
+	if err != nil {
+		h.respondError(c, err, "投递记录不存在")
+		return
+	}
+
+	response.Success(c, delivery)
+}
+
+func (h *WebhookHandler) respondError(c *gin.Context, err error, notFoundMessage string) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		response.NotFound(c, notFoundMessage)
+		return
+	}
+	response.BadRequest(c, err.Error())
+}
+
+func parseWebhookID(c *gin.Context, param string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(param), 10, 32)
+	if err != nil {
+		response.BadRequest(c, "无效的ID")
+		return 0, false
+	}
+	return uint(id), true
+}
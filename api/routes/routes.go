@@ -1,6 +1,8 @@
 package routes
 
 import (
+	"mime"
+
 	handlers2 "github.com/drama-generator/backend/api/handlers"
 	middlewares2 "github.com/drama-generator/backend/api/middlewares"
 	services2 "github.com/drama-generator/backend/application/services"
@@ -11,7 +13,8 @@ import (
 	"gorm.io/gorm"
 )
 
-func SetupRouter(cfg *config.Config, db *gorm.DB, log *logger.Logger, localStorage interface{}) *gin.Engine {
+// SetupRouter 注册路由；eventBus、jobQueue 和 settings 由启动流程创建，传入各处理器和业务服务
+func SetupRouter(cfg *config.Config, db *gorm.DB, log *logger.Logger, localStorage interface{}, eventBus *services2.EventBus, jobQueue *services2.JobQueue, settings *services2.Settings) *gin.Engine {
 	r := gin.New()
 
 	r.Use(gin.Recovery())
@@ -19,6 +22,9 @@ func SetupRouter(cfg *config.Config, db *gorm.DB, log *logger.Logger, localStora
 	r.Use(middlewares2.CORSMiddleware(cfg.Server.CORSOrigins))
 
 	// 静态文件服务（用户上传的文件）
+	// HLS 播放列表和切片需要正确的 Content-Type，系统 mime 表可能把 .ts 识别为其他类型
+	_ = mime.AddExtensionType(".m3u8", "application/vnd.apple.mpegurl")
+	_ = mime.AddExtensionType(".ts", "video/mp2t")
 	r.Static("/static", cfg.Storage.LocalPath)
 
 	r.GET("/health", func(c *gin.Context) {
@@ -29,37 +35,73 @@ func SetupRouter(cfg *config.Config, db *gorm.DB, log *logger.Logger, localStora
 		})
 	})
 
-	aiService := services2.NewAIService(db, log)
+	// 注册了队列任务处理函数的服务只创建一次，由各处理器共享
+	aiService := services2.NewAIService(db, settings.Retry, log)
 	localStoragePtr := localStorage.(*storage2.LocalStorage)
 	transferService := services2.NewResourceTransferService(db, log)
 	promptI18n := services2.NewPromptI18n(cfg)
-	dramaHandler := handlers2.NewDramaHandler(db, cfg, log, nil)
-	aiConfigHandler := handlers2.NewAIConfigHandler(db, cfg, log)
-	scriptGenHandler := handlers2.NewScriptGenerationHandler(db, cfg, log)
-	imageGenService := services2.NewImageGenerationService(db, cfg, transferService, localStoragePtr, log)
-	imageGenHandler := handlers2.NewImageGenerationHandler(db, cfg, log, transferService, localStoragePtr)
-	videoGenHandler := handlers2.NewVideoGenerationHandler(db, transferService, localStoragePtr, aiService, log, promptI18n)
-	videoMergeHandler := handlers2.NewVideoMergeHandler(db, nil, cfg.Storage.LocalPath, cfg.Storage.BaseURL, log)
+	taskService := services2.NewTaskService(db, jobQueue, eventBus, log)
+	imageGenService := services2.NewImageGenerationService(db, jobQueue, eventBus, taskService, settings, aiService, cfg, transferService, localStoragePtr, log)
+	videoGenService := services2.NewVideoGenerationService(db, jobQueue, eventBus, taskService, settings, transferService, localStoragePtr, aiService, log, promptI18n)
+	videoMergeService := services2.NewVideoMergeService(db, jobQueue, eventBus, settings, aiService, transferService, cfg.Storage.LocalPath, cfg.Storage.BaseURL, log)
+	characterLibraryService := services2.NewCharacterLibraryService(db, jobQueue, taskService, aiService, log, cfg)
+	propService := services2.NewPropService(db, jobQueue, aiService, taskService, imageGenService, log, cfg)
+	scriptGenService := services2.NewScriptGenerationService(db, jobQueue, taskService, aiService, cfg, log)
+	storyboardService := services2.NewStoryboardService(db, jobQueue, taskService, aiService, cfg, log)
+	framePromptService := services2.NewFramePromptService(db, jobQueue, taskService, aiService, cfg, log)
+	productionService := services2.NewEpisodeProductionService(db, jobQueue, eventBus, taskService, aiService, characterLibraryService, propService, imageGenService, storyboardService, framePromptService, videoGenService, videoMergeService, log)
+	subtitleService := services2.NewSubtitleService(db, jobQueue, taskService, cfg.Storage.LocalPath, cfg.Storage.BaseURL, log)
+	webhookService := services2.NewWebhookService(db, jobQueue, cfg.Webhook, log)
+	timelineRenderService := services2.NewTimelineRenderService(db, jobQueue, taskService, cfg.Storage.LocalPath, cfg.Storage.BaseURL, log)
+	voiceService := services2.NewVoiceService(db, jobQueue, taskService, aiService, cfg.Storage.LocalPath, cfg.Storage.BaseURL, log)
+	audioLibraryService := services2.NewAudioLibraryService(db, jobQueue, taskService, aiService, cfg.Storage.LocalPath, cfg.Storage.BaseURL, log)
+	lipSyncService := services2.NewLipSyncService(db, jobQueue, eventBus, taskService, aiService, localStoragePtr, log)
+	renditionService := services2.NewRenditionService(db, jobQueue, taskService, cfg.Storage.LocalPath, cfg.Storage.BaseURL, log)
+	thumbnailService := services2.NewThumbnailService(db, jobQueue, taskService, cfg.Storage.LocalPath, cfg.Storage.BaseURL, log)
+	qcService := services2.NewQCService(db, jobQueue, taskService, settings.QC, aiService, cfg.Storage.LocalPath, log)
+	reframeService := services2.NewReframeService(db, jobQueue, taskService, cfg.Storage.LocalPath, cfg.Storage.BaseURL, log)
+
+	dramaHandler := handlers2.NewDramaHandler(db, cfg, log, videoMergeService, subtitleService)
+	aiConfigHandler := handlers2.NewAIConfigHandler(db, cfg, log, aiService)
+	scriptGenHandler := handlers2.NewScriptGenerationHandler(scriptGenService, taskService, log)
+	imageGenHandler := handlers2.NewImageGenerationHandler(db, cfg, log, imageGenService, taskService)
+	videoGenHandler := handlers2.NewVideoGenerationHandler(videoGenService, log)
+	videoMergeHandler := handlers2.NewVideoMergeHandler(videoMergeService, log)
 	assetHandler := handlers2.NewAssetHandler(db, cfg, log)
-	characterLibraryService := services2.NewCharacterLibraryService(db, log, cfg)
-	characterLibraryHandler := handlers2.NewCharacterLibraryHandler(db, cfg, log, transferService, localStoragePtr)
+	characterLibraryHandler := handlers2.NewCharacterLibraryHandler(characterLibraryService, imageGenService, log)
 	uploadHandler, err := handlers2.NewUploadHandler(cfg, log, characterLibraryService)
 	if err != nil {
 		log.Fatalw("Failed to create upload handler", "error", err)
 	}
-	storyboardHandler := handlers2.NewStoryboardHandler(db, cfg, log)
+	storyboardHandler := handlers2.NewStoryboardHandler(storyboardService, taskService, log)
 	sceneHandler := handlers2.NewSceneHandler(db, log, imageGenService)
-	taskHandler := handlers2.NewTaskHandler(db, log)
-	framePromptService := services2.NewFramePromptService(db, cfg, log)
+	taskHandler := handlers2.NewTaskHandler(taskService, jobQueue, log)
+	eventHandler := handlers2.NewEventHandler(eventBus, log)
 	framePromptHandler := handlers2.NewFramePromptHandler(framePromptService, log)
 	audioExtractionHandler := handlers2.NewAudioExtractionHandler(log, cfg.Storage.LocalPath)
 	settingsHandler := handlers2.NewSettingsHandler(cfg, log)
-	propHandler := handlers2.NewPropHandler(db, cfg, log, aiService, imageGenService)
+	propHandler := handlers2.NewPropHandler(propService, log)
+	productionHandler := handlers2.NewEpisodeProductionHandler(productionService, log)
+	webhookHandler := handlers2.NewWebhookHandler(webhookService, log)
+	timelineHandler := handlers2.NewTimelineHandler(db, cfg, log, timelineRenderService)
+	voiceHandler := handlers2.NewVoiceHandler(voiceService, log)
+	subtitleHandler := handlers2.NewSubtitleHandler(subtitleService, log)
+	audioLibraryHandler := handlers2.NewAudioLibraryHandler(audioLibraryService, log)
+	lipSyncHandler := handlers2.NewLipSyncHandler(lipSyncService, log)
+	renditionHandler := handlers2.NewRenditionHandler(renditionService, log)
+	thumbnailHandler := handlers2.NewThumbnailHandler(thumbnailService, log)
+	qcHandler := handlers2.NewQCHandler(qcService, log)
+	brandingHandler := handlers2.NewBrandingHandler(db, cfg, log)
+	reframeHandler := handlers2.NewReframeHandler(reframeService, log)
+	colorGradingHandler := handlers2.NewColorGradingHandler(db, cfg, log)
 
 	api := r.Group("/api/v1")
 	{
 		api.Use(middlewares2.RateLimitMiddleware())
 
+		// 实时事件推送（SSE）
+		api.GET("/events", eventHandler.StreamEvents)
+
 		dramas := api.Group("/dramas")
 		{
 			dramas.GET("", dramaHandler.ListDramas)
@@ -74,6 +116,15 @@ func SetupRouter(cfg *config.Config, db *gorm.DB, log *logger.Logger, localStora
 			dramas.PUT("/:id/characters", dramaHandler.SaveCharacters)
 			dramas.PUT("/:id/episodes", dramaHandler.SaveEpisodes)
 			dramas.PUT("/:id/progress", dramaHandler.SaveProgress)
+			dramas.GET("/:id/subtitle-style", subtitleHandler.GetSubtitleStyle)
+			dramas.PUT("/:id/subtitle-style", subtitleHandler.UpdateSubtitleStyle)
+			dramas.GET("/:id/output-settings", renditionHandler.GetOutputSettings)
+			dramas.PUT("/:id/output-settings", renditionHandler.UpdateOutputSettings)
+			dramas.GET("/:id/branding", brandingHandler.GetBranding)
+			dramas.PUT("/:id/branding", brandingHandler.UpdateBranding)
+			dramas.GET("/:id/color-grading", colorGradingHandler.GetColorGrading)
+			dramas.PUT("/:id/color-grading", colorGradingHandler.UpdateColorGrading)
+			dramas.POST("/:id/color-grading/lut", colorGradingHandler.UploadLUT)
 			dramas.GET("/:id/props", propHandler.ListProps) // Added prop list route
 		}
 
@@ -112,6 +163,7 @@ func SetupRouter(cfg *config.Config, db *gorm.DB, log *logger.Logger, localStora
 			characters.PUT("/:id/image", characterLibraryHandler.UploadCharacterImage)
 			characters.PUT("/:id/image-from-library", characterLibraryHandler.ApplyLibraryItemToCharacter)
 			characters.POST("/:id/add-to-library", characterLibraryHandler.AddCharacterToLibrary)
+			characters.PUT("/:id/voice", voiceHandler.SetCharacterVoice)
 		}
 
 		props := api.Group("/props")
@@ -138,6 +190,21 @@ func SetupRouter(cfg *config.Config, db *gorm.DB, log *logger.Logger, localStora
 			episodes.GET("/:episode_id/storyboards", sceneHandler.GetStoryboardsForEpisode)
 			episodes.POST("/:episode_id/finalize", dramaHandler.FinalizeEpisode)
 			episodes.GET("/:episode_id/download", dramaHandler.DownloadEpisodeVideo)
+			episodes.POST("/:episode_id/produce", productionHandler.ProduceEpisode)
+			episodes.GET("/:episode_id/produce", productionHandler.GetProduction)
+			episodes.POST("/:episode_id/produce/approve", productionHandler.ApproveStep)
+			episodes.GET("/:episode_id/export", timelineHandler.ExportEpisode)
+			episodes.POST("/:episode_id/timeline", timelineHandler.BuildEpisodeTimeline)
+			episodes.POST("/:episode_id/voice", voiceHandler.VoiceEpisode)
+			episodes.GET("/:episode_id/dialogue-lines", voiceHandler.ListDialogueLines)
+			episodes.GET("/:episode_id/subtitles", subtitleHandler.ExportEpisodeSubtitles)
+			episodes.POST("/:episode_id/subtitles/embed", subtitleHandler.EmbedEpisodeSubtitles)
+			episodes.POST("/:episode_id/audio-match", audioLibraryHandler.MatchEpisodeAudio)
+			episodes.POST("/:episode_id/lip-sync", lipSyncHandler.LipSyncEpisode)
+			episodes.GET("/:episode_id/renditions", renditionHandler.ListEpisodeRenditions)
+			episodes.POST("/:episode_id/renditions", renditionHandler.RenderEpisodeRenditions)
+			episodes.POST("/:episode_id/poster", thumbnailHandler.SetEpisodePoster)
+			episodes.GET("/:episode_id/readiness", qcHandler.GetEpisodeReadiness)
 		}
 
 		// 任务路由
@@ -145,6 +212,48 @@ func SetupRouter(cfg *config.Config, db *gorm.DB, log *logger.Logger, localStora
 		{
 			tasks.GET("/:task_id", taskHandler.GetTaskStatus)
 			tasks.GET("", taskHandler.GetResourceTasks)
+			tasks.POST("/:task_id/cancel", taskHandler.CancelTask)
+			tasks.POST("/:task_id/pause", taskHandler.PauseTask)
+			tasks.POST("/:task_id/resume", taskHandler.ResumeTask)
+		}
+
+		// Webhook 路由
+		webhooks := api.Group("/webhooks")
+		{
+			webhooks.GET("", webhookHandler.ListWebhooks)
+			webhooks.POST("", webhookHandler.CreateWebhook)
+			webhooks.GET("/:id", webhookHandler.GetWebhook)
+			webhooks.PUT("/:id", webhookHandler.UpdateWebhook)
+			webhooks.DELETE("/:id", webhookHandler.DeleteWebhook)
+			webhooks.GET("/:id/deliveries", webhookHandler.ListDeliveries)
+			webhooks.GET("/deliveries/:delivery_id", webhookHandler.GetDelivery)
+			webhooks.POST("/deliveries/:delivery_id/redeliver", webhookHandler.RedeliverDelivery)
+		}
+
+		// 时间线编辑（时间单位：毫秒）
+		timelines := api.Group("/timelines")
+		{
+			timelines.GET("", timelineHandler.ListTimelines)
+			timelines.POST("", timelineHandler.CreateTimeline)
+			timelines.GET("/:id", timelineHandler.GetTimeline)
+			timelines.PUT("/:id", timelineHandler.UpdateTimeline)
+			timelines.DELETE("/:id", timelineHandler.DeleteTimeline)
+			timelines.POST("/:id/render", timelineHandler.RenderTimeline)
+			timelines.GET("/:id/export", timelineHandler.ExportTimeline)
+			timelines.POST("/:id/tracks", timelineHandler.AddTrack)
+			timelines.PUT("/tracks/:track_id", timelineHandler.UpdateTrack)
+			timelines.DELETE("/tracks/:track_id", timelineHandler.DeleteTrack)
+			timelines.POST("/tracks/:track_id/clips", timelineHandler.AddClip)
+			timelines.PUT("/clips/:clip_id", timelineHandler.UpdateClip)
+			timelines.DELETE("/clips/:clip_id", timelineHandler.DeleteClip)
+			timelines.POST("/clips/:clip_id/move", timelineHandler.MoveClip)
+			timelines.POST("/clips/:clip_id/split", timelineHandler.SplitClip)
+			timelines.POST("/clips/:clip_id/ripple-delete", timelineHandler.RippleDeleteClip)
+			timelines.PUT("/clips/:clip_id/transitions/:position", timelineHandler.SetTransition)
+			timelines.DELETE("/clips/:clip_id/transitions/:position", timelineHandler.RemoveTransition)
+			timelines.POST("/clips/:clip_id/effects", timelineHandler.AddEffect)
+			timelines.PUT("/effects/:effect_id", timelineHandler.UpdateEffect)
+			timelines.DELETE("/effects/:effect_id", timelineHandler.DeleteEffect)
 		}
 
 		// 场景路由
@@ -164,11 +273,15 @@ func SetupRouter(cfg *config.Config, db *gorm.DB, log *logger.Logger, localStora
 			images.POST("", imageGenHandler.GenerateImage)
 			images.GET("/:id", imageGenHandler.GetImageGeneration)
 			images.DELETE("/:id", imageGenHandler.DeleteImageGeneration)
+			images.POST("/:id/cancel", imageGenHandler.CancelImageGeneration)
+			images.POST("/:id/qc", qcHandler.CheckImage)
 			images.POST("/scene/:scene_id", imageGenHandler.GenerateImagesForScene)
 			images.POST("/upload", imageGenHandler.UploadImage)
 			images.GET("/episode/:episode_id/backgrounds", imageGenHandler.GetBackgroundsForEpisode)
 			images.POST("/episode/:episode_id/backgrounds/extract", imageGenHandler.ExtractBackgroundsForEpisode)
 			images.POST("/episode/:episode_id/batch", imageGenHandler.BatchGenerateForEpisode)
+			images.POST("/episode/:episode_id/batch/pause", imageGenHandler.PauseEpisodeBatch)
+			images.POST("/episode/:episode_id/batch/resume", imageGenHandler.ResumeEpisodeBatch)
 		}
 
 		videos := api.Group("/videos")
@@ -177,8 +290,22 @@ func SetupRouter(cfg *config.Config, db *gorm.DB, log *logger.Logger, localStora
 			videos.POST("", videoGenHandler.GenerateVideo)
 			videos.GET("/:id", videoGenHandler.GetVideoGeneration)
 			videos.DELETE("/:id", videoGenHandler.DeleteVideoGeneration)
+			videos.POST("/:id/cancel", videoGenHandler.CancelVideoGeneration)
+			videos.POST("/:id/qc", qcHandler.CheckVideo)
 			videos.POST("/image/:image_gen_id", videoGenHandler.GenerateVideoFromImage)
 			videos.POST("/episode/:episode_id/batch", videoGenHandler.BatchGenerateForEpisode)
+			videos.POST("/episode/:episode_id/batch/pause", videoGenHandler.PauseEpisodeBatch)
+			videos.POST("/episode/:episode_id/batch/resume", videoGenHandler.ResumeEpisodeBatch)
+			videos.POST("/callbacks/:provider", videoGenHandler.HandleProviderCallback)
+		}
+
+		// 画面比例转换（横屏转竖屏等），智能裁剪的关键帧可以手动调整
+		reframes := api.Group("/reframes")
+		{
+			reframes.GET("", reframeHandler.ListReframes)
+			reframes.POST("", reframeHandler.CreateReframe)
+			reframes.GET("/:id", reframeHandler.GetReframe)
+			reframes.PUT("/:id/keyframes", reframeHandler.UpdateKeyframes)
 		}
 
 		videoMerges := api.Group("/video-merges")
@@ -198,6 +325,7 @@ func SetupRouter(cfg *config.Config, db *gorm.DB, log *logger.Logger, localStora
 			assets.DELETE("/:id", assetHandler.DeleteAsset)
 			assets.POST("/import/image/:image_gen_id", assetHandler.ImportFromImageGen)
 			assets.POST("/import/video/:video_gen_id", assetHandler.ImportFromVideoGen)
+			assets.POST("/:id/poster", thumbnailHandler.SetAssetPoster)
 		}
 
 		storyboards := api.Group("/storyboards")
@@ -209,6 +337,8 @@ func SetupRouter(cfg *config.Config, db *gorm.DB, log *logger.Logger, localStora
 			storyboards.POST("/:id/props", propHandler.AssociateProps)
 			storyboards.POST("/:id/frame-prompt", framePromptHandler.GenerateFramePrompt)
 			storyboards.GET("/:id/frame-prompts", handlers2.GetStoryboardFramePrompts(db, log))
+			storyboards.PUT("/:id/audio", audioLibraryHandler.SetStoryboardAudio)
+			storyboards.POST("/:id/lip-sync", lipSyncHandler.LipSyncStoryboard)
 		}
 
 		audio := api.Group("/audio")
@@ -217,6 +347,14 @@ func SetupRouter(cfg *config.Config, db *gorm.DB, log *logger.Logger, localStora
 			audio.POST("/extract/batch", audioExtractionHandler.BatchExtractAudio)
 		}
 
+		audioLibrary := api.Group("/audio-library")
+		{
+			audioLibrary.GET("", audioLibraryHandler.ListAudioLibrary)
+			audioLibrary.POST("", audioLibraryHandler.CreateAudioLibraryItem)
+			audioLibrary.PUT("/:id", audioLibraryHandler.UpdateAudioLibraryItem)
+			audioLibrary.POST("/generate", audioLibraryHandler.GenerateMusic)
+		}
+
 		settings := api.Group("/settings")
 		{
 			settings.GET("/language", settingsHandler.GetLanguage)
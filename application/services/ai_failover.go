@@ -0,0 +1,161 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/drama-generator/backend/domain/models"
+	"github.com/drama-generator/backend/pkg/config"
+	"github.com/drama-generator/backend/pkg/utils"
+)
+
+// RetryPolicy AI调用的重试策略
+type RetryPolicy struct {
+	MaxRetries int           // 单个配置上临时错误的最大重试次数
+	BaseDelay  time.Duration // 首次重试等待时间，之后按指数退避
+	MaxDelay   time.Duration // 单次等待上限
+}
+
+// NewRetryPolicy 根据配置文件生成AI调用重试策略，未填写时重试 3 次，首次等待 2 秒，单次最多等待 30 秒
+func NewRetryPolicy(cfg config.AIConfig) RetryPolicy {
+	policy := RetryPolicy{
+		MaxRetries: 3,
+		BaseDelay:  2 * time.Second,
+		MaxDelay:   30 * time.Second,
+	}
+	if cfg.MaxRetries > 0 {
+		policy.MaxRetries = cfg.MaxRetries
+	}
+	if cfg.RetryBaseDelay > 0 {
+		policy.BaseDelay = time.Duration(cfg.RetryBaseDelay) * time.Second
+	}
+	if cfg.RetryMaxDelay > 0 {
+		policy.MaxDelay = time.Duration(cfg.RetryMaxDelay) * time.Second
+	}
+	return policy
+}
+
+// failoverCandidate 故障转移候选：配置及在该配置上使用的模型
+type failoverCandidate struct {
+	config *models.AIServiceConfig
+	model  string
+}
+
+// getFailoverCandidates 按优先级返回可用于故障转移的激活配置
+// 指定模型时，包含该模型的配置排在前面，其余配置使用各自的第一个模型兜底
+func (s *AIService) getFailoverCandidates(serviceType string, modelName string) ([]failoverCandidate, error) {
+	var configs []models.AIServiceConfig
+	err := s.db.Where("service_type = ? AND is_active = ?", serviceType, true).
+		Order("priority DESC, created_at DESC").
+		Find(&configs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var matched, others []failoverCandidate
+	for i := range configs {
+		config := &configs[i]
+		if modelName != "" && containsModel(config.Model, modelName) {
+			matched = append(matched, failoverCandidate{config: config, model: modelName})
+			continue
+		}
+		model := ""
+		if len(config.Model) > 0 {
+			model = config.Model[0]
+		}
+		others = append(others, failoverCandidate{config: config, model: model})
+	}
+
+	candidates := append(matched, others...)
+	if len(candidates) == 0 {
+		return nil, errors.New("no active config found")
+	}
+	return candidates, nil
+}
+
+// ExecuteWithFailover 按优先级依次使用激活配置执行AI调用
+// 临时错误（超时、429、5xx）在同一配置上按指数退避重试，重试耗尽后切换到下一个配置；
+// 永久错误（4xx，如内容审核拒绝）换配置也无法成功，直接返回
+// ctx 取消（任务暂停、取消或队列停止）时立即停止重试并返回 ctx.Err()
+// 返回最终成功服务本次请求的配置
+func (s *AIService) ExecuteWithFailover(ctx context.Context, serviceType string, modelName string, fn func(ctx context.Context, config *models.AIServiceConfig, model string) error) (*models.AIServiceConfig, error) {
+	candidates, err := s.getFailoverCandidates(serviceType, modelName)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := s.retryPolicy
+	var lastErr error
+	for i, candidate := range candidates {
+		for attempt := 0; attempt <= policy.MaxRetries; attempt++ {
+			if attempt > 0 {
+				delay := utils.Backoff(attempt, policy.BaseDelay, policy.MaxDelay)
+				s.log.Warnw("Retrying AI request",
+					"service_type", serviceType,
+					"config_id", candidate.config.ID,
+					"provider", candidate.config.Provider,
+					"model", candidate.model,
+					"attempt", attempt,
+					"delay", delay,
+					"error", lastErr)
+				select {
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				case <-time.After(delay):
+				}
+			}
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
+			lastErr = fn(ctx, candidate.config, candidate.model)
+			if lastErr == nil {
+				if i > 0 {
+					s.log.Infow("AI request served by fallback config",
+						"service_type", serviceType,
+						"config_id", candidate.config.ID,
+						"provider", candidate.config.Provider,
+						"model", candidate.model)
+				}
+				return candidate.config, nil
+			}
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if !utils.IsTransientError(lastErr) {
+				s.log.Warnw("AI request failed with permanent error, not falling back",
+					"service_type", serviceType,
+					"config_id", candidate.config.ID,
+					"provider", candidate.config.Provider,
+					"model", candidate.model,
+					"error", lastErr)
+				return nil, lastErr
+			}
+		}
+
+		if i < len(candidates)-1 {
+			s.log.Warnw("AI config failed, falling back to next config",
+				"service_type", serviceType,
+				"config_id", candidate.config.ID,
+				"provider", candidate.config.Provider,
+				"model", candidate.model,
+				"error", lastErr)
+		}
+	}
+
+	if len(candidates) == 1 {
+		return nil, lastErr
+	}
+	return nil, fmt.Errorf("all %d %s configs failed, last error: %w", len(candidates), serviceType, lastErr)
+}
+
+func containsModel(modelList []string, modelName string) bool {
+	for _, model := range modelList {
+		if model == modelName {
+			return true
+		}
+	}
+	return false
+}
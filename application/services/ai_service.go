@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"fmt"
 
@@ -11,19 +12,21 @@ import (
 )
 
 type AIService struct {
-	db  *gorm.DB
-	log *logger.Logger
+	db          *gorm.DB
+	log         *logger.Logger
+	retryPolicy RetryPolicy
 }
 
-func NewAIService(db *gorm.DB, log *logger.Logger) *AIService {
+func NewAIService(db *gorm.DB, retryPolicy RetryPolicy, log *logger.Logger) *AIService {
 	return &AIService{
-		db:  db,
-		log: log,
+		db:          db,
+		log:         log,
+		retryPolicy: retryPolicy,
 	}
 }
 
 type CreateAIConfigRequest struct {
-	ServiceType   string            `json:"service_type" binding:"required,oneof=text image video"`
+	ServiceType   string            `json:"service_type" binding:"required,oneof=text image video tts music lipsync"`
 	Name          string            `json:"name" binding:"required,min=1,max=100"`
 	Provider      string            `json:"provider" binding:"required"`
 	BaseURL       string            `json:"base_url" binding:"required,url"`
@@ -81,6 +84,8 @@ func (s *AIService) CreateConfig(req *CreateAIConfigRequest) (*models.AIServiceC
 				if queryEndpoint == "" {
 					queryEndpoint = "/videos/{taskId}"
 				}
+			} else if req.ServiceType == "tts" {
+				endpoint = "/audio/speech"
 			}
 		case "chatfire":
 			if req.ServiceType == "text" {
@@ -106,6 +111,8 @@ func (s *AIService) CreateConfig(req *CreateAIConfigRequest) (*models.AIServiceC
 				endpoint = "/chat/completions"
 			} else if req.ServiceType == "image" {
 				endpoint = "/images/generations"
+			} else if req.ServiceType == "tts" {
+				endpoint = "/audio/speech"
 			}
 		}
 	}
@@ -355,6 +362,39 @@ func (s *AIService) GetConfigForModel(serviceType string, modelName string) (*mo
 	return nil, errors.New("no active config found for model: " + modelName)
 }
 
+// ResolveProvider 获取指定服务类型/模型实际使用的厂商，用于任务队列按厂商限制并发
+func (s *AIService) ResolveProvider(serviceType string, modelName string) string {
+	var config *models.AIServiceConfig
+	if modelName != "" {
+		config, _ = s.GetConfigForModel(serviceType, modelName)
+	}
+	if config == nil {
+		config, _ = s.GetDefaultConfig(serviceType)
+	}
+	if config == nil {
+		return ""
+	}
+	return config.Provider
+}
+
+// ResolveModelForProvider 获取指定厂商优先级最高的激活配置的第一个模型，用于只指定了厂商的请求
+func (s *AIService) ResolveModelForProvider(serviceType string, provider string) (string, error) {
+	var config models.AIServiceConfig
+	err := s.db.Where("service_type = ? AND provider = ? AND is_active = ?", serviceType, provider, true).
+		Order("priority DESC, created_at DESC").
+		First(&config).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", fmt.Errorf("no active %s config found for provider: %s", serviceType, provider)
+		}
+		return "", err
+	}
+	if len(config.Model) == 0 {
+		return "", fmt.Errorf("%s config %d has no model", serviceType, config.ID)
+	}
+	return config.Model[0], nil
+}
+
 func (s *AIService) GetAIClient(serviceType string) (ai.AIClient, error) {
 	config, err := s.GetDefaultConfig(serviceType)
 	if err != nil {
@@ -367,25 +407,7 @@ func (s *AIService) GetAIClient(serviceType string) (ai.AIClient, error) {
 		model = config.Model[0]
 	}
 
-	// 使用数据库配置中的 endpoint，如果为空则根据 provider 设置默认值
-	endpoint := config.Endpoint
-	if endpoint == "" {
-		switch config.Provider {
-		case "gemini", "google":
-			endpoint = "/v1beta/models/{model}:generateContent"
-		default:
-			endpoint = "/chat/completions"
-		}
-	}
-
-	// 根据 provider 创建对应的客户端
-	switch config.Provider {
-	case "gemini", "google":
-		return ai.NewGeminiClient(config.BaseURL, config.APIKey, model, endpoint), nil
-	default:
-		// openai, chatfire 等其他厂商都使用 OpenAI 格式
-		return ai.NewOpenAIClient(config.BaseURL, config.APIKey, model, endpoint), nil
-	}
+	return newTextClient(config, model), nil
 }
 
 // GetAIClientForModel 根据服务类型和模型名称获取对应的AI客户端
@@ -395,6 +417,11 @@ func (s *AIService) GetAIClientForModel(serviceType string, modelName string) (a
 		return nil, err
 	}
 
+	return newTextClient(config, modelName), nil
+}
+
+// newTextClient 根据配置创建文本AI客户端
+func newTextClient(config *models.AIServiceConfig, model string) ai.AIClient {
 	// 使用数据库配置中的 endpoint，如果为空则根据 provider 设置默认值
 	endpoint := config.Endpoint
 	if endpoint == "" {
@@ -409,20 +436,39 @@ func (s *AIService) GetAIClientForModel(serviceType string, modelName string) (a
 	// 根据 provider 创建对应的客户端
 	switch config.Provider {
 	case "gemini", "google":
-		return ai.NewGeminiClient(config.BaseURL, config.APIKey, modelName, endpoint), nil
+		return ai.NewGeminiClient(config.BaseURL, config.APIKey, model, endpoint)
 	default:
 		// openai, chatfire 等其他厂商都使用 OpenAI 格式
-		return ai.NewOpenAIClient(config.BaseURL, config.APIKey, modelName, endpoint), nil
+		return ai.NewOpenAIClient(config.BaseURL, config.APIKey, model, endpoint)
 	}
 }
 
+// GenerateText 使用默认文本配置生成文本，失败时按优先级自动切换到其他配置
 func (s *AIService) GenerateText(prompt string, systemPrompt string, options ...func(*ai.ChatCompletionRequest)) (string, error) {
-	client, err := s.GetAIClient("text")
+	return s.GenerateTextWithModel("", prompt, systemPrompt, options...)
+}
+
+// GenerateTextWithModel 使用指定模型生成文本，model 为空时使用默认配置
+func (s *AIService) GenerateTextWithModel(model string, prompt string, systemPrompt string, options ...func(*ai.ChatCompletionRequest)) (string, error) {
+	return s.GenerateTextWithContext(context.Background(), model, prompt, systemPrompt, options...)
+}
+
+// GenerateTextWithContext 同 GenerateTextWithModel，队列任务传入任务的 ctx，暂停或取消时停止重试
+// 临时错误按退避策略重试，重试耗尽时切换到下一个包含该模型（或其次优先级）的配置，永久错误直接返回
+func (s *AIService) GenerateTextWithContext(ctx context.Context, model string, prompt string, systemPrompt string, options ...func(*ai.ChatCompletionRequest)) (string, error) {
+	var text string
+	_, err := s.ExecuteWithFailover(ctx, "text", model, func(ctx context.Context, config *models.AIServiceConfig, servedModel string) error {
+		result, genErr := newTextClient(config, servedModel).GenerateText(prompt, systemPrompt, options...)
+		if genErr != nil {
+			return genErr
+		}
+		text = result
+		return nil
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed to get AI client: %w", err)
+		return "", err
 	}
-
-	return client.GenerateText(prompt, systemPrompt, options...)
+	return text, nil
 }
 
 func (s *AIService) GenerateImage(prompt string, size string, n int) ([]string, error) {
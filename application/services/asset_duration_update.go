@@ -28,13 +28,17 @@ func (s *AssetService) UpdateAssetDurationFromFile(assetID uint, localFilePath s
 	}
 
 	durationInt := int(duration + 0.5)
-	if err := s.db.Model(&asset).Update("duration", durationInt).Error; err != nil {
+	durationMs := int(duration*1000 + 0.5)
+	if err := s.db.Model(&asset).Updates(map[string]interface{}{
+		"duration":    durationInt,
+		"duration_ms": durationMs,
+	}).Error; err != nil {
 		return fmt.Errorf("failed to update duration: %w", err)
 	}
 
 	s.log.Infow("Updated asset duration from file",
 		"asset_id", assetID,
-		"duration", durationInt,
+		"duration_ms", durationMs,
 		"file", localFilePath)
 
 	return nil
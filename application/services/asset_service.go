@@ -279,9 +279,8 @@ func (s *AssetService) ImportFromVideoGen(videoGenID uint) (*models.Asset, error
 		Height:        videoGen.Height,
 	}
 
-	if videoGen.FirstFrameURL != nil {
-		asset.ThumbnailURL = videoGen.FirstFrameURL
-	}
+	asset.ThumbnailURL = videoGenThumbnail(&videoGen)
+	asset.SpriteVTTURL = videoGen.SpriteVTTURL
 
 	if err := s.db.Create(asset).Error; err != nil {
 		return nil, fmt.Errorf("failed to create asset: %w", err)
@@ -0,0 +1,601 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	models "github.com/drama-generator/backend/domain/models"
+	"github.com/drama-generator/backend/infrastructure/external/ffmpeg"
+	"github.com/drama-generator/backend/pkg/audio"
+	"github.com/drama-generator/backend/pkg/logger"
+	"github.com/drama-generator/backend/pkg/utils"
+	"gorm.io/gorm"
+)
+
+// audioMatchThreshold 匹配得分低于该值的素材不采用，得分为每个关键词的平均得分（0-3）
+const audioMatchThreshold = 0.5
+
+// musicGenerationPayload 配乐生成任务参数
+type musicGenerationPayload struct {
+	Prompt        string   `json:"prompt"`
+	Name          string   `json:"name"`
+	Tags          []string `json:"tags,omitempty"`
+	Duration      int      `json:"duration,omitempty"`
+	DramaID       *uint    `json:"drama_id,omitempty"`
+	StoryboardIDs []uint   `json:"storyboard_ids,omitempty"`
+}
+
+// ListAudioLibraryRequest 检索音频库，keyword 匹配名称、描述和标签，tags 需全部命中
+// 指定 drama_id 时返回该剧本的素材和不属于任何剧本的公共素材
+type ListAudioLibraryRequest struct {
+	Category string
+	Keyword  string
+	Tags     []string
+	DramaID  *uint
+	Page     int
+	PageSize int
+}
+
+// CreateAudioLibraryItemRequest 将已上传的音频加入音频库
+type CreateAudioLibraryItemRequest struct {
+	DramaID     *uint    `json:"drama_id"`
+	Name        string   `json:"name" binding:"required,max=200"`
+	Category    string   `json:"category" binding:"required,oneof=bgm sfx"`
+	URL         string   `json:"url" binding:"required"`
+	LocalPath   *string  `json:"local_path"`
+	Description *string  `json:"description"`
+	Tags        []string `json:"tags"`
+	Duration    *int     `json:"duration"` // 秒，未提供时用 ffprobe 读取本地文件
+}
+
+type UpdateAudioLibraryItemRequest struct {
+	Name        *string  `json:"name" binding:"omitempty,max=200"`
+	Category    *string  `json:"category" binding:"omitempty,oneof=bgm sfx"`
+	Description *string  `json:"description"`
+	Tags        []string `json:"tags"` // 传入时整体替换
+}
+
+// MatchEpisodeAudioRequest 为章节分镜匹配音频库中的配乐和音效
+// 默认跳过已指定配乐/音效的分镜；generate_missing 为 true 时为没有匹配到的配乐提示词生成配乐
+type MatchEpisodeAudioRequest struct {
+	StoryboardIDs   []uint `json:"storyboard_ids"`
+	Overwrite       bool   `json:"overwrite"`
+	GenerateMissing bool   `json:"generate_missing"`
+}
+
+// StoryboardAudioMatch 单个分镜的匹配结果，Score 为 0 表示未匹配或沿用原有素材
+type StoryboardAudioMatch struct {
+	StoryboardID     uint    `json:"storyboard_id"`
+	StoryboardNumber int     `json:"storyboard_number"`
+	BgmAssetID       *uint   `json:"bgm_asset_id"`
+	BgmScore         float64 `json:"bgm_score"`
+	SfxAssetID       *uint   `json:"sfx_asset_id"`
+	SfxScore         float64 `json:"sfx_score"`
+}
+
+type MatchEpisodeAudioResult struct {
+	Matches   []StoryboardAudioMatch `json:"matches"`
+	TaskIDs   []string               `json:"task_ids,omitempty"` // 生成配乐的任务
+	Unmatched int                    `json:"unmatched"`
+}
+
+// SetStoryboardAudioRequest 手动指定分镜的配乐和音效，传 0 时清除
+type SetStoryboardAudioRequest struct {
+	BgmAssetID *uint `json:"bgm_asset_id"`
+	SfxAssetID *uint `json:"sfx_asset_id"`
+}
+
+// GenerateMusicRequest 按提示词生成配乐并加入音频库
+type GenerateMusicRequest struct {
+	Prompt        string   `json:"prompt" binding:"required"`
+	Name          string   `json:"name" binding:"max=200"`
+	Tags          []string `json:"tags"`
+	Duration      int      `json:"duration"` // 期望时长（秒），仅部分厂商支持
+	DramaID       *uint    `json:"drama_id"`
+	StoryboardIDs []uint   `json:"storyboard_ids"` // 生成完成后作为这些分镜的配乐
+}
+
+type AudioLibraryService struct {
+	db          *gorm.DB
+	aiService   *AIService
+	taskService *TaskService
+	ffmpeg      *ffmpeg.FFmpeg
+	storagePath string
+	baseURL     string
+	log         *logger.Logger
+	jobQueue    *JobQueue
+}
+
+func NewAudioLibraryService(db *gorm.DB, jobQueue *JobQueue, taskService *TaskService, aiService *AIService, storagePath, baseURL string, log *logger.Logger) *AudioLibraryService {
+	service := &AudioLibraryService{
+		db:          db,
+		aiService:   aiService,
+		taskService: taskService,
+		ffmpeg:      ffmpeg.NewFFmpeg(log),
+		storagePath: storagePath,
+		baseURL:     baseURL,
+		log:         log,
+		jobQueue:    jobQueue,
+	}
+
+	service.jobQueue.RegisterHandler("music_generation", service.handleMusicGenerationJob)
+
+	return service
+}
+
+// ListAudioLibrary 检索音频库中的背景音乐和音效
+func (s *AudioLibraryService) ListAudioLibrary(req *ListAudioLibraryRequest) ([]models.Asset, int64, error) {
+	query := s.db.Model(&models.Asset{}).Where("type = ?", models.AssetTypeAudio)
+
+	if req.Category != "" {
+		query = query.Where("category = ?", req.Category)
+	} else {
+		query = query.Where("category IN ?", []string{models.AssetCategoryBGM, models.AssetCategorySFX})
+	}
+	if req.DramaID != nil {
+		query = query.Where("drama_id = ? OR drama_id IS NULL", *req.DramaID)
+	}
+	if req.Keyword != "" {
+		keyword := "%" + strings.ToLower(req.Keyword) + "%"
+		query = query.Where("LOWER(name) LIKE ? OR LOWER(description) LIKE ? OR LOWER(tags) LIKE ?", keyword, keyword, keyword)
+	}
+	for _, tag := range req.Tags {
+		query = query.Where("LOWER(tags) LIKE ?", "%"+strings.ToLower(tag)+"%")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var assets []models.Asset
+	offset := (req.Page - 1) * req.PageSize
+	if err := query.Order("created_at DESC").Offset(offset).Limit(req.PageSize).Find(&assets).Error; err != nil {
+		return nil, 0, err
+	}
+	return assets, total, nil
+}
+
+// CreateAudioLibraryItem 添加音频库素材
+func (s *AudioLibraryService) CreateAudioLibraryItem(req *CreateAudioLibraryItemRequest) (*models.Asset, error) {
+	if req.DramaID != nil {
+		var drama models.Drama
+		if err := s.db.First(&drama, *req.DramaID).Error; err != nil {
+			return nil, fmt.Errorf("drama not found")
+		}
+	}
+
+	category := req.Category
+	asset := &models.Asset{
+		DramaID:     req.DramaID,
+		Name:        req.Name,
+		Description: req.Description,
+		Type:        models.AssetTypeAudio,
+		Category:    &category,
+		URL:         req.URL,
+		LocalPath:   req.LocalPath,
+		Duration:    req.Duration,
+	}
+	if tags := audio.JoinTags(req.Tags); tags != "" {
+		asset.Tags = &tags
+	}
+	if asset.Duration == nil && req.LocalPath != nil && *req.LocalPath != "" {
+		if seconds, err := s.ffmpeg.GetVideoDuration(resolveStoragePath(s.storagePath, *req.LocalPath)); err == nil {
+			duration := int(math.Ceil(seconds))
+			durationMs := int(math.Round(seconds * 1000))
+			asset.Duration = &duration
+			asset.DurationMs = &durationMs
+		} else {
+			s.log.Warnw("Failed to probe audio duration", "error", err, "path", *req.LocalPath)
+		}
+	}
+
+	if err := s.db.Create(asset).Error; err != nil {
+		return nil, fmt.Errorf("failed to create asset: %w", err)
+	}
+	return asset, nil
+}
+
+// UpdateAudioLibraryItem 修改音频库素材的名称、分类、描述和标签
+func (s *AudioLibraryService) UpdateAudioLibraryItem(assetID uint, req *UpdateAudioLibraryItemRequest) (*models.Asset, error) {
+	asset, err := s.getLibraryAsset(assetID)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{}
+	if req.Name != nil {
+		updates["name"] = *req.Name
+	}
+	if req.Category != nil {
+		updates["category"] = *req.Category
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.Tags != nil {
+		if tags := audio.JoinTags(req.Tags); tags != "" {
+			updates["tags"] = tags
+		} else {
+			updates["tags"] = nil
+		}
+	}
+
+	if len(updates) > 0 {
+		if err := s.db.Model(asset).Updates(updates).Error; err != nil {
+			return nil, fmt.Errorf("failed to update asset: %w", err)
+		}
+	}
+	return s.getLibraryAsset(assetID)
+}
+
+// MatchEpisodeAudio 按分镜的配乐提示词和音效描述检索音频库，将得分最高的素材指定给分镜
+func (s *AudioLibraryService) MatchEpisodeAudio(episodeID uint, req *MatchEpisodeAudioRequest) (*MatchEpisodeAudioResult, error) {
+	var episode models.Episode
+	if err := s.db.First(&episode, episodeID).Error; err != nil {
+		return nil, fmt.Errorf("episode not found")
+	}
+
+	query := s.db.Where("episode_id = ?", episodeID)
+	if len(req.StoryboardIDs) > 0 {
+		query = query.Where("id IN ?", req.StoryboardIDs)
+	}
+	var storyboards []models.Storyboard
+	if err := query.Order("storyboard_number ASC").Find(&storyboards).Error; err != nil {
+		return nil, fmt.Errorf("failed to load storyboards: %w", err)
+	}
+
+	bgmCandidates, err := s.libraryCandidates(models.AssetCategoryBGM, episode.DramaID)
+	if err != nil {
+		return nil, err
+	}
+	sfxCandidates, err := s.libraryCandidates(models.AssetCategorySFX, episode.DramaID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &MatchEpisodeAudioResult{Matches: make([]StoryboardAudioMatch, 0, len(storyboards))}
+	// 相同的配乐提示词只生成一次
+	missing := make(map[string][]uint)
+	var missingPrompts []string
+	for i := range storyboards {
+		storyboard := &storyboards[i]
+		match := StoryboardAudioMatch{
+			StoryboardID:     storyboard.ID,
+			StoryboardNumber: storyboard.StoryboardNumber,
+			BgmAssetID:       storyboard.BgmAssetID,
+			SfxAssetID:       storyboard.SfxAssetID,
+		}
+		updates := map[string]interface{}{}
+
+		if prompt := audioPrompt(storyboard.BgmPrompt); prompt != "" && (storyboard.BgmAssetID == nil || req.Overwrite) {
+			if best, ok := bestAudioMatch(prompt, bgmCandidates); ok {
+				match.BgmAssetID = &best.ID
+				match.BgmScore = best.Score
+				updates["bgm_asset_id"] = best.ID
+			} else {
+				result.Unmatched++
+				if _, exists := missing[prompt]; !exists {
+					missingPrompts = append(missingPrompts, prompt)
+				}
+				missing[prompt] = append(missing[prompt], storyboard.ID)
+			}
+		}
+		if prompt := audioPrompt(storyboard.SoundEffect); prompt != "" && (storyboard.SfxAssetID == nil || req.Overwrite) {
+			if best, ok := bestAudioMatch(prompt, sfxCandidates); ok {
+				match.SfxAssetID = &best.ID
+				match.SfxScore = best.Score
+				updates["sfx_asset_id"] = best.ID
+			} else {
+				result.Unmatched++
+			}
+		}
+
+		if len(updates) > 0 {
+			if err := s.db.Model(storyboard).Updates(updates).Error; err != nil {
+				return nil, fmt.Errorf("failed to update storyboard: %w", err)
+			}
+		}
+		result.Matches = append(result.Matches, match)
+	}
+
+	if req.GenerateMissing {
+		for _, prompt := range missingPrompts {
+			task, err := s.GenerateMusic(&GenerateMusicRequest{
+				Prompt:        prompt,
+				DramaID:       &episode.DramaID,
+				Tags:          audio.Keywords(prompt),
+				StoryboardIDs: missing[prompt],
+			})
+			if err != nil {
+				return nil, err
+			}
+			result.TaskIDs = append(result.TaskIDs, task.ID)
+		}
+	}
+
+	s.log.Infow("Episode audio matched", "episode_id", episodeID, "storyboards", len(storyboards),
+		"unmatched", result.Unmatched, "generating", len(result.TaskIDs))
+	return result, nil
+}
+
+// SetStoryboardAudio 手动指定分镜的配乐和音效
+func (s *AudioLibraryService) SetStoryboardAudio(storyboardID uint, req *SetStoryboardAudioRequest) (*models.Storyboard, error) {
+	var storyboard models.Storyboard
+	if err := s.db.First(&storyboard, storyboardID).Error; err != nil {
+		return nil, fmt.Errorf("storyboard not found")
+	}
+
+	updates := map[string]interface{}{}
+	for column, assetID := range map[string]*uint{"bgm_asset_id": req.BgmAssetID, "sfx_asset_id": req.SfxAssetID} {
+		if assetID == nil {
+			continue
+		}
+		if *assetID == 0 {
+			updates[column] = nil
+			continue
+		}
+		if _, err := s.getLibraryAsset(*assetID); err != nil {
+			return nil, err
+		}
+		updates[column] = *assetID
+	}
+
+	if len(updates) > 0 {
+		if err := s.db.Model(&storyboard).Updates(updates).Error; err != nil {
+			return nil, fmt.Errorf("failed to update storyboard: %w", err)
+		}
+	}
+	if err := s.db.First(&storyboard, storyboardID).Error; err != nil {
+		return nil, err
+	}
+	return &storyboard, nil
+}
+
+// GenerateMusic 创建配乐生成任务，生成的配乐加入音频库
+func (s *AudioLibraryService) GenerateMusic(req *GenerateMusicRequest) (*models.AsyncTask, error) {
+	prompt := strings.TrimSpace(req.Prompt)
+	if prompt == "" {
+		return nil, fmt.Errorf("prompt is required")
+	}
+	var dramaID uint
+	if req.DramaID != nil {
+		var drama models.Drama
+		if err := s.db.First(&drama, *req.DramaID).Error; err != nil {
+			return nil, fmt.Errorf("drama not found")
+		}
+		dramaID = drama.ID
+	}
+
+	config, err := s.aiService.GetDefaultConfig("music")
+	if err != nil {
+		return nil, fmt.Errorf("no music AI config found: %w", err)
+	}
+	if _, err := newMusicClient(config, ""); err != nil {
+		return nil, err
+	}
+
+	// 配乐属于音频库，不关联具体资源；指定剧本时以剧本 ID 作为资源 ID
+	resourceID := ""
+	if dramaID != 0 {
+		resourceID = fmt.Sprintf("%d", dramaID)
+	}
+	task, err := s.jobQueue.Enqueue("music_generation", resourceID, JobOptions{
+		Queue:    JobQueueAudio,
+		Provider: config.Provider,
+		Priority: JobPriorityInteractive,
+		DramaID:  dramaID,
+		Payload: musicGenerationPayload{
+			Prompt:        prompt,
+			Name:          req.Name,
+			Tags:          req.Tags,
+			Duration:      req.Duration,
+			DramaID:       req.DramaID,
+			StoryboardIDs: req.StoryboardIDs,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.log.Infow("Music generation queued", "task_id", task.ID, "provider", config.Provider)
+	return task, nil
+}
+
+// handleMusicGenerationJob 任务队列处理函数
+func (s *AudioLibraryService) handleMusicGenerationJob(ctx context.Context, task *models.AsyncTask) error {
+	var payload musicGenerationPayload
+	if err := decodeJobPayload(task, &payload); err != nil {
+		return err
+	}
+
+	config, err := s.aiService.GetDefaultConfig("music")
+	if err != nil {
+		s.taskService.UpdateTaskError(task.ID, fmt.Errorf("no music AI config found: %w", err))
+		return nil
+	}
+	model := ""
+	if len(config.Model) > 0 {
+		model = config.Model[0]
+	}
+	client, err := newMusicClient(config, model)
+	if err != nil {
+		s.taskService.UpdateTaskError(task.ID, err)
+		return nil
+	}
+
+	s.taskService.UpdateTaskStatus(task.ID, "processing", 10, "正在生成配乐")
+	var opts []audio.MusicOption
+	if payload.Duration > 0 {
+		opts = append(opts, audio.WithMusicDuration(payload.Duration))
+	}
+	result, err := client.GenerateMusic(payload.Prompt, opts...)
+	if err != nil {
+		if utils.IsTransientError(err) {
+			return err
+		}
+		s.taskService.UpdateTaskError(task.ID, fmt.Errorf("music generation failed: %w", err))
+		return nil
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	s.taskService.UpdateTaskStatus(task.ID, "processing", 90, "正在保存配乐")
+	asset, err := s.saveGeneratedMusic(&payload, result)
+	if err != nil {
+		s.taskService.UpdateTaskError(task.ID, err)
+		return nil
+	}
+
+	if len(payload.StoryboardIDs) > 0 {
+		if err := s.db.Model(&models.Storyboard{}).Where("id IN ?", payload.StoryboardIDs).
+			Update("bgm_asset_id", asset.ID).Error; err != nil {
+			s.taskService.UpdateTaskError(task.ID, fmt.Errorf("failed to assign music to storyboards: %w", err))
+			return nil
+		}
+	}
+
+	s.taskService.UpdateTaskResult(task.ID, map[string]interface{}{
+		"asset_id":       asset.ID,
+		"url":            asset.URL,
+		"duration":       asset.Duration,
+		"storyboard_ids": payload.StoryboardIDs,
+	})
+	return nil
+}
+
+// saveGeneratedMusic 保存生成的配乐并创建音频库素材，提示词作为素材描述参与后续匹配
+func (s *AudioLibraryService) saveGeneratedMusic(payload *musicGenerationPayload, result *audio.MusicResult) (*models.Asset, error) {
+	format := result.Format
+	if format == "" {
+		format = "mp3"
+	}
+
+	audioDir := filepath.Join(s.storagePath, "audio", "library")
+	if err := os.MkdirAll(audioDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create audio directory: %w", err)
+	}
+	fileName := fmt.Sprintf("music_%d.%s", time.Now().UnixNano(), format)
+	filePath := filepath.Join(audioDir, fileName)
+	if err := os.WriteFile(filePath, result.Audio, 0644); err != nil {
+		return nil, fmt.Errorf("failed to save audio: %w", err)
+	}
+
+	duration := result.Duration
+	if seconds, err := s.ffmpeg.GetVideoDuration(filePath); err == nil {
+		duration = int(math.Round(seconds * 1000))
+	}
+
+	// 只保存相对路径
+	relPath := filepath.ToSlash(filepath.Join("audio", "library", fileName))
+	size := int64(len(result.Audio))
+	mimeType := audioMimeType(format)
+	category := models.AssetCategoryBGM
+	name := payload.Name
+	if name == "" {
+		name = payload.Prompt
+		if runes := []rune(name); len(runes) > 50 {
+			name = string(runes[:50])
+		}
+	}
+	asset := &models.Asset{
+		DramaID:     payload.DramaID,
+		Name:        name,
+		Description: &payload.Prompt,
+		Type:        models.AssetTypeAudio,
+		Category:    &category,
+		URL:         fmt.Sprintf("%s/%s", s.baseURL, relPath),
+		LocalPath:   &relPath,
+		FileSize:    &size,
+		MimeType:    &mimeType,
+		Format:      &format,
+	}
+	if duration > 0 {
+		durationSeconds := int(math.Ceil(float64(duration) / 1000))
+		asset.Duration = &durationSeconds
+		asset.DurationMs = &duration
+	}
+	if tags := audio.JoinTags(payload.Tags); tags != "" {
+		asset.Tags = &tags
+	}
+	if err := s.db.Create(asset).Error; err != nil {
+		os.Remove(filePath)
+		return nil, fmt.Errorf("failed to create asset: %w", err)
+	}
+	return asset, nil
+}
+
+// getLibraryAsset 获取音频库素材，只接受背景音乐和音效
+func (s *AudioLibraryService) getLibraryAsset(assetID uint) (*models.Asset, error) {
+	var asset models.Asset
+	if err := s.db.First(&asset, assetID).Error; err != nil {
+		return nil, fmt.Errorf("audio asset not found")
+	}
+	if asset.Type != models.AssetTypeAudio || asset.Category == nil ||
+		(*asset.Category != models.AssetCategoryBGM && *asset.Category != models.AssetCategorySFX) {
+		return nil, fmt.Errorf("asset %d is not a music or sound effect asset", assetID)
+	}
+	return &asset, nil
+}
+
+// libraryCandidates 剧本可用的音频库素材：本剧本的素材和公共素材
+func (s *AudioLibraryService) libraryCandidates(category string, dramaID uint) ([]audio.Candidate, error) {
+	var assets []models.Asset
+	if err := s.db.Where("type = ? AND category = ? AND (drama_id = ? OR drama_id IS NULL)",
+		models.AssetTypeAudio, category, dramaID).
+		Order("created_at DESC").
+		Find(&assets).Error; err != nil {
+		return nil, fmt.Errorf("failed to load audio library: %w", err)
+	}
+
+	candidates := make([]audio.Candidate, 0, len(assets))
+	for _, asset := range assets {
+		candidate := audio.Candidate{ID: asset.ID, Name: asset.Name}
+		if asset.Description != nil {
+			candidate.Description = *asset.Description
+		}
+		if asset.Tags != nil {
+			candidate.Tags = audio.ParseTags(*asset.Tags)
+		}
+		candidates = append(candidates, candidate)
+	}
+	return candidates, nil
+}
+
+// bestAudioMatch 返回得分最高且达到阈值的素材
+func bestAudioMatch(prompt string, candidates []audio.Candidate) (audio.Match, bool) {
+	matches := audio.Rank(prompt, candidates)
+	if len(matches) == 0 || matches[0].Score < audioMatchThreshold {
+		return audio.Match{}, false
+	}
+	return matches[0], true
+}
+
+// audioPrompt 整理分镜的配乐提示词或音效描述，"无" 等占位内容返回空字符串
+func audioPrompt(text *string) string {
+	if text == nil {
+		return ""
+	}
+	prompt := strings.TrimSpace(*text)
+	switch prompt {
+	case "无", "（无）", "(无)", "none", "None":
+		return ""
+	}
+	return prompt
+}
+
+// newMusicClient 根据配置中的 provider 创建配乐生成客户端
+func newMusicClient(config *models.AIServiceConfig, model string) (audio.MusicClient, error) {
+	switch config.Provider {
+	case "minimax":
+		return audio.NewMinimaxMusicClient(config.BaseURL, config.APIKey, model), nil
+	default:
+		return nil, fmt.Errorf("unsupported music provider: %s", config.Provider)
+	}
+}
@@ -0,0 +1,203 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+
+	models "github.com/drama-generator/backend/domain/models"
+	"github.com/drama-generator/backend/infrastructure/external/ffmpeg"
+	"github.com/drama-generator/backend/pkg/config"
+)
+
+// 片段增益的上下限（dB）：过大的提升会放大底噪，剩余的差异交给成片的 loudnorm 处理
+const (
+	maxClipGainBoost = 12.0
+	maxClipGainCut   = 20.0
+)
+
+// MasteringSettings 成片响度标准化设置
+type MasteringSettings struct {
+	Enabled    bool
+	Profile    string                           // 默认输出档位
+	Profiles   map[string]ffmpeg.LoudnessTarget // 可用档位，包含内置档位和配置文件中的自定义档位
+	Denoise    bool
+	NoiseFloor float64
+}
+
+// NewMasteringSettings 根据配置文件生成响度标准化设置，自定义档位未填写的真峰值和响度范围使用 -1 dBTP 和 11 LU
+func NewMasteringSettings(cfg config.MasteringConfig) (MasteringSettings, error) {
+	profiles := make(map[string]ffmpeg.LoudnessTarget, len(ffmpeg.LoudnessProfiles)+len(cfg.Profiles))
+	for name, target := range ffmpeg.LoudnessProfiles {
+		profiles[name] = target
+	}
+	for name, profile := range cfg.Profiles {
+		target := ffmpeg.LoudnessTarget{Integrated: profile.TargetLUFS, TruePeak: profile.TruePeak, Range: profile.LRA}
+		if target.TruePeak == 0 {
+			target.TruePeak = -1
+		}
+		if target.Range == 0 {
+			target.Range = 11
+		}
+		if err := target.Validate(); err != nil {
+			return MasteringSettings{}, fmt.Errorf("invalid loudness profile %q: %w", name, err)
+		}
+		profiles[strings.ToLower(name)] = target
+	}
+
+	settings := MasteringSettings{
+		Enabled:    !cfg.Disabled,
+		Profile:    ffmpeg.LoudnessProfileStreaming,
+		Profiles:   profiles,
+		Denoise:    cfg.Denoise,
+		NoiseFloor: cfg.NoiseFloor,
+	}
+	if cfg.Profile != "" {
+		settings.Profile = strings.ToLower(cfg.Profile)
+		if _, ok := profiles[settings.Profile]; !ok {
+			return MasteringSettings{}, fmt.Errorf("unknown loudness profile %q", cfg.Profile)
+		}
+	}
+	return settings, nil
+}
+
+// ProfileNames 返回可用的输出档位名称
+func (m MasteringSettings) ProfileNames() []string {
+	names := make([]string, 0, len(m.Profiles))
+	for name := range m.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// resolveProfile 返回档位名称对应的响度目标，名称为空时使用默认档位
+func (m MasteringSettings) resolveProfile(name string) (string, ffmpeg.LoudnessTarget, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		name = m.Profile
+	}
+	target, ok := m.Profiles[name]
+	if !ok {
+		return "", ffmpeg.LoudnessTarget{}, fmt.Errorf("unknown loudness profile %q, available: %s",
+			name, strings.Join(m.ProfileNames(), ", "))
+	}
+	return name, target, nil
+}
+
+// mergeProfile 创建合成记录时确定输出档位：请求指定的档位总是生效，
+// 未指定时使用默认档位，关闭响度标准化时返回 nil
+func (m MasteringSettings) mergeProfile(profile string, denoise *bool) (*string, bool, error) {
+	useDenoise := m.Denoise
+	if denoise != nil {
+		useDenoise = *denoise
+	}
+	if profile == "" && !m.Enabled {
+		return nil, false, nil
+	}
+
+	name, _, err := m.resolveProfile(profile)
+	if err != nil {
+		return nil, false, err
+	}
+	return &name, useDenoise, nil
+}
+
+// applyClipGains 按成片目标响度为每个片段计算增益，先把各厂商片段拉到相近的响度再合成，
+// 避免转场时音量突变；成片的 loudnorm 再做最终的标准化
+func (s *VideoMergeService) applyClipGains(ctx context.Context, scenes []models.SceneClip, clips []ffmpeg.VideoClip, target ffmpeg.LoudnessTarget) {
+	for i := range scenes {
+		measurement := s.clipLoudness(ctx, &scenes[i])
+		if measurement == nil || measurement.Silent() {
+			continue
+		}
+		clips[i].Gain = clipGain(measurement, target)
+	}
+}
+
+// clipGain 片段增益：目标响度与片段响度之差，提升时不让真峰值超过 0 dBTP
+func clipGain(measurement *ffmpeg.LoudnessMeasurement, target ffmpeg.LoudnessTarget) float64 {
+	gain := target.Integrated - measurement.Integrated
+	if headroom := -measurement.TruePeak; gain > 0 && gain > headroom {
+		gain = math.Max(headroom, 0)
+	}
+	gain = math.Max(-maxClipGainCut, math.Min(maxClipGainBoost, gain))
+	return math.Round(gain*10) / 10
+}
+
+// clipLoudness 返回片段在裁剪范围内的响度；素材上缓存的是整段素材的测量值，
+// 只有片段使用整段素材时才读取和写回缓存，裁剪过的片段单独测量裁剪范围
+func (s *VideoMergeService) clipLoudness(ctx context.Context, scene *models.SceneClip) *ffmpeg.LoudnessMeasurement {
+	var asset *models.Asset
+	if scene.AssetID != nil {
+		var found models.Asset
+		if err := s.db.First(&found, *scene.AssetID).Error; err == nil && coversWholeAsset(scene, &found) {
+			asset = &found
+			if asset.Loudness != nil && asset.TruePeak != nil {
+				measurement := &ffmpeg.LoudnessMeasurement{Integrated: *asset.Loudness, TruePeak: *asset.TruePeak}
+				if asset.LoudnessRange != nil {
+					measurement.Range = *asset.LoudnessRange
+				}
+				return measurement
+			}
+		}
+	}
+
+	measurement, err := s.ffmpeg.MeasureLoudness(ctx, scene.VideoURL, scene.StartTime, scene.EndTime)
+	if err != nil {
+		s.log.Warnw("Failed to measure clip loudness", "scene_id", scene.SceneID, "video_url", scene.VideoURL,
+			"start", scene.StartTime, "end", scene.EndTime, "error", err)
+		return nil
+	}
+
+	if asset != nil {
+		if err := s.db.Model(asset).Updates(map[string]interface{}{
+			"loudness":       measurement.Integrated,
+			"true_peak":      measurement.TruePeak,
+			"loudness_range": measurement.Range,
+		}).Error; err != nil {
+			s.log.Warnw("Failed to save clip loudness", "asset_id", asset.ID, "error", err)
+		}
+	}
+	return measurement
+}
+
+// coversWholeAsset 片段是否使用整段素材：未裁剪，或裁剪范围从 0 开始且覆盖素材的全部时长
+func coversWholeAsset(scene *models.SceneClip, asset *models.Asset) bool {
+	if scene.EndTime <= scene.StartTime {
+		return true
+	}
+	if scene.StartTime > 0 {
+		return false
+	}
+	duration := assetDurationMs(asset)
+	return duration > 0 && scene.EndTime*1000 >= float64(duration)
+}
+
+// masterMergedVideo 对合成后的视频做两遍 loudnorm 标准化并保存响度测量
+// 标准化失败时保留未处理的合成结果，不影响合成任务完成
+func (s *VideoMergeService) masterMergedVideo(ctx context.Context, videoMerge *models.VideoMerge, target ffmpeg.LoudnessTarget, inputPath, outputPath string) error {
+	result, err := s.ffmpeg.MasterAudio(ctx, &ffmpeg.MasteringOptions{
+		InputPath:  inputPath,
+		OutputPath: outputPath,
+		Target:     target,
+		Denoise:    videoMerge.Denoise,
+		NoiseFloor: s.settings.Mastering.NoiseFloor,
+	})
+	if err != nil {
+		s.log.Warnw("Audio mastering failed, keeping unmastered video", "merge_id", videoMerge.ID, "error", err)
+		return os.Rename(inputPath, outputPath)
+	}
+	os.Remove(inputPath)
+
+	if result != nil {
+		if data, err := json.Marshal(result); err == nil {
+			s.db.Model(&models.VideoMerge{}).Where("id = ?", videoMerge.ID).Update("loudness", data)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,335 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	models "github.com/drama-generator/backend/domain/models"
+	"github.com/drama-generator/backend/infrastructure/external/ffmpeg"
+	"github.com/drama-generator/backend/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// defaultTitleCardTemplate 标题卡的默认模板，{drama}、{episode}、{title} 分别替换为剧名、集数和章节标题
+const defaultTitleCardTemplate = "第{episode}集 – {title}"
+
+// maxTitleCardSeconds 标题卡时长上限（秒）
+const maxTitleCardSeconds = 15
+
+// brandingColorPattern 标题卡颜色：FFmpeg 颜色名称或 #RRGGBB，可带 @透明度
+var brandingColorPattern = regexp.MustCompile(`^(#[0-9A-Fa-f]{6}|[A-Za-z]+)(@[0-9.]+)?$`)
+
+// BrandingSettings 剧本的品牌包装：正片叠加水印，前后依次拼接片头、标题卡和片尾，素材均为素材库中的素材
+type BrandingSettings struct {
+	Watermark    *WatermarkSettings `json:"watermark,omitempty"`
+	IntroAssetID *uint              `json:"intro_asset_id,omitempty"`
+	OutroAssetID *uint              `json:"outro_asset_id,omitempty"`
+	TitleCard    *TitleCardSettings `json:"title_card,omitempty"`
+}
+
+// WatermarkSettings 水印图片素材及其位置和透明度
+type WatermarkSettings struct {
+	AssetID  uint    `json:"asset_id"`
+	Position string  `json:"position,omitempty"` // top_left、top_right（默认）、bottom_left、bottom_right、center
+	Opacity  float64 `json:"opacity,omitempty"`  // 0-1，为空时使用 0.8
+	Scale    float64 `json:"scale,omitempty"`    // 水印宽度占画面宽度的比例，为空时使用 0.12
+}
+
+// TitleCardSettings 标题卡模板，背景为图片或视频素材（可以是上传的或从生成结果导入的），为空时使用纯色背景
+type TitleCardSettings struct {
+	Template          string  `json:"template,omitempty"`
+	Duration          float64 `json:"duration,omitempty"`
+	BackgroundAssetID *uint   `json:"background_asset_id,omitempty"`
+	Color             string  `json:"color,omitempty"`
+	Font              string  `json:"font,omitempty"`
+	FontColor         string  `json:"font_color,omitempty"`
+	FontSize          int     `json:"font_size,omitempty"`
+}
+
+// Empty 没有任何品牌包装
+func (b *BrandingSettings) Empty() bool {
+	return b == nil || (b.Watermark == nil && b.IntroAssetID == nil && b.OutroAssetID == nil && b.TitleCard == nil)
+}
+
+// Normalize 校验设置并补全默认值，不检查素材是否存在
+func (b BrandingSettings) Normalize() (BrandingSettings, error) {
+	if wm := b.Watermark; wm != nil {
+		normalized := *wm
+		if normalized.AssetID == 0 {
+			return b, fmt.Errorf("watermark asset_id is required")
+		}
+		switch normalized.Position {
+		case "":
+			normalized.Position = ffmpeg.WatermarkTopRight
+		case ffmpeg.WatermarkTopLeft, ffmpeg.WatermarkTopRight, ffmpeg.WatermarkBottomLeft, ffmpeg.WatermarkBottomRight, ffmpeg.WatermarkCenter:
+		default:
+			return b, fmt.Errorf("unsupported watermark position %q", normalized.Position)
+		}
+		if normalized.Opacity < 0 || normalized.Opacity > 1 {
+			return b, fmt.Errorf("watermark opacity must be between 0 and 1")
+		}
+		if normalized.Scale < 0 || normalized.Scale > 1 {
+			return b, fmt.Errorf("watermark scale must be between 0 and 1")
+		}
+		b.Watermark = &normalized
+	}
+
+	if card := b.TitleCard; card != nil {
+		normalized := *card
+		if strings.TrimSpace(normalized.Template) == "" {
+			normalized.Template = defaultTitleCardTemplate
+		}
+		if normalized.Duration < 0 || normalized.Duration > maxTitleCardSeconds {
+			return b, fmt.Errorf("title card duration must be between 0 and %d seconds", maxTitleCardSeconds)
+		}
+		if normalized.FontSize < 0 {
+			return b, fmt.Errorf("title card font_size must be positive")
+		}
+		for _, color := range []string{normalized.Color, normalized.FontColor} {
+			if color != "" && !brandingColorPattern.MatchString(color) {
+				return b, fmt.Errorf("invalid title card color %q", color)
+			}
+		}
+		b.TitleCard = &normalized
+	}
+	return b, nil
+}
+
+// TitleText 按模板生成章节的标题卡文字，章节没有标题时去掉模板末尾的分隔符
+func (c *TitleCardSettings) TitleText(dramaTitle string, episodeNum int, episodeTitle string) string {
+	text := strings.NewReplacer(
+		"{drama}", dramaTitle,
+		"{episode}", strconv.Itoa(episodeNum),
+		"{title}", episodeTitle,
+	).Replace(c.Template)
+	return strings.TrimSpace(strings.TrimRight(text, " -–—:："))
+}
+
+// mergeBranding 合成记录中保存的品牌包装，创建合成任务时按剧本设置解析，合成后记录各段时长
+type mergeBranding struct {
+	Intro     string                 `json:"intro,omitempty"`
+	Outro     string                 `json:"outro,omitempty"`
+	TitleCard *ffmpeg.TitleCard      `json:"title_card,omitempty"`
+	Watermark *ffmpeg.Watermark      `json:"watermark,omitempty"`
+	Result    *ffmpeg.BrandingResult `json:"result,omitempty"`
+}
+
+type BrandingService struct {
+	db  *gorm.DB
+	log *logger.Logger
+}
+
+func NewBrandingService(db *gorm.DB, log *logger.Logger) *BrandingService {
+	return &BrandingService{
+		db:  db,
+		log: log,
+	}
+}
+
+// GetDramaBranding 获取剧本的品牌包装设置
+func (s *BrandingService) GetDramaBranding(dramaID uint) (*BrandingSettings, error) {
+	var drama models.Drama
+	if err := s.db.Select("id", "branding").First(&drama, dramaID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("drama not found")
+		}
+		return nil, err
+	}
+
+	settings := dramaBranding(s.log, &drama)
+	return &settings, nil
+}
+
+// UpdateDramaBranding 保存剧本的品牌包装设置，素材需属于该剧本或为公共素材
+func (s *BrandingService) UpdateDramaBranding(dramaID uint, settings BrandingSettings) (*BrandingSettings, error) {
+	var drama models.Drama
+	if err := s.db.Select("id").First(&drama, dramaID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("drama not found")
+		}
+		return nil, err
+	}
+
+	normalized, err := settings.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validateAssets(dramaID, &normalized); err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(normalized)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.Model(&drama).Update("branding", data).Error; err != nil {
+		return nil, fmt.Errorf("failed to save branding: %w", err)
+	}
+
+	s.log.Infow("Branding updated", "drama_id", dramaID,
+		"watermark", normalized.Watermark != nil,
+		"intro", normalized.IntroAssetID != nil,
+		"outro", normalized.OutroAssetID != nil,
+		"title_card", normalized.TitleCard != nil)
+	return &normalized, nil
+}
+
+func (s *BrandingService) validateAssets(dramaID uint, settings *BrandingSettings) error {
+	check := func(assetID uint, name string, types ...models.AssetType) error {
+		var asset models.Asset
+		if err := s.db.Select("id", "drama_id", "type").First(&asset, assetID).Error; err != nil {
+			return fmt.Errorf("%s asset %d not found", name, assetID)
+		}
+		if asset.DramaID != nil && *asset.DramaID != dramaID {
+			return fmt.Errorf("%s asset %d belongs to another drama", name, assetID)
+		}
+		for _, t := range types {
+			if asset.Type == t {
+				return nil
+			}
+		}
+		return fmt.Errorf("%s asset %d must be of type %v", name, assetID, types)
+	}
+
+	if settings.Watermark != nil {
+		if err := check(settings.Watermark.AssetID, "watermark", models.AssetTypeImage); err != nil {
+			return err
+		}
+	}
+	if settings.IntroAssetID != nil {
+		if err := check(*settings.IntroAssetID, "intro", models.AssetTypeVideo); err != nil {
+			return err
+		}
+	}
+	if settings.OutroAssetID != nil {
+		if err := check(*settings.OutroAssetID, "outro", models.AssetTypeVideo); err != nil {
+			return err
+		}
+	}
+	if settings.TitleCard != nil && settings.TitleCard.BackgroundAssetID != nil {
+		if err := check(*settings.TitleCard.BackgroundAssetID, "title card background", models.AssetTypeImage, models.AssetTypeVideo); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dramaBranding 解析剧本的品牌包装设置，未设置或无效时返回空设置
+func dramaBranding(log *logger.Logger, drama *models.Drama) BrandingSettings {
+	if len(drama.Branding) == 0 {
+		return BrandingSettings{}
+	}
+	var settings BrandingSettings
+	if err := json.Unmarshal(drama.Branding, &settings); err != nil {
+		log.Warnw("Failed to parse branding", "error", err, "drama_id", drama.ID)
+		return BrandingSettings{}
+	}
+	normalized, err := settings.Normalize()
+	if err != nil {
+		log.Warnw("Invalid branding", "error", err, "drama_id", drama.ID)
+		return BrandingSettings{}
+	}
+	return normalized
+}
+
+// resolveMergeBranding 将剧本的品牌包装解析为合成使用的素材路径和标题卡文字，没有设置时返回 nil
+func resolveMergeBranding(db *gorm.DB, log *logger.Logger, storagePath string, episode *models.Episode) (*mergeBranding, error) {
+	settings := dramaBranding(log, &episode.Drama)
+	if settings.Empty() {
+		return nil, nil
+	}
+
+	source := func(assetID uint, name string) (*models.Asset, string, error) {
+		var asset models.Asset
+		if err := db.First(&asset, assetID).Error; err != nil {
+			return nil, "", fmt.Errorf("branding %s asset %d not found", name, assetID)
+		}
+		if asset.LocalPath != nil && *asset.LocalPath != "" {
+			return &asset, resolveStoragePath(storagePath, *asset.LocalPath), nil
+		}
+		if asset.URL == "" {
+			return nil, "", fmt.Errorf("branding %s asset %d has no file", name, assetID)
+		}
+		return &asset, asset.URL, nil
+	}
+
+	branding := &mergeBranding{}
+	if wm := settings.Watermark; wm != nil {
+		_, path, err := source(wm.AssetID, "watermark")
+		if err != nil {
+			return nil, err
+		}
+		branding.Watermark = &ffmpeg.Watermark{Source: path, Position: wm.Position, Opacity: wm.Opacity, Scale: wm.Scale}
+	}
+	if settings.IntroAssetID != nil {
+		_, path, err := source(*settings.IntroAssetID, "intro")
+		if err != nil {
+			return nil, err
+		}
+		branding.Intro = path
+	}
+	if settings.OutroAssetID != nil {
+		_, path, err := source(*settings.OutroAssetID, "outro")
+		if err != nil {
+			return nil, err
+		}
+		branding.Outro = path
+	}
+	if card := settings.TitleCard; card != nil {
+		titleCard := &ffmpeg.TitleCard{
+			Text:      card.TitleText(episode.Drama.Title, episode.EpisodeNum, episode.Title),
+			Duration:  card.Duration,
+			Color:     card.Color,
+			Font:      card.Font,
+			FontColor: card.FontColor,
+			FontSize:  card.FontSize,
+		}
+		if card.BackgroundAssetID != nil {
+			asset, path, err := source(*card.BackgroundAssetID, "title card background")
+			if err != nil {
+				return nil, err
+			}
+			titleCard.Background = path
+			titleCard.IsImage = asset.Type == models.AssetTypeImage
+		}
+		branding.TitleCard = titleCard
+	}
+	return branding, nil
+}
+
+// mergeLeadIn 合成成片中正片之前片头和标题卡的时长（毫秒）
+func mergeLeadIn(merge *models.VideoMerge) int {
+	if len(merge.Branding) == 0 {
+		return 0
+	}
+	var branding mergeBranding
+	if err := json.Unmarshal(merge.Branding, &branding); err != nil || branding.Result == nil {
+		return 0
+	}
+	return int(branding.Result.LeadIn() * 1000)
+}
+
+// applyBranding 为合成结果加上品牌包装，并在合成记录中保存各段时长
+func (s *VideoMergeService) applyBranding(ctx context.Context, videoMerge *models.VideoMerge, branding *mergeBranding, inputPath, outputPath string) (*ffmpeg.BrandingResult, error) {
+	result, err := s.ffmpeg.ApplyBranding(ctx, &ffmpeg.BrandingOptions{
+		InputPath:  inputPath,
+		OutputPath: outputPath,
+		Intro:      branding.Intro,
+		Outro:      branding.Outro,
+		TitleCard:  branding.TitleCard,
+		Watermark:  branding.Watermark,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	branding.Result = result
+	if data, err := json.Marshal(branding); err == nil {
+		s.db.Model(&models.VideoMerge{}).Where("id = ?", videoMerge.ID).Update("branding", data)
+	}
+	return result, nil
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"time"
@@ -19,18 +20,24 @@ type CharacterLibraryService struct {
 	config      *config.Config
 	aiService   *AIService
 	taskService *TaskService
+	jobQueue    *JobQueue
 	promptI18n  *PromptI18n
 }
 
-func NewCharacterLibraryService(db *gorm.DB, log *logger.Logger, cfg *config.Config) *CharacterLibraryService {
-	return &CharacterLibraryService{
+func NewCharacterLibraryService(db *gorm.DB, jobQueue *JobQueue, taskService *TaskService, aiService *AIService, log *logger.Logger, cfg *config.Config) *CharacterLibraryService {
+	service := &CharacterLibraryService{
 		db:          db,
 		log:         log,
 		config:      cfg,
-		aiService:   NewAIService(db, log),
-		taskService: NewTaskService(db, log),
+		aiService:   aiService,
+		taskService: taskService,
+		jobQueue:    jobQueue,
 		promptI18n:  NewPromptI18n(cfg),
 	}
+
+	service.jobQueue.RegisterHandler("character_extraction", service.handleCharacterExtractionJob)
+
+	return service
 }
 
 type CreateLibraryItemRequest struct {
@@ -501,6 +508,11 @@ func (s *CharacterLibraryService) BatchGenerateCharacterImages(characterIDs []st
 
 // ExtractCharactersFromScript 从分集剧本中提取角色
 func (s *CharacterLibraryService) ExtractCharactersFromScript(episodeID uint) (string, error) {
+	return s.ExtractCharactersFromScriptWithModel(episodeID, "")
+}
+
+// ExtractCharactersFromScriptWithModel 使用指定文本模型从分集剧本中提取角色，model 为空时使用默认配置
+func (s *CharacterLibraryService) ExtractCharactersFromScriptWithModel(episodeID uint, model string) (string, error) {
 	var episode models.Episode
 	if err := s.db.First(&episode, episodeID).Error; err != nil {
 		return "", fmt.Errorf("episode not found")
@@ -510,17 +522,43 @@ func (s *CharacterLibraryService) ExtractCharactersFromScript(episodeID uint) (s
 		return "", fmt.Errorf("剧本内容为空")
 	}
 
-	task, err := s.taskService.CreateTask("character_extraction", fmt.Sprintf("%d", episode.DramaID))
+	task, err := s.jobQueue.Enqueue("character_extraction", fmt.Sprintf("%d", episode.DramaID), JobOptions{
+		Queue:    JobQueueText,
+		Provider: s.aiService.ResolveProvider("text", model),
+		Priority: JobPriorityInteractive,
+		DramaID:  episode.DramaID,
+		Payload:  characterExtractionPayload{EpisodeID: episode.ID, Model: model},
+	})
 	if err != nil {
 		return "", fmt.Errorf("创建任务失败: %w", err)
 	}
 
-	go s.processCharacterExtraction(task.ID, episode)
-
 	return task.ID, nil
 }
 
-func (s *CharacterLibraryService) processCharacterExtraction(taskID string, episode models.Episode) {
+type characterExtractionPayload struct {
+	EpisodeID uint   `json:"episode_id"`
+	Model     string `json:"model,omitempty"`
+}
+
+// handleCharacterExtractionJob 任务队列处理函数
+func (s *CharacterLibraryService) handleCharacterExtractionJob(ctx context.Context, task *models.AsyncTask) error {
+	var payload characterExtractionPayload
+	if err := decodeJobPayload(task, &payload); err != nil {
+		return err
+	}
+
+	var episode models.Episode
+	if err := s.db.First(&episode, payload.EpisodeID).Error; err != nil {
+		s.taskService.UpdateTaskError(task.ID, fmt.Errorf("episode not found"))
+		return nil
+	}
+
+	s.processCharacterExtraction(ctx, task.ID, episode, payload.Model)
+	return ctx.Err()
+}
+
+func (s *CharacterLibraryService) processCharacterExtraction(ctx context.Context, taskID string, episode models.Episode, model string) {
 	s.taskService.UpdateTaskStatus(taskID, "processing", 0, "正在分析剧本...")
 
 	script := ""
@@ -537,7 +575,11 @@ func (s *CharacterLibraryService) processCharacterExtraction(taskID string, epis
 	prompt := s.promptI18n.GetCharacterExtractionPrompt(drama.Style)
 	userPrompt := fmt.Sprintf("【剧本内容】\n%s", script)
 
-	response, err := s.aiService.GenerateText(userPrompt, prompt, ai.WithMaxTokens(3000))
+	response, err := s.aiService.GenerateTextWithContext(ctx, model, userPrompt, prompt, ai.WithMaxTokens(3000))
+	// 任务被暂停、取消或队列停止，不写入失败状态
+	if ctx.Err() != nil {
+		return
+	}
 	if err != nil {
 		s.taskService.UpdateTaskError(taskID, err)
 		return
@@ -0,0 +1,442 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	models "github.com/drama-generator/backend/domain/models"
+	"github.com/drama-generator/backend/infrastructure/external/ffmpeg"
+	"github.com/drama-generator/backend/pkg/colormatch"
+	"github.com/drama-generator/backend/pkg/logger"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 剧本 LUT 文件在存储目录下的子目录和大小上限
+const (
+	colorLUTDir     = "luts"
+	maxColorLUTSize = 10 << 20
+)
+
+// 配色匹配生成的片段特效：名称，以及配置中标记自动生成的字段
+// 编辑者修改配置后移除该标记，之后合成不再覆盖该特效
+const (
+	autoColorEffectName = "自动配色"
+	colorEffectAutoKey  = "auto"
+)
+
+// 片段配色的来源
+const (
+	colorSourceAuto     = "auto"     // 按统计自动校正
+	colorSourceEdited   = "edited"   // 使用编辑者调整的 color 特效
+	colorSourceDisabled = "disabled" // 编辑者禁用了 color 特效，不做校正
+)
+
+// ColorGradingSettings 剧本的配色设置：AutoMatch 为合成成片时默认进行镜头配色匹配，
+// LUT 为存储目录下的 .cube 或 .3dl 文件，配色匹配后统一套用
+type ColorGradingSettings struct {
+	AutoMatch bool   `json:"auto_match"`
+	LUT       string `json:"lut,omitempty"`
+}
+
+// Normalize 校验 LUT 路径，不检查文件是否存在
+func (c ColorGradingSettings) Normalize() (ColorGradingSettings, error) {
+	if c.LUT != "" {
+		lut, err := cleanLUTPath(c.LUT)
+		if err != nil {
+			return c, err
+		}
+		c.LUT = lut
+	}
+	return c, nil
+}
+
+// cleanLUTPath 只允许存储目录 luts 下的 .cube 和 .3dl 文件，返回规范化的相对路径
+func cleanLUTPath(path string) (string, error) {
+	cleaned := filepath.ToSlash(filepath.Clean(path))
+	if filepath.IsAbs(path) || !strings.HasPrefix(cleaned, colorLUTDir+"/") {
+		return "", fmt.Errorf("lut must be a file under %s/", colorLUTDir)
+	}
+	switch strings.ToLower(filepath.Ext(cleaned)) {
+	case ".cube", ".3dl":
+		return cleaned, nil
+	default:
+		return "", fmt.Errorf("lut must be a .cube or .3dl file")
+	}
+}
+
+// ColorMatchSelection 合成时的配色匹配：ColorMatch 为空时使用剧本设置；
+// ColorReference 为参考镜头的分镜 ID，为空时匹配到各镜头统计的中位数
+type ColorMatchSelection struct {
+	ColorMatch     *bool `json:"color_match"`
+	ColorReference uint  `json:"color_reference_storyboard_id"`
+}
+
+// mergeColorMatch 合成记录中保存的配色匹配，创建时解析参考镜头和 LUT，合成时记录匹配目标和各片段的校正
+type mergeColorMatch struct {
+	ReferenceSceneID uint              `json:"reference_scene_id,omitempty"`
+	LUT              string            `json:"lut,omitempty"`
+	Target           *colormatch.Stats `json:"target,omitempty"`
+	Clips            []mergeColorClip  `json:"clips,omitempty"`
+}
+
+// mergeColorClip 片段的配色统计和实际使用的校正
+type mergeColorClip struct {
+	SceneID    uint                   `json:"scene_id"`
+	Source     string                 `json:"source"`
+	Stats      *colormatch.Stats      `json:"stats,omitempty"`
+	Correction *colormatch.Correction `json:"correction,omitempty"`
+}
+
+type ColorGradingService struct {
+	db          *gorm.DB
+	storagePath string
+	log         *logger.Logger
+}
+
+func NewColorGradingService(db *gorm.DB, storagePath string, log *logger.Logger) *ColorGradingService {
+	return &ColorGradingService{
+		db:          db,
+		storagePath: storagePath,
+		log:         log,
+	}
+}
+
+// GetDramaColorGrading 获取剧本的配色设置
+func (s *ColorGradingService) GetDramaColorGrading(dramaID uint) (*ColorGradingSettings, error) {
+	var drama models.Drama
+	if err := s.db.Select("id", "color_grading").First(&drama, dramaID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("drama not found")
+		}
+		return nil, err
+	}
+
+	settings := dramaColorGrading(s.log, &drama)
+	return &settings, nil
+}
+
+// UpdateDramaColorGrading 保存剧本的配色设置，LUT 需为已上传的文件
+func (s *ColorGradingService) UpdateDramaColorGrading(dramaID uint, settings ColorGradingSettings) (*ColorGradingSettings, error) {
+	var drama models.Drama
+	if err := s.db.Select("id").First(&drama, dramaID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("drama not found")
+		}
+		return nil, err
+	}
+
+	normalized, err := settings.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	if normalized.LUT != "" {
+		if _, err := os.Stat(filepath.Join(s.storagePath, normalized.LUT)); err != nil {
+			return nil, fmt.Errorf("lut %s not found", normalized.LUT)
+		}
+	}
+	if err := s.save(&drama, normalized); err != nil {
+		return nil, err
+	}
+
+	s.log.Infow("Color grading updated", "drama_id", dramaID, "auto_match", normalized.AutoMatch, "lut", normalized.LUT)
+	return &normalized, nil
+}
+
+// UploadDramaLUT 保存上传的 .cube 或 .3dl 文件并设为剧本的 LUT，其他配色设置保持不变
+func (s *ColorGradingService) UploadDramaLUT(dramaID uint, filename string, file io.Reader) (*ColorGradingSettings, error) {
+	var drama models.Drama
+	if err := s.db.Select("id", "color_grading").First(&drama, dramaID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("drama not found")
+		}
+		return nil, err
+	}
+
+	ext := strings.ToLower(filepath.Ext(filename))
+	if ext != ".cube" && ext != ".3dl" {
+		return nil, fmt.Errorf("lut must be a .cube or .3dl file")
+	}
+	data, err := io.ReadAll(io.LimitReader(file, maxColorLUTSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lut: %w", err)
+	}
+	if len(data) > maxColorLUTSize {
+		return nil, fmt.Errorf("lut file exceeds %d MB", maxColorLUTSize>>20)
+	}
+	// lut3d 只支持三维 LUT，一维 .cube 文件会在合成时失败
+	if ext == ".cube" && !bytes.Contains(data, []byte("LUT_3D_SIZE")) {
+		return nil, fmt.Errorf("cube file has no LUT_3D_SIZE, only 3D LUTs are supported")
+	}
+
+	relPath := fmt.Sprintf("%s/drama_%d_%d%s", colorLUTDir, dramaID, time.Now().UnixNano(), ext)
+	fullPath := filepath.Join(s.storagePath, relPath)
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create lut directory: %w", err)
+	}
+	if err := os.WriteFile(fullPath, data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to save lut: %w", err)
+	}
+
+	settings := dramaColorGrading(s.log, &drama)
+	settings.LUT = relPath
+	if err := s.save(&drama, settings); err != nil {
+		os.Remove(fullPath)
+		return nil, err
+	}
+
+	s.log.Infow("Color grading LUT uploaded", "drama_id", dramaID, "lut", relPath, "size", len(data))
+	return &settings, nil
+}
+
+func (s *ColorGradingService) save(drama *models.Drama, settings ColorGradingSettings) error {
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	if err := s.db.Model(drama).Update("color_grading", data).Error; err != nil {
+		return fmt.Errorf("failed to save color grading: %w", err)
+	}
+	return nil
+}
+
+// dramaColorGrading 解析剧本的配色设置，未设置或无效时返回空设置
+func dramaColorGrading(log *logger.Logger, drama *models.Drama) ColorGradingSettings {
+	if len(drama.ColorGrading) == 0 {
+		return ColorGradingSettings{}
+	}
+	var settings ColorGradingSettings
+	if err := json.Unmarshal(drama.ColorGrading, &settings); err != nil {
+		log.Warnw("Failed to parse color grading", "error", err, "drama_id", drama.ID)
+		return ColorGradingSettings{}
+	}
+	normalized, err := settings.Normalize()
+	if err != nil {
+		log.Warnw("Invalid color grading", "error", err, "drama_id", drama.ID)
+		return ColorGradingSettings{}
+	}
+	return normalized
+}
+
+// resolveMergeColorMatch 按请求和剧本设置决定是否进行配色匹配，不匹配时返回 nil
+func resolveMergeColorMatch(log *logger.Logger, episode *models.Episode, scenes []models.SceneClip, selection ColorMatchSelection) (*mergeColorMatch, error) {
+	settings := dramaColorGrading(log, &episode.Drama)
+	enabled := settings.AutoMatch
+	if selection.ColorMatch != nil {
+		enabled = *selection.ColorMatch
+	}
+	if !enabled {
+		if selection.ColorReference != 0 {
+			return nil, fmt.Errorf("color_reference_storyboard_id requires color matching")
+		}
+		return nil, nil
+	}
+
+	if selection.ColorReference != 0 {
+		found := false
+		for _, scene := range scenes {
+			found = found || scene.SceneID == selection.ColorReference
+		}
+		if !found {
+			return nil, fmt.Errorf("reference storyboard %d is not in the merge", selection.ColorReference)
+		}
+	}
+	return &mergeColorMatch{ReferenceSceneID: selection.ColorReference, LUT: settings.LUT}, nil
+}
+
+// colorEffectConfig 转换 color 特效配置供 FFmpeg 使用：lut 转换为存储目录下的完整路径，
+// 路径无效或文件不存在时去掉 lut
+func colorEffectConfig(log *logger.Logger, storagePath string, config map[string]interface{}) map[string]interface{} {
+	lut, ok := config["lut"].(string)
+	if !ok {
+		return config
+	}
+	resolved := make(map[string]interface{}, len(config))
+	for key, value := range config {
+		resolved[key] = value
+	}
+	delete(resolved, "lut")
+	if cleaned, err := cleanLUTPath(lut); err == nil {
+		path := filepath.Join(storagePath, cleaned)
+		if _, err := os.Stat(path); err == nil {
+			resolved["lut"] = path
+			return resolved
+		}
+	}
+	log.Warnw("Ignoring invalid color effect lut", "lut", lut)
+	return resolved
+}
+
+// isAutoColorEffect 特效是否由配色匹配生成且未被编辑
+func isAutoColorEffect(effect *models.ClipEffect) bool {
+	auto, _ := effect.Config[colorEffectAutoKey].(bool)
+	return auto
+}
+
+// timelineColorClip 章节时间线上分镜对应的视频片段及其第一个 color 特效
+type timelineColorClip struct {
+	clip   *models.TimelineClip
+	effect *models.ClipEffect
+}
+
+// timelineColorClips 章节最新时间线视频轨道上按分镜 ID 索引的片段，同一分镜只取最早的片段
+func (s *VideoMergeService) timelineColorClips(episodeID uint) map[uint]*timelineColorClip {
+	var timeline models.Timeline
+	s.db.Select("id").Where("episode_id = ?", episodeID).Order("updated_at DESC").Limit(1).Find(&timeline)
+	if timeline.ID == 0 {
+		return nil
+	}
+
+	var clips []models.TimelineClip
+	err := s.db.
+		Joins("JOIN timeline_tracks ON timeline_tracks.id = timeline_clips.track_id AND timeline_tracks.deleted_at IS NULL").
+		Where("timeline_tracks.timeline_id = ? AND timeline_tracks.type = ? AND timeline_clips.storyboard_id IS NOT NULL",
+			timeline.ID, models.TrackTypeVideo).
+		Preload("Track").
+		Preload("Effects", func(db *gorm.DB) *gorm.DB {
+			return db.Where("type = ?", models.EffectTypeColor).Order("clip_effects.`order` ASC, clip_effects.id ASC")
+		}).
+		Order("timeline_clips.start_time ASC").
+		Find(&clips).Error
+	if err != nil {
+		s.log.Warnw("Failed to load timeline color effects", "error", err, "timeline_id", timeline.ID)
+		return nil
+	}
+
+	byScene := make(map[uint]*timelineColorClip)
+	for i := range clips {
+		clip := &clips[i]
+		if _, ok := byScene[*clip.StoryboardID]; ok {
+			continue
+		}
+		entry := &timelineColorClip{clip: clip}
+		if len(clip.Effects) > 0 {
+			entry.effect = &clip.Effects[0]
+		}
+		byScene[*clip.StoryboardID] = entry
+	}
+	return byScene
+}
+
+// applyColorMatch 统计各片段的画面颜色，按参考镜头（或各镜头的中位数）计算校正并设置到片段的特效上
+// 章节时间线上的片段会写入或更新 color 特效，编辑者修改过或禁用的特效保持不变并按其设置合成
+func (s *VideoMergeService) applyColorMatch(ctx context.Context, videoMerge *models.VideoMerge, scenes []models.SceneClip, clips []ffmpeg.VideoClip) error {
+	if len(videoMerge.ColorMatch) == 0 {
+		return nil
+	}
+	var match mergeColorMatch
+	if err := json.Unmarshal(videoMerge.ColorMatch, &match); err != nil {
+		return fmt.Errorf("failed to parse color match: %w", err)
+	}
+
+	// 重新合成时按当前片段和特效重新统计
+	timelineClips := s.timelineColorClips(videoMerge.EpisodeID)
+	match.Target = nil
+	match.Clips = make([]mergeColorClip, len(scenes))
+	var measured []colormatch.Stats
+	for i, scene := range scenes {
+		entry := &match.Clips[i]
+		entry.SceneID = scene.SceneID
+		entry.Source = colorSourceAuto
+		if tc := timelineClips[scene.SceneID]; tc != nil && tc.effect != nil {
+			switch {
+			case !tc.effect.IsEnabled:
+				entry.Source = colorSourceDisabled
+			case !isAutoColorEffect(tc.effect):
+				entry.Source = colorSourceEdited
+			}
+		}
+		// 参考镜头即使由编辑者调整过也需要统计，作为匹配目标
+		if entry.Source != colorSourceAuto && scene.SceneID != match.ReferenceSceneID {
+			continue
+		}
+
+		stats, err := s.ffmpeg.MeasureColor(ctx, scene.VideoURL, scene.StartTime, scene.EndTime)
+		if err != nil {
+			s.log.Warnw("Failed to measure clip color", "scene_id", scene.SceneID, "video_url", scene.VideoURL, "error", err)
+			continue
+		}
+		entry.Stats = &stats
+		measured = append(measured, stats)
+		if scene.SceneID == match.ReferenceSceneID {
+			target := stats
+			match.Target = &target
+		}
+	}
+	if match.Target == nil && len(measured) > 0 {
+		median := colormatch.Median(measured)
+		match.Target = &median
+	}
+
+	for i, scene := range scenes {
+		entry := &match.Clips[i]
+		tc := timelineClips[scene.SceneID]
+		var config map[string]interface{}
+		switch entry.Source {
+		case colorSourceDisabled:
+			continue
+		case colorSourceEdited:
+			config = tc.effect.Config
+		default:
+			correction := colormatch.Correction{Contrast: 1, Saturation: 1}
+			if entry.Stats != nil && match.Target != nil {
+				correction = colormatch.Derive(*entry.Stats, *match.Target)
+			}
+			entry.Correction = &correction
+			config = correction.EffectConfig()
+			if match.LUT != "" {
+				config["lut"] = match.LUT
+			}
+			config[colorEffectAutoKey] = true
+			if tc != nil {
+				s.saveAutoColorEffect(tc, config)
+			}
+		}
+		clips[i].Effects = []ffmpeg.RenderEffect{{
+			Type:   string(models.EffectTypeColor),
+			Config: colorEffectConfig(s.log, s.storagePath, config),
+		}}
+	}
+
+	if data, err := json.Marshal(match); err == nil {
+		s.db.Model(&models.VideoMerge{}).Where("id = ?", videoMerge.ID).Update("color_match", data)
+	}
+	s.log.Infow("Color match applied", "merge_id", videoMerge.ID, "reference_scene_id", match.ReferenceSceneID,
+		"measured", len(measured), "lut", match.LUT)
+	return nil
+}
+
+// saveAutoColorEffect 在时间线片段上写入或更新自动配色特效，锁定的轨道不修改
+func (s *VideoMergeService) saveAutoColorEffect(tc *timelineColorClip, config map[string]interface{}) {
+	if tc.clip.Track.IsLocked {
+		return
+	}
+	if tc.effect != nil {
+		tc.effect.Config = config
+		if err := s.db.Omit(clause.Associations).Save(tc.effect).Error; err != nil {
+			s.log.Warnw("Failed to update auto color effect", "error", err, "effect_id", tc.effect.ID)
+		}
+		return
+	}
+
+	effect := &models.ClipEffect{
+		ClipID:    tc.clip.ID,
+		Type:      models.EffectTypeColor,
+		Name:      autoColorEffectName,
+		IsEnabled: true,
+		Config:    config,
+	}
+	if err := s.db.Omit(clause.Associations).Create(effect).Error; err != nil {
+		s.log.Warnw("Failed to create auto color effect", "error", err, "clip_id", tc.clip.ID)
+		return
+	}
+	tc.effect = effect
+}
@@ -0,0 +1,890 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/drama-generator/backend/domain/models"
+	"github.com/drama-generator/backend/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// 一键制作流水线的步骤，按执行顺序排列
+const (
+	ProductionStepCharacters   = "characters"
+	ProductionStepProps        = "props"
+	ProductionStepBackgrounds  = "backgrounds"
+	ProductionStepStoryboards  = "storyboards"
+	ProductionStepFramePrompts = "frame_prompts"
+	ProductionStepImages       = "images"
+	ProductionStepVideos       = "videos"
+	ProductionStepFinalize     = "finalize"
+)
+
+const (
+	// productionWaitInterval 等待前置步骤完成时的重新检查间隔
+	productionWaitInterval = 10 * time.Second
+	// productionPollInterval 步骤已提交底层任务后检查其进度的间隔
+	productionPollInterval = 5 * time.Second
+)
+
+var productionSteps = []string{
+	ProductionStepCharacters,
+	ProductionStepProps,
+	ProductionStepBackgrounds,
+	ProductionStepStoryboards,
+	ProductionStepFramePrompts,
+	ProductionStepImages,
+	ProductionStepVideos,
+	ProductionStepFinalize,
+}
+
+// productionStepDeps 步骤依赖关系（DAG），角色/道具/场景提取互不依赖，可以并行执行
+var productionStepDeps = map[string][]string{
+	ProductionStepStoryboards:  {ProductionStepCharacters, ProductionStepProps, ProductionStepBackgrounds},
+	ProductionStepFramePrompts: {ProductionStepStoryboards},
+	ProductionStepImages:       {ProductionStepFramePrompts},
+	ProductionStepVideos:       {ProductionStepImages},
+	ProductionStepFinalize:     {ProductionStepVideos},
+}
+
+var productionStepLabels = map[string]string{
+	ProductionStepCharacters:   "角色提取",
+	ProductionStepProps:        "道具提取",
+	ProductionStepBackgrounds:  "场景提取",
+	ProductionStepStoryboards:  "分镜生成",
+	ProductionStepFramePrompts: "首帧提示词",
+	ProductionStepImages:       "分镜图片",
+	ProductionStepVideos:       "分镜视频",
+	ProductionStepFinalize:     "合成成片",
+}
+
+// productionStepServiceTypes 步骤使用的AI服务类型，用于把只指定厂商的覆盖参数解析为模型
+var productionStepServiceTypes = map[string]string{
+	ProductionStepCharacters:   "text",
+	ProductionStepProps:        "text",
+	ProductionStepBackgrounds:  "text",
+	ProductionStepStoryboards:  "text",
+	ProductionStepFramePrompts: "text",
+	ProductionStepImages:       "image",
+	ProductionStepVideos:       "video",
+}
+
+type EpisodeProductionService struct {
+	db                 *gorm.DB
+	log                *logger.Logger
+	jobQueue           *JobQueue
+	eventBus           *EventBus
+	taskService        *TaskService
+	aiService          *AIService
+	characterService   *CharacterLibraryService
+	propService        *PropService
+	imageService       *ImageGenerationService
+	storyboardService  *StoryboardService
+	framePromptService *FramePromptService
+	videoService       *VideoGenerationService
+	mergeService       *VideoMergeService
+}
+
+func NewEpisodeProductionService(db *gorm.DB, jobQueue *JobQueue, eventBus *EventBus, taskService *TaskService, aiService *AIService, characterService *CharacterLibraryService, propService *PropService, imageService *ImageGenerationService, storyboardService *StoryboardService, framePromptService *FramePromptService, videoService *VideoGenerationService, mergeService *VideoMergeService, log *logger.Logger) *EpisodeProductionService {
+	service := &EpisodeProductionService{
+		db:                 db,
+		log:                log,
+		jobQueue:           jobQueue,
+		eventBus:           eventBus,
+		taskService:        taskService,
+		aiService:          aiService,
+		characterService:   characterService,
+		propService:        propService,
+		imageService:       imageService,
+		storyboardService:  storyboardService,
+		framePromptService: framePromptService,
+		videoService:       videoService,
+		mergeService:       mergeService,
+	}
+
+	service.jobQueue.RegisterHandler("episode_production_step", service.handleProductionStepJob)
+	service.jobQueue.RegisterCanceler("episode_production_step", service.handleProductionStepCancelled)
+
+	return service
+}
+
+// ProductionStepOverride 单个步骤的模型/厂商覆盖参数
+type ProductionStepOverride struct {
+	Model    string `json:"model"`
+	Provider string `json:"provider"`
+}
+
+// ProduceEpisodeRequest 一键制作请求
+type ProduceEpisodeRequest struct {
+	Steps         map[string]ProductionStepOverride `json:"steps"`          // 按步骤覆盖模型/厂商
+	ApprovalGates []string                          `json:"approval_gates"` // 这些步骤完成后暂停，审核通过后继续
+}
+
+// productionStepPayload 步骤任务参数，执行过程中会写回已提交的底层任务，用于断点续跑
+type productionStepPayload struct {
+	EpisodeID uint     `json:"episode_id"`
+	Step      string   `json:"step"`
+	Model     string   `json:"model,omitempty"`
+	Provider  string   `json:"provider,omitempty"`
+	Gate      bool     `json:"gate,omitempty"`
+	Approved  bool     `json:"approved,omitempty"`
+	Started   bool     `json:"started,omitempty"`
+	Tracked   []string `json:"tracked,omitempty"`
+}
+
+// EpisodeProduction 一键制作进度
+type EpisodeProduction struct {
+	Task  *models.AsyncTask       `json:"task"`
+	Steps []EpisodeProductionStep `json:"steps"`
+}
+
+// EpisodeProductionStep 一键制作中单个步骤的进度
+type EpisodeProductionStep struct {
+	Step             string   `json:"step"`
+	Label            string   `json:"label"`
+	TaskID           string   `json:"task_id"`
+	Status           string   `json:"status"`
+	Progress         int      `json:"progress"`
+	Message          string   `json:"message"`
+	Error            string   `json:"error,omitempty"`
+	DependsOn        []string `json:"depends_on,omitempty"`
+	Model            string   `json:"model,omitempty"`
+	Provider         string   `json:"provider,omitempty"`
+	Gate             bool     `json:"gate"`
+	Approved         bool     `json:"approved"`
+	AwaitingApproval bool     `json:"awaiting_approval"`
+	TrackedTasks     []string `json:"tracked_tasks,omitempty"`
+}
+
+// ProduceEpisode 一键制作剧集：按DAG依次执行角色/道具/场景提取、分镜、帧提示词、图片、视频和成片合成
+// 已完成的步骤自动跳过；剧集已有暂停（失败或等待审核）的制作时从中断的步骤继续
+func (s *EpisodeProductionService) ProduceEpisode(episodeID string, req *ProduceEpisodeRequest) (*EpisodeProduction, error) {
+	var episode models.Episode
+	if err := s.db.Where("id = ?", episodeID).First(&episode).Error; err != nil {
+		return nil, fmt.Errorf("episode not found")
+	}
+	if req == nil {
+		req = &ProduceEpisodeRequest{}
+	}
+	if err := validateProduceRequest(req); err != nil {
+		return nil, err
+	}
+
+	if s.jobQueue.HasActiveJob("episode_production", episodeID) {
+		parent, err := s.jobQueue.LatestParent("episode_production", episodeID, "paused")
+		if err != nil {
+			return nil, fmt.Errorf("该剧集已有进行中的制作任务")
+		}
+		return s.resumeProduction(parent, req)
+	}
+
+	parent, err := s.jobQueue.CreateParent("episode_production", episodeID, episode.DramaID)
+	if err != nil {
+		return nil, err
+	}
+
+	gates := make(map[string]bool)
+	for _, step := range req.ApprovalGates {
+		gates[step] = true
+	}
+
+	for _, step := range productionSteps {
+		override := req.Steps[step]
+		_, err := s.jobQueue.Enqueue("episode_production_step", episodeID, JobOptions{
+			Queue:    JobQueueDefault,
+			ParentID: parent.ID,
+			DramaID:  episode.DramaID,
+			Priority: JobPriorityBatch,
+			Payload: productionStepPayload{
+				EpisodeID: episode.ID,
+				Step:      step,
+				Model:     override.Model,
+				Provider:  override.Provider,
+				Gate:      gates[step],
+			},
+		})
+		if err != nil {
+			s.jobQueue.Cancel(parent.ID)
+			return nil, err
+		}
+	}
+
+	s.log.Infow("Episode production started", "episode_id", episodeID, "task_id", parent.ID, "gates", req.ApprovalGates)
+	return s.getProduction(parent.ID)
+}
+
+func validateProduceRequest(req *ProduceEpisodeRequest) error {
+	for step := range req.Steps {
+		if _, ok := productionStepLabels[step]; !ok {
+			return fmt.Errorf("未知的制作步骤: %s", step)
+		}
+	}
+	for _, step := range req.ApprovalGates {
+		if _, ok := productionStepLabels[step]; !ok {
+			return fmt.Errorf("未知的制作步骤: %s", step)
+		}
+		if step == ProductionStepFinalize {
+			return fmt.Errorf("成片合成是最后一步，不能设置审核")
+		}
+	}
+	return nil
+}
+
+// resumeProduction 从失败或等待审核的步骤继续执行，请求中的覆盖参数应用到尚未完成的步骤
+func (s *EpisodeProductionService) resumeProduction(parent *models.AsyncTask, req *ProduceEpisodeRequest) (*EpisodeProduction, error) {
+	children, err := s.loadSteps(parent.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range children {
+		child := &children[i]
+		if child.Status == "completed" {
+			continue
+		}
+		payload, err := decodeProductionStep(child)
+		if err != nil {
+			return nil, err
+		}
+		if override, ok := req.Steps[payload.Step]; ok {
+			payload.Model = override.Model
+			payload.Provider = override.Provider
+			if err := s.saveStepPayload(child.ID, payload); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := s.resumeParent(parent.ID); err != nil {
+		return nil, err
+	}
+
+	s.log.Infow("Episode production resumed", "episode_id", parent.ResourceID, "task_id", parent.ID)
+	return s.getProduction(parent.ID)
+}
+
+// resumeParent 清除失败步骤的错误后恢复流水线，失败的步骤会重新提交底层任务
+func (s *EpisodeProductionService) resumeParent(parentID string) error {
+	s.db.Model(&models.AsyncTask{}).
+		Where("parent_id = ? AND status = ? AND error <> ''", parentID, "paused").
+		Update("error", "")
+	s.db.Model(&models.AsyncTask{}).Where("id = ?", parentID).Update("error", "")
+	return s.jobQueue.Resume(parentID)
+}
+
+// GetProduction 获取剧集最近一次一键制作的进度
+func (s *EpisodeProductionService) GetProduction(episodeID string) (*EpisodeProduction, error) {
+	parent, err := s.jobQueue.LatestParent("episode_production", episodeID,
+		"processing", "paused", "completed", "failed", "cancelled")
+	if err != nil {
+		return nil, fmt.Errorf("production not found")
+	}
+	return s.getProduction(parent.ID)
+}
+
+// ApproveStep 审核通过设置了审核的步骤；流水线正在等待该步骤审核时自动继续执行
+// 步骤尚未完成时也可以预先审核，完成后不再暂停
+func (s *EpisodeProductionService) ApproveStep(episodeID string, step string) (*EpisodeProduction, error) {
+	parent, err := s.jobQueue.LatestParent("episode_production", episodeID, "processing", "paused")
+	if err != nil {
+		return nil, fmt.Errorf("production not found")
+	}
+
+	children, err := s.loadSteps(parent.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var target *models.AsyncTask
+	var payload *productionStepPayload
+	for i := range children {
+		p, err := decodeProductionStep(&children[i])
+		if err != nil {
+			return nil, err
+		}
+		if p.Step == step {
+			target = &children[i]
+			payload = p
+			break
+		}
+	}
+	if target == nil {
+		return nil, fmt.Errorf("未知的制作步骤: %s", step)
+	}
+	if !payload.Gate {
+		return nil, fmt.Errorf("步骤「%s」未设置审核", productionStepLabels[step])
+	}
+
+	if !payload.Approved {
+		payload.Approved = true
+		if err := s.saveStepPayload(target.ID, payload); err != nil {
+			return nil, err
+		}
+		s.eventBus.publishTask(s.db, target.ID)
+	}
+
+	if parent.Status == "paused" && target.Status == "completed" {
+		if err := s.resumeParent(parent.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	s.log.Infow("Episode production step approved", "episode_id", episodeID, "step", step, "task_id", parent.ID)
+	return s.getProduction(parent.ID)
+}
+
+func (s *EpisodeProductionService) getProduction(parentID string) (*EpisodeProduction, error) {
+	var parent models.AsyncTask
+	if err := s.db.Where("id = ?", parentID).First(&parent).Error; err != nil {
+		return nil, err
+	}
+
+	children, err := s.loadSteps(parent.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	production := &EpisodeProduction{Task: &parent}
+	for i := range children {
+		child := &children[i]
+		payload, err := decodeProductionStep(child)
+		if err != nil {
+			return nil, err
+		}
+		production.Steps = append(production.Steps, EpisodeProductionStep{
+			Step:             payload.Step,
+			Label:            productionStepLabels[payload.Step],
+			TaskID:           child.ID,
+			Status:           child.Status,
+			Progress:         child.Progress,
+			Message:          child.Message,
+			Error:            child.Error,
+			DependsOn:        productionStepDeps[payload.Step],
+			Model:            payload.Model,
+			Provider:         payload.Provider,
+			Gate:             payload.Gate,
+			Approved:         payload.Approved,
+			AwaitingApproval: payload.Gate && !payload.Approved && child.Status == "completed",
+			TrackedTasks:     payload.Tracked,
+		})
+	}
+	return production, nil
+}
+
+// loadSteps 按创建顺序加载流水线的步骤任务
+func (s *EpisodeProductionService) loadSteps(parentID string) ([]models.AsyncTask, error) {
+	var children []models.AsyncTask
+	if err := s.db.Where("parent_id = ? AND type = ?", parentID, "episode_production_step").
+		Order("created_at ASC").
+		Find(&children).Error; err != nil {
+		return nil, err
+	}
+	return children, nil
+}
+
+func decodeProductionStep(task *models.AsyncTask) (*productionStepPayload, error) {
+	var payload productionStepPayload
+	if err := decodeJobPayload(task, &payload); err != nil {
+		return nil, err
+	}
+	return &payload, nil
+}
+
+func (s *EpisodeProductionService) saveStepPayload(taskID string, payload *productionStepPayload) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job payload: %w", err)
+	}
+	return s.db.Model(&models.AsyncTask{}).Where("id = ?", taskID).Update("payload", string(data)).Error
+}
+
+// handleProductionStepJob 任务队列处理函数
+// 步骤任务先等待前置步骤完成，再提交底层任务并定期检查，底层任务全部结束后根据业务数据判断步骤是否完成
+func (s *EpisodeProductionService) handleProductionStepJob(ctx context.Context, task *models.AsyncTask) error {
+	payload, err := decodeProductionStep(task)
+	if err != nil {
+		return err
+	}
+
+	var parent models.AsyncTask
+	if err := s.db.Where("id = ?", task.ParentID).First(&parent).Error; err != nil {
+		return fmt.Errorf("production task not found: %w", err)
+	}
+	switch parent.Status {
+	case "paused":
+		s.parkStep(task.ID, "流水线已暂停")
+		return nil
+	case "processing":
+	default:
+		return nil
+	}
+
+	ready, blocked, err := s.dependenciesReady(task.ParentID, payload.Step)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		// 前置步骤失败或等待审核时流水线应处于暂停状态（例如被重新发起过），这里补充暂停
+		s.parkStep(task.ID, "前置步骤失败或等待审核")
+		s.jobQueue.Pause(task.ParentID)
+		return nil
+	}
+	if !ready {
+		if task.Message != "等待前置步骤完成" {
+			s.taskService.UpdateTaskStatus(task.ID, "processing", 0, "等待前置步骤完成")
+		}
+		return RescheduleJob(productionWaitInterval)
+	}
+
+	var episode models.Episode
+	if err := s.db.Preload("Drama").Where("id = ?", payload.EpisodeID).First(&episode).Error; err != nil {
+		s.failStep(task, payload, fmt.Errorf("episode not found"))
+		return nil
+	}
+
+	label := productionStepLabels[payload.Step]
+	if !payload.Started {
+		if s.stepDone(payload.Step, &episode) {
+			s.completeStep(task, payload, fmt.Sprintf("%s已完成，跳过", label))
+			return nil
+		}
+
+		tracked, err := s.startStep(payload, &episode)
+		if err != nil {
+			s.failStep(task, payload, err)
+			return nil
+		}
+		payload.Started = true
+		payload.Tracked = tracked
+		if err := s.saveStepPayload(task.ID, payload); err != nil {
+			return err
+		}
+		s.taskService.UpdateTaskStatus(task.ID, "processing", 10, fmt.Sprintf("%s进行中", label))
+		return RescheduleJob(productionPollInterval)
+	}
+
+	if s.stepBusy(payload, &episode) {
+		progress, message := s.stepProgress(payload.Step, &episode)
+		s.taskService.UpdateTaskStatus(task.ID, "processing", progress, message)
+		return RescheduleJob(productionPollInterval)
+	}
+
+	// 提取类步骤的结果可能为空（剧本中没有道具等），底层任务成功即视为完成
+	if s.stepDone(payload.Step, &episode) || (productionStepServiceTypes[payload.Step] == "text" && s.trackedCompleted(payload)) {
+		s.completeStep(task, payload, fmt.Sprintf("%s已完成", label))
+		return nil
+	}
+	s.failStep(task, payload, s.trackedError(payload))
+	return nil
+}
+
+// handleProductionStepCancelled 流水线被取消时同步取消步骤提交的底层任务
+func (s *EpisodeProductionService) handleProductionStepCancelled(task *models.AsyncTask) {
+	payload, err := decodeProductionStep(task)
+	if err != nil {
+		return
+	}
+	for _, taskID := range payload.Tracked {
+		var tracked models.AsyncTask
+		if err := s.db.Select("status").Where("id = ?", taskID).First(&tracked).Error; err != nil {
+			continue
+		}
+		if !isTerminalTaskStatus(tracked.Status) {
+			s.jobQueue.Cancel(taskID)
+		}
+	}
+}
+
+// dependenciesReady 检查前置步骤：ready 表示全部完成且已审核，blocked 表示有前置步骤失败、被取消或等待审核
+func (s *EpisodeProductionService) dependenciesReady(parentID, step string) (ready bool, blocked bool, err error) {
+	deps := productionStepDeps[step]
+	if len(deps) == 0 {
+		return true, false, nil
+	}
+
+	children, err := s.loadSteps(parentID)
+	if err != nil {
+		return false, false, err
+	}
+
+	ready = true
+	for i := range children {
+		child := &children[i]
+		payload, err := decodeProductionStep(child)
+		if err != nil {
+			return false, false, err
+		}
+		if !slices.Contains(deps, payload.Step) {
+			continue
+		}
+		switch {
+		case child.Status == "completed":
+			if payload.Gate && !payload.Approved {
+				return false, true, nil
+			}
+		case child.Status == "failed" || child.Status == "cancelled" || child.Error != "":
+			return false, true, nil
+		default:
+			ready = false
+		}
+	}
+	return ready, false, nil
+}
+
+// parkStep 暂停步骤，流水线恢复时重新排队
+func (s *EpisodeProductionService) parkStep(taskID string, message string) {
+	s.db.Model(&models.AsyncTask{}).
+		Where("id = ? AND status = ?", taskID, "processing").
+		Updates(map[string]interface{}{"status": "paused", "message": message})
+}
+
+// completeStep 标记步骤完成，设置了审核且尚未审核的步骤完成后暂停流水线
+func (s *EpisodeProductionService) completeStep(task *models.AsyncTask, payload *productionStepPayload, message string) {
+	s.db.Model(&models.AsyncTask{}).Where("id = ?", task.ID).Updates(map[string]interface{}{"message": message, "error": ""})
+	s.taskService.UpdateTaskResult(task.ID, map[string]interface{}{
+		"step":    payload.Step,
+		"message": message,
+		"tracked": payload.Tracked,
+	})
+	s.log.Infow("Episode production step completed", "episode_id", payload.EpisodeID, "step", payload.Step)
+
+	if payload.Gate && !payload.Approved {
+		if err := s.jobQueue.Pause(task.ParentID); err != nil {
+			s.log.Warnw("Failed to pause production for approval", "error", err, "task_id", task.ParentID)
+			return
+		}
+		s.log.Infow("Episode production waiting for approval", "episode_id", payload.EpisodeID, "step", payload.Step)
+	}
+}
+
+// failStep 步骤失败：暂停该步骤和整个流水线并记录错误，修复后重新发起制作即从该步骤继续
+// 不直接标记为 failed，避免最后一步失败时父任务因子任务全部结束而被汇总为完成
+func (s *EpisodeProductionService) failStep(task *models.AsyncTask, payload *productionStepPayload, stepErr error) {
+	label := productionStepLabels[payload.Step]
+	s.log.Errorw("Episode production step failed", "episode_id", payload.EpisodeID, "step", payload.Step, "error", stepErr)
+
+	payload.Started = false
+	payload.Tracked = nil
+	if err := s.saveStepPayload(task.ID, payload); err != nil {
+		s.log.Warnw("Failed to reset production step", "error", err, "task_id", task.ID)
+	}
+
+	s.db.Model(&models.AsyncTask{}).
+		Where("id = ? AND status = ?", task.ID, "processing").
+		Updates(map[string]interface{}{
+			"status":   "paused",
+			"progress": 0,
+			"error":    stepErr.Error(),
+			"message":  fmt.Sprintf("%s失败，重新发起制作将从此步骤继续", label),
+		})
+	s.eventBus.publishTask(s.db, task.ID)
+
+	if err := s.jobQueue.Pause(task.ParentID); err != nil {
+		s.log.Warnw("Failed to pause production", "error", err, "task_id", task.ParentID)
+	}
+	s.db.Model(&models.AsyncTask{}).
+		Where("id = ?", task.ParentID).
+		Update("error", fmt.Sprintf("%s失败: %s", label, stepErr.Error()))
+	s.eventBus.publishTask(s.db, task.ParentID)
+}
+
+// startStep 提交步骤的底层任务，返回需要跟踪的任务ID
+func (s *EpisodeProductionService) startStep(payload *productionStepPayload, episode *models.Episode) ([]string, error) {
+	model, err := s.resolveStepModel(payload)
+	if err != nil {
+		return nil, err
+	}
+	episodeID := fmt.Sprintf("%d", episode.ID)
+
+	switch payload.Step {
+	case ProductionStepCharacters:
+		taskID, err := s.characterService.ExtractCharactersFromScriptWithModel(episode.ID, model)
+		if err != nil {
+			return nil, err
+		}
+		return []string{taskID}, nil
+
+	case ProductionStepProps:
+		taskID, err := s.propService.ExtractPropsFromScriptWithModel(episode.ID, model)
+		if err != nil {
+			return nil, err
+		}
+		return []string{taskID}, nil
+
+	case ProductionStepBackgrounds:
+		taskID, err := s.imageService.ExtractBackgroundsForEpisode(episodeID, model, episode.Drama.Style)
+		if err != nil {
+			return nil, err
+		}
+		return []string{taskID}, nil
+
+	case ProductionStepStoryboards:
+		taskID, err := s.storyboardService.GenerateStoryboard(episodeID, model)
+		if err != nil {
+			return nil, err
+		}
+		return []string{taskID}, nil
+
+	case ProductionStepFramePrompts:
+		var storyboards []models.Storyboard
+		if err := s.db.Where("episode_id = ?", episode.ID).
+			Where("id NOT IN (?)", s.db.Model(&models.FramePrompt{}).Select("storyboard_id").Where("frame_type = ?", models.FrameTypeFirst)).
+			Order("storyboard_number ASC").
+			Find(&storyboards).Error; err != nil {
+			return nil, err
+		}
+		var tracked []string
+		for _, storyboard := range storyboards {
+			taskID, err := s.framePromptService.GenerateFramePrompt(GenerateFramePromptRequest{
+				StoryboardID: fmt.Sprintf("%d", storyboard.ID),
+				FrameType:    FrameTypeFirst,
+			}, model)
+			if err != nil {
+				return nil, err
+			}
+			tracked = append(tracked, taskID)
+		}
+		return tracked, nil
+
+	case ProductionStepImages:
+		batch, _, err := s.imageService.BatchGenerateImagesForEpisodeWithOptions(episodeID, EpisodeBatchOptions{
+			Model:          model,
+			Provider:       payload.Provider,
+			OnlyMissing:    true,
+			UseFramePrompt: true,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return []string{batch.ID}, nil
+
+	case ProductionStepVideos:
+		batch, _, err := s.videoService.BatchGenerateVideosForEpisodeWithOptions(episodeID, EpisodeBatchOptions{
+			Model:       model,
+			Provider:    payload.Provider,
+			OnlyMissing: true,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return []string{batch.ID}, nil
+
+	case ProductionStepFinalize:
+		result, err := s.mergeService.FinalizeEpisode(episodeID, nil)
+		if err != nil {
+			return nil, err
+		}
+		// 合成状态同时通过 video_merges 表检查，这里找不到队列任务也不影响判断
+		var tracked []string
+		var mergeTask models.AsyncTask
+		if err := s.db.Where("type = ? AND resource_id = ?", "video_merge", fmt.Sprintf("%v", result["merge_id"])).
+			Order("created_at DESC").
+			First(&mergeTask).Error; err == nil {
+			tracked = append(tracked, mergeTask.ID)
+		}
+		return tracked, nil
+	}
+
+	return nil, fmt.Errorf("未知的制作步骤: %s", payload.Step)
+}
+
+// resolveStepModel 只指定了厂商时使用该厂商配置的第一个模型
+func (s *EpisodeProductionService) resolveStepModel(payload *productionStepPayload) (string, error) {
+	if payload.Model != "" || payload.Provider == "" {
+		return payload.Model, nil
+	}
+	serviceType, ok := productionStepServiceTypes[payload.Step]
+	if !ok {
+		return "", nil
+	}
+	return s.aiService.ResolveModelForProvider(serviceType, payload.Provider)
+}
+
+// stepDone 根据业务数据判断步骤是否已经完成，用于跳过已完成的步骤和校验执行结果
+func (s *EpisodeProductionService) stepDone(step string, episode *models.Episode) bool {
+	switch step {
+	case ProductionStepCharacters:
+		return s.db.Model(episode).Association("Characters").Count() > 0
+
+	case ProductionStepProps:
+		// 道具属于剧目而不是剧集，以该剧集成功执行过道具提取为准
+		var count int64
+		s.db.Model(&models.AsyncTask{}).
+			Where("type = ? AND resource_id = ? AND status = ?", "prop_extraction", fmt.Sprintf("%d", episode.ID), "completed").
+			Count(&count)
+		return count > 0
+
+	case ProductionStepBackgrounds:
+		var count int64
+		s.db.Model(&models.Scene{}).Where("episode_id = ?", episode.ID).Count(&count)
+		return count > 0
+
+	case ProductionStepStoryboards:
+		var count int64
+		s.db.Model(&models.Storyboard{}).Where("episode_id = ?", episode.ID).Count(&count)
+		return count > 0
+
+	case ProductionStepFramePrompts:
+		var total, done int64
+		s.db.Model(&models.Storyboard{}).Where("episode_id = ?", episode.ID).Count(&total)
+		s.db.Model(&models.FramePrompt{}).
+			Where("frame_type = ? AND storyboard_id IN (?)", models.FrameTypeFirst, s.episodeStoryboardIDs(episode.ID)).
+			Distinct("storyboard_id").
+			Count(&done)
+		return total > 0 && done >= total
+
+	case ProductionStepImages:
+		done, total := s.imageProgress(episode.ID)
+		return total > 0 && done >= total
+
+	case ProductionStepVideos:
+		done, total := s.videoProgress(episode.ID)
+		return total > 0 && done >= total
+
+	case ProductionStepFinalize:
+		var merge models.VideoMerge
+		if err := s.db.Where("episode_id = ?", episode.ID).Order("created_at DESC").First(&merge).Error; err != nil {
+			return false
+		}
+		if merge.Status != models.VideoMergeStatusCompleted {
+			return false
+		}
+		// 成片之后又有新生成的视频时需要重新合成
+		var newer int64
+		s.db.Model(&models.VideoGeneration{}).
+			Where("storyboard_id IN (?) AND status = ? AND completed_at > ?", s.episodeStoryboardIDs(episode.ID), models.VideoStatusCompleted, merge.CreatedAt).
+			Count(&newer)
+		return newer == 0
+	}
+	return false
+}
+
+// stepBusy 判断步骤提交的底层任务是否仍在执行
+// 图片/视频/合成的状态轮询任务不挂在批量父任务下，需要额外检查业务记录
+func (s *EpisodeProductionService) stepBusy(payload *productionStepPayload, episode *models.Episode) bool {
+	if len(payload.Tracked) > 0 {
+		var count int64
+		s.db.Model(&models.AsyncTask{}).Where("id IN ? AND status IN ?", payload.Tracked, activeTaskStatuses).Count(&count)
+		if count > 0 {
+			return true
+		}
+	}
+
+	var count int64
+	switch payload.Step {
+	case ProductionStepImages:
+		s.db.Model(&models.ImageGeneration{}).
+			Where("storyboard_id IN (?) AND status IN ?", s.episodeStoryboardIDs(episode.ID), []models.ImageGenerationStatus{models.ImageStatusPending, models.ImageStatusProcessing}).
+			Count(&count)
+	case ProductionStepVideos:
+		s.db.Model(&models.VideoGeneration{}).
+			Where("storyboard_id IN (?) AND status IN ?", s.episodeStoryboardIDs(episode.ID), []models.VideoStatus{models.VideoStatusPending, models.VideoStatusProcessing}).
+			Count(&count)
+	case ProductionStepFinalize:
+		s.db.Model(&models.VideoMerge{}).
+			Where("episode_id = ? AND status IN ?", episode.ID, []models.VideoMergeStatus{models.VideoMergeStatusPending, models.VideoMergeStatusProcessing}).
+			Count(&count)
+	}
+	return count > 0
+}
+
+// stepProgress 步骤执行中的进度描述
+func (s *EpisodeProductionService) stepProgress(step string, episode *models.Episode) (int, string) {
+	label := productionStepLabels[step]
+	var done, total int64
+	switch step {
+	case ProductionStepImages:
+		done, total = s.imageProgress(episode.ID)
+	case ProductionStepVideos:
+		done, total = s.videoProgress(episode.ID)
+	default:
+		return 50, fmt.Sprintf("%s进行中", label)
+	}
+	if total == 0 {
+		return 50, fmt.Sprintf("%s进行中", label)
+	}
+	return int(10 + done*90/total), fmt.Sprintf("%s进行中 %d/%d", label, done, total)
+}
+
+// imageProgress 统计有图片提示词的分镜中已有完成图片的数量
+func (s *EpisodeProductionService) imageProgress(episodeID uint) (done int64, total int64) {
+	promptedIDs := s.db.Model(&models.Storyboard{}).Select("id").
+		Where("episode_id = ? AND image_prompt IS NOT NULL AND image_prompt <> ''", episodeID)
+	s.db.Model(&models.Storyboard{}).
+		Where("episode_id = ? AND image_prompt IS NOT NULL AND image_prompt <> ''", episodeID).
+		Count(&total)
+	s.db.Model(&models.ImageGeneration{}).
+		Where("storyboard_id IN (?) AND status = ?", promptedIDs, models.ImageStatusCompleted).
+		Distinct("storyboard_id").
+		Count(&done)
+	return done, total
+}
+
+// videoProgress 统计有图片提示词的分镜中已有完成视频的数量
+func (s *EpisodeProductionService) videoProgress(episodeID uint) (done int64, total int64) {
+	promptedIDs := s.db.Model(&models.Storyboard{}).Select("id").
+		Where("episode_id = ? AND image_prompt IS NOT NULL AND image_prompt <> ''", episodeID)
+	s.db.Model(&models.Storyboard{}).
+		Where("episode_id = ? AND image_prompt IS NOT NULL AND image_prompt <> ''", episodeID).
+		Count(&total)
+	s.db.Model(&models.VideoGeneration{}).
+		Where("storyboard_id IN (?) AND status = ?", promptedIDs, models.VideoStatusCompleted).
+		Distinct("storyboard_id").
+		Count(&done)
+	return done, total
+}
+
+func (s *EpisodeProductionService) episodeStoryboardIDs(episodeID uint) *gorm.DB {
+	return s.db.Model(&models.Storyboard{}).Select("id").Where("episode_id = ?", episodeID)
+}
+
+// trackedCompleted 步骤提交的底层任务是否全部成功完成
+func (s *EpisodeProductionService) trackedCompleted(payload *productionStepPayload) bool {
+	if len(payload.Tracked) == 0 {
+		return false
+	}
+	var count int64
+	s.db.Model(&models.AsyncTask{}).Where("id IN ? AND status = ?", payload.Tracked, "completed").Count(&count)
+	return int(count) == len(payload.Tracked)
+}
+
+// trackedError 底层任务结束但步骤未完成时，汇总底层任务的失败原因
+func (s *EpisodeProductionService) trackedError(payload *productionStepPayload) error {
+	label := productionStepLabels[payload.Step]
+	if len(payload.Tracked) > 0 {
+		var failed []models.AsyncTask
+		s.db.Where("id IN ? AND status IN ?", payload.Tracked, []string{"failed", "cancelled"}).Find(&failed)
+		if len(failed) > 0 {
+			reason := failed[0].Error
+			if reason == "" {
+				reason = failed[0].Message
+			}
+			return fmt.Errorf("%s任务%s: %s", label, failed[0].Status, reason)
+		}
+	}
+
+	switch payload.Step {
+	case ProductionStepImages:
+		done, total := s.imageProgress(payload.EpisodeID)
+		if total == 0 {
+			return fmt.Errorf("没有带图片提示词的分镜")
+		}
+		return fmt.Errorf("%d/%d 个分镜图片生成失败", total-done, total)
+	case ProductionStepVideos:
+		done, total := s.videoProgress(payload.EpisodeID)
+		if total == 0 {
+			return fmt.Errorf("没有可生成视频的分镜")
+		}
+		return fmt.Errorf("%d/%d 个分镜视频生成失败", total-done, total)
+	case ProductionStepFinalize:
+		var merge models.VideoMerge
+		if err := s.db.Where("episode_id = ?", payload.EpisodeID).Order("created_at DESC").First(&merge).Error; err == nil && merge.ErrorMsg != nil {
+			return fmt.Errorf("视频合成失败: %s", *merge.ErrorMsg)
+		}
+	}
+	return fmt.Errorf("%s执行结束但没有产生结果", label)
+}
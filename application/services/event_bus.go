@@ -0,0 +1,178 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"github.com/drama-generator/backend/domain/models"
+	"gorm.io/gorm"
+)
+
+// 事件类型
+const (
+	EventTaskUpdated   = "task.updated"
+	EventTaskCompleted = "task.completed"
+	EventTaskFailed    = "task.failed"
+	EventTaskCancelled = "task.cancelled"
+
+	EventImageProcessing = "image.processing"
+	EventImageCompleted  = "image.completed"
+	EventImageFailed     = "image.failed"
+	EventImageCancelled  = "image.cancelled"
+
+	EventVideoProcessing = "video.processing"
+	EventVideoCompleted  = "video.completed"
+	EventVideoFailed     = "video.failed"
+	EventVideoCancelled  = "video.cancelled"
+
+	EventMergeProcessing = "merge.processing"
+	EventMergeCompleted  = "merge.completed"
+	EventMergeFailed     = "merge.failed"
+
+	EventLipSyncCompleted = "lipsync.completed"
+	EventLipSyncFailed    = "lipsync.failed"
+)
+
+// 事件历史保留条数，用于断线重连时按 Last-Event-ID 补发
+const eventHistorySize = 2000
+
+// Event 推送给前端的状态变更事件
+type Event struct {
+	ID      int64       `json:"id"`
+	Type    string      `json:"type"`
+	DramaID uint        `json:"drama_id,omitempty"`
+	Data    interface{} `json:"data"`
+	Time    time.Time   `json:"time"`
+}
+
+// EventBus 进程内事件总线，所有任务/生成状态写入都通过它发布
+type EventBus struct {
+	mu          sync.Mutex
+	nextID      int64
+	history     []Event
+	subscribers map[int]*eventSubscriber
+	nextSubID   int
+}
+
+type eventSubscriber struct {
+	dramaID uint
+	ch      chan Event
+}
+
+// NewEventBus 创建事件总线，启动时创建一个并传入任务队列、各业务服务和事件推送处理器
+func NewEventBus() *EventBus {
+	return &EventBus{
+		// 以启动时间（毫秒）作为起始ID，重启后新事件ID仍大于浏览器保存的 Last-Event-ID
+		nextID:      time.Now().UnixMilli(),
+		subscribers: make(map[int]*eventSubscriber),
+	}
+}
+
+// Publish 发布事件，dramaID 为 0 的事件只推送给未按剧目过滤的订阅者
+func (b *EventBus) Publish(eventType string, dramaID uint, data interface{}) Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextID++
+	event := Event{
+		ID:      b.nextID,
+		Type:    eventType,
+		DramaID: dramaID,
+		Data:    data,
+		Time:    time.Now(),
+	}
+
+	b.history = append(b.history, event)
+	if len(b.history) > eventHistorySize {
+		b.history = b.history[len(b.history)-eventHistorySize:]
+	}
+
+	for id, sub := range b.subscribers {
+		if !sub.matches(event) {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			// 消费过慢的订阅者直接断开，客户端重连后通过 Last-Event-ID 补发
+			close(sub.ch)
+			delete(b.subscribers, id)
+		}
+	}
+
+	return event
+}
+
+// Subscribe 订阅事件，返回 lastEventID 之后需要补发的历史事件
+// 补发和订阅在同一把锁内完成，保证中间不会漏掉事件
+func (b *EventBus) Subscribe(dramaID uint, lastEventID int64) ([]Event, <-chan Event, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sub := &eventSubscriber{
+		dramaID: dramaID,
+		ch:      make(chan Event, 256),
+	}
+
+	var replay []Event
+	if lastEventID > 0 {
+		for _, event := range b.history {
+			if event.ID > lastEventID && sub.matches(event) {
+				replay = append(replay, event)
+			}
+		}
+	}
+
+	b.nextSubID++
+	subID := b.nextSubID
+	b.subscribers[subID] = sub
+
+	unsubscribe := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[subID]; ok {
+			close(sub.ch)
+			delete(b.subscribers, subID)
+		}
+	}
+
+	return replay, sub.ch, unsubscribe
+}
+
+func (s *eventSubscriber) matches(event Event) bool {
+	return s.dramaID == 0 || s.dramaID == event.DramaID
+}
+
+// publishTask 读取任务最新状态并发布任务事件
+func (b *EventBus) publishTask(db *gorm.DB, taskID string) {
+	var task models.AsyncTask
+	if err := db.Where("id = ?", taskID).First(&task).Error; err != nil {
+		return
+	}
+
+	eventType := EventTaskUpdated
+	switch task.Status {
+	case "completed":
+		eventType = EventTaskCompleted
+	case "failed":
+		eventType = EventTaskFailed
+	case "cancelled":
+		eventType = EventTaskCancelled
+	}
+
+	b.Publish(eventType, task.DramaID, taskEventData(&task))
+}
+
+// taskEventData 任务事件的数据，SSE 和 webhook 共用
+func taskEventData(task *models.AsyncTask) map[string]interface{} {
+	return map[string]interface{}{
+		"task_id":     task.ID,
+		"type":        task.Type,
+		"status":      task.Status,
+		"progress":    task.Progress,
+		"message":     task.Message,
+		"error":       task.Error,
+		"resource_id": task.ResourceID,
+		"parent_id":   task.ParentID,
+	}
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -18,18 +19,30 @@ type FramePromptService struct {
 	config      *config.Config
 	promptI18n  *PromptI18n
 	taskService *TaskService
+	jobQueue    *JobQueue
 }
 
 // NewFramePromptService 创建帧提示词服务
-func NewFramePromptService(db *gorm.DB, cfg *config.Config, log *logger.Logger) *FramePromptService {
-	return &FramePromptService{
+func NewFramePromptService(db *gorm.DB, jobQueue *JobQueue, taskService *TaskService, aiService *AIService, cfg *config.Config, log *logger.Logger) *FramePromptService {
+	service := &FramePromptService{
 		db:          db,
-		aiService:   NewAIService(db, log),
+		aiService:   aiService,
 		log:         log,
 		config:      cfg,
 		promptI18n:  NewPromptI18n(cfg),
-		taskService: NewTaskService(db, log),
+		taskService: taskService,
+		jobQueue:    jobQueue,
 	}
+
+	service.jobQueue.RegisterHandler("frame_prompt_generation", service.handleFramePromptGenerationJob)
+
+	return service
+}
+
+// framePromptGenerationPayload 帧提示词生成任务参数
+type framePromptGenerationPayload struct {
+	Request GenerateFramePromptRequest `json:"request"`
+	Model   string                     `json:"model"`
 }
 
 // FrameType 帧类型
@@ -78,20 +91,36 @@ func (s *FramePromptService) GenerateFramePrompt(req GenerateFramePromptRequest,
 		return "", fmt.Errorf("storyboard not found: %w", err)
 	}
 
-	// 创建任务
-	task, err := s.taskService.CreateTask("frame_prompt_generation", req.StoryboardID)
+	var episode models.Episode
+	s.db.Select("id", "drama_id").First(&episode, storyboard.EpisodeID)
+
+	// 创建排队任务，异步处理帧提示词生成
+	task, err := s.jobQueue.Enqueue("frame_prompt_generation", req.StoryboardID, JobOptions{
+		Queue:    JobQueueText,
+		Provider: s.aiService.ResolveProvider("text", model),
+		Priority: JobPriorityInteractive,
+		DramaID:  episode.DramaID,
+		Payload:  framePromptGenerationPayload{Request: req, Model: model},
+	})
 	if err != nil {
 		s.log.Errorw("Failed to create frame prompt generation task", "error", err, "storyboard_id", req.StoryboardID)
 		return "", fmt.Errorf("创建任务失败: %w", err)
 	}
 
-	// 异步处理帧提示词生成
-	go s.processFramePromptGeneration(task.ID, req, model)
-
 	s.log.Infow("Frame prompt generation task created", "task_id", task.ID, "storyboard_id", req.StoryboardID, "frame_type", req.FrameType)
 	return task.ID, nil
 }
 
+// handleFramePromptGenerationJob 任务队列处理函数
+func (s *FramePromptService) handleFramePromptGenerationJob(ctx context.Context, task *models.AsyncTask) error {
+	var payload framePromptGenerationPayload
+	if err := decodeJobPayload(task, &payload); err != nil {
+		return err
+	}
+	s.processFramePromptGeneration(task.ID, payload.Request, payload.Model)
+	return nil
+}
+
 // processFramePromptGeneration 异步处理帧提示词生成
 func (s *FramePromptService) processFramePromptGeneration(taskID string, req GenerateFramePromptRequest, model string) {
 	// 更新任务状态为处理中
@@ -216,19 +245,7 @@ func (s *FramePromptService) generateFirstFrame(sb models.Storyboard, scene *mod
 	userPrompt := s.promptI18n.FormatUserPrompt("frame_info", contextInfo)
 
 	// 调用AI生成（如果指定了模型则使用指定的模型）
-	var aiResponse string
-	var err error
-	if model != "" {
-		client, getErr := s.aiService.GetAIClientForModel("text", model)
-		if getErr != nil {
-			s.log.Warnw("Failed to get client for specified model, using default", "model", model, "error", getErr)
-			aiResponse, err = s.aiService.GenerateText(userPrompt, systemPrompt)
-		} else {
-			aiResponse, err = client.GenerateText(userPrompt, systemPrompt)
-		}
-	} else {
-		aiResponse, err = s.aiService.GenerateText(userPrompt, systemPrompt)
-	}
+	aiResponse, err := s.aiService.GenerateTextWithModel(model, userPrompt, systemPrompt)
 	if err != nil {
 		s.log.Warnw("AI generation failed, using fallback", "error", err)
 		// 降级方案：使用简单拼接
@@ -264,19 +281,7 @@ func (s *FramePromptService) generateKeyFrame(sb models.Storyboard, scene *model
 	userPrompt := s.promptI18n.FormatUserPrompt("key_frame_info", contextInfo)
 
 	// 调用AI生成（如果指定了模型则使用指定的模型）
-	var aiResponse string
-	var err error
-	if model != "" {
-		client, getErr := s.aiService.GetAIClientForModel("text", model)
-		if getErr != nil {
-			s.log.Warnw("Failed to get client for specified model, using default", "model", model, "error", getErr)
-			aiResponse, err = s.aiService.GenerateText(userPrompt, systemPrompt)
-		} else {
-			aiResponse, err = client.GenerateText(userPrompt, systemPrompt)
-		}
-	} else {
-		aiResponse, err = s.aiService.GenerateText(userPrompt, systemPrompt)
-	}
+	aiResponse, err := s.aiService.GenerateTextWithModel(model, userPrompt, systemPrompt)
 	if err != nil {
 		s.log.Warnw("AI generation failed, using fallback", "error", err)
 		fallbackPrompt := s.buildFallbackPrompt(sb, scene, "key frame, dynamic action")
@@ -311,19 +316,7 @@ func (s *FramePromptService) generateLastFrame(sb models.Storyboard, scene *mode
 	userPrompt := s.promptI18n.FormatUserPrompt("last_frame_info", contextInfo)
 
 	// 调用AI生成（如果指定了模型则使用指定的模型）
-	var aiResponse string
-	var err error
-	if model != "" {
-		client, getErr := s.aiService.GetAIClientForModel("text", model)
-		if getErr != nil {
-			s.log.Warnw("Failed to get client for specified model, using default", "model", model, "error", getErr)
-			aiResponse, err = s.aiService.GenerateText(userPrompt, systemPrompt)
-		} else {
-			aiResponse, err = client.GenerateText(userPrompt, systemPrompt)
-		}
-	} else {
-		aiResponse, err = s.aiService.GenerateText(userPrompt, systemPrompt)
-	}
+	aiResponse, err := s.aiService.GenerateTextWithModel(model, userPrompt, systemPrompt)
 	if err != nil {
 		s.log.Warnw("AI generation failed, using fallback", "error", err)
 		fallbackPrompt := s.buildFallbackPrompt(sb, scene, "last frame, final state")
@@ -388,19 +381,7 @@ func (s *FramePromptService) generateActionSequence(sb models.Storyboard, scene
 	userPrompt := s.promptI18n.FormatUserPrompt("frame_info", contextInfo)
 
 	// 调用AI生成（如果指定了模型则使用指定的模型）
-	var aiResponse string
-	var err error
-	if model != "" {
-		client, getErr := s.aiService.GetAIClientForModel("text", model)
-		if getErr != nil {
-			s.log.Warnw("Failed to get client for specified model, using default", "model", model, "error", getErr)
-			aiResponse, err = s.aiService.GenerateText(userPrompt, systemPrompt)
-		} else {
-			aiResponse, err = client.GenerateText(userPrompt, systemPrompt)
-		}
-	} else {
-		aiResponse, err = s.aiService.GenerateText(userPrompt, systemPrompt)
-	}
+	aiResponse, err := s.aiService.GenerateTextWithModel(model, userPrompt, systemPrompt)
 
 	if err != nil {
 		s.log.Warnw("AI generation failed for action sequence, using fallback", "error", err)
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	models "github.com/drama-generator/backend/domain/models"
+	"github.com/drama-generator/backend/infrastructure/external/ffmpeg"
+	"github.com/drama-generator/backend/pkg/config"
+	"gorm.io/gorm"
+)
+
+// maxFrameRate 合成允许的最高帧率
+const maxFrameRate = 120
+
+// FrameRateSettings 合成时统一帧率的设置
+type FrameRateSettings struct {
+	Enabled       bool
+	FPS           int
+	Interpolation string
+	Stretch       bool
+	MaxStretch    float64
+}
+
+// NewFrameRateSettings 根据配置文件生成统一帧率设置，默认不统一帧率；
+// 未填写的帧率、转换方式和最大放慢倍数使用 30、blend 和 2
+func NewFrameRateSettings(cfg config.FrameRateConfig) (FrameRateSettings, error) {
+	settings := FrameRateSettings{
+		Enabled:       cfg.Enabled,
+		FPS:           cfg.FPS,
+		Interpolation: strings.ToLower(cfg.Interpolation),
+		Stretch:       cfg.Stretch,
+		MaxStretch:    cfg.MaxStretch,
+	}
+	if settings.FPS == 0 {
+		settings.FPS = 30
+	}
+	if settings.FPS < 1 || settings.FPS > maxFrameRate {
+		return FrameRateSettings{}, fmt.Errorf("invalid fps %d", cfg.FPS)
+	}
+	if settings.Interpolation == "" {
+		settings.Interpolation = ffmpeg.InterpolationBlend
+	}
+	if !ffmpeg.ValidInterpolation(settings.Interpolation) {
+		return FrameRateSettings{}, fmt.Errorf("unknown interpolation %q", cfg.Interpolation)
+	}
+	if settings.MaxStretch == 0 {
+		settings.MaxStretch = 2
+	}
+	if settings.MaxStretch < 1 {
+		return FrameRateSettings{}, fmt.Errorf("invalid max stretch %v", cfg.MaxStretch)
+	}
+
+	return settings, nil
+}
+
+// FrameRateSelection 合成时指定的帧率：FPS 为空时依次使用章节时间线、第一个输出规格和配置的帧率；
+// Interpolation 和 Stretch 为空时使用配置的默认值
+type FrameRateSelection struct {
+	FPS           int    `json:"fps"`
+	Interpolation string `json:"interpolation"`
+	Stretch       *bool  `json:"stretch"`
+}
+
+// resolve 解析合成的目标帧率，关闭统一帧率且请求未指定帧率时返回 nil
+func (f FrameRateSettings) resolve(db *gorm.DB, episodeID uint, selection FrameRateSelection, outputs *OutputSettings) (*ffmpeg.FrameRate, error) {
+	if selection.FPS < 0 || selection.FPS > maxFrameRate {
+		return nil, fmt.Errorf("invalid fps %d", selection.FPS)
+	}
+	interpolation := strings.ToLower(selection.Interpolation)
+	if interpolation != "" && !ffmpeg.ValidInterpolation(interpolation) {
+		return nil, fmt.Errorf("unknown interpolation %q, available: %s, %s, %s", selection.Interpolation,
+			ffmpeg.InterpolationMotion, ffmpeg.InterpolationBlend, ffmpeg.InterpolationDrop)
+	}
+	if !f.Enabled && selection.FPS == 0 {
+		return nil, nil
+	}
+
+	fps := selection.FPS
+	if fps == 0 {
+		var timeline models.Timeline
+		db.Select("fps").Where("episode_id = ? AND fps > 0", episodeID).Order("updated_at DESC").Limit(1).Find(&timeline)
+		fps = timeline.FPS
+	}
+	if fps == 0 && outputs != nil && len(outputs.Profiles) > 0 {
+		fps = outputs.Profiles[0].FPS
+	}
+	if fps == 0 {
+		fps = f.FPS
+	}
+
+	rate := &ffmpeg.FrameRate{FPS: fps, Interpolation: interpolation}
+	if rate.Interpolation == "" {
+		rate.Interpolation = f.Interpolation
+	}
+	stretch := f.Stretch
+	if selection.Stretch != nil {
+		stretch = *selection.Stretch
+	}
+	if stretch {
+		rate.MaxStretch = f.MaxStretch
+	}
+	return rate, nil
+}
+
+// mergeFrameRate 合成记录中保存的统一帧率，未设置时返回 nil
+func mergeFrameRate(videoMerge *models.VideoMerge) (*ffmpeg.FrameRate, error) {
+	if len(videoMerge.FrameRate) == 0 {
+		return nil, nil
+	}
+	var rate ffmpeg.FrameRate
+	if err := json.Unmarshal(videoMerge.FrameRate, &rate); err != nil {
+		return nil, fmt.Errorf("failed to parse frame rate: %w", err)
+	}
+	return &rate, nil
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
@@ -29,6 +30,33 @@ type ImageGenerationService struct {
 	config          *config.Config
 	promptI18n      *PromptI18n
 	taskService     *TaskService
+	jobQueue        *JobQueue
+	eventBus        *EventBus
+	settings        *Settings
+}
+
+// 图片异步任务轮询参数
+const (
+	imagePollInterval = 5 * time.Second
+	imagePollTimeout  = 5 * time.Minute
+)
+
+// imageGenerationPayload 图片生成任务参数
+type imageGenerationPayload struct {
+	ImageGenID uint `json:"image_gen_id"`
+}
+
+// imageStatusPollPayload 图片异步任务状态轮询参数
+type imageStatusPollPayload struct {
+	ImageGenID uint   `json:"image_gen_id"`
+	TaskID     string `json:"task_id"`
+}
+
+// backgroundExtractionPayload 场景提取任务参数
+type backgroundExtractionPayload struct {
+	EpisodeID string `json:"episode_id"`
+	Model     string `json:"model"`
+	Style     string `json:"style"`
 }
 
 // truncateImageURL 截断图片 URL，避免 base64 格式的 URL 占满日志
@@ -49,17 +77,30 @@ func truncateImageURL(url string) string {
 	return url
 }
 
-func NewImageGenerationService(db *gorm.DB, cfg *config.Config, transferService *ResourceTransferService, localStorage *storage.LocalStorage, log *logger.Logger) *ImageGenerationService {
-	return &ImageGenerationService{
+func NewImageGenerationService(db *gorm.DB, jobQueue *JobQueue, eventBus *EventBus, taskService *TaskService, settings *Settings, aiService *AIService, cfg *config.Config, transferService *ResourceTransferService, localStorage *storage.LocalStorage, log *logger.Logger) *ImageGenerationService {
+	service := &ImageGenerationService{
 		db:              db,
-		aiService:       NewAIService(db, log),
+		aiService:       aiService,
 		transferService: transferService,
 		localStorage:    localStorage,
 		config:          cfg,
 		promptI18n:      NewPromptI18n(cfg),
 		log:             log,
-		taskService:     NewTaskService(db, log),
+		taskService:     taskService,
+		jobQueue:        jobQueue,
+		eventBus:        eventBus,
+		settings:        settings,
 	}
+
+	service.jobQueue.RegisterHandler("image_generation", service.handleImageGenerationJob)
+	service.jobQueue.RegisterHandler("image_status_poll", service.handleImageStatusPollJob)
+	service.jobQueue.RegisterHandler("background_extraction", service.handleBackgroundExtractionJob)
+	service.jobQueue.RegisterCanceler("image_generation", service.handleImageJobCancelled)
+	service.jobQueue.RegisterCanceler("image_status_poll", service.handleImageJobCancelled)
+	service.jobQueue.RegisterInterruptHandler("image_generation", service.handleImageJobInterrupted)
+	service.jobQueue.RegisterStartupRecoverer("image_generations", service.RecoverPendingTasks)
+
+	return service
 }
 
 // GetDB 获取数据库连接
@@ -92,6 +133,11 @@ type GenerateImageRequest struct {
 }
 
 func (s *ImageGenerationService) GenerateImage(request *GenerateImageRequest) (*models.ImageGeneration, error) {
+	return s.generateImage(request, JobOptions{Priority: JobPriorityInteractive})
+}
+
+// generateImage 创建图片生成记录并加入 image 工作池，opts 只需指定优先级和父任务
+func (s *ImageGenerationService) generateImage(request *GenerateImageRequest, opts JobOptions) (*models.ImageGeneration, error) {
 	var drama models.Drama
 	if err := s.db.Where("id = ? ", request.DramaID).First(&drama).Error; err != nil {
 		return nil, fmt.Errorf("drama not found")
@@ -150,18 +196,53 @@ func (s *ImageGenerationService) GenerateImage(request *GenerateImageRequest) (*
 		return nil, fmt.Errorf("failed to create record: %w", err)
 	}
 
-	go s.ProcessImageGeneration(imageGen.ID)
+	if err := s.enqueueImageGeneration(imageGen, opts); err != nil {
+		s.updateImageGenError(imageGen.ID, err.Error())
+		return nil, err
+	}
 
 	return imageGen, nil
 }
 
-func (s *ImageGenerationService) ProcessImageGeneration(imageGenID uint) {
+// enqueueImageGeneration 将图片生成记录加入 image 工作池
+func (s *ImageGenerationService) enqueueImageGeneration(imageGen *models.ImageGeneration, opts JobOptions) error {
+	opts.Queue = JobQueueImage
+	opts.Provider = s.aiService.ResolveProvider("image", imageGen.Model)
+	opts.DramaID = imageGen.DramaID
+	opts.Payload = imageGenerationPayload{ImageGenID: imageGen.ID}
+	_, err := s.jobQueue.Enqueue("image_generation", fmt.Sprintf("%d", imageGen.ID), opts)
+	return err
+}
+
+// handleImageGenerationJob 任务队列处理函数
+func (s *ImageGenerationService) handleImageGenerationJob(ctx context.Context, task *models.AsyncTask) error {
+	var payload imageGenerationPayload
+	if err := decodeJobPayload(task, &payload); err != nil {
+		return err
+	}
+	s.ProcessImageGeneration(ctx, payload.ImageGenID)
+	return ctx.Err()
+}
+
+func (s *ImageGenerationService) ProcessImageGeneration(ctx context.Context, imageGenID uint) {
 	var imageGen models.ImageGeneration
 	imageRatio := "16:9"
 	if err := s.db.First(&imageGen, imageGenID).Error; err != nil {
 		s.log.Errorw("Failed to load image generation", "error", err, "id", imageGenID)
 		return
 	}
+	switch imageGen.Status {
+	case models.ImageStatusCancelled, models.ImageStatusCompleted, models.ImageStatusFailed:
+		s.log.Infow("Image generation already finished, skipping", "id", imageGenID, "status", imageGen.Status)
+		return
+	case models.ImageStatusProcessing:
+		// 中断前已提交到厂商：继续轮询远程任务，不重复提交
+		if imageGen.TaskID != nil && *imageGen.TaskID != "" {
+			s.log.Infow("Resuming image status polling", "id", imageGenID, "task_id", *imageGen.TaskID)
+			s.enqueueImageStatusPoll(&imageGen, *imageGen.TaskID)
+			return
+		}
+	}
 
 	// 获取drama的style信息
 	var drama models.Drama
@@ -170,6 +251,7 @@ func (s *ImageGenerationService) ProcessImageGeneration(imageGenID uint) {
 	}
 
 	s.db.Model(&imageGen).Update("status", models.ImageStatusProcessing)
+	s.publishImageEvent(imageGenID, EventImageProcessing)
 
 	// 如果关联了background，同步更新background为generating状态
 	if imageGen.StoryboardID != nil {
@@ -180,13 +262,6 @@ func (s *ImageGenerationService) ProcessImageGeneration(imageGenID uint) {
 		}
 	}
 
-	client, err := s.getImageClientWithModel(imageGen.Provider, imageGen.Model)
-	if err != nil {
-		s.log.Errorw("Failed to get image client", "error", err, "provider", imageGen.Provider, "model", imageGen.Model)
-		s.updateImageGenError(imageGenID, err.Error())
-		return
-	}
-
 	// 解析参考图片
 	var referenceImagePaths []string
 	if len(imageGen.ReferenceImages) > 0 {
@@ -251,9 +326,6 @@ func (s *ImageGenerationService) ProcessImageGeneration(imageGenID uint) {
 	if imageGen.Seed != nil {
 		opts = append(opts, image.WithSeed(*imageGen.Seed))
 	}
-	if imageGen.Model != "" {
-		opts = append(opts, image.WithModel(imageGen.Model))
-	}
 	if imageGen.Width != nil && imageGen.Height != nil {
 		opts = append(opts, image.WithDimensions(*imageGen.Width, *imageGen.Height))
 	}
@@ -287,52 +359,111 @@ func (s *ImageGenerationService) ProcessImageGeneration(imageGenID uint) {
 			"id", imageGenID,
 			"reference_count", len(referenceImages))
 	}
-	result, err := client.GenerateImage(prompt, opts...)
+	// 按优先级依次尝试激活的图片配置，临时错误自动重试
+	var result *image.ImageResult
+	var servedModel string
+	config, err := s.aiService.ExecuteWithFailover(ctx, "image", imageGen.Model, func(ctx context.Context, config *models.AIServiceConfig, model string) error {
+		servedModel = model
+		client := newImageClient(config, model, imageGen.Provider)
+		callOpts := append([]image.ImageOption{}, opts...)
+		if model != "" {
+			callOpts = append(callOpts, image.WithModel(model))
+		}
+		var genErr error
+		result, genErr = client.GenerateImage(prompt, callOpts...)
+		return genErr
+	})
 	if err != nil {
+		// 任务被暂停、取消或队列停止：记录状态由对应操作处理，不标记为失败
+		if ctx.Err() != nil {
+			s.log.Infow("Generation interrupted", "id", imageGenID, "error", err)
+			return
+		}
 		s.log.Errorw("Image generation API call failed", "error", err, "id", imageGenID, "prompt", imageGen.Prompt)
 		s.updateImageGenError(imageGenID, err.Error())
 		return
 	}
+	s.recordServedConfig(&imageGen, config, servedModel)
 
 	s.log.Infow("Image generation API call completed", "id", imageGenID, "completed", result.Completed, "has_url", result.ImageURL != "")
 
 	if !result.Completed {
-		s.db.Model(&imageGen).Updates(map[string]interface{}{
-			"status":  models.ImageStatusProcessing,
-			"task_id": result.TaskID,
-		})
-		go s.pollTaskStatus(imageGenID, client, result.TaskID)
+		updated := s.db.Model(&models.ImageGeneration{}).
+			Where("id = ? AND status <> ?", imageGenID, models.ImageStatusCancelled).
+			Updates(map[string]interface{}{
+				"status":  models.ImageStatusProcessing,
+				"task_id": result.TaskID,
+			})
+		if updated.Error == nil && updated.RowsAffected == 0 {
+			s.log.Infow("Image generation cancelled during submission", "id", imageGenID, "task_id", result.TaskID)
+			return
+		}
+		s.enqueueImageStatusPoll(&imageGen, result.TaskID)
 		return
 	}
 
 	s.completeImageGeneration(imageGenID, result)
 }
 
-func (s *ImageGenerationService) pollTaskStatus(imageGenID uint, client image.ImageClient, taskID string) {
-	maxAttempts := 60
-	pollInterval := 5 * time.Second
+// enqueueImageStatusPoll 创建轮询任务，轮询不占用生成任务的执行槽位
+func (s *ImageGenerationService) enqueueImageStatusPoll(imageGen *models.ImageGeneration, taskID string) {
+	_, err := s.jobQueue.Enqueue("image_status_poll", fmt.Sprintf("%d", imageGen.ID), JobOptions{
+		Queue:    JobQueueDefault,
+		Priority: JobPriorityInteractive,
+		DramaID:  imageGen.DramaID,
+		Delay:    imagePollInterval,
+		Payload: imageStatusPollPayload{
+			ImageGenID: imageGen.ID,
+			TaskID:     taskID,
+		},
+	})
+	if err != nil {
+		s.log.Errorw("Failed to enqueue image status poll", "error", err, "id", imageGen.ID, "task_id", taskID)
+		s.updateImageGenError(imageGen.ID, err.Error())
+	}
+}
 
-	for i := 0; i < maxAttempts; i++ {
-		time.Sleep(pollInterval)
+// handleImageStatusPollJob 查询一次远程任务状态，未完成时重新排队
+func (s *ImageGenerationService) handleImageStatusPollJob(ctx context.Context, task *models.AsyncTask) error {
+	var payload imageStatusPollPayload
+	if err := decodeJobPayload(task, &payload); err != nil {
+		return err
+	}
 
-		result, err := client.GetTaskStatus(taskID)
-		if err != nil {
-			s.log.Errorw("Failed to get task status", "error", err, "task_id", taskID)
-			continue
-		}
+	if time.Since(task.CreatedAt) > imagePollTimeout {
+		s.updateImageGenError(payload.ImageGenID, "timeout: image generation took too long")
+		return nil
+	}
 
-		if result.Completed {
-			s.completeImageGeneration(imageGenID, result)
-			return
-		}
+	var imageGen models.ImageGeneration
+	if err := s.db.First(&imageGen, payload.ImageGenID).Error; err != nil {
+		return fmt.Errorf("load image generation: %w", err)
+	}
 
-		if result.Error != "" {
-			s.updateImageGenError(imageGenID, result.Error)
-			return
-		}
+	// 远程任务必须向提交它的配置查询
+	client, err := s.getImageClientForGeneration(&imageGen)
+	if err != nil {
+		s.updateImageGenError(payload.ImageGenID, err.Error())
+		return nil
+	}
+
+	result, err := client.GetTaskStatus(payload.TaskID)
+	if err != nil {
+		s.log.Errorw("Failed to get task status", "error", err, "task_id", payload.TaskID)
+		return RescheduleJob(imagePollInterval)
 	}
 
-	s.updateImageGenError(imageGenID, "timeout: image generation took too long")
+	if result.Completed {
+		s.completeImageGeneration(payload.ImageGenID, result)
+		return nil
+	}
+
+	if result.Error != "" {
+		s.updateImageGenError(payload.ImageGenID, result.Error)
+		return nil
+	}
+
+	return RescheduleJob(imagePollInterval)
 }
 
 func (s *ImageGenerationService) completeImageGeneration(imageGenID uint, result *image.ImageResult) {
@@ -382,6 +513,10 @@ func (s *ImageGenerationService) completeImageGeneration(imageGenID uint, result
 		s.log.Errorw("Failed to load image generation", "error", err, "id", imageGenID)
 		return
 	}
+	if imageGen.Status == models.ImageStatusCancelled {
+		s.log.Infow("Image generation cancelled, discarding result", "id", imageGenID)
+		return
+	}
 
 	// 使用 Updates 更新基本字段
 	if err := s.db.Model(&models.ImageGeneration{}).Where("id = ?", imageGenID).Updates(updates).Error; err != nil {
@@ -395,6 +530,9 @@ func (s *ImageGenerationService) completeImageGeneration(imageGenID uint, result
 	}
 
 	s.log.Infow("Image generation completed", "id", imageGenID)
+	s.publishImageEvent(imageGenID, EventImageCompleted)
+	// 检查纯色画面和尺寸
+	scheduleImageQC(s.jobQueue, s.settings.QC, s.log, &imageGen)
 
 	// 如果关联了storyboard，同步更新storyboard的composed_image
 	if imageGen.StoryboardID != nil {
@@ -470,6 +608,9 @@ func (s *ImageGenerationService) updateImageGenError(imageGenID uint, errorMsg s
 		s.log.Errorw("Failed to load image generation", "error", err, "id", imageGenID)
 		return
 	}
+	if imageGen.Status == models.ImageStatusCancelled {
+		return
+	}
 
 	// 更新image_generation状态
 	s.db.Model(&models.ImageGeneration{}).Where("id = ?", imageGenID).Updates(map[string]interface{}{
@@ -477,6 +618,7 @@ func (s *ImageGenerationService) updateImageGenError(imageGenID uint, errorMsg s
 		"error_msg": errorMsg,
 	})
 	s.log.Errorw("Image generation failed", "id", imageGenID, "error", errorMsg)
+	s.publishImageEvent(imageGenID, EventImageFailed)
 
 	// 如果关联了scene，同步更新scene为失败状态
 	if imageGen.SceneID != nil {
@@ -485,6 +627,30 @@ func (s *ImageGenerationService) updateImageGenError(imageGenID uint, errorMsg s
 	}
 }
 
+// publishImageEvent 发布图片生成状态变更事件
+func (s *ImageGenerationService) publishImageEvent(imageGenID uint, eventType string) {
+	var imageGen models.ImageGeneration
+	if err := s.db.First(&imageGen, imageGenID).Error; err != nil {
+		return
+	}
+
+	data := map[string]interface{}{
+		"id":            imageGen.ID,
+		"status":        imageGen.Status,
+		"image_type":    imageGen.ImageType,
+		"frame_type":    imageGen.FrameType,
+		"storyboard_id": imageGen.StoryboardID,
+		"scene_id":      imageGen.SceneID,
+		"character_id":  imageGen.CharacterID,
+		"prop_id":       imageGen.PropID,
+		"image_url":     imageGen.ImageURL,
+		"local_path":    imageGen.LocalPath,
+		"error_msg":     imageGen.ErrorMsg,
+	}
+	s.eventBus.Publish(eventType, imageGen.DramaID, data)
+	dispatchWebhookEvent(s.db, s.jobQueue, s.log, eventType, imageGen.DramaID, data)
+}
+
 func (s *ImageGenerationService) getImageClient(provider string) (image.ImageClient, error) {
 	config, err := s.aiService.GetDefaultConfig("image")
 	if err != nil {
@@ -497,34 +663,7 @@ func (s *ImageGenerationService) getImageClient(provider string) (image.ImageCli
 		model = config.Model[0]
 	}
 
-	// 使用配置中的 provider，如果没有则使用传入的 provider
-	actualProvider := config.Provider
-	if actualProvider == "" {
-		actualProvider = provider
-	}
-
-	// 根据 provider 自动设置默认端点
-	var endpoint string
-	var queryEndpoint string
-
-	switch actualProvider {
-	case "openai", "dalle":
-		endpoint = "/images/generations"
-		return image.NewOpenAIImageClient(config.BaseURL, config.APIKey, model, endpoint), nil
-	case "chatfire":
-		endpoint = "/images/generations"
-		return image.NewOpenAIImageClient(config.BaseURL, config.APIKey, model, endpoint), nil
-	case "volcengine", "volces", "doubao":
-		endpoint = "/images/generations"
-		queryEndpoint = ""
-		return image.NewVolcEngineImageClient(config.BaseURL, config.APIKey, model, endpoint, queryEndpoint), nil
-	case "gemini", "google":
-		endpoint = "/v1beta/models/{model}:generateContent"
-		return image.NewGeminiImageClient(config.BaseURL, config.APIKey, model, endpoint), nil
-	default:
-		endpoint = "/images/generations"
-		return image.NewOpenAIImageClient(config.BaseURL, config.APIKey, model, endpoint), nil
-	}
+	return newImageClient(config, model, provider), nil
 }
 
 // getImageClientWithModel 根据模型名称获取图片客户端
@@ -555,6 +694,40 @@ func (s *ImageGenerationService) getImageClientWithModel(provider string, modelN
 		model = config.Model[0]
 	}
 
+	return newImageClient(config, model, provider), nil
+}
+
+// getImageClientForGeneration 获取提交该图片生成任务时实际使用的客户端
+func (s *ImageGenerationService) getImageClientForGeneration(imageGen *models.ImageGeneration) (image.ImageClient, error) {
+	if imageGen.ServiceConfigID != nil {
+		config, err := s.aiService.GetConfig(*imageGen.ServiceConfigID)
+		if err == nil {
+			return newImageClient(config, imageGen.Model, imageGen.Provider), nil
+		}
+		s.log.Warnw("Served config not found, resolving by model", "config_id", *imageGen.ServiceConfigID, "error", err)
+	}
+	return s.getImageClientWithModel(imageGen.Provider, imageGen.Model)
+}
+
+// recordServedConfig 记录实际完成本次生成的配置、厂商和模型
+func (s *ImageGenerationService) recordServedConfig(imageGen *models.ImageGeneration, config *models.AIServiceConfig, model string) {
+	imageGen.ServiceConfigID = &config.ID
+	imageGen.Model = model
+	if config.Provider != "" {
+		imageGen.Provider = config.Provider
+	}
+
+	if err := s.db.Model(&models.ImageGeneration{}).Where("id = ?", imageGen.ID).Updates(map[string]interface{}{
+		"service_config_id": config.ID,
+		"provider":          imageGen.Provider,
+		"model":             imageGen.Model,
+	}).Error; err != nil {
+		s.log.Warnw("Failed to record served config", "error", err, "id", imageGen.ID, "config_id", config.ID)
+	}
+}
+
+// newImageClient 根据配置创建图片客户端，配置未设置厂商时使用 provider
+func newImageClient(config *models.AIServiceConfig, model string, provider string) image.ImageClient {
 	// 使用配置中的 provider，如果没有则使用传入的 provider
 	actualProvider := config.Provider
 	if actualProvider == "" {
@@ -568,20 +741,20 @@ func (s *ImageGenerationService) getImageClientWithModel(provider string, modelN
 	switch actualProvider {
 	case "openai", "dalle":
 		endpoint = "/images/generations"
-		return image.NewOpenAIImageClient(config.BaseURL, config.APIKey, model, endpoint), nil
+		return image.NewOpenAIImageClient(config.BaseURL, config.APIKey, model, endpoint)
 	case "chatfire":
 		endpoint = "/images/generations"
-		return image.NewOpenAIImageClient(config.BaseURL, config.APIKey, model, endpoint), nil
+		return image.NewOpenAIImageClient(config.BaseURL, config.APIKey, model, endpoint)
 	case "volcengine", "volces", "doubao":
 		endpoint = "/images/generations"
 		queryEndpoint = ""
-		return image.NewVolcEngineImageClient(config.BaseURL, config.APIKey, model, endpoint, queryEndpoint), nil
+		return image.NewVolcEngineImageClient(config.BaseURL, config.APIKey, model, endpoint, queryEndpoint)
 	case "gemini", "google":
 		endpoint = "/v1beta/models/{model}:generateContent"
-		return image.NewGeminiImageClient(config.BaseURL, config.APIKey, model, endpoint), nil
+		return image.NewGeminiImageClient(config.BaseURL, config.APIKey, model, endpoint)
 	default:
 		endpoint = "/images/generations"
-		return image.NewOpenAIImageClient(config.BaseURL, config.APIKey, model, endpoint), nil
+		return image.NewOpenAIImageClient(config.BaseURL, config.APIKey, model, endpoint)
 	}
 }
 
@@ -630,6 +803,129 @@ func (s *ImageGenerationService) ListImageGenerations(dramaID *uint, sceneID *ui
 	return images, total, nil
 }
 
+// CancelImageGeneration 取消图片生成
+// 图片厂商均未提供取消接口，已提交的远程任务会继续执行，但结果不会再写回
+func (s *ImageGenerationService) CancelImageGeneration(imageGenID uint) error {
+	var imageGen models.ImageGeneration
+	if err := s.db.First(&imageGen, imageGenID).Error; err != nil {
+		return fmt.Errorf("image generation not found")
+	}
+	if imageGen.Status != models.ImageStatusPending && imageGen.Status != models.ImageStatusProcessing {
+		return fmt.Errorf("图片生成已结束，无法取消")
+	}
+
+	s.jobQueue.CancelResourceJobs(fmt.Sprintf("%d", imageGenID), "image_generation", "image_status_poll")
+	s.markImageGenCancelled(imageGenID)
+	return nil
+}
+
+// PauseEpisodeBatch 暂停剧集最近一次批量图片生成中尚未开始的任务
+func (s *ImageGenerationService) PauseEpisodeBatch(episodeID string) (*models.AsyncTask, error) {
+	batch, err := s.jobQueue.LatestParent("image_batch", episodeID, "processing")
+	if err != nil {
+		return nil, fmt.Errorf("没有进行中的批量任务")
+	}
+	if err := s.jobQueue.Pause(batch.ID); err != nil {
+		return nil, err
+	}
+	return batch, nil
+}
+
+// ResumeEpisodeBatch 恢复剧集已暂停的批量图片生成
+func (s *ImageGenerationService) ResumeEpisodeBatch(episodeID string) (*models.AsyncTask, error) {
+	batch, err := s.jobQueue.LatestParent("image_batch", episodeID, "paused")
+	if err != nil {
+		return nil, fmt.Errorf("没有已暂停的批量任务")
+	}
+	if err := s.jobQueue.Resume(batch.ID); err != nil {
+		return nil, err
+	}
+	return batch, nil
+}
+
+// handleImageJobCancelled 队列任务取消回调
+func (s *ImageGenerationService) handleImageJobCancelled(task *models.AsyncTask) {
+	id, err := strconv.ParseUint(task.ResourceID, 10, 32)
+	if err != nil {
+		return
+	}
+	s.markImageGenCancelled(uint(id))
+}
+
+// handleImageJobInterrupted 图片生成任务中断回调
+// 已拿到远程任务ID或尚未开始的记录可以重新执行（处理函数会续上轮询），提交中途被中断的记录无法确认厂商是否已受理，标记为失败
+func (s *ImageGenerationService) handleImageJobInterrupted(task *models.AsyncTask) bool {
+	var imageGen models.ImageGeneration
+	if err := s.db.Where("id = ?", task.ResourceID).First(&imageGen).Error; err != nil {
+		return true
+	}
+	if imageGen.Status != models.ImageStatusProcessing || (imageGen.TaskID != nil && *imageGen.TaskID != "") {
+		return true
+	}
+	s.updateImageGenError(imageGen.ID, InterruptedByRestartMessage)
+	return false
+}
+
+// RecoverPendingTasks 启动时恢复没有队列任务跟踪的图片生成记录
+// 已提交到厂商的记录继续轮询，未开始的记录重新入队，提交中途中断的记录标记为失败
+func (s *ImageGenerationService) RecoverPendingTasks() {
+	var imageGens []models.ImageGeneration
+	if err := s.db.Where("status IN ?", []models.ImageGenerationStatus{models.ImageStatusPending, models.ImageStatusProcessing}).
+		Find(&imageGens).Error; err != nil {
+		s.log.Errorw("Failed to load pending image generations", "error", err)
+		return
+	}
+
+	resumed, requeued, failed := 0, 0, 0
+	for i := range imageGens {
+		imageGen := &imageGens[i]
+		resourceID := fmt.Sprintf("%d", imageGen.ID)
+		if s.jobQueue.HasActiveJob("image_generation", resourceID) || s.jobQueue.HasActiveJob("image_status_poll", resourceID) {
+			continue
+		}
+
+		switch {
+		case imageGen.TaskID != nil && *imageGen.TaskID != "":
+			s.enqueueImageStatusPoll(imageGen, *imageGen.TaskID)
+			resumed++
+		case imageGen.Status == models.ImageStatusPending:
+			if err := s.enqueueImageGeneration(imageGen, JobOptions{Priority: JobPriorityBatch}); err != nil {
+				s.updateImageGenError(imageGen.ID, err.Error())
+				continue
+			}
+			requeued++
+		default:
+			s.updateImageGenError(imageGen.ID, InterruptedByRestartMessage)
+			failed++
+		}
+	}
+
+	if resumed+requeued+failed > 0 {
+		s.log.Infow("Recovered image generations", "resumed_polling", resumed, "requeued", requeued, "failed", failed)
+	}
+}
+
+// markImageGenCancelled 将未结束的图片生成标记为已取消
+func (s *ImageGenerationService) markImageGenCancelled(imageGenID uint) {
+	result := s.db.Model(&models.ImageGeneration{}).
+		Where("id = ? AND status IN ?", imageGenID, []models.ImageGenerationStatus{models.ImageStatusPending, models.ImageStatusProcessing}).
+		Updates(map[string]interface{}{
+			"status":    models.ImageStatusCancelled,
+			"error_msg": "cancelled by user",
+		})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return
+	}
+	s.log.Infow("Image generation cancelled", "id", imageGenID)
+	s.publishImageEvent(imageGenID, EventImageCancelled)
+
+	// 关联的场景恢复为待生成状态
+	var imageGen models.ImageGeneration
+	if err := s.db.First(&imageGen, imageGenID).Error; err == nil && imageGen.SceneID != nil {
+		s.db.Model(&models.Scene{}).Where("id = ? AND status = ?", *imageGen.SceneID, "generating").Update("status", "pending")
+	}
+}
+
 func (s *ImageGenerationService) DeleteImageGeneration(imageGenID uint) error {
 	result := s.db.Where("id = ? ", imageGenID).Delete(&models.ImageGeneration{})
 	if result.Error != nil {
@@ -742,14 +1038,29 @@ type BackgroundInfo struct {
 }
 
 func (s *ImageGenerationService) BatchGenerateImagesForEpisode(episodeID string) ([]*models.ImageGeneration, error) {
+	_, results, err := s.BatchGenerateImagesForEpisodeWithOptions(episodeID, EpisodeBatchOptions{})
+	return results, err
+}
+
+// EpisodeBatchOptions 整集批量生成参数
+type EpisodeBatchOptions struct {
+	Model          string // 指定模型，为空时使用默认配置
+	Provider       string // 指定厂商
+	OnlyMissing    bool   // 只为还没有已完成结果的分镜生成
+	UseFramePrompt bool   // 图片生成优先使用已生成的首帧提示词
+	Chained        bool   // 视频按分镜顺序链式生成，上一个分镜的尾帧作为下一个分镜的首帧
+}
+
+// BatchGenerateImagesForEpisodeWithOptions 按参数为整集分镜批量生成图片，返回批量父任务
+func (s *ImageGenerationService) BatchGenerateImagesForEpisodeWithOptions(episodeID string, opts EpisodeBatchOptions) (*models.AsyncTask, []*models.ImageGeneration, error) {
 	var ep models.Episode
 	if err := s.db.Preload("Drama").Where("id = ?", episodeID).First(&ep).Error; err != nil {
-		return nil, fmt.Errorf("episode not found")
+		return nil, nil, fmt.Errorf("episode not found")
 	}
 	// 从数据库读取已保存的场景
 	var scenes []models.Storyboard
 	if err := s.db.Where("episode_id = ?", episodeID).Find(&scenes).Error; err != nil {
-		return nil, fmt.Errorf("failed to get scenes: %w", err)
+		return nil, nil, fmt.Errorf("failed to get scenes: %w", err)
 	}
 
 	backgrounds := s.extractUniqueBackgrounds(scenes)
@@ -757,6 +1068,13 @@ func (s *ImageGenerationService) BatchGenerateImagesForEpisode(episodeID string)
 		"episode_id", episodeID,
 		"background_count", len(backgrounds))
 
+	// 整集批量任务挂在同一个父任务下，便于整体暂停/恢复/取消
+	batch, err := s.jobQueue.CreateParent("image_batch", episodeID, ep.DramaID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer s.jobQueue.refreshParent(batch.ID)
+
 	// 为每个背景生成图片
 	var results []*models.ImageGeneration
 	for _, bg := range scenes {
@@ -765,16 +1083,44 @@ func (s *ImageGenerationService) BatchGenerateImagesForEpisode(episodeID string)
 			continue
 		}
 
+		// 已生成首帧提示词时优先使用，生成的图片标记为首帧
+		prompt := *bg.ImagePrompt
+		var frameType *string
+		if opts.UseFramePrompt {
+			var framePrompt models.FramePrompt
+			if err := s.db.Where("storyboard_id = ? AND frame_type = ?", bg.ID, models.FrameTypeFirst).
+				Order("updated_at DESC").First(&framePrompt).Error; err == nil && framePrompt.Prompt != "" {
+				prompt = framePrompt.Prompt
+				first := models.FrameTypeFirst
+				frameType = &first
+			}
+		}
+
+		if opts.OnlyMissing {
+			var count int64
+			s.db.Model(&models.ImageGeneration{}).
+				Where("storyboard_id = ? AND status IN ?", bg.ID, []models.ImageGenerationStatus{
+					models.ImageStatusPending, models.ImageStatusProcessing, models.ImageStatusCompleted,
+				}).
+				Count(&count)
+			if count > 0 {
+				continue
+			}
+		}
+
 		// 更新背景状态为处理中
 		s.db.Model(bg).Update("status", "generating")
 
 		req := &GenerateImageRequest{
 			StoryboardID: &bg.ID,
 			DramaID:      fmt.Sprintf("%d", ep.DramaID),
-			Prompt:       *bg.ImagePrompt,
+			FrameType:    frameType,
+			Prompt:       prompt,
+			Provider:     opts.Provider,
+			Model:        opts.Model,
 		}
 
-		imageGen, err := s.GenerateImage(req)
+		imageGen, err := s.generateImage(req, JobOptions{Priority: JobPriorityBatch, ParentID: batch.ID})
 		if err != nil {
 			s.log.Errorw("Failed to generate image for background",
 				"scene_id", bg.ID,
@@ -793,7 +1139,7 @@ func (s *ImageGenerationService) BatchGenerateImagesForEpisode(episodeID string)
 		results = append(results, imageGen)
 	}
 
-	return results, nil
+	return batch, results, nil
 }
 
 // GetScencesForEpisode 获取项目的场景列表（项目级）
@@ -824,20 +1170,33 @@ func (s *ImageGenerationService) ExtractBackgroundsForEpisode(episodeID string,
 		return "", fmt.Errorf("episode has no script content")
 	}
 
-	// 创建任务
-	task, err := s.taskService.CreateTask("background_extraction", episodeID)
+	// 创建排队任务，异步处理场景提取
+	task, err := s.jobQueue.Enqueue("background_extraction", episodeID, JobOptions{
+		Queue:    JobQueueText,
+		Provider: s.aiService.ResolveProvider("text", model),
+		Priority: JobPriorityInteractive,
+		DramaID:  episode.DramaID,
+		Payload:  backgroundExtractionPayload{EpisodeID: episodeID, Model: model, Style: style},
+	})
 	if err != nil {
 		s.log.Errorw("Failed to create background extraction task", "error", err, "episode_id", episodeID)
 		return "", fmt.Errorf("创建任务失败: %w", err)
 	}
 
-	// 异步处理场景提取
-	go s.processBackgroundExtraction(task.ID, episodeID, model, style)
-
 	s.log.Infow("Background extraction task created", "task_id", task.ID, "episode_id", episodeID)
 	return task.ID, nil
 }
 
+// handleBackgroundExtractionJob 任务队列处理函数
+func (s *ImageGenerationService) handleBackgroundExtractionJob(ctx context.Context, task *models.AsyncTask) error {
+	var payload backgroundExtractionPayload
+	if err := decodeJobPayload(task, &payload); err != nil {
+		return err
+	}
+	s.processBackgroundExtraction(task.ID, payload.EpisodeID, payload.Model, payload.Style)
+	return nil
+}
+
 // processBackgroundExtraction 异步处理场景提取
 func (s *ImageGenerationService) processBackgroundExtraction(taskID string, episodeID string, model string, style string) {
 	// 更新任务状态为处理中
@@ -933,21 +1292,8 @@ func (s *ImageGenerationService) extractBackgroundsFromScript(scriptContent stri
 		return []BackgroundInfo{}, nil
 	}
 
-	// 获取AI客户端（如果指定了模型则使用指定的模型）
-	var client ai.AIClient
-	var err error
 	if model != "" {
 		s.log.Infow("Using specified model for background extraction", "model", model)
-		client, err = s.aiService.GetAIClientForModel("text", model)
-		if err != nil {
-			s.log.Warnw("Failed to get client for specified model, using default", "model", model, "error", err)
-			client, err = s.aiService.GetAIClient("text")
-		}
-	} else {
-		client, err = s.aiService.GetAIClient("text")
-	}
-	if err != nil {
-		return nil, fmt.Errorf("failed to get AI client: %w", err)
 	}
 
 	// 使用国际化提示词
@@ -1047,7 +1393,7 @@ Please strictly follow the JSON format and ensure all fields use English.`
 		"prompt_length", len(prompt),
 		"full_prompt", prompt)
 
-	response, err := client.GenerateText(prompt, "", ai.WithTemperature(0.7))
+	response, err := s.aiService.GenerateTextWithModel(model, prompt, "", ai.WithTemperature(0.7))
 	if err != nil {
 		s.log.Errorw("Failed to extract backgrounds with AI", "error", err)
 		return nil, fmt.Errorf("AI提取场景失败: %w", err)
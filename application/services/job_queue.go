@@ -0,0 +1,830 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/drama-generator/backend/domain/models"
+	"github.com/drama-generator/backend/pkg/config"
+	"github.com/drama-generator/backend/pkg/logger"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 任务优先级：交互式的单次请求优先于整集批量任务
+const (
+	JobPriorityBatch       = 0
+	JobPriorityInteractive = 10
+)
+
+// 工作池名称，每个工作池有独立的并发上限
+const (
+	JobQueueText    = "text"
+	JobQueueImage   = "image"
+	JobQueueVideo   = "video"
+	JobQueueAudio   = "audio"
+	JobQueueFFmpeg  = "ffmpeg"
+	JobQueueDefault = "default"
+
+	// JobQueueBatch 批量父任务，不参与调度，只用于汇总子任务进度和整体暂停/取消
+	JobQueueBatch = "batch"
+)
+
+// JobHandler 队列任务处理函数
+// 返回 nil 表示执行结束（若处理函数未自行写入终态，队列会将任务标记为完成）
+// 返回 RescheduleJob 表示稍后再次执行，返回其他错误会按退避策略重试
+type JobHandler func(ctx context.Context, task *models.AsyncTask) error
+
+// JobCanceler 任务被取消时的回调，用于同步取消关联的业务记录和远程任务
+type JobCanceler func(task *models.AsyncTask)
+
+// JobOptions 入队参数
+type JobOptions struct {
+	Queue       string        // 工作池，默认 default
+	Provider    string        // AI厂商，用于按厂商限制并发
+	ParentID    string        // 父任务ID
+	DramaID     uint          // 所属剧目，用于事件推送
+	Priority    int           // 优先级
+	MaxAttempts int           // 最大执行次数，默认使用配置
+	Delay       time.Duration // 延迟执行
+	Payload     interface{}   // 任务参数，序列化为JSON保存
+}
+
+// jobRescheduleError 表示任务需要在指定时间后再次执行，不计入失败次数
+type jobRescheduleError struct {
+	after time.Duration
+}
+
+func (e *jobRescheduleError) Error() string {
+	return fmt.Sprintf("job rescheduled after %s", e.after)
+}
+
+// RescheduleJob 供轮询类任务使用：本次未完成，after 之后再次执行
+func RescheduleJob(after time.Duration) error {
+	return &jobRescheduleError{after: after}
+}
+
+// JobQueue 基于 async_tasks 表的持久化任务队列
+// 任务按优先级领取，按工作池/厂商限制并发，通过租约+心跳保证崩溃后的任务会被重新执行
+type JobQueue struct {
+	db       *gorm.DB
+	eventBus *EventBus
+	log      *logger.Logger
+	cfg      config.QueueConfig
+	workerID string
+
+	mu        sync.Mutex
+	handlers  map[string]JobHandler
+	cancelers map[string]JobCanceler
+	running   map[string]int
+	inflight  map[string]context.CancelFunc
+	started   bool
+
+	interruptHandlers map[string]JobInterruptHandler
+	recoverers        []startupRecoverer
+
+	wake chan struct{}
+	stop chan struct{}
+}
+
+// NewJobQueue 创建任务队列，启动时创建一个并传入各业务服务，由各服务注册任务处理函数
+func NewJobQueue(db *gorm.DB, eventBus *EventBus, cfg config.QueueConfig, log *logger.Logger) *JobQueue {
+	hostname, _ := os.Hostname()
+	return &JobQueue{
+		db:        db,
+		eventBus:  eventBus,
+		log:       log,
+		cfg:       cfg,
+		workerID:  fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), uuid.New().String()[:8]),
+		handlers:  make(map[string]JobHandler),
+		cancelers: make(map[string]JobCanceler),
+		running:   make(map[string]int),
+		inflight:  make(map[string]context.CancelFunc),
+		wake:      make(chan struct{}, 1),
+
+		interruptHandlers: make(map[string]JobInterruptHandler),
+	}
+}
+
+// RegisterHandler 注册任务类型的处理函数，每种任务类型只能由一个服务注册，重复注册说明服务被创建了多次，直接 panic
+func (q *JobQueue) RegisterHandler(taskType string, handler JobHandler) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, ok := q.handlers[taskType]; ok {
+		panic(fmt.Sprintf("job handler already registered for task type: %s", taskType))
+	}
+	q.handlers[taskType] = handler
+}
+
+// RegisterCanceler 注册任务类型的取消回调，重复注册时 panic
+func (q *JobQueue) RegisterCanceler(taskType string, canceler JobCanceler) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, ok := q.cancelers[taskType]; ok {
+		panic(fmt.Sprintf("job canceler already registered for task type: %s", taskType))
+	}
+	q.cancelers[taskType] = canceler
+}
+
+// Enqueue 创建一个排队中的任务
+func (q *JobQueue) Enqueue(taskType, resourceID string, opts JobOptions) (*models.AsyncTask, error) {
+	payload := ""
+	if opts.Payload != nil {
+		data, err := json.Marshal(opts.Payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal job payload: %w", err)
+		}
+		payload = string(data)
+	}
+
+	queue := opts.Queue
+	if queue == "" {
+		queue = JobQueueDefault
+	}
+
+	maxAttempts := opts.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = q.maxAttempts()
+	}
+
+	var availableAt *time.Time
+	if opts.Delay > 0 {
+		at := time.Now().Add(opts.Delay)
+		availableAt = &at
+	}
+
+	task := &models.AsyncTask{
+		ID:          uuid.New().String(),
+		Type:        taskType,
+		Status:      "pending",
+		Progress:    0,
+		ResourceID:  resourceID,
+		Queue:       queue,
+		Provider:    opts.Provider,
+		ParentID:    opts.ParentID,
+		DramaID:     opts.DramaID,
+		Priority:    opts.Priority,
+		Payload:     payload,
+		MaxAttempts: maxAttempts,
+		AvailableAt: availableAt,
+	}
+
+	if err := q.db.Create(task).Error; err != nil {
+		return nil, fmt.Errorf("failed to create task: %w", err)
+	}
+
+	q.eventBus.publishTask(q.db, task.ID)
+	q.notify()
+	return task, nil
+}
+
+// CreateParent 创建批量父任务，子任务入队时通过 JobOptions.ParentID 关联
+func (q *JobQueue) CreateParent(taskType, resourceID string, dramaID uint) (*models.AsyncTask, error) {
+	task := &models.AsyncTask{
+		ID:         uuid.New().String(),
+		Type:       taskType,
+		Status:     "processing",
+		Progress:   0,
+		ResourceID: resourceID,
+		DramaID:    dramaID,
+		Queue:      JobQueueBatch,
+	}
+
+	if err := q.db.Create(task).Error; err != nil {
+		return nil, fmt.Errorf("failed to create task: %w", err)
+	}
+	return task, nil
+}
+
+// LatestParent 查找资源最近一次处于指定状态的批量父任务
+func (q *JobQueue) LatestParent(taskType, resourceID string, statuses ...string) (*models.AsyncTask, error) {
+	var task models.AsyncTask
+	if err := q.db.Where("type = ? AND resource_id = ? AND queue = ? AND status IN ?", taskType, resourceID, JobQueueBatch, statuses).
+		Order("created_at DESC").
+		First(&task).Error; err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// HasActiveJob 检查资源是否已有排队中或执行中的同类任务
+func (q *JobQueue) HasActiveJob(taskType, resourceID string) bool {
+	var count int64
+	q.db.Model(&models.AsyncTask{}).
+		Where("type = ? AND resource_id = ? AND status IN ?", taskType, resourceID, activeTaskStatuses).
+		Count(&count)
+	return count > 0
+}
+
+// Start 执行启动恢复，然后启动调度和心跳
+func (q *JobQueue) Start() {
+	q.mu.Lock()
+	if q.started {
+		q.mu.Unlock()
+		return
+	}
+	q.started = true
+	q.stop = make(chan struct{})
+	q.mu.Unlock()
+
+	q.recoverOnStartup()
+
+	q.log.Infow("Job queue started",
+		"worker_id", q.workerID,
+		"poll_interval", q.pollInterval(),
+		"lease", q.leaseDuration())
+
+	go q.dispatchLoop()
+	go q.heartbeatLoop()
+}
+
+// Stop 停止调度，并把本进程持有的任务释放回队列，重启后立即重新执行
+// 不可安全重跑的任务类型由其中断回调决定是否改为失败
+func (q *JobQueue) Stop() {
+	q.mu.Lock()
+	if !q.started {
+		q.mu.Unlock()
+		return
+	}
+	q.started = false
+	close(q.stop)
+
+	ids := make([]string, 0, len(q.inflight))
+	for id, cancel := range q.inflight {
+		ids = append(ids, id)
+		cancel()
+	}
+	q.mu.Unlock()
+
+	if len(ids) > 0 {
+		var tasks []models.AsyncTask
+		q.db.Where("id IN ? AND lease_owner = ? AND status = ?", ids, q.workerID, "processing").Find(&tasks)
+		for i := range tasks {
+			// 主动停止不计入执行次数
+			tasks[i].Attempts--
+			q.releaseInterrupted(&tasks[i], "服务停止，任务已重新排队")
+		}
+	}
+
+	q.log.Infow("Job queue stopped", "released", len(ids))
+}
+
+func (q *JobQueue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (q *JobQueue) dispatchLoop() {
+	ticker := time.NewTicker(q.pollInterval())
+	defer ticker.Stop()
+
+	for {
+		q.reapExpiredLeases()
+		q.dispatch()
+
+		select {
+		case <-q.stop:
+			return
+		case <-ticker.C:
+		case <-q.wake:
+		}
+	}
+}
+
+// dispatch 按优先级领取可执行的任务，受工作池和厂商并发上限约束
+func (q *JobQueue) dispatch() {
+	q.mu.Lock()
+	types := make([]string, 0, len(q.handlers))
+	for taskType := range q.handlers {
+		types = append(types, taskType)
+	}
+	q.mu.Unlock()
+
+	if len(types) == 0 {
+		return
+	}
+
+	var candidates []models.AsyncTask
+	if err := q.db.Where("status = ? AND queue <> '' AND type IN ? AND (available_at IS NULL OR available_at <= ?)",
+		"pending", types, time.Now()).
+		Order("priority DESC, created_at ASC").
+		Limit(100).
+		Find(&candidates).Error; err != nil {
+		q.log.Errorw("Failed to load pending jobs", "error", err)
+		return
+	}
+
+	for i := range candidates {
+		task := candidates[i]
+		if !q.acquireSlot(task.Queue, task.Provider) {
+			continue
+		}
+		if !q.claim(&task) {
+			q.releaseSlot(task.Queue, task.Provider)
+			continue
+		}
+		go q.run(task)
+	}
+}
+
+// claim 通过条件更新领取任务，保证同一任务只会被一个工作进程执行
+func (q *JobQueue) claim(task *models.AsyncTask) bool {
+	now := time.Now()
+	leaseExpiresAt := now.Add(q.leaseDuration())
+
+	result := q.db.Model(&models.AsyncTask{}).
+		Where("id = ? AND status = ?", task.ID, "pending").
+		Updates(map[string]interface{}{
+			"status":           "processing",
+			"lease_owner":      q.workerID,
+			"lease_expires_at": leaseExpiresAt,
+			"heartbeat_at":     now,
+			"attempts":         gorm.Expr("attempts + 1"),
+		})
+	if result.Error != nil {
+		q.log.Errorw("Failed to claim job", "error", result.Error, "task_id", task.ID)
+		return false
+	}
+	if result.RowsAffected == 0 {
+		return false
+	}
+
+	task.Status = "processing"
+	task.LeaseOwner = q.workerID
+	task.LeaseExpiresAt = &leaseExpiresAt
+	task.HeartbeatAt = &now
+	task.Attempts++
+	q.eventBus.publishTask(q.db, task.ID)
+	return true
+}
+
+func (q *JobQueue) run(task models.AsyncTask) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	q.mu.Lock()
+	q.inflight[task.ID] = cancel
+	handler := q.handlers[task.Type]
+	q.mu.Unlock()
+
+	defer func() {
+		cancel()
+		q.mu.Lock()
+		delete(q.inflight, task.ID)
+		q.mu.Unlock()
+		q.releaseSlot(task.Queue, task.Provider)
+		q.notify()
+	}()
+
+	q.log.Infow("Job started",
+		"task_id", task.ID,
+		"type", task.Type,
+		"queue", task.Queue,
+		"provider", task.Provider,
+		"priority", task.Priority,
+		"attempt", task.Attempts)
+
+	err := q.execute(ctx, handler, &task)
+	q.finish(&task, err)
+}
+
+func (q *JobQueue) execute(ctx context.Context, handler JobHandler, task *models.AsyncTask) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			q.log.Errorw("Job panicked", "task_id", task.ID, "type", task.Type, "panic", r)
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+
+	if handler == nil {
+		return fmt.Errorf("no handler registered for task type: %s", task.Type)
+	}
+	return handler(ctx, task)
+}
+
+// finish 根据处理结果更新任务状态并释放租约
+// 由队列写入终态时和 TaskService 一样推送事件并触发任务 webhook
+func (q *JobQueue) finish(task *models.AsyncTask, err error) {
+	now := time.Now()
+	var webhookEvent string
+	defer func() {
+		q.eventBus.publishTask(q.db, task.ID)
+		if webhookEvent != "" {
+			dispatchTaskWebhook(q.db, q, q.log, task.ID, webhookEvent)
+		}
+		q.refreshParent(task.ParentID)
+	}()
+
+	var reschedule *jobRescheduleError
+	switch {
+	case errors.As(err, &reschedule):
+		q.ownedTask(task.ID).Updates(map[string]interface{}{
+			"status":           "pending",
+			"available_at":     now.Add(reschedule.after),
+			"attempts":         gorm.Expr("attempts - 1"),
+			"lease_owner":      "",
+			"lease_expires_at": nil,
+		})
+		return
+
+	case err == nil:
+		// 处理函数可能已经自行写入 completed/failed（已由 TaskService 触发 webhook），这里只补全仍处于 processing 的任务
+		completed := q.db.Model(&models.AsyncTask{}).
+			Where("id = ? AND lease_owner = ? AND status = ?", task.ID, q.workerID, "processing").
+			Updates(map[string]interface{}{
+				"status":       "completed",
+				"progress":     100,
+				"completed_at": &now,
+			})
+		if completed.Error == nil && completed.RowsAffected > 0 {
+			webhookEvent = EventTaskCompleted
+		}
+		q.ownedTask(task.ID).Updates(map[string]interface{}{
+			"lease_owner":      "",
+			"lease_expires_at": nil,
+		})
+		q.log.Infow("Job finished", "task_id", task.ID, "type", task.Type)
+		return
+
+	case task.Attempts < task.MaxAttempts:
+		backoff := q.backoff(task.Attempts)
+		q.ownedTask(task.ID).Updates(map[string]interface{}{
+			"status":           "pending",
+			"error":            err.Error(),
+			"message":          fmt.Sprintf("第%d次执行失败，%s后重试", task.Attempts, backoff),
+			"available_at":     now.Add(backoff),
+			"lease_owner":      "",
+			"lease_expires_at": nil,
+		})
+		q.log.Warnw("Job failed, will retry", "task_id", task.ID, "type", task.Type, "attempt", task.Attempts, "backoff", backoff, "error", err)
+		return
+
+	default:
+		failed := q.ownedTask(task.ID).Updates(map[string]interface{}{
+			"status":           "failed",
+			"error":            err.Error(),
+			"progress":         0,
+			"completed_at":     &now,
+			"lease_owner":      "",
+			"lease_expires_at": nil,
+		})
+		if failed.Error == nil && failed.RowsAffected > 0 {
+			webhookEvent = EventTaskFailed
+		}
+		q.log.Errorw("Job failed", "task_id", task.ID, "type", task.Type, "attempts", task.Attempts, "error", err)
+	}
+}
+
+// ownedTask 仅匹配本进程仍持有租约的任务，避免覆盖已被其他进程接管的任务
+func (q *JobQueue) ownedTask(taskID string) *gorm.DB {
+	return q.db.Model(&models.AsyncTask{}).Where("id = ? AND lease_owner = ?", taskID, q.workerID)
+}
+
+// reapExpiredLeases 回收租约过期（工作进程崩溃或失联）的任务
+func (q *JobQueue) reapExpiredLeases() {
+	var tasks []models.AsyncTask
+	if err := q.db.Where("status = ? AND lease_owner <> '' AND lease_expires_at < ?", "processing", time.Now()).
+		Find(&tasks).Error; err != nil {
+		q.log.Errorw("Failed to load jobs with expired lease", "error", err)
+		return
+	}
+
+	for i := range tasks {
+		q.releaseInterrupted(&tasks[i], "工作进程失联，任务已重新排队")
+	}
+	if len(tasks) > 0 {
+		q.log.Warnw("Reaped jobs with expired lease", "count", len(tasks))
+	}
+}
+
+// heartbeatLoop 定期续租本进程正在执行的任务
+func (q *JobQueue) heartbeatLoop() {
+	ticker := time.NewTicker(q.leaseDuration() / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-q.stop:
+			return
+		case <-ticker.C:
+		}
+
+		q.mu.Lock()
+		ids := make([]string, 0, len(q.inflight))
+		for id := range q.inflight {
+			ids = append(ids, id)
+		}
+		q.mu.Unlock()
+
+		if len(ids) == 0 {
+			continue
+		}
+
+		now := time.Now()
+		if err := q.db.Model(&models.AsyncTask{}).
+			Where("id IN ? AND lease_owner = ?", ids, q.workerID).
+			Updates(map[string]interface{}{
+				"lease_expires_at": now.Add(q.leaseDuration()),
+				"heartbeat_at":     now,
+			}).Error; err != nil {
+			q.log.Warnw("Failed to renew job leases", "error", err, "count", len(ids))
+		}
+	}
+}
+
+func (q *JobQueue) acquireSlot(queue, provider string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.running[queue] >= q.queueLimit(queue) {
+		return false
+	}
+	providerKey := queue + "/" + provider
+	if limit := q.providerLimit(queue, provider); limit > 0 && q.running[providerKey] >= limit {
+		return false
+	}
+
+	q.running[queue]++
+	q.running[providerKey]++
+	return true
+}
+
+func (q *JobQueue) releaseSlot(queue, provider string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.running[queue]--
+	q.running[queue+"/"+provider]--
+}
+
+func (q *JobQueue) queueLimit(queue string) int {
+	if limit, ok := q.cfg.Concurrency[queue]; ok && limit > 0 {
+		return limit
+	}
+	switch queue {
+	case JobQueueFFmpeg:
+		return 1
+	case JobQueueDefault:
+		return 8
+	default:
+		return 4
+	}
+}
+
+func (q *JobQueue) providerLimit(queue, provider string) int {
+	if provider == "" {
+		return 0
+	}
+	return q.cfg.ProviderConcurrency[queue][provider]
+}
+
+func (q *JobQueue) backoff(attempt int) time.Duration {
+	backoff := 5 * time.Second << uint(attempt-1)
+	if backoff > 5*time.Minute || backoff <= 0 {
+		backoff = 5 * time.Minute
+	}
+	return backoff
+}
+
+func (q *JobQueue) pollInterval() time.Duration {
+	if q.cfg.PollInterval > 0 {
+		return time.Duration(q.cfg.PollInterval) * time.Second
+	}
+	return 2 * time.Second
+}
+
+func (q *JobQueue) leaseDuration() time.Duration {
+	if q.cfg.LeaseSeconds > 0 {
+		return time.Duration(q.cfg.LeaseSeconds) * time.Second
+	}
+	return 2 * time.Minute
+}
+
+func (q *JobQueue) maxAttempts() int {
+	if q.cfg.MaxAttempts > 0 {
+		return q.cfg.MaxAttempts
+	}
+	return 3
+}
+
+// decodeJobPayload 解析任务参数
+func decodeJobPayload(task *models.AsyncTask, v interface{}) error {
+	if task.Payload == "" {
+		return fmt.Errorf("job payload is empty")
+	}
+	if err := json.Unmarshal([]byte(task.Payload), v); err != nil {
+		return fmt.Errorf("failed to parse job payload: %w", err)
+	}
+	return nil
+}
+
+// Cancel 取消任务：排队中的任务不再执行，执行中的任务通过 context 通知处理函数停止
+// 父任务会级联取消所有未结束的子任务
+func (q *JobQueue) Cancel(taskID string) error {
+	var task models.AsyncTask
+	if err := q.db.Where("id = ?", taskID).First(&task).Error; err != nil {
+		return err
+	}
+	if isTerminalTaskStatus(task.Status) {
+		return fmt.Errorf("任务已结束，无法取消")
+	}
+
+	q.cancelTask(&task)
+	q.refreshParent(task.ParentID)
+	return nil
+}
+
+// CancelResourceJobs 取消资源上所有未结束的指定类型任务
+func (q *JobQueue) CancelResourceJobs(resourceID string, taskTypes ...string) {
+	var tasks []models.AsyncTask
+	q.db.Where("resource_id = ? AND type IN ? AND status IN ?", resourceID, taskTypes, activeTaskStatuses).Find(&tasks)
+
+	for i := range tasks {
+		q.cancelTask(&tasks[i])
+		q.refreshParent(tasks[i].ParentID)
+	}
+}
+
+func (q *JobQueue) cancelTask(task *models.AsyncTask) {
+	now := time.Now()
+	result := q.db.Model(&models.AsyncTask{}).
+		Where("id = ? AND status IN ?", task.ID, activeTaskStatuses).
+		Updates(map[string]interface{}{
+			"status":           "cancelled",
+			"message":          "任务已取消",
+			"completed_at":     &now,
+			"lease_owner":      "",
+			"lease_expires_at": nil,
+		})
+	if result.Error != nil {
+		q.log.Errorw("Failed to cancel task", "error", result.Error, "task_id", task.ID)
+		return
+	}
+	if result.RowsAffected == 0 {
+		return
+	}
+
+	q.mu.Lock()
+	cancel := q.inflight[task.ID]
+	canceler := q.cancelers[task.Type]
+	q.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+	q.eventBus.publishTask(q.db, task.ID)
+	if canceler != nil {
+		canceler(task)
+	}
+	q.log.Infow("Job cancelled", "task_id", task.ID, "type", task.Type)
+
+	var children []models.AsyncTask
+	q.db.Where("parent_id = ? AND status IN ?", task.ID, activeTaskStatuses).Find(&children)
+	for i := range children {
+		q.cancelTask(&children[i])
+	}
+}
+
+// Pause 暂停任务：排队中的任务不再被领取，已在执行的任务会继续执行完
+// 父任务会暂停所有排队中的子任务
+func (q *JobQueue) Pause(taskID string) error {
+	var task models.AsyncTask
+	if err := q.db.Where("id = ?", taskID).First(&task).Error; err != nil {
+		return err
+	}
+
+	if task.Queue == JobQueueBatch {
+		if task.Status != "processing" {
+			return fmt.Errorf("批量任务当前状态为 %s，无法暂停", task.Status)
+		}
+		q.db.Model(&models.AsyncTask{}).
+			Where("parent_id = ? AND status = ?", task.ID, "pending").
+			Updates(map[string]interface{}{"status": "paused", "message": "批量任务已暂停"})
+		q.db.Model(&models.AsyncTask{}).
+			Where("id = ?", task.ID).
+			Updates(map[string]interface{}{"status": "paused", "message": "已暂停"})
+		q.log.Infow("Batch paused", "task_id", task.ID, "type", task.Type)
+		q.eventBus.publishTask(q.db, task.ID)
+		return nil
+	}
+
+	result := q.db.Model(&models.AsyncTask{}).
+		Where("id = ? AND status = ?", task.ID, "pending").
+		Updates(map[string]interface{}{"status": "paused", "message": "已暂停"})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("只能暂停排队中的任务，当前状态为 %s", task.Status)
+	}
+	q.eventBus.publishTask(q.db, task.ID)
+	return nil
+}
+
+// Resume 恢复已暂停的任务
+func (q *JobQueue) Resume(taskID string) error {
+	var task models.AsyncTask
+	if err := q.db.Where("id = ?", taskID).First(&task).Error; err != nil {
+		return err
+	}
+	if task.Status != "paused" {
+		return fmt.Errorf("任务未暂停，当前状态为 %s", task.Status)
+	}
+
+	now := time.Now()
+	if task.Queue == JobQueueBatch {
+		q.db.Model(&models.AsyncTask{}).
+			Where("parent_id = ? AND status = ?", task.ID, "paused").
+			Updates(map[string]interface{}{"status": "pending", "message": "", "available_at": now})
+		q.db.Model(&models.AsyncTask{}).
+			Where("id = ?", task.ID).
+			Updates(map[string]interface{}{"status": "processing", "message": ""})
+		q.log.Infow("Batch resumed", "task_id", task.ID, "type", task.Type)
+	} else {
+		q.db.Model(&models.AsyncTask{}).
+			Where("id = ? AND status = ?", task.ID, "paused").
+			Updates(map[string]interface{}{"status": "pending", "message": "", "available_at": now})
+	}
+
+	q.eventBus.publishTask(q.db, task.ID)
+	q.notify()
+	return nil
+}
+
+// refreshParent 根据子任务状态更新父任务进度，子任务全部结束后父任务完成
+func (q *JobQueue) refreshParent(parentID string) {
+	if parentID == "" {
+		return
+	}
+
+	var rows []struct {
+		Status string
+		Count  int
+	}
+	if err := q.db.Model(&models.AsyncTask{}).
+		Select("status, COUNT(*) AS count").
+		Where("parent_id = ?", parentID).
+		Group("status").
+		Scan(&rows).Error; err != nil {
+		q.log.Warnw("Failed to count child tasks", "error", err, "parent_id", parentID)
+		return
+	}
+
+	counts := make(map[string]int)
+	total := 0
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+		total += row.Count
+	}
+	if total == 0 {
+		// 没有任何子任务入队的批量任务直接结束
+		now := time.Now()
+		q.db.Model(&models.AsyncTask{}).
+			Where("id = ? AND status IN ?", parentID, []string{"processing", "paused"}).
+			Updates(map[string]interface{}{"status": "completed", "progress": 100, "message": "没有需要执行的任务", "completed_at": &now})
+		q.eventBus.publishTask(q.db, parentID)
+		return
+	}
+
+	finished := counts["completed"] + counts["failed"] + counts["cancelled"]
+	updates := map[string]interface{}{
+		"progress": finished * 100 / total,
+		"message":  fmt.Sprintf("已完成 %d/%d，失败 %d，取消 %d", counts["completed"], total, counts["failed"], counts["cancelled"]),
+	}
+	if finished == total {
+		now := time.Now()
+		updates["status"] = "completed"
+		updates["completed_at"] = &now
+		if counts["completed"] == 0 && counts["failed"] > 0 {
+			updates["status"] = "failed"
+			updates["error"] = "所有子任务均执行失败"
+		}
+	}
+
+	result := q.db.Model(&models.AsyncTask{}).
+		Where("id = ? AND status IN ?", parentID, []string{"processing", "paused"}).
+		Updates(updates)
+	q.eventBus.publishTask(q.db, parentID)
+
+	// 批量任务/流水线结束时通知 webhook
+	if status, ok := updates["status"]; ok && result.RowsAffected > 0 {
+		eventType := EventTaskCompleted
+		if status == "failed" {
+			eventType = EventTaskFailed
+		}
+		dispatchTaskWebhook(q.db, q, q.log, parentID, eventType)
+	}
+}
+
+// activeTaskStatuses 未结束的任务状态
+var activeTaskStatuses = []string{"pending", "processing", "paused"}
+
+func isTerminalTaskStatus(status string) bool {
+	return status == "completed" || status == "failed" || status == "cancelled"
+}
@@ -0,0 +1,616 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/drama-generator/backend/domain/models"
+	"github.com/drama-generator/backend/pkg/config"
+	"github.com/drama-generator/backend/pkg/logger"
+	"go.uber.org/zap"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+	_ "modernc.org/sqlite"
+)
+
+// newTestDB 创建内存 SQLite 数据库，单连接保证所有查询看到同一个库
+func newTestDB(t *testing.T, tables ...interface{}) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Dialector{DriverName: "sqlite", DSN: ":memory:"}, &gorm.Config{
+		Logger: gormlogger.Discard,
+	})
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("get sql db: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	tables = append(tables, &models.AsyncTask{}, &models.WebhookSubscription{}, &models.WebhookDelivery{})
+	if err := db.AutoMigrate(tables...); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+func newTestLogger() *logger.Logger {
+	return &logger.Logger{SugaredLogger: zap.NewNop().Sugar()}
+}
+
+func newTestJobQueue(t *testing.T, db *gorm.DB, cfg config.QueueConfig) *JobQueue {
+	t.Helper()
+	return NewJobQueue(db, NewEventBus(), cfg, newTestLogger())
+}
+
+func loadTask(t *testing.T, db *gorm.DB, id string) models.AsyncTask {
+	t.Helper()
+	var task models.AsyncTask
+	if err := db.Where("id = ?", id).First(&task).Error; err != nil {
+		t.Fatalf("load task %s: %v", id, err)
+	}
+	return task
+}
+
+// waitFor 轮询直到条件成立，队列任务在独立的 goroutine 中执行
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestJobQueueClaimOnce(t *testing.T) {
+	db := newTestDB(t)
+	workers := []*JobQueue{
+		newTestJobQueue(t, db, config.QueueConfig{}),
+		newTestJobQueue(t, db, config.QueueConfig{}),
+		newTestJobQueue(t, db, config.QueueConfig{}),
+	}
+
+	task, err := workers[0].Enqueue("test", "1", JobOptions{})
+	if err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+
+	// 每个工作进程都在任务仍为 pending 时读到了它，同时尝试领取
+	var claimed int32
+	var wg sync.WaitGroup
+	for _, q := range workers {
+		candidate := loadTask(t, db, task.ID)
+		wg.Add(1)
+		go func(q *JobQueue) {
+			defer wg.Done()
+			if q.claim(&candidate) {
+				atomic.AddInt32(&claimed, 1)
+			}
+		}(q)
+	}
+	wg.Wait()
+
+	if claimed != 1 {
+		t.Fatalf("task claimed %d times, want 1", claimed)
+	}
+	got := loadTask(t, db, task.ID)
+	if got.Status != "processing" || got.Attempts != 1 || got.LeaseOwner == "" || got.LeaseExpiresAt == nil {
+		t.Errorf("claimed task = status %s attempts %d owner %q lease %v", got.Status, got.Attempts, got.LeaseOwner, got.LeaseExpiresAt)
+	}
+}
+
+func TestJobQueueReapExpiredLease(t *testing.T) {
+	tests := []struct {
+		name        string
+		attempts    int
+		safe        bool
+		wantStatus  string
+		wantReclaim bool
+	}{
+		{name: "requeued", attempts: 1, safe: true, wantStatus: "pending", wantReclaim: true},
+		{name: "unsafe to rerun", attempts: 1, safe: false, wantStatus: "failed"},
+		{name: "out of attempts", attempts: 3, safe: true, wantStatus: "failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			crashed := newTestJobQueue(t, db, config.QueueConfig{})
+			survivor := newTestJobQueue(t, db, config.QueueConfig{})
+			survivor.RegisterInterruptHandler("test", func(task *models.AsyncTask) bool { return tt.safe })
+
+			task, err := crashed.Enqueue("test", "1", JobOptions{MaxAttempts: 3})
+			if err != nil {
+				t.Fatalf("Enqueue() error = %v", err)
+			}
+			claimed := loadTask(t, db, task.ID)
+			if !crashed.claim(&claimed) {
+				t.Fatal("claim() = false")
+			}
+			expired := time.Now().Add(-time.Second)
+			db.Model(&models.AsyncTask{}).Where("id = ?", task.ID).
+				Updates(map[string]interface{}{"lease_expires_at": expired, "attempts": tt.attempts})
+
+			survivor.reapExpiredLeases()
+
+			got := loadTask(t, db, task.ID)
+			if got.Status != tt.wantStatus || got.LeaseOwner != "" {
+				t.Fatalf("reaped task = status %s owner %q, want %s and no owner", got.Status, got.LeaseOwner, tt.wantStatus)
+			}
+			if reclaimed := survivor.claim(&got); reclaimed != tt.wantReclaim {
+				t.Fatalf("claim() after reap = %v, want %v", reclaimed, tt.wantReclaim)
+			}
+			if tt.wantReclaim && loadTask(t, db, task.ID).LeaseOwner != survivor.workerID {
+				t.Error("reclaimed task is not owned by the surviving worker")
+			}
+		})
+	}
+}
+
+func TestJobQueueRetryBackoff(t *testing.T) {
+	db := newTestDB(t)
+	q := newTestJobQueue(t, db, config.QueueConfig{})
+
+	var calls int32
+	q.RegisterHandler("test", func(ctx context.Context, task *models.AsyncTask) error {
+		atomic.AddInt32(&calls, 1)
+		return errors.New("provider unavailable")
+	})
+
+	task, err := q.Enqueue("test", "1", JobOptions{MaxAttempts: 3})
+	if err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		current := loadTask(t, db, task.ID)
+		if !q.acquireSlot(current.Queue, current.Provider) || !q.claim(&current) {
+			t.Fatalf("attempt %d: task could not be claimed", attempt)
+		}
+		before := time.Now()
+		q.run(current)
+
+		got := loadTask(t, db, task.ID)
+		if got.Attempts != attempt {
+			t.Fatalf("attempt %d: attempts = %d", attempt, got.Attempts)
+		}
+		if attempt == 3 {
+			if got.Status != "failed" || got.Error != "provider unavailable" || got.CompletedAt == nil {
+				t.Fatalf("final attempt: status %s error %q completed %v", got.Status, got.Error, got.CompletedAt)
+			}
+			break
+		}
+
+		if got.Status != "pending" || got.LeaseOwner != "" || got.AvailableAt == nil {
+			t.Fatalf("attempt %d: status %s owner %q available_at %v", attempt, got.Status, got.LeaseOwner, got.AvailableAt)
+		}
+		if delay := got.AvailableAt.Sub(before); delay < q.backoff(attempt)-time.Second || delay > q.backoff(attempt)+time.Second {
+			t.Errorf("attempt %d: retry delay = %s, want about %s", attempt, delay, q.backoff(attempt))
+		}
+		// 跳过退避等待
+		db.Model(&models.AsyncTask{}).Where("id = ?", task.ID).Update("available_at", time.Now())
+	}
+
+	if calls != 3 {
+		t.Errorf("handler called %d times, want 3", calls)
+	}
+	if q.running[JobQueueDefault] != 0 {
+		t.Errorf("running slots = %d after all attempts, want 0", q.running[JobQueueDefault])
+	}
+}
+
+func TestJobQueueBackoff(t *testing.T) {
+	q := &JobQueue{}
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{attempt: 1, want: 5 * time.Second},
+		{attempt: 2, want: 10 * time.Second},
+		{attempt: 3, want: 20 * time.Second},
+		{attempt: 7, want: 5 * time.Minute},
+		{attempt: 64, want: 5 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := q.backoff(tt.attempt); got != tt.want {
+			t.Errorf("backoff(%d) = %s, want %s", tt.attempt, got, tt.want)
+		}
+	}
+}
+
+func TestJobQueueSlotLimits(t *testing.T) {
+	q := &JobQueue{
+		cfg: config.QueueConfig{
+			Concurrency:         map[string]int{JobQueueImage: 2},
+			ProviderConcurrency: map[string]map[string]int{JobQueueImage: {"openai": 1}},
+		},
+		running: make(map[string]int),
+	}
+
+	steps := []struct {
+		acquire  bool
+		queue    string
+		provider string
+		want     bool
+	}{
+		{acquire: true, queue: JobQueueImage, provider: "openai", want: true},
+		{acquire: true, queue: JobQueueImage, provider: "openai", want: false}, // 厂商上限
+		{acquire: true, queue: JobQueueImage, provider: "gemini", want: true},
+		{acquire: true, queue: JobQueueImage, provider: "gemini", want: false}, // 工作池上限
+		{acquire: true, queue: JobQueueFFmpeg, want: true},
+		{acquire: true, queue: JobQueueFFmpeg, want: false}, // ffmpeg 默认串行
+		{queue: JobQueueImage, provider: "gemini"},
+		{acquire: true, queue: JobQueueImage, provider: "openai", want: false},
+		{acquire: true, queue: JobQueueImage, provider: "gemini", want: true},
+	}
+	for i, step := range steps {
+		if !step.acquire {
+			q.releaseSlot(step.queue, step.provider)
+			continue
+		}
+		if got := q.acquireSlot(step.queue, step.provider); got != step.want {
+			t.Errorf("step %d: acquireSlot(%s, %s) = %v, want %v", i, step.queue, step.provider, got, step.want)
+		}
+	}
+}
+
+func TestJobQueueDispatchRespectsLimits(t *testing.T) {
+	db := newTestDB(t)
+	q := newTestJobQueue(t, db, config.QueueConfig{
+		Concurrency:         map[string]int{JobQueueImage: 2},
+		ProviderConcurrency: map[string]map[string]int{JobQueueImage: {"openai": 1}},
+	})
+
+	release := make(chan struct{})
+	var active, peak int32
+	q.RegisterHandler("test", func(ctx context.Context, task *models.AsyncTask) error {
+		n := atomic.AddInt32(&active, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		<-release
+		atomic.AddInt32(&active, -1)
+		return nil
+	})
+
+	var ids []string
+	for _, provider := range []string{"openai", "openai", "gemini", "gemini"} {
+		task, err := q.Enqueue("test", provider, JobOptions{Queue: JobQueueImage, Provider: provider})
+		if err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+		ids = append(ids, task.ID)
+	}
+
+	q.dispatch()
+	waitFor(t, "two running jobs", func() bool { return atomic.LoadInt32(&active) == 2 })
+
+	var processing []models.AsyncTask
+	db.Where("status = ?", "processing").Find(&processing)
+	if len(processing) != 2 {
+		t.Fatalf("%d jobs processing, want 2", len(processing))
+	}
+	if processing[0].Provider == processing[1].Provider {
+		t.Errorf("both running jobs use provider %s, provider limit is 1", processing[0].Provider)
+	}
+
+	// 槽位占满时再次调度不会领取新任务
+	q.dispatch()
+	var count int64
+	db.Model(&models.AsyncTask{}).Where("status = ?", "processing").Count(&count)
+	if count != 2 {
+		t.Errorf("%d jobs processing after second dispatch, want 2", count)
+	}
+
+	close(release)
+	waitFor(t, "all jobs to complete", func() bool {
+		q.dispatch()
+		var done int64
+		db.Model(&models.AsyncTask{}).Where("status = ?", "completed").Count(&done)
+		return done == int64(len(ids))
+	})
+	if peak > 2 {
+		t.Errorf("peak concurrency = %d, want at most 2", peak)
+	}
+}
+
+func TestJobQueueCancelParentCascades(t *testing.T) {
+	db := newTestDB(t)
+	q := newTestJobQueue(t, db, config.QueueConfig{})
+
+	started := make(chan string, 1)
+	stopped := make(chan struct{})
+	q.RegisterHandler("child", func(ctx context.Context, task *models.AsyncTask) error {
+		started <- task.ID
+		<-ctx.Done()
+		close(stopped)
+		return ctx.Err()
+	})
+
+	var mu sync.Mutex
+	cancelled := make(map[string]bool)
+	q.RegisterCanceler("child", func(task *models.AsyncTask) {
+		mu.Lock()
+		defer mu.Unlock()
+		cancelled[task.ID] = true
+	})
+
+	parent, err := q.CreateParent("batch", "1", 0)
+	if err != nil {
+		t.Fatalf("CreateParent() error = %v", err)
+	}
+	var children []string
+	for i := 0; i < 3; i++ {
+		// 第一个子任务立即执行，其余延迟到测试结束后，保证取消时处于排队状态
+		opts := JobOptions{ParentID: parent.ID}
+		if i > 0 {
+			opts.Delay = time.Hour
+		}
+		child, err := q.Enqueue("child", "1", opts)
+		if err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+		children = append(children, child.ID)
+	}
+
+	q.dispatch()
+	running := <-started
+	if running != children[0] {
+		t.Fatalf("running child = %s, want %s", running, children[0])
+	}
+
+	if err := q.Cancel(parent.ID); err != nil {
+		t.Fatalf("Cancel() error = %v", err)
+	}
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("running child was not notified through its context")
+	}
+	waitFor(t, "running child to be released", func() bool {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		return len(q.inflight) == 0
+	})
+
+	if got := loadTask(t, db, parent.ID); got.Status != "cancelled" {
+		t.Errorf("parent status = %s, want cancelled", got.Status)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for _, id := range children {
+		// 执行中的子任务返回后，finish 不能覆盖取消状态
+		if got := loadTask(t, db, id); got.Status != "cancelled" || got.LeaseOwner != "" {
+			t.Errorf("child %s = status %s owner %q, want cancelled", id, got.Status, got.LeaseOwner)
+		}
+		if !cancelled[id] {
+			t.Errorf("canceler not called for child %s", id)
+		}
+	}
+
+	if err := q.Cancel(parent.ID); err == nil {
+		t.Error("Cancel() on a cancelled task should fail")
+	}
+}
+
+func TestJobQueueRegisterHandlerTwicePanics(t *testing.T) {
+	q := newTestJobQueue(t, newTestDB(t), config.QueueConfig{})
+	handler := func(ctx context.Context, task *models.AsyncTask) error { return nil }
+	q.RegisterHandler("test", handler)
+
+	defer func() {
+		if recover() == nil {
+			t.Error("RegisterHandler() with a duplicate task type should panic")
+		}
+	}()
+	q.RegisterHandler("test", handler)
+}
+
+func TestJobQueueTaskTransitions(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     string
+		op         string
+		wantErr    bool
+		wantStatus string
+	}{
+		{name: "pause pending", status: "pending", op: "pause", wantStatus: "paused"},
+		{name: "pause processing", status: "processing", op: "pause", wantErr: true, wantStatus: "processing"},
+		{name: "pause paused", status: "paused", op: "pause", wantErr: true, wantStatus: "paused"},
+		{name: "resume paused", status: "paused", op: "resume", wantStatus: "pending"},
+		{name: "resume pending", status: "pending", op: "resume", wantErr: true, wantStatus: "pending"},
+		{name: "resume completed", status: "completed", op: "resume", wantErr: true, wantStatus: "completed"},
+		{name: "cancel pending", status: "pending", op: "cancel", wantStatus: "cancelled"},
+		{name: "cancel paused", status: "paused", op: "cancel", wantStatus: "cancelled"},
+		{name: "cancel processing", status: "processing", op: "cancel", wantStatus: "cancelled"},
+		{name: "cancel completed", status: "completed", op: "cancel", wantErr: true, wantStatus: "completed"},
+		{name: "cancel failed", status: "failed", op: "cancel", wantErr: true, wantStatus: "failed"},
+		{name: "cancel cancelled", status: "cancelled", op: "cancel", wantErr: true, wantStatus: "cancelled"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			q := newTestJobQueue(t, db, config.QueueConfig{})
+			var cancelled int
+			q.RegisterCanceler("test", func(task *models.AsyncTask) { cancelled++ })
+
+			task, err := q.Enqueue("test", "1", JobOptions{})
+			if err != nil {
+				t.Fatalf("Enqueue() error = %v", err)
+			}
+			db.Model(&models.AsyncTask{}).Where("id = ?", task.ID).Update("status", tt.status)
+
+			switch tt.op {
+			case "pause":
+				err = q.Pause(task.ID)
+			case "resume":
+				err = q.Resume(task.ID)
+			case "cancel":
+				err = q.Cancel(task.ID)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("%s error = %v, wantErr %v", tt.op, err, tt.wantErr)
+			}
+
+			got := loadTask(t, db, task.ID)
+			if got.Status != tt.wantStatus {
+				t.Errorf("status = %s, want %s", got.Status, tt.wantStatus)
+			}
+			if wantCanceler := tt.op == "cancel" && !tt.wantErr; (cancelled == 1) != wantCanceler {
+				t.Errorf("canceler called %d times", cancelled)
+			}
+		})
+	}
+}
+
+func TestJobQueuePauseResumeBatch(t *testing.T) {
+	db := newTestDB(t)
+	q := newTestJobQueue(t, db, config.QueueConfig{})
+	q.RegisterHandler("child", func(ctx context.Context, task *models.AsyncTask) error { return nil })
+
+	parent, err := q.CreateParent("batch", "1", 0)
+	if err != nil {
+		t.Fatalf("CreateParent() error = %v", err)
+	}
+	children := make(map[string]string)
+	for _, status := range []string{"pending", "pending", "processing", "completed", "cancelled"} {
+		child, err := q.Enqueue("child", "1", JobOptions{ParentID: parent.ID})
+		if err != nil {
+			t.Fatalf("Enqueue() error = %v", err)
+		}
+		updates := map[string]interface{}{"status": status}
+		if status == "processing" {
+			// 执行中的子任务由其他工作进程持有，本进程不会执行它
+			updates["lease_owner"] = "other-worker"
+		}
+		db.Model(&models.AsyncTask{}).Where("id = ?", child.ID).Updates(updates)
+		children[child.ID] = status
+	}
+
+	assertChildren := func(step string, want map[string]string) {
+		t.Helper()
+		for id, initial := range children {
+			if got := loadTask(t, db, id).Status; got != want[initial] {
+				t.Errorf("%s: %s child is %s, want %s", step, initial, got, want[initial])
+			}
+		}
+	}
+
+	if err := q.Pause(parent.ID); err != nil {
+		t.Fatalf("Pause() error = %v", err)
+	}
+	if got := loadTask(t, db, parent.ID).Status; got != "paused" {
+		t.Fatalf("parent status after pause = %s, want paused", got)
+	}
+	paused := map[string]string{"pending": "paused", "processing": "processing", "completed": "completed", "cancelled": "cancelled"}
+	assertChildren("pause", paused)
+
+	// 暂停的子任务不会被领取
+	q.dispatch()
+	assertChildren("dispatch while paused", paused)
+
+	if err := q.Pause(parent.ID); err == nil {
+		t.Error("Pause() on a paused batch should fail")
+	}
+
+	if err := q.Resume(parent.ID); err != nil {
+		t.Fatalf("Resume() error = %v", err)
+	}
+	if got := loadTask(t, db, parent.ID).Status; got != "processing" {
+		t.Fatalf("parent status after resume = %s, want processing", got)
+	}
+	// 只重新打开暂停的子任务，已结束的子任务保持原状态
+	assertChildren("resume", map[string]string{"pending": "pending", "processing": "processing", "completed": "completed", "cancelled": "cancelled"})
+
+	q.dispatch()
+	waitFor(t, "resumed children to run", func() bool {
+		var done int64
+		db.Model(&models.AsyncTask{}).Where("parent_id = ? AND status = ?", parent.ID, "completed").Count(&done)
+		return done == 3
+	})
+	assertChildren("run", map[string]string{"pending": "completed", "processing": "processing", "completed": "completed", "cancelled": "cancelled"})
+}
+
+func TestJobQueueFinishNotifiesSubscribers(t *testing.T) {
+	tests := []struct {
+		name       string
+		taskType   string
+		result     error
+		wantStatus string
+		wantEvent  string
+		wantHook   bool
+	}{
+		{name: "implicit completion", taskType: "test", wantStatus: "completed", wantEvent: EventTaskCompleted, wantHook: true},
+		{name: "out of attempts", taskType: "test", result: errors.New("boom"), wantStatus: "failed", wantEvent: EventTaskFailed, wantHook: true},
+		// 投递任务失败不能再触发 task.failed，否则订阅地址不可用时会不断生成新的投递
+		{name: "webhook delivery failed", taskType: "webhook_delivery", result: errors.New("boom"), wantStatus: "failed", wantEvent: EventTaskFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			q := newTestJobQueue(t, db, config.QueueConfig{})
+			q.RegisterHandler(tt.taskType, func(ctx context.Context, task *models.AsyncTask) error { return tt.result })
+			db.Create(&models.WebhookSubscription{
+				URL:      "https://hooks.example.com/drama",
+				Secret:   "secret",
+				Events:   models.StringList{EventTaskCompleted, EventTaskFailed},
+				IsActive: true,
+			})
+
+			task, err := q.Enqueue(tt.taskType, "1", JobOptions{MaxAttempts: 1})
+			if err != nil {
+				t.Fatalf("Enqueue() error = %v", err)
+			}
+			_, events, unsubscribe := q.eventBus.Subscribe(0, 0)
+			defer unsubscribe()
+
+			current := loadTask(t, db, task.ID)
+			if !q.acquireSlot(current.Queue, current.Provider) || !q.claim(&current) {
+				t.Fatal("task could not be claimed")
+			}
+			q.run(current)
+
+			if got := loadTask(t, db, task.ID); got.Status != tt.wantStatus {
+				t.Fatalf("status = %s, want %s", got.Status, tt.wantStatus)
+			}
+
+			published := false
+			for len(events) > 0 {
+				event := <-events
+				if data, ok := event.Data.(map[string]interface{}); ok && data["task_id"] == task.ID && event.Type == tt.wantEvent {
+					published = true
+				}
+			}
+			if !published {
+				t.Errorf("no %s event published for the task", tt.wantEvent)
+			}
+
+			var deliveries []models.WebhookDelivery
+			db.Find(&deliveries)
+			if !tt.wantHook {
+				if len(deliveries) != 0 {
+					t.Errorf("%d webhook deliveries created, want none", len(deliveries))
+				}
+				return
+			}
+			if len(deliveries) != 1 || deliveries[0].EventType != tt.wantEvent {
+				t.Fatalf("webhook deliveries = %+v, want one %s delivery", deliveries, tt.wantEvent)
+			}
+		})
+	}
+}
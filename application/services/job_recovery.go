@@ -0,0 +1,143 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/drama-generator/backend/domain/models"
+)
+
+// InterruptedByRestartMessage 因服务重启/崩溃中断且无法恢复的任务和记录统一写入的失败原因
+const InterruptedByRestartMessage = "interrupted by restart"
+
+// JobInterruptHandler 执行中的任务因服务停止或工作进程崩溃而中断时的回调
+// 返回 true 表示任务可以安全地重新执行（幂等，或处理函数能从断点恢复）
+// 返回 false 表示重新执行会产生副作用，任务将被标记为失败，回调负责同步更新关联的业务记录
+type JobInterruptHandler func(task *models.AsyncTask) bool
+
+// StartupRecoverer 启动恢复函数，扫描业务表中因重启中断、且没有队列任务跟踪的记录
+type StartupRecoverer func()
+
+type startupRecoverer struct {
+	name string
+	fn   StartupRecoverer
+}
+
+// RegisterInterruptHandler 注册任务类型的中断回调，未注册的任务类型中断后直接重新排队，重复注册时 panic
+func (q *JobQueue) RegisterInterruptHandler(taskType string, handler JobInterruptHandler) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, ok := q.interruptHandlers[taskType]; ok {
+		panic(fmt.Sprintf("job interrupt handler already registered for task type: %s", taskType))
+	}
+	q.interruptHandlers[taskType] = handler
+}
+
+// RegisterStartupRecoverer 注册启动恢复函数，同名重复注册时 panic
+func (q *JobQueue) RegisterStartupRecoverer(name string, fn StartupRecoverer) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i := range q.recoverers {
+		if q.recoverers[i].name == name {
+			panic(fmt.Sprintf("startup recoverer already registered: %s", name))
+		}
+	}
+	q.recoverers = append(q.recoverers, startupRecoverer{name: name, fn: fn})
+}
+
+// recoverOnStartup 在开始调度前恢复上次运行中断的任务
+// 队列任务由租约机制接管（租约过期后按中断回调重新排队或标记失败），这里处理租约覆盖不到的部分：
+// 没有任务参数、无法重新执行的旧任务，子任务已全部结束但未汇总的批量父任务，以及各业务表中的中断记录
+func (q *JobQueue) recoverOnStartup() {
+	now := time.Now()
+	legacy := q.db.Model(&models.AsyncTask{}).
+		Where("status IN ? AND (queue = '' OR queue IS NULL)", []string{"pending", "processing"}).
+		Updates(map[string]interface{}{
+			"status":       "failed",
+			"error":        InterruptedByRestartMessage,
+			"message":      "服务重启导致任务中断，请重新发起",
+			"completed_at": &now,
+		})
+	if legacy.RowsAffected > 0 {
+		q.log.Warnw("Marked legacy tasks interrupted by restart as failed", "count", legacy.RowsAffected)
+	}
+
+	var parents []models.AsyncTask
+	q.db.Select("id").Where("queue = ? AND status IN ?", JobQueueBatch, []string{"processing", "paused"}).Find(&parents)
+	for _, parent := range parents {
+		q.refreshParent(parent.ID)
+	}
+
+	q.mu.Lock()
+	recoverers := append([]startupRecoverer(nil), q.recoverers...)
+	q.mu.Unlock()
+
+	for _, r := range recoverers {
+		q.runRecoverer(r)
+	}
+}
+
+func (q *JobQueue) runRecoverer(r startupRecoverer) {
+	defer func() {
+		if err := recover(); err != nil {
+			q.log.Errorw("Startup recoverer panicked", "name", r.name, "panic", err)
+		}
+	}()
+
+	q.log.Infow("Running startup recovery", "name", r.name)
+	r.fn()
+}
+
+// releaseInterrupted 释放被中断的执行中任务：可安全重跑的重新排队，否则标记为失败
+func (q *JobQueue) releaseInterrupted(task *models.AsyncTask, message string) {
+	now := time.Now()
+
+	q.mu.Lock()
+	handler := q.interruptHandlers[task.Type]
+	q.mu.Unlock()
+
+	var updates map[string]interface{}
+	switch {
+	case task.Attempts >= task.MaxAttempts:
+		updates = map[string]interface{}{
+			"status":       "failed",
+			"error":        "任务执行中断（工作进程可能已崩溃），已达到最大执行次数",
+			"completed_at": &now,
+		}
+	case handler != nil && !handler(task):
+		updates = map[string]interface{}{
+			"status":       "failed",
+			"error":        InterruptedByRestartMessage,
+			"message":      "任务执行中断且无法安全重试，请重新发起",
+			"completed_at": &now,
+		}
+	default:
+		updates = map[string]interface{}{
+			"status":       "pending",
+			"message":      message,
+			"attempts":     task.Attempts,
+			"available_at": now,
+		}
+	}
+	updates["lease_owner"] = ""
+	updates["lease_expires_at"] = nil
+
+	// 只处理仍由原持有者占用的任务，避免覆盖已被其他进程回收的任务
+	result := q.db.Model(&models.AsyncTask{}).
+		Where("id = ? AND status = ? AND lease_owner = ?", task.ID, "processing", task.LeaseOwner).
+		Updates(updates)
+	if result.Error != nil {
+		q.log.Errorw("Failed to release interrupted job", "error", result.Error, "task_id", task.ID)
+		return
+	}
+	if result.RowsAffected == 0 {
+		return
+	}
+
+	q.log.Warnw("Released interrupted job", "task_id", task.ID, "type", task.Type, "status", updates["status"])
+	q.eventBus.publishTask(q.db, task.ID)
+	if updates["status"] == "failed" {
+		dispatchTaskWebhook(q.db, q, q.log, task.ID, EventTaskFailed)
+	}
+	q.refreshParent(task.ParentID)
+}
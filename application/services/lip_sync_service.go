@@ -0,0 +1,564 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	models "github.com/drama-generator/backend/domain/models"
+	"github.com/drama-generator/backend/infrastructure/external/ffmpeg"
+	"github.com/drama-generator/backend/infrastructure/storage"
+	"github.com/drama-generator/backend/pkg/logger"
+	"github.com/drama-generator/backend/pkg/utils"
+	"github.com/drama-generator/backend/pkg/video"
+	"gorm.io/gorm"
+)
+
+// 口型同步任务的轮询间隔和超时
+const (
+	lipSyncPollInterval = 15 * time.Second
+	lipSyncPollTimeout  = 30 * time.Minute
+)
+
+// lipSyncPayload 口型同步任务参数
+type lipSyncPayload struct {
+	VideoGenID uint `json:"video_gen_id"`
+}
+
+// lipSyncPollPayload 口型同步远程任务状态轮询参数
+type lipSyncPollPayload struct {
+	VideoGenID uint   `json:"video_gen_id"`
+	TaskID     string `json:"task_id"`
+}
+
+// LipSyncEpisodeResult 章节口型同步的提交结果
+type LipSyncEpisodeResult struct {
+	Queued  []uint           `json:"queued"`  // 已创建任务的视频生成记录
+	Skipped []LipSyncSkipped `json:"skipped"` // 跳过的分镜及原因
+}
+
+type LipSyncSkipped struct {
+	StoryboardID     uint   `json:"storyboard_id"`
+	StoryboardNumber int    `json:"storyboard_number"`
+	Reason           string `json:"reason"`
+}
+
+// LipSyncService 为有对白的分镜按配音同步口型，同步后的视频作为分镜的新素材，合成和剪辑时优先使用
+type LipSyncService struct {
+	db           *gorm.DB
+	aiService    *AIService
+	taskService  *TaskService
+	ffmpeg       *ffmpeg.FFmpeg
+	localStorage *storage.LocalStorage
+	log          *logger.Logger
+	jobQueue     *JobQueue
+	eventBus     *EventBus
+}
+
+func NewLipSyncService(db *gorm.DB, jobQueue *JobQueue, eventBus *EventBus, taskService *TaskService, aiService *AIService, localStorage *storage.LocalStorage, log *logger.Logger) *LipSyncService {
+	service := &LipSyncService{
+		db:           db,
+		aiService:    aiService,
+		taskService:  taskService,
+		ffmpeg:       ffmpeg.NewFFmpeg(log),
+		localStorage: localStorage,
+		log:          log,
+		jobQueue:     jobQueue,
+		eventBus:     eventBus,
+	}
+
+	service.jobQueue.RegisterHandler("lip_sync", service.handleLipSyncJob)
+	service.jobQueue.RegisterHandler("lip_sync_poll", service.handleLipSyncPollJob)
+	service.jobQueue.RegisterStartupRecoverer("lip_sync", service.RecoverPendingLipSyncs)
+
+	return service
+}
+
+// LipSyncStoryboard 为分镜当前采用的视频创建口型同步任务
+func (s *LipSyncService) LipSyncStoryboard(storyboardID uint) (*models.VideoGeneration, error) {
+	var storyboard models.Storyboard
+	if err := s.db.First(&storyboard, storyboardID).Error; err != nil {
+		return nil, fmt.Errorf("storyboard not found")
+	}
+	if _, err := s.aiService.GetDefaultConfig("lipsync"); err != nil {
+		return nil, fmt.Errorf("no lipsync AI config found: %w", err)
+	}
+
+	videoGen, reason := lipSyncSource(s.db, &storyboard)
+	if videoGen == nil {
+		return nil, fmt.Errorf("%s", reason)
+	}
+	if err := enqueueLipSync(s.db, s.jobQueue, videoGen); err != nil {
+		return nil, err
+	}
+	return videoGen, nil
+}
+
+// LipSyncEpisode 为章节中所有有对白且已配音的分镜创建口型同步任务
+func (s *LipSyncService) LipSyncEpisode(episodeID uint) (*LipSyncEpisodeResult, error) {
+	var episode models.Episode
+	if err := s.db.First(&episode, episodeID).Error; err != nil {
+		return nil, fmt.Errorf("episode not found")
+	}
+	if _, err := s.aiService.GetDefaultConfig("lipsync"); err != nil {
+		return nil, fmt.Errorf("no lipsync AI config found: %w", err)
+	}
+
+	var storyboards []models.Storyboard
+	if err := s.db.Where("episode_id = ? AND dialogue IS NOT NULL AND dialogue != ''", episodeID).
+		Order("storyboard_number ASC").
+		Find(&storyboards).Error; err != nil {
+		return nil, err
+	}
+
+	result := &LipSyncEpisodeResult{Queued: []uint{}, Skipped: []LipSyncSkipped{}}
+	for i := range storyboards {
+		storyboard := &storyboards[i]
+		videoGen, reason := lipSyncSource(s.db, storyboard)
+		if videoGen == nil {
+			result.Skipped = append(result.Skipped, LipSyncSkipped{
+				StoryboardID: storyboard.ID, StoryboardNumber: storyboard.StoryboardNumber, Reason: reason,
+			})
+			continue
+		}
+		if err := enqueueLipSync(s.db, s.jobQueue, videoGen); err != nil {
+			return nil, err
+		}
+		result.Queued = append(result.Queued, videoGen.ID)
+	}
+	return result, nil
+}
+
+// scheduleStoryboardLipSync 视频生成完成或配音完成后自动创建口型同步任务，
+// 未配置口型同步服务、分镜没有对白或尚未配音时不做处理
+func scheduleStoryboardLipSync(db *gorm.DB, jobQueue *JobQueue, log *logger.Logger, storyboardID uint) {
+	var configs int64
+	db.Model(&models.AIServiceConfig{}).Where("service_type = ? AND is_active = ?", "lipsync", true).Count(&configs)
+	if configs == 0 {
+		return
+	}
+
+	var storyboard models.Storyboard
+	if err := db.First(&storyboard, storyboardID).Error; err != nil {
+		return
+	}
+	videoGen, reason := lipSyncSource(db, &storyboard)
+	if videoGen == nil {
+		log.Infow("Storyboard not ready for lip sync", "storyboard_id", storyboardID, "reason", reason)
+		return
+	}
+	if err := enqueueLipSync(db, jobQueue, videoGen); err != nil {
+		log.Errorw("Failed to enqueue lip sync", "error", err, "video_gen_id", videoGen.ID)
+	}
+}
+
+// lipSyncSource 返回分镜需要同步口型的视频生成记录，不满足条件时返回原因
+func lipSyncSource(db *gorm.DB, storyboard *models.Storyboard) (*models.VideoGeneration, string) {
+	if dialogueText(storyboard.Dialogue) == "" {
+		return nil, "storyboard has no dialogue"
+	}
+
+	var voiced int64
+	db.Model(&models.DialogueLine{}).
+		Where("storyboard_id = ? AND status = ? AND asset_id IS NOT NULL", storyboard.ID, models.DialogueLineStatusCompleted).
+		Count(&voiced)
+	if voiced == 0 {
+		return nil, "storyboard has not been voiced"
+	}
+
+	take := findSourceTake(db, "", storyboard.EpisodeID, storyboard)
+	if take == nil || take.VideoGenID == nil {
+		return nil, "storyboard has no generated video"
+	}
+	var videoGen models.VideoGeneration
+	if err := db.First(&videoGen, *take.VideoGenID).Error; err != nil || videoGen.Status != models.VideoStatusCompleted {
+		return nil, "storyboard has no generated video"
+	}
+	return &videoGen, ""
+}
+
+// enqueueLipSync 重置视频生成记录的口型同步状态并创建任务，已有进行中的任务时不重复创建
+func enqueueLipSync(db *gorm.DB, jobQueue *JobQueue, videoGen *models.VideoGeneration) error {
+	resourceID := fmt.Sprintf("%d", videoGen.ID)
+	if jobQueue.HasActiveJob("lip_sync", resourceID) || jobQueue.HasActiveJob("lip_sync_poll", resourceID) {
+		return nil
+	}
+
+	status := models.LipSyncStatusPending
+	if err := db.Model(&models.VideoGeneration{}).Where("id = ?", videoGen.ID).Updates(map[string]interface{}{
+		"lip_sync_status":  status,
+		"lip_sync_task_id": nil,
+		"lip_sync_error":   nil,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to update lip sync status: %w", err)
+	}
+	videoGen.LipSyncStatus = &status
+
+	_, err := jobQueue.Enqueue("lip_sync", resourceID, JobOptions{
+		Queue:    JobQueueVideo,
+		Priority: JobPriorityBatch,
+		DramaID:  videoGen.DramaID,
+		Payload:  lipSyncPayload{VideoGenID: videoGen.ID},
+	})
+	return err
+}
+
+// handleLipSyncJob 拼接分镜的台词音轨并提交口型同步任务
+func (s *LipSyncService) handleLipSyncJob(ctx context.Context, task *models.AsyncTask) error {
+	var payload lipSyncPayload
+	if err := decodeJobPayload(task, &payload); err != nil {
+		return err
+	}
+
+	var videoGen models.VideoGeneration
+	if err := s.db.Preload("Storyboard").First(&videoGen, payload.VideoGenID).Error; err != nil || videoGen.Storyboard == nil {
+		s.taskService.UpdateTaskError(task.ID, fmt.Errorf("video generation not found"))
+		return nil
+	}
+	storyboard := videoGen.Storyboard
+
+	voices, err := storyboardVoices(s.db, storyboard.EpisodeID, storyboard.ID)
+	if err != nil {
+		return err
+	}
+	if len(voices) == 0 {
+		s.updateLipSyncStatus(videoGen.ID, models.LipSyncStatusSkipped, "storyboard has not been voiced")
+		s.taskService.UpdateTaskResult(task.ID, map[string]interface{}{"video_gen_id": videoGen.ID, "skipped": true})
+		return nil
+	}
+
+	client, err := s.getLipSyncClient()
+	if err != nil {
+		s.failLipSync(task.ID, videoGen.ID, err.Error())
+		return nil
+	}
+
+	voiceAsset, err := s.buildVoiceTrack(ctx, &videoGen, storyboard, voices)
+	if err != nil {
+		s.failLipSync(task.ID, videoGen.ID, err.Error())
+		return nil
+	}
+
+	// 厂商需要能访问到视频和配音地址，本地存储的 base_url 需配置为外网可访问的地址
+	source := &video.VideoResult{VideoURL: s.sourceVideoURL(&videoGen)}
+	if videoGen.Duration != nil {
+		source.Duration = *videoGen.Duration
+	}
+	result, err := client.SyncLips(source, voiceAsset.URL, video.WithSyncMode(video.LipSyncModeCutOff))
+	if err != nil {
+		if utils.IsTransientError(err) {
+			return err
+		}
+		s.failLipSync(task.ID, videoGen.ID, err.Error())
+		return nil
+	}
+
+	if result.Completed && result.VideoURL != "" {
+		if err := s.completeLipSync(&videoGen, result); err != nil {
+			s.failLipSync(task.ID, videoGen.ID, err.Error())
+		}
+		return nil
+	}
+	if result.TaskID == "" {
+		s.failLipSync(task.ID, videoGen.ID, "no task ID or video URL returned")
+		return nil
+	}
+
+	s.db.Model(&models.VideoGeneration{}).Where("id = ?", videoGen.ID).Updates(map[string]interface{}{
+		"lip_sync_status":  models.LipSyncStatusProcessing,
+		"lip_sync_task_id": result.TaskID,
+	})
+	s.enqueueLipSyncPoll(&videoGen, result.TaskID)
+	return nil
+}
+
+// enqueueLipSyncPoll 创建口型同步远程任务的状态轮询任务
+func (s *LipSyncService) enqueueLipSyncPoll(videoGen *models.VideoGeneration, taskID string) {
+	_, err := s.jobQueue.Enqueue("lip_sync_poll", fmt.Sprintf("%d", videoGen.ID), JobOptions{
+		Queue:    JobQueueDefault,
+		Priority: JobPriorityBatch,
+		DramaID:  videoGen.DramaID,
+		Delay:    lipSyncPollInterval,
+		Payload:  lipSyncPollPayload{VideoGenID: videoGen.ID, TaskID: taskID},
+	})
+	if err != nil {
+		s.log.Errorw("Failed to enqueue lip sync poll", "error", err, "video_gen_id", videoGen.ID, "task_id", taskID)
+		s.updateLipSyncStatus(videoGen.ID, models.LipSyncStatusFailed, err.Error())
+	}
+}
+
+// handleLipSyncPollJob 查询一次口型同步任务状态，未完成时重新排队
+func (s *LipSyncService) handleLipSyncPollJob(ctx context.Context, task *models.AsyncTask) error {
+	var payload lipSyncPollPayload
+	if err := decodeJobPayload(task, &payload); err != nil {
+		return err
+	}
+
+	var videoGen models.VideoGeneration
+	if err := s.db.First(&videoGen, payload.VideoGenID).Error; err != nil {
+		return nil
+	}
+	// 期间重新发起了同步，旧任务的结果不再需要
+	if videoGen.LipSyncTaskID == nil || *videoGen.LipSyncTaskID != payload.TaskID {
+		return nil
+	}
+
+	if time.Since(task.CreatedAt) > lipSyncPollTimeout {
+		s.failLipSync(task.ID, videoGen.ID, "timeout: lip sync took too long")
+		return nil
+	}
+
+	client, err := s.getLipSyncClient()
+	if err != nil {
+		s.failLipSync(task.ID, videoGen.ID, err.Error())
+		return nil
+	}
+
+	result, err := client.GetTaskStatus(payload.TaskID)
+	if err != nil {
+		s.log.Errorw("Failed to get lip sync status", "error", err, "task_id", payload.TaskID)
+		return RescheduleJob(lipSyncPollInterval)
+	}
+
+	if result.Completed {
+		if result.VideoURL == "" {
+			s.failLipSync(task.ID, videoGen.ID, "task completed but no video URL")
+			return nil
+		}
+		if err := s.completeLipSync(&videoGen, result); err != nil {
+			s.failLipSync(task.ID, videoGen.ID, err.Error())
+		}
+		return nil
+	}
+	if result.Error != "" {
+		s.failLipSync(task.ID, videoGen.ID, result.Error)
+		return nil
+	}
+
+	return RescheduleJob(lipSyncPollInterval)
+}
+
+// buildVoiceTrack 将分镜的台词按时间线上的摆放方式拼接为一条音轨，补齐到视频时长后保存为配音素材
+func (s *LipSyncService) buildVoiceTrack(ctx context.Context, videoGen *models.VideoGeneration, storyboard *models.Storyboard, voices []buildVoice) (*models.Asset, error) {
+	inputs := make([]string, 0, len(voices))
+	for _, voice := range voices {
+		if voice.asset.LocalPath != nil && *voice.asset.LocalPath != "" {
+			inputs = append(inputs, s.localStorage.GetAbsolutePath(*voice.asset.LocalPath))
+		} else {
+			inputs = append(inputs, voice.asset.URL)
+		}
+	}
+
+	var duration float64
+	if videoGen.LocalPath != nil && *videoGen.LocalPath != "" {
+		if seconds, err := s.ffmpeg.GetVideoDuration(s.localStorage.GetAbsolutePath(*videoGen.LocalPath)); err == nil {
+			duration = seconds
+		}
+	}
+	if duration == 0 && videoGen.Duration != nil {
+		duration = float64(*videoGen.Duration)
+	}
+
+	relPath := filepath.ToSlash(filepath.Join("audio", "voice", fmt.Sprintf("episode_%d", storyboard.EpisodeID),
+		fmt.Sprintf("storyboard_%d_lipsync_%d.wav", storyboard.ID, time.Now().UnixNano())))
+	filePath := s.localStorage.GetAbsolutePath(relPath)
+	if err := s.ffmpeg.BuildVoiceTrack(ctx, &ffmpeg.VoiceTrackOptions{
+		Inputs:     inputs,
+		OutputPath: filePath,
+		Duration:   duration,
+	}); err != nil {
+		return nil, err
+	}
+
+	format := "wav"
+	mimeType := audioMimeType(format)
+	category := models.AssetCategoryVoice
+	asset := &models.Asset{
+		DramaID:       &videoGen.DramaID,
+		EpisodeID:     &storyboard.EpisodeID,
+		StoryboardID:  &storyboard.ID,
+		StoryboardNum: &storyboard.StoryboardNumber,
+		Name:          fmt.Sprintf("镜头%d 对白音轨", storyboard.StoryboardNumber),
+		Type:          models.AssetTypeAudio,
+		Category:      &category,
+		URL:           s.localStorage.GetURL(relPath),
+		LocalPath:     &relPath,
+		MimeType:      &mimeType,
+		Format:        &format,
+	}
+	if info, err := os.Stat(filePath); err == nil {
+		size := info.Size()
+		asset.FileSize = &size
+	}
+	if duration > 0 {
+		seconds := int(duration + 0.999)
+		durationMs := int(duration*1000 + 0.5)
+		asset.Duration = &seconds
+		asset.DurationMs = &durationMs
+	}
+	if err := s.db.Create(asset).Error; err != nil {
+		os.Remove(filePath)
+		return nil, fmt.Errorf("failed to create asset: %w", err)
+	}
+	return asset, nil
+}
+
+// sourceVideoURL 优先使用本地存储的视频地址，厂商返回的原始地址通常有时效
+func (s *LipSyncService) sourceVideoURL(videoGen *models.VideoGeneration) string {
+	if videoGen.LocalPath != nil && *videoGen.LocalPath != "" {
+		return s.localStorage.GetURL(*videoGen.LocalPath)
+	}
+	if videoGen.VideoURL != nil {
+		return *videoGen.VideoURL
+	}
+	return ""
+}
+
+// completeLipSync 下载同步后的视频并保存为分镜的口型同步素材
+func (s *LipSyncService) completeLipSync(videoGen *models.VideoGeneration, result *video.VideoResult) error {
+	var storyboard models.Storyboard
+	if err := s.db.First(&storyboard, *videoGen.StoryboardID).Error; err != nil {
+		return fmt.Errorf("storyboard not found")
+	}
+
+	download, err := s.localStorage.DownloadFromURLWithPath(result.VideoURL, "videos/lipsync")
+	if err != nil {
+		return fmt.Errorf("failed to download lip sync video: %w", err)
+	}
+	relPath := filepath.ToSlash(download.RelativePath)
+
+	format := strings.TrimPrefix(filepath.Ext(relPath), ".")
+	category := models.AssetCategoryLipSync
+	asset := &models.Asset{
+		DramaID:       &videoGen.DramaID,
+		EpisodeID:     &storyboard.EpisodeID,
+		StoryboardID:  &storyboard.ID,
+		StoryboardNum: &storyboard.StoryboardNumber,
+		Name:          fmt.Sprintf("镜头%d 对口型", storyboard.StoryboardNumber),
+		Type:          models.AssetTypeVideo,
+		Category:      &category,
+		URL:           download.URL,
+		LocalPath:     &relPath,
+		ThumbnailURL:  videoGenThumbnail(videoGen),
+		SpriteVTTURL:  videoGen.SpriteVTTURL,
+		Width:         videoGen.Width,
+		Height:        videoGen.Height,
+		Format:        &format,
+		VideoGenID:    &videoGen.ID,
+	}
+	if seconds, err := s.ffmpeg.GetVideoDuration(download.AbsolutePath); err == nil {
+		duration := int(seconds + 0.5)
+		durationMs := int(seconds*1000 + 0.5)
+		asset.Duration = &duration
+		asset.DurationMs = &durationMs
+	} else if result.Duration > 0 {
+		asset.Duration = &result.Duration
+	}
+	if info, err := os.Stat(download.AbsolutePath); err == nil {
+		size := info.Size()
+		asset.FileSize = &size
+	}
+	if err := s.db.Create(asset).Error; err != nil {
+		os.Remove(download.AbsolutePath)
+		return fmt.Errorf("failed to create asset: %w", err)
+	}
+
+	s.db.Model(&models.VideoGeneration{}).Where("id = ?", videoGen.ID).Updates(map[string]interface{}{
+		"lip_sync_status":   models.LipSyncStatusCompleted,
+		"lip_sync_asset_id": asset.ID,
+		"lip_sync_error":    nil,
+	})
+	s.log.Infow("Lip sync completed", "video_gen_id", videoGen.ID, "asset_id", asset.ID)
+	s.publishLipSyncEvent(videoGen.ID, EventLipSyncCompleted)
+	return nil
+}
+
+func (s *LipSyncService) failLipSync(taskID string, videoGenID uint, errorMsg string) {
+	s.updateLipSyncStatus(videoGenID, models.LipSyncStatusFailed, errorMsg)
+	s.taskService.UpdateTaskError(taskID, fmt.Errorf("%s", errorMsg))
+	s.log.Errorw("Lip sync failed", "video_gen_id", videoGenID, "error", errorMsg)
+	s.publishLipSyncEvent(videoGenID, EventLipSyncFailed)
+}
+
+func (s *LipSyncService) updateLipSyncStatus(videoGenID uint, status models.LipSyncStatus, errorMsg string) {
+	s.db.Model(&models.VideoGeneration{}).Where("id = ?", videoGenID).Updates(map[string]interface{}{
+		"lip_sync_status": status,
+		"lip_sync_error":  errorMsg,
+	})
+}
+
+// publishLipSyncEvent 发布口型同步状态变更事件
+func (s *LipSyncService) publishLipSyncEvent(videoGenID uint, eventType string) {
+	var videoGen models.VideoGeneration
+	if err := s.db.First(&videoGen, videoGenID).Error; err != nil {
+		return
+	}
+
+	data := map[string]interface{}{
+		"video_gen_id":      videoGen.ID,
+		"storyboard_id":     videoGen.StoryboardID,
+		"lip_sync_status":   videoGen.LipSyncStatus,
+		"lip_sync_asset_id": videoGen.LipSyncAssetID,
+		"lip_sync_error":    videoGen.LipSyncError,
+	}
+	s.eventBus.Publish(eventType, videoGen.DramaID, data)
+	dispatchWebhookEvent(s.db, s.jobQueue, s.log, eventType, videoGen.DramaID, data)
+}
+
+// RecoverPendingLipSyncs 启动时恢复没有队列任务跟踪的口型同步：已提交的继续轮询，未提交的重新入队
+func (s *LipSyncService) RecoverPendingLipSyncs() {
+	var videoGens []models.VideoGeneration
+	if err := s.db.Where("lip_sync_status IN ?", []models.LipSyncStatus{models.LipSyncStatusPending, models.LipSyncStatusProcessing}).
+		Find(&videoGens).Error; err != nil {
+		s.log.Errorw("Failed to load pending lip syncs", "error", err)
+		return
+	}
+
+	recovered := 0
+	for i := range videoGens {
+		videoGen := &videoGens[i]
+		resourceID := fmt.Sprintf("%d", videoGen.ID)
+		if s.jobQueue.HasActiveJob("lip_sync", resourceID) || s.jobQueue.HasActiveJob("lip_sync_poll", resourceID) {
+			continue
+		}
+		if videoGen.LipSyncTaskID != nil && *videoGen.LipSyncTaskID != "" {
+			s.enqueueLipSyncPoll(videoGen, *videoGen.LipSyncTaskID)
+		} else if err := enqueueLipSync(s.db, s.jobQueue, videoGen); err != nil {
+			s.updateLipSyncStatus(videoGen.ID, models.LipSyncStatusFailed, err.Error())
+			continue
+		}
+		recovered++
+	}
+
+	if recovered > 0 {
+		s.log.Infow("Recovered lip syncs", "count", recovered)
+	}
+}
+
+func (s *LipSyncService) getLipSyncClient() (video.LipSyncClient, error) {
+	config, err := s.aiService.GetDefaultConfig("lipsync")
+	if err != nil {
+		return nil, fmt.Errorf("no lipsync AI config found: %w", err)
+	}
+
+	model := ""
+	if len(config.Model) > 0 {
+		model = config.Model[0]
+	}
+	return newLipSyncClient(config, model)
+}
+
+// newLipSyncClient 根据配置中的 provider 创建对应的口型同步客户端
+func newLipSyncClient(config *models.AIServiceConfig, model string) (video.LipSyncClient, error) {
+	switch config.Provider {
+	case "sync", "synclabs", "sync.so":
+		return video.NewSyncLabsLipSyncClient(config.BaseURL, config.APIKey, model), nil
+	case "kling", "klingai":
+		return video.NewKlingLipSyncClient(config.BaseURL, config.APIKey), nil
+	default:
+		return nil, fmt.Errorf("unsupported lipsync provider: %s", config.Provider)
+	}
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -18,21 +19,29 @@ type PropService struct {
 	aiService              *AIService
 	taskService            *TaskService
 	imageGenerationService *ImageGenerationService
+	jobQueue               *JobQueue
 	log                    *logger.Logger
 	config                 *config.Config
 	promptI18n             *PromptI18n
 }
 
-func NewPropService(db *gorm.DB, aiService *AIService, taskService *TaskService, imageGenerationService *ImageGenerationService, log *logger.Logger, cfg *config.Config) *PropService {
-	return &PropService{
+func NewPropService(db *gorm.DB, jobQueue *JobQueue, aiService *AIService, taskService *TaskService, imageGenerationService *ImageGenerationService, log *logger.Logger, cfg *config.Config) *PropService {
+	service := &PropService{
 		db:                     db,
 		aiService:              aiService,
 		taskService:            taskService,
 		imageGenerationService: imageGenerationService,
+		jobQueue:               jobQueue,
 		log:                    log,
 		config:                 cfg,
 		promptI18n:             NewPromptI18n(cfg),
 	}
+
+	service.jobQueue.RegisterHandler("prop_extraction", service.handlePropExtractionJob)
+	service.jobQueue.RegisterHandler("prop_image_generation", service.handlePropImageGenerationJob)
+	service.jobQueue.RegisterInterruptHandler("prop_image_generation", service.handlePropImageJobInterrupted)
+
+	return service
 }
 
 // ListProps 获取剧本的道具列表
@@ -61,22 +70,53 @@ func (s *PropService) DeleteProp(id uint) error {
 
 // ExtractPropsFromScript 从剧本提取道具（异步）
 func (s *PropService) ExtractPropsFromScript(episodeID uint) (string, error) {
+	return s.ExtractPropsFromScriptWithModel(episodeID, "")
+}
+
+// ExtractPropsFromScriptWithModel 使用指定文本模型从剧本提取道具，model 为空时使用默认配置
+func (s *PropService) ExtractPropsFromScriptWithModel(episodeID uint, model string) (string, error) {
 	var episode models.Episode
 	if err := s.db.First(&episode, episodeID).Error; err != nil {
 		return "", fmt.Errorf("episode not found: %w", err)
 	}
 
-	task, err := s.taskService.CreateTask("prop_extraction", fmt.Sprintf("%d", episodeID))
+	task, err := s.jobQueue.Enqueue("prop_extraction", fmt.Sprintf("%d", episodeID), JobOptions{
+		Queue:    JobQueueText,
+		Provider: s.aiService.ResolveProvider("text", model),
+		Priority: JobPriorityInteractive,
+		DramaID:  episode.DramaID,
+		Payload:  propExtractionPayload{EpisodeID: episode.ID, Model: model},
+	})
 	if err != nil {
 		return "", err
 	}
 
-	go s.processPropExtraction(task.ID, episode)
-
 	return task.ID, nil
 }
 
-func (s *PropService) processPropExtraction(taskID string, episode models.Episode) {
+type propExtractionPayload struct {
+	EpisodeID uint   `json:"episode_id"`
+	Model     string `json:"model,omitempty"`
+}
+
+// handlePropExtractionJob 任务队列处理函数
+func (s *PropService) handlePropExtractionJob(ctx context.Context, task *models.AsyncTask) error {
+	var payload propExtractionPayload
+	if err := decodeJobPayload(task, &payload); err != nil {
+		return err
+	}
+
+	var episode models.Episode
+	if err := s.db.First(&episode, payload.EpisodeID).Error; err != nil {
+		s.taskService.UpdateTaskError(task.ID, fmt.Errorf("episode not found: %w", err))
+		return nil
+	}
+
+	s.processPropExtraction(ctx, task.ID, episode, payload.Model)
+	return ctx.Err()
+}
+
+func (s *PropService) processPropExtraction(ctx context.Context, taskID string, episode models.Episode, model string) {
 	s.taskService.UpdateTaskStatus(taskID, "processing", 0, "正在分析剧本...")
 
 	script := ""
@@ -93,7 +133,11 @@ func (s *PropService) processPropExtraction(taskID string, episode models.Episod
 	promptTemplate := s.promptI18n.GetPropExtractionPrompt(drama.Style)
 	prompt := fmt.Sprintf(promptTemplate, script)
 
-	response, err := s.aiService.GenerateText(prompt, "", ai.WithMaxTokens(2000))
+	response, err := s.aiService.GenerateTextWithContext(ctx, model, prompt, "", ai.WithMaxTokens(2000))
+	// 任务被暂停、取消或队列停止，不写入失败状态
+	if ctx.Err() != nil {
+		return
+	}
 	if err != nil {
 		s.taskService.UpdateTaskError(taskID, err)
 		return
@@ -157,15 +201,45 @@ func (s *PropService) GeneratePropImage(propID uint) (string, error) {
 	}
 
 	// 2. 创建任务
-	task, err := s.taskService.CreateTask("prop_image_generation", fmt.Sprintf("%d", propID))
+	// 该任务只负责提交图片生成并等待结果，放在 default 工作池，避免占用 image 工作池的并发
+	task, err := s.jobQueue.Enqueue("prop_image_generation", fmt.Sprintf("%d", propID), JobOptions{
+		Queue:    JobQueueDefault,
+		Priority: JobPriorityInteractive,
+		DramaID:  prop.DramaID,
+		Payload:  map[string]uint{"prop_id": prop.ID},
+	})
 	if err != nil {
 		return "", err
 	}
 
-	go s.processPropImageGeneration(task.ID, prop)
 	return task.ID, nil
 }
 
+// handlePropImageGenerationJob 任务队列处理函数
+func (s *PropService) handlePropImageGenerationJob(ctx context.Context, task *models.AsyncTask) error {
+	var payload struct {
+		PropID uint `json:"prop_id"`
+	}
+	if err := decodeJobPayload(task, &payload); err != nil {
+		return err
+	}
+
+	var prop models.Prop
+	if err := s.db.First(&prop, payload.PropID).Error; err != nil {
+		s.taskService.UpdateTaskError(task.ID, fmt.Errorf("prop not found: %w", err))
+		return nil
+	}
+
+	s.processPropImageGeneration(task.ID, prop)
+	return nil
+}
+
+// handlePropImageJobInterrupted 道具图片任务每次执行都会创建新的图片生成记录，中断后不重跑，
+// 已创建的图片生成记录由图片生成服务自行恢复，完成后同样会回写道具图片
+func (s *PropService) handlePropImageJobInterrupted(task *models.AsyncTask) bool {
+	return false
+}
+
 func (s *PropService) processPropImageGeneration(taskID string, prop models.Prop) {
 	s.taskService.UpdateTaskStatus(taskID, "processing", 0, "正在生成图片...")
 
@@ -0,0 +1,524 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	models "github.com/drama-generator/backend/domain/models"
+	"github.com/drama-generator/backend/infrastructure/external/ffmpeg"
+	"github.com/drama-generator/backend/pkg/config"
+	"github.com/drama-generator/backend/pkg/logger"
+	"github.com/drama-generator/backend/pkg/qc"
+	"gorm.io/gorm"
+)
+
+// QCSettings 生成结果自动质检设置
+type QCSettings struct {
+	Enabled          bool
+	AutoRegenerate   bool
+	MaxRegenerations int
+	RequireAudio     bool
+}
+
+// NewQCSettings 根据配置文件生成自动质检设置，自动重新生成次数上限默认 1
+func NewQCSettings(cfg config.QCConfig) QCSettings {
+	settings := QCSettings{
+		Enabled:          !cfg.Disabled,
+		AutoRegenerate:   cfg.AutoRegenerate,
+		MaxRegenerations: cfg.MaxRegenerations,
+		RequireAudio:     cfg.RequireAudio,
+	}
+	if settings.MaxRegenerations <= 0 {
+		settings.MaxRegenerations = 1
+	}
+	return settings
+}
+
+// imagePromptRatio 图片生成时通过提示词要求的宽高比（见 ProcessImageGeneration），厂商不一定遵守，不符时只提示
+const imagePromptRatio = "16:9"
+
+// videoQCPayload 视频质检任务参数
+type videoQCPayload struct {
+	VideoGenID uint    `json:"video_gen_id"`
+	Duration   float64 `json:"duration,omitempty"` // 请求时长，完成后记录中的时长会被实际时长覆盖
+}
+
+// imageQCPayload 图片质检任务参数
+type imageQCPayload struct {
+	ImageGenID uint `json:"image_gen_id"`
+}
+
+type QCService struct {
+	db          *gorm.DB
+	aiService   *AIService
+	taskService *TaskService
+	ffmpeg      *ffmpeg.FFmpeg
+	storagePath string
+	log         *logger.Logger
+	jobQueue    *JobQueue
+	settings    QCSettings
+}
+
+func NewQCService(db *gorm.DB, jobQueue *JobQueue, taskService *TaskService, settings QCSettings, aiService *AIService, storagePath string, log *logger.Logger) *QCService {
+	service := &QCService{
+		db:          db,
+		aiService:   aiService,
+		taskService: taskService,
+		ffmpeg:      ffmpeg.NewFFmpeg(log),
+		storagePath: storagePath,
+		log:         log,
+		jobQueue:    jobQueue,
+		settings:    settings,
+	}
+
+	service.jobQueue.RegisterHandler("video_qc", service.handleVideoQCJob)
+	service.jobQueue.RegisterHandler("image_qc", service.handleImageQCJob)
+
+	return service
+}
+
+// GenerationQC 单条生成记录的状态和质检结果
+type GenerationQC struct {
+	ID       uint       `json:"id"`
+	Status   string     `json:"status"`
+	QCStatus string     `json:"qc_status,omitempty"`
+	Issues   []qc.Issue `json:"issues,omitempty"`
+}
+
+// StoryboardReadiness 分镜的就绪情况，Problems 为阻止或影响成片的问题
+type StoryboardReadiness struct {
+	StoryboardID     uint          `json:"storyboard_id"`
+	StoryboardNumber int           `json:"storyboard_number"`
+	Ready            bool          `json:"ready"`
+	Image            *GenerationQC `json:"image,omitempty"`
+	Video            *GenerationQC `json:"video,omitempty"`
+	Problems         []string      `json:"problems,omitempty"`
+}
+
+// EpisodeReadiness 章节成片前的就绪报告：每个分镜都有质检未失败的视频时就绪
+type EpisodeReadiness struct {
+	EpisodeID   uint                  `json:"episode_id"`
+	Ready       bool                  `json:"ready"`
+	Total       int                   `json:"total"`
+	ReadyCount  int                   `json:"ready_count"`
+	Generating  int                   `json:"generating"`
+	QCFailed    int                   `json:"qc_failed"`
+	Storyboards []StoryboardReadiness `json:"storyboards"`
+}
+
+// CheckVideo 重新对已完成的视频做质检
+func (s *QCService) CheckVideo(videoGenID uint) (*models.AsyncTask, error) {
+	var videoGen models.VideoGeneration
+	if err := s.db.First(&videoGen, videoGenID).Error; err != nil {
+		return nil, fmt.Errorf("video generation not found")
+	}
+	if videoGen.Status != models.VideoStatusCompleted {
+		return nil, fmt.Errorf("video generation is not completed")
+	}
+	return enqueueVideoQC(s.jobQueue, &videoGen, 0, JobPriorityInteractive)
+}
+
+// CheckImage 重新对已完成的图片做质检
+func (s *QCService) CheckImage(imageGenID uint) (*models.AsyncTask, error) {
+	var imageGen models.ImageGeneration
+	if err := s.db.First(&imageGen, imageGenID).Error; err != nil {
+		return nil, fmt.Errorf("image generation not found")
+	}
+	if imageGen.Status != models.ImageStatusCompleted {
+		return nil, fmt.Errorf("image generation is not completed")
+	}
+	return enqueueImageQC(s.jobQueue, &imageGen, JobPriorityInteractive)
+}
+
+// EpisodeReadiness 汇总章节各分镜最新图片和视频的生成状态与质检结果
+func (s *QCService) EpisodeReadiness(episodeID uint) (*EpisodeReadiness, error) {
+	var episode models.Episode
+	if err := s.db.First(&episode, episodeID).Error; err != nil {
+		return nil, fmt.Errorf("episode not found")
+	}
+
+	var storyboards []models.Storyboard
+	if err := s.db.Where("episode_id = ?", episodeID).Order("storyboard_number ASC").Find(&storyboards).Error; err != nil {
+		return nil, fmt.Errorf("failed to load storyboards: %w", err)
+	}
+
+	report := &EpisodeReadiness{EpisodeID: episodeID, Total: len(storyboards), Storyboards: []StoryboardReadiness{}}
+	for _, storyboard := range storyboards {
+		item := s.storyboardReadiness(&storyboard)
+		if item.Ready {
+			report.ReadyCount++
+		}
+		if item.Video != nil && item.Video.QCStatus == qc.StatusFailed {
+			report.QCFailed++
+		}
+		if s.storyboardGenerating(storyboard.ID) {
+			report.Generating++
+		}
+		report.Storyboards = append(report.Storyboards, item)
+	}
+	report.Ready = report.Total > 0 && report.ReadyCount == report.Total
+	return report, nil
+}
+
+func (s *QCService) storyboardReadiness(storyboard *models.Storyboard) StoryboardReadiness {
+	item := StoryboardReadiness{StoryboardID: storyboard.ID, StoryboardNumber: storyboard.StoryboardNumber}
+
+	var imageGen models.ImageGeneration
+	if err := s.db.Where("storyboard_id = ? AND status = ? AND provider <> ?", storyboard.ID, models.ImageStatusCompleted, chainFrameProvider).
+		Order("created_at DESC").First(&imageGen).Error; err == nil {
+		item.Image = &GenerationQC{ID: imageGen.ID, Status: string(imageGen.Status), QCStatus: getString(imageGen.QCStatus), Issues: decodeQCIssues(imageGen.QCFlags)}
+		if item.Image.QCStatus == qc.StatusFailed {
+			item.Problems = append(item.Problems, "图片质检未通过："+issueMessages(item.Image.Issues))
+		}
+	}
+
+	var videoGen models.VideoGeneration
+	if err := s.db.Where("storyboard_id = ? AND status = ?", storyboard.ID, models.VideoStatusCompleted).
+		Order("created_at DESC").First(&videoGen).Error; err != nil {
+		if s.storyboardGenerating(storyboard.ID) {
+			item.Problems = append(item.Problems, "视频生成中")
+		} else {
+			item.Problems = append(item.Problems, "没有可用的视频")
+		}
+		return item
+	}
+
+	item.Video = &GenerationQC{ID: videoGen.ID, Status: string(videoGen.Status), QCStatus: getString(videoGen.QCStatus), Issues: decodeQCIssues(videoGen.QCFlags)}
+	switch item.Video.QCStatus {
+	case qc.StatusFailed:
+		message := "视频质检未通过：" + issueMessages(item.Video.Issues)
+		if s.storyboardGenerating(storyboard.ID) {
+			message += "，正在重新生成"
+		}
+		item.Problems = append(item.Problems, message)
+	case qc.StatusError:
+		item.Problems = append(item.Problems, "视频未能完成质检："+issueMessages(item.Video.Issues))
+	case "":
+		item.Problems = append(item.Problems, "视频未质检")
+	}
+	item.Ready = item.Video.QCStatus != qc.StatusFailed
+	return item
+}
+
+// storyboardGenerating 分镜是否有排队中或生成中的视频
+func (s *QCService) storyboardGenerating(storyboardID uint) bool {
+	var count int64
+	s.db.Model(&models.VideoGeneration{}).
+		Where("storyboard_id = ? AND status IN ?", storyboardID, []models.VideoStatus{models.VideoStatusPending, models.VideoStatusProcessing}).
+		Count(&count)
+	return count > 0
+}
+
+// handleVideoQCJob 探测视频并与请求规格比较，结果写入生成记录；未通过时按设置自动重新生成
+func (s *QCService) handleVideoQCJob(ctx context.Context, task *models.AsyncTask) error {
+	var payload videoQCPayload
+	if err := decodeJobPayload(task, &payload); err != nil {
+		return err
+	}
+
+	var videoGen models.VideoGeneration
+	if err := s.db.First(&videoGen, payload.VideoGenID).Error; err != nil {
+		s.taskService.UpdateTaskError(task.ID, fmt.Errorf("video generation not found"))
+		return nil
+	}
+	if videoGen.Status != models.VideoStatusCompleted {
+		return nil
+	}
+
+	spec := s.videoSpec(&videoGen, payload.Duration)
+	var report qc.Report
+	switch {
+	case videoGen.LocalPath != nil && *videoGen.LocalPath != "":
+		report = s.probeVideo(ctx, resolveStoragePath(s.storagePath, *videoGen.LocalPath), spec)
+	case videoGen.VideoURL != nil && *videoGen.VideoURL != "":
+		report = s.probeVideo(ctx, *videoGen.VideoURL, spec)
+	default:
+		report = qc.Unreadable(fmt.Errorf("video has no file"))
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	if err := s.saveQCReport(&models.VideoGeneration{}, videoGen.ID, report); err != nil {
+		return err
+	}
+	s.log.Infow("Video QC finished", "id", videoGen.ID, "status", report.Status, "flags", report.Flags())
+
+	if report.Status == qc.StatusFailed && s.settings.AutoRegenerate {
+		if videoGen.QCRetries >= s.settings.MaxRegenerations {
+			s.log.Warnw("Video QC failed, regeneration limit reached", "id", videoGen.ID, "retries", videoGen.QCRetries)
+		} else if retry, err := s.regenerateVideo(&videoGen, spec.Duration); err != nil {
+			s.log.Errorw("Failed to regenerate video after QC", "error", err, "id", videoGen.ID)
+		} else {
+			s.log.Infow("Video regenerated after QC failure", "id", videoGen.ID, "retry_id", retry.ID)
+		}
+	}
+
+	s.taskService.UpdateTaskResult(task.ID, report)
+	return nil
+}
+
+func (s *QCService) probeVideo(ctx context.Context, source string, spec qc.VideoSpec) qc.Report {
+	probe, err := s.ffmpeg.ProbeVideoQC(ctx, source)
+	if err != nil {
+		return qc.Unreadable(err)
+	}
+	return qc.CheckVideo(*probe, spec)
+}
+
+// videoSpec 视频的请求规格：时长优先使用完成前记录的请求时长，其次为分镜时长；
+// 没有指定画幅时，图生视频的画幅应与参考图一致
+func (s *QCService) videoSpec(videoGen *models.VideoGeneration, requested float64) qc.VideoSpec {
+	spec := qc.VideoSpec{Duration: requested, RequireAudio: s.settings.RequireAudio}
+	if spec.Duration <= 0 && videoGen.StoryboardID != nil {
+		var storyboard models.Storyboard
+		if err := s.db.Select("id", "duration").First(&storyboard, *videoGen.StoryboardID).Error; err == nil {
+			spec.Duration = float64(storyboard.Duration)
+		}
+	}
+
+	if videoGen.AspectRatio != nil {
+		spec.AspectRatio = qc.ParseAspectRatio(*videoGen.AspectRatio)
+	} else if videoGen.ImageGenID != nil && (videoGen.ReferenceMode == nil || *videoGen.ReferenceMode == "single") {
+		var imageGen models.ImageGeneration
+		if err := s.db.Select("id", "width", "height").First(&imageGen, *videoGen.ImageGenID).Error; err == nil &&
+			imageGen.Width != nil && imageGen.Height != nil && *imageGen.Width > 0 && *imageGen.Height > 0 {
+			spec.AspectRatio = float64(*imageGen.Width) / float64(*imageGen.Height)
+		}
+	}
+
+	if videoGen.Resolution != nil {
+		spec.MinShortSide = qc.ParseResolution(*videoGen.Resolution)
+	}
+	return spec
+}
+
+// regenerateVideo 按原请求重新生成视频，不沿用随机种子
+func (s *QCService) regenerateVideo(videoGen *models.VideoGeneration, requested float64) (*models.VideoGeneration, error) {
+	retry := &models.VideoGeneration{
+		StoryboardID:       videoGen.StoryboardID,
+		DramaID:            videoGen.DramaID,
+		Provider:           videoGen.Provider,
+		Prompt:             videoGen.Prompt,
+		Model:              videoGen.Model,
+		ImageGenID:         videoGen.ImageGenID,
+		ReferenceMode:      videoGen.ReferenceMode,
+		ImageURL:           videoGen.ImageURL,
+		FirstFrameURL:      videoGen.FirstFrameURL,
+		LastFrameURL:       videoGen.LastFrameURL,
+		ReferenceImageURLs: videoGen.ReferenceImageURLs,
+		ChainPrevID:        videoGen.ChainPrevID,
+		Duration:           videoGen.Duration,
+		FPS:                videoGen.FPS,
+		Resolution:         videoGen.Resolution,
+		AspectRatio:        videoGen.AspectRatio,
+		Style:              videoGen.Style,
+		MotionLevel:        videoGen.MotionLevel,
+		CameraMotion:       videoGen.CameraMotion,
+		Status:             models.VideoStatusPending,
+		QCRetryOfID:        &videoGen.ID,
+		QCRetries:          videoGen.QCRetries + 1,
+	}
+	if requested > 0 {
+		duration := int(math.Round(requested))
+		retry.Duration = &duration
+	}
+
+	if err := s.db.Create(retry).Error; err != nil {
+		return nil, fmt.Errorf("failed to create record: %w", err)
+	}
+	_, err := s.jobQueue.Enqueue("video_generation", fmt.Sprintf("%d", retry.ID), JobOptions{
+		Queue:    JobQueueVideo,
+		Provider: s.aiService.ResolveProvider("video", retry.Model),
+		Priority: JobPriorityBatch,
+		DramaID:  retry.DramaID,
+		Payload:  videoGenerationPayload{VideoGenID: retry.ID},
+	})
+	if err != nil {
+		s.db.Model(retry).Updates(map[string]interface{}{"status": models.VideoStatusFailed, "error_msg": err.Error()})
+		return nil, err
+	}
+	return retry, nil
+}
+
+// handleImageQCJob 探测图片的纯色画面和尺寸，结果写入生成记录；未通过时按设置自动重新生成
+func (s *QCService) handleImageQCJob(ctx context.Context, task *models.AsyncTask) error {
+	var payload imageQCPayload
+	if err := decodeJobPayload(task, &payload); err != nil {
+		return err
+	}
+
+	var imageGen models.ImageGeneration
+	if err := s.db.First(&imageGen, payload.ImageGenID).Error; err != nil {
+		s.taskService.UpdateTaskError(task.ID, fmt.Errorf("image generation not found"))
+		return nil
+	}
+	if imageGen.Status != models.ImageStatusCompleted {
+		return nil
+	}
+
+	var report qc.Report
+	switch {
+	case imageGen.LocalPath != nil && *imageGen.LocalPath != "":
+		report = s.probeImage(ctx, resolveStoragePath(s.storagePath, *imageGen.LocalPath), s.imageSpec(&imageGen))
+	case imageGen.ImageURL != nil && (strings.HasPrefix(*imageGen.ImageURL, "http://") || strings.HasPrefix(*imageGen.ImageURL, "https://")):
+		report = s.probeImage(ctx, *imageGen.ImageURL, s.imageSpec(&imageGen))
+	default:
+		report = qc.Unreadable(fmt.Errorf("image has no file"))
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	if err := s.saveQCReport(&models.ImageGeneration{}, imageGen.ID, report); err != nil {
+		return err
+	}
+	s.log.Infow("Image QC finished", "id", imageGen.ID, "status", report.Status, "flags", report.Flags())
+
+	if report.Status == qc.StatusFailed && s.settings.AutoRegenerate {
+		if imageGen.QCRetries >= s.settings.MaxRegenerations {
+			s.log.Warnw("Image QC failed, regeneration limit reached", "id", imageGen.ID, "retries", imageGen.QCRetries)
+		} else if retry, err := s.regenerateImage(&imageGen); err != nil {
+			s.log.Errorw("Failed to regenerate image after QC", "error", err, "id", imageGen.ID)
+		} else {
+			s.log.Infow("Image regenerated after QC failure", "id", imageGen.ID, "retry_id", retry.ID)
+		}
+	}
+
+	s.taskService.UpdateTaskResult(task.ID, report)
+	return nil
+}
+
+func (s *QCService) probeImage(ctx context.Context, source string, spec qc.ImageSpec) qc.Report {
+	probe, err := s.ffmpeg.ProbeImageQC(ctx, source)
+	if err != nil {
+		return qc.Unreadable(err)
+	}
+	return qc.CheckImage(*probe, spec)
+}
+
+// imageSpec 图片的请求规格：指定了 size 时按尺寸检查，否则只按提示词要求的宽高比提示
+func (s *QCService) imageSpec(imageGen *models.ImageGeneration) qc.ImageSpec {
+	if width, height := qc.ParseSize(imageGen.Size); width > 0 {
+		return qc.ImageSpec{Width: width, Height: height}
+	}
+	return qc.ImageSpec{AspectRatio: qc.ParseAspectRatio(imagePromptRatio)}
+}
+
+// regenerateImage 按原请求重新生成图片，不沿用随机种子
+func (s *QCService) regenerateImage(imageGen *models.ImageGeneration) (*models.ImageGeneration, error) {
+	retry := &models.ImageGeneration{
+		StoryboardID:    imageGen.StoryboardID,
+		DramaID:         imageGen.DramaID,
+		SceneID:         imageGen.SceneID,
+		CharacterID:     imageGen.CharacterID,
+		PropID:          imageGen.PropID,
+		ImageType:       imageGen.ImageType,
+		FrameType:       imageGen.FrameType,
+		Provider:        imageGen.Provider,
+		Prompt:          imageGen.Prompt,
+		NegPrompt:       imageGen.NegPrompt,
+		Model:           imageGen.Model,
+		Size:            imageGen.Size,
+		Quality:         imageGen.Quality,
+		Style:           imageGen.Style,
+		Steps:           imageGen.Steps,
+		CfgScale:        imageGen.CfgScale,
+		ReferenceImages: imageGen.ReferenceImages,
+		Status:          models.ImageStatusPending,
+		QCRetryOfID:     &imageGen.ID,
+		QCRetries:       imageGen.QCRetries + 1,
+	}
+
+	if err := s.db.Create(retry).Error; err != nil {
+		return nil, fmt.Errorf("failed to create record: %w", err)
+	}
+	_, err := s.jobQueue.Enqueue("image_generation", fmt.Sprintf("%d", retry.ID), JobOptions{
+		Queue:    JobQueueImage,
+		Provider: s.aiService.ResolveProvider("image", retry.Model),
+		Priority: JobPriorityBatch,
+		DramaID:  retry.DramaID,
+		Payload:  imageGenerationPayload{ImageGenID: retry.ID},
+	})
+	if err != nil {
+		s.db.Model(retry).Updates(map[string]interface{}{"status": models.ImageStatusFailed, "error_msg": err.Error()})
+		return nil, err
+	}
+	return retry, nil
+}
+
+// saveQCReport 将质检结论和问题写入生成记录，model 为 VideoGeneration 或 ImageGeneration
+func (s *QCService) saveQCReport(model interface{}, id uint, report qc.Report) error {
+	flags, err := json.Marshal(report.Issues)
+	if err != nil {
+		return fmt.Errorf("failed to marshal qc flags: %w", err)
+	}
+	now := time.Now()
+	return s.db.Model(model).Where("id = ?", id).Updates(map[string]interface{}{
+		"qc_status":     report.Status,
+		"qc_flags":      flags,
+		"qc_checked_at": &now,
+	}).Error
+}
+
+// scheduleVideoQC 视频生成完成后创建质检任务，requested 为完成前记录的请求时长
+func scheduleVideoQC(jobQueue *JobQueue, settings QCSettings, log *logger.Logger, videoGen *models.VideoGeneration, requested *int) {
+	if !settings.Enabled {
+		return
+	}
+	var duration float64
+	if requested != nil {
+		duration = float64(*requested)
+	}
+	if _, err := enqueueVideoQC(jobQueue, videoGen, duration, JobPriorityBatch); err != nil {
+		log.Errorw("Failed to enqueue video QC", "error", err, "video_gen_id", videoGen.ID)
+	}
+}
+
+// scheduleImageQC 图片生成完成后创建质检任务
+func scheduleImageQC(jobQueue *JobQueue, settings QCSettings, log *logger.Logger, imageGen *models.ImageGeneration) {
+	if !settings.Enabled {
+		return
+	}
+	if _, err := enqueueImageQC(jobQueue, imageGen, JobPriorityBatch); err != nil {
+		log.Errorw("Failed to enqueue image QC", "error", err, "image_gen_id", imageGen.ID)
+	}
+}
+
+func enqueueVideoQC(jobQueue *JobQueue, videoGen *models.VideoGeneration, duration float64, priority int) (*models.AsyncTask, error) {
+	return jobQueue.Enqueue("video_qc", fmt.Sprintf("%d", videoGen.ID), JobOptions{
+		Queue:    JobQueueFFmpeg,
+		Priority: priority,
+		DramaID:  videoGen.DramaID,
+		Payload:  videoQCPayload{VideoGenID: videoGen.ID, Duration: duration},
+	})
+}
+
+func enqueueImageQC(jobQueue *JobQueue, imageGen *models.ImageGeneration, priority int) (*models.AsyncTask, error) {
+	return jobQueue.Enqueue("image_qc", fmt.Sprintf("%d", imageGen.ID), JobOptions{
+		Queue:    JobQueueFFmpeg,
+		Priority: priority,
+		DramaID:  imageGen.DramaID,
+		Payload:  imageQCPayload{ImageGenID: imageGen.ID},
+	})
+}
+
+func decodeQCIssues(data []byte) []qc.Issue {
+	var issues []qc.Issue
+	if len(data) > 0 {
+		json.Unmarshal(data, &issues)
+	}
+	return issues
+}
+
+func issueMessages(issues []qc.Issue) string {
+	messages := make([]string, 0, len(issues))
+	for _, issue := range issues {
+		messages = append(messages, issue.Message)
+	}
+	return strings.Join(messages, "；")
+}
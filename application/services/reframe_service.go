@@ -0,0 +1,434 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	models "github.com/drama-generator/backend/domain/models"
+	"github.com/drama-generator/backend/infrastructure/external/ffmpeg"
+	"github.com/drama-generator/backend/pkg/logger"
+	"github.com/drama-generator/backend/pkg/reframe"
+	"gorm.io/gorm"
+)
+
+// ReframeRequest 将视频、图片或章节成片转换到其他宽高比
+type ReframeRequest struct {
+	SourceType  string `json:"source_type" binding:"required"` // video、image 或 merge
+	SourceID    uint   `json:"source_id" binding:"required"`
+	AspectRatio string `json:"aspect_ratio" binding:"required"` // 如 9:16
+	Strategy    string `json:"strategy"`                        // crop、letterbox、blur_pad 或 smart（默认）
+	Reanalyze   bool   `json:"reanalyze"`                       // 丢弃已有（包括手动调整过的）关键帧重新分析
+}
+
+// UpdateReframeKeyframesRequest 手动调整裁剪关键帧
+type UpdateReframeKeyframesRequest struct {
+	Keyframes []reframe.Keyframe `json:"keyframes" binding:"required,min=1"`
+}
+
+// reframePayload 画面比例转换任务参数
+type reframePayload struct {
+	ReframeID uint `json:"reframe_id"`
+}
+
+// reframeSource 转换来源的文件
+type reframeSource struct {
+	DramaID uint
+	Path    string // 本地绝对路径或远程 URL
+	IsImage bool
+}
+
+type ReframeService struct {
+	db          *gorm.DB
+	taskService *TaskService
+	ffmpeg      *ffmpeg.FFmpeg
+	storagePath string
+	baseURL     string
+	log         *logger.Logger
+	jobQueue    *JobQueue
+}
+
+func NewReframeService(db *gorm.DB, jobQueue *JobQueue, taskService *TaskService, storagePath, baseURL string, log *logger.Logger) *ReframeService {
+	service := &ReframeService{
+		db:          db,
+		taskService: taskService,
+		ffmpeg:      ffmpeg.NewFFmpeg(log),
+		storagePath: storagePath,
+		baseURL:     baseURL,
+		log:         log,
+		jobQueue:    jobQueue,
+	}
+
+	service.jobQueue.RegisterHandler("reframe", service.handleReframeJob)
+
+	return service
+}
+
+// CreateReframe 创建或重新生成画面比例转换，同一来源的同一宽高比复用已有记录和关键帧
+func (s *ReframeService) CreateReframe(req *ReframeRequest) (*models.Reframe, error) {
+	aspect, _, err := reframe.ParseAspect(req.AspectRatio)
+	if err != nil {
+		return nil, err
+	}
+	strategy := strings.ToLower(strings.TrimSpace(req.Strategy))
+	if strategy == "" {
+		strategy = ffmpeg.FitSmart
+	}
+	switch strategy {
+	case ffmpeg.FitCrop, ffmpeg.FitLetterbox, ffmpeg.FitBlurPad, ffmpeg.FitSmart:
+	default:
+		return nil, fmt.Errorf("unsupported strategy %q", req.Strategy)
+	}
+	source, err := s.resolveSource(req.SourceType, req.SourceID)
+	if err != nil {
+		return nil, err
+	}
+
+	var record models.Reframe
+	err = s.db.Where("source_type = ? AND source_id = ? AND aspect_ratio = ?", req.SourceType, req.SourceID, aspect).First(&record).Error
+	switch {
+	case err == nil:
+		if s.jobQueue.HasActiveJob("reframe", fmt.Sprintf("%d", record.ID)) {
+			return nil, fmt.Errorf("reframe is already being processed")
+		}
+		updates := map[string]interface{}{
+			"strategy":  strategy,
+			"status":    models.ReframeStatusPending,
+			"error_msg": nil,
+		}
+		if req.Reanalyze {
+			updates["keyframes"] = nil
+			updates["edited"] = false
+		}
+		if err := s.db.Model(&record).Updates(updates).Error; err != nil {
+			return nil, fmt.Errorf("failed to update reframe: %w", err)
+		}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		record = models.Reframe{
+			DramaID:     source.DramaID,
+			SourceType:  req.SourceType,
+			SourceID:    req.SourceID,
+			AspectRatio: aspect,
+			Strategy:    strategy,
+			Status:      models.ReframeStatusPending,
+		}
+		if err := s.db.Create(&record).Error; err != nil {
+			return nil, fmt.Errorf("failed to create reframe: %w", err)
+		}
+	default:
+		return nil, err
+	}
+
+	if err := s.enqueueReframe(&record); err != nil {
+		s.updateReframeError(record.ID, err.Error())
+		return nil, err
+	}
+	return s.GetReframe(record.ID)
+}
+
+// GetReframe 获取画面比例转换记录
+func (s *ReframeService) GetReframe(id uint) (*models.Reframe, error) {
+	var record models.Reframe
+	if err := s.db.First(&record, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("reframe not found")
+		}
+		return nil, err
+	}
+	return &record, nil
+}
+
+// ListReframes 来源的各宽高比转换记录
+func (s *ReframeService) ListReframes(sourceType string, sourceID uint) ([]models.Reframe, error) {
+	var records []models.Reframe
+	if err := s.db.Where("source_type = ? AND source_id = ?", sourceType, sourceID).Order("aspect_ratio").Find(&records).Error; err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// UpdateKeyframes 保存手动调整的裁剪关键帧并改为智能裁剪；已生成文件的记录按新关键帧重新输出，
+// 成片的关键帧在下次生成输出规格时使用
+func (s *ReframeService) UpdateKeyframes(id uint, keyframes []reframe.Keyframe) (*models.Reframe, error) {
+	record, err := s.GetReframe(id)
+	if err != nil {
+		return nil, err
+	}
+	normalized, err := reframe.Normalize(keyframes)
+	if err != nil {
+		return nil, err
+	}
+	if s.jobQueue.HasActiveJob("reframe", fmt.Sprintf("%d", record.ID)) {
+		return nil, fmt.Errorf("reframe is already being processed")
+	}
+	data, err := json.Marshal(normalized)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{
+		"keyframes": data,
+		"edited":    true,
+		"strategy":  ffmpeg.FitSmart,
+	}
+	rerender := record.SourceType != models.ReframeSourceMerge || record.LocalPath != nil
+	if rerender {
+		updates["status"] = models.ReframeStatusPending
+		updates["error_msg"] = nil
+	}
+	if err := s.db.Model(record).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("failed to save keyframes: %w", err)
+	}
+	if rerender {
+		if err := s.enqueueReframe(record); err != nil {
+			s.updateReframeError(record.ID, err.Error())
+			return nil, err
+		}
+	}
+
+	s.log.Infow("Reframe keyframes updated", "id", record.ID, "keyframes", len(normalized), "rerender", rerender)
+	return s.GetReframe(record.ID)
+}
+
+func (s *ReframeService) enqueueReframe(record *models.Reframe) error {
+	_, err := s.jobQueue.Enqueue("reframe", fmt.Sprintf("%d", record.ID), JobOptions{
+		Queue:    JobQueueFFmpeg,
+		Priority: JobPriorityInteractive,
+		DramaID:  record.DramaID,
+		Payload:  reframePayload{ReframeID: record.ID},
+	})
+	return err
+}
+
+// handleReframeJob 任务队列处理函数：智能裁剪没有关键帧时先分析画面主体，再按目标宽高比输出文件
+// 输出文件按时间戳命名，可以安全重跑
+func (s *ReframeService) handleReframeJob(ctx context.Context, task *models.AsyncTask) error {
+	var payload reframePayload
+	if err := decodeJobPayload(task, &payload); err != nil {
+		return err
+	}
+
+	var record models.Reframe
+	if err := s.db.First(&record, payload.ReframeID).Error; err != nil {
+		s.taskService.UpdateTaskError(task.ID, fmt.Errorf("reframe not found"))
+		return nil
+	}
+	s.db.Model(&record).Update("status", models.ReframeStatusProcessing)
+
+	relPath, err := s.render(ctx, task.ID, &record)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != nil {
+		s.log.Errorw("Reframe failed", "error", err, "id", record.ID)
+		s.updateReframeError(record.ID, err.Error())
+		s.taskService.UpdateTaskError(task.ID, err)
+		return nil
+	}
+
+	outputURL := fmt.Sprintf("%s/%s", s.baseURL, relPath)
+	s.taskService.UpdateTaskResult(task.ID, map[string]interface{}{
+		"reframe_id": record.ID,
+		"output_url": outputURL,
+		"local_path": relPath,
+	})
+	s.log.Infow("Reframe completed", "id", record.ID, "aspect_ratio", record.AspectRatio, "strategy", record.Strategy, "output", relPath)
+	return nil
+}
+
+func (s *ReframeService) render(ctx context.Context, taskID string, record *models.Reframe) (string, error) {
+	source, err := s.resolveSource(record.SourceType, record.SourceID)
+	if err != nil {
+		return "", err
+	}
+	_, ratio, err := reframe.ParseAspect(record.AspectRatio)
+	if err != nil {
+		return "", err
+	}
+
+	var duration float64
+	if !source.IsImage {
+		duration, _ = s.ffmpeg.GetVideoDuration(source.Path)
+	}
+
+	var keyframes []reframe.Keyframe
+	if record.Strategy == ffmpeg.FitSmart {
+		s.taskService.UpdateTaskStatus(taskID, "processing", 10, "正在分析画面主体")
+		if keyframes, err = reframeKeyframes(ctx, s.db, s.ffmpeg, record, &ffmpeg.ReframeAnalysisOptions{
+			InputPath: source.Path,
+			Aspect:    ratio,
+			Duration:  duration,
+			IsImage:   source.IsImage,
+		}); err != nil {
+			return "", err
+		}
+	}
+
+	sourceWidth, sourceHeight := s.ffmpeg.GetVideoResolution(source.Path)
+	width, height := reframe.OutputSize(sourceWidth, sourceHeight, ratio)
+	name := fmt.Sprintf("%s_%d_%s_%d", record.SourceType, record.SourceID, strings.ReplaceAll(record.AspectRatio, ":", "x"), time.Now().Unix())
+	relPath := filepath.ToSlash(filepath.Join("videos", "reframed", name+".mp4"))
+	if source.IsImage {
+		relPath = filepath.ToSlash(filepath.Join("images", "reframed", name+".jpg"))
+	}
+
+	s.taskService.UpdateTaskStatus(taskID, "processing", 30, "正在转换画面比例")
+	if err := s.ffmpeg.Reframe(ctx, &ffmpeg.ReframeOptions{
+		InputPath:  source.Path,
+		OutputPath: filepath.Join(s.storagePath, relPath),
+		Width:      width,
+		Height:     height,
+		Fit:        record.Strategy,
+		Keyframes:  keyframes,
+		IsImage:    source.IsImage,
+		Duration:   duration,
+		Progress: func(percent int) {
+			s.taskService.UpdateTaskStatus(taskID, "processing", 30+percent*70/100, fmt.Sprintf("正在转换画面比例 %d%%", percent))
+		},
+	}); err != nil {
+		return "", err
+	}
+
+	outputURL := fmt.Sprintf("%s/%s", s.baseURL, relPath)
+	if err := s.db.Model(record).Updates(map[string]interface{}{
+		"status":     models.ReframeStatusCompleted,
+		"width":      width,
+		"height":     height,
+		"output_url": outputURL,
+		"local_path": relPath,
+		"error_msg":  nil,
+	}).Error; err != nil {
+		return "", fmt.Errorf("failed to save reframe: %w", err)
+	}
+	return relPath, nil
+}
+
+// resolveSource 查找转换来源的文件，来源需已生成完成
+func (s *ReframeService) resolveSource(sourceType string, sourceID uint) (*reframeSource, error) {
+	switch sourceType {
+	case models.ReframeSourceVideo:
+		var videoGen models.VideoGeneration
+		if err := s.db.First(&videoGen, sourceID).Error; err != nil {
+			return nil, fmt.Errorf("video generation not found")
+		}
+		if videoGen.Status != models.VideoStatusCompleted {
+			return nil, fmt.Errorf("video is not completed")
+		}
+		switch {
+		case videoGen.LocalPath != nil && *videoGen.LocalPath != "":
+			return &reframeSource{DramaID: videoGen.DramaID, Path: resolveStoragePath(s.storagePath, *videoGen.LocalPath)}, nil
+		case videoGen.VideoURL != nil && *videoGen.VideoURL != "":
+			return &reframeSource{DramaID: videoGen.DramaID, Path: *videoGen.VideoURL}, nil
+		}
+		return nil, fmt.Errorf("video has no file")
+	case models.ReframeSourceImage:
+		var imageGen models.ImageGeneration
+		if err := s.db.First(&imageGen, sourceID).Error; err != nil {
+			return nil, fmt.Errorf("image generation not found")
+		}
+		if imageGen.Status != models.ImageStatusCompleted {
+			return nil, fmt.Errorf("image is not completed")
+		}
+		switch {
+		case imageGen.LocalPath != nil && *imageGen.LocalPath != "":
+			return &reframeSource{DramaID: imageGen.DramaID, Path: resolveStoragePath(s.storagePath, *imageGen.LocalPath), IsImage: true}, nil
+		case imageGen.ImageURL != nil && (strings.HasPrefix(*imageGen.ImageURL, "http://") || strings.HasPrefix(*imageGen.ImageURL, "https://")):
+			return &reframeSource{DramaID: imageGen.DramaID, Path: *imageGen.ImageURL, IsImage: true}, nil
+		}
+		return nil, fmt.Errorf("image has no file")
+	case models.ReframeSourceMerge:
+		var merge models.VideoMerge
+		if err := s.db.First(&merge, sourceID).Error; err != nil {
+			return nil, fmt.Errorf("video merge not found")
+		}
+		if merge.Status != models.VideoMergeStatusCompleted || merge.MergedURL == nil || *merge.MergedURL == "" {
+			return nil, fmt.Errorf("video merge is not completed")
+		}
+		return &reframeSource{DramaID: merge.DramaID, Path: mergeSourcePath(s.storagePath, *merge.MergedURL)}, nil
+	}
+	return nil, fmt.Errorf("unsupported source type %q", sourceType)
+}
+
+func (s *ReframeService) updateReframeError(id uint, message string) {
+	s.db.Model(&models.Reframe{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":    models.ReframeStatusFailed,
+		"error_msg": message,
+	})
+}
+
+// reframeKeyframes 智能裁剪的关键帧：记录中已有（分析过或手动调整过）的直接使用，否则分析画面主体并保存到记录
+func reframeKeyframes(ctx context.Context, db *gorm.DB, ff *ffmpeg.FFmpeg, record *models.Reframe, analysis *ffmpeg.ReframeAnalysisOptions) ([]reframe.Keyframe, error) {
+	if len(record.Keyframes) > 0 {
+		var keyframes []reframe.Keyframe
+		if err := json.Unmarshal(record.Keyframes, &keyframes); err == nil && len(keyframes) > 0 {
+			return keyframes, nil
+		}
+	}
+
+	keyframes, err := ff.AnalyzeReframe(ctx, analysis)
+	if err != nil {
+		return nil, fmt.Errorf("failed to analyze frames: %w", err)
+	}
+	data, err := json.Marshal(keyframes)
+	if err != nil {
+		return nil, err
+	}
+	record.Keyframes = data
+	record.Edited = false
+	if record.ID == 0 {
+		err = db.Create(record).Error
+	} else {
+		err = db.Model(record).Updates(map[string]interface{}{"keyframes": data, "edited": false}).Error
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to save keyframes: %w", err)
+	}
+	return keyframes, nil
+}
+
+// mergeReframeKeyframes 成片按智能裁剪输出规格转码时的关键帧，按宽高比保存在成片的转换记录中，
+// 分析失败时返回空，转码退化为居中裁剪
+func mergeReframeKeyframes(ctx context.Context, db *gorm.DB, ff *ffmpeg.FFmpeg, log *logger.Logger, merge *models.VideoMerge, source string, duration float64, profile ffmpeg.OutputProfile) []reframe.Keyframe {
+	aspect := reframe.FormatAspect(profile.Width, profile.Height)
+	var record models.Reframe
+	err := db.Where("source_type = ? AND source_id = ? AND aspect_ratio = ?", models.ReframeSourceMerge, merge.ID, aspect).First(&record).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Warnw("Failed to load reframe keyframes", "error", err, "merge_id", merge.ID)
+		return nil
+	}
+	if record.ID == 0 {
+		record = models.Reframe{
+			DramaID:     merge.DramaID,
+			SourceType:  models.ReframeSourceMerge,
+			SourceID:    merge.ID,
+			AspectRatio: aspect,
+			Strategy:    ffmpeg.FitSmart,
+			Status:      models.ReframeStatusCompleted,
+			Width:       profile.Width,
+			Height:      profile.Height,
+		}
+	}
+
+	keyframes, err := reframeKeyframes(ctx, db, ff, &record, &ffmpeg.ReframeAnalysisOptions{
+		InputPath: source,
+		Aspect:    float64(profile.Width) / float64(profile.Height),
+		Duration:  duration,
+	})
+	if err != nil {
+		log.Warnw("Smart crop analysis failed, using center crop", "error", err, "merge_id", merge.ID, "profile", profile.Name)
+		return nil
+	}
+	return keyframes
+}
+
+// mergeSourcePath 成片地址对应的本地路径，远程地址原样返回
+func mergeSourcePath(storagePath, mergedURL string) string {
+	if strings.HasPrefix(mergedURL, "http://") || strings.HasPrefix(mergedURL, "https://") {
+		return mergedURL
+	}
+	return resolveStoragePath(storagePath, mergedURL)
+}
@@ -0,0 +1,506 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	models "github.com/drama-generator/backend/domain/models"
+	"github.com/drama-generator/backend/infrastructure/external/ffmpeg"
+	"github.com/drama-generator/backend/pkg/logger"
+	"github.com/drama-generator/backend/pkg/reframe"
+	"gorm.io/gorm"
+)
+
+// HLS 切片时长的默认值和上限（秒），需为转码关键帧间隔（2 秒）的整数倍
+const (
+	defaultHLSSegmentSeconds = 6
+	maxHLSSegmentSeconds     = 30
+)
+
+// OutputSettings 剧本的成片输出设置：合成完成后按输出规格转码，可选生成 HLS 码率阶梯
+type OutputSettings struct {
+	Profiles       []ffmpeg.OutputProfile `json:"profiles"`
+	HLS            bool                   `json:"hls"`
+	SegmentSeconds int                    `json:"segment_seconds,omitempty"`
+}
+
+// Normalize 校验输出规格并补全默认值，规格名称不能重复
+func (o OutputSettings) Normalize() (OutputSettings, error) {
+	normalized := OutputSettings{HLS: o.HLS, SegmentSeconds: o.SegmentSeconds}
+	seen := make(map[string]bool, len(o.Profiles))
+	for _, profile := range o.Profiles {
+		p, err := profile.Normalize()
+		if err != nil {
+			return o, err
+		}
+		if seen[p.Name] {
+			return o, fmt.Errorf("duplicate output profile %q", p.Name)
+		}
+		seen[p.Name] = true
+		normalized.Profiles = append(normalized.Profiles, p)
+	}
+
+	if normalized.HLS && len(normalized.Profiles) == 0 {
+		return o, fmt.Errorf("hls requires at least one output profile")
+	}
+	if normalized.SegmentSeconds == 0 {
+		normalized.SegmentSeconds = defaultHLSSegmentSeconds
+	}
+	if normalized.SegmentSeconds < 2 || normalized.SegmentSeconds > maxHLSSegmentSeconds {
+		return o, fmt.Errorf("segment_seconds must be between 2 and %d", maxHLSSegmentSeconds)
+	}
+	// 切片边界需要落在关键帧上
+	normalized.SegmentSeconds += normalized.SegmentSeconds % 2
+	return normalized, nil
+}
+
+// Empty 没有需要额外生成的输出
+func (o *OutputSettings) Empty() bool {
+	return o == nil || len(o.Profiles) == 0
+}
+
+// OutputSelection 合成或重新生成时选择的输出：Profiles 为空时使用剧本设置的全部规格，
+// 可以填写剧本未设置的内置规格名称；HLS 为空时使用剧本设置
+type OutputSelection struct {
+	Profiles []string `json:"output_profiles"`
+	HLS      *bool    `json:"hls"`
+}
+
+// episodeRenditionsPayload 输出规格转码任务参数
+type episodeRenditionsPayload struct {
+	EpisodeID uint           `json:"episode_id"`
+	MergeID   uint           `json:"merge_id"`
+	Settings  OutputSettings `json:"settings"`
+}
+
+// EpisodeRenditions 章节当前成片的各规格输出
+type EpisodeRenditions struct {
+	EpisodeID  uint           `json:"episode_id"`
+	VideoURL   *string        `json:"video_url"`
+	Renditions []models.Asset `json:"renditions"`
+	HLS        *models.Asset  `json:"hls,omitempty"`
+}
+
+type RenditionService struct {
+	db          *gorm.DB
+	taskService *TaskService
+	ffmpeg      *ffmpeg.FFmpeg
+	storagePath string
+	baseURL     string
+	log         *logger.Logger
+	jobQueue    *JobQueue
+}
+
+func NewRenditionService(db *gorm.DB, jobQueue *JobQueue, taskService *TaskService, storagePath, baseURL string, log *logger.Logger) *RenditionService {
+	service := &RenditionService{
+		db:          db,
+		taskService: taskService,
+		ffmpeg:      ffmpeg.NewFFmpeg(log),
+		storagePath: storagePath,
+		baseURL:     baseURL,
+		log:         log,
+		jobQueue:    jobQueue,
+	}
+
+	service.jobQueue.RegisterHandler("episode_renditions", service.handleEpisodeRenditionsJob)
+
+	return service
+}
+
+// GetDramaOutputSettings 获取剧本的成片输出设置
+func (s *RenditionService) GetDramaOutputSettings(dramaID uint) (*OutputSettings, error) {
+	var drama models.Drama
+	if err := s.db.Select("id", "output_settings").First(&drama, dramaID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("drama not found")
+		}
+		return nil, err
+	}
+
+	settings := dramaOutputSettings(s.log, &drama)
+	return &settings, nil
+}
+
+// UpdateDramaOutputSettings 保存剧本的成片输出设置，规格只填写名称时使用同名的内置规格
+func (s *RenditionService) UpdateDramaOutputSettings(dramaID uint, settings OutputSettings) (*OutputSettings, error) {
+	var drama models.Drama
+	if err := s.db.Select("id").First(&drama, dramaID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("drama not found")
+		}
+		return nil, err
+	}
+
+	normalized, err := settings.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(normalized)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.Model(&drama).Update("output_settings", data).Error; err != nil {
+		return nil, fmt.Errorf("failed to save output settings: %w", err)
+	}
+
+	s.log.Infow("Output settings updated", "drama_id", dramaID, "profiles", len(normalized.Profiles), "hls", normalized.HLS)
+	return &normalized, nil
+}
+
+// RenderEpisodeOutputs 为章节当前成片重新生成输出规格，未指定时使用剧本设置
+func (s *RenditionService) RenderEpisodeOutputs(episodeID uint, selection OutputSelection) (*models.AsyncTask, error) {
+	var episode models.Episode
+	if err := s.db.Preload("Drama").First(&episode, episodeID).Error; err != nil {
+		return nil, fmt.Errorf("episode not found")
+	}
+	if episode.VideoURL == nil || *episode.VideoURL == "" {
+		return nil, fmt.Errorf("episode has no finalized video")
+	}
+	var merge models.VideoMerge
+	if err := s.db.Where("episode_id = ? AND status = ? AND merged_url = ?", episode.ID, models.VideoMergeStatusCompleted, *episode.VideoURL).
+		Order("completed_at DESC").First(&merge).Error; err != nil {
+		return nil, fmt.Errorf("episode has no completed merge")
+	}
+
+	settings, err := resolveOutputSettings(s.log, &episode.Drama, selection)
+	if err != nil {
+		return nil, err
+	}
+	if settings.Empty() {
+		return nil, fmt.Errorf("no output profiles configured for this drama")
+	}
+	if s.jobQueue.HasActiveJob("episode_renditions", fmt.Sprintf("%d", episodeID)) {
+		return nil, fmt.Errorf("episode renditions are already being processed")
+	}
+
+	return enqueueEpisodeRenditions(s.jobQueue, s.log, &merge, settings)
+}
+
+// ListEpisodeRenditions 章节当前成片的各规格输出；成片重新合成后旧的输出不再返回
+func (s *RenditionService) ListEpisodeRenditions(episodeID uint) (*EpisodeRenditions, error) {
+	var episode models.Episode
+	if err := s.db.First(&episode, episodeID).Error; err != nil {
+		return nil, fmt.Errorf("episode not found")
+	}
+
+	result := &EpisodeRenditions{EpisodeID: episode.ID, VideoURL: episode.VideoURL, Renditions: []models.Asset{}}
+	if episode.VideoURL == nil || *episode.VideoURL == "" {
+		return result, nil
+	}
+
+	query := s.db.Where("episode_id = ? AND type = ?", episode.ID, models.AssetTypeVideo)
+	var merge models.VideoMerge
+	if err := s.db.Where("episode_id = ? AND status = ? AND merged_url = ?", episode.ID, models.VideoMergeStatusCompleted, *episode.VideoURL).
+		Order("completed_at DESC").First(&merge).Error; err == nil && merge.CompletedAt != nil {
+		query = query.Where("created_at >= ?", *merge.CompletedAt)
+	}
+
+	var assets []models.Asset
+	if err := query.Session(&gorm.Session{}).Where("category = ?", models.AssetCategoryRendition).Order("created_at DESC").Find(&assets).Error; err != nil {
+		return nil, err
+	}
+	// 同一规格只返回最新的一次
+	seen := make(map[string]bool)
+	for _, asset := range assets {
+		if seen[asset.Name] {
+			continue
+		}
+		seen[asset.Name] = true
+		result.Renditions = append(result.Renditions, asset)
+	}
+
+	var hls models.Asset
+	if err := query.Session(&gorm.Session{}).Where("category = ?", models.AssetCategoryHLS).Order("created_at DESC").First(&hls).Error; err == nil {
+		result.HLS = &hls
+	}
+	return result, nil
+}
+
+// handleEpisodeRenditionsJob 任务队列处理函数，输出文件按时间戳命名，可以安全重跑
+func (s *RenditionService) handleEpisodeRenditionsJob(ctx context.Context, task *models.AsyncTask) error {
+	var payload episodeRenditionsPayload
+	if err := decodeJobPayload(task, &payload); err != nil {
+		return err
+	}
+
+	assets, err := s.renderOutputs(ctx, task.ID, &payload)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != nil {
+		s.log.Errorw("Episode renditions failed", "error", err, "episode_id", payload.EpisodeID, "merge_id", payload.MergeID)
+		s.taskService.UpdateTaskError(task.ID, err)
+		return nil
+	}
+
+	outputs := make([]map[string]interface{}, 0, len(assets))
+	for _, asset := range assets {
+		outputs = append(outputs, map[string]interface{}{
+			"asset_id":   asset.ID,
+			"name":       asset.Name,
+			"category":   asset.Category,
+			"url":        asset.URL,
+			"local_path": asset.LocalPath,
+		})
+	}
+	s.taskService.UpdateTaskResult(task.ID, map[string]interface{}{
+		"episode_id": payload.EpisodeID,
+		"merge_id":   payload.MergeID,
+		"outputs":    outputs,
+	})
+	return nil
+}
+
+func (s *RenditionService) renderOutputs(ctx context.Context, taskID string, payload *episodeRenditionsPayload) ([]*models.Asset, error) {
+	var merge models.VideoMerge
+	if err := s.db.Preload("Episode").First(&merge, payload.MergeID).Error; err != nil {
+		return nil, fmt.Errorf("video merge not found")
+	}
+	if merge.Status != models.VideoMergeStatusCompleted || merge.MergedURL == nil || *merge.MergedURL == "" {
+		return nil, fmt.Errorf("video merge is not completed")
+	}
+	episode := merge.Episode
+
+	source := mergeSourcePath(s.storagePath, *merge.MergedURL)
+	duration, _ := s.ffmpeg.GetVideoDuration(source)
+
+	stamp := time.Now().Unix()
+	profiles := payload.Settings.Profiles
+	steps := len(profiles)
+	if payload.Settings.HLS {
+		steps++
+	}
+
+	var assets []*models.Asset
+	var variants []ffmpeg.HLSVariant
+	for i, profile := range profiles {
+		step := i
+		s.taskService.UpdateTaskStatus(taskID, "processing", step*100/steps, fmt.Sprintf("正在生成 %s", profile.Name))
+
+		// 智能裁剪使用成片在该宽高比下保存的关键帧，没有时先分析画面主体
+		var keyframes []reframe.Keyframe
+		if profile.Fit == ffmpeg.FitSmart {
+			s.taskService.UpdateTaskStatus(taskID, "processing", step*100/steps, fmt.Sprintf("正在分析 %s 的画面主体", profile.Name))
+			keyframes = mergeReframeKeyframes(ctx, s.db, s.ffmpeg, s.log, &merge, source, duration, profile)
+			if ctx.Err() != nil {
+				return assets, ctx.Err()
+			}
+		}
+
+		relPath := filepath.ToSlash(filepath.Join("videos", "renditions", fmt.Sprintf("episode_%d_%s_%d.mp4", episode.ID, profile.Name, stamp)))
+		outputPath := filepath.Join(s.storagePath, relPath)
+		if err := s.ffmpeg.TranscodeRendition(ctx, &ffmpeg.RenditionOptions{
+			InputPath:  source,
+			OutputPath: outputPath,
+			Profile:    profile,
+			Keyframes:  keyframes,
+			Duration:   duration,
+			Progress: func(percent int) {
+				s.taskService.UpdateTaskStatus(taskID, "processing", (step*100+percent)/steps,
+					fmt.Sprintf("正在生成 %s %d%%", profile.Name, percent))
+			},
+		}); err != nil {
+			return assets, fmt.Errorf("failed to transcode %s: %w", profile.Name, err)
+		}
+
+		asset, err := s.createOutputAsset(&episode, profile, models.AssetCategoryRendition, relPath, "video/mp4", "mp4", duration)
+		if err != nil {
+			os.Remove(outputPath)
+			return assets, err
+		}
+		assets = append(assets, asset)
+		variants = append(variants, ffmpeg.HLSVariant{Profile: profile, InputPath: outputPath})
+	}
+
+	if payload.Settings.HLS {
+		s.taskService.UpdateTaskStatus(taskID, "processing", len(profiles)*100/steps, "正在生成 HLS 切片")
+
+		relDir := filepath.ToSlash(filepath.Join("videos", "hls", fmt.Sprintf("episode_%d_%d", episode.ID, stamp)))
+		outputDir := filepath.Join(s.storagePath, relDir)
+		if _, err := s.ffmpeg.PackageHLS(ctx, &ffmpeg.HLSOptions{
+			Variants:       variants,
+			OutputDir:      outputDir,
+			SegmentSeconds: payload.Settings.SegmentSeconds,
+		}); err != nil {
+			os.RemoveAll(outputDir)
+			return assets, err
+		}
+
+		// 主播放列表的分辨率取最高一路
+		top := profiles[0]
+		for _, profile := range profiles[1:] {
+			if profile.Bandwidth() > top.Bandwidth() {
+				top = profile
+			}
+		}
+		top.Name = "HLS"
+		relPath := relDir + "/" + ffmpeg.HLSMasterPlaylist
+		asset, err := s.createOutputAsset(&episode, top, models.AssetCategoryHLS, relPath, "application/vnd.apple.mpegurl", "m3u8", duration)
+		if err != nil {
+			os.RemoveAll(outputDir)
+			return assets, err
+		}
+		assets = append(assets, asset)
+	}
+
+	s.log.Infow("Episode renditions completed", "episode_id", episode.ID, "merge_id", merge.ID, "outputs", len(assets))
+	return assets, nil
+}
+
+// createOutputAsset 将输出文件登记为章节素材，Name 使用规格名称便于按规格查找
+func (s *RenditionService) createOutputAsset(episode *models.Episode, profile ffmpeg.OutputProfile, category, relPath, mimeType, fileFormat string, duration float64) (*models.Asset, error) {
+	description := fmt.Sprintf("第%d集 %dx%d %s %s", episode.EpisodeNum, profile.Width, profile.Height, strings.ToUpper(profile.Codec), profile.VideoBitrate)
+	if category == models.AssetCategoryRendition {
+		description += " " + profile.Fit
+	}
+	width, height := profile.Width, profile.Height
+	asset := &models.Asset{
+		DramaID:     &episode.DramaID,
+		EpisodeID:   &episode.ID,
+		Name:        profile.Name,
+		Description: &description,
+		Type:        models.AssetTypeVideo,
+		Category:    &category,
+		URL:         fmt.Sprintf("%s/%s", s.baseURL, relPath),
+		LocalPath:   &relPath,
+		MimeType:    &mimeType,
+		Format:      &fileFormat,
+		Width:       &width,
+		Height:      &height,
+
+		ThumbnailURL: episode.Thumbnail,
+		SpriteVTTURL: episode.SpriteVTTURL,
+	}
+	if duration > 0 {
+		seconds := int(math.Ceil(duration))
+		durationMs := int(math.Round(duration * 1000))
+		asset.Duration = &seconds
+		asset.DurationMs = &durationMs
+	}
+	if info, err := os.Stat(filepath.Join(s.storagePath, relPath)); err == nil {
+		size := info.Size()
+		asset.FileSize = &size
+	}
+
+	if err := s.db.Create(asset).Error; err != nil {
+		return nil, fmt.Errorf("failed to create asset: %w", err)
+	}
+	return asset, nil
+}
+
+// scheduleEpisodeRenditions 合成完成后按合成记录保存的输出设置创建转码任务
+func scheduleEpisodeRenditions(jobQueue *JobQueue, log *logger.Logger, merge *models.VideoMerge) {
+	if merge.EpisodeID == 0 || len(merge.OutputSettings) == 0 {
+		return
+	}
+	var settings OutputSettings
+	if err := json.Unmarshal(merge.OutputSettings, &settings); err != nil {
+		log.Warnw("Failed to parse merge output settings", "error", err, "merge_id", merge.ID)
+		return
+	}
+	if settings.Empty() {
+		return
+	}
+	if _, err := enqueueEpisodeRenditions(jobQueue, log, merge, &settings); err != nil {
+		log.Errorw("Failed to enqueue episode renditions", "error", err, "merge_id", merge.ID)
+	}
+}
+
+// enqueueEpisodeRenditions 转码是 CPU 密集型任务，放入 ffmpeg 工作池
+func enqueueEpisodeRenditions(jobQueue *JobQueue, log *logger.Logger, merge *models.VideoMerge, settings *OutputSettings) (*models.AsyncTask, error) {
+	task, err := jobQueue.Enqueue("episode_renditions", fmt.Sprintf("%d", merge.EpisodeID), JobOptions{
+		Queue:    JobQueueFFmpeg,
+		Priority: JobPriorityBatch,
+		DramaID:  merge.DramaID,
+		Payload:  episodeRenditionsPayload{EpisodeID: merge.EpisodeID, MergeID: merge.ID, Settings: *settings},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infow("Episode renditions queued", "episode_id", merge.EpisodeID, "merge_id", merge.ID, "profiles", len(settings.Profiles), "hls", settings.HLS, "task_id", task.ID)
+	return task, nil
+}
+
+// dramaOutputSettings 解析剧本的输出设置，未设置或无效时返回空设置
+func dramaOutputSettings(log *logger.Logger, drama *models.Drama) OutputSettings {
+	empty := OutputSettings{Profiles: []ffmpeg.OutputProfile{}, SegmentSeconds: defaultHLSSegmentSeconds}
+	if len(drama.OutputSettings) == 0 {
+		return empty
+	}
+	var settings OutputSettings
+	if err := json.Unmarshal(drama.OutputSettings, &settings); err != nil {
+		log.Warnw("Failed to parse output settings", "error", err, "drama_id", drama.ID)
+		return empty
+	}
+	normalized, err := settings.Normalize()
+	if err != nil {
+		log.Warnw("Invalid output settings", "error", err, "drama_id", drama.ID)
+		return empty
+	}
+	if normalized.Profiles == nil {
+		normalized.Profiles = []ffmpeg.OutputProfile{}
+	}
+	return normalized
+}
+
+// resolveOutputSettings 按请求的选择确定本次需要生成的输出，规格优先使用剧本中的同名设置，其次使用内置规格
+func resolveOutputSettings(log *logger.Logger, drama *models.Drama, selection OutputSelection) (*OutputSettings, error) {
+	configured := dramaOutputSettings(log, drama)
+	settings := OutputSettings{HLS: configured.HLS, SegmentSeconds: configured.SegmentSeconds}
+	if selection.HLS != nil {
+		settings.HLS = *selection.HLS
+	}
+
+	if len(selection.Profiles) == 0 {
+		settings.Profiles = configured.Profiles
+	} else {
+		byName := make(map[string]ffmpeg.OutputProfile, len(configured.Profiles))
+		for _, profile := range configured.Profiles {
+			byName[profile.Name] = profile
+		}
+		for _, name := range selection.Profiles {
+			name = strings.ToLower(strings.TrimSpace(name))
+			if profile, ok := byName[name]; ok {
+				settings.Profiles = append(settings.Profiles, profile)
+				continue
+			}
+			if _, ok := ffmpeg.OutputProfiles[name]; !ok {
+				return nil, fmt.Errorf("unknown output profile %q, available: %s",
+					name, strings.Join(outputProfileNames(configured), ", "))
+			}
+			settings.Profiles = append(settings.Profiles, ffmpeg.OutputProfile{Name: name})
+		}
+	}
+
+	normalized, err := settings.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	return &normalized, nil
+}
+
+// outputProfileNames 剧本设置的规格和内置规格名称
+func outputProfileNames(configured OutputSettings) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, profile := range configured.Profiles {
+		seen[profile.Name] = true
+		names = append(names, profile.Name)
+	}
+	for name := range ffmpeg.OutputProfiles {
+		if !seen[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 
@@ -19,17 +20,23 @@ type ScriptGenerationService struct {
 	config      *config.Config
 	promptI18n  *PromptI18n
 	taskService *TaskService
+	jobQueue    *JobQueue
 }
 
-func NewScriptGenerationService(db *gorm.DB, cfg *config.Config, log *logger.Logger) *ScriptGenerationService {
-	return &ScriptGenerationService{
+func NewScriptGenerationService(db *gorm.DB, jobQueue *JobQueue, taskService *TaskService, aiService *AIService, cfg *config.Config, log *logger.Logger) *ScriptGenerationService {
+	service := &ScriptGenerationService{
 		db:          db,
-		aiService:   NewAIService(db, log),
+		aiService:   aiService,
 		log:         log,
 		config:      cfg,
 		promptI18n:  NewPromptI18n(cfg),
-		taskService: NewTaskService(db, log),
+		taskService: taskService,
+		jobQueue:    jobQueue,
 	}
+
+	service.jobQueue.RegisterHandler("character_generation", service.handleCharacterGenerationJob)
+
+	return service
 }
 
 type GenerateCharactersRequest struct {
@@ -47,22 +54,35 @@ func (s *ScriptGenerationService) GenerateCharacters(req *GenerateCharactersRequ
 		return "", fmt.Errorf("drama not found")
 	}
 
-	// 创建任务
-	task, err := s.taskService.CreateTask("character_generation", req.DramaID)
+	// 创建排队任务，异步处理角色生成
+	task, err := s.jobQueue.Enqueue("character_generation", req.DramaID, JobOptions{
+		Queue:    JobQueueText,
+		Provider: s.aiService.ResolveProvider("text", req.Model),
+		Priority: JobPriorityInteractive,
+		DramaID:  drama.ID,
+		Payload:  req,
+	})
 	if err != nil {
 		s.log.Errorw("Failed to create character generation task", "error", err)
 		return "", fmt.Errorf("创建任务失败: %w", err)
 	}
 
-	// 异步处理角色生成
-	go s.processCharacterGeneration(task.ID, req)
-
 	s.log.Infow("Character generation task created", "task_id", task.ID, "drama_id", req.DramaID)
 	return task.ID, nil
 }
 
+// handleCharacterGenerationJob 任务队列处理函数
+func (s *ScriptGenerationService) handleCharacterGenerationJob(ctx context.Context, task *models.AsyncTask) error {
+	var req GenerateCharactersRequest
+	if err := decodeJobPayload(task, &req); err != nil {
+		return err
+	}
+	s.processCharacterGeneration(ctx, task.ID, &req)
+	return ctx.Err()
+}
+
 // processCharacterGeneration 异步处理角色生成
-func (s *ScriptGenerationService) processCharacterGeneration(taskID string, req *GenerateCharactersRequest) {
+func (s *ScriptGenerationService) processCharacterGeneration(ctx context.Context, taskID string, req *GenerateCharactersRequest) {
 	// 更新任务状态为处理中
 	s.taskService.UpdateTaskStatus(taskID, "processing", 0, "正在生成角色...")
 
@@ -94,19 +114,13 @@ func (s *ScriptGenerationService) processCharacterGeneration(taskID string, req
 	}
 
 	// 如果指定了模型，使用指定的模型；否则使用默认配置
-	var text string
-	var err error
 	if req.Model != "" {
 		s.log.Infow("Using specified model for character generation", "model", req.Model, "task_id", taskID)
-		client, getErr := s.aiService.GetAIClientForModel("text", req.Model)
-		if getErr != nil {
-			s.log.Warnw("Failed to get client for specified model, using default", "model", req.Model, "error", getErr, "task_id", taskID)
-			text, err = s.aiService.GenerateText(userPrompt, systemPrompt, ai.WithTemperature(temperature))
-		} else {
-			text, err = client.GenerateText(userPrompt, systemPrompt, ai.WithTemperature(temperature))
-		}
-	} else {
-		text, err = s.aiService.GenerateText(userPrompt, systemPrompt, ai.WithTemperature(temperature))
+	}
+	text, err := s.aiService.GenerateTextWithContext(ctx, req.Model, userPrompt, systemPrompt, ai.WithTemperature(temperature))
+	// 任务被暂停、取消或队列停止，不写入失败状态
+	if ctx.Err() != nil {
+		return
 	}
 
 	if err != nil {
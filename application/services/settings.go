@@ -0,0 +1,40 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/drama-generator/backend/pkg/config"
+)
+
+// Settings 由配置文件生成的业务设置，启动时校验一次，再由各服务的构造函数传入
+type Settings struct {
+	Retry     RetryPolicy
+	Callback  VideoCallbackSettings
+	Mastering MasteringSettings
+	QC        QCSettings
+	FrameRate FrameRateSettings
+}
+
+// NewSettings 根据配置文件生成业务设置，配置无效时返回错误
+func NewSettings(cfg *config.Config) (*Settings, error) {
+	callback, err := NewVideoCallbackSettings(cfg.Callback)
+	if err != nil {
+		return nil, fmt.Errorf("invalid callback config: %w", err)
+	}
+	mastering, err := NewMasteringSettings(cfg.Mastering)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mastering config: %w", err)
+	}
+	frameRate, err := NewFrameRateSettings(cfg.FrameRate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid frame rate config: %w", err)
+	}
+
+	return &Settings{
+		Retry:     NewRetryPolicy(cfg.AI),
+		Callback:  callback,
+		Mastering: mastering,
+		QC:        NewQCSettings(cfg.QC),
+		FrameRate: frameRate,
+	}, nil
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"strconv"
 
 	"fmt"
@@ -19,20 +20,33 @@ type StoryboardService struct {
 	db          *gorm.DB
 	aiService   *AIService
 	taskService *TaskService
+	jobQueue    *JobQueue
 	log         *logger.Logger
 	config      *config.Config
 	promptI18n  *PromptI18n
 }
 
-func NewStoryboardService(db *gorm.DB, cfg *config.Config, log *logger.Logger) *StoryboardService {
-	return &StoryboardService{
+func NewStoryboardService(db *gorm.DB, jobQueue *JobQueue, taskService *TaskService, aiService *AIService, cfg *config.Config, log *logger.Logger) *StoryboardService {
+	service := &StoryboardService{
 		db:          db,
-		aiService:   NewAIService(db, log),
-		taskService: NewTaskService(db, log),
+		aiService:   aiService,
+		taskService: taskService,
+		jobQueue:    jobQueue,
 		log:         log,
 		config:      cfg,
 		promptI18n:  NewPromptI18n(cfg),
 	}
+
+	service.jobQueue.RegisterHandler("storyboard_generation", service.handleStoryboardGenerationJob)
+
+	return service
+}
+
+// storyboardGenerationPayload 分镜生成任务参数
+type storyboardGenerationPayload struct {
+	EpisodeID string `json:"episode_id"`
+	Model     string `json:"model"`
+	Prompt    string `json:"prompt"`
 }
 
 type Storyboard struct {
@@ -150,9 +164,6 @@ func (s *StoryboardService) GenerateStoryboard(episodeID string, model string) (
 
 %s
 
-【剧本原文】
-%s
-
 【分镜要素】每个镜头聚焦单一动作，描述要详尽具体：
 1. **镜头标题(title)**：用3-5个字概括该镜头的核心内容或情绪
    - 例如："噩梦惊醒"、"对视沉思"、"逃离现场"、"意外发现"
@@ -212,7 +223,7 @@ func (s *StoryboardService) GenerateStoryboard(episodeID string, model string) (
       "action": "陈峥缓缓转身，目光与身后的李芳对视，李芳手握手电筒，光束在两人之间晃动，眼神中透露疑惑和警惕",
       "dialogue": "陈峥：\"我们被耍了，这里根本没有我们要找的东西。\" 李芳：\"现在怎么办？我们的时间不多了。\"",
       "result": "两人站在昏暗中陷入沉思，手电筒光束照在地面形成圆形光斑，背景传来微弱的金属摩擦声，气氛紧张凝重",
-      "atmosphere": "低调光线·暗部占画面70%，侧面硬光勾勒人物轮廓，冷暖光对比强烈，海风吹过产生呼啸声，营造紧迫感",
+      "atmosphere": "低调光线·暗部占画面70%%，侧面硬光勾勒人物轮廓，冷暖光对比强烈，海风吹过产生呼啸声，营造紧迫感",
       "emotion": "紧张感↑↑·警惕↑↑（悬置）",
       "duration": 7,
       "bgm_prompt": "紧张感逐渐升级的音效，低频持续音",
@@ -320,8 +331,20 @@ func (s *StoryboardService) GenerateStoryboard(episodeID string, model string) (
 - 为视频生成AI提供足够的画面构建信息
 - 避免抽象词汇，使用具象的视觉化描述`, systemPrompt, scriptLabel, scriptContent, taskLabel, taskInstruction, charListLabel, characterList, charConstraint, sceneListLabel, sceneList, sceneConstraint)
 
-	// 创建异步任务
-	task, err := s.taskService.CreateTask("storyboard_generation", episodeID)
+	dramaID, _ := strconv.ParseUint(episode.DramaID, 10, 32)
+
+	// 创建排队任务，由任务队列的工作池执行AI调用和后续逻辑
+	task, err := s.jobQueue.Enqueue("storyboard_generation", episodeID, JobOptions{
+		Queue:    JobQueueText,
+		Provider: s.aiService.ResolveProvider("text", model),
+		Priority: JobPriorityInteractive,
+		DramaID:  uint(dramaID),
+		Payload: storyboardGenerationPayload{
+			EpisodeID: episodeID,
+			Model:     model,
+			Prompt:    prompt,
+		},
+	})
 	if err != nil {
 		s.log.Errorw("Failed to create task", "error", err)
 		return "", fmt.Errorf("创建任务失败: %w", err)
@@ -337,15 +360,22 @@ func (s *StoryboardService) GenerateStoryboard(episodeID string, model string) (
 		"scene_count", len(scenes),
 		"scenes", sceneList)
 
-	// 启动后台goroutine处理AI调用和后续逻辑
-	go s.processStoryboardGeneration(task.ID, episodeID, model, prompt)
-
 	// 立即返回任务ID
 	return task.ID, nil
 }
 
+// handleStoryboardGenerationJob 任务队列处理函数
+func (s *StoryboardService) handleStoryboardGenerationJob(ctx context.Context, task *models.AsyncTask) error {
+	var payload storyboardGenerationPayload
+	if err := decodeJobPayload(task, &payload); err != nil {
+		return err
+	}
+	s.processStoryboardGeneration(ctx, task.ID, payload.EpisodeID, payload.Model, payload.Prompt)
+	return ctx.Err()
+}
+
 // processStoryboardGeneration 后台处理故事板生成
-func (s *StoryboardService) processStoryboardGeneration(taskID, episodeID, model, prompt string) {
+func (s *StoryboardService) processStoryboardGeneration(ctx context.Context, taskID, episodeID, model, prompt string) {
 	// 更新任务状态为处理中
 	if err := s.taskService.UpdateTaskStatus(taskID, "processing", 10, "开始生成分镜头..."); err != nil {
 		s.log.Errorw("Failed to update task status", "error", err, "task_id", taskID)
@@ -356,19 +386,13 @@ func (s *StoryboardService) processStoryboardGeneration(taskID, episodeID, model
 
 	// 调用AI服务生成（如果指定了模型则使用指定的模型）
 	// 设置较大的max_tokens以确保完整返回所有分镜的JSON
-	var text string
-	var err error
 	if model != "" {
 		s.log.Infow("Using specified model for storyboard generation", "model", model, "task_id", taskID)
-		client, getErr := s.aiService.GetAIClientForModel("text", model)
-		if getErr != nil {
-			s.log.Warnw("Failed to get client for specified model, using default", "model", model, "error", getErr, "task_id", taskID)
-			text, err = s.aiService.GenerateText(prompt, "", ai.WithMaxTokens(16000))
-		} else {
-			text, err = client.GenerateText(prompt, "", ai.WithMaxTokens(16000))
-		}
-	} else {
-		text, err = s.aiService.GenerateText(prompt, "", ai.WithMaxTokens(16000))
+	}
+	text, err := s.aiService.GenerateTextWithContext(ctx, model, prompt, "", ai.WithMaxTokens(16000))
+	// 任务被暂停、取消或队列停止，不写入失败状态
+	if ctx.Err() != nil {
+		return
 	}
 
 	if err != nil {
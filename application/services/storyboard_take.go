@@ -0,0 +1,99 @@
+package services
+
+import (
+	"path/filepath"
+	"strings"
+
+	models "github.com/drama-generator/backend/domain/models"
+	"gorm.io/gorm"
+)
+
+// storyboardTake 分镜当前采用的视频
+type storyboardTake struct {
+	Source     string // 本地文件完整路径或远程 URL
+	AssetID    *uint
+	VideoGenID *uint
+	Duration   int  // 视频时长（秒），未知时为 0
+	LipSynced  bool // 采用的是口型同步后的视频，音轨只包含配音
+}
+
+// findStoryboardTake 查找分镜当前采用的视频：
+// 优先使用素材库中该分镜最新的视频，其次是最新完成的视频生成记录，最后回退到分镜的 video_url；
+// 采用的视频已完成口型同步时，改用同步后的版本
+func findStoryboardTake(db *gorm.DB, storagePath string, episodeID uint, storyboard *models.Storyboard) *storyboardTake {
+	take := findSourceTake(db, storagePath, episodeID, storyboard)
+	if take == nil || take.VideoGenID == nil {
+		return take
+	}
+
+	var synced models.Asset
+	if err := db.Where("video_gen_id = ? AND type = ? AND category = ?",
+		*take.VideoGenID, models.AssetTypeVideo, models.AssetCategoryLipSync).
+		Order("created_at DESC").
+		First(&synced).Error; err == nil {
+		take.AssetID = &synced.ID
+		take.Source = synced.URL
+		if synced.LocalPath != nil && *synced.LocalPath != "" {
+			take.Source = resolveStoragePath(storagePath, *synced.LocalPath)
+		}
+		if synced.Duration != nil {
+			take.Duration = *synced.Duration
+		}
+		take.LipSynced = true
+	}
+	return take
+}
+
+// findSourceTake 查找分镜当前采用的原始视频，不包含口型同步生成的版本
+func findSourceTake(db *gorm.DB, storagePath string, episodeID uint, storyboard *models.Storyboard) *storyboardTake {
+	var asset models.Asset
+	if err := db.Where("storyboard_id = ? AND type = ? AND episode_id = ? AND (category IS NULL OR category <> ?)",
+		storyboard.ID, models.AssetTypeVideo, episodeID, models.AssetCategoryLipSync).
+		Order("created_at DESC").
+		First(&asset).Error; err == nil {
+		take := &storyboardTake{AssetID: &asset.ID, VideoGenID: asset.VideoGenID, Source: asset.URL}
+		if asset.LocalPath != nil && *asset.LocalPath != "" {
+			take.Source = resolveStoragePath(storagePath, *asset.LocalPath)
+		}
+		if asset.Duration != nil {
+			take.Duration = *asset.Duration
+		}
+		return take
+	}
+
+	var videoGen models.VideoGeneration
+	if err := db.Where("storyboard_id = ? AND status = ?", storyboard.ID, "completed").
+		Order("created_at DESC").
+		First(&videoGen).Error; err == nil {
+		take := &storyboardTake{VideoGenID: &videoGen.ID}
+		if videoGen.Duration != nil {
+			take.Duration = *videoGen.Duration
+		}
+		if videoGen.LocalPath != nil && *videoGen.LocalPath != "" {
+			take.Source = resolveStoragePath(storagePath, *videoGen.LocalPath)
+			return take
+		}
+		if videoGen.VideoURL != nil && *videoGen.VideoURL != "" {
+			take.Source = *videoGen.VideoURL
+			return take
+		}
+		if storyboard.VideoURL != nil && *storyboard.VideoURL != "" {
+			take.Source = *storyboard.VideoURL
+			return take
+		}
+		return nil
+	}
+
+	if storyboard.VideoURL != nil && *storyboard.VideoURL != "" {
+		return &storyboardTake{Source: *storyboard.VideoURL}
+	}
+	return nil
+}
+
+// resolveStoragePath 将存储目录下的相对路径转换为完整路径
+func resolveStoragePath(storagePath, localPath string) string {
+	if filepath.IsAbs(localPath) || strings.HasPrefix(localPath, storagePath) {
+		return localPath
+	}
+	return filepath.Join(storagePath, localPath)
+}
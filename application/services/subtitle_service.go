@@ -0,0 +1,562 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	models "github.com/drama-generator/backend/domain/models"
+	"github.com/drama-generator/backend/infrastructure/external/ffmpeg"
+	"github.com/drama-generator/backend/pkg/logger"
+	"github.com/drama-generator/backend/pkg/subtitle"
+	"github.com/drama-generator/backend/pkg/tts"
+	"gorm.io/gorm"
+)
+
+// 字幕嵌入方式
+const (
+	SubtitleModeSoft = "soft" // 封装为字幕轨道，播放器可开关
+	SubtitleModeBurn = "burn" // 按剧集字幕样式烧录到画面
+)
+
+// episodeSubtitlePayload 字幕视频任务参数
+type episodeSubtitlePayload struct {
+	EpisodeID uint   `json:"episode_id"`
+	Mode      string `json:"mode"`
+}
+
+// EmbedSubtitlesRequest 为成片添加字幕
+type EmbedSubtitlesRequest struct {
+	Mode string `json:"mode" binding:"required,oneof=soft burn"`
+}
+
+// EpisodeSubtitleFile 保存到存储目录的字幕文件
+type EpisodeSubtitleFile struct {
+	Format    subtitle.Format `json:"format"`
+	URL       string          `json:"url"`
+	LocalPath string          `json:"local_path"`
+}
+
+// subtitleShot 成片中的一个镜头，时间单位为毫秒
+type subtitleShot struct {
+	storyboard *models.Storyboard // 素材未关联分镜时为空
+	start      int
+	duration   int
+}
+
+type SubtitleService struct {
+	db          *gorm.DB
+	taskService *TaskService
+	ffmpeg      *ffmpeg.FFmpeg
+	storagePath string
+	baseURL     string
+	log         *logger.Logger
+	jobQueue    *JobQueue
+}
+
+func NewSubtitleService(db *gorm.DB, jobQueue *JobQueue, taskService *TaskService, storagePath, baseURL string, log *logger.Logger) *SubtitleService {
+	service := &SubtitleService{
+		db:          db,
+		taskService: taskService,
+		ffmpeg:      ffmpeg.NewFFmpeg(log),
+		storagePath: storagePath,
+		baseURL:     baseURL,
+		log:         log,
+		jobQueue:    jobQueue,
+	}
+
+	service.jobQueue.RegisterHandler("episode_subtitles", service.handleEpisodeSubtitlesJob)
+
+	return service
+}
+
+// GetDramaSubtitleStyle 获取剧本的字幕样式，未设置时为默认样式
+func (s *SubtitleService) GetDramaSubtitleStyle(dramaID uint) (*subtitle.Style, error) {
+	var drama models.Drama
+	if err := s.db.Select("id", "subtitle_style").First(&drama, dramaID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("drama not found")
+		}
+		return nil, err
+	}
+
+	style := s.dramaSubtitleStyle(&drama)
+	return &style, nil
+}
+
+// UpdateDramaSubtitleStyle 保存剧本的字幕样式
+func (s *SubtitleService) UpdateDramaSubtitleStyle(dramaID uint, style subtitle.Style) (*subtitle.Style, error) {
+	var drama models.Drama
+	if err := s.db.Select("id").First(&drama, dramaID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("drama not found")
+		}
+		return nil, err
+	}
+
+	normalized, err := style.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(normalized)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.Model(&drama).Update("subtitle_style", data).Error; err != nil {
+		return nil, fmt.Errorf("failed to save subtitle style: %w", err)
+	}
+
+	s.log.Infow("Subtitle style updated", "drama_id", dramaID)
+	return &normalized, nil
+}
+
+// BuildEpisodeSubtitles 生成章节字幕，时间轴与成片一致
+// 镜头按生成成片的合成记录排列，没有成片时按分镜顺序和时长排列；
+// 已配音的台词使用配音时长，未配音的台词按字数分配镜头时长
+func (s *SubtitleService) BuildEpisodeSubtitles(episodeID uint) (*subtitle.Document, error) {
+	var episode models.Episode
+	if err := s.db.Preload("Drama").First(&episode, episodeID).Error; err != nil {
+		return nil, fmt.Errorf("episode not found")
+	}
+	return s.buildEpisodeSubtitles(&episode)
+}
+
+func (s *SubtitleService) buildEpisodeSubtitles(episode *models.Episode) (*subtitle.Document, error) {
+	var storyboards []models.Storyboard
+	if err := s.db.Where("episode_id = ?", episode.ID).Order("storyboard_number ASC").Find(&storyboards).Error; err != nil {
+		return nil, fmt.Errorf("failed to load storyboards: %w", err)
+	}
+
+	var speakers []string
+	if err := s.db.Model(&models.Character{}).Where("drama_id = ?", episode.DramaID).Pluck("name", &speakers).Error; err != nil {
+		return nil, fmt.Errorf("failed to load characters: %w", err)
+	}
+
+	var lines []models.DialogueLine
+	if err := s.db.Where("episode_id = ? AND status = ? AND duration > 0", episode.ID, models.DialogueLineStatusCompleted).
+		Order("line_index ASC").Find(&lines).Error; err != nil {
+		return nil, fmt.Errorf("failed to load dialogue lines: %w", err)
+	}
+	voicedLines := make(map[uint][]models.DialogueLine)
+	for _, line := range lines {
+		voicedLines[line.StoryboardID] = append(voicedLines[line.StoryboardID], line)
+	}
+
+	doc := &subtitle.Document{
+		Title: fmt.Sprintf("%s 第%d集 %s", episode.Drama.Title, episode.EpisodeNum, episode.Title),
+		Style: s.dramaSubtitleStyle(&episode.Drama),
+	}
+	for _, shot := range s.episodeShots(episode, storyboards) {
+		if shot.storyboard == nil {
+			continue
+		}
+		doc.Cues = append(doc.Cues, shotCues(shot, voicedLines[shot.storyboard.ID], speakers)...)
+	}
+	return doc, nil
+}
+
+// ExportEpisodeSubtitles 按指定格式导出章节字幕
+func (s *SubtitleService) ExportEpisodeSubtitles(episodeID uint, format subtitle.Format) ([]byte, string, error) {
+	var episode models.Episode
+	if err := s.db.Preload("Drama").First(&episode, episodeID).Error; err != nil {
+		return nil, "", fmt.Errorf("episode not found")
+	}
+
+	doc, err := s.buildEpisodeSubtitles(&episode)
+	if err != nil {
+		return nil, "", err
+	}
+	data, err := s.writeSubtitle(doc, format, &episode)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, fmt.Sprintf("episode_%d.%s", episodeID, format.Extension()), nil
+}
+
+// SaveEpisodeSubtitles 生成全部格式的字幕文件并保存到存储目录，章节没有对白时返回空列表
+func (s *SubtitleService) SaveEpisodeSubtitles(episodeID uint) ([]EpisodeSubtitleFile, error) {
+	var episode models.Episode
+	if err := s.db.Preload("Drama").First(&episode, episodeID).Error; err != nil {
+		return nil, fmt.Errorf("episode not found")
+	}
+
+	doc, err := s.buildEpisodeSubtitles(&episode)
+	if err != nil {
+		return nil, err
+	}
+	if len(doc.Cues) == 0 {
+		return []EpisodeSubtitleFile{}, nil
+	}
+
+	relDir := filepath.Join("subtitles", fmt.Sprintf("episode_%d", episodeID))
+	if err := os.MkdirAll(filepath.Join(s.storagePath, relDir), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create subtitle directory: %w", err)
+	}
+
+	files := make([]EpisodeSubtitleFile, 0, len(subtitle.Formats))
+	for _, format := range subtitle.Formats {
+		data, err := s.writeSubtitle(doc, format, &episode)
+		if err != nil {
+			return nil, err
+		}
+
+		relPath := filepath.ToSlash(filepath.Join(relDir, fmt.Sprintf("episode_%d.%s", episodeID, format.Extension())))
+		if err := os.WriteFile(filepath.Join(s.storagePath, relPath), data, 0644); err != nil {
+			return nil, fmt.Errorf("failed to save subtitle file: %w", err)
+		}
+		files = append(files, EpisodeSubtitleFile{
+			Format:    format,
+			URL:       fmt.Sprintf("%s/%s", s.baseURL, relPath),
+			LocalPath: relPath,
+		})
+	}
+	return files, nil
+}
+
+// EmbedEpisodeSubtitles 创建字幕视频任务，为章节成片添加软字幕或烧录字幕，结果保存为新的视频素材
+func (s *SubtitleService) EmbedEpisodeSubtitles(episodeID uint, mode string) (*models.AsyncTask, error) {
+	if mode != SubtitleModeSoft && mode != SubtitleModeBurn {
+		return nil, fmt.Errorf("invalid subtitle mode: %s", mode)
+	}
+
+	var episode models.Episode
+	if err := s.db.First(&episode, episodeID).Error; err != nil {
+		return nil, fmt.Errorf("episode not found")
+	}
+	if episode.VideoURL == nil || *episode.VideoURL == "" {
+		return nil, fmt.Errorf("episode has no finalized video")
+	}
+	if s.jobQueue.HasActiveJob("episode_subtitles", fmt.Sprintf("%d", episodeID)) {
+		return nil, fmt.Errorf("episode subtitles are already being processed")
+	}
+
+	task, err := s.jobQueue.Enqueue("episode_subtitles", fmt.Sprintf("%d", episodeID), JobOptions{
+		Queue:    JobQueueFFmpeg,
+		Priority: JobPriorityInteractive,
+		DramaID:  episode.DramaID,
+		Payload:  episodeSubtitlePayload{EpisodeID: episodeID, Mode: mode},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.log.Infow("Episode subtitles queued", "episode_id", episodeID, "mode", mode, "task_id", task.ID)
+	return task, nil
+}
+
+// SubtitledVideos 当前成片的字幕版视频，按嵌入方式返回相对路径；成片重新合成后旧的字幕版不再返回
+func (s *SubtitleService) SubtitledVideos(episode *models.Episode) map[string]string {
+	videos := make(map[string]string)
+	if episode.VideoURL == nil || *episode.VideoURL == "" {
+		return videos
+	}
+
+	query := s.db.Where("episode_id = ? AND type = ?", episode.ID, models.AssetTypeVideo)
+	var merge models.VideoMerge
+	if err := s.db.Where("episode_id = ? AND status = ? AND merged_url = ?", episode.ID, models.VideoMergeStatusCompleted, *episode.VideoURL).
+		Order("completed_at DESC").First(&merge).Error; err == nil && merge.CompletedAt != nil {
+		query = query.Where("created_at >= ?", *merge.CompletedAt)
+	}
+
+	for mode, category := range map[string]string{
+		SubtitleModeSoft: models.AssetCategorySubtitleSoft,
+		SubtitleModeBurn: models.AssetCategorySubtitleBurn,
+	} {
+		var asset models.Asset
+		if err := query.Session(&gorm.Session{}).Where("category = ?", category).Order("created_at DESC").First(&asset).Error; err != nil {
+			continue
+		}
+		if asset.LocalPath != nil && *asset.LocalPath != "" {
+			videos[mode] = *asset.LocalPath
+		} else {
+			videos[mode] = asset.URL
+		}
+	}
+	return videos
+}
+
+// handleEpisodeSubtitlesJob 任务队列处理函数，字幕视频可以安全重跑
+func (s *SubtitleService) handleEpisodeSubtitlesJob(ctx context.Context, task *models.AsyncTask) error {
+	var payload episodeSubtitlePayload
+	if err := decodeJobPayload(task, &payload); err != nil {
+		return err
+	}
+
+	asset, err := s.embedSubtitles(ctx, task.ID, &payload)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != nil {
+		s.log.Errorw("Episode subtitles failed", "error", err, "episode_id", payload.EpisodeID, "mode", payload.Mode)
+		s.taskService.UpdateTaskError(task.ID, err)
+		return nil
+	}
+
+	s.taskService.UpdateTaskResult(task.ID, map[string]interface{}{
+		"episode_id": payload.EpisodeID,
+		"mode":       payload.Mode,
+		"asset_id":   asset.ID,
+		"url":        asset.URL,
+		"local_path": asset.LocalPath,
+	})
+	return nil
+}
+
+func (s *SubtitleService) embedSubtitles(ctx context.Context, taskID string, payload *episodeSubtitlePayload) (*models.Asset, error) {
+	var episode models.Episode
+	if err := s.db.Preload("Drama").First(&episode, payload.EpisodeID).Error; err != nil {
+		return nil, fmt.Errorf("episode not found")
+	}
+	if episode.VideoURL == nil || *episode.VideoURL == "" {
+		return nil, fmt.Errorf("episode has no finalized video")
+	}
+
+	doc, err := s.buildEpisodeSubtitles(&episode)
+	if err != nil {
+		return nil, err
+	}
+	if len(doc.Cues) == 0 {
+		return nil, fmt.Errorf("episode has no dialogue to subtitle")
+	}
+
+	// 软字幕轨道只支持纯文本，烧录时使用 ASS 保留字幕样式
+	burn := payload.Mode == SubtitleModeBurn
+	format, category, label := subtitle.FormatSRT, models.AssetCategorySubtitleSoft, "软字幕"
+	if burn {
+		format, category, label = subtitle.FormatASS, models.AssetCategorySubtitleBurn, "硬字幕"
+	}
+
+	data, err := s.writeSubtitle(doc, format, &episode)
+	if err != nil {
+		return nil, err
+	}
+	workDir, err := os.MkdirTemp("", "drama-subtitles-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create work directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+	subtitlePath := filepath.Join(workDir, "episode."+format.Extension())
+	if err := os.WriteFile(subtitlePath, data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write subtitle file: %w", err)
+	}
+
+	s.taskService.UpdateTaskStatus(taskID, "processing", 0, "正在生成字幕视频...")
+
+	source := s.episodeVideoSource(&episode)
+	duration, _ := s.ffmpeg.GetVideoDuration(source)
+	fileName := fmt.Sprintf("episode_%d_%s_%d.mp4", episode.ID, payload.Mode, time.Now().Unix())
+	outputPath := filepath.Join(s.storagePath, "videos", "subtitled", fileName)
+	if _, err := s.ffmpeg.AddSubtitles(ctx, &ffmpeg.SubtitleOptions{
+		VideoPath:    source,
+		SubtitlePath: subtitlePath,
+		OutputPath:   outputPath,
+		Burn:         burn,
+		Language:     "chi",
+		Duration:     duration,
+		Progress: func(percent int) {
+			s.taskService.UpdateTaskStatus(taskID, "processing", percent, fmt.Sprintf("正在生成字幕视频 %d%%", percent))
+		},
+	}); err != nil {
+		return nil, err
+	}
+
+	// 只保存相对路径
+	relPath := filepath.ToSlash(filepath.Join("videos", "subtitled", fileName))
+	mimeType := "video/mp4"
+	fileFormat := "mp4"
+	asset := &models.Asset{
+		DramaID:   &episode.DramaID,
+		EpisodeID: &episode.ID,
+		Name:      fmt.Sprintf("第%d集 %s", episode.EpisodeNum, label),
+		Type:      models.AssetTypeVideo,
+		Category:  &category,
+		URL:       fmt.Sprintf("%s/%s", s.baseURL, relPath),
+		LocalPath: &relPath,
+		MimeType:  &mimeType,
+		Format:    &fileFormat,
+
+		ThumbnailURL: episode.Thumbnail,
+		SpriteVTTURL: episode.SpriteVTTURL,
+	}
+	if duration > 0 {
+		seconds := int(math.Ceil(duration))
+		durationMs := int(math.Round(duration * 1000))
+		asset.Duration = &seconds
+		asset.DurationMs = &durationMs
+	}
+	if info, err := os.Stat(outputPath); err == nil {
+		size := info.Size()
+		asset.FileSize = &size
+	}
+
+	if err := s.db.Create(asset).Error; err != nil {
+		os.Remove(outputPath)
+		return nil, fmt.Errorf("failed to create asset: %w", err)
+	}
+
+	s.log.Infow("Episode subtitles embedded", "episode_id", episode.ID, "mode", payload.Mode, "asset_id", asset.ID)
+	return asset, nil
+}
+
+// writeSubtitle 输出字幕文件，ASS 按成片分辨率换算字号
+func (s *SubtitleService) writeSubtitle(doc *subtitle.Document, format subtitle.Format, episode *models.Episode) ([]byte, error) {
+	if format == subtitle.FormatASS && doc.Width == 0 {
+		doc.Width, doc.Height = s.ffmpeg.GetVideoResolution(s.episodeVideoSource(episode))
+	}
+	return subtitle.Write(doc, format)
+}
+
+// episodeShots 优先按生成当前成片的合成片段排列镜头，没有成片时按分镜顺序和时长排列
+// 合成时转场通过延长前一片段实现，不改变后续片段的起点；成片有片头和标题卡时镜头整体后移
+func (s *SubtitleService) episodeShots(episode *models.Episode, storyboards []models.Storyboard) []subtitleShot {
+	storyboardByID := make(map[uint]*models.Storyboard, len(storyboards))
+	for i := range storyboards {
+		storyboardByID[storyboards[i].ID] = &storyboards[i]
+	}
+
+	var shots []subtitleShot
+	cursor := 0
+	if clips, leadIn := s.mergedSceneClips(episode); len(clips) > 0 {
+		cursor = leadIn
+		for _, clip := range clips {
+			storyboard := storyboardByID[clip.SceneID]
+			duration := sceneClipDuration(clip, storyboard)
+			shots = append(shots, subtitleShot{storyboard: storyboard, start: cursor, duration: duration})
+			cursor += duration
+		}
+		return shots
+	}
+
+	for i := range storyboards {
+		duration := storyboards[i].Duration * 1000
+		if duration <= 0 {
+			duration = defaultStoryboardClipMs
+		}
+		shots = append(shots, subtitleShot{storyboard: &storyboards[i], start: cursor, duration: duration})
+		cursor += duration
+	}
+	return shots
+}
+
+// mergedSceneClips 当前成片对应的合成片段，以及正片之前片头和标题卡的时长（毫秒）
+func (s *SubtitleService) mergedSceneClips(episode *models.Episode) ([]models.SceneClip, int) {
+	if episode.VideoURL == nil || *episode.VideoURL == "" {
+		return nil, 0
+	}
+
+	var merge models.VideoMerge
+	if err := s.db.Where("episode_id = ? AND status = ? AND merged_url = ?", episode.ID, models.VideoMergeStatusCompleted, *episode.VideoURL).
+		Order("completed_at DESC").First(&merge).Error; err != nil {
+		return nil, 0
+	}
+
+	var clips []models.SceneClip
+	if err := json.Unmarshal(merge.Scenes, &clips); err != nil {
+		s.log.Warnw("Failed to parse merge scenes", "error", err, "merge_id", merge.ID)
+		return nil, 0
+	}
+	sort.SliceStable(clips, func(i, j int) bool {
+		return clips[i].Order < clips[j].Order
+	})
+	return clips, mergeLeadIn(&merge)
+}
+
+func (s *SubtitleService) episodeVideoSource(episode *models.Episode) string {
+	if episode.VideoURL == nil || *episode.VideoURL == "" {
+		return ""
+	}
+	videoURL := *episode.VideoURL
+	if strings.HasPrefix(videoURL, "http://") || strings.HasPrefix(videoURL, "https://") {
+		return videoURL
+	}
+	return resolveStoragePath(s.storagePath, videoURL)
+}
+
+// dramaSubtitleStyle 解析剧本的字幕样式，未设置的字段使用默认值
+func (s *SubtitleService) dramaSubtitleStyle(drama *models.Drama) subtitle.Style {
+	style := subtitle.DefaultStyle()
+	if len(drama.SubtitleStyle) == 0 {
+		return style
+	}
+	if err := json.Unmarshal(drama.SubtitleStyle, &style); err != nil {
+		s.log.Warnw("Failed to parse subtitle style", "error", err, "drama_id", drama.ID)
+		return subtitle.DefaultStyle()
+	}
+	normalized, err := style.Normalize()
+	if err != nil {
+		s.log.Warnw("Invalid subtitle style", "error", err, "drama_id", drama.ID)
+		return subtitle.DefaultStyle()
+	}
+	return normalized
+}
+
+// sceneClipDuration 合成片段在成片中的时长（毫秒）
+func sceneClipDuration(clip models.SceneClip, storyboard *models.Storyboard) int {
+	switch {
+	case clip.EndTime > clip.StartTime:
+		return int(math.Round((clip.EndTime - clip.StartTime) * 1000))
+	case clip.Duration > 0:
+		return int(math.Round(clip.Duration * 1000))
+	case storyboard != nil && storyboard.Duration > 0:
+		return storyboard.Duration * 1000
+	default:
+		return defaultStoryboardClipMs
+	}
+}
+
+// shotCues 生成一个镜头的字幕：已配音的台词从镜头开始依次排列，超出镜头的部分截断；
+// 未配音时解析分镜对白，按字数比例分配镜头时长
+func shotCues(shot subtitleShot, voiced []models.DialogueLine, speakers []string) []subtitle.Cue {
+	end := shot.start + shot.duration
+	var cues []subtitle.Cue
+
+	if len(voiced) > 0 {
+		cursor := shot.start
+		for _, line := range voiced {
+			if cursor >= end {
+				break
+			}
+			cues = append(cues, subtitle.Cue{
+				Start:   cursor,
+				End:     min(cursor+line.Duration, end),
+				Speaker: line.Speaker,
+				Text:    line.Text,
+			})
+			cursor += line.Duration
+		}
+		return cues
+	}
+
+	if shot.storyboard.Dialogue == nil {
+		return nil
+	}
+	lines := tts.ParseDialogue(*shot.storyboard.Dialogue, speakers)
+	total := 0
+	for _, line := range lines {
+		total += utf8.RuneCountInString(line.Text)
+	}
+	if total == 0 {
+		return nil
+	}
+
+	cursor := shot.start
+	spoken := 0
+	for i, line := range lines {
+		spoken += utf8.RuneCountInString(line.Text)
+		cueEnd := shot.start + shot.duration*spoken/total
+		if i == len(lines)-1 {
+			cueEnd = end
+		}
+		cues = append(cues, subtitle.Cue{Start: cursor, End: cueEnd, Speaker: line.Speaker, Text: line.Text})
+		cursor = cueEnd
+	}
+	return cues
+}
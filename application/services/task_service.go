@@ -12,14 +12,18 @@ import (
 )
 
 type TaskService struct {
-	db  *gorm.DB
-	log *logger.Logger
+	db       *gorm.DB
+	log      *logger.Logger
+	jobQueue *JobQueue
+	eventBus *EventBus
 }
 
-func NewTaskService(db *gorm.DB, log *logger.Logger) *TaskService {
+func NewTaskService(db *gorm.DB, jobQueue *JobQueue, eventBus *EventBus, log *logger.Logger) *TaskService {
 	return &TaskService{
-		db:  db,
-		log: log,
+		db:       db,
+		log:      log,
+		jobQueue: jobQueue,
+		eventBus: eventBus,
 	}
 }
 
@@ -54,23 +58,38 @@ func (s *TaskService) UpdateTaskStatus(taskID, status string, progress int, mess
 		updates["completed_at"] = &now
 	}
 
-	return s.db.Model(&models.AsyncTask{}).
-		Where("id = ?", taskID).
-		Updates(updates).Error
+	// 已取消的任务不再被处理函数覆盖状态
+	if err := s.db.Model(&models.AsyncTask{}).
+		Where("id = ? AND status <> ?", taskID, "cancelled").
+		Updates(updates).Error; err != nil {
+		return err
+	}
+
+	s.eventBus.publishTask(s.db, taskID)
+	return nil
 }
 
 // UpdateTaskError 更新任务错误
 func (s *TaskService) UpdateTaskError(taskID string, err error) error {
 	now := time.Now()
-	return s.db.Model(&models.AsyncTask{}).
-		Where("id = ?", taskID).
+	updated := s.db.Model(&models.AsyncTask{}).
+		Where("id = ? AND status <> ?", taskID, "cancelled").
 		Updates(map[string]interface{}{
 			"status":       "failed",
 			"error":        err.Error(),
 			"progress":     0,
 			"completed_at": &now,
 			"updated_at":   time.Now(),
-		}).Error
+		})
+	if updated.Error != nil {
+		return updated.Error
+	}
+
+	s.eventBus.publishTask(s.db, taskID)
+	if updated.RowsAffected > 0 {
+		dispatchTaskWebhook(s.db, s.jobQueue, s.log, taskID, EventTaskFailed)
+	}
+	return nil
 }
 
 // UpdateTaskResult 更新任务结果
@@ -81,15 +100,24 @@ func (s *TaskService) UpdateTaskResult(taskID string, result interface{}) error
 	}
 
 	now := time.Now()
-	return s.db.Model(&models.AsyncTask{}).
-		Where("id = ?", taskID).
+	updated := s.db.Model(&models.AsyncTask{}).
+		Where("id = ? AND status <> ?", taskID, "cancelled").
 		Updates(map[string]interface{}{
 			"status":       "completed",
 			"progress":     100,
 			"result":       string(resultJSON),
 			"completed_at": &now,
 			"updated_at":   time.Now(),
-		}).Error
+		})
+	if updated.Error != nil {
+		return updated.Error
+	}
+
+	s.eventBus.publishTask(s.db, taskID)
+	if updated.RowsAffected > 0 {
+		dispatchTaskWebhook(s.db, s.jobQueue, s.log, taskID, EventTaskCompleted)
+	}
+	return nil
 }
 
 // GetTask 获取任务信息
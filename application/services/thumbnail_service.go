@@ -0,0 +1,340 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	models "github.com/drama-generator/backend/domain/models"
+	"github.com/drama-generator/backend/infrastructure/external/ffmpeg"
+	"github.com/drama-generator/backend/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// posterFileName 封面文件名，与雪碧图保存在同一目录
+const posterFileName = "poster.jpg"
+
+// videoThumbnailPayload 视频封面任务参数
+type videoThumbnailPayload struct {
+	VideoGenID uint `json:"video_gen_id"`
+}
+
+// episodeThumbnailPayload 成片封面任务参数
+type episodeThumbnailPayload struct {
+	EpisodeID uint `json:"episode_id"`
+	MergeID   uint `json:"merge_id"`
+}
+
+// SetPosterRequest 指定封面截取的时间点（秒）
+type SetPosterRequest struct {
+	Timestamp *float64 `json:"timestamp" binding:"required,min=0"`
+}
+
+// PosterResult 设置的封面
+type PosterResult struct {
+	ThumbnailURL string  `json:"thumbnail_url"`
+	Timestamp    float64 `json:"timestamp"`
+}
+
+// thumbnailOutput 一次生成的封面和雪碧图
+type thumbnailOutput struct {
+	posterURL string
+	spriteURL *string // 雪碧图生成失败时为空，不影响封面
+}
+
+type ThumbnailService struct {
+	db          *gorm.DB
+	taskService *TaskService
+	ffmpeg      *ffmpeg.FFmpeg
+	storagePath string
+	baseURL     string
+	log         *logger.Logger
+	jobQueue    *JobQueue
+}
+
+func NewThumbnailService(db *gorm.DB, jobQueue *JobQueue, taskService *TaskService, storagePath, baseURL string, log *logger.Logger) *ThumbnailService {
+	service := &ThumbnailService{
+		db:          db,
+		taskService: taskService,
+		ffmpeg:      ffmpeg.NewFFmpeg(log),
+		storagePath: storagePath,
+		baseURL:     baseURL,
+		log:         log,
+		jobQueue:    jobQueue,
+	}
+
+	service.jobQueue.RegisterHandler("video_thumbnail", service.handleVideoThumbnailJob)
+	service.jobQueue.RegisterHandler("episode_thumbnail", service.handleEpisodeThumbnailJob)
+
+	return service
+}
+
+// SetEpisodePoster 截取章节成片指定时间点的画面作为章节封面，剧本没有封面时同时设为剧本封面
+func (s *ThumbnailService) SetEpisodePoster(episodeID uint, timestamp float64) (*PosterResult, error) {
+	var episode models.Episode
+	if err := s.db.First(&episode, episodeID).Error; err != nil {
+		return nil, fmt.Errorf("episode not found")
+	}
+	if episode.VideoURL == nil || *episode.VideoURL == "" {
+		return nil, fmt.Errorf("episode has no finalized video")
+	}
+
+	relPath := filepath.ToSlash(filepath.Join("thumbnails", "episodes", fmt.Sprintf("episode_%d_poster_%d.jpg", episode.ID, time.Now().UnixNano())))
+	posterURL, err := s.extractPosterAt(s.videoSource(*episode.VideoURL), relPath, timestamp)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Model(&episode).Update("thumbnail", posterURL).Error; err != nil {
+		return nil, fmt.Errorf("failed to save episode thumbnail: %w", err)
+	}
+	s.fillDramaThumbnail(episode.DramaID, posterURL)
+
+	s.log.Infow("Episode poster set", "episode_id", episode.ID, "timestamp", timestamp, "url", posterURL)
+	return &PosterResult{ThumbnailURL: posterURL, Timestamp: timestamp}, nil
+}
+
+// SetAssetPoster 截取视频素材指定时间点的画面作为素材封面
+func (s *ThumbnailService) SetAssetPoster(assetID uint, timestamp float64) (*PosterResult, error) {
+	var asset models.Asset
+	if err := s.db.First(&asset, assetID).Error; err != nil {
+		return nil, fmt.Errorf("asset not found")
+	}
+	if asset.Type != models.AssetTypeVideo {
+		return nil, fmt.Errorf("asset is not a video")
+	}
+
+	source := asset.URL
+	if asset.LocalPath != nil && *asset.LocalPath != "" {
+		source = resolveStoragePath(s.storagePath, *asset.LocalPath)
+	}
+	relPath := filepath.ToSlash(filepath.Join("thumbnails", "assets", fmt.Sprintf("asset_%d_poster_%d.jpg", asset.ID, time.Now().UnixNano())))
+	posterURL, err := s.extractPosterAt(source, relPath, timestamp)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Model(&asset).Update("thumbnail_url", posterURL).Error; err != nil {
+		return nil, fmt.Errorf("failed to save asset thumbnail: %w", err)
+	}
+
+	s.log.Infow("Asset poster set", "asset_id", asset.ID, "timestamp", timestamp, "url", posterURL)
+	return &PosterResult{ThumbnailURL: posterURL, Timestamp: timestamp}, nil
+}
+
+// extractPosterAt 截取指定时间点的原始分辨率画面，返回访问地址
+func (s *ThumbnailService) extractPosterAt(source, relPath string, timestamp float64) (string, error) {
+	if duration, err := s.ffmpeg.GetVideoDuration(source); err == nil && timestamp > duration {
+		return "", fmt.Errorf("timestamp %.3fs exceeds video duration %.3fs", timestamp, duration)
+	}
+
+	outputPath := filepath.Join(s.storagePath, relPath)
+	if err := s.ffmpeg.ExtractFrame(context.Background(), source, outputPath, timestamp, 0); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/%s", s.baseURL, relPath), nil
+}
+
+// handleVideoThumbnailJob 任务队列处理函数，输出目录按时间戳命名，可以安全重跑
+func (s *ThumbnailService) handleVideoThumbnailJob(ctx context.Context, task *models.AsyncTask) error {
+	var payload videoThumbnailPayload
+	if err := decodeJobPayload(task, &payload); err != nil {
+		return err
+	}
+
+	var videoGen models.VideoGeneration
+	if err := s.db.First(&videoGen, payload.VideoGenID).Error; err != nil {
+		s.taskService.UpdateTaskError(task.ID, fmt.Errorf("video generation not found"))
+		return nil
+	}
+	source := ""
+	if videoGen.LocalPath != nil && *videoGen.LocalPath != "" {
+		source = resolveStoragePath(s.storagePath, *videoGen.LocalPath)
+	} else if videoGen.VideoURL != nil {
+		source = *videoGen.VideoURL
+	}
+	if source == "" {
+		s.taskService.UpdateTaskError(task.ID, fmt.Errorf("video generation has no video"))
+		return nil
+	}
+
+	relDir := filepath.ToSlash(filepath.Join("thumbnails", "videos", fmt.Sprintf("video_%d_%d", videoGen.ID, time.Now().Unix())))
+	output, err := s.buildThumbnails(ctx, source, relDir)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != nil {
+		s.log.Errorw("Video thumbnail failed", "error", err, "video_gen_id", videoGen.ID)
+		s.taskService.UpdateTaskError(task.ID, err)
+		return nil
+	}
+
+	s.db.Model(&models.VideoGeneration{}).Where("id = ?", videoGen.ID).Updates(map[string]interface{}{
+		"thumbnail_url":  output.posterURL,
+		"sprite_vtt_url": output.spriteURL,
+	})
+
+	// 已导入素材库的视频：替换为空或仍使用厂商首帧的封面，手动设置的封面保留
+	assets := s.db.Model(&models.Asset{}).Where("video_gen_id = ?", videoGen.ID)
+	if videoGen.FirstFrameURL != nil {
+		assets = assets.Where("thumbnail_url IS NULL OR thumbnail_url = '' OR thumbnail_url = ?", *videoGen.FirstFrameURL)
+	} else {
+		assets = assets.Where("thumbnail_url IS NULL OR thumbnail_url = ''")
+	}
+	assets.Updates(map[string]interface{}{"thumbnail_url": output.posterURL})
+	s.db.Model(&models.Asset{}).Where("video_gen_id = ?", videoGen.ID).Update("sprite_vtt_url", output.spriteURL)
+
+	s.taskService.UpdateTaskResult(task.ID, map[string]interface{}{
+		"video_gen_id":   videoGen.ID,
+		"thumbnail_url":  output.posterURL,
+		"sprite_vtt_url": output.spriteURL,
+	})
+	return nil
+}
+
+// handleEpisodeThumbnailJob 任务队列处理函数，为合成完成的成片生成封面和雪碧图
+func (s *ThumbnailService) handleEpisodeThumbnailJob(ctx context.Context, task *models.AsyncTask) error {
+	var payload episodeThumbnailPayload
+	if err := decodeJobPayload(task, &payload); err != nil {
+		return err
+	}
+
+	var merge models.VideoMerge
+	if err := s.db.First(&merge, payload.MergeID).Error; err != nil {
+		s.taskService.UpdateTaskError(task.ID, fmt.Errorf("video merge not found"))
+		return nil
+	}
+	if merge.Status != models.VideoMergeStatusCompleted || merge.MergedURL == nil || *merge.MergedURL == "" {
+		s.taskService.UpdateTaskError(task.ID, fmt.Errorf("video merge is not completed"))
+		return nil
+	}
+
+	relDir := filepath.ToSlash(filepath.Join("thumbnails", "episodes", fmt.Sprintf("episode_%d_%d", merge.EpisodeID, time.Now().Unix())))
+	output, err := s.buildThumbnails(ctx, s.videoSource(*merge.MergedURL), relDir)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err != nil {
+		s.log.Errorw("Episode thumbnail failed", "error", err, "episode_id", merge.EpisodeID, "merge_id", merge.ID)
+		s.taskService.UpdateTaskError(task.ID, err)
+		return nil
+	}
+
+	// 章节已经重新合成时不再覆盖封面
+	updated := s.db.Model(&models.Episode{}).Where("id = ? AND video_url = ?", merge.EpisodeID, *merge.MergedURL).Updates(map[string]interface{}{
+		"thumbnail":      output.posterURL,
+		"sprite_vtt_url": output.spriteURL,
+	})
+	if updated.RowsAffected > 0 {
+		s.fillDramaThumbnail(merge.DramaID, output.posterURL)
+
+		// 成片的其他规格和字幕版沿用成片封面
+		query := s.db.Model(&models.Asset{}).
+			Where("episode_id = ? AND type = ? AND (thumbnail_url IS NULL OR thumbnail_url = '')", merge.EpisodeID, models.AssetTypeVideo).
+			Where("category IN ?", []string{models.AssetCategoryRendition, models.AssetCategoryHLS, models.AssetCategorySubtitleSoft, models.AssetCategorySubtitleBurn})
+		if merge.CompletedAt != nil {
+			query = query.Where("created_at >= ?", *merge.CompletedAt)
+		}
+		query.Updates(map[string]interface{}{
+			"thumbnail_url":  output.posterURL,
+			"sprite_vtt_url": output.spriteURL,
+		})
+	}
+
+	s.taskService.UpdateTaskResult(task.ID, map[string]interface{}{
+		"episode_id":     merge.EpisodeID,
+		"merge_id":       merge.ID,
+		"thumbnail_url":  output.posterURL,
+		"sprite_vtt_url": output.spriteURL,
+	})
+	return nil
+}
+
+// buildThumbnails 在 relDir 下生成封面和拖动预览雪碧图
+func (s *ThumbnailService) buildThumbnails(ctx context.Context, source, relDir string) (*thumbnailOutput, error) {
+	duration, err := s.ffmpeg.GetVideoDuration(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to probe video duration: %w", err)
+	}
+
+	outputDir := filepath.Join(s.storagePath, relDir)
+	if _, err := s.ffmpeg.ExtractPoster(ctx, &ffmpeg.PosterOptions{
+		InputPath:  source,
+		OutputPath: filepath.Join(outputDir, posterFileName),
+		Duration:   duration,
+	}); err != nil {
+		os.RemoveAll(outputDir)
+		return nil, err
+	}
+	output := &thumbnailOutput{posterURL: fmt.Sprintf("%s/%s/%s", s.baseURL, relDir, posterFileName)}
+
+	width, height := s.ffmpeg.GetVideoResolution(source)
+	if _, err := s.ffmpeg.BuildSprite(ctx, &ffmpeg.SpriteOptions{
+		InputPath: source,
+		OutputDir: outputDir,
+		Duration:  duration,
+		Width:     width,
+		Height:    height,
+	}); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		s.log.Warnw("Failed to build sprite sheet", "error", err, "source", source)
+		return output, nil
+	}
+	spriteURL := fmt.Sprintf("%s/%s/%s", s.baseURL, relDir, ffmpeg.SpriteVTTName)
+	output.spriteURL = &spriteURL
+	return output, nil
+}
+
+// fillDramaThumbnail 剧本还没有封面时使用章节封面
+func (s *ThumbnailService) fillDramaThumbnail(dramaID uint, posterURL string) {
+	s.db.Model(&models.Drama{}).Where("id = ? AND (thumbnail IS NULL OR thumbnail = '')", dramaID).Update("thumbnail", posterURL)
+}
+
+func (s *ThumbnailService) videoSource(videoURL string) string {
+	if strings.HasPrefix(videoURL, "http://") || strings.HasPrefix(videoURL, "https://") {
+		return videoURL
+	}
+	return resolveStoragePath(s.storagePath, videoURL)
+}
+
+// scheduleVideoThumbnail 视频生成完成后创建封面任务
+func scheduleVideoThumbnail(jobQueue *JobQueue, log *logger.Logger, videoGen *models.VideoGeneration) {
+	_, err := jobQueue.Enqueue("video_thumbnail", fmt.Sprintf("%d", videoGen.ID), JobOptions{
+		Queue:    JobQueueFFmpeg,
+		Priority: JobPriorityBatch,
+		DramaID:  videoGen.DramaID,
+		Payload:  videoThumbnailPayload{VideoGenID: videoGen.ID},
+	})
+	if err != nil {
+		log.Errorw("Failed to enqueue video thumbnail", "error", err, "video_gen_id", videoGen.ID)
+	}
+}
+
+// scheduleEpisodeThumbnail 成片合成完成后创建封面任务
+func scheduleEpisodeThumbnail(jobQueue *JobQueue, log *logger.Logger, merge *models.VideoMerge) {
+	if merge.EpisodeID == 0 {
+		return
+	}
+	_, err := jobQueue.Enqueue("episode_thumbnail", fmt.Sprintf("%d", merge.EpisodeID), JobOptions{
+		Queue:    JobQueueFFmpeg,
+		Priority: JobPriorityBatch,
+		DramaID:  merge.DramaID,
+		Payload:  episodeThumbnailPayload{EpisodeID: merge.EpisodeID, MergeID: merge.ID},
+	})
+	if err != nil {
+		log.Errorw("Failed to enqueue episode thumbnail", "error", err, "merge_id", merge.ID)
+	}
+}
+
+// videoGenThumbnail 视频的封面，未生成时使用厂商返回的首帧
+func videoGenThumbnail(videoGen *models.VideoGeneration) *string {
+	if videoGen.ThumbnailURL != nil && *videoGen.ThumbnailURL != "" {
+		return videoGen.ThumbnailURL
+	}
+	return videoGen.FirstFrameURL
+}
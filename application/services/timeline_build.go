@@ -0,0 +1,623 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	models "github.com/drama-generator/backend/domain/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 自动剪辑的默认转场
+const (
+	defaultBuildTransition         = models.TransitionTypeDissolve
+	defaultBuildTransitionDuration = 500
+)
+
+// 自动剪辑的配乐和音效淡入淡出（毫秒）：相邻配乐之间交叉淡化，独立配乐首尾淡入淡出
+const (
+	buildMusicCrossfade = 1500
+	buildMusicFade      = 1000
+	buildSFXFadeOut     = 200
+)
+
+// BuildTimelineRequest 根据分镜自动生成时间线
+type BuildTimelineRequest struct {
+	Name               string                `json:"name" binding:"max=200"`
+	FPS                int                   `json:"fps"`
+	Resolution         *string               `json:"resolution"`
+	TransitionType     models.TransitionType `json:"transition_type"`
+	TransitionDuration *int                  `json:"transition_duration"` // 为 0 时不添加转场
+}
+
+// buildShot 自动剪辑中一个分镜在视频轨道上的位置
+type buildShot struct {
+	storyboard *models.Storyboard
+	start      int
+	duration   int
+}
+
+// BuildEpisodeTimeline 按分镜顺序将每个分镜当前采用的视频排列到视频轨道上，生成可继续编辑的时间线：
+// 片段按分镜时长裁剪，相邻片段之间添加默认转场，对白生成文字轨道，配音、背景音乐和音效放到音频轨道
+func (s *TimelineService) BuildEpisodeTimeline(episodeID uint, req *BuildTimelineRequest) (*models.Timeline, error) {
+	var episode models.Episode
+	if err := s.db.First(&episode, episodeID).Error; err != nil {
+		return nil, fmt.Errorf("episode not found")
+	}
+
+	var storyboards []models.Storyboard
+	if err := s.db.Where("episode_id = ?", episodeID).
+		Order("storyboard_number ASC").
+		Find(&storyboards).Error; err != nil {
+		return nil, fmt.Errorf("failed to load storyboards: %w", err)
+	}
+	if len(storyboards) == 0 {
+		return nil, fmt.Errorf("episode has no storyboards")
+	}
+
+	transitionType := req.TransitionType
+	if transitionType == "" {
+		transitionType = defaultBuildTransition
+	}
+	if !isValidTransitionType(transitionType) {
+		return nil, fmt.Errorf("invalid transition type: %s", transitionType)
+	}
+	transitionDuration := defaultBuildTransitionDuration
+	if req.TransitionDuration != nil {
+		transitionDuration = *req.TransitionDuration
+	}
+	if transitionDuration < 0 {
+		return nil, fmt.Errorf("transition duration must not be negative")
+	}
+
+	timeline := &models.Timeline{
+		DramaID:    episode.DramaID,
+		EpisodeID:  &episode.ID,
+		Name:       req.Name,
+		FPS:        30,
+		Resolution: req.Resolution,
+		Status:     models.TimelineStatusDraft,
+	}
+	if timeline.Name == "" {
+		timeline.Name = fmt.Sprintf("第%d集 自动剪辑", episode.EpisodeNum)
+	}
+	if req.FPS != 0 {
+		if err := validateTimelineFPS(req.FPS); err != nil {
+			return nil, err
+		}
+		timeline.FPS = req.FPS
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(timeline).Error; err != nil {
+			return fmt.Errorf("failed to create timeline: %w", err)
+		}
+
+		shots, err := s.buildVideoTrack(tx, timeline, &episode, storyboards, transitionType, transitionDuration)
+		if err != nil {
+			return err
+		}
+		if len(shots) == 0 {
+			return fmt.Errorf("no storyboard videos available")
+		}
+		if err := s.buildDialogueTrack(tx, timeline, shots); err != nil {
+			return err
+		}
+		if err := s.buildAudioTracks(tx, timeline, &episode, shots); err != nil {
+			return err
+		}
+		return updateTimelineDuration(tx, timeline.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.log.Infow("Timeline built from storyboards", "id", timeline.ID, "episode_id", episodeID)
+	return s.GetTimeline(timeline.ID)
+}
+
+// buildVideoTrack 创建视频轨道，没有可用视频的分镜会被跳过
+func (s *TimelineService) buildVideoTrack(tx *gorm.DB, timeline *models.Timeline, episode *models.Episode,
+	storyboards []models.Storyboard, transitionType models.TransitionType, transitionDuration int) ([]buildShot, error) {
+	track, err := createBuildTrack(tx, timeline.ID, "视频", models.TrackTypeVideo, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var shots []buildShot
+	var prev *models.TimelineClip
+	position := 0
+	for i := range storyboards {
+		storyboard := &storyboards[i]
+		// 只需要素材引用，不解析文件路径
+		take := findStoryboardTake(tx, "", episode.ID, storyboard)
+		if take == nil {
+			s.log.Warnw("Storyboard has no video, skipping", "storyboard_number", storyboard.StoryboardNumber)
+			continue
+		}
+		asset, err := ensureTakeAsset(tx, episode, storyboard, take)
+		if err != nil {
+			return nil, err
+		}
+
+		// 按分镜设定的时长裁剪，视频比分镜短时使用整段视频
+		clip := &models.TimelineClip{
+			TrackID:      track.ID,
+			AssetID:      &asset.ID,
+			StoryboardID: &storyboard.ID,
+			Name:         fmt.Sprintf("镜头%d", storyboard.StoryboardNumber),
+			StartTime:    position,
+			// 口型同步后的视频音轨只有配音，配音已放到配音轨道上，静音避免重复
+			IsMuted: take.LipSynced,
+		}
+		if storyboard.Duration > 0 {
+			clip.Duration = storyboard.Duration * 1000
+			if sourceDuration := assetDurationMs(asset); sourceDuration > 0 && clip.Duration > sourceDuration {
+				clip.Duration = sourceDuration
+			}
+		}
+		if clip.Duration == 0 && assetDurationMs(asset) == 0 {
+			clip.Duration = defaultStoryboardClipMs
+		}
+		if err := normalizeClipTiming(clip, asset); err != nil {
+			return nil, fmt.Errorf("storyboard %d: %w", storyboard.StoryboardNumber, err)
+		}
+
+		if prev != nil && transitionDuration > 0 {
+			// 转场时长不超过相邻两个片段中较短者的一半
+			duration := minInt(transitionDuration, minInt(prev.Duration, clip.Duration)/2)
+			if duration > 0 {
+				transition := &models.ClipTransition{Type: transitionType, Duration: duration}
+				if err := tx.Create(transition).Error; err != nil {
+					return nil, fmt.Errorf("failed to create transition: %w", err)
+				}
+				clip.TransitionIn = &transition.ID
+			}
+		}
+
+		if err := tx.Omit(clause.Associations).Create(clip).Error; err != nil {
+			return nil, fmt.Errorf("failed to create clip: %w", err)
+		}
+		shots = append(shots, buildShot{storyboard: storyboard, start: clip.StartTime, duration: clip.Duration})
+		prev = clip
+		position = clip.EndTime
+	}
+	return shots, nil
+}
+
+// buildDialogueTrack 将分镜对白放到文字轨道上，与对应镜头对齐
+func (s *TimelineService) buildDialogueTrack(tx *gorm.DB, timeline *models.Timeline, shots []buildShot) error {
+	var track *models.TimelineTrack
+	for _, shot := range shots {
+		dialogue := dialogueText(shot.storyboard.Dialogue)
+		if dialogue == "" {
+			continue
+		}
+		if track == nil {
+			var err error
+			if track, err = createBuildTrack(tx, timeline.ID, "对白", models.TrackTypeText, 1); err != nil {
+				return err
+			}
+		}
+
+		clip := &models.TimelineClip{
+			TrackID:      track.ID,
+			StoryboardID: &shot.storyboard.ID,
+			Name:         dialogue,
+			StartTime:    shot.start,
+			Duration:     shot.duration,
+			EndTime:      shot.start + shot.duration,
+		}
+		if err := tx.Omit(clause.Associations).Create(clip).Error; err != nil {
+			return fmt.Errorf("failed to create clip: %w", err)
+		}
+	}
+	return nil
+}
+
+// buildAudioTracks 配音按分镜对齐到配音轨道，再生成背景音乐和音效轨道
+func (s *TimelineService) buildAudioTracks(tx *gorm.DB, timeline *models.Timeline, episode *models.Episode, shots []buildShot) error {
+	var voiceTrack *models.TimelineTrack
+	for _, shot := range shots {
+		voices, err := storyboardVoices(tx, episode.ID, shot.storyboard.ID)
+		if err != nil {
+			return err
+		}
+
+		// 台词从镜头起点依次排列，超出镜头的部分截掉，避免与下一镜头的配音重叠
+		position := shot.start
+		shotEnd := shot.start + shot.duration
+		for i := range voices {
+			asset := &voices[i].asset
+			if position >= shotEnd {
+				s.log.Warnw("Voice exceeds shot duration, truncated", "storyboard_number", shot.storyboard.StoryboardNumber)
+				break
+			}
+			duration := shotEnd - position
+			if voices[i].duration > 0 && voices[i].duration < duration {
+				duration = voices[i].duration
+			}
+
+			if voiceTrack == nil {
+				if voiceTrack, err = createBuildAudioTrack(tx, timeline.ID, "配音", models.TrackAudioRoleDialogue, 2); err != nil {
+					return err
+				}
+			}
+			if err := createBuildAudioClip(tx, voiceTrack, asset, shot.storyboard.ID, position, duration); err != nil {
+				return err
+			}
+			position += duration
+		}
+	}
+
+	if err := s.buildMusicTrack(tx, timeline, episode, shots); err != nil {
+		return err
+	}
+	return s.buildSFXTrack(tx, timeline, shots)
+}
+
+// buildMusicCue 背景音乐轨道上的一段配乐，连续使用同一配乐的分镜合并为一段
+type buildMusicCue struct {
+	asset        *models.Asset
+	storyboardID uint
+	start        int
+	end          int
+}
+
+// buildMusicTrack 按分镜匹配的配乐生成背景音乐轨道，相邻配乐之间交叉淡化；
+// 没有分镜匹配配乐时，将章节的背景音乐从头依次排列
+func (s *TimelineService) buildMusicTrack(tx *gorm.DB, timeline *models.Timeline, episode *models.Episode, shots []buildShot) error {
+	cues, err := storyboardMusicCues(tx, shots)
+	if err != nil {
+		return err
+	}
+	if len(cues) == 0 {
+		if cues, err = episodeMusicCues(tx, episode.ID, shots); err != nil {
+			return err
+		}
+	}
+	if len(cues) == 0 {
+		return nil
+	}
+
+	track, err := createBuildAudioTrack(tx, timeline.ID, "背景音乐", models.TrackAudioRoleMusic, 3)
+	if err != nil {
+		return err
+	}
+
+	clips := make([]*models.TimelineClip, 0, len(cues))
+	for _, cue := range cues {
+		duration := cue.end - cue.start
+		if sourceDuration := assetDurationMs(cue.asset); sourceDuration > 0 && sourceDuration < duration {
+			duration = sourceDuration
+		}
+		clip, err := newBuildAudioClip(track, cue.asset, cue.storyboardID, cue.start, duration)
+		if err != nil {
+			return err
+		}
+		clips = append(clips, clip)
+	}
+
+	for i, clip := range clips {
+		// 与前一段首尾相接时交叉淡化，否则淡入
+		if i > 0 && clips[i-1].EndTime == clip.StartTime {
+			duration := minInt(buildMusicCrossfade, minInt(clips[i-1].Duration, clip.Duration)/2)
+			if duration > 0 {
+				transition := &models.ClipTransition{Type: models.TransitionTypeCrossFade, Duration: duration}
+				if err := tx.Create(transition).Error; err != nil {
+					return fmt.Errorf("failed to create transition: %w", err)
+				}
+				clip.TransitionIn = &transition.ID
+			}
+		} else {
+			fadeIn := minInt(buildMusicFade, clip.Duration/2)
+			clip.FadeIn = &fadeIn
+		}
+		if i == len(clips)-1 || clips[i+1].StartTime != clip.EndTime {
+			fadeOut := minInt(buildMusicFade, clip.Duration/2)
+			clip.FadeOut = &fadeOut
+		}
+		if err := tx.Omit(clause.Associations).Create(clip).Error; err != nil {
+			return fmt.Errorf("failed to create clip: %w", err)
+		}
+	}
+	return nil
+}
+
+// buildSFXTrack 将分镜匹配的音效放到镜头起点，超出镜头的部分截掉
+func (s *TimelineService) buildSFXTrack(tx *gorm.DB, timeline *models.Timeline, shots []buildShot) error {
+	assets, err := loadBuildAudioAssets(tx, shots, func(storyboard *models.Storyboard) *uint {
+		return storyboard.SfxAssetID
+	})
+	if err != nil {
+		return err
+	}
+
+	var track *models.TimelineTrack
+	for _, shot := range shots {
+		if shot.storyboard.SfxAssetID == nil {
+			continue
+		}
+		asset, ok := assets[*shot.storyboard.SfxAssetID]
+		if !ok {
+			continue
+		}
+		if track == nil {
+			if track, err = createBuildAudioTrack(tx, timeline.ID, "音效", models.TrackAudioRoleSFX, 4); err != nil {
+				return err
+			}
+		}
+
+		duration := shot.duration
+		if sourceDuration := assetDurationMs(asset); sourceDuration > 0 && sourceDuration < duration {
+			duration = sourceDuration
+		}
+		clip, err := newBuildAudioClip(track, asset, shot.storyboard.ID, shot.start, duration)
+		if err != nil {
+			return err
+		}
+		fadeOut := minInt(buildSFXFadeOut, clip.Duration/2)
+		clip.FadeOut = &fadeOut
+		if err := tx.Omit(clause.Associations).Create(clip).Error; err != nil {
+			return fmt.Errorf("failed to create clip: %w", err)
+		}
+	}
+	return nil
+}
+
+// storyboardMusicCues 按分镜匹配的配乐生成配乐段，连续镜头使用同一配乐时合并
+func storyboardMusicCues(tx *gorm.DB, shots []buildShot) ([]buildMusicCue, error) {
+	assets, err := loadBuildAudioAssets(tx, shots, func(storyboard *models.Storyboard) *uint {
+		return storyboard.BgmAssetID
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var cues []buildMusicCue
+	for _, shot := range shots {
+		if shot.storyboard.BgmAssetID == nil {
+			continue
+		}
+		asset, ok := assets[*shot.storyboard.BgmAssetID]
+		if !ok {
+			continue
+		}
+		if n := len(cues); n > 0 && cues[n-1].asset.ID == asset.ID && cues[n-1].end == shot.start {
+			cues[n-1].end = shot.start + shot.duration
+			continue
+		}
+		cues = append(cues, buildMusicCue{
+			asset:        asset,
+			storyboardID: shot.storyboard.ID,
+			start:        shot.start,
+			end:          shot.start + shot.duration,
+		})
+	}
+	return cues, nil
+}
+
+// episodeMusicCues 章节的背景音乐从头依次排列，直到铺满所有镜头
+func episodeMusicCues(tx *gorm.DB, episodeID uint, shots []buildShot) ([]buildMusicCue, error) {
+	var bgmAssets []models.Asset
+	if err := tx.Where("episode_id = ? AND storyboard_id IS NULL AND type = ? AND category = ?",
+		episodeID, models.AssetTypeAudio, models.AssetCategoryBGM).
+		Order("created_at ASC").
+		Find(&bgmAssets).Error; err != nil {
+		return nil, err
+	}
+
+	last := shots[len(shots)-1]
+	total := last.start + last.duration
+	var cues []buildMusicCue
+	position := 0
+	for i := range bgmAssets {
+		if position >= total {
+			break
+		}
+		asset := &bgmAssets[i]
+		end := total
+		if sourceDuration := assetDurationMs(asset); sourceDuration > 0 && position+sourceDuration < end {
+			end = position + sourceDuration
+		}
+		cues = append(cues, buildMusicCue{asset: asset, start: position, end: end})
+		position = end
+	}
+	return cues, nil
+}
+
+// loadBuildAudioAssets 批量加载分镜引用的音频库素材，已删除的素材不会出现在结果中
+func loadBuildAudioAssets(tx *gorm.DB, shots []buildShot, assetID func(*models.Storyboard) *uint) (map[uint]*models.Asset, error) {
+	var ids []uint
+	for _, shot := range shots {
+		if id := assetID(shot.storyboard); id != nil {
+			ids = append(ids, *id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	var assets []models.Asset
+	if err := tx.Where("id IN ? AND type = ?", ids, models.AssetTypeAudio).Find(&assets).Error; err != nil {
+		return nil, err
+	}
+	result := make(map[uint]*models.Asset, len(assets))
+	for i := range assets {
+		result[assets[i].ID] = &assets[i]
+	}
+	return result, nil
+}
+
+func createBuildTrack(tx *gorm.DB, timelineID uint, name string, trackType models.TrackType, order int) (*models.TimelineTrack, error) {
+	return createBuildTrackWithRole(tx, timelineID, name, trackType, "", order)
+}
+
+// createBuildAudioTrack 创建带音频用途的音频轨道
+func createBuildAudioTrack(tx *gorm.DB, timelineID uint, name string, role models.TrackAudioRole, order int) (*models.TimelineTrack, error) {
+	return createBuildTrackWithRole(tx, timelineID, name, models.TrackTypeAudio, role, order)
+}
+
+func createBuildTrackWithRole(tx *gorm.DB, timelineID uint, name string, trackType models.TrackType,
+	role models.TrackAudioRole, order int) (*models.TimelineTrack, error) {
+	volume := 100
+	track := &models.TimelineTrack{
+		TimelineID: timelineID,
+		Name:       name,
+		Type:       trackType,
+		Order:      order,
+		Volume:     &volume,
+		AudioRole:  role,
+	}
+	if err := tx.Omit(clause.Associations).Create(track).Error; err != nil {
+		return nil, fmt.Errorf("failed to create track: %w", err)
+	}
+	return track, nil
+}
+
+func createBuildAudioClip(tx *gorm.DB, track *models.TimelineTrack, asset *models.Asset, storyboardID uint, start, duration int) error {
+	clip, err := newBuildAudioClip(track, asset, storyboardID, start, duration)
+	if err != nil {
+		return err
+	}
+	if err := tx.Omit(clause.Associations).Create(clip).Error; err != nil {
+		return fmt.Errorf("failed to create clip: %w", err)
+	}
+	return nil
+}
+
+// newBuildAudioClip 构造音频片段并计算入点出点，由调用方补充淡入淡出后保存
+func newBuildAudioClip(track *models.TimelineTrack, asset *models.Asset, storyboardID uint, start, duration int) (*models.TimelineClip, error) {
+	clip := &models.TimelineClip{
+		TrackID:   track.ID,
+		AssetID:   &asset.ID,
+		Name:      asset.Name,
+		StartTime: start,
+		Duration:  duration,
+	}
+	if storyboardID != 0 {
+		clip.StoryboardID = &storyboardID
+	}
+	if err := normalizeClipTiming(clip, asset); err != nil {
+		return nil, fmt.Errorf("audio asset %d: %w", asset.ID, err)
+	}
+	return clip, nil
+}
+
+// buildVoice 分镜的一条配音及其时长（毫秒）
+type buildVoice struct {
+	asset    models.Asset
+	duration int
+}
+
+// storyboardVoices 返回分镜的配音：优先使用按台词顺序合成的配音，没有时使用最新的一条配音素材
+func storyboardVoices(tx *gorm.DB, episodeID, storyboardID uint) ([]buildVoice, error) {
+	var lines []models.DialogueLine
+	if err := tx.Preload("Asset").
+		Where("storyboard_id = ? AND status = ? AND asset_id IS NOT NULL", storyboardID, models.DialogueLineStatusCompleted).
+		Order("line_index ASC").
+		Find(&lines).Error; err != nil {
+		return nil, err
+	}
+
+	var voices []buildVoice
+	for _, line := range lines {
+		if line.Asset == nil {
+			continue
+		}
+		// 台词记录的是毫秒级实测时长，比素材的整秒时长更准确
+		duration := line.Duration
+		if duration <= 0 {
+			duration = assetDurationMs(line.Asset)
+		}
+		voices = append(voices, buildVoice{asset: *line.Asset, duration: duration})
+	}
+	if len(voices) > 0 {
+		return voices, nil
+	}
+
+	var asset models.Asset
+	err := tx.Where("episode_id = ? AND storyboard_id = ? AND type = ? AND category = ?",
+		episodeID, storyboardID, models.AssetTypeAudio, models.AssetCategoryVoice).
+		Order("created_at DESC").
+		First(&asset).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []buildVoice{{asset: asset, duration: assetDurationMs(&asset)}}, nil
+}
+
+// ensureTakeAsset 时间线片段需要引用素材，分镜视频尚未入库时为其创建视频素材
+func ensureTakeAsset(tx *gorm.DB, episode *models.Episode, storyboard *models.Storyboard, take *storyboardTake) (*models.Asset, error) {
+	var asset models.Asset
+	if take.AssetID != nil {
+		if err := tx.First(&asset, *take.AssetID).Error; err != nil {
+			return nil, err
+		}
+		return &asset, nil
+	}
+
+	asset = models.Asset{
+		DramaID:       &episode.DramaID,
+		EpisodeID:     &episode.ID,
+		StoryboardID:  &storyboard.ID,
+		StoryboardNum: &storyboard.StoryboardNumber,
+		Name:          fmt.Sprintf("镜头%d", storyboard.StoryboardNumber),
+		Type:          models.AssetTypeVideo,
+		VideoGenID:    take.VideoGenID,
+	}
+	if take.VideoGenID != nil {
+		var videoGen models.VideoGeneration
+		if err := tx.First(&videoGen, *take.VideoGenID).Error; err != nil {
+			return nil, err
+		}
+		if videoGen.VideoURL != nil {
+			asset.URL = *videoGen.VideoURL
+		}
+		asset.LocalPath = videoGen.LocalPath
+		asset.Duration = videoGen.Duration
+		asset.Width = videoGen.Width
+		asset.Height = videoGen.Height
+		asset.ThumbnailURL = videoGenThumbnail(&videoGen)
+		asset.SpriteVTTURL = videoGen.SpriteVTTURL
+	}
+	if asset.URL == "" && storyboard.VideoURL != nil {
+		asset.URL = *storyboard.VideoURL
+	}
+
+	if err := tx.Omit(clause.Associations).Create(&asset).Error; err != nil {
+		return nil, fmt.Errorf("failed to create asset: %w", err)
+	}
+	return &asset, nil
+}
+
+// dialogueText 整理对白文本，无对白的分镜返回空字符串
+func dialogueText(dialogue *string) string {
+	if dialogue == nil {
+		return ""
+	}
+	text := strings.Join(strings.Fields(*dialogue), " ")
+	switch text {
+	case "", "无", "（无）", "(无)":
+		return ""
+	}
+	// 片段名称最长 200 个字符
+	if utf8.RuneCountInString(text) > 200 {
+		text = string([]rune(text)[:200])
+	}
+	return text
+}
+
+func isValidTransitionType(transitionType models.TransitionType) bool {
+	switch transitionType {
+	case models.TransitionTypeFade, models.TransitionTypeCrossFade, models.TransitionTypeSlide,
+		models.TransitionTypeWipe, models.TransitionTypeZoom, models.TransitionTypeDissolve:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,222 @@
+package services
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	models "github.com/drama-generator/backend/domain/models"
+	"github.com/drama-generator/backend/pkg/interchange"
+	"github.com/drama-generator/backend/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// 分镜未设置时长且视频时长未知时使用的默认片段时长（毫秒）
+const defaultStoryboardClipMs = 5000
+
+type TimelineExportService struct {
+	db              *gorm.DB
+	timelineService *TimelineService
+	storagePath     string
+	log             *logger.Logger
+}
+
+func NewTimelineExportService(db *gorm.DB, storagePath string, log *logger.Logger) *TimelineExportService {
+	return &TimelineExportService{
+		db:              db,
+		timelineService: NewTimelineService(db, log),
+		storagePath:     storagePath,
+		log:             log,
+	}
+}
+
+// ExportTimeline 将时间线导出为 EDL/FCPXML/OTIO，素材引用本地文件路径
+func (s *TimelineExportService) ExportTimeline(timelineID uint, format interchange.Format) ([]byte, error) {
+	timeline, err := s.timelineService.GetTimeline(timelineID)
+	if err != nil {
+		return nil, fmt.Errorf("timeline not found: %w", err)
+	}
+
+	storyboards, err := s.loadClipStoryboards(timeline)
+	if err != nil {
+		return nil, err
+	}
+
+	width, height := parseTimelineResolution(timeline.Resolution)
+	export := &interchange.Timeline{
+		Name:   timeline.Name,
+		FPS:    timeline.FPS,
+		Width:  width,
+		Height: height,
+	}
+
+	for _, track := range timeline.Tracks {
+		exportTrack := interchange.Track{
+			Name: track.Name,
+			Kind: interchange.TrackKind(track.Type),
+		}
+		for i := range track.Clips {
+			clip := &track.Clips[i]
+			exportClip := interchange.Clip{
+				Name:          clip.Name,
+				Start:         clip.StartTime,
+				Duration:      clip.Duration,
+				TransitionIn:  exportTransition(&clip.InTransition),
+				TransitionOut: exportTransition(&clip.OutTransition),
+			}
+			if clip.TrimStart != nil {
+				exportClip.SourceIn = *clip.TrimStart
+			}
+			if clip.Speed != nil {
+				exportClip.Speed = *clip.Speed
+			}
+
+			if track.Type == models.TrackTypeText {
+				exportClip.Text = clip.Name
+			} else if clip.AssetID != nil && clip.Asset.ID != 0 {
+				exportClip.MediaPath = s.assetMediaPath(&clip.Asset)
+				exportClip.MediaDuration = assetDurationMs(&clip.Asset)
+			}
+
+			if clip.StoryboardID != nil {
+				if storyboard, ok := storyboards[*clip.StoryboardID]; ok {
+					exportClip.Markers = []interchange.Marker{storyboardMarker(storyboard)}
+					if exportClip.MediaPath == "" && track.Type != models.TrackTypeText && timeline.EpisodeID != nil {
+						if take := findStoryboardTake(s.db, s.storagePath, *timeline.EpisodeID, storyboard); take != nil {
+							exportClip.MediaPath = s.absMediaPath(take.Source)
+							exportClip.MediaDuration = take.Duration * 1000
+						}
+					}
+				}
+			}
+			exportTrack.Clips = append(exportTrack.Clips, exportClip)
+		}
+		export.Tracks = append(export.Tracks, exportTrack)
+	}
+
+	return interchange.Encode(export, format)
+}
+
+// ExportEpisode 按分镜顺序将章节导出为单轨剪辑序列，每个分镜使用当前采用的视频
+func (s *TimelineExportService) ExportEpisode(episodeID uint, format interchange.Format) ([]byte, error) {
+	var episode models.Episode
+	if err := s.db.Preload("Drama").First(&episode, episodeID).Error; err != nil {
+		return nil, fmt.Errorf("episode not found: %w", err)
+	}
+
+	var storyboards []models.Storyboard
+	if err := s.db.Where("episode_id = ?", episodeID).
+		Order("storyboard_number ASC").
+		Find(&storyboards).Error; err != nil {
+		return nil, fmt.Errorf("failed to load storyboards: %w", err)
+	}
+
+	track := interchange.Track{Name: "V1", Kind: interchange.TrackVideo}
+	position := 0
+	for i := range storyboards {
+		storyboard := &storyboards[i]
+		take := findStoryboardTake(s.db, s.storagePath, episodeID, storyboard)
+		if take == nil {
+			s.log.Warnw("Storyboard has no video, skipping export", "storyboard_number", storyboard.StoryboardNumber)
+			continue
+		}
+
+		// 片段时长使用分镜设定的时长，不超过视频实际时长
+		duration := storyboard.Duration * 1000
+		mediaDuration := take.Duration * 1000
+		if duration <= 0 || (mediaDuration > 0 && duration > mediaDuration) {
+			duration = mediaDuration
+		}
+		if duration <= 0 {
+			duration = defaultStoryboardClipMs
+		}
+
+		name := fmt.Sprintf("镜头%d", storyboard.StoryboardNumber)
+		if storyboard.Title != nil && *storyboard.Title != "" {
+			name = fmt.Sprintf("%s %s", name, *storyboard.Title)
+		}
+		track.Clips = append(track.Clips, interchange.Clip{
+			Name:          name,
+			MediaPath:     s.absMediaPath(take.Source),
+			MediaDuration: mediaDuration,
+			Start:         position,
+			Duration:      duration,
+			Markers:       []interchange.Marker{storyboardMarker(storyboard)},
+		})
+		position += duration
+	}
+	if len(track.Clips) == 0 {
+		return nil, fmt.Errorf("no videos available for export")
+	}
+
+	export := &interchange.Timeline{
+		Name:   fmt.Sprintf("%s - 第%d集", episode.Drama.Title, episode.EpisodeNum),
+		FPS:    30,
+		Width:  1920,
+		Height: 1080,
+		Tracks: []interchange.Track{track},
+	}
+	return interchange.Encode(export, format)
+}
+
+// loadClipStoryboards 加载时间线片段关联的分镜
+func (s *TimelineExportService) loadClipStoryboards(timeline *models.Timeline) (map[uint]*models.Storyboard, error) {
+	var ids []uint
+	for _, track := range timeline.Tracks {
+		for _, clip := range track.Clips {
+			if clip.StoryboardID != nil {
+				ids = append(ids, *clip.StoryboardID)
+			}
+		}
+	}
+
+	result := make(map[uint]*models.Storyboard)
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	var storyboards []models.Storyboard
+	if err := s.db.Where("id IN ?", ids).Find(&storyboards).Error; err != nil {
+		return nil, fmt.Errorf("failed to load storyboards: %w", err)
+	}
+	for i := range storyboards {
+		result[storyboards[i].ID] = &storyboards[i]
+	}
+	return result, nil
+}
+
+// assetMediaPath 优先使用素材的本地文件完整路径，否则使用远程 URL
+func (s *TimelineExportService) assetMediaPath(asset *models.Asset) string {
+	if asset.LocalPath != nil && *asset.LocalPath != "" {
+		return s.absMediaPath(resolveStoragePath(s.storagePath, *asset.LocalPath))
+	}
+	return asset.URL
+}
+
+// absMediaPath 剪辑软件需要绝对路径，远程 URL 保持不变
+func (s *TimelineExportService) absMediaPath(source string) string {
+	if source == "" || strings.Contains(source, "://") {
+		return source
+	}
+	if abs, err := filepath.Abs(source); err == nil {
+		return abs
+	}
+	return source
+}
+
+// storyboardMarker 以分镜编号命名的标记点
+func storyboardMarker(storyboard *models.Storyboard) interchange.Marker {
+	marker := interchange.Marker{Name: fmt.Sprintf("Storyboard %d", storyboard.StoryboardNumber)}
+	if storyboard.Title != nil {
+		marker.Note = *storyboard.Title
+	}
+	return marker
+}
+
+// exportTransition 转换片段转场，未设置时返回 nil
+func exportTransition(transition *models.ClipTransition) *interchange.Transition {
+	if transition.ID == 0 || transition.Duration <= 0 {
+		return nil
+	}
+	return &interchange.Transition{Name: string(transition.Type), Duration: transition.Duration}
+}
@@ -0,0 +1,312 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	models "github.com/drama-generator/backend/domain/models"
+	"github.com/drama-generator/backend/infrastructure/external/ffmpeg"
+	"github.com/drama-generator/backend/pkg/logger"
+	"gorm.io/gorm"
+)
+
+// timelineRenderPayload 时间线渲染任务参数
+type timelineRenderPayload struct {
+	TimelineID     uint                  `json:"timeline_id"`
+	PreviousStatus models.TimelineStatus `json:"previous_status"`
+}
+
+// 常用清晰度简写对应的 16:9 分辨率
+var resolutionPresets = map[string][2]int{
+	"480p":  {854, 480},
+	"720p":  {1280, 720},
+	"1080p": {1920, 1080},
+	"2k":    {2560, 1440},
+	"1440p": {2560, 1440},
+	"4k":    {3840, 2160},
+	"2160p": {3840, 2160},
+}
+
+var resolutionPattern = regexp.MustCompile(`^(\d{2,5})\s*[xX*:]\s*(\d{2,5})$`)
+
+type TimelineRenderService struct {
+	db              *gorm.DB
+	timelineService *TimelineService
+	taskService     *TaskService
+	ffmpeg          *ffmpeg.FFmpeg
+	storagePath     string
+	baseURL         string
+	log             *logger.Logger
+	jobQueue        *JobQueue
+}
+
+func NewTimelineRenderService(db *gorm.DB, jobQueue *JobQueue, taskService *TaskService, storagePath, baseURL string, log *logger.Logger) *TimelineRenderService {
+	service := &TimelineRenderService{
+		db:              db,
+		timelineService: NewTimelineService(db, log),
+		taskService:     taskService,
+		ffmpeg:          ffmpeg.NewFFmpeg(log),
+		storagePath:     storagePath,
+		baseURL:         baseURL,
+		log:             log,
+		jobQueue:        jobQueue,
+	}
+
+	service.jobQueue.RegisterHandler("timeline_render", service.handleTimelineRenderJob)
+	service.jobQueue.RegisterCanceler("timeline_render", service.cancelTimelineRender)
+
+	return service
+}
+
+// RenderTimeline 创建时间线渲染任务，渲染结果保存为新的视频素材
+func (s *TimelineRenderService) RenderTimeline(timelineID uint) (*models.AsyncTask, error) {
+	var timeline models.Timeline
+	if err := s.db.First(&timeline, timelineID).Error; err != nil {
+		return nil, err
+	}
+	if timeline.Status == models.TimelineStatusExporting ||
+		s.jobQueue.HasActiveJob("timeline_render", fmt.Sprintf("%d", timelineID)) {
+		return nil, fmt.Errorf("timeline is already rendering")
+	}
+	if timeline.Duration <= 0 {
+		return nil, fmt.Errorf("timeline is empty")
+	}
+
+	if err := s.db.Model(&timeline).Update("status", models.TimelineStatusExporting).Error; err != nil {
+		return nil, fmt.Errorf("failed to update timeline status: %w", err)
+	}
+
+	task, err := s.jobQueue.Enqueue("timeline_render", fmt.Sprintf("%d", timelineID), JobOptions{
+		Queue:    JobQueueFFmpeg,
+		Priority: JobPriorityInteractive,
+		DramaID:  timeline.DramaID,
+		Payload:  timelineRenderPayload{TimelineID: timelineID, PreviousStatus: timeline.Status},
+	})
+	if err != nil {
+		s.restoreTimelineStatus(timelineID, timeline.Status)
+		return nil, err
+	}
+
+	s.log.Infow("Timeline render queued", "timeline_id", timelineID, "task_id", task.ID)
+	return task, nil
+}
+
+// handleTimelineRenderJob 任务队列处理函数，渲染可以安全重跑，中断后由队列重新排队即可
+func (s *TimelineRenderService) handleTimelineRenderJob(ctx context.Context, task *models.AsyncTask) error {
+	var payload timelineRenderPayload
+	if err := decodeJobPayload(task, &payload); err != nil {
+		return err
+	}
+
+	asset, err := s.renderTimeline(ctx, task.ID, payload.TimelineID)
+	if ctx.Err() != nil {
+		// 取消时由取消回调恢复状态，服务停止时任务重新排队
+		return ctx.Err()
+	}
+	s.restoreTimelineStatus(payload.TimelineID, payload.PreviousStatus)
+	if err != nil {
+		s.log.Errorw("Timeline render failed", "error", err, "timeline_id", payload.TimelineID)
+		s.taskService.UpdateTaskError(task.ID, err)
+		return nil
+	}
+
+	s.taskService.UpdateTaskResult(task.ID, map[string]interface{}{
+		"timeline_id": payload.TimelineID,
+		"asset_id":    asset.ID,
+		"url":         asset.URL,
+		"duration":    asset.Duration,
+	})
+	return nil
+}
+
+// cancelTimelineRender 取消渲染时恢复时间线状态
+func (s *TimelineRenderService) cancelTimelineRender(task *models.AsyncTask) {
+	var payload timelineRenderPayload
+	if err := decodeJobPayload(task, &payload); err != nil {
+		return
+	}
+	s.restoreTimelineStatus(payload.TimelineID, payload.PreviousStatus)
+}
+
+func (s *TimelineRenderService) renderTimeline(ctx context.Context, taskID string, timelineID uint) (*models.Asset, error) {
+	timeline, err := s.timelineService.GetTimeline(timelineID)
+	if err != nil {
+		return nil, fmt.Errorf("timeline not found: %w", err)
+	}
+	if timeline.Duration <= 0 {
+		return nil, fmt.Errorf("timeline is empty")
+	}
+
+	s.taskService.UpdateTaskStatus(taskID, "processing", 0, "正在准备素材...")
+
+	width, height := parseTimelineResolution(timeline.Resolution)
+	opts := &ffmpeg.RenderOptions{
+		Width:    width,
+		Height:   height,
+		FPS:      timeline.FPS,
+		Duration: float64(timeline.Duration) / 1000,
+		Progress: func(percent int) {
+			s.taskService.UpdateTaskStatus(taskID, "processing", percent, fmt.Sprintf("正在渲染 %d%%", percent))
+		},
+	}
+	for i := range timeline.Tracks {
+		opts.Tracks = append(opts.Tracks, s.buildRenderTrack(&timeline.Tracks[i]))
+	}
+
+	videoDir := filepath.Join(s.storagePath, "videos", "timelines")
+	fileName := fmt.Sprintf("timeline_%d_%d.mp4", timelineID, time.Now().Unix())
+	opts.OutputPath = filepath.Join(videoDir, fileName)
+
+	if _, err := s.ffmpeg.RenderTimeline(ctx, opts); err != nil {
+		return nil, err
+	}
+
+	// 只保存相对路径
+	relPath := filepath.ToSlash(filepath.Join("videos", "timelines", fileName))
+	durationSeconds := int(math.Ceil(opts.Duration))
+	mimeType := "video/mp4"
+	format := "mp4"
+	asset := &models.Asset{
+		DramaID:   &timeline.DramaID,
+		EpisodeID: timeline.EpisodeID,
+		Name:      fmt.Sprintf("%s 渲染", timeline.Name),
+		Type:      models.AssetTypeVideo,
+		URL:       fmt.Sprintf("%s/%s", s.baseURL, relPath),
+		LocalPath: &relPath,
+		MimeType:  &mimeType,
+		Format:    &format,
+		Width:     &opts.Width,
+		Height:    &opts.Height,
+		Duration:  &durationSeconds,
+	}
+	if timeline.Duration > 0 {
+		durationMs := timeline.Duration
+		asset.DurationMs = &durationMs
+	}
+	if info, err := os.Stat(opts.OutputPath); err == nil {
+		size := info.Size()
+		asset.FileSize = &size
+	}
+
+	if err := s.db.Create(asset).Error; err != nil {
+		os.Remove(opts.OutputPath)
+		return nil, fmt.Errorf("failed to create asset: %w", err)
+	}
+
+	s.log.Infow("Timeline rendered", "timeline_id", timelineID, "asset_id", asset.ID, "path", relPath)
+	return asset, nil
+}
+
+// buildRenderTrack 将轨道转换为渲染参数，时间由毫秒换算为秒，音量由百分比换算为倍数
+func (s *TimelineRenderService) buildRenderTrack(track *models.TimelineTrack) ffmpeg.RenderTrack {
+	renderTrack := ffmpeg.RenderTrack{
+		Type:   string(track.Type),
+		Role:   string(track.AudioRole),
+		Order:  track.Order,
+		Muted:  track.IsMuted,
+		Volume: percentToGain(track.Volume),
+	}
+
+	for i := range track.Clips {
+		clip := &track.Clips[i]
+		renderClip := ffmpeg.RenderClip{
+			Text:     clip.Name,
+			Start:    msToSeconds(clip.StartTime),
+			Duration: msToSeconds(clip.Duration),
+			Speed:    1,
+			Volume:   percentToGain(clip.Volume),
+			Muted:    clip.IsMuted,
+		}
+		if clip.TrimStart != nil {
+			renderClip.TrimStart = msToSeconds(*clip.TrimStart)
+		}
+		if clip.Speed != nil && *clip.Speed > 0 {
+			renderClip.Speed = *clip.Speed
+		}
+		if clip.FadeIn != nil {
+			renderClip.FadeIn = msToSeconds(*clip.FadeIn)
+		}
+		if clip.FadeOut != nil {
+			renderClip.FadeOut = msToSeconds(*clip.FadeOut)
+		}
+		// 音频片段的入场转场按交叉淡化处理
+		if track.Type == models.TrackTypeAudio && clip.TransitionIn != nil && clip.InTransition.Duration > 0 {
+			renderClip.CrossfadeIn = msToSeconds(clip.InTransition.Duration)
+		}
+		if clip.AssetID != nil && clip.Asset.ID != 0 {
+			renderClip.Source = s.resolveAssetSource(&clip.Asset)
+			renderClip.IsImage = clip.Asset.Type == models.AssetTypeImage
+		}
+		for _, effect := range clip.Effects {
+			if !effect.IsEnabled {
+				continue
+			}
+			config := effect.Config
+			if effect.Type == models.EffectTypeColor {
+				config = colorEffectConfig(s.log, s.storagePath, config)
+			}
+			renderClip.Effects = append(renderClip.Effects, ffmpeg.RenderEffect{
+				Type:   string(effect.Type),
+				Config: config,
+			})
+		}
+		renderTrack.Clips = append(renderTrack.Clips, renderClip)
+	}
+	return renderTrack
+}
+
+// resolveAssetSource 优先使用素材的本地文件，否则使用远程 URL
+func (s *TimelineRenderService) resolveAssetSource(asset *models.Asset) string {
+	if asset.LocalPath != nil && *asset.LocalPath != "" {
+		return resolveStoragePath(s.storagePath, *asset.LocalPath)
+	}
+	return asset.URL
+}
+
+func (s *TimelineRenderService) restoreTimelineStatus(timelineID uint, status models.TimelineStatus) {
+	if status == "" || status == models.TimelineStatusExporting {
+		status = models.TimelineStatusEditing
+	}
+	if err := s.db.Model(&models.Timeline{}).
+		Where("id = ? AND status = ?", timelineID, models.TimelineStatusExporting).
+		Update("status", status).Error; err != nil {
+		s.log.Errorw("Failed to restore timeline status", "error", err, "timeline_id", timelineID)
+	}
+}
+
+// parseTimelineResolution 解析 1920x1080 或 1080p 形式的分辨率，默认 1920x1080
+func parseTimelineResolution(resolution *string) (int, int) {
+	if resolution == nil {
+		return 1920, 1080
+	}
+	value := strings.ToLower(strings.TrimSpace(*resolution))
+	if preset, ok := resolutionPresets[value]; ok {
+		return preset[0], preset[1]
+	}
+	if matches := resolutionPattern.FindStringSubmatch(value); matches != nil {
+		width, _ := strconv.Atoi(matches[1])
+		height, _ := strconv.Atoi(matches[2])
+		// libx264 要求宽高为偶数
+		return width &^ 1, height &^ 1
+	}
+	return 1920, 1080
+}
+
+func msToSeconds(ms int) float64 {
+	return float64(ms) / 1000
+}
+
+// percentToGain 音量百分比换算为倍数，未设置时为原始音量
+func percentToGain(volume *int) float64 {
+	if volume == nil {
+		return 1
+	}
+	return float64(*volume) / 100
+}
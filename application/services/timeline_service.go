@@ -0,0 +1,1175 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	models "github.com/drama-generator/backend/domain/models"
+	"github.com/drama-generator/backend/pkg/logger"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 片段变速范围
+const (
+	minClipSpeed = 0.1
+	maxClipSpeed = 16.0
+)
+
+type TimelineService struct {
+	db  *gorm.DB
+	log *logger.Logger
+}
+
+func NewTimelineService(db *gorm.DB, log *logger.Logger) *TimelineService {
+	return &TimelineService{
+		db:  db,
+		log: log,
+	}
+}
+
+type CreateTimelineRequest struct {
+	DramaID     uint    `json:"drama_id" binding:"required"`
+	EpisodeID   *uint   `json:"episode_id"`
+	Name        string  `json:"name" binding:"required,max=200"`
+	Description *string `json:"description"`
+	FPS         int     `json:"fps"`
+	Resolution  *string `json:"resolution"`
+}
+
+type UpdateTimelineRequest struct {
+	Name        *string                `json:"name" binding:"omitempty,max=200"`
+	Description *string                `json:"description"`
+	FPS         *int                   `json:"fps"`
+	Resolution  *string                `json:"resolution"`
+	Status      *models.TimelineStatus `json:"status"`
+}
+
+type CreateTrackRequest struct {
+	Name      string                `json:"name" binding:"required,max=100"`
+	Type      models.TrackType      `json:"type" binding:"required"`
+	Order     *int                  `json:"order"`
+	IsLocked  bool                  `json:"is_locked"`
+	IsMuted   bool                  `json:"is_muted"`
+	Volume    *int                  `json:"volume"`
+	AudioRole models.TrackAudioRole `json:"audio_role"` // 仅音频轨道：dialogue、music、sfx
+}
+
+type UpdateTrackRequest struct {
+	Name      *string                `json:"name" binding:"omitempty,max=100"`
+	Order     *int                   `json:"order"`
+	IsLocked  *bool                  `json:"is_locked"`
+	IsMuted   *bool                  `json:"is_muted"`
+	Volume    *int                   `json:"volume"`
+	AudioRole *models.TrackAudioRole `json:"audio_role"` // 传空字符串时清除
+}
+
+// CreateClipRequest 添加片段，时间单位为毫秒
+// 有素材时长的片段可只传 start_time，默认使用整段素材；duration 与 trim_end 同时缺省时按素材时长计算
+type CreateClipRequest struct {
+	AssetID      *uint    `json:"asset_id"`
+	StoryboardID *uint    `json:"storyboard_id"`
+	Name         string   `json:"name" binding:"max=200"`
+	StartTime    int      `json:"start_time"`
+	Duration     int      `json:"duration"`
+	TrimStart    *int     `json:"trim_start"`
+	TrimEnd      *int     `json:"trim_end"`
+	Speed        *float64 `json:"speed"`
+	Volume       *int     `json:"volume"`
+	IsMuted      bool     `json:"is_muted"`
+	FadeIn       *int     `json:"fade_in"`
+	FadeOut      *int     `json:"fade_out"`
+}
+
+// UpdateClipRequest 更新片段属性，只修改 duration 时按新时长重新计算出点
+type UpdateClipRequest struct {
+	Name      *string  `json:"name" binding:"omitempty,max=200"`
+	StartTime *int     `json:"start_time"`
+	Duration  *int     `json:"duration"`
+	TrimStart *int     `json:"trim_start"`
+	TrimEnd   *int     `json:"trim_end"`
+	Speed     *float64 `json:"speed"`
+	Volume    *int     `json:"volume"`
+	IsMuted   *bool    `json:"is_muted"`
+	FadeIn    *int     `json:"fade_in"`
+	FadeOut   *int     `json:"fade_out"`
+}
+
+// MoveClipRequest 移动片段，不指定 track_id 时在当前轨道内移动
+type MoveClipRequest struct {
+	TrackID   *uint `json:"track_id"`
+	StartTime *int  `json:"start_time" binding:"required"`
+}
+
+// SplitClipRequest 在时间线位置 at（毫秒）处将片段一分为二
+type SplitClipRequest struct {
+	At int `json:"at" binding:"required"`
+}
+
+type TransitionRequest struct {
+	Type     models.TransitionType  `json:"type" binding:"required"`
+	Duration int                    `json:"duration"`
+	Easing   *string                `json:"easing"`
+	Config   map[string]interface{} `json:"config"`
+}
+
+type CreateEffectRequest struct {
+	Type      models.EffectType      `json:"type" binding:"required"`
+	Name      string                 `json:"name" binding:"max=100"`
+	IsEnabled *bool                  `json:"is_enabled"`
+	Order     *int                   `json:"order"`
+	Config    map[string]interface{} `json:"config"`
+}
+
+type UpdateEffectRequest struct {
+	Name      *string                `json:"name" binding:"omitempty,max=100"`
+	IsEnabled *bool                  `json:"is_enabled"`
+	Order     *int                   `json:"order"`
+	Config    map[string]interface{} `json:"config"`
+}
+
+// CreateTimeline 创建时间线
+func (s *TimelineService) CreateTimeline(req *CreateTimelineRequest) (*models.Timeline, error) {
+	var drama models.Drama
+	if err := s.db.First(&drama, req.DramaID).Error; err != nil {
+		return nil, fmt.Errorf("drama not found")
+	}
+	if req.EpisodeID != nil {
+		var episode models.Episode
+		if err := s.db.Where("id = ? AND drama_id = ?", *req.EpisodeID, req.DramaID).First(&episode).Error; err != nil {
+			return nil, fmt.Errorf("episode not found")
+		}
+	}
+
+	timeline := &models.Timeline{
+		DramaID:     req.DramaID,
+		EpisodeID:   req.EpisodeID,
+		Name:        req.Name,
+		Description: req.Description,
+		FPS:         30,
+		Resolution:  req.Resolution,
+		Status:      models.TimelineStatusDraft,
+	}
+	if req.FPS != 0 {
+		if err := validateTimelineFPS(req.FPS); err != nil {
+			return nil, err
+		}
+		timeline.FPS = req.FPS
+	}
+
+	if err := s.db.Omit(clause.Associations).Create(timeline).Error; err != nil {
+		return nil, fmt.Errorf("failed to create timeline: %w", err)
+	}
+
+	s.log.Infow("Timeline created", "id", timeline.ID, "drama_id", timeline.DramaID)
+	return timeline, nil
+}
+
+// ListTimelines 获取时间线列表（不含轨道）
+func (s *TimelineService) ListTimelines(dramaID, episodeID *uint, page, pageSize int) ([]models.Timeline, int64, error) {
+	query := s.db.Model(&models.Timeline{})
+	if dramaID != nil {
+		query = query.Where("drama_id = ?", *dramaID)
+	}
+	if episodeID != nil {
+		query = query.Where("episode_id = ?", *episodeID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var timelines []models.Timeline
+	if err := query.Order("updated_at DESC").
+		Offset((page - 1) * pageSize).Limit(pageSize).
+		Find(&timelines).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return timelines, total, nil
+}
+
+// GetTimeline 获取完整时间线：轨道、片段、转场和特效
+func (s *TimelineService) GetTimeline(id uint) (*models.Timeline, error) {
+	var timeline models.Timeline
+	err := s.db.
+		Preload("Tracks", func(db *gorm.DB) *gorm.DB {
+			return db.Order("timeline_tracks.`order` ASC, timeline_tracks.id ASC")
+		}).
+		Preload("Tracks.Clips", func(db *gorm.DB) *gorm.DB {
+			return db.Order("timeline_clips.start_time ASC")
+		}).
+		Preload("Tracks.Clips.Asset").
+		Preload("Tracks.Clips.InTransition").
+		Preload("Tracks.Clips.OutTransition").
+		Preload("Tracks.Clips.Effects", func(db *gorm.DB) *gorm.DB {
+			return db.Order("clip_effects.`order` ASC, clip_effects.id ASC")
+		}).
+		First(&timeline, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &timeline, nil
+}
+
+// UpdateTimeline 更新时间线属性
+func (s *TimelineService) UpdateTimeline(id uint, req *UpdateTimelineRequest) (*models.Timeline, error) {
+	var timeline models.Timeline
+	if err := s.db.First(&timeline, id).Error; err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{}
+	if req.Name != nil {
+		updates["name"] = *req.Name
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.FPS != nil {
+		if err := validateTimelineFPS(*req.FPS); err != nil {
+			return nil, err
+		}
+		updates["fps"] = *req.FPS
+	}
+	if req.Resolution != nil {
+		updates["resolution"] = *req.Resolution
+	}
+	if req.Status != nil {
+		switch *req.Status {
+		case models.TimelineStatusDraft, models.TimelineStatusEditing, models.TimelineStatusCompleted:
+			updates["status"] = *req.Status
+		default:
+			return nil, fmt.Errorf("invalid timeline status: %s", *req.Status)
+		}
+	}
+
+	if len(updates) > 0 {
+		if err := s.db.Model(&timeline).Updates(updates).Error; err != nil {
+			return nil, fmt.Errorf("failed to update timeline: %w", err)
+		}
+	}
+
+	return s.GetTimeline(id)
+}
+
+// DeleteTimeline 删除时间线及其轨道、片段、转场和特效
+func (s *TimelineService) DeleteTimeline(id uint) error {
+	var timeline models.Timeline
+	if err := s.db.First(&timeline, id).Error; err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var trackIDs []uint
+		if err := tx.Model(&models.TimelineTrack{}).Where("timeline_id = ?", id).Pluck("id", &trackIDs).Error; err != nil {
+			return err
+		}
+		if len(trackIDs) > 0 {
+			if err := deleteTrackClips(tx, trackIDs); err != nil {
+				return err
+			}
+			if err := tx.Where("id IN ?", trackIDs).Delete(&models.TimelineTrack{}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&timeline).Error
+	})
+}
+
+// AddTrack 添加轨道，未指定顺序时追加到最后
+func (s *TimelineService) AddTrack(timelineID uint, req *CreateTrackRequest) (*models.TimelineTrack, error) {
+	var timeline models.Timeline
+	if err := s.db.First(&timeline, timelineID).Error; err != nil {
+		return nil, err
+	}
+
+	switch req.Type {
+	case models.TrackTypeVideo, models.TrackTypeAudio, models.TrackTypeText:
+	default:
+		return nil, fmt.Errorf("invalid track type: %s", req.Type)
+	}
+	if err := validateVolume(req.Volume); err != nil {
+		return nil, err
+	}
+	if err := validateTrackAudioRole(req.Type, req.AudioRole); err != nil {
+		return nil, err
+	}
+
+	track := &models.TimelineTrack{
+		TimelineID: timelineID,
+		Name:       req.Name,
+		Type:       req.Type,
+		IsLocked:   req.IsLocked,
+		IsMuted:    req.IsMuted,
+		Volume:     req.Volume,
+		AudioRole:  req.AudioRole,
+	}
+	if req.Order != nil {
+		track.Order = *req.Order
+	} else {
+		var maxOrder *int
+		s.db.Model(&models.TimelineTrack{}).Where("timeline_id = ?", timelineID).Select("MAX(`order`)").Scan(&maxOrder)
+		if maxOrder != nil {
+			track.Order = *maxOrder + 1
+		}
+	}
+
+	if err := s.db.Omit(clause.Associations).Create(track).Error; err != nil {
+		return nil, fmt.Errorf("failed to create track: %w", err)
+	}
+	return track, nil
+}
+
+// UpdateTrack 更新轨道属性
+func (s *TimelineService) UpdateTrack(trackID uint, req *UpdateTrackRequest) (*models.TimelineTrack, error) {
+	var track models.TimelineTrack
+	if err := s.db.First(&track, trackID).Error; err != nil {
+		return nil, err
+	}
+	if err := validateVolume(req.Volume); err != nil {
+		return nil, err
+	}
+	if req.AudioRole != nil {
+		if err := validateTrackAudioRole(track.Type, *req.AudioRole); err != nil {
+			return nil, err
+		}
+	}
+
+	updates := map[string]interface{}{}
+	if req.Name != nil {
+		updates["name"] = *req.Name
+	}
+	if req.AudioRole != nil {
+		updates["audio_role"] = *req.AudioRole
+	}
+	if req.Order != nil {
+		updates["order"] = *req.Order
+	}
+	if req.IsLocked != nil {
+		updates["is_locked"] = *req.IsLocked
+	}
+	if req.IsMuted != nil {
+		updates["is_muted"] = *req.IsMuted
+	}
+	if req.Volume != nil {
+		updates["volume"] = *req.Volume
+	}
+
+	if len(updates) > 0 {
+		if err := s.db.Model(&track).Updates(updates).Error; err != nil {
+			return nil, fmt.Errorf("failed to update track: %w", err)
+		}
+	}
+
+	if err := s.db.First(&track, trackID).Error; err != nil {
+		return nil, err
+	}
+	return &track, nil
+}
+
+// DeleteTrack 删除轨道及其片段
+func (s *TimelineService) DeleteTrack(trackID uint) error {
+	var track models.TimelineTrack
+	if err := s.db.First(&track, trackID).Error; err != nil {
+		return err
+	}
+	if track.IsLocked {
+		return fmt.Errorf("track is locked")
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := deleteTrackClips(tx, []uint{trackID}); err != nil {
+			return err
+		}
+		if err := tx.Delete(&track).Error; err != nil {
+			return err
+		}
+		return updateTimelineDuration(tx, track.TimelineID)
+	})
+}
+
+// AddClip 在轨道上添加片段，同一轨道上的片段不能重叠
+func (s *TimelineService) AddClip(trackID uint, req *CreateClipRequest) (*models.TimelineClip, error) {
+	track, err := s.loadEditableTrack(trackID)
+	if err != nil {
+		return nil, err
+	}
+
+	clip := &models.TimelineClip{
+		TrackID:      trackID,
+		AssetID:      req.AssetID,
+		StoryboardID: req.StoryboardID,
+		Name:         req.Name,
+		StartTime:    req.StartTime,
+		Duration:     req.Duration,
+		TrimStart:    req.TrimStart,
+		TrimEnd:      req.TrimEnd,
+		Speed:        req.Speed,
+		Volume:       req.Volume,
+		IsMuted:      req.IsMuted,
+		FadeIn:       req.FadeIn,
+		FadeOut:      req.FadeOut,
+	}
+
+	asset, err := s.loadClipAsset(track, clip.AssetID)
+	if err != nil {
+		return nil, err
+	}
+	if clip.StoryboardID != nil {
+		var storyboard models.Storyboard
+		if err := s.db.First(&storyboard, *clip.StoryboardID).Error; err != nil {
+			return nil, fmt.Errorf("storyboard not found")
+		}
+	}
+	if err := normalizeClipTiming(clip, asset); err != nil {
+		return nil, err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := checkClipOverlap(tx, trackID, clip.StartTime, clip.EndTime); err != nil {
+			return err
+		}
+		if err := tx.Omit(clause.Associations).Create(clip).Error; err != nil {
+			return fmt.Errorf("failed to create clip: %w", err)
+		}
+		return updateTimelineDuration(tx, track.TimelineID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.getClip(clip.ID)
+}
+
+// UpdateClip 更新片段的位置、裁剪、变速和音量等属性
+func (s *TimelineService) UpdateClip(clipID uint, req *UpdateClipRequest) (*models.TimelineClip, error) {
+	clip, track, err := s.loadEditableClip(clipID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		clip.Name = *req.Name
+	}
+	if req.StartTime != nil {
+		clip.StartTime = *req.StartTime
+	}
+	if req.Speed != nil {
+		clip.Speed = req.Speed
+	}
+	if req.TrimStart != nil {
+		clip.TrimStart = req.TrimStart
+	}
+	if req.TrimEnd != nil {
+		clip.TrimEnd = req.TrimEnd
+	}
+	if req.Duration != nil {
+		clip.Duration = *req.Duration
+		if req.TrimEnd == nil {
+			clip.TrimEnd = nil
+		}
+	}
+	if req.Volume != nil {
+		clip.Volume = req.Volume
+	}
+	if req.IsMuted != nil {
+		clip.IsMuted = *req.IsMuted
+	}
+	if req.FadeIn != nil {
+		clip.FadeIn = req.FadeIn
+	}
+	if req.FadeOut != nil {
+		clip.FadeOut = req.FadeOut
+	}
+
+	asset, err := s.loadClipAsset(track, clip.AssetID)
+	if err != nil {
+		return nil, err
+	}
+	if err := normalizeClipTiming(clip, asset); err != nil {
+		return nil, err
+	}
+	if err := s.validateClipTransitions(clip); err != nil {
+		return nil, err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := checkClipOverlap(tx, clip.TrackID, clip.StartTime, clip.EndTime, clip.ID); err != nil {
+			return err
+		}
+		if err := tx.Omit(clause.Associations).Save(clip).Error; err != nil {
+			return fmt.Errorf("failed to update clip: %w", err)
+		}
+		return updateTimelineDuration(tx, track.TimelineID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.getClip(clipID)
+}
+
+// MoveClip 移动片段到同一时间线的任意同类型轨道和位置
+func (s *TimelineService) MoveClip(clipID uint, req *MoveClipRequest) (*models.TimelineClip, error) {
+	clip, track, err := s.loadEditableClip(clipID)
+	if err != nil {
+		return nil, err
+	}
+
+	target := track
+	if req.TrackID != nil && *req.TrackID != clip.TrackID {
+		target, err = s.loadEditableTrack(*req.TrackID)
+		if err != nil {
+			return nil, err
+		}
+		if target.TimelineID != track.TimelineID {
+			return nil, fmt.Errorf("target track belongs to another timeline")
+		}
+		if _, err := s.loadClipAsset(target, clip.AssetID); err != nil {
+			return nil, err
+		}
+	}
+
+	if *req.StartTime < 0 {
+		return nil, fmt.Errorf("start_time must not be negative")
+	}
+	clip.TrackID = target.ID
+	clip.StartTime = *req.StartTime
+	clip.EndTime = clip.StartTime + clip.Duration
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := checkClipOverlap(tx, clip.TrackID, clip.StartTime, clip.EndTime, clip.ID); err != nil {
+			return err
+		}
+		if err := tx.Model(&models.TimelineClip{}).Where("id = ?", clip.ID).Updates(map[string]interface{}{
+			"track_id":   clip.TrackID,
+			"start_time": clip.StartTime,
+			"end_time":   clip.EndTime,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to move clip: %w", err)
+		}
+		return updateTimelineDuration(tx, track.TimelineID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.getClip(clipID)
+}
+
+// SplitClip 在时间线位置 at 处切分片段，返回切分后的前后两个片段
+// 前段保留入场转场和淡入，后段继承出场转场和淡出，特效复制到两段
+func (s *TimelineService) SplitClip(clipID uint, at int) ([]*models.TimelineClip, error) {
+	clip, track, err := s.loadEditableClip(clipID)
+	if err != nil {
+		return nil, err
+	}
+	if at <= clip.StartTime || at >= clip.EndTime {
+		return nil, fmt.Errorf("split point must be inside the clip (%d-%dms)", clip.StartTime, clip.EndTime)
+	}
+
+	speed := 1.0
+	if clip.Speed != nil {
+		speed = *clip.Speed
+	}
+
+	second := *clip
+	second.ID = 0
+	second.CreatedAt = time.Time{}
+	second.UpdatedAt = time.Time{}
+	second.StartTime = at
+	second.Duration = clip.EndTime - at
+	second.FadeIn = nil
+	second.TransitionIn = nil
+	second.Effects = nil
+
+	clip.Duration = at - clip.StartTime
+	clip.EndTime = at
+	clip.FadeOut = nil
+	clip.TransitionOut = nil
+
+	// 有裁剪信息时按变速换算素材上的切分点
+	if clip.TrimStart != nil && clip.TrimEnd != nil {
+		sourceAt := *clip.TrimStart + int(math.Round(float64(clip.Duration)*speed))
+		trimEnd := *clip.TrimEnd
+		clip.TrimEnd = &sourceAt
+		second.TrimStart = &sourceAt
+		second.TrimEnd = &trimEnd
+	}
+	if clip.FadeIn != nil && *clip.FadeIn > clip.Duration {
+		fadeIn := clip.Duration
+		clip.FadeIn = &fadeIn
+	}
+	if second.FadeOut != nil && *second.FadeOut > second.Duration {
+		fadeOut := second.Duration
+		second.FadeOut = &fadeOut
+	}
+
+	var effects []models.ClipEffect
+	if err := s.db.Where("clip_id = ?", clip.ID).Find(&effects).Error; err != nil {
+		return nil, err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Save(clip).Error; err != nil {
+			return fmt.Errorf("failed to update clip: %w", err)
+		}
+		if err := tx.Omit(clause.Associations).Create(&second).Error; err != nil {
+			return fmt.Errorf("failed to create clip: %w", err)
+		}
+		for _, effect := range effects {
+			copied := models.ClipEffect{
+				ClipID:    second.ID,
+				Type:      effect.Type,
+				Name:      effect.Name,
+				IsEnabled: effect.IsEnabled,
+				Order:     effect.Order,
+				Config:    effect.Config,
+			}
+			if err := tx.Omit(clause.Associations).Create(&copied).Error; err != nil {
+				return fmt.Errorf("failed to copy effect: %w", err)
+			}
+		}
+		return updateTimelineDuration(tx, track.TimelineID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	first, err := s.getClip(clip.ID)
+	if err != nil {
+		return nil, err
+	}
+	last, err := s.getClip(second.ID)
+	if err != nil {
+		return nil, err
+	}
+	return []*models.TimelineClip{first, last}, nil
+}
+
+// DeleteClip 删除片段；ripple 为 true 时同一轨道上后续片段左移填补空隙
+func (s *TimelineService) DeleteClip(clipID uint, ripple bool) error {
+	clip, track, err := s.loadEditableClip(clipID)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := deleteClips(tx, []models.TimelineClip{*clip}); err != nil {
+			return err
+		}
+		if ripple {
+			if err := tx.Model(&models.TimelineClip{}).
+				Where("track_id = ? AND start_time >= ?", clip.TrackID, clip.EndTime).
+				Updates(map[string]interface{}{
+					"start_time": gorm.Expr("start_time - ?", clip.Duration),
+					"end_time":   gorm.Expr("end_time - ?", clip.Duration),
+				}).Error; err != nil {
+				return fmt.Errorf("failed to ripple clips: %w", err)
+			}
+		}
+		return updateTimelineDuration(tx, track.TimelineID)
+	})
+}
+
+// SetTransition 设置片段的入场（in）或出场（out）转场
+func (s *TimelineService) SetTransition(clipID uint, position string, req *TransitionRequest) (*models.TimelineClip, error) {
+	clip, _, err := s.loadEditableClip(clipID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isValidTransitionType(req.Type) {
+		return nil, fmt.Errorf("invalid transition type: %s", req.Type)
+	}
+	duration := req.Duration
+	if duration == 0 {
+		duration = 500
+	}
+	if duration < 0 || duration > clip.Duration {
+		return nil, fmt.Errorf("transition duration must be between 1 and %dms", clip.Duration)
+	}
+
+	var existingID *uint
+	switch position {
+	case "in":
+		existingID = clip.TransitionIn
+	case "out":
+		existingID = clip.TransitionOut
+	default:
+		return nil, fmt.Errorf("invalid transition position: %s", position)
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		transition := &models.ClipTransition{}
+		if existingID != nil {
+			if err := tx.First(transition, *existingID).Error; err != nil {
+				return err
+			}
+		}
+		transition.Type = req.Type
+		transition.Duration = duration
+		transition.Easing = req.Easing
+		transition.Config = req.Config
+
+		if err := tx.Save(transition).Error; err != nil {
+			return fmt.Errorf("failed to save transition: %w", err)
+		}
+		if existingID != nil {
+			return nil
+		}
+		return tx.Model(&models.TimelineClip{}).Where("id = ?", clip.ID).Update("transition_"+position, transition.ID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.getClip(clipID)
+}
+
+// RemoveTransition 移除片段的入场或出场转场
+func (s *TimelineService) RemoveTransition(clipID uint, position string) (*models.TimelineClip, error) {
+	clip, _, err := s.loadEditableClip(clipID)
+	if err != nil {
+		return nil, err
+	}
+
+	var transitionID *uint
+	switch position {
+	case "in":
+		transitionID = clip.TransitionIn
+	case "out":
+		transitionID = clip.TransitionOut
+	default:
+		return nil, fmt.Errorf("invalid transition position: %s", position)
+	}
+
+	if transitionID != nil {
+		err = s.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&models.TimelineClip{}).Where("id = ?", clip.ID).Update("transition_"+position, nil).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&models.ClipTransition{}, *transitionID).Error
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s.getClip(clipID)
+}
+
+// AddEffect 为片段添加特效，未指定顺序时追加到最后
+func (s *TimelineService) AddEffect(clipID uint, req *CreateEffectRequest) (*models.ClipEffect, error) {
+	clip, _, err := s.loadEditableClip(clipID)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateEffectType(req.Type); err != nil {
+		return nil, err
+	}
+	if err := validateEffectConfig(req.Type, req.Config); err != nil {
+		return nil, err
+	}
+
+	effect := &models.ClipEffect{
+		ClipID:    clip.ID,
+		Type:      req.Type,
+		Name:      req.Name,
+		IsEnabled: true,
+		Config:    req.Config,
+	}
+	if req.IsEnabled != nil {
+		effect.IsEnabled = *req.IsEnabled
+	}
+	if req.Order != nil {
+		effect.Order = *req.Order
+	} else {
+		var count int64
+		s.db.Model(&models.ClipEffect{}).Where("clip_id = ?", clip.ID).Count(&count)
+		effect.Order = int(count)
+	}
+
+	// is_enabled 的数据库默认值为 true，显式写入以支持创建禁用的特效
+	if err := s.db.Omit(clause.Associations).Create(effect).Error; err != nil {
+		return nil, fmt.Errorf("failed to create effect: %w", err)
+	}
+	if !effect.IsEnabled {
+		s.db.Model(effect).Update("is_enabled", false)
+	}
+	return effect, nil
+}
+
+// UpdateEffect 更新特效
+func (s *TimelineService) UpdateEffect(effectID uint, req *UpdateEffectRequest) (*models.ClipEffect, error) {
+	var effect models.ClipEffect
+	if err := s.db.First(&effect, effectID).Error; err != nil {
+		return nil, err
+	}
+	if _, _, err := s.loadEditableClip(effect.ClipID); err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		effect.Name = *req.Name
+	}
+	if req.IsEnabled != nil {
+		effect.IsEnabled = *req.IsEnabled
+	}
+	if req.Order != nil {
+		effect.Order = *req.Order
+	}
+	if req.Config != nil {
+		if err := validateEffectConfig(effect.Type, req.Config); err != nil {
+			return nil, err
+		}
+		// 编辑者修改配置后，配色匹配不再覆盖该特效
+		if effect.Type == models.EffectTypeColor {
+			delete(req.Config, colorEffectAutoKey)
+		}
+		effect.Config = req.Config
+	}
+
+	if err := s.db.Omit(clause.Associations).Save(&effect).Error; err != nil {
+		return nil, fmt.Errorf("failed to update effect: %w", err)
+	}
+	return &effect, nil
+}
+
+// DeleteEffect 删除特效
+func (s *TimelineService) DeleteEffect(effectID uint) error {
+	var effect models.ClipEffect
+	if err := s.db.First(&effect, effectID).Error; err != nil {
+		return err
+	}
+	if _, _, err := s.loadEditableClip(effect.ClipID); err != nil {
+		return err
+	}
+	return s.db.Delete(&effect).Error
+}
+
+func (s *TimelineService) getClip(clipID uint) (*models.TimelineClip, error) {
+	var clip models.TimelineClip
+	err := s.db.
+		Preload("Asset").
+		Preload("InTransition").
+		Preload("OutTransition").
+		Preload("Effects", func(db *gorm.DB) *gorm.DB {
+			return db.Order("clip_effects.`order` ASC, clip_effects.id ASC")
+		}).
+		First(&clip, clipID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &clip, nil
+}
+
+// loadEditableTrack 加载轨道，锁定的轨道不允许修改片段
+func (s *TimelineService) loadEditableTrack(trackID uint) (*models.TimelineTrack, error) {
+	var track models.TimelineTrack
+	if err := s.db.First(&track, trackID).Error; err != nil {
+		return nil, err
+	}
+	if track.IsLocked {
+		return nil, fmt.Errorf("track is locked")
+	}
+	return &track, nil
+}
+
+// loadEditableClip 加载片段及其所在轨道
+func (s *TimelineService) loadEditableClip(clipID uint) (*models.TimelineClip, *models.TimelineTrack, error) {
+	var clip models.TimelineClip
+	if err := s.db.First(&clip, clipID).Error; err != nil {
+		return nil, nil, err
+	}
+	track, err := s.loadEditableTrack(clip.TrackID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &clip, track, nil
+}
+
+// loadClipAsset 加载片段素材并检查素材类型与轨道类型是否匹配
+// 视频轨道接受视频和图片，音频轨道接受音频和视频（取其音轨），文字轨道不引用素材
+func (s *TimelineService) loadClipAsset(track *models.TimelineTrack, assetID *uint) (*models.Asset, error) {
+	if assetID == nil {
+		if track.Type != models.TrackTypeText {
+			return nil, fmt.Errorf("asset_id is required on a %s track", track.Type)
+		}
+		return nil, nil
+	}
+
+	var asset models.Asset
+	if err := s.db.First(&asset, *assetID).Error; err != nil {
+		return nil, fmt.Errorf("asset not found")
+	}
+
+	compatible := false
+	switch track.Type {
+	case models.TrackTypeVideo:
+		compatible = asset.Type == models.AssetTypeVideo || asset.Type == models.AssetTypeImage
+	case models.TrackTypeAudio:
+		compatible = asset.Type == models.AssetTypeAudio || asset.Type == models.AssetTypeVideo
+	}
+	if !compatible {
+		return nil, fmt.Errorf("%s asset cannot be placed on a %s track", asset.Type, track.Type)
+	}
+	return &asset, nil
+}
+
+// validateClipTransitions 片段变短后转场时长不能超过片段时长
+func (s *TimelineService) validateClipTransitions(clip *models.TimelineClip) error {
+	var ids []uint
+	if clip.TransitionIn != nil {
+		ids = append(ids, *clip.TransitionIn)
+	}
+	if clip.TransitionOut != nil {
+		ids = append(ids, *clip.TransitionOut)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var longest *int
+	s.db.Model(&models.ClipTransition{}).Where("id IN ?", ids).Select("MAX(duration)").Scan(&longest)
+	if longest != nil && *longest > clip.Duration {
+		return fmt.Errorf("clip duration %dms is shorter than its transition (%dms)", clip.Duration, *longest)
+	}
+	return nil
+}
+
+// normalizeClipTiming 校验并补全片段的裁剪范围、时长和结束时间
+// 有素材时长的片段裁剪范围不能超出素材，时间线上的时长 = (trim_end - trim_start) / speed
+func normalizeClipTiming(clip *models.TimelineClip, asset *models.Asset) error {
+	if clip.StartTime < 0 {
+		return fmt.Errorf("start_time must not be negative")
+	}
+
+	speed := 1.0
+	if clip.Speed != nil {
+		speed = *clip.Speed
+	}
+	if speed < minClipSpeed || speed > maxClipSpeed {
+		return fmt.Errorf("speed must be between %.1f and %.1f", minClipSpeed, maxClipSpeed)
+	}
+	clip.Speed = &speed
+
+	sourceDuration := assetDurationMs(asset)
+	if clip.TrimStart != nil || clip.TrimEnd != nil || sourceDuration > 0 {
+		trimStart := 0
+		if clip.TrimStart != nil {
+			trimStart = *clip.TrimStart
+		}
+
+		var trimEnd int
+		switch {
+		case clip.TrimEnd != nil:
+			trimEnd = *clip.TrimEnd
+		case clip.Duration > 0:
+			trimEnd = trimStart + int(math.Round(float64(clip.Duration)*speed))
+		case sourceDuration > 0:
+			trimEnd = sourceDuration
+		default:
+			return fmt.Errorf("duration or trim_end is required")
+		}
+
+		if trimStart < 0 || trimEnd <= trimStart {
+			return fmt.Errorf("trim_end must be greater than trim_start")
+		}
+		if limit := assetTrimLimitMs(asset); limit > 0 && trimEnd > limit {
+			return fmt.Errorf("trim range %d-%dms exceeds asset duration %dms", trimStart, trimEnd, limit)
+		}
+
+		clip.TrimStart = &trimStart
+		clip.TrimEnd = &trimEnd
+		clip.Duration = int(math.Round(float64(trimEnd-trimStart) / speed))
+	}
+
+	if clip.Duration <= 0 {
+		return fmt.Errorf("duration is required")
+	}
+	clip.EndTime = clip.StartTime + clip.Duration
+
+	if err := validateVolume(clip.Volume); err != nil {
+		return err
+	}
+	fadeIn, fadeOut := 0, 0
+	if clip.FadeIn != nil {
+		fadeIn = *clip.FadeIn
+	}
+	if clip.FadeOut != nil {
+		fadeOut = *clip.FadeOut
+	}
+	if fadeIn < 0 || fadeOut < 0 || fadeIn+fadeOut > clip.Duration {
+		return fmt.Errorf("fade_in + fade_out must not exceed clip duration %dms", clip.Duration)
+	}
+	return nil
+}
+
+// assetDurationMs 返回素材时长（毫秒），优先使用探测到的精确时长，图片或未知时长返回 0
+func assetDurationMs(asset *models.Asset) int {
+	if asset == nil || asset.Type == models.AssetTypeImage {
+		return 0
+	}
+	if asset.DurationMs != nil && *asset.DurationMs > 0 {
+		return *asset.DurationMs
+	}
+	if asset.Duration == nil {
+		return 0
+	}
+	return *asset.Duration * 1000
+}
+
+// assetTrimLimitMs 返回裁剪范围允许的最大出点（毫秒），0 表示不限制
+// 只有取整后的秒数时实际时长可能多出不到一秒，放宽一秒以免拒绝有效的裁剪
+func assetTrimLimitMs(asset *models.Asset) int {
+	if asset == nil || asset.Type == models.AssetTypeImage {
+		return 0
+	}
+	if asset.DurationMs != nil && *asset.DurationMs > 0 {
+		return *asset.DurationMs
+	}
+	if asset.Duration == nil || *asset.Duration <= 0 {
+		return 0
+	}
+	return *asset.Duration*1000 + 999
+}
+
+// checkClipOverlap 检查轨道上 [start, end) 范围内是否已有其他片段
+func checkClipOverlap(tx *gorm.DB, trackID uint, start, end int, excludeIDs ...uint) error {
+	query := tx.Model(&models.TimelineClip{}).
+		Where("track_id = ? AND start_time < ? AND end_time > ?", trackID, end, start)
+	if len(excludeIDs) > 0 {
+		query = query.Where("id NOT IN ?", excludeIDs)
+	}
+
+	var conflict models.TimelineClip
+	err := query.Order("start_time ASC").First(&conflict).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("clip overlaps clip %d (%d-%dms) on the same track", conflict.ID, conflict.StartTime, conflict.EndTime)
+}
+
+// updateTimelineDuration 时间线时长取所有片段的最大结束时间
+func updateTimelineDuration(tx *gorm.DB, timelineID uint) error {
+	var duration *int
+	if err := tx.Model(&models.TimelineClip{}).
+		Joins("JOIN timeline_tracks ON timeline_tracks.id = timeline_clips.track_id AND timeline_tracks.deleted_at IS NULL").
+		Where("timeline_tracks.timeline_id = ?", timelineID).
+		Select("MAX(timeline_clips.end_time)").
+		Scan(&duration).Error; err != nil {
+		return err
+	}
+
+	total := 0
+	if duration != nil {
+		total = *duration
+	}
+	return tx.Model(&models.Timeline{}).Where("id = ?", timelineID).Update("duration", total).Error
+}
+
+// deleteTrackClips 删除轨道上的全部片段
+func deleteTrackClips(tx *gorm.DB, trackIDs []uint) error {
+	var clips []models.TimelineClip
+	if err := tx.Where("track_id IN ?", trackIDs).Find(&clips).Error; err != nil {
+		return err
+	}
+	return deleteClips(tx, clips)
+}
+
+// deleteClips 删除片段及其转场和特效
+func deleteClips(tx *gorm.DB, clips []models.TimelineClip) error {
+	if len(clips) == 0 {
+		return nil
+	}
+
+	var clipIDs, transitionIDs []uint
+	for _, clip := range clips {
+		clipIDs = append(clipIDs, clip.ID)
+		if clip.TransitionIn != nil {
+			transitionIDs = append(transitionIDs, *clip.TransitionIn)
+		}
+		if clip.TransitionOut != nil {
+			transitionIDs = append(transitionIDs, *clip.TransitionOut)
+		}
+	}
+
+	if err := tx.Where("clip_id IN ?", clipIDs).Delete(&models.ClipEffect{}).Error; err != nil {
+		return err
+	}
+	if len(transitionIDs) > 0 {
+		if err := tx.Where("id IN ?", transitionIDs).Delete(&models.ClipTransition{}).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Where("id IN ?", clipIDs).Delete(&models.TimelineClip{}).Error
+}
+
+func validateTimelineFPS(fps int) error {
+	if fps < 1 || fps > 120 {
+		return fmt.Errorf("fps must be between 1 and 120")
+	}
+	return nil
+}
+
+func validateVolume(volume *int) error {
+	if volume != nil && (*volume < 0 || *volume > 200) {
+		return fmt.Errorf("volume must be between 0 and 200")
+	}
+	return nil
+}
+
+// validateTrackAudioRole 音频用途只能设置在音频轨道上
+func validateTrackAudioRole(trackType models.TrackType, role models.TrackAudioRole) error {
+	switch role {
+	case "":
+		return nil
+	case models.TrackAudioRoleDialogue, models.TrackAudioRoleMusic, models.TrackAudioRoleSFX:
+	default:
+		return fmt.Errorf("invalid audio role: %s", role)
+	}
+	if trackType != models.TrackTypeAudio {
+		return fmt.Errorf("audio role is only allowed on audio tracks")
+	}
+	return nil
+}
+
+func validateEffectType(effectType models.EffectType) error {
+	switch effectType {
+	case models.EffectTypeFilter, models.EffectTypeColor, models.EffectTypeBlur,
+		models.EffectTypeBrightness, models.EffectTypeContrast, models.EffectTypeSaturation:
+		return nil
+	default:
+		return fmt.Errorf("invalid effect type: %s", effectType)
+	}
+}
+
+// validateEffectConfig 校验特效配置，color 特效的 lut 只能是存储目录 luts 下的文件，校验后改写为规范化的相对路径
+// 渲染时由服务端拼接存储目录，不接受绝对路径或目录之外的文件
+func validateEffectConfig(effectType models.EffectType, config map[string]interface{}) error {
+	value, ok := config["lut"]
+	if !ok || effectType != models.EffectTypeColor {
+		return nil
+	}
+	lut, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("lut must be a string")
+	}
+	if lut == "" {
+		delete(config, "lut")
+		return nil
+	}
+	cleaned, err := cleanLUTPath(lut)
+	if err != nil {
+		return err
+	}
+	config["lut"] = cleaned
+	return nil
+}
@@ -0,0 +1,208 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/drama-generator/backend/domain/models"
+)
+
+// newTestTimeline 创建时间线服务和一条视频轨道，以及一个 10 秒的视频素材
+func newTestTimeline(t *testing.T) (*TimelineService, *models.TimelineTrack, *models.Asset) {
+	t.Helper()
+	db := newTestDB(t, &models.Timeline{}, &models.TimelineTrack{}, &models.TimelineClip{},
+		&models.ClipTransition{}, &models.ClipEffect{}, &models.Asset{})
+
+	timeline := &models.Timeline{DramaID: 1, Name: "test", FPS: 30}
+	if err := db.Create(timeline).Error; err != nil {
+		t.Fatalf("create timeline: %v", err)
+	}
+	track := &models.TimelineTrack{TimelineID: timeline.ID, Name: "video", Type: models.TrackTypeVideo}
+	if err := db.Create(track).Error; err != nil {
+		t.Fatalf("create track: %v", err)
+	}
+	durationMs := 10000
+	asset := &models.Asset{Name: "shot", Type: models.AssetTypeVideo, URL: "/static/shot.mp4", DurationMs: &durationMs}
+	if err := db.Create(asset).Error; err != nil {
+		t.Fatalf("create asset: %v", err)
+	}
+	return NewTimelineService(db, newTestLogger()), track, asset
+}
+
+func addTestClip(t *testing.T, s *TimelineService, track *models.TimelineTrack, asset *models.Asset, start, duration int) *models.TimelineClip {
+	t.Helper()
+	clip, err := s.AddClip(track.ID, &CreateClipRequest{AssetID: &asset.ID, StartTime: start, Duration: duration})
+	if err != nil {
+		t.Fatalf("AddClip(%d, %d): %v", start, duration, err)
+	}
+	return clip
+}
+
+func TestAddClipRejectsOverlap(t *testing.T) {
+	tests := []struct {
+		name     string
+		start    int
+		duration int
+		wantErr  bool
+	}{
+		{name: "overlaps start", start: 500, duration: 1000, wantErr: true},
+		{name: "inside", start: 1200, duration: 500, wantErr: true},
+		{name: "overlaps end", start: 2500, duration: 1000, wantErr: true},
+		{name: "covers", start: 0, duration: 5000, wantErr: true},
+		{name: "touches start", start: 0, duration: 1000},
+		{name: "touches end", start: 3000, duration: 1000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, track, asset := newTestTimeline(t)
+			addTestClip(t, s, track, asset, 1000, 2000)
+
+			_, err := s.AddClip(track.ID, &CreateClipRequest{AssetID: &asset.ID, StartTime: tt.start, Duration: tt.duration})
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "overlaps") {
+					t.Fatalf("AddClip() error = %v, want an overlap error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("AddClip() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestNormalizeClipTimingReportsTrimLimit(t *testing.T) {
+	duration := 4
+	asset := &models.Asset{Type: models.AssetTypeVideo, Duration: &duration}
+	trimEnd := 6000
+	err := normalizeClipTiming(&models.TimelineClip{TrimEnd: &trimEnd}, asset)
+	if err == nil || !strings.Contains(err.Error(), "asset duration 4999ms") {
+		t.Fatalf("normalizeClipTiming() error = %v, want the trim limit in the message", err)
+	}
+}
+
+func TestSplitClip(t *testing.T) {
+	tests := []struct {
+		name    string
+		at      int
+		wantErr bool
+	}{
+		{name: "at start boundary", at: 1000, wantErr: true},
+		{name: "at end boundary", at: 5000, wantErr: true},
+		{name: "outside", at: 6000, wantErr: true},
+		{name: "just after start", at: 1001},
+		{name: "middle", at: 2500},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, track, asset := newTestTimeline(t)
+			speed := 2.0
+			clip, err := s.AddClip(track.ID, &CreateClipRequest{AssetID: &asset.ID, StartTime: 1000, Duration: 4000, Speed: &speed})
+			if err != nil {
+				t.Fatalf("AddClip: %v", err)
+			}
+
+			parts, err := s.SplitClip(clip.ID, tt.at)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("SplitClip(%d) should fail", tt.at)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SplitClip(%d): %v", tt.at, err)
+			}
+
+			first, second := parts[0], parts[1]
+			if first.StartTime != 1000 || first.EndTime != tt.at || second.StartTime != tt.at || second.EndTime != 5000 {
+				t.Errorf("split = %d-%d and %d-%d, want 1000-%d and %d-5000",
+					first.StartTime, first.EndTime, second.StartTime, second.EndTime, tt.at, tt.at)
+			}
+			// 2 倍速时素材上的切分点 = 片段内偏移 * 2，两段裁剪范围首尾相接
+			sourceAt := (tt.at - 1000) * 2
+			if *first.TrimStart != 0 || *first.TrimEnd != sourceAt || *second.TrimStart != sourceAt || *second.TrimEnd != 8000 {
+				t.Errorf("trim = %d-%d and %d-%d, want 0-%d and %d-8000",
+					*first.TrimStart, *first.TrimEnd, *second.TrimStart, *second.TrimEnd, sourceAt, sourceAt)
+			}
+		})
+	}
+}
+
+func TestDeleteClipRipple(t *testing.T) {
+	tests := []struct {
+		name      string
+		ripple    bool
+		wantStart []int
+	}{
+		{name: "ripple", ripple: true, wantStart: []int{0, 3000, 4000}},
+		{name: "no ripple", ripple: false, wantStart: []int{0, 5000, 6000}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, track, asset := newTestTimeline(t)
+			addTestClip(t, s, track, asset, 0, 1000)
+			removed := addTestClip(t, s, track, asset, 1000, 2000)
+			addTestClip(t, s, track, asset, 5000, 1000)
+			addTestClip(t, s, track, asset, 6000, 1000)
+
+			if err := s.DeleteClip(removed.ID, tt.ripple); err != nil {
+				t.Fatalf("DeleteClip: %v", err)
+			}
+
+			var clips []models.TimelineClip
+			s.db.Where("track_id = ?", track.ID).Order("start_time ASC").Find(&clips)
+			if len(clips) != len(tt.wantStart) {
+				t.Fatalf("%d clips left, want %d", len(clips), len(tt.wantStart))
+			}
+			for i, clip := range clips {
+				if clip.StartTime != tt.wantStart[i] || clip.EndTime != clip.StartTime+clip.Duration {
+					t.Errorf("clip %d = %d-%d, want start %d", i, clip.StartTime, clip.EndTime, tt.wantStart[i])
+				}
+			}
+
+			var timeline models.Timeline
+			s.db.First(&timeline, track.TimelineID)
+			if want := tt.wantStart[len(tt.wantStart)-1] + 1000; timeline.Duration != want {
+				t.Errorf("timeline duration = %d, want %d", timeline.Duration, want)
+			}
+		})
+	}
+}
+
+func TestValidateEffectConfigLUT(t *testing.T) {
+	tests := []struct {
+		name    string
+		lut     interface{}
+		want    string
+		wantErr bool
+	}{
+		{name: "under luts", lut: "luts/./drama_1.cube", want: "luts/drama_1.cube"},
+		{name: "absolute", lut: "/etc/passwd", wantErr: true},
+		{name: "escapes luts", lut: "luts/../configs/config.yaml", wantErr: true},
+		{name: "outside luts", lut: "videos/lut.cube", wantErr: true},
+		{name: "wrong extension", lut: "luts/drama_1.png", wantErr: true},
+		{name: "not a string", lut: 42, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := map[string]interface{}{"lut": tt.lut}
+			err := validateEffectConfig(models.EffectTypeColor, config)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("validateEffectConfig(%v) should fail", tt.lut)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validateEffectConfig(%v): %v", tt.lut, err)
+			}
+			if config["lut"] != tt.want {
+				t.Errorf("lut = %v, want %s", config["lut"], tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	models "github.com/drama-generator/backend/domain/models"
+	"github.com/drama-generator/backend/pkg/config"
+	"github.com/drama-generator/backend/pkg/video"
+)
+
+// ErrInvalidCallbackToken 回调地址签名校验失败
+var ErrInvalidCallbackToken = errors.New("invalid callback token")
+
+// VideoCallbackSettings 厂商任务回调设置
+type VideoCallbackSettings struct {
+	PublicURL            string        // 为空时不注册回调
+	Secret               string        // 回调地址签名密钥
+	FallbackPollInterval time.Duration // 已注册回调时的兜底轮询间隔
+}
+
+// 回调签名密钥的最短长度，以及示例配置中的占位密钥
+const (
+	minCallbackSecretLength   = 16
+	placeholderCallbackSecret = "change-me"
+)
+
+// NewVideoCallbackSettings 根据配置文件生成厂商任务回调设置，兜底轮询间隔默认 2 分钟
+// 配置了 public_url 时必须配置固定的签名密钥，保证重启前注册的回调地址仍然有效
+func NewVideoCallbackSettings(cfg config.CallbackConfig) (VideoCallbackSettings, error) {
+	settings := VideoCallbackSettings{
+		PublicURL:            strings.TrimRight(cfg.PublicURL, "/"),
+		Secret:               cfg.Secret,
+		FallbackPollInterval: 2 * time.Minute,
+	}
+	if settings.Secret == placeholderCallbackSecret {
+		return settings, fmt.Errorf("callback secret is the example placeholder, set a random secret")
+	}
+	if settings.PublicURL != "" && len(settings.Secret) < minCallbackSecretLength {
+		return settings, fmt.Errorf("callback secret of at least %d characters is required when public_url is set", minCallbackSecretLength)
+	}
+	if cfg.FallbackPollInterval > 0 {
+		settings.FallbackPollInterval = time.Duration(cfg.FallbackPollInterval) * time.Second
+	}
+	return settings, nil
+}
+
+// videoCallbackPayload 厂商回调的任务结果，由 video_callback 任务写回视频生成记录
+type videoCallbackPayload struct {
+	VideoGenID uint   `json:"video_gen_id"`
+	TaskID     string `json:"task_id"`
+	VideoURL   string `json:"video_url,omitempty"`
+	Duration   int    `json:"duration,omitempty"`
+	Width      int    `json:"width,omitempty"`
+	Height     int    `json:"height,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// callbackURL 返回提交任务时注册的回调地址，未启用或厂商不支持时返回空字符串
+func (c VideoCallbackSettings) callbackURL(provider string, videoGenID uint) string {
+	callbackProvider := video.CallbackProvider(provider)
+	if c.PublicURL == "" || c.Secret == "" || callbackProvider == "" {
+		return ""
+	}
+
+	query := url.Values{}
+	query.Set("video_gen_id", strconv.FormatUint(uint64(videoGenID), 10))
+	query.Set("token", c.sign(callbackProvider, videoGenID))
+	return fmt.Sprintf("%s/api/v1/videos/callbacks/%s?%s", c.PublicURL, callbackProvider, query.Encode())
+}
+
+// pollDelay 已注册回调的任务只做低频兜底轮询
+func (c VideoCallbackSettings) pollDelay(callback bool) time.Duration {
+	if callback {
+		return c.FallbackPollInterval
+	}
+	return videoPollInterval
+}
+
+// sign 回调地址签名：HMAC-SHA256(secret, provider:video_gen_id)
+func (c VideoCallbackSettings) sign(provider string, videoGenID uint) string {
+	mac := hmac.New(sha256.New, []byte(c.Secret))
+	mac.Write([]byte(fmt.Sprintf("%s:%d", provider, videoGenID)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// HandleProviderCallback 校验并处理厂商推送的任务回调
+// 返回非空 challenge 时调用方需原样返回给厂商；任务结果交由 video_callback 任务异步写回，保证尽快响应厂商
+func (s *VideoGenerationService) HandleProviderCallback(provider, videoGenIDParam, token string, body []byte) (string, error) {
+	id, err := strconv.ParseUint(videoGenIDParam, 10, 32)
+	if err != nil {
+		return "", fmt.Errorf("invalid video_gen_id")
+	}
+	videoGenID := uint(id)
+
+	callbacks := s.settings.Callback
+	if callbacks.Secret == "" || !hmac.Equal([]byte(token), []byte(callbacks.sign(provider, videoGenID))) {
+		return "", ErrInvalidCallbackToken
+	}
+
+	event, err := video.ParseCallback(provider, body)
+	if err != nil {
+		return "", err
+	}
+	if event.Challenge != "" {
+		return event.Challenge, nil
+	}
+
+	var videoGen models.VideoGeneration
+	if err := s.db.First(&videoGen, videoGenID).Error; err != nil {
+		return "", err
+	}
+	if video.CallbackProvider(videoGen.Provider) != provider {
+		return "", fmt.Errorf("provider mismatch")
+	}
+
+	result := event.Result
+	// 回调可能先于提交结果写库到达，此时交给兜底轮询
+	if videoGen.TaskID == nil || *videoGen.TaskID == "" {
+		s.log.Infow("Video callback arrived before task id was saved, leaving to polling", "id", videoGenID, "task_id", result.TaskID)
+		return "", nil
+	}
+	if *videoGen.TaskID != result.TaskID {
+		return "", fmt.Errorf("task id mismatch")
+	}
+	if videoGen.Status != models.VideoStatusProcessing {
+		s.log.Infow("Video generation already finished, ignoring callback", "id", videoGenID, "status", videoGen.Status)
+		return "", nil
+	}
+	if !result.Completed && result.Error == "" {
+		s.log.Infow("Video generation in progress (callback)", "id", videoGenID, "task_id", result.TaskID, "status", result.Status)
+		return "", nil
+	}
+
+	_, err = s.jobQueue.Enqueue("video_callback", fmt.Sprintf("%d", videoGenID), JobOptions{
+		Queue:    JobQueueDefault,
+		Priority: JobPriorityInteractive,
+		DramaID:  videoGen.DramaID,
+		Payload: videoCallbackPayload{
+			VideoGenID: videoGenID,
+			TaskID:     result.TaskID,
+			VideoURL:   result.VideoURL,
+			Duration:   result.Duration,
+			Width:      result.Width,
+			Height:     result.Height,
+			Error:      result.Error,
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	s.log.Infow("Video callback received", "id", videoGenID, "task_id", result.TaskID, "status", result.Status)
+	return "", nil
+}
+
+// handleVideoCallbackJob 将厂商回调的结果写回视频生成记录
+func (s *VideoGenerationService) handleVideoCallbackJob(ctx context.Context, task *models.AsyncTask) error {
+	var payload videoCallbackPayload
+	if err := decodeJobPayload(task, &payload); err != nil {
+		return err
+	}
+
+	var videoGen models.VideoGeneration
+	if err := s.db.First(&videoGen, payload.VideoGenID).Error; err != nil {
+		return err
+	}
+	if videoGen.Status != models.VideoStatusProcessing || videoGen.TaskID == nil || *videoGen.TaskID != payload.TaskID {
+		return nil
+	}
+
+	switch {
+	case payload.Error != "":
+		s.updateVideoGenError(payload.VideoGenID, payload.Error)
+	case payload.VideoURL != "":
+		s.completeVideoGeneration(payload.VideoGenID, payload.VideoURL, &payload.Duration, &payload.Width, &payload.Height, nil)
+	default:
+		// 回调不含下载地址（如 MiniMax 只返回 file_id），查询一次任务状态获取
+		if _, err := s.pollTaskStatus(payload.VideoGenID, payload.TaskID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
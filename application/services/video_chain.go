@@ -0,0 +1,264 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	models "github.com/drama-generator/backend/domain/models"
+	"github.com/drama-generator/backend/pkg/qc"
+)
+
+// videoChainWaitInterval 链式生成等待上一个分镜视频完成的轮询间隔
+const videoChainWaitInterval = 10 * time.Second
+
+// chainFrameOffsets 截取尾帧时距视频结尾的秒数，贴近结尾可能解码不出画面，依次往前重试
+var chainFrameOffsets = []float64{0.05, 0.3, 1}
+
+// chainFrameProvider 从视频中截取的尾帧图片的 provider，批量生成视频选取分镜图片时跳过这些图片
+const chainFrameProvider = "ffmpeg"
+
+// videoChainPayload 链式生成任务参数，PrevID 为截取尾帧任务要截取的上一个视频
+type videoChainPayload struct {
+	VideoGenID uint `json:"video_gen_id"`
+	PrevID     uint `json:"prev_id,omitempty"`
+}
+
+// generateChainedVideo 创建依赖上一个分镜视频的首尾帧模式视频生成记录，分镜自己的图片作为尾帧，
+// 首帧在上一个视频完成后由 video_chain_frame 任务截取填入
+func (s *VideoGenerationService) generateChainedVideo(imageGenID, prevID uint, provider, model string, opts JobOptions) (*models.VideoGeneration, error) {
+	req, err := s.videoRequestFromImage(imageGenID, provider, model)
+	if err != nil {
+		return nil, err
+	}
+	req.ReferenceMode = "first_last"
+	req.LastFrameURL = &req.ImageURL
+	req.LastFrameLocalPath = req.ImageLocalPath
+
+	videoGen, err := s.newVideoGeneration(req)
+	if err != nil {
+		return nil, err
+	}
+	videoGen.ChainPrevID = &prevID
+
+	if err := s.db.Create(videoGen).Error; err != nil {
+		return nil, fmt.Errorf("failed to create record: %w", err)
+	}
+
+	if err := s.enqueueVideoChain(videoGen, opts); err != nil {
+		s.updateVideoGenError(videoGen.ID, err.Error())
+		return nil, err
+	}
+
+	return videoGen, nil
+}
+
+// enqueueVideoChain 创建等待上一个分镜视频完成的任务，opts 只需指定优先级和父任务
+// 等待只是轮询数据库，放在 default 工作池，不占用 ffmpeg 工作池的槽位
+func (s *VideoGenerationService) enqueueVideoChain(videoGen *models.VideoGeneration, opts JobOptions) error {
+	opts.Queue = JobQueueDefault
+	opts.DramaID = videoGen.DramaID
+	opts.Payload = videoChainPayload{VideoGenID: videoGen.ID}
+	_, err := s.jobQueue.Enqueue("video_chain", fmt.Sprintf("%d", videoGen.ID), opts)
+	return err
+}
+
+// enqueueVideoChainFrame 上一个分镜视频就绪后，在 ffmpeg 工作池中截取其尾帧
+func (s *VideoGenerationService) enqueueVideoChainFrame(videoGen *models.VideoGeneration, prevID uint, opts JobOptions) error {
+	opts.Queue = JobQueueFFmpeg
+	opts.DramaID = videoGen.DramaID
+	opts.Payload = videoChainPayload{VideoGenID: videoGen.ID, PrevID: prevID}
+	_, err := s.jobQueue.Enqueue("video_chain_frame", fmt.Sprintf("%d", videoGen.ID), opts)
+	return err
+}
+
+// handleVideoChainJob 等待上一个分镜视频完成（及其质检），就绪后创建截取尾帧的任务
+// 上一个视频失败或取消时本视频随之失败，后续分镜依次中止
+func (s *VideoGenerationService) handleVideoChainJob(ctx context.Context, task *models.AsyncTask) error {
+	var payload videoChainPayload
+	if err := decodeJobPayload(task, &payload); err != nil {
+		return err
+	}
+
+	var videoGen models.VideoGeneration
+	if err := s.db.First(&videoGen, payload.VideoGenID).Error; err != nil {
+		s.taskService.UpdateTaskError(task.ID, fmt.Errorf("video generation not found"))
+		return nil
+	}
+	if videoGen.Status != models.VideoStatusPending {
+		return nil
+	}
+	if videoGen.ChainPrevID == nil {
+		return s.enqueueVideoGeneration(&videoGen, JobOptions{Priority: task.Priority, ParentID: task.ParentID})
+	}
+
+	var prev models.VideoGeneration
+	if err := s.db.First(&prev, *videoGen.ChainPrevID).Error; err != nil {
+		s.abortVideoChain(task, videoGen.ID, "上一个分镜视频不存在，链式生成中止")
+		return nil
+	}
+	switch prev.Status {
+	case models.VideoStatusCompleted:
+	case models.VideoStatusPending, models.VideoStatusProcessing:
+		if task.Message != "等待上一个分镜视频完成" {
+			s.taskService.UpdateTaskStatus(task.ID, "processing", 0, "等待上一个分镜视频完成")
+		}
+		return RescheduleJob(videoChainWaitInterval)
+	default:
+		s.abortVideoChain(task, videoGen.ID, fmt.Sprintf("上一个分镜视频未完成（%s），链式生成中止", prev.Status))
+		return nil
+	}
+
+	// 上一个视频质检未通过并已重新生成时，改为衔接重新生成的视频
+	if s.jobQueue.HasActiveJob("video_qc", fmt.Sprintf("%d", prev.ID)) {
+		if task.Message != "等待上一个分镜视频质检" {
+			s.taskService.UpdateTaskStatus(task.ID, "processing", 0, "等待上一个分镜视频质检")
+		}
+		return RescheduleJob(videoChainWaitInterval)
+	}
+	if prev.QCStatus != nil && *prev.QCStatus == qc.StatusFailed {
+		var retry models.VideoGeneration
+		if err := s.db.Select("id").Where("qc_retry_of_id = ?", prev.ID).Order("created_at DESC").First(&retry).Error; err == nil {
+			if err := s.db.Model(&models.VideoGeneration{}).Where("id = ?", videoGen.ID).Update("chain_prev_id", retry.ID).Error; err != nil {
+				return err
+			}
+			s.log.Infow("Chained video follows QC regeneration", "id", videoGen.ID, "prev_id", prev.ID, "retry_id", retry.ID)
+			return RescheduleJob(0)
+		}
+	}
+
+	return s.enqueueVideoChainFrame(&videoGen, prev.ID, JobOptions{Priority: task.Priority, ParentID: task.ParentID})
+}
+
+// handleVideoChainFrameJob 截取上一个分镜视频的尾帧保存为尾帧图片，作为本视频的首帧并提交生成
+// 排队期间上一个视频发生变化（如质检重新生成）时回到等待
+func (s *VideoGenerationService) handleVideoChainFrameJob(ctx context.Context, task *models.AsyncTask) error {
+	var payload videoChainPayload
+	if err := decodeJobPayload(task, &payload); err != nil {
+		return err
+	}
+
+	var videoGen models.VideoGeneration
+	if err := s.db.First(&videoGen, payload.VideoGenID).Error; err != nil {
+		s.taskService.UpdateTaskError(task.ID, fmt.Errorf("video generation not found"))
+		return nil
+	}
+	if videoGen.Status != models.VideoStatusPending {
+		return nil
+	}
+
+	var prev models.VideoGeneration
+	err := s.db.First(&prev, payload.PrevID).Error
+	if err != nil || videoGen.ChainPrevID == nil || *videoGen.ChainPrevID != prev.ID || prev.Status != models.VideoStatusCompleted {
+		return s.enqueueVideoChain(&videoGen, JobOptions{Priority: task.Priority, ParentID: task.ParentID})
+	}
+
+	s.taskService.UpdateTaskStatus(task.ID, "processing", 50, "截取上一个分镜视频尾帧")
+	frame, err := s.extractLastFrame(ctx, &prev)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		s.log.Errorw("Failed to extract chain frame", "error", err, "id", videoGen.ID, "prev_id", prev.ID)
+		s.abortVideoChain(task, videoGen.ID, fmt.Sprintf("failed to extract last frame of previous video: %v", err))
+		return nil
+	}
+
+	// 首帧保存本地相对路径，提交时转换为 base64
+	updated := s.db.Model(&models.VideoGeneration{}).
+		Where("id = ? AND status = ?", videoGen.ID, models.VideoStatusPending).
+		Update("first_frame_url", *frame.LocalPath)
+	if updated.Error != nil {
+		return updated.Error
+	}
+	if updated.RowsAffected == 0 {
+		return nil
+	}
+
+	if err := s.enqueueVideoGeneration(&videoGen, JobOptions{Priority: task.Priority, ParentID: task.ParentID}); err != nil {
+		return err
+	}
+	s.log.Infow("Chained video generation submitted", "id", videoGen.ID, "prev_id", prev.ID, "first_frame_image_id", frame.ID)
+	return nil
+}
+
+// abortVideoChain 链式生成中止：视频记录和链式任务都标记为失败，后续分镜的链式任务随之中止
+func (s *VideoGenerationService) abortVideoChain(task *models.AsyncTask, videoGenID uint, message string) {
+	s.updateVideoGenError(videoGenID, message)
+	s.taskService.UpdateTaskError(task.ID, errors.New(message))
+}
+
+// extractLastFrame 截取视频最后一帧，保存为所属分镜 frame_type 为 last 的图片生成记录
+func (s *VideoGenerationService) extractLastFrame(ctx context.Context, videoGen *models.VideoGeneration) (*models.ImageGeneration, error) {
+	if s.localStorage == nil {
+		return nil, fmt.Errorf("local storage is not configured")
+	}
+
+	var source string
+	switch {
+	case videoGen.LocalPath != nil && *videoGen.LocalPath != "":
+		source = s.localStorage.GetAbsolutePath(*videoGen.LocalPath)
+	case videoGen.VideoURL != nil && *videoGen.VideoURL != "":
+		source = *videoGen.VideoURL
+	default:
+		return nil, fmt.Errorf("video %d has no file", videoGen.ID)
+	}
+
+	duration, err := s.ffmpeg.GetVideoDuration(source)
+	if err != nil {
+		if videoGen.Duration == nil || *videoGen.Duration <= 0 {
+			return nil, fmt.Errorf("failed to probe video duration: %w", err)
+		}
+		duration = float64(*videoGen.Duration)
+	}
+
+	relPath := filepath.ToSlash(filepath.Join("images", "frames", fmt.Sprintf("video_%d_last_%d.jpg", videoGen.ID, time.Now().UnixNano())))
+	outputPath := s.localStorage.GetAbsolutePath(relPath)
+	var timestamp float64
+	for _, offset := range chainFrameOffsets {
+		timestamp = duration - offset
+		if timestamp < 0 {
+			timestamp = 0
+		}
+		if err = s.ffmpeg.ExtractFrame(ctx, source, outputPath, timestamp, 0); err == nil || ctx.Err() != nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	frameType := models.FrameTypeLast
+	imageURL := s.localStorage.GetURL(relPath)
+	imageGen := &models.ImageGeneration{
+		StoryboardID: videoGen.StoryboardID,
+		DramaID:      videoGen.DramaID,
+		ImageType:    string(models.ImageTypeStoryboard),
+		FrameType:    &frameType,
+		Provider:     chainFrameProvider,
+		Prompt:       fmt.Sprintf("视频 #%d 在 %.3fs 处的尾帧", videoGen.ID, timestamp),
+		ImageURL:     &imageURL,
+		LocalPath:    &relPath,
+		Status:       models.ImageStatusCompleted,
+		Width:        videoGen.Width,
+		Height:       videoGen.Height,
+		CompletedAt:  &now,
+	}
+	if err := s.db.Create(imageGen).Error; err != nil {
+		return nil, fmt.Errorf("failed to save last frame: %w", err)
+	}
+	return imageGen, nil
+}
+
+// latestStoryboardImage 分镜最近一张已完成的图片，不包括链式生成截取的尾帧
+func (s *VideoGenerationService) latestStoryboardImage(storyboardID uint) (*models.ImageGeneration, error) {
+	var imageGen models.ImageGeneration
+	if err := s.db.Where("storyboard_id = ? AND status = ? AND provider <> ?", storyboardID, models.ImageStatusCompleted, chainFrameProvider).
+		Order("created_at DESC").First(&imageGen).Error; err != nil {
+		return nil, err
+	}
+	return &imageGen, nil
+}
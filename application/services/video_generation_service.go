@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strconv"
@@ -24,9 +25,31 @@ type VideoGenerationService struct {
 	aiService       *AIService
 	ffmpeg          *ffmpeg.FFmpeg
 	promptI18n      *PromptI18n
+	jobQueue        *JobQueue
+	eventBus        *EventBus
+	settings        *Settings
+	taskService     *TaskService
 }
 
-func NewVideoGenerationService(db *gorm.DB, transferService *ResourceTransferService, localStorage *storage.LocalStorage, aiService *AIService, log *logger.Logger, promptI18n *PromptI18n) *VideoGenerationService {
+// 视频异步任务轮询参数：最长轮询 50 分钟，已注册厂商回调的任务按回调设置低频兜底轮询
+const (
+	videoPollInterval = 10 * time.Second
+	videoPollTimeout  = 50 * time.Minute
+)
+
+// videoGenerationPayload 视频生成任务参数
+type videoGenerationPayload struct {
+	VideoGenID uint `json:"video_gen_id"`
+}
+
+// videoStatusPollPayload 视频异步任务状态轮询参数
+type videoStatusPollPayload struct {
+	VideoGenID uint   `json:"video_gen_id"`
+	TaskID     string `json:"task_id"`
+	Callback   bool   `json:"callback,omitempty"` // 已注册厂商回调
+}
+
+func NewVideoGenerationService(db *gorm.DB, jobQueue *JobQueue, eventBus *EventBus, taskService *TaskService, settings *Settings, transferService *ResourceTransferService, localStorage *storage.LocalStorage, aiService *AIService, log *logger.Logger, promptI18n *PromptI18n) *VideoGenerationService {
 	service := &VideoGenerationService{
 		db:              db,
 		localStorage:    localStorage,
@@ -35,9 +58,23 @@ func NewVideoGenerationService(db *gorm.DB, transferService *ResourceTransferSer
 		log:             log,
 		ffmpeg:          ffmpeg.NewFFmpeg(log),
 		promptI18n:      promptI18n,
+		jobQueue:        jobQueue,
+		eventBus:        eventBus,
+		settings:        settings,
+		taskService:     taskService,
 	}
 
-	go service.RecoverPendingTasks()
+	service.jobQueue.RegisterHandler("video_generation", service.handleVideoGenerationJob)
+	service.jobQueue.RegisterHandler("video_status_poll", service.handleVideoStatusPollJob)
+	service.jobQueue.RegisterHandler("video_callback", service.handleVideoCallbackJob)
+	service.jobQueue.RegisterCanceler("video_generation", service.handleVideoJobCancelled)
+	service.jobQueue.RegisterCanceler("video_status_poll", service.handleVideoJobCancelled)
+	service.jobQueue.RegisterHandler("video_chain", service.handleVideoChainJob)
+	service.jobQueue.RegisterCanceler("video_chain", service.handleVideoJobCancelled)
+	service.jobQueue.RegisterHandler("video_chain_frame", service.handleVideoChainFrameJob)
+	service.jobQueue.RegisterCanceler("video_chain_frame", service.handleVideoJobCancelled)
+	service.jobQueue.RegisterInterruptHandler("video_generation", service.handleVideoJobInterrupted)
+	service.jobQueue.RegisterStartupRecoverer("video_generations", service.RecoverPendingTasks)
 
 	return service
 }
@@ -76,6 +113,31 @@ type GenerateVideoRequest struct {
 }
 
 func (s *VideoGenerationService) GenerateVideo(request *GenerateVideoRequest) (*models.VideoGeneration, error) {
+	return s.generateVideo(request, JobOptions{Priority: JobPriorityInteractive})
+}
+
+// generateVideo 创建视频生成记录并加入 video 工作池，opts 只需指定优先级和父任务
+func (s *VideoGenerationService) generateVideo(request *GenerateVideoRequest, opts JobOptions) (*models.VideoGeneration, error) {
+	videoGen, err := s.newVideoGeneration(request)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Create(videoGen).Error; err != nil {
+		return nil, fmt.Errorf("failed to create record: %w", err)
+	}
+
+	// 加入任务队列异步处理，API 立即返回
+	if err := s.enqueueVideoGeneration(videoGen, opts); err != nil {
+		s.updateVideoGenError(videoGen.ID, err.Error())
+		return nil, err
+	}
+
+	return videoGen, nil
+}
+
+// newVideoGeneration 校验请求并构造视频生成记录（未保存）
+func (s *VideoGenerationService) newVideoGeneration(request *GenerateVideoRequest) (*models.VideoGeneration, error) {
 	if request.StoryboardID != nil {
 		var storyboard models.Storyboard
 		if err := s.db.Preload("Episode").Where("id = ?", *request.StoryboardID).First(&storyboard).Error; err != nil {
@@ -175,24 +237,48 @@ func (s *VideoGenerationService) GenerateVideo(request *GenerateVideoRequest) (*
 		}
 	}
 
-	if err := s.db.Create(videoGen).Error; err != nil {
-		return nil, fmt.Errorf("failed to create record: %w", err)
-	}
+	return videoGen, nil
+}
 
-	// Start background goroutine to process video generation asynchronously
-	// This allows the API to return immediately while video generation happens in background
-	// CRITICAL: The goroutine will handle all video generation logic including API calls and polling
-	go s.ProcessVideoGeneration(videoGen.ID)
+// enqueueVideoGeneration 将视频生成记录加入 video 工作池
+// 工作池和厂商并发上限由队列配置控制，避免批量生成时压垮上游接口
+func (s *VideoGenerationService) enqueueVideoGeneration(videoGen *models.VideoGeneration, opts JobOptions) error {
+	opts.Queue = JobQueueVideo
+	opts.Provider = s.aiService.ResolveProvider("video", videoGen.Model)
+	opts.DramaID = videoGen.DramaID
+	opts.Payload = videoGenerationPayload{VideoGenID: videoGen.ID}
+	_, err := s.jobQueue.Enqueue("video_generation", fmt.Sprintf("%d", videoGen.ID), opts)
+	return err
+}
 
-	return videoGen, nil
+// handleVideoGenerationJob 任务队列处理函数
+func (s *VideoGenerationService) handleVideoGenerationJob(ctx context.Context, task *models.AsyncTask) error {
+	var payload videoGenerationPayload
+	if err := decodeJobPayload(task, &payload); err != nil {
+		return err
+	}
+	s.ProcessVideoGeneration(ctx, payload.VideoGenID)
+	return ctx.Err()
 }
 
-func (s *VideoGenerationService) ProcessVideoGeneration(videoGenID uint) {
+func (s *VideoGenerationService) ProcessVideoGeneration(ctx context.Context, videoGenID uint) {
 	var videoGen models.VideoGeneration
 	if err := s.db.First(&videoGen, videoGenID).Error; err != nil {
 		s.log.Errorw("Failed to load video generation", "error", err, "id", videoGenID)
 		return
 	}
+	switch videoGen.Status {
+	case models.VideoStatusCancelled, models.VideoStatusCompleted, models.VideoStatusFailed:
+		s.log.Infow("Video generation already finished, skipping", "id", videoGenID, "status", videoGen.Status)
+		return
+	case models.VideoStatusProcessing:
+		// 中断前已提交到厂商：继续轮询远程任务，不重复提交
+		if videoGen.TaskID != nil && *videoGen.TaskID != "" {
+			s.log.Infow("Resuming video status polling", "id", videoGenID, "task_id", *videoGen.TaskID)
+			s.enqueueVideoStatusPoll(&videoGen, *videoGen.TaskID)
+			return
+		}
+	}
 
 	// 获取drama的style信息
 	var drama models.Drama
@@ -201,20 +287,11 @@ func (s *VideoGenerationService) ProcessVideoGeneration(videoGenID uint) {
 	}
 
 	s.db.Model(&videoGen).Update("status", models.VideoStatusProcessing)
-
-	client, err := s.getVideoClient(videoGen.Provider, videoGen.Model)
-	if err != nil {
-		s.log.Errorw("Failed to get video client", "error", err, "provider", videoGen.Provider, "model", videoGen.Model)
-		s.updateVideoGenError(videoGenID, err.Error())
-		return
-	}
+	s.publishVideoEvent(videoGenID, EventVideoProcessing)
 
 	s.log.Infow("Starting video generation", "id", videoGenID, "prompt", videoGen.Prompt, "provider", videoGen.Provider)
 
 	var opts []video.VideoOption
-	if videoGen.Model != "" {
-		opts = append(opts, video.WithModel(videoGen.Model))
-	}
 	if videoGen.Duration != nil {
 		opts = append(opts, video.WithDuration(*videoGen.Duration))
 	}
@@ -335,24 +412,56 @@ func (s *VideoGenerationService) ProcessVideoGeneration(videoGenID uint) {
 		"constraint_prompt", constraintPrompt,
 		"final_prompt", prompt)
 
-	result, err := client.GenerateVideo(imageURL, prompt, opts...)
+	// 按优先级依次尝试激活的视频配置，临时错误自动重试
+	var client video.VideoClient
+	var result *video.VideoResult
+	var servedModel string
+	config, err := s.aiService.ExecuteWithFailover(ctx, "video", videoGen.Model, func(ctx context.Context, config *models.AIServiceConfig, model string) error {
+		var clientErr error
+		client, clientErr = newVideoClient(config, model)
+		if clientErr != nil {
+			return clientErr
+		}
+		servedModel = model
+		callOpts := append([]video.VideoOption{}, opts...)
+		if model != "" {
+			callOpts = append(callOpts, video.WithModel(model))
+		}
+		if callbackURL := s.settings.Callback.callbackURL(config.Provider, videoGenID); callbackURL != "" {
+			callOpts = append(callOpts, video.WithCallbackURL(callbackURL))
+		}
+		var genErr error
+		result, genErr = client.GenerateVideo(imageURL, prompt, callOpts...)
+		return genErr
+	})
 	if err != nil {
+		// 任务被暂停、取消或队列停止：记录状态由对应操作处理，不标记为失败
+		if ctx.Err() != nil {
+			s.log.Infow("Generation interrupted", "id", videoGenID, "error", err)
+			return
+		}
 		s.log.Errorw("Video generation API call failed", "error", err, "id", videoGenID)
 		s.updateVideoGenError(videoGenID, err.Error())
 		return
 	}
+	s.recordServedConfig(&videoGen, config, servedModel)
 
 	// CRITICAL FIX: Validate TaskID before starting polling goroutine
 	// Empty TaskID would cause polling to fail silently or cause issues
 	if result.TaskID != "" {
-		s.db.Model(&videoGen).Updates(map[string]interface{}{
-			"task_id": result.TaskID,
-			"status":  models.VideoStatusProcessing,
-		})
-		// Start background goroutine to poll task status
-		// This allows the API to return immediately while video generation continues asynchronously
-		// The goroutine will poll until completion, failure, or timeout (max 300 attempts * 10s = 50 minutes)
-		go s.pollTaskStatus(videoGenID, result.TaskID, videoGen.Provider, videoGen.Model)
+		// 提交期间被取消：记录状态已是 cancelled，补发远程取消后不再轮询
+		updated := s.db.Model(&models.VideoGeneration{}).
+			Where("id = ? AND status <> ?", videoGenID, models.VideoStatusCancelled).
+			Updates(map[string]interface{}{
+				"task_id": result.TaskID,
+				"status":  models.VideoStatusProcessing,
+			})
+		if updated.Error == nil && updated.RowsAffected == 0 {
+			s.cancelRemoteTask(client, videoGenID, result.TaskID)
+			return
+		}
+		// 创建轮询任务，轮询直到完成、失败或超时（最长 50 分钟）；已注册回调时轮询仅作兜底
+		s.enqueueVideoStatusPoll(&videoGen, result.TaskID)
 		return
 	}
 
@@ -364,89 +473,112 @@ func (s *VideoGenerationService) ProcessVideoGeneration(videoGenID uint) {
 	s.updateVideoGenError(videoGenID, "no task ID or video URL returned")
 }
 
-func (s *VideoGenerationService) pollTaskStatus(videoGenID uint, taskID string, provider string, model string) {
-	// CRITICAL FIX: Validate taskID parameter to prevent invalid API calls
+// enqueueVideoStatusPoll 创建视频状态轮询任务，轮询不占用 video 工作池的执行槽位
+func (s *VideoGenerationService) enqueueVideoStatusPoll(videoGen *models.VideoGeneration, taskID string) {
+	callback := s.settings.Callback.callbackURL(videoGen.Provider, videoGen.ID) != ""
+	_, err := s.jobQueue.Enqueue("video_status_poll", fmt.Sprintf("%d", videoGen.ID), JobOptions{
+		Queue:    JobQueueDefault,
+		Priority: JobPriorityInteractive,
+		DramaID:  videoGen.DramaID,
+		Delay:    s.settings.Callback.pollDelay(callback),
+		Payload: videoStatusPollPayload{
+			VideoGenID: videoGen.ID,
+			TaskID:     taskID,
+			Callback:   callback,
+		},
+	})
+	if err != nil {
+		s.log.Errorw("Failed to enqueue video status poll", "error", err, "id", videoGen.ID, "task_id", taskID)
+	}
+}
+
+// handleVideoStatusPollJob 查询一次远程任务状态，未完成时重新排队
+func (s *VideoGenerationService) handleVideoStatusPollJob(ctx context.Context, task *models.AsyncTask) error {
+	var payload videoStatusPollPayload
+	if err := decodeJobPayload(task, &payload); err != nil {
+		return err
+	}
+
+	done, err := s.pollTaskStatus(payload.VideoGenID, payload.TaskID)
+	if err != nil {
+		s.log.Errorw("Failed to get task status", "error", err, "task_id", payload.TaskID)
+	}
+	if done {
+		return nil
+	}
+
+	// 超时后标记为失败，避免无限轮询
+	if time.Since(task.CreatedAt) > videoPollTimeout {
+		s.updateVideoGenError(payload.VideoGenID, fmt.Sprintf("polling timeout after %.1f minutes", videoPollTimeout.Minutes()))
+		return nil
+	}
+
+	return RescheduleJob(s.settings.Callback.pollDelay(payload.Callback))
+}
+
+// pollTaskStatus 查询一次远程任务状态，返回轮询是否已结束
+// 查询出错（可能是网络抖动）时返回 false 和错误，由调用方决定是否继续轮询
+func (s *VideoGenerationService) pollTaskStatus(videoGenID uint, taskID string) (bool, error) {
 	// Empty taskID would cause unnecessary API calls and potential errors
 	if taskID == "" {
 		s.log.Errorw("Invalid empty taskID for polling", "video_gen_id", videoGenID)
 		s.updateVideoGenError(videoGenID, "invalid task ID for polling")
-		return
+		return true, nil
 	}
 
-	client, err := s.getVideoClient(provider, model)
+	var videoGen models.VideoGeneration
+	if err := s.db.First(&videoGen, videoGenID).Error; err != nil {
+		s.log.Errorw("Failed to load video generation", "error", err, "id", videoGenID)
+		return true, nil
+	}
+
+	// Check if status was manually changed (e.g., cancelled by user)
+	// If status is no longer "processing", stop polling to avoid unnecessary API calls
+	if videoGen.Status != models.VideoStatusProcessing {
+		s.log.Infow("Video generation status changed, stopping poll", "id", videoGenID, "status", videoGen.Status)
+		return true, nil
+	}
+
+	// 远程任务必须向提交它的配置查询
+	client, err := s.getVideoClientForGeneration(&videoGen)
 	if err != nil {
 		s.log.Errorw("Failed to get video client for polling", "error", err)
 		s.updateVideoGenError(videoGenID, "failed to get video client")
-		return
+		return true, nil
 	}
 
-	// Polling configuration: max 300 attempts with 10 second intervals
-	// Total maximum polling time: 300 * 10s = 50 minutes
-	// This prevents infinite polling if the task never completes
-	maxAttempts := 300
-	interval := 10 * time.Second
-
-	for attempt := 0; attempt < maxAttempts; attempt++ {
-		// Sleep before each poll attempt to avoid overwhelming the API
-		// First iteration sleeps before the first check (after 0 attempts)
-		time.Sleep(interval)
-
-		var videoGen models.VideoGeneration
-		if err := s.db.First(&videoGen, videoGenID).Error; err != nil {
-			s.log.Errorw("Failed to load video generation", "error", err, "id", videoGenID)
-			return
-		}
-
-		// CRITICAL FIX: Check if status was manually changed (e.g., cancelled by user)
-		// If status is no longer "processing", stop polling to avoid unnecessary API calls
-		// This prevents polling when the task has been cancelled or failed externally
-		if videoGen.Status != models.VideoStatusProcessing {
-			s.log.Infow("Video generation status changed, stopping poll", "id", videoGenID, "status", videoGen.Status)
-			return
-		}
-
-		// Poll the video generation API for task status
-		// Continue polling on transient errors (network issues, temporary API failures)
-		// Only stop on permanent errors or task completion
-		result, err := client.GetTaskStatus(taskID)
-		if err != nil {
-			s.log.Errorw("Failed to get task status", "error", err, "task_id", taskID, "attempt", attempt+1)
-			// Continue polling on error - might be transient network issue
-			// Will eventually timeout after maxAttempts if error persists
-			continue
-		}
+	result, err := client.GetTaskStatus(taskID)
+	if err != nil {
+		return false, err
+	}
 
-		// Check if task completed successfully
-		// CRITICAL FIX: Validate that video URL exists when task is marked as completed
-		// Some APIs may mark task as completed but fail to provide the video URL
-		if result.Completed {
-			if result.VideoURL != "" {
-				// Successfully completed with video URL - download and update database
-				s.completeVideoGeneration(videoGenID, result.VideoURL, &result.Duration, &result.Width, &result.Height, nil)
-				return
-			}
-			// Task marked as completed but no video URL - this is an error condition
-			s.updateVideoGenError(videoGenID, "task completed but no video URL")
-			return
-		}
-
-		// Check if task failed with an error message
-		if result.Error != "" {
-			s.updateVideoGenError(videoGenID, result.Error)
-			return
+	// Some APIs may mark task as completed but fail to provide the video URL
+	if result.Completed {
+		if result.VideoURL != "" {
+			s.completeVideoGeneration(videoGenID, result.VideoURL, &result.Duration, &result.Width, &result.Height, nil)
+			return true, nil
 		}
+		s.updateVideoGenError(videoGenID, "task completed but no video URL")
+		return true, nil
+	}
 
-		// Task still in progress - log and continue polling
-		s.log.Infow("Video generation in progress", "id", videoGenID, "attempt", attempt+1, "max_attempts", maxAttempts)
+	if result.Error != "" {
+		s.updateVideoGenError(videoGenID, result.Error)
+		return true, nil
 	}
 
-	// CRITICAL FIX: Handle polling timeout gracefully
-	// After maxAttempts (50 minutes), mark task as failed if still not completed
-	// This prevents indefinite polling and resource waste
-	s.updateVideoGenError(videoGenID, fmt.Sprintf("polling timeout after %d attempts (%.1f minutes)", maxAttempts, float64(maxAttempts*int(interval))/60.0))
+	s.log.Infow("Video generation in progress", "id", videoGenID, "task_id", taskID)
+	return false, nil
 }
 
 func (s *VideoGenerationService) completeVideoGeneration(videoGenID uint, videoURL string, duration *int, width *int, height *int, firstFrameURL *string) {
+	// 厂商回调和兜底轮询可能先后送达同一结果，只处理仍在进行中的记录
+	var current models.VideoGeneration
+	if err := s.db.Select("id", "status", "duration").First(&current, videoGenID).Error; err == nil && current.Status != models.VideoStatusProcessing {
+		s.log.Infow("Video generation no longer processing, discarding result", "id", videoGenID, "status", current.Status)
+		return
+	}
+
 	var localVideoPath *string
 
 	// 下载视频到本地存储并保存相对路径到数据库
@@ -536,8 +668,13 @@ func (s *VideoGenerationService) completeVideoGeneration(videoGenID uint, videoU
 		updates["first_frame_url"] = *firstFrameURL
 	}
 
-	if err := s.db.Model(&models.VideoGeneration{}).Where("id = ?", videoGenID).Updates(updates).Error; err != nil {
-		s.log.Errorw("Failed to update video generation", "error", err, "id", videoGenID)
+	updated := s.db.Model(&models.VideoGeneration{}).Where("id = ? AND status = ?", videoGenID, models.VideoStatusProcessing).Updates(updates)
+	if updated.Error != nil {
+		s.log.Errorw("Failed to update video generation", "error", updated.Error, "id", videoGenID)
+		return
+	}
+	if updated.RowsAffected == 0 {
+		s.log.Infow("Video generation finished elsewhere, discarding result", "id", videoGenID)
 		return
 	}
 
@@ -557,19 +694,49 @@ func (s *VideoGenerationService) completeVideoGeneration(videoGenID uint, videoU
 			} else {
 				s.log.Infow("Updated storyboard with video info", "storyboard_id", *videoGen.StoryboardID, "duration", duration)
 			}
+			// 有对白且已配音的分镜自动同步口型
+			scheduleStoryboardLipSync(s.db, s.jobQueue, s.log, *videoGen.StoryboardID)
 		}
+		// 从视频中选取封面并生成拖动预览雪碧图
+		scheduleVideoThumbnail(s.jobQueue, s.log, &videoGen)
+		// 按请求时长（完成前的值）质检黑场、冻帧、时长和画幅
+		scheduleVideoQC(s.jobQueue, s.settings.QC, s.log, &videoGen, current.Duration)
 	}
 
 	s.log.Infow("Video generation completed", "id", videoGenID, "url", videoURL, "duration", duration)
+	s.publishVideoEvent(videoGenID, EventVideoCompleted)
 }
 
 func (s *VideoGenerationService) updateVideoGenError(videoGenID uint, errorMsg string) {
-	if err := s.db.Model(&models.VideoGeneration{}).Where("id = ?", videoGenID).Updates(map[string]interface{}{
+	if err := s.db.Model(&models.VideoGeneration{}).Where("id = ? AND status <> ?", videoGenID, models.VideoStatusCancelled).Updates(map[string]interface{}{
 		"status":    models.VideoStatusFailed,
 		"error_msg": errorMsg,
 	}).Error; err != nil {
 		s.log.Errorw("Failed to update video generation error", "error", err, "id", videoGenID)
+		return
 	}
+	s.publishVideoEvent(videoGenID, EventVideoFailed)
+}
+
+// publishVideoEvent 发布视频生成状态变更事件
+func (s *VideoGenerationService) publishVideoEvent(videoGenID uint, eventType string) {
+	var videoGen models.VideoGeneration
+	if err := s.db.First(&videoGen, videoGenID).Error; err != nil {
+		return
+	}
+
+	data := map[string]interface{}{
+		"id":            videoGen.ID,
+		"status":        videoGen.Status,
+		"storyboard_id": videoGen.StoryboardID,
+		"image_gen_id":  videoGen.ImageGenID,
+		"video_url":     videoGen.VideoURL,
+		"local_path":    videoGen.LocalPath,
+		"duration":      videoGen.Duration,
+		"error_msg":     videoGen.ErrorMsg,
+	}
+	s.eventBus.Publish(eventType, videoGen.DramaID, data)
+	dispatchWebhookEvent(s.db, s.jobQueue, s.log, eventType, videoGen.DramaID, data)
 }
 
 func (s *VideoGenerationService) getVideoClient(provider string, modelName string) (video.VideoClient, error) {
@@ -593,15 +760,46 @@ func (s *VideoGenerationService) getVideoClient(provider string, modelName strin
 		}
 	}
 
-	// 使用配置中的信息创建客户端
-	baseURL := config.BaseURL
-	apiKey := config.APIKey
 	model := modelName
 	if model == "" && len(config.Model) > 0 {
 		model = config.Model[0]
 	}
 
-	// 根据配置中的 provider 创建对应的客户端
+	return newVideoClient(config, model)
+}
+
+// getVideoClientForGeneration 获取提交该视频生成任务时实际使用的客户端
+func (s *VideoGenerationService) getVideoClientForGeneration(videoGen *models.VideoGeneration) (video.VideoClient, error) {
+	if videoGen.ServiceConfigID != nil {
+		config, err := s.aiService.GetConfig(*videoGen.ServiceConfigID)
+		if err == nil {
+			return newVideoClient(config, videoGen.Model)
+		}
+		s.log.Warnw("Served config not found, resolving by model", "config_id", *videoGen.ServiceConfigID, "error", err)
+	}
+	return s.getVideoClient(videoGen.Provider, videoGen.Model)
+}
+
+// recordServedConfig 记录实际完成本次生成的配置、厂商和模型
+func (s *VideoGenerationService) recordServedConfig(videoGen *models.VideoGeneration, config *models.AIServiceConfig, model string) {
+	videoGen.ServiceConfigID = &config.ID
+	videoGen.Provider = config.Provider
+	videoGen.Model = model
+
+	if err := s.db.Model(&models.VideoGeneration{}).Where("id = ?", videoGen.ID).Updates(map[string]interface{}{
+		"service_config_id": config.ID,
+		"provider":          config.Provider,
+		"model":             model,
+	}).Error; err != nil {
+		s.log.Warnw("Failed to record served config", "error", err, "id", videoGen.ID, "config_id", config.ID)
+	}
+}
+
+// newVideoClient 根据配置中的 provider 创建对应的视频客户端
+func newVideoClient(config *models.AIServiceConfig, model string) (video.VideoClient, error) {
+	baseURL := config.BaseURL
+	apiKey := config.APIKey
+
 	var endpoint string
 	var queryEndpoint string
 
@@ -624,33 +822,55 @@ func (s *VideoGenerationService) getVideoClient(provider string, modelName strin
 	case "minimax":
 		return video.NewMinimaxClient(baseURL, apiKey, model), nil
 	default:
-		return nil, fmt.Errorf("unsupported video provider: %s", provider)
+		return nil, fmt.Errorf("unsupported video provider: %s", config.Provider)
 	}
 }
 
+// RecoverPendingTasks 启动时恢复没有队列任务跟踪的视频生成记录
+// 已提交到厂商的记录继续轮询，未开始的记录重新入队，提交中途中断的记录标记为失败
 func (s *VideoGenerationService) RecoverPendingTasks() {
 	var pendingVideos []models.VideoGeneration
-	// Query for pending tasks with non-empty task_id
-	// Note: Using IS NOT NULL and != '' to ensure we only get valid task IDs
-	if err := s.db.Where("status = ? AND task_id IS NOT NULL AND task_id != ''", models.VideoStatusProcessing).Find(&pendingVideos).Error; err != nil {
+	if err := s.db.Where("status IN ?", []models.VideoStatus{models.VideoStatusPending, models.VideoStatusProcessing}).
+		Find(&pendingVideos).Error; err != nil {
 		s.log.Errorw("Failed to load pending video tasks", "error", err)
 		return
 	}
 
-	s.log.Infow("Recovering pending video generation tasks", "count", len(pendingVideos))
-
-	for _, videoGen := range pendingVideos {
-		// CRITICAL FIX: Check for nil TaskID before dereferencing to prevent panic
-		// Even though we filter for non-empty task_id, GORM might still return nil pointers
-		// This nil check prevents a potential runtime panic
-		if videoGen.TaskID == nil || *videoGen.TaskID == "" {
-			s.log.Warnw("Skipping video generation with nil or empty TaskID", "id", videoGen.ID)
+	resumed, requeued, failed := 0, 0, 0
+	for i := range pendingVideos {
+		videoGen := &pendingVideos[i]
+		// 已有生成或轮询任务（排队中或执行中）的记录由队列负责，避免重复处理
+		resourceID := fmt.Sprintf("%d", videoGen.ID)
+		if s.jobQueue.HasActiveJob("video_generation", resourceID) || s.jobQueue.HasActiveJob("video_status_poll", resourceID) ||
+			s.jobQueue.HasActiveJob("video_chain", resourceID) || s.jobQueue.HasActiveJob("video_chain_frame", resourceID) {
 			continue
 		}
 
-		// Start goroutine to poll task status for each pending video
-		// Each goroutine will poll independently until completion or timeout
-		go s.pollTaskStatus(videoGen.ID, *videoGen.TaskID, videoGen.Provider, videoGen.Model)
+		switch {
+		case videoGen.TaskID != nil && *videoGen.TaskID != "":
+			s.enqueueVideoStatusPoll(videoGen, *videoGen.TaskID)
+			resumed++
+		case videoGen.Status == models.VideoStatusPending && videoGen.ChainPrevID != nil && videoGen.FirstFrameURL == nil:
+			// 链式生成尚未拿到上一个分镜的尾帧，重新等待
+			if err := s.enqueueVideoChain(videoGen, JobOptions{Priority: JobPriorityBatch}); err != nil {
+				s.updateVideoGenError(videoGen.ID, err.Error())
+				continue
+			}
+			requeued++
+		case videoGen.Status == models.VideoStatusPending:
+			if err := s.enqueueVideoGeneration(videoGen, JobOptions{Priority: JobPriorityBatch}); err != nil {
+				s.updateVideoGenError(videoGen.ID, err.Error())
+				continue
+			}
+			requeued++
+		default:
+			s.updateVideoGenError(videoGen.ID, InterruptedByRestartMessage)
+			failed++
+		}
+	}
+
+	if resumed+requeued+failed > 0 {
+		s.log.Infow("Recovered video generations", "resumed_polling", resumed, "requeued", requeued, "failed", failed)
 	}
 }
 
@@ -690,6 +910,24 @@ func (s *VideoGenerationService) ListVideoGenerations(dramaID *uint, storyboardI
 }
 
 func (s *VideoGenerationService) GenerateVideoFromImage(imageGenID uint) (*models.VideoGeneration, error) {
+	return s.generateVideoFromImage(imageGenID, JobOptions{Priority: JobPriorityInteractive})
+}
+
+func (s *VideoGenerationService) generateVideoFromImage(imageGenID uint, opts JobOptions) (*models.VideoGeneration, error) {
+	return s.generateVideoFromImageWithModel(imageGenID, "", "", opts)
+}
+
+// generateVideoFromImageWithModel 使用指定厂商/模型根据已完成的图片生成视频，为空时使用默认值
+func (s *VideoGenerationService) generateVideoFromImageWithModel(imageGenID uint, provider, model string, opts JobOptions) (*models.VideoGeneration, error) {
+	req, err := s.videoRequestFromImage(imageGenID, provider, model)
+	if err != nil {
+		return nil, err
+	}
+	return s.generateVideo(req, opts)
+}
+
+// videoRequestFromImage 根据已完成的图片构造单图模式的视频生成请求，时长使用分镜时长
+func (s *VideoGenerationService) videoRequestFromImage(imageGenID uint, provider, model string) (*GenerateVideoRequest, error) {
 	var imageGen models.ImageGeneration
 	if err := s.db.First(&imageGen, imageGenID).Error; err != nil {
 		return nil, fmt.Errorf("image generation not found")
@@ -712,47 +950,195 @@ func (s *VideoGenerationService) GenerateVideoFromImage(imageGenID uint) (*model
 	}
 
 	req := &GenerateVideoRequest{
-		DramaID:      fmt.Sprintf("%d", imageGen.DramaID),
-		StoryboardID: imageGen.StoryboardID,
-		ImageGenID:   &imageGenID,
-		ImageURL:     *imageGen.ImageURL,
-		Prompt:       imageGen.Prompt,
-		Provider:     "doubao",
-		Duration:     duration,
+		DramaID:        fmt.Sprintf("%d", imageGen.DramaID),
+		StoryboardID:   imageGen.StoryboardID,
+		ImageGenID:     &imageGenID,
+		ImageURL:       *imageGen.ImageURL,
+		ImageLocalPath: imageGen.LocalPath,
+		Prompt:         imageGen.Prompt,
+		Provider:       provider,
+		Model:          model,
+		Duration:       duration,
+	}
+	if req.Provider == "" {
+		req.Provider = "doubao"
 	}
 
-	return s.GenerateVideo(req)
+	return req, nil
 }
 
 func (s *VideoGenerationService) BatchGenerateVideosForEpisode(episodeID string) ([]*models.VideoGeneration, error) {
+	_, results, err := s.BatchGenerateVideosForEpisodeWithOptions(episodeID, EpisodeBatchOptions{})
+	return results, err
+}
+
+// BatchGenerateVideosForEpisodeWithOptions 按参数为整集已有图片的分镜批量生成视频，返回批量父任务
+// 链式模式下只有第一个分镜立即生成，其余分镜依次等待上一个分镜完成后以其尾帧作为首帧、本分镜图片作为尾帧生成
+func (s *VideoGenerationService) BatchGenerateVideosForEpisodeWithOptions(episodeID string, opts EpisodeBatchOptions) (*models.AsyncTask, []*models.VideoGeneration, error) {
 	var episode models.Episode
-	if err := s.db.Preload("Storyboards").Where("id = ?", episodeID).First(&episode).Error; err != nil {
-		return nil, fmt.Errorf("episode not found")
+	if err := s.db.Preload("Storyboards", func(db *gorm.DB) *gorm.DB {
+		return db.Order("storyboard_number ASC")
+	}).Where("id = ?", episodeID).First(&episode).Error; err != nil {
+		return nil, nil, fmt.Errorf("episode not found")
 	}
 
+	// 整集批量任务挂在同一个父任务下，便于整体暂停/恢复/取消
+	batch, err := s.jobQueue.CreateParent("video_batch", episodeID, episode.DramaID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer s.jobQueue.refreshParent(batch.ID)
+
+	jobOpts := JobOptions{Priority: JobPriorityBatch, ParentID: batch.ID}
 	var results []*models.VideoGeneration
+	// 上一个分镜的视频，中间有跳过的分镜时断开，避免把不相邻的镜头接在一起
+	var prev *models.VideoGeneration
 	for _, storyboard := range episode.Storyboards {
 		if storyboard.ImagePrompt == nil {
+			prev = nil
 			continue
 		}
 
-		var imageGen models.ImageGeneration
-		if err := s.db.Where("storyboard_id = ? AND status = ?", storyboard.ID, models.ImageStatusCompleted).
-			Order("created_at DESC").First(&imageGen).Error; err != nil {
+		imageGen, err := s.latestStoryboardImage(storyboard.ID)
+		if err != nil {
 			s.log.Warnw("No completed image for storyboard", "storyboard_id", storyboard.ID)
+			prev = nil
 			continue
 		}
 
-		videoGen, err := s.GenerateVideoFromImage(imageGen.ID)
+		if opts.OnlyMissing {
+			var existing models.VideoGeneration
+			if err := s.db.Where("storyboard_id = ? AND status IN ?", storyboard.ID, []models.VideoStatus{
+				models.VideoStatusPending, models.VideoStatusProcessing, models.VideoStatusCompleted,
+			}).Order("created_at DESC").First(&existing).Error; err == nil {
+				// 已有视频的分镜不再生成，链式模式下后续分镜接在它后面
+				prev = &existing
+				continue
+			}
+		}
+
+		var videoGen *models.VideoGeneration
+		if opts.Chained && prev != nil {
+			videoGen, err = s.generateChainedVideo(imageGen.ID, prev.ID, opts.Provider, opts.Model, jobOpts)
+		} else {
+			videoGen, err = s.generateVideoFromImageWithModel(imageGen.ID, opts.Provider, opts.Model, jobOpts)
+		}
 		if err != nil {
 			s.log.Errorw("Failed to generate video", "storyboard_id", storyboard.ID, "error", err)
+			prev = nil
 			continue
 		}
 
+		prev = videoGen
 		results = append(results, videoGen)
 	}
 
-	return results, nil
+	return batch, results, nil
+}
+
+// CancelVideoGeneration 取消视频生成：停止排队/轮询任务，并在厂商支持时取消远程任务
+func (s *VideoGenerationService) CancelVideoGeneration(id uint) error {
+	var videoGen models.VideoGeneration
+	if err := s.db.First(&videoGen, id).Error; err != nil {
+		return fmt.Errorf("video generation not found")
+	}
+	if videoGen.Status != models.VideoStatusPending && videoGen.Status != models.VideoStatusProcessing {
+		return fmt.Errorf("视频生成已结束，无法取消")
+	}
+
+	s.jobQueue.CancelResourceJobs(fmt.Sprintf("%d", id), "video_generation", "video_status_poll", "video_chain", "video_chain_frame")
+	s.markVideoGenCancelled(id)
+	return nil
+}
+
+// PauseEpisodeBatch 暂停剧集最近一次批量视频生成中尚未开始的任务
+func (s *VideoGenerationService) PauseEpisodeBatch(episodeID string) (*models.AsyncTask, error) {
+	batch, err := s.jobQueue.LatestParent("video_batch", episodeID, "processing")
+	if err != nil {
+		return nil, fmt.Errorf("没有进行中的批量任务")
+	}
+	if err := s.jobQueue.Pause(batch.ID); err != nil {
+		return nil, err
+	}
+	return batch, nil
+}
+
+// ResumeEpisodeBatch 恢复剧集已暂停的批量视频生成
+func (s *VideoGenerationService) ResumeEpisodeBatch(episodeID string) (*models.AsyncTask, error) {
+	batch, err := s.jobQueue.LatestParent("video_batch", episodeID, "paused")
+	if err != nil {
+		return nil, fmt.Errorf("没有已暂停的批量任务")
+	}
+	if err := s.jobQueue.Resume(batch.ID); err != nil {
+		return nil, err
+	}
+	return batch, nil
+}
+
+// handleVideoJobInterrupted 视频生成任务中断回调
+// 已拿到远程任务ID或尚未开始的记录可以重新执行（处理函数会续上轮询），提交中途被中断的记录无法确认厂商是否已受理，标记为失败
+func (s *VideoGenerationService) handleVideoJobInterrupted(task *models.AsyncTask) bool {
+	var videoGen models.VideoGeneration
+	if err := s.db.Where("id = ?", task.ResourceID).First(&videoGen).Error; err != nil {
+		return true
+	}
+	if videoGen.Status != models.VideoStatusProcessing || (videoGen.TaskID != nil && *videoGen.TaskID != "") {
+		return true
+	}
+	s.updateVideoGenError(videoGen.ID, InterruptedByRestartMessage)
+	return false
+}
+
+// handleVideoJobCancelled 队列任务取消回调
+func (s *VideoGenerationService) handleVideoJobCancelled(task *models.AsyncTask) {
+	id, err := strconv.ParseUint(task.ResourceID, 10, 32)
+	if err != nil {
+		return
+	}
+	s.markVideoGenCancelled(uint(id))
+}
+
+// markVideoGenCancelled 将未结束的视频生成标记为已取消，已提交的远程任务尝试一并取消
+func (s *VideoGenerationService) markVideoGenCancelled(id uint) {
+	var videoGen models.VideoGeneration
+	if err := s.db.First(&videoGen, id).Error; err != nil {
+		return
+	}
+
+	result := s.db.Model(&models.VideoGeneration{}).
+		Where("id = ? AND status IN ?", id, []models.VideoStatus{models.VideoStatusPending, models.VideoStatusProcessing}).
+		Updates(map[string]interface{}{
+			"status":    models.VideoStatusCancelled,
+			"error_msg": "cancelled by user",
+		})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return
+	}
+	s.log.Infow("Video generation cancelled", "id", id)
+	s.publishVideoEvent(id, EventVideoCancelled)
+
+	if videoGen.TaskID == nil || *videoGen.TaskID == "" {
+		return
+	}
+	client, err := s.getVideoClientForGeneration(&videoGen)
+	if err != nil {
+		return
+	}
+	s.cancelRemoteTask(client, id, *videoGen.TaskID)
+}
+
+// cancelRemoteTask 厂商支持时取消远程任务，不支持时远程任务会继续运行但结果会被丢弃
+func (s *VideoGenerationService) cancelRemoteTask(client video.VideoClient, videoGenID uint, taskID string) {
+	canceler, ok := client.(video.TaskCanceler)
+	if !ok {
+		s.log.Infow("Provider does not support remote cancel, result will be discarded", "id", videoGenID, "task_id", taskID)
+		return
+	}
+	if err := canceler.CancelTask(taskID); err != nil {
+		s.log.Warnw("Failed to cancel remote video task", "error", err, "id", videoGenID, "task_id", taskID)
+		return
+	}
+	s.log.Infow("Remote video task cancelled", "id", videoGenID, "task_id", taskID)
 }
 
 func (s *VideoGenerationService) DeleteVideoGeneration(id uint) error {
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -16,6 +17,23 @@ import (
 	"gorm.io/gorm"
 )
 
+// 远程合成任务的轮询间隔和超时
+const (
+	mergePollInterval = 5 * time.Second
+	mergePollTimeout  = 20 * time.Minute
+)
+
+// videoMergePayload 视频合成任务参数
+type videoMergePayload struct {
+	MergeID uint `json:"merge_id"`
+}
+
+// videoMergePollPayload 远程合成任务状态轮询参数
+type videoMergePollPayload struct {
+	MergeID uint   `json:"merge_id"`
+	TaskID  string `json:"task_id"`
+}
+
 type VideoMergeService struct {
 	db              *gorm.DB
 	aiService       *AIService
@@ -24,18 +42,30 @@ type VideoMergeService struct {
 	storagePath     string
 	baseURL         string
 	log             *logger.Logger
+	jobQueue        *JobQueue
+	eventBus        *EventBus
+	settings        *Settings
 }
 
-func NewVideoMergeService(db *gorm.DB, transferService *ResourceTransferService, storagePath, baseURL string, log *logger.Logger) *VideoMergeService {
-	return &VideoMergeService{
+func NewVideoMergeService(db *gorm.DB, jobQueue *JobQueue, eventBus *EventBus, settings *Settings, aiService *AIService, transferService *ResourceTransferService, storagePath, baseURL string, log *logger.Logger) *VideoMergeService {
+	service := &VideoMergeService{
 		db:              db,
-		aiService:       NewAIService(db, log),
+		aiService:       aiService,
 		transferService: transferService,
 		ffmpeg:          ffmpeg.NewFFmpeg(log),
 		storagePath:     storagePath,
 		baseURL:         baseURL,
 		log:             log,
+		jobQueue:        jobQueue,
+		eventBus:        eventBus,
+		settings:        settings,
 	}
+
+	service.jobQueue.RegisterHandler("video_merge", service.handleVideoMergeJob)
+	service.jobQueue.RegisterHandler("video_merge_poll", service.handleVideoMergePollJob)
+	service.jobQueue.RegisterStartupRecoverer("video_merges", service.RecoverPendingMerges)
+
+	return service
 }
 
 type MergeVideoRequest struct {
@@ -45,6 +75,22 @@ type MergeVideoRequest struct {
 	Scenes    []models.SceneClip `json:"scenes" binding:"required,min=1"`
 	Provider  string             `json:"provider"`
 	Model     string             `json:"model"`
+
+	// LoudnessProfile 响度标准化的输出档位，为空时使用配置的默认档位；Denoise 为空时使用配置的默认值
+	LoudnessProfile string `json:"loudness_profile"`
+	Denoise         *bool  `json:"denoise"`
+
+	// SkipBranding 不添加剧本的水印、片头片尾和标题卡
+	SkipBranding bool `json:"skip_branding"`
+
+	// 合成完成后需要生成的输出规格和 HLS，未指定时使用剧本的输出设置
+	OutputSelection
+
+	// 各片段统一转换到的帧率，以及是否放慢比分镜时长短的片段
+	FrameRateSelection
+
+	// 是否进行镜头配色匹配，以及匹配的参考镜头
+	ColorMatchSelection
 }
 
 func (s *VideoMergeService) MergeVideos(req *MergeVideoRequest) (*models.VideoMerge, error) {
@@ -66,6 +112,57 @@ func (s *VideoMergeService) MergeVideos(req *MergeVideoRequest) (*models.VideoMe
 		provider = "doubao"
 	}
 
+	loudnessProfile, denoise, err := s.settings.Mastering.mergeProfile(req.LoudnessProfile, req.Denoise)
+	if err != nil {
+		return nil, err
+	}
+
+	outputs, err := resolveOutputSettings(s.log, &episode.Drama, req.OutputSelection)
+	if err != nil {
+		return nil, err
+	}
+	var outputSettings []byte
+	if !outputs.Empty() {
+		if outputSettings, err = json.Marshal(outputs); err != nil {
+			return nil, fmt.Errorf("failed to serialize output settings: %w", err)
+		}
+	}
+
+	frameRate, err := s.settings.FrameRate.resolve(s.db, episode.ID, req.FrameRateSelection, outputs)
+	if err != nil {
+		return nil, err
+	}
+	var frameRateJSON []byte
+	if frameRate != nil {
+		if frameRateJSON, err = json.Marshal(frameRate); err != nil {
+			return nil, fmt.Errorf("failed to serialize frame rate: %w", err)
+		}
+	}
+
+	var brandingJSON []byte
+	if !req.SkipBranding {
+		branding, err := resolveMergeBranding(s.db, s.log, s.storagePath, &episode)
+		if err != nil {
+			return nil, err
+		}
+		if branding != nil {
+			if brandingJSON, err = json.Marshal(branding); err != nil {
+				return nil, fmt.Errorf("failed to serialize branding: %w", err)
+			}
+		}
+	}
+
+	var colorMatchJSON []byte
+	colorMatch, err := resolveMergeColorMatch(s.log, &episode, req.Scenes, req.ColorMatchSelection)
+	if err != nil {
+		return nil, err
+	}
+	if colorMatch != nil {
+		if colorMatchJSON, err = json.Marshal(colorMatch); err != nil {
+			return nil, fmt.Errorf("failed to serialize color match: %w", err)
+		}
+	}
+
 	// 序列化场景列表
 	scenesJSON, err := json.Marshal(req.Scenes)
 	if err != nil {
@@ -87,17 +184,49 @@ func (s *VideoMergeService) MergeVideos(req *MergeVideoRequest) (*models.VideoMe
 		Model:     &req.Model,
 		Scenes:    scenesJSON,
 		Status:    models.VideoMergeStatusPending,
+
+		LoudnessProfile: loudnessProfile,
+		Denoise:         denoise,
+		OutputSettings:  outputSettings,
+		Branding:        brandingJSON,
+		FrameRate:       frameRateJSON,
+		ColorMatch:      colorMatchJSON,
 	}
 
 	if err := s.db.Create(videoMerge).Error; err != nil {
 		return nil, fmt.Errorf("failed to create merge record: %w", err)
 	}
 
-	go s.processMergeVideo(videoMerge.ID)
+	if err := s.enqueueMerge(videoMerge); err != nil {
+		s.updateMergeError(videoMerge.ID, err.Error())
+		return nil, err
+	}
 
 	return videoMerge, nil
 }
 
+// enqueueMerge FFmpeg 合成是 CPU 密集型任务，放入 ffmpeg 工作池限制并发
+func (s *VideoMergeService) enqueueMerge(videoMerge *models.VideoMerge) error {
+	_, err := s.jobQueue.Enqueue("video_merge", fmt.Sprintf("%d", videoMerge.ID), JobOptions{
+		Queue:    JobQueueFFmpeg,
+		Priority: JobPriorityInteractive,
+		DramaID:  videoMerge.DramaID,
+		Payload:  videoMergePayload{MergeID: videoMerge.ID},
+	})
+	return err
+}
+
+// handleVideoMergeJob 任务队列处理函数
+// 本地合成可以安全重跑，中断后由队列重新排队即可
+func (s *VideoMergeService) handleVideoMergeJob(ctx context.Context, task *models.AsyncTask) error {
+	var payload videoMergePayload
+	if err := decodeJobPayload(task, &payload); err != nil {
+		return err
+	}
+	s.processMergeVideo(payload.MergeID)
+	return nil
+}
+
 func (s *VideoMergeService) processMergeVideo(mergeID uint) {
 	var videoMerge models.VideoMerge
 	if err := s.db.First(&videoMerge, mergeID).Error; err != nil {
@@ -105,7 +234,20 @@ func (s *VideoMergeService) processMergeVideo(mergeID uint) {
 		return
 	}
 
+	switch videoMerge.Status {
+	case models.VideoMergeStatusCompleted, models.VideoMergeStatusFailed:
+		s.log.Infow("Video merge already finished, skipping", "id", mergeID, "status", videoMerge.Status)
+		return
+	case models.VideoMergeStatusProcessing:
+		// 中断前已提交远程合成任务：继续轮询，不重复提交
+		if videoMerge.TaskID != nil && *videoMerge.TaskID != "" {
+			s.enqueueMergePoll(&videoMerge, *videoMerge.TaskID)
+			return
+		}
+	}
+
 	s.db.Model(&videoMerge).Update("status", models.VideoMergeStatusProcessing)
+	s.publishMergeEvent(mergeID, EventMergeProcessing)
 
 	client, err := s.getVideoClient(videoMerge.Provider)
 	if err != nil {
@@ -121,7 +263,7 @@ func (s *VideoMergeService) processMergeVideo(mergeID uint) {
 	}
 
 	// 调用视频合并API
-	result, err := s.mergeVideoClips(client, scenes)
+	result, err := s.mergeVideoClips(client, &videoMerge, scenes)
 	if err != nil {
 		s.updateMergeError(mergeID, err.Error())
 		return
@@ -132,14 +274,14 @@ func (s *VideoMergeService) processMergeVideo(mergeID uint) {
 			"status":  models.VideoMergeStatusProcessing,
 			"task_id": result.TaskID,
 		})
-		go s.pollMergeStatus(mergeID, client, result.TaskID)
+		s.enqueueMergePoll(&videoMerge, result.TaskID)
 		return
 	}
 
 	s.completeMerge(mergeID, result)
 }
 
-func (s *VideoMergeService) mergeVideoClips(client video.VideoClient, scenes []models.SceneClip) (*video.VideoResult, error) {
+func (s *VideoMergeService) mergeVideoClips(client video.VideoClient, videoMerge *models.VideoMerge, scenes []models.SceneClip) (*video.VideoResult, error) {
 	if len(scenes) == 0 {
 		return nil, fmt.Errorf("no scenes to merge")
 	}
@@ -157,6 +299,11 @@ func (s *VideoMergeService) mergeVideoClips(client video.VideoClient, scenes []m
 		totalDuration += scene.Duration
 	}
 
+	frameRate, err := mergeFrameRate(videoMerge)
+	if err != nil {
+		return nil, err
+	}
+
 	// 准备FFmpeg合成选项
 	clips := make([]ffmpeg.VideoClip, len(scenes))
 	for i, scene := range scenes {
@@ -172,6 +319,10 @@ func (s *VideoMergeService) mergeVideoClips(client video.VideoClient, scenes []m
 			EndTime:    scene.EndTime,
 			Transition: scene.Transition,
 		}
+		// 允许放慢时，比分镜时长短的片段放慢填满分镜时长
+		if frameRate != nil && frameRate.MaxStretch > 1 {
+			clips[i].TargetDuration = scene.Duration
+		}
 
 		s.log.Infow("Clip added to merge queue",
 			"order", scene.Order,
@@ -182,6 +333,23 @@ func (s *VideoMergeService) mergeVideoClips(client video.VideoClient, scenes []m
 			"end_time", scene.EndTime)
 	}
 
+	// 响度标准化：先按目标响度调整各片段增益，合成后再对成片做 loudnorm
+	ctx := context.Background()
+	var target *ffmpeg.LoudnessTarget
+	if videoMerge.LoudnessProfile != nil {
+		_, profileTarget, err := s.settings.Mastering.resolveProfile(*videoMerge.LoudnessProfile)
+		if err != nil {
+			return nil, err
+		}
+		target = &profileTarget
+		s.applyClipGains(ctx, scenes, clips, profileTarget)
+	}
+
+	// 配色匹配：按参考镜头统一各片段的亮度、对比度和色温，并套用剧本 LUT
+	if err := s.applyColorMatch(ctx, videoMerge, scenes, clips); err != nil {
+		return nil, err
+	}
+
 	// 创建视频输出目录
 	videoDir := filepath.Join(s.storagePath, "videos", "merged")
 	if err := os.MkdirAll(videoDir, 0755); err != nil {
@@ -192,10 +360,25 @@ func (s *VideoMergeService) mergeVideoClips(client video.VideoClient, scenes []m
 	fileName := fmt.Sprintf("merged_%d.mp4", time.Now().Unix())
 	outputPath := filepath.Join(videoDir, fileName)
 
+	var branding *mergeBranding
+	if len(videoMerge.Branding) > 0 {
+		branding = &mergeBranding{}
+		if err := json.Unmarshal(videoMerge.Branding, branding); err != nil {
+			return nil, fmt.Errorf("failed to parse branding: %w", err)
+		}
+	}
+
+	// 需要品牌包装或标准化时先合成到临时文件，处理后写入最终路径
+	mergeOutputPath := outputPath
+	if target != nil || branding != nil {
+		mergeOutputPath = filepath.Join(videoDir, fmt.Sprintf("premaster_%d.mp4", time.Now().UnixNano()))
+	}
+
 	// 使用FFmpeg合成视频
 	mergedPath, err := s.ffmpeg.MergeVideos(&ffmpeg.MergeOptions{
-		OutputPath: outputPath,
+		OutputPath: mergeOutputPath,
 		Clips:      clips,
+		FrameRate:  frameRate,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("ffmpeg merge failed: %w", err)
@@ -203,6 +386,27 @@ func (s *VideoMergeService) mergeVideoClips(client video.VideoClient, scenes []m
 
 	s.log.Infow("Video merged successfully", "path", mergedPath)
 
+	// 品牌包装在标准化之前，使片头片尾与正片一起标准化响度
+	if branding != nil {
+		brandedPath := outputPath
+		if target != nil {
+			brandedPath = filepath.Join(videoDir, fmt.Sprintf("branded_%d.mp4", time.Now().UnixNano()))
+		}
+		result, err := s.applyBranding(ctx, videoMerge, branding, mergedPath, brandedPath)
+		os.Remove(mergedPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply branding: %w", err)
+		}
+		mergedPath = brandedPath
+		totalDuration += result.IntroDuration + result.TitleDuration + result.OutroDuration
+	}
+
+	if target != nil {
+		if err := s.masterMergedVideo(ctx, videoMerge, *target, mergedPath, outputPath); err != nil {
+			return nil, fmt.Errorf("failed to save mastered video: %w", err)
+		}
+	}
+
 	// 生成相对路径（不包含协议、IP、端口）
 	relPath := filepath.Join("videos", "merged", fileName)
 
@@ -216,31 +420,99 @@ func (s *VideoMergeService) mergeVideoClips(client video.VideoClient, scenes []m
 	return result, nil
 }
 
-func (s *VideoMergeService) pollMergeStatus(mergeID uint, client video.VideoClient, taskID string) {
-	maxAttempts := 240
-	pollInterval := 5 * time.Second
+// enqueueMergePoll 创建远程合成任务的状态轮询任务
+func (s *VideoMergeService) enqueueMergePoll(videoMerge *models.VideoMerge, taskID string) {
+	_, err := s.jobQueue.Enqueue("video_merge_poll", fmt.Sprintf("%d", videoMerge.ID), JobOptions{
+		Queue:    JobQueueDefault,
+		Priority: JobPriorityInteractive,
+		DramaID:  videoMerge.DramaID,
+		Delay:    mergePollInterval,
+		Payload:  videoMergePollPayload{MergeID: videoMerge.ID, TaskID: taskID},
+	})
+	if err != nil {
+		s.log.Errorw("Failed to enqueue merge status poll", "error", err, "id", videoMerge.ID, "task_id", taskID)
+		s.updateMergeError(videoMerge.ID, err.Error())
+	}
+}
 
-	for i := 0; i < maxAttempts; i++ {
-		time.Sleep(pollInterval)
+// handleVideoMergePollJob 查询一次远程合成任务状态，未完成时重新排队
+func (s *VideoMergeService) handleVideoMergePollJob(ctx context.Context, task *models.AsyncTask) error {
+	var payload videoMergePollPayload
+	if err := decodeJobPayload(task, &payload); err != nil {
+		return err
+	}
 
-		result, err := client.GetTaskStatus(taskID)
-		if err != nil {
-			s.log.Errorw("Failed to get merge task status", "error", err, "task_id", taskID)
+	if time.Since(task.CreatedAt) > mergePollTimeout {
+		s.updateMergeError(payload.MergeID, "timeout: video merge took too long")
+		return nil
+	}
+
+	var videoMerge models.VideoMerge
+	if err := s.db.First(&videoMerge, payload.MergeID).Error; err != nil {
+		return fmt.Errorf("load video merge: %w", err)
+	}
+	if videoMerge.Status != models.VideoMergeStatusProcessing {
+		return nil
+	}
+
+	client, err := s.getVideoClient(videoMerge.Provider)
+	if err != nil {
+		s.updateMergeError(payload.MergeID, err.Error())
+		return nil
+	}
+
+	result, err := client.GetTaskStatus(payload.TaskID)
+	if err != nil {
+		s.log.Errorw("Failed to get merge task status", "error", err, "task_id", payload.TaskID)
+		return RescheduleJob(mergePollInterval)
+	}
+
+	if result.Completed {
+		s.completeMerge(payload.MergeID, result)
+		return nil
+	}
+
+	if result.Error != "" {
+		s.updateMergeError(payload.MergeID, result.Error)
+		return nil
+	}
+
+	return RescheduleJob(mergePollInterval)
+}
+
+// RecoverPendingMerges 启动时恢复没有队列任务跟踪的视频合成记录
+// 远程合成继续轮询，本地 FFmpeg 合成可以安全重跑，直接重新入队
+func (s *VideoMergeService) RecoverPendingMerges() {
+	var merges []models.VideoMerge
+	if err := s.db.Where("status IN ?", []models.VideoMergeStatus{models.VideoMergeStatusPending, models.VideoMergeStatusProcessing}).
+		Find(&merges).Error; err != nil {
+		s.log.Errorw("Failed to load pending video merges", "error", err)
+		return
+	}
+
+	resumed, requeued := 0, 0
+	for i := range merges {
+		videoMerge := &merges[i]
+		resourceID := fmt.Sprintf("%d", videoMerge.ID)
+		if s.jobQueue.HasActiveJob("video_merge", resourceID) || s.jobQueue.HasActiveJob("video_merge_poll", resourceID) {
 			continue
 		}
 
-		if result.Completed {
-			s.completeMerge(mergeID, result)
-			return
+		if videoMerge.Status == models.VideoMergeStatusProcessing && videoMerge.TaskID != nil && *videoMerge.TaskID != "" {
+			s.enqueueMergePoll(videoMerge, *videoMerge.TaskID)
+			resumed++
+			continue
 		}
-
-		if result.Error != "" {
-			s.updateMergeError(mergeID, result.Error)
-			return
+		if err := s.enqueueMerge(videoMerge); err != nil {
+			s.updateMergeError(videoMerge.ID, err.Error())
+			continue
 		}
+		requeued++
 	}
 
-	s.updateMergeError(mergeID, "timeout: video merge took too long")
+	if resumed+requeued > 0 {
+		s.log.Infow("Recovered video merges", "resumed_polling", resumed, "requeued", requeued)
+	}
 }
 
 func (s *VideoMergeService) completeMerge(mergeID uint, result *video.VideoResult) {
@@ -280,6 +552,19 @@ func (s *VideoMergeService) completeMerge(mergeID uint, result *video.VideoResul
 	}
 
 	s.log.Infow("Video merge completed", "id", mergeID, "url", finalVideoURL)
+	s.publishMergeEvent(mergeID, EventMergeCompleted)
+
+	if videoMerge.EpisodeID != 0 {
+		dispatchWebhookEvent(s.db, s.jobQueue, s.log, EventEpisodeFinalized, videoMerge.DramaID, map[string]interface{}{
+			"episode_id": videoMerge.EpisodeID,
+			"merge_id":   mergeID,
+			"video_url":  finalVideoURL,
+			"duration":   result.Duration,
+		})
+	}
+
+	scheduleEpisodeThumbnail(s.jobQueue, s.log, &videoMerge)
+	scheduleEpisodeRenditions(s.jobQueue, s.log, &videoMerge)
 }
 
 func (s *VideoMergeService) updateMergeError(mergeID uint, errorMsg string) {
@@ -288,6 +573,26 @@ func (s *VideoMergeService) updateMergeError(mergeID uint, errorMsg string) {
 		"error_msg": errorMsg,
 	})
 	s.log.Errorw("Video merge failed", "id", mergeID, "error", errorMsg)
+	s.publishMergeEvent(mergeID, EventMergeFailed)
+}
+
+// publishMergeEvent 发布视频合成状态变更事件
+func (s *VideoMergeService) publishMergeEvent(mergeID uint, eventType string) {
+	var videoMerge models.VideoMerge
+	if err := s.db.First(&videoMerge, mergeID).Error; err != nil {
+		return
+	}
+
+	data := map[string]interface{}{
+		"id":         videoMerge.ID,
+		"episode_id": videoMerge.EpisodeID,
+		"status":     videoMerge.Status,
+		"merged_url": videoMerge.MergedURL,
+		"duration":   videoMerge.Duration,
+		"error_msg":  videoMerge.ErrorMsg,
+	}
+	s.eventBus.Publish(eventType, videoMerge.DramaID, data)
+	dispatchWebhookEvent(s.db, s.jobQueue, s.log, eventType, videoMerge.DramaID, data)
 }
 
 func (s *VideoMergeService) getVideoClient(provider string) (video.VideoClient, error) {
@@ -404,8 +709,20 @@ func getAssetIDString(assetID interface{}) string {
 
 // FinalizeEpisodeRequest 完成剧集制作请求
 type FinalizeEpisodeRequest struct {
-	EpisodeID string         `json:"episode_id"`
-	Clips     []TimelineClip `json:"clips"`
+	EpisodeID       string         `json:"episode_id"`
+	Clips           []TimelineClip `json:"clips"`
+	LoudnessProfile string         `json:"loudness_profile"` // 响度标准化的输出档位，为空时使用默认档位
+	Denoise         *bool          `json:"denoise"`
+	SkipBranding    bool           `json:"skip_branding"` // 不添加剧本的水印、片头片尾和标题卡
+
+	// 需要生成的输出规格和 HLS，未指定时使用剧本的输出设置
+	OutputSelection
+
+	// 统一的帧率，未指定时使用时间线或输出规格的帧率
+	FrameRateSelection
+
+	// 镜头配色匹配，未指定时使用剧本的配色设置
+	ColorMatchSelection
 }
 
 // FinalizeEpisode 完成集数制作，根据时间线场景顺序合成最终视频
@@ -435,6 +752,7 @@ func (s *VideoMergeService) FinalizeEpisode(episodeID string, timelineData *Fina
 			// 优先使用素材库中的视频（通过AssetID）
 			var videoURL string
 			var sceneID uint
+			var assetID *uint
 
 			if assetIDStr != "" {
 				// 从素材库获取视频，优先使用 local_path
@@ -454,6 +772,7 @@ func (s *VideoMergeService) FinalizeEpisode(episodeID string, timelineData *Fina
 						videoURL = asset.URL
 						s.log.Infow("Using remote video from asset library", "asset_id", assetIDStr, "video_url", videoURL)
 					}
+					assetID = &asset.ID
 					// 如果asset关联了storyboard，使用关联的storyboard_id
 					if asset.StoryboardID != nil {
 						sceneID = *asset.StoryboardID
@@ -510,6 +829,7 @@ func (s *VideoMergeService) FinalizeEpisode(episodeID string, timelineData *Fina
 
 			sceneClip := models.SceneClip{
 				SceneID:    sceneID,
+				AssetID:    assetID,
 				VideoURL:   videoURL,
 				Duration:   clip.Duration,
 				Order:      clip.Order,
@@ -532,60 +852,18 @@ func (s *VideoMergeService) FinalizeEpisode(episodeID string, timelineData *Fina
 		}
 
 		order := 0
-		for _, scene := range episode.Storyboards {
-			// 优先从素材库查找该分镜关联的视频
+		for i := range episode.Storyboards {
+			scene := &episode.Storyboards[i]
 			var videoURL string
-			var asset models.Asset
-			if err := s.db.Where("storyboard_id = ? AND type = ? AND episode_id = ?",
-				scene.ID, models.AssetTypeVideo, episode.ID).
-				Order("created_at DESC").
-				First(&asset).Error; err == nil {
-				// 优先使用 local_path
-				if asset.LocalPath != nil && *asset.LocalPath != "" {
-					// 检查是否已经是完整路径
-					if filepath.IsAbs(*asset.LocalPath) || filepath.HasPrefix(*asset.LocalPath, s.storagePath) {
-						videoURL = *asset.LocalPath
-					} else {
-						videoURL = filepath.Join(s.storagePath, *asset.LocalPath)
-					}
-					s.log.Infow("Using local video from asset library for storyboard",
-						"storyboard_id", scene.ID,
-						"asset_id", asset.ID,
-						"local_path", videoURL)
-				} else {
-					videoURL = asset.URL
-					s.log.Infow("Using remote video from asset library for storyboard",
-						"storyboard_id", scene.ID,
-						"asset_id", asset.ID,
-						"video_url", videoURL)
-				}
-			} else {
-				// 如果素材库没有，查找 video_generation 记录
-				var videoGen models.VideoGeneration
-				if err := s.db.Where("storyboard_id = ? AND status = ?", scene.ID, "completed").Order("created_at DESC").First(&videoGen).Error; err == nil {
-					if videoGen.LocalPath != nil && *videoGen.LocalPath != "" {
-						// 检查是否已经是完整路径
-						if filepath.IsAbs(*videoGen.LocalPath) || filepath.HasPrefix(*videoGen.LocalPath, s.storagePath) {
-							videoURL = *videoGen.LocalPath
-						} else {
-							videoURL = filepath.Join(s.storagePath, *videoGen.LocalPath)
-						}
-						s.log.Infow("Using local video from video_generation for storyboard",
-							"storyboard_id", scene.ID,
-							"local_path", videoURL)
-					} else if scene.VideoURL != nil && *scene.VideoURL != "" {
-						videoURL = *scene.VideoURL
-						s.log.Infow("Using remote video from storyboard",
-							"storyboard_id", scene.ID,
-							"video_url", videoURL)
-					}
-				} else if scene.VideoURL != nil && *scene.VideoURL != "" {
-					// 最后回退到 storyboard 的 video_url
-					videoURL = *scene.VideoURL
-					s.log.Infow("Using fallback video from storyboard",
-						"storyboard_id", scene.ID,
-						"video_url", videoURL)
-				}
+			var assetID *uint
+			if take := findStoryboardTake(s.db, s.storagePath, episode.ID, scene); take != nil {
+				videoURL = take.Source
+				assetID = take.AssetID
+				s.log.Infow("Using video for storyboard",
+					"storyboard_id", scene.ID,
+					"asset_id", take.AssetID,
+					"video_gen_id", take.VideoGenID,
+					"video_url", videoURL)
 			}
 
 			// 跳过没有视频的场景
@@ -597,6 +875,7 @@ func (s *VideoMergeService) FinalizeEpisode(episodeID string, timelineData *Fina
 
 			clip := models.SceneClip{
 				SceneID:  scene.ID,
+				AssetID:  assetID,
 				VideoURL: videoURL,
 				Duration: float64(scene.Duration),
 				Order:    order,
@@ -621,6 +900,14 @@ func (s *VideoMergeService) FinalizeEpisode(episodeID string, timelineData *Fina
 		Scenes:    sceneClips,
 		Provider:  "doubao", // 默认使用doubao
 	}
+	if timelineData != nil {
+		finalReq.LoudnessProfile = timelineData.LoudnessProfile
+		finalReq.Denoise = timelineData.Denoise
+		finalReq.OutputSelection = timelineData.OutputSelection
+		finalReq.SkipBranding = timelineData.SkipBranding
+		finalReq.FrameRateSelection = timelineData.FrameRateSelection
+		finalReq.ColorMatchSelection = timelineData.ColorMatchSelection
+	}
 
 	// 执行视频合成
 	videoMerge, err := s.MergeVideos(finalReq)
@@ -0,0 +1,484 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	models "github.com/drama-generator/backend/domain/models"
+	"github.com/drama-generator/backend/infrastructure/external/ffmpeg"
+	"github.com/drama-generator/backend/pkg/logger"
+	"github.com/drama-generator/backend/pkg/tts"
+	"github.com/drama-generator/backend/pkg/utils"
+	"gorm.io/gorm"
+)
+
+// 配音语速范围
+const (
+	minVoiceSpeed = 0.5
+	maxVoiceSpeed = 2.0
+)
+
+// episodeVoicePayload 章节配音任务参数
+type episodeVoicePayload struct {
+	EpisodeID     uint   `json:"episode_id"`
+	StoryboardIDs []uint `json:"storyboard_ids,omitempty"`
+	Overwrite     bool   `json:"overwrite"`
+}
+
+// VoiceEpisodeRequest 章节配音，不指定分镜时为全部有对白的分镜配音
+// 默认跳过已完成配音的分镜，overwrite 为 true 时重新配音
+type VoiceEpisodeRequest struct {
+	StoryboardIDs []uint `json:"storyboard_ids"`
+	Overwrite     bool   `json:"overwrite"`
+}
+
+type SetCharacterVoiceRequest struct {
+	VoiceID    *string  `json:"voice_id"`
+	VoiceSpeed *float64 `json:"voice_speed"`
+}
+
+// volcengineTTSSettings 火山语音需要在配置的 settings 中提供 app_id
+type volcengineTTSSettings struct {
+	AppID   string `json:"app_id"`
+	Cluster string `json:"cluster"`
+}
+
+type VoiceService struct {
+	db          *gorm.DB
+	aiService   *AIService
+	taskService *TaskService
+	ffmpeg      *ffmpeg.FFmpeg
+	storagePath string
+	baseURL     string
+	log         *logger.Logger
+	jobQueue    *JobQueue
+}
+
+func NewVoiceService(db *gorm.DB, jobQueue *JobQueue, taskService *TaskService, aiService *AIService, storagePath, baseURL string, log *logger.Logger) *VoiceService {
+	service := &VoiceService{
+		db:          db,
+		aiService:   aiService,
+		taskService: taskService,
+		ffmpeg:      ffmpeg.NewFFmpeg(log),
+		storagePath: storagePath,
+		baseURL:     baseURL,
+		log:         log,
+		jobQueue:    jobQueue,
+	}
+
+	service.jobQueue.RegisterHandler("episode_voice", service.handleEpisodeVoiceJob)
+
+	return service
+}
+
+// SetCharacterVoice 为角色指定配音音色和语速，voice_id 传空字符串时恢复按声音描述自动选择
+func (s *VoiceService) SetCharacterVoice(characterID uint, req *SetCharacterVoiceRequest) (*models.Character, error) {
+	var character models.Character
+	if err := s.db.First(&character, characterID).Error; err != nil {
+		return nil, fmt.Errorf("character not found")
+	}
+
+	updates := map[string]interface{}{}
+	if req.VoiceID != nil {
+		voiceID := strings.TrimSpace(*req.VoiceID)
+		if voiceID == "" {
+			updates["voice_id"] = nil
+		} else {
+			updates["voice_id"] = voiceID
+		}
+	}
+	if req.VoiceSpeed != nil {
+		if *req.VoiceSpeed < minVoiceSpeed || *req.VoiceSpeed > maxVoiceSpeed {
+			return nil, fmt.Errorf("voice_speed must be between %.1f and %.1f", minVoiceSpeed, maxVoiceSpeed)
+		}
+		updates["voice_speed"] = *req.VoiceSpeed
+	}
+
+	if len(updates) > 0 {
+		if err := s.db.Model(&character).Updates(updates).Error; err != nil {
+			return nil, fmt.Errorf("failed to update character voice: %w", err)
+		}
+	}
+	if err := s.db.First(&character, characterID).Error; err != nil {
+		return nil, err
+	}
+	return &character, nil
+}
+
+// VoiceEpisode 创建章节配音任务：对白按说话人拆分，每句台词生成一个音频素材
+func (s *VoiceService) VoiceEpisode(episodeID uint, req *VoiceEpisodeRequest) (*models.AsyncTask, error) {
+	var episode models.Episode
+	if err := s.db.First(&episode, episodeID).Error; err != nil {
+		return nil, fmt.Errorf("episode not found")
+	}
+	if s.jobQueue.HasActiveJob("episode_voice", fmt.Sprintf("%d", episodeID)) {
+		return nil, fmt.Errorf("episode is already being voiced")
+	}
+
+	config, err := s.aiService.GetDefaultConfig("tts")
+	if err != nil {
+		return nil, fmt.Errorf("no tts AI config found: %w", err)
+	}
+
+	task, err := s.jobQueue.Enqueue("episode_voice", fmt.Sprintf("%d", episodeID), JobOptions{
+		Queue:    JobQueueAudio,
+		Provider: config.Provider,
+		Priority: JobPriorityInteractive,
+		DramaID:  episode.DramaID,
+		Payload: episodeVoicePayload{
+			EpisodeID:     episodeID,
+			StoryboardIDs: req.StoryboardIDs,
+			Overwrite:     req.Overwrite,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.log.Infow("Episode voice queued", "episode_id", episodeID, "task_id", task.ID)
+	return task, nil
+}
+
+// ListDialogueLines 获取章节的台词及配音，按分镜顺序排列
+func (s *VoiceService) ListDialogueLines(episodeID uint) ([]models.DialogueLine, error) {
+	var lines []models.DialogueLine
+	err := s.db.Preload("Asset").
+		Joins("JOIN storyboards ON storyboards.id = dialogue_lines.storyboard_id AND storyboards.deleted_at IS NULL").
+		Where("dialogue_lines.episode_id = ?", episodeID).
+		Order("storyboards.storyboard_number ASC, dialogue_lines.line_index ASC").
+		Find(&lines).Error
+	if err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+// handleEpisodeVoiceJob 任务队列处理函数
+// 已完成的分镜会被跳过，临时错误返回给队列重试时从中断的分镜继续
+func (s *VoiceService) handleEpisodeVoiceJob(ctx context.Context, task *models.AsyncTask) error {
+	var payload episodeVoicePayload
+	if err := decodeJobPayload(task, &payload); err != nil {
+		return err
+	}
+
+	var episode models.Episode
+	if err := s.db.First(&episode, payload.EpisodeID).Error; err != nil {
+		s.taskService.UpdateTaskError(task.ID, fmt.Errorf("episode not found"))
+		return nil
+	}
+
+	config, err := s.aiService.GetDefaultConfig("tts")
+	if err != nil {
+		s.taskService.UpdateTaskError(task.ID, fmt.Errorf("no tts AI config found: %w", err))
+		return nil
+	}
+	model := ""
+	if len(config.Model) > 0 {
+		model = config.Model[0]
+	}
+	client, err := newTTSClient(config, model)
+	if err != nil {
+		s.taskService.UpdateTaskError(task.ID, err)
+		return nil
+	}
+
+	var characters []models.Character
+	if err := s.db.Where("drama_id = ?", episode.DramaID).Find(&characters).Error; err != nil {
+		return err
+	}
+
+	query := s.db.Preload("Characters").
+		Where("episode_id = ? AND dialogue IS NOT NULL AND dialogue != ''", episode.ID)
+	if len(payload.StoryboardIDs) > 0 {
+		query = query.Where("id IN ?", payload.StoryboardIDs)
+	}
+	var storyboards []models.Storyboard
+	if err := query.Order("storyboard_number ASC").Find(&storyboards).Error; err != nil {
+		return err
+	}
+
+	voiced, skipped, failed := 0, 0, 0
+	for i := range storyboards {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		storyboard := &storyboards[i]
+		s.taskService.UpdateTaskStatus(task.ID, "processing", i*100/len(storyboards),
+			fmt.Sprintf("正在为镜头%d配音 (%d/%d)", storyboard.StoryboardNumber, i+1, len(storyboards)))
+
+		if !payload.Overwrite && s.isStoryboardVoiced(storyboard.ID) {
+			skipped++
+			continue
+		}
+
+		lines, err := s.voiceStoryboard(client, config.Provider, &episode, storyboard, characters)
+		if err != nil {
+			if utils.IsTransientError(err) {
+				return err
+			}
+			s.log.Errorw("Failed to voice storyboard", "error", err, "storyboard_id", storyboard.ID)
+		}
+		storyboardVoiced := false
+		for _, line := range lines {
+			if line.Status == models.DialogueLineStatusCompleted {
+				voiced++
+				storyboardVoiced = true
+			} else {
+				failed++
+			}
+		}
+		// 配音变化后，已生成视频的分镜重新同步口型
+		if storyboardVoiced {
+			scheduleStoryboardLipSync(s.db, s.jobQueue, s.log, storyboard.ID)
+		}
+	}
+
+	if voiced == 0 && failed > 0 {
+		s.taskService.UpdateTaskError(task.ID, fmt.Errorf("all %d dialogue lines failed to synthesize", failed))
+		return nil
+	}
+
+	s.taskService.UpdateTaskResult(task.ID, map[string]interface{}{
+		"episode_id": episode.ID,
+		"voiced":     voiced,
+		"failed":     failed,
+		"skipped":    skipped,
+	})
+	return nil
+}
+
+// isStoryboardVoiced 分镜已有台词且全部配音完成
+func (s *VoiceService) isStoryboardVoiced(storyboardID uint) bool {
+	var total, completed int64
+	s.db.Model(&models.DialogueLine{}).Where("storyboard_id = ?", storyboardID).Count(&total)
+	s.db.Model(&models.DialogueLine{}).
+		Where("storyboard_id = ? AND status = ?", storyboardID, models.DialogueLineStatusCompleted).
+		Count(&completed)
+	return total > 0 && total == completed
+}
+
+// voiceStoryboard 拆分分镜对白并逐句合成，替换该分镜原有的台词记录
+// 遇到临时错误时立即返回，由任务重试
+func (s *VoiceService) voiceStoryboard(client tts.TTSClient, provider string, episode *models.Episode,
+	storyboard *models.Storyboard, characters []models.Character) ([]models.DialogueLine, error) {
+	names := make([]string, 0, len(characters))
+	for _, character := range characters {
+		names = append(names, character.Name)
+	}
+	parsed := tts.ParseDialogue(*storyboard.Dialogue, names)
+
+	if err := s.db.Where("storyboard_id = ?", storyboard.ID).Delete(&models.DialogueLine{}).Error; err != nil {
+		return nil, fmt.Errorf("failed to clear dialogue lines: %w", err)
+	}
+
+	lines := make([]models.DialogueLine, 0, len(parsed))
+	for index, parsedLine := range parsed {
+		character := matchSpeaker(parsedLine.Speaker, characters, storyboard.Characters)
+		voice, speed := speakerVoice(provider, parsedLine.Speaker, character)
+
+		line := models.DialogueLine{
+			EpisodeID:    episode.ID,
+			StoryboardID: storyboard.ID,
+			LineIndex:    index,
+			Speaker:      parsedLine.Speaker,
+			Text:         parsedLine.Text,
+			Voice:        voice,
+			Status:       models.DialogueLineStatusPending,
+		}
+		if character != nil {
+			line.CharacterID = &character.ID
+			if line.Speaker == "" {
+				line.Speaker = character.Name
+			}
+		}
+		if err := s.db.Create(&line).Error; err != nil {
+			return lines, fmt.Errorf("failed to create dialogue line: %w", err)
+		}
+
+		opts := []tts.TTSOption{tts.WithSpeed(speed)}
+		if voice != "" {
+			opts = append(opts, tts.WithVoice(voice))
+		}
+		result, err := client.Synthesize(line.Text, opts...)
+		if err == nil {
+			err = s.saveLineAudio(episode, storyboard, &line, result)
+		}
+		if err != nil {
+			errMsg := err.Error()
+			line.Status = models.DialogueLineStatusFailed
+			line.ErrorMessage = &errMsg
+			s.db.Model(&line).Updates(map[string]interface{}{"status": line.Status, "error_message": errMsg})
+			lines = append(lines, line)
+			if utils.IsTransientError(err) {
+				return lines, err
+			}
+			s.log.Warnw("Failed to synthesize dialogue line", "error", err, "storyboard_id", storyboard.ID, "line", index)
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	s.log.Infow("Storyboard voiced", "storyboard_id", storyboard.ID, "lines", len(lines))
+	return lines, nil
+}
+
+// saveLineAudio 保存合成的音频并创建音频素材，时长优先使用 ffprobe 实测值
+func (s *VoiceService) saveLineAudio(episode *models.Episode, storyboard *models.Storyboard, line *models.DialogueLine, result *tts.TTSResult) error {
+	format := result.Format
+	if format == "" {
+		format = "mp3"
+	}
+
+	audioDir := filepath.Join(s.storagePath, "audio", "voice", fmt.Sprintf("episode_%d", episode.ID))
+	if err := os.MkdirAll(audioDir, 0755); err != nil {
+		return fmt.Errorf("failed to create audio directory: %w", err)
+	}
+	fileName := fmt.Sprintf("storyboard_%d_line_%d_%d.%s", storyboard.ID, line.LineIndex, time.Now().Unix(), format)
+	filePath := filepath.Join(audioDir, fileName)
+	if err := os.WriteFile(filePath, result.Audio, 0644); err != nil {
+		return fmt.Errorf("failed to save audio: %w", err)
+	}
+
+	duration := result.Duration
+	if seconds, err := s.ffmpeg.GetVideoDuration(filePath); err == nil {
+		duration = int(math.Round(seconds * 1000))
+	} else if wavDuration := tts.WAVDuration(result.Audio); wavDuration > 0 {
+		duration = wavDuration
+	}
+	if duration <= 0 {
+		duration = tts.EstimateSpeechDuration(line.Text, 1)
+	}
+
+	// 只保存相对路径
+	relPath := filepath.ToSlash(filepath.Join("audio", "voice", fmt.Sprintf("episode_%d", episode.ID), fileName))
+	durationSeconds := int(math.Ceil(float64(duration) / 1000))
+	size := int64(len(result.Audio))
+	mimeType := audioMimeType(format)
+	category := models.AssetCategoryVoice
+	name := fmt.Sprintf("镜头%d 台词%d", storyboard.StoryboardNumber, line.LineIndex+1)
+	if line.Speaker != "" {
+		name = fmt.Sprintf("%s %s", name, line.Speaker)
+	}
+	asset := &models.Asset{
+		DramaID:       &episode.DramaID,
+		EpisodeID:     &episode.ID,
+		StoryboardID:  &storyboard.ID,
+		StoryboardNum: &storyboard.StoryboardNumber,
+		Name:          name,
+		Description:   &line.Text,
+		Type:          models.AssetTypeAudio,
+		Category:      &category,
+		URL:           fmt.Sprintf("%s/%s", s.baseURL, relPath),
+		LocalPath:     &relPath,
+		FileSize:      &size,
+		MimeType:      &mimeType,
+		Duration:      &durationSeconds,
+		DurationMs:    &duration,
+		Format:        &format,
+	}
+	if err := s.db.Create(asset).Error; err != nil {
+		os.Remove(filePath)
+		return fmt.Errorf("failed to create asset: %w", err)
+	}
+
+	line.AssetID = &asset.ID
+	line.Duration = duration
+	line.Status = models.DialogueLineStatusCompleted
+	return s.db.Model(line).Updates(map[string]interface{}{
+		"asset_id":      asset.ID,
+		"duration":      duration,
+		"status":        line.Status,
+		"error_message": nil,
+	}).Error
+}
+
+// matchSpeaker 按名字匹配说话人对应的角色；未标注说话人且分镜只有一个角色时视为该角色的独白
+func matchSpeaker(speaker string, characters, storyboardCharacters []models.Character) *models.Character {
+	if speaker == tts.NarratorSpeaker {
+		return nil
+	}
+	if speaker == "" {
+		if len(storyboardCharacters) == 1 {
+			return &storyboardCharacters[0]
+		}
+		return nil
+	}
+	for i := range characters {
+		if characters[i].Name == speaker {
+			return &characters[i]
+		}
+	}
+	// 对白中可能只写名或全名，退而做包含匹配
+	for i := range characters {
+		if strings.Contains(characters[i].Name, speaker) || strings.Contains(speaker, characters[i].Name) {
+			return &characters[i]
+		}
+	}
+	return nil
+}
+
+// speakerVoice 角色指定的音色优先，否则按声音描述选择预设音色；旁白和未知说话人使用旁白音色
+func speakerVoice(provider, speaker string, character *models.Character) (string, float64) {
+	if character == nil {
+		return tts.NarratorVoice(provider), 1
+	}
+
+	speed := 1.0
+	if character.VoiceSpeed != nil && *character.VoiceSpeed > 0 {
+		speed = *character.VoiceSpeed
+	}
+	if character.VoiceID != nil && *character.VoiceID != "" {
+		return *character.VoiceID, speed
+	}
+	style := ""
+	if character.VoiceStyle != nil {
+		style = *character.VoiceStyle
+	}
+	return tts.DefaultVoice(provider, style), speed
+}
+
+// newTTSClient 根据配置中的 provider 创建对应的语音合成客户端，未知厂商按 OpenAI 兼容接口处理
+func newTTSClient(config *models.AIServiceConfig, model string) (tts.TTSClient, error) {
+	switch config.Provider {
+	case "doubao", "volcengine", "volces":
+		var settings volcengineTTSSettings
+		if config.Settings != "" {
+			if err := json.Unmarshal([]byte(config.Settings), &settings); err != nil {
+				return nil, fmt.Errorf("invalid volcengine tts settings: %w", err)
+			}
+		}
+		if settings.AppID == "" {
+			return nil, fmt.Errorf("volcengine tts requires app_id in settings")
+		}
+		return tts.NewVolcengineTTSClient(config.BaseURL, settings.AppID, config.APIKey, settings.Cluster), nil
+	case "minimax":
+		return tts.NewMinimaxTTSClient(config.BaseURL, config.APIKey, model), nil
+	case "local":
+		return tts.NewLocalTTSClient(), nil
+	default:
+		return tts.NewOpenAITTSClient(config.BaseURL, config.APIKey, model, config.Endpoint), nil
+	}
+}
+
+func audioMimeType(format string) string {
+	switch format {
+	case "wav":
+		return "audio/wav"
+	case "ogg", "opus":
+		return "audio/ogg"
+	case "aac":
+		return "audio/aac"
+	case "flac":
+		return "audio/flac"
+	case "pcm":
+		return "audio/pcm"
+	default:
+		return "audio/mpeg"
+	}
+}
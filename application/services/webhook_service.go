@@ -0,0 +1,509 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/drama-generator/backend/domain/models"
+	"github.com/drama-generator/backend/pkg/config"
+	"github.com/drama-generator/backend/pkg/logger"
+	"github.com/drama-generator/backend/pkg/utils"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// 只在剧集成片合成完成时触发，不进入SSE事件总线
+const EventEpisodeFinalized = "episode.finalized"
+
+// WebhookEvents 可以订阅的 webhook 事件类型
+var WebhookEvents = []string{
+	EventImageCompleted,
+	EventImageFailed,
+	EventVideoCompleted,
+	EventVideoFailed,
+	EventMergeCompleted,
+	EventMergeFailed,
+	EventTaskCompleted,
+	EventTaskFailed,
+	EventEpisodeFinalized,
+}
+
+const (
+	// webhookMaxAttempts 单次投递的最大尝试次数，按队列退避策略（5s 起指数增长，最长 5 分钟）重试
+	webhookMaxAttempts = 8
+	// webhookTimeout 单次请求超时
+	webhookTimeout = 10 * time.Second
+	// webhookResponseLimit 投递记录中保存的响应体长度上限
+	webhookResponseLimit = 2048
+	// webhookResolveTimeout 保存订阅时解析地址的超时
+	webhookResolveTimeout = 5 * time.Second
+)
+
+// webhook 请求头
+const (
+	WebhookHeaderEvent     = "X-Webhook-Event"
+	WebhookHeaderDelivery  = "X-Webhook-Delivery"
+	WebhookHeaderTimestamp = "X-Webhook-Timestamp"
+	WebhookHeaderSignature = "X-Webhook-Signature"
+)
+
+type WebhookService struct {
+	db         *gorm.DB
+	log        *logger.Logger
+	jobQueue   *JobQueue
+	hostGuard  *utils.HostGuard
+	httpClient *http.Client
+}
+
+// NewWebhookService 投递只连接校验过的地址，不经过环境变量中的代理，重定向同样经过校验
+func NewWebhookService(db *gorm.DB, jobQueue *JobQueue, cfg config.WebhookConfig, log *logger.Logger) *WebhookService {
+	hostGuard := utils.NewHostGuard(cfg.AllowedHosts)
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = hostGuard.DialContext
+
+	service := &WebhookService{
+		db:         db,
+		log:        log,
+		jobQueue:   jobQueue,
+		hostGuard:  hostGuard,
+		httpClient: &http.Client{Timeout: webhookTimeout, Transport: transport},
+	}
+
+	service.jobQueue.RegisterHandler("webhook_delivery", service.handleWebhookDeliveryJob)
+
+	return service
+}
+
+type CreateWebhookRequest struct {
+	Name        string   `json:"name"`
+	URL         string   `json:"url" binding:"required"`
+	Secret      string   `json:"secret"` // 为空时自动生成
+	Events      []string `json:"events" binding:"required,min=1"`
+	DramaID     *uint    `json:"drama_id"`
+	Description *string  `json:"description"`
+}
+
+type UpdateWebhookRequest struct {
+	Name        *string  `json:"name"`
+	URL         *string  `json:"url"`
+	Secret      *string  `json:"secret"`
+	Events      []string `json:"events"`
+	DramaID     *uint    `json:"drama_id"`
+	IsActive    *bool    `json:"is_active"`
+	Description *string  `json:"description"`
+}
+
+// CreateSubscription 创建 webhook 订阅，返回订阅和签名密钥（密钥之后不再返回）
+func (s *WebhookService) CreateSubscription(req *CreateWebhookRequest) (*models.WebhookSubscription, string, error) {
+	if err := s.validateWebhookURL(req.URL); err != nil {
+		return nil, "", err
+	}
+	if err := validateWebhookEvents(req.Events); err != nil {
+		return nil, "", err
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		generated, err := generateWebhookSecret()
+		if err != nil {
+			return nil, "", err
+		}
+		secret = generated
+	}
+
+	subscription := &models.WebhookSubscription{
+		Name:        req.Name,
+		URL:         req.URL,
+		Secret:      secret,
+		Events:      req.Events,
+		DramaID:     req.DramaID,
+		IsActive:    true,
+		Description: req.Description,
+	}
+	if err := s.db.Create(subscription).Error; err != nil {
+		return nil, "", fmt.Errorf("failed to create webhook: %w", err)
+	}
+
+	s.log.Infow("Webhook subscription created", "id", subscription.ID, "url", subscription.URL, "events", req.Events)
+	return subscription, secret, nil
+}
+
+// ListSubscriptions 获取 webhook 订阅列表
+func (s *WebhookService) ListSubscriptions() ([]models.WebhookSubscription, error) {
+	var subscriptions []models.WebhookSubscription
+	if err := s.db.Order("created_at DESC").Find(&subscriptions).Error; err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+// GetSubscription 获取 webhook 订阅
+func (s *WebhookService) GetSubscription(id uint) (*models.WebhookSubscription, error) {
+	var subscription models.WebhookSubscription
+	if err := s.db.First(&subscription, id).Error; err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// UpdateSubscription 更新 webhook 订阅
+func (s *WebhookService) UpdateSubscription(id uint, req *UpdateWebhookRequest) (*models.WebhookSubscription, error) {
+	subscription, err := s.GetSubscription(id)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{}
+	if req.Name != nil {
+		updates["name"] = *req.Name
+	}
+	if req.URL != nil {
+		if err := s.validateWebhookURL(*req.URL); err != nil {
+			return nil, err
+		}
+		updates["url"] = *req.URL
+	}
+	if req.Secret != nil && *req.Secret != "" {
+		updates["secret"] = *req.Secret
+	}
+	if req.Events != nil {
+		if err := validateWebhookEvents(req.Events); err != nil {
+			return nil, err
+		}
+		updates["events"] = models.StringList(req.Events)
+	}
+	if req.DramaID != nil {
+		// drama_id 传 0 表示取消剧目过滤
+		if *req.DramaID == 0 {
+			updates["drama_id"] = nil
+		} else {
+			updates["drama_id"] = *req.DramaID
+		}
+	}
+	if req.IsActive != nil {
+		updates["is_active"] = *req.IsActive
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+
+	if len(updates) > 0 {
+		if err := s.db.Model(subscription).Updates(updates).Error; err != nil {
+			return nil, err
+		}
+	}
+	return s.GetSubscription(id)
+}
+
+// DeleteSubscription 删除 webhook 订阅，尚未投递成功的记录不再重试
+func (s *WebhookService) DeleteSubscription(id uint) error {
+	result := s.db.Delete(&models.WebhookSubscription{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ListDeliveries 分页获取订阅的投递记录
+func (s *WebhookService) ListDeliveries(subscriptionID uint, status string, page, pageSize int) ([]models.WebhookDelivery, int64, error) {
+	query := s.db.Model(&models.WebhookDelivery{}).Where("subscription_id = ?", subscriptionID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var deliveries []models.WebhookDelivery
+	if err := query.Order("created_at DESC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&deliveries).Error; err != nil {
+		return nil, 0, err
+	}
+	return deliveries, total, nil
+}
+
+// GetDelivery 获取投递记录
+func (s *WebhookService) GetDelivery(id uint) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	if err := s.db.First(&delivery, id).Error; err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// Redeliver 重新投递：复制原事件生成新的投递记录，事件ID保持不变
+func (s *WebhookService) Redeliver(deliveryID uint) (*models.WebhookDelivery, error) {
+	original, err := s.GetDelivery(deliveryID)
+	if err != nil {
+		return nil, err
+	}
+
+	subscription, err := s.GetSubscription(original.SubscriptionID)
+	if err != nil {
+		return nil, fmt.Errorf("webhook 订阅不存在或已删除")
+	}
+
+	delivery := &models.WebhookDelivery{
+		SubscriptionID: subscription.ID,
+		EventID:        original.EventID,
+		EventType:      original.EventType,
+		Payload:        original.Payload,
+		Status:         models.WebhookDeliveryPending,
+		RedeliveryOf:   &original.ID,
+	}
+	if err := enqueueWebhookDelivery(s.db, s.jobQueue, subscription, delivery); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+type webhookDeliveryPayload struct {
+	DeliveryID uint `json:"delivery_id"`
+}
+
+// handleWebhookDeliveryJob 任务队列处理函数，失败时返回错误由队列按退避策略重试
+func (s *WebhookService) handleWebhookDeliveryJob(ctx context.Context, task *models.AsyncTask) error {
+	var payload webhookDeliveryPayload
+	if err := decodeJobPayload(task, &payload); err != nil {
+		return err
+	}
+
+	delivery, err := s.GetDelivery(payload.DeliveryID)
+	if err != nil {
+		s.log.Warnw("Webhook delivery not found", "delivery_id", payload.DeliveryID)
+		return nil
+	}
+	if delivery.Status != models.WebhookDeliveryPending {
+		return nil
+	}
+
+	subscription, err := s.GetSubscription(delivery.SubscriptionID)
+	if err != nil {
+		s.finishDelivery(delivery, models.WebhookDeliveryFailed, "webhook 订阅已删除")
+		return nil
+	}
+
+	statusCode, body, duration, sendErr := s.send(ctx, subscription, delivery)
+
+	updates := map[string]interface{}{
+		"attempts":    gorm.Expr("attempts + 1"),
+		"duration_ms": duration.Milliseconds(),
+	}
+	if statusCode > 0 {
+		updates["response_status"] = statusCode
+		updates["response_body"] = body
+	}
+	if sendErr == nil {
+		now := time.Now()
+		updates["status"] = models.WebhookDeliverySuccess
+		updates["error_msg"] = nil
+		updates["delivered_at"] = &now
+		s.db.Model(&models.WebhookDelivery{}).Where("id = ?", delivery.ID).Updates(updates)
+		s.log.Infow("Webhook delivered", "delivery_id", delivery.ID, "event", delivery.EventType, "url", subscription.URL, "status", statusCode)
+		return nil
+	}
+
+	updates["error_msg"] = sendErr.Error()
+	if task.Attempts >= task.MaxAttempts {
+		updates["status"] = models.WebhookDeliveryFailed
+	}
+	s.db.Model(&models.WebhookDelivery{}).Where("id = ?", delivery.ID).Updates(updates)
+	s.log.Warnw("Webhook delivery failed", "delivery_id", delivery.ID, "event", delivery.EventType, "url", subscription.URL, "attempt", task.Attempts, "error", sendErr)
+	return sendErr
+}
+
+// send 发送签名后的事件，2xx 视为投递成功
+func (s *WebhookService) send(ctx context.Context, subscription *models.WebhookSubscription, delivery *models.WebhookDelivery) (int, string, time.Duration, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	body := []byte(delivery.Payload)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", 0, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "drama-generator-webhook/1.0")
+	req.Header.Set(WebhookHeaderEvent, delivery.EventType)
+	req.Header.Set(WebhookHeaderDelivery, delivery.EventID)
+	req.Header.Set(WebhookHeaderTimestamp, timestamp)
+	req.Header.Set(WebhookHeaderSignature, "sha256="+SignWebhookPayload(subscription.Secret, timestamp, body))
+
+	start := time.Now()
+	resp, err := s.httpClient.Do(req)
+	duration := time.Since(start)
+	if err != nil {
+		return 0, "", duration, fmt.Errorf("send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, webhookResponseLimit))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, string(respBody), duration, fmt.Errorf("webhook endpoint returned status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, string(respBody), duration, nil
+}
+
+func (s *WebhookService) finishDelivery(delivery *models.WebhookDelivery, status models.WebhookDeliveryStatus, errorMsg string) {
+	s.db.Model(&models.WebhookDelivery{}).Where("id = ?", delivery.ID).Updates(map[string]interface{}{
+		"status":    status,
+		"error_msg": errorMsg,
+	})
+}
+
+// SignWebhookPayload 计算 webhook 签名：HMAC-SHA256(secret, timestamp + "." + body) 的十六进制字符串
+// 接收方用相同方式计算并与 X-Webhook-Signature 中 sha256= 之后的部分做常量时间比较，同时校验时间戳防重放
+func SignWebhookPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// dispatchWebhookEvent 为订阅了该事件的 webhook 创建投递记录并加入队列，非 webhook 事件直接忽略
+// 在写入终态的位置调用，投递本身异步进行，不阻塞业务流程
+func dispatchWebhookEvent(db *gorm.DB, jobQueue *JobQueue, log *logger.Logger, eventType string, dramaID uint, data interface{}) {
+	if !slices.Contains(WebhookEvents, eventType) {
+		return
+	}
+
+	var subscriptions []models.WebhookSubscription
+	if err := db.Where("is_active = ?", true).Find(&subscriptions).Error; err != nil {
+		log.Warnw("Failed to load webhook subscriptions", "error", err, "event", eventType)
+		return
+	}
+
+	var matched []*models.WebhookSubscription
+	for i := range subscriptions {
+		subscription := &subscriptions[i]
+		if !slices.Contains(subscription.Events, eventType) {
+			continue
+		}
+		if subscription.DramaID != nil && *subscription.DramaID != dramaID {
+			continue
+		}
+		matched = append(matched, subscription)
+	}
+	if len(matched) == 0 {
+		return
+	}
+
+	eventID := uuid.New().String()
+	body, err := json.Marshal(map[string]interface{}{
+		"id":         eventID,
+		"type":       eventType,
+		"drama_id":   dramaID,
+		"created_at": time.Now(),
+		"data":       data,
+	})
+	if err != nil {
+		log.Warnw("Failed to marshal webhook event", "error", err, "event", eventType)
+		return
+	}
+
+	for _, subscription := range matched {
+		delivery := &models.WebhookDelivery{
+			SubscriptionID: subscription.ID,
+			EventID:        eventID,
+			EventType:      eventType,
+			Payload:        string(body),
+			Status:         models.WebhookDeliveryPending,
+		}
+		if err := enqueueWebhookDelivery(db, jobQueue, subscription, delivery); err != nil {
+			log.Warnw("Failed to enqueue webhook delivery", "error", err, "subscription_id", subscription.ID, "event", eventType)
+		}
+	}
+}
+
+// dispatchTaskWebhook 读取任务最新状态并触发任务 webhook 事件
+// webhook 投递任务本身不触发，避免投递失败的事件再生成新的投递
+func dispatchTaskWebhook(db *gorm.DB, jobQueue *JobQueue, log *logger.Logger, taskID string, eventType string) {
+	var task models.AsyncTask
+	if err := db.Where("id = ?", taskID).First(&task).Error; err != nil {
+		return
+	}
+	if task.Type == "webhook_delivery" {
+		return
+	}
+	dispatchWebhookEvent(db, jobQueue, log, eventType, task.DramaID, taskEventData(&task))
+}
+
+func enqueueWebhookDelivery(db *gorm.DB, jobQueue *JobQueue, subscription *models.WebhookSubscription, delivery *models.WebhookDelivery) error {
+	if err := db.Create(delivery).Error; err != nil {
+		return fmt.Errorf("failed to create webhook delivery: %w", err)
+	}
+
+	var dramaID uint
+	if subscription.DramaID != nil {
+		dramaID = *subscription.DramaID
+	}
+	_, err := jobQueue.Enqueue("webhook_delivery", fmt.Sprintf("%d", delivery.ID), JobOptions{
+		Queue:       JobQueueDefault,
+		DramaID:     dramaID,
+		MaxAttempts: webhookMaxAttempts,
+		Payload:     webhookDeliveryPayload{DeliveryID: delivery.ID},
+	})
+	if err != nil {
+		db.Model(delivery).Updates(map[string]interface{}{
+			"status":    models.WebhookDeliveryFailed,
+			"error_msg": err.Error(),
+		})
+		return err
+	}
+	return nil
+}
+
+// validateWebhookURL 校验地址格式，并解析主机名拒绝回环、内网和链路本地地址（配置 allowed_hosts 的除外）
+func (s *WebhookService) validateWebhookURL(rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Hostname() == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return fmt.Errorf("无效的 webhook 地址: %s", rawURL)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), webhookResolveTimeout)
+	defer cancel()
+	if _, err := s.hostGuard.CheckHost(ctx, parsed.Hostname()); err != nil {
+		return fmt.Errorf("不允许的 webhook 地址: %w", err)
+	}
+	return nil
+}
+
+func validateWebhookEvents(events []string) error {
+	if len(events) == 0 {
+		return fmt.Errorf("至少需要订阅一个事件")
+	}
+	for _, event := range events {
+		if !slices.Contains(WebhookEvents, event) {
+			return fmt.Errorf("不支持的 webhook 事件: %s", event)
+		}
+	}
+	return nil
+}
+
+func generateWebhookSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return "whsec_" + hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/drama-generator/backend/domain/models"
+	"github.com/drama-generator/backend/pkg/config"
+	"gorm.io/gorm"
+)
+
+// newTestWebhookService 创建 webhook 服务和一个订阅，allowedHosts 为空时只能投递到公网地址
+func newTestWebhookService(t *testing.T, url string, allowedHosts []string) *WebhookService {
+	t.Helper()
+	db := newTestDB(t)
+	q := newTestJobQueue(t, db, config.QueueConfig{})
+	service := NewWebhookService(db, q, config.WebhookConfig{AllowedHosts: allowedHosts}, newTestLogger())
+
+	// 直接写入数据库，不经过保存时的地址校验，用于验证投递时的连接校验
+	subscription := &models.WebhookSubscription{
+		URL:      url,
+		Secret:   "test-secret",
+		Events:   models.StringList{EventTaskCompleted},
+		IsActive: true,
+	}
+	if err := db.Create(subscription).Error; err != nil {
+		t.Fatalf("create subscription: %v", err)
+	}
+	return service
+}
+
+// runWebhookDelivery 同步执行一次排队中的投递任务，跳过退避等待
+func runWebhookDelivery(t *testing.T, service *WebhookService) models.AsyncTask {
+	t.Helper()
+	db := service.db
+	var task models.AsyncTask
+	if err := db.Where("type = ?", "webhook_delivery").First(&task).Error; err != nil {
+		t.Fatalf("load delivery job: %v", err)
+	}
+	db.Model(&models.AsyncTask{}).Where("id = ?", task.ID).Update("available_at", time.Now())
+	task = loadTask(t, db, task.ID)
+
+	q := service.jobQueue
+	if !q.acquireSlot(task.Queue, task.Provider) || !q.claim(&task) {
+		t.Fatalf("delivery job could not be claimed, status %s", task.Status)
+	}
+	q.run(task)
+	return loadTask(t, db, task.ID)
+}
+
+func loadDelivery(t *testing.T, db *gorm.DB) models.WebhookDelivery {
+	t.Helper()
+	var delivery models.WebhookDelivery
+	if err := db.First(&delivery).Error; err != nil {
+		t.Fatalf("load delivery: %v", err)
+	}
+	return delivery
+}
+
+func TestWebhookDeliverySignature(t *testing.T) {
+	var received int32
+	var gotErr atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&received, 1)
+		body, _ := io.ReadAll(r.Body)
+		timestamp := r.Header.Get(WebhookHeaderTimestamp)
+
+		mac := hmac.New(sha256.New, []byte("test-secret"))
+		mac.Write([]byte(timestamp + "." + string(body)))
+		want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+		switch {
+		case r.Header.Get(WebhookHeaderSignature) != want:
+			gotErr.Store("signature " + r.Header.Get(WebhookHeaderSignature) + " does not match " + want)
+		case r.Header.Get(WebhookHeaderEvent) != EventTaskCompleted:
+			gotErr.Store("event header " + r.Header.Get(WebhookHeaderEvent))
+		case r.Header.Get(WebhookHeaderDelivery) == "":
+			gotErr.Store("missing delivery header")
+		case !strings.Contains(string(body), `"task_id":"task-1"`):
+			gotErr.Store("unexpected body " + string(body))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	service := newTestWebhookService(t, server.URL, []string{"127.0.0.1"})
+	dispatchWebhookEvent(service.db, service.jobQueue, service.log, EventTaskCompleted, 0, map[string]interface{}{"task_id": "task-1"})
+
+	task := runWebhookDelivery(t, service)
+	if err, ok := gotErr.Load().(string); ok {
+		t.Fatal(err)
+	}
+	if received != 1 {
+		t.Fatalf("endpoint received %d requests, want 1", received)
+	}
+
+	delivery := loadDelivery(t, service.db)
+	if delivery.Status != models.WebhookDeliverySuccess || delivery.Attempts != 1 || delivery.DeliveredAt == nil {
+		t.Errorf("delivery = status %s attempts %d delivered_at %v", delivery.Status, delivery.Attempts, delivery.DeliveredAt)
+	}
+	if delivery.ResponseStatus == nil || *delivery.ResponseStatus != http.StatusNoContent {
+		t.Errorf("delivery response status = %v, want 204", delivery.ResponseStatus)
+	}
+	if task.Status != "completed" {
+		t.Errorf("delivery job status = %s, want completed", task.Status)
+	}
+}
+
+func TestWebhookDeliveryRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int32
+		wantAttempts int
+		wantStatus   models.WebhookDeliveryStatus
+	}{
+		{name: "recovers", failures: 2, wantAttempts: 3, wantStatus: models.WebhookDeliverySuccess},
+		{name: "gives up", failures: webhookMaxAttempts, wantAttempts: webhookMaxAttempts, wantStatus: models.WebhookDeliveryFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if atomic.AddInt32(&requests, 1) <= tt.failures {
+					http.Error(w, "unavailable", http.StatusServiceUnavailable)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer server.Close()
+
+			service := newTestWebhookService(t, server.URL, []string{"127.0.0.1"})
+			dispatchWebhookEvent(service.db, service.jobQueue, service.log, EventTaskCompleted, 0, map[string]interface{}{"task_id": "task-1"})
+
+			for attempt := 1; attempt <= tt.wantAttempts; attempt++ {
+				task := runWebhookDelivery(t, service)
+				delivery := loadDelivery(t, service.db)
+				if delivery.Attempts != attempt {
+					t.Fatalf("attempt %d: delivery attempts = %d", attempt, delivery.Attempts)
+				}
+				if attempt == tt.wantAttempts {
+					break
+				}
+
+				// 失败的尝试记录响应和错误，投递保持 pending 等待队列重试
+				if delivery.Status != models.WebhookDeliveryPending || task.Status != "pending" {
+					t.Fatalf("attempt %d: delivery %s job %s, want both pending", attempt, delivery.Status, task.Status)
+				}
+				if delivery.ResponseStatus == nil || *delivery.ResponseStatus != http.StatusServiceUnavailable || delivery.ErrorMsg == nil {
+					t.Fatalf("attempt %d: failed attempt was not logged: status %v error %v", attempt, delivery.ResponseStatus, delivery.ErrorMsg)
+				}
+			}
+
+			delivery := loadDelivery(t, service.db)
+			if delivery.Status != tt.wantStatus {
+				t.Errorf("final delivery status = %s, want %s", delivery.Status, tt.wantStatus)
+			}
+			if requests != int32(tt.wantAttempts) {
+				t.Errorf("endpoint received %d requests, want %d", requests, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestWebhookDeliveryRefusesLoopback(t *testing.T) {
+	var received int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&received, 1)
+	}))
+	defer server.Close()
+
+	service := newTestWebhookService(t, server.URL, nil)
+	if _, _, err := service.CreateSubscription(&CreateWebhookRequest{URL: server.URL, Events: []string{EventTaskCompleted}}); err == nil {
+		t.Error("CreateSubscription() with a loopback URL should fail")
+	}
+
+	dispatchWebhookEvent(service.db, service.jobQueue, service.log, EventTaskCompleted, 0, map[string]interface{}{"task_id": "task-1"})
+	runWebhookDelivery(t, service)
+
+	if received != 0 {
+		t.Fatalf("loopback endpoint received %d requests", received)
+	}
+	delivery := loadDelivery(t, service.db)
+	if delivery.ErrorMsg == nil || !strings.Contains(*delivery.ErrorMsg, "not a public address") {
+		t.Errorf("delivery error = %v, want the dial to be refused", delivery.ErrorMsg)
+	}
+}
@@ -23,8 +23,10 @@ type Asset struct {
 	Description  *string   `gorm:"type:text" json:"description,omitempty"`
 	Type         AssetType `gorm:"type:varchar(20);not null;index" json:"type"`
 	Category     *string   `gorm:"type:varchar(50);index" json:"category,omitempty"`
+	Tags         *string   `gorm:"type:varchar(500)" json:"tags,omitempty"` // 逗号分隔的标签，用于音频库检索和匹配
 	URL          string    `gorm:"type:varchar(1000);not null" json:"url"`
 	ThumbnailURL *string   `gorm:"type:varchar(1000)" json:"thumbnail_url,omitempty"`
+	SpriteVTTURL *string   `gorm:"type:varchar(1000)" json:"sprite_vtt_url,omitempty"` // 视频拖动预览的雪碧图 WebVTT
 	LocalPath    *string   `gorm:"type:varchar(500)" json:"local_path"`
 
 	FileSize *int64  `json:"file_size,omitempty"`
@@ -34,6 +36,14 @@ type Asset struct {
 	Duration *int    `json:"duration,omitempty"`
 	Format   *string `gorm:"type:varchar(50)" json:"format,omitempty"`
 
+	// 探测得到的精确时长（毫秒），Duration 为取整后的秒数，剪辑校验裁剪范围时优先使用
+	DurationMs *int `json:"duration_ms,omitempty"`
+
+	// 音频的 EBU R128 响度测量，合成时按此计算片段增益，测量一次后复用
+	Loudness      *float64 `json:"loudness,omitempty"`       // 综合响度（LUFS）
+	TruePeak      *float64 `json:"true_peak,omitempty"`      // 真峰值（dBTP）
+	LoudnessRange *float64 `json:"loudness_range,omitempty"` // 响度范围（LU）
+
 	ImageGenID *uint           `gorm:"index" json:"image_gen_id,omitempty"`
 	ImageGen   ImageGeneration `gorm:"foreignKey:ImageGenID" json:"image_gen,omitempty"`
 
@@ -52,6 +62,28 @@ const (
 	AssetTypeAudio AssetType = "audio"
 )
 
+// 音频素材分类：配音按分镜对齐，背景音乐和音效可以属于章节，也可以作为音频库素材跨剧本复用
+const (
+	AssetCategoryVoice = "voice"
+	AssetCategoryBGM   = "bgm"
+	AssetCategorySFX   = "sfx"
+)
+
+// 带字幕的成片：软字幕封装为字幕轨道，烧录字幕渲染在画面上
+const (
+	AssetCategorySubtitleSoft = "subtitle_soft"
+	AssetCategorySubtitleBurn = "subtitle_burn"
+)
+
+// AssetCategoryLipSync 按配音同步口型后的分镜视频，VideoGenID 指向原视频生成记录
+const AssetCategoryLipSync = "lipsync"
+
+// 成片的其他输出规格：按输出规格转码的 MP4，以及 HLS 主播放列表（URL 指向 master.m3u8）
+const (
+	AssetCategoryRendition = "rendition"
+	AssetCategoryHLS       = "hls"
+)
+
 func (Asset) TableName() string {
 	return "assets"
 }
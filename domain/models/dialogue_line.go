@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DialogueLine 分镜对白按说话人拆分后的一句台词及其配音
+type DialogueLine struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	EpisodeID    uint  `gorm:"not null;index" json:"episode_id"`
+	StoryboardID uint  `gorm:"not null;index" json:"storyboard_id"`
+	LineIndex    int   `gorm:"not null;default:0" json:"line_index"` // 在分镜内的顺序
+	CharacterID  *uint `gorm:"index" json:"character_id,omitempty"`
+
+	Speaker string `gorm:"type:varchar(100)" json:"speaker"` // 为空表示未标注说话人
+	Text    string `gorm:"type:text;not null" json:"text"`
+	Voice   string `gorm:"type:varchar(100)" json:"voice"`
+
+	AssetID  *uint  `gorm:"index" json:"asset_id,omitempty"`
+	Asset    *Asset `gorm:"foreignKey:AssetID" json:"asset,omitempty"`
+	Duration int    `gorm:"default:0" json:"duration"` // 实测时长（毫秒）
+
+	Status       string  `gorm:"type:varchar(20);not null;default:'pending'" json:"status"` // pending, completed, failed
+	ErrorMessage *string `gorm:"type:text" json:"error_message,omitempty"`
+}
+
+const (
+	DialogueLineStatusPending   = "pending"
+	DialogueLineStatusCompleted = "completed"
+	DialogueLineStatusFailed    = "failed"
+)
+
+func (DialogueLine) TableName() string {
+	return "dialogue_lines"
+}
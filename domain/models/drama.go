@@ -8,20 +8,24 @@ import (
 )
 
 type Drama struct {
-	ID            uint           `gorm:"primaryKey;autoIncrement" json:"id"`
-	Title         string         `gorm:"type:varchar(200);not null" json:"title"`
-	Description   *string        `gorm:"type:text" json:"description"`
-	Genre         *string        `gorm:"type:varchar(50)" json:"genre"`
-	Style         string         `gorm:"type:varchar(50);default:'realistic'" json:"style"`
-	TotalEpisodes int            `gorm:"default:1" json:"total_episodes"`
-	TotalDuration int            `gorm:"default:0" json:"total_duration"`
-	Status        string         `gorm:"type:varchar(20);default:'draft';not null" json:"status"`
-	Thumbnail     *string        `gorm:"type:varchar(500)" json:"thumbnail"`
-	Tags          datatypes.JSON `gorm:"type:json" json:"tags"`
-	Metadata      datatypes.JSON `gorm:"type:json" json:"metadata"`
-	CreatedAt     time.Time      `gorm:"not null;autoCreateTime" json:"created_at"`
-	UpdatedAt     time.Time      `gorm:"not null;autoUpdateTime" json:"updated_at"`
-	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
+	ID             uint           `gorm:"primaryKey;autoIncrement" json:"id"`
+	Title          string         `gorm:"type:varchar(200);not null" json:"title"`
+	Description    *string        `gorm:"type:text" json:"description"`
+	Genre          *string        `gorm:"type:varchar(50)" json:"genre"`
+	Style          string         `gorm:"type:varchar(50);default:'realistic'" json:"style"`
+	TotalEpisodes  int            `gorm:"default:1" json:"total_episodes"`
+	TotalDuration  int            `gorm:"default:0" json:"total_duration"`
+	Status         string         `gorm:"type:varchar(20);default:'draft';not null" json:"status"`
+	Thumbnail      *string        `gorm:"type:varchar(500)" json:"thumbnail"`
+	Tags           datatypes.JSON `gorm:"type:json" json:"tags"`
+	Metadata       datatypes.JSON `gorm:"type:json" json:"metadata"`
+	SubtitleStyle  datatypes.JSON `gorm:"type:json" json:"subtitle_style,omitempty"`  // 字幕样式，为空时使用默认样式
+	OutputSettings datatypes.JSON `gorm:"type:json" json:"output_settings,omitempty"` // 成片输出规格和 HLS 设置，为空时只输出合成的原始成片
+	Branding       datatypes.JSON `gorm:"type:json" json:"branding,omitempty"`        // 水印、片头片尾和标题卡，合成成片时自动添加
+	ColorGrading   datatypes.JSON `gorm:"type:json" json:"color_grading,omitempty"`   // 镜头配色匹配和剧本 LUT，合成成片时使用
+	CreatedAt      time.Time      `gorm:"not null;autoCreateTime" json:"created_at"`
+	UpdatedAt      time.Time      `gorm:"not null;autoUpdateTime" json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"-"`
 
 	Episodes   []Episode   `gorm:"foreignKey:DramaID" json:"episodes,omitempty"`
 	Characters []Character `gorm:"foreignKey:DramaID" json:"characters,omitempty"`
@@ -42,6 +46,8 @@ type Character struct {
 	Appearance      *string        `gorm:"type:text" json:"appearance"`
 	Personality     *string        `gorm:"type:text" json:"personality"`
 	VoiceStyle      *string        `gorm:"type:varchar(200)" json:"voice_style"`
+	VoiceID         *string        `gorm:"type:varchar(100)" json:"voice_id"` // 指定的配音音色，为空时按 voice_style 选择预设音色
+	VoiceSpeed      *float64       `json:"voice_speed"`                       // 配音语速倍数
 	ImageURL        *string        `gorm:"type:varchar(500)" json:"image_url"`
 	LocalPath       *string        `gorm:"type:text" json:"local_path,omitempty"`
 	ReferenceImages datatypes.JSON `gorm:"type:json" json:"reference_images"`
@@ -74,6 +80,7 @@ type Episode struct {
 	Status        string         `gorm:"type:varchar(20);default:'draft'" json:"status"`
 	VideoURL      *string        `gorm:"type:varchar(500)" json:"video_url"`
 	Thumbnail     *string        `gorm:"type:varchar(500)" json:"thumbnail"`
+	SpriteVTTURL  *string        `gorm:"type:varchar(500)" json:"sprite_vtt_url"` // 拖动预览的雪碧图 WebVTT
 	CreatedAt     time.Time      `gorm:"not null;autoCreateTime" json:"created_at"`
 	UpdatedAt     time.Time      `gorm:"not null;autoUpdateTime" json:"updated_at"`
 	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
@@ -107,6 +114,8 @@ type Storyboard struct {
 	VideoPrompt      *string        `gorm:"type:text" json:"video_prompt"`
 	BgmPrompt        *string        `gorm:"type:text" json:"bgm_prompt"`
 	SoundEffect      *string        `gorm:"size:255" json:"sound_effect"`
+	BgmAssetID       *uint          `gorm:"index" json:"bgm_asset_id"` // 从音频库匹配的配乐
+	SfxAssetID       *uint          `gorm:"index" json:"sfx_asset_id"` // 从音频库匹配的音效
 	Dialogue         *string        `gorm:"type:text" json:"dialogue"`
 	Description      *string        `gorm:"type:text" json:"description"`
 	Duration         int            `gorm:"default:5" json:"duration"`
@@ -19,6 +19,7 @@ type ImageGeneration struct {
 	Prompt          string                `gorm:"type:text;not null" json:"prompt"`
 	NegPrompt       *string               `gorm:"column:negative_prompt;type:text" json:"negative_prompt,omitempty"`
 	Model           string                `gorm:"size:100" json:"model"`
+	ServiceConfigID *uint                 `gorm:"index" json:"service_config_id,omitempty"` // 实际完成生成的AI服务配置（故障转移后可能与请求的不同）
 	Size            string                `gorm:"size:20" json:"size"`
 	Quality         string                `gorm:"size:20" json:"quality"`
 	Style           *string               `gorm:"size:50" json:"style,omitempty"`
@@ -34,6 +35,11 @@ type ImageGeneration struct {
 	Width           *int                  `json:"width,omitempty"`
 	Height          *int                  `json:"height,omitempty"`
 	ReferenceImages datatypes.JSON        `gorm:"type:json" json:"reference_images,omitempty"`
+	QCStatus        *string               `gorm:"size:20;index" json:"qc_status,omitempty"` // 自动质检结论：纯色画面、尺寸
+	QCFlags         datatypes.JSON        `gorm:"type:json" json:"qc_flags,omitempty"`
+	QCCheckedAt     *time.Time            `json:"qc_checked_at,omitempty"`
+	QCRetryOfID     *uint                 `gorm:"index" json:"qc_retry_of_id,omitempty"` // 质检未通过自动重新生成时指向原记录
+	QCRetries       int                   `gorm:"default:0" json:"qc_retries"`
 	CreatedAt       time.Time             `json:"created_at"`
 	UpdatedAt       time.Time             `json:"updated_at"`
 	CompletedAt     *time.Time            `json:"completed_at,omitempty"`
@@ -56,6 +62,7 @@ const (
 	ImageStatusProcessing ImageGenerationStatus = "processing"
 	ImageStatusCompleted  ImageGenerationStatus = "completed"
 	ImageStatusFailed     ImageGenerationStatus = "failed"
+	ImageStatusCancelled  ImageGenerationStatus = "cancelled"
 )
 
 type ImageProvider string
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+type ReframeStatus string
+
+const (
+	ReframeStatusPending    ReframeStatus = "pending"
+	ReframeStatusProcessing ReframeStatus = "processing"
+	ReframeStatusCompleted  ReframeStatus = "completed"
+	ReframeStatusFailed     ReframeStatus = "failed"
+)
+
+// 画面比例转换的来源
+const (
+	ReframeSourceVideo = "video" // 视频生成记录
+	ReframeSourceImage = "image" // 图片生成记录
+	ReframeSourceMerge = "merge" // 章节成片，输出规格转码时使用
+)
+
+// Reframe 视频或图片转换到其他宽高比的结果，同一来源的同一宽高比只保留一条
+// Keyframes 为智能裁剪的裁剪位置，可以手动调整，调整后重新分析不会覆盖
+type Reframe struct {
+	ID          uint           `gorm:"primaryKey;autoIncrement" json:"id"`
+	DramaID     uint           `gorm:"not null;index" json:"drama_id"`
+	SourceType  string         `gorm:"type:varchar(20);not null;index:idx_reframe_source" json:"source_type"`
+	SourceID    uint           `gorm:"not null;index:idx_reframe_source" json:"source_id"`
+	AspectRatio string         `gorm:"type:varchar(20);not null" json:"aspect_ratio"` // 如 9:16
+	Strategy    string         `gorm:"type:varchar(20);not null" json:"strategy"`     // crop、letterbox、blur_pad 或 smart
+	Keyframes   datatypes.JSON `json:"keyframes,omitempty"`
+	Edited      bool           `gorm:"default:false" json:"edited"` // 关键帧经过手动调整
+	Width       int            `json:"width,omitempty"`
+	Height      int            `json:"height,omitempty"`
+	Status      ReframeStatus  `gorm:"type:varchar(20);not null;default:'pending'" json:"status"`
+	OutputURL   *string        `gorm:"type:varchar(500)" json:"output_url,omitempty"` // 成片只保存关键帧，由输出规格转码使用
+	LocalPath   *string        `gorm:"type:varchar(500)" json:"local_path,omitempty"`
+	ErrorMsg    *string        `gorm:"type:text" json:"error_msg,omitempty"`
+	CreatedAt   time.Time      `gorm:"not null;autoCreateTime" json:"created_at"`
+	UpdatedAt   time.Time      `gorm:"not null;autoUpdateTime" json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (r *Reframe) TableName() string {
+	return "reframes"
+}
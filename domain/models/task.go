@@ -10,14 +10,28 @@ import (
 type AsyncTask struct {
 	ID          string         `gorm:"primaryKey;size:36" json:"id"`
 	Type        string         `gorm:"size:50;not null;index" json:"type"`   // 任务类型：storyboard_generation
-	Status      string         `gorm:"size:20;not null;index" json:"status"` // pending, processing, completed, failed
+	Status      string         `gorm:"size:20;not null;index" json:"status"` // pending, processing, paused, completed, failed, cancelled
 	Progress    int            `gorm:"default:0" json:"progress"`            // 0-100
 	Message     string         `gorm:"size:500" json:"message,omitempty"`    // 当前状态消息
 	Error       string         `gorm:"type:text" json:"error,omitempty"`     // 错误信息
 	Result      string         `gorm:"type:text" json:"result,omitempty"`    // JSON格式的结果数据
 	ResourceID  string         `gorm:"size:36;index" json:"resource_id"`     // 关联资源ID（如episode_id）
+	DramaID     uint           `gorm:"index" json:"drama_id,omitempty"`      // 所属剧目，用于按剧目推送事件
 	CreatedAt   time.Time      `gorm:"autoCreateTime" json:"created_at"`
 	UpdatedAt   time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
 	CompletedAt *time.Time     `json:"completed_at,omitempty"`
 	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// 队列相关字段（由 JobQueue 管理，旧的直接执行任务这些字段为空）
+	Queue          string     `gorm:"size:20;index" json:"queue,omitempty"`        // 工作池：text, image, video, ffmpeg, default
+	Provider       string     `gorm:"size:50" json:"provider,omitempty"`           // AI厂商，用于按厂商限制并发
+	ParentID       string     `gorm:"size:36;index" json:"parent_id,omitempty"`    // 父任务ID（批量任务的子任务）
+	Priority       int        `gorm:"default:0;index" json:"priority"`             // 优先级，数值越大越先执行
+	Payload        string     `gorm:"type:text" json:"-"`                          // JSON格式的任务参数
+	Attempts       int        `gorm:"default:0" json:"attempts"`                   // 已执行次数
+	MaxAttempts    int        `gorm:"default:0" json:"max_attempts"`               // 最大执行次数
+	AvailableAt    *time.Time `gorm:"index" json:"available_at,omitempty"`         // 最早可执行时间（用于退避和轮询）
+	LeaseOwner     string     `gorm:"size:100;index" json:"lease_owner,omitempty"` // 持有租约的工作进程
+	LeaseExpiresAt *time.Time `json:"lease_expires_at,omitempty"`                  // 租约过期时间，过期后任务会被重新领取
+	HeartbeatAt    *time.Time `json:"heartbeat_at,omitempty"`                      // 最近一次心跳时间
 }
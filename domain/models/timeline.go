@@ -21,7 +21,7 @@ type Timeline struct {
 	Name        string  `gorm:"type:varchar(200);not null" json:"name"`
 	Description *string `gorm:"type:text" json:"description,omitempty"`
 
-	Duration   int     `gorm:"default:0" json:"duration"`
+	Duration   int     `gorm:"default:0" json:"duration"` // 毫秒，等于最后一个片段的结束时间
 	FPS        int     `gorm:"default:30" json:"fps"`
 	Resolution *string `gorm:"type:varchar(50)" json:"resolution,omitempty"`
 
@@ -59,6 +59,9 @@ type TimelineTrack struct {
 	IsMuted  bool      `gorm:"default:false" json:"is_muted"`
 	Volume   *int      `gorm:"default:100" json:"volume,omitempty"`
 
+	// AudioRole 音频轨道的用途，渲染时配乐和音效轨道在对白出现时自动压低
+	AudioRole TrackAudioRole `gorm:"type:varchar(20)" json:"audio_role,omitempty"`
+
 	Clips []TimelineClip `gorm:"foreignKey:TrackID" json:"clips,omitempty"`
 }
 
@@ -70,6 +73,14 @@ const (
 	TrackTypeText  TrackType = "text"
 )
 
+type TrackAudioRole string
+
+const (
+	TrackAudioRoleDialogue TrackAudioRole = "dialogue"
+	TrackAudioRoleMusic    TrackAudioRole = "music"
+	TrackAudioRoleSFX      TrackAudioRole = "sfx"
+)
+
 func (TimelineTrack) TableName() string {
 	return "timeline_tracks"
 }
@@ -91,6 +102,7 @@ type TimelineClip struct {
 
 	Name string `gorm:"type:varchar(200)" json:"name"`
 
+	// 时间单位均为毫秒：StartTime/EndTime 为片段在时间线上的位置，TrimStart/TrimEnd 为素材的入点和出点
 	StartTime int `gorm:"not null" json:"start_time"`
 	EndTime   int `gorm:"not null" json:"end_time"`
 	Duration  int `gorm:"not null" json:"duration"`
@@ -3,6 +3,7 @@ package models
 import (
 	"time"
 
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
 
@@ -22,6 +23,8 @@ type VideoGeneration struct {
 	Prompt   string `gorm:"type:text;not null" json:"prompt"`
 	Model    string `gorm:"type:varchar(100)" json:"model,omitempty"`
 
+	ServiceConfigID *uint `gorm:"index" json:"service_config_id,omitempty"` // 实际完成生成的AI服务配置（故障转移后可能与请求的不同）
+
 	ImageGenID *uint           `gorm:"index" json:"image_gen_id,omitempty"`
 	ImageGen   ImageGeneration `gorm:"foreignKey:ImageGenID" json:"image_gen,omitempty"`
 
@@ -33,6 +36,9 @@ type VideoGeneration struct {
 	LastFrameURL       *string `gorm:"type:varchar(1000)" json:"last_frame_url,omitempty"`
 	ReferenceImageURLs *string `gorm:"type:text" json:"reference_image_urls,omitempty"` // JSON数组存储多张参考图
 
+	// 链式生成：依赖的上一个分镜视频，其完成后截取尾帧作为本视频的首帧
+	ChainPrevID *uint `gorm:"index" json:"chain_prev_id,omitempty"`
+
 	Duration     *int    `json:"duration,omitempty"`
 	FPS          *int    `json:"fps,omitempty"`
 	Resolution   *string `gorm:"type:varchar(50)" json:"resolution,omitempty"`
@@ -54,6 +60,24 @@ type VideoGeneration struct {
 
 	Width  *int `json:"width,omitempty"`
 	Height *int `json:"height,omitempty"`
+
+	// 从视频中选取的封面（跳过黑场和模糊画面）和拖动预览的雪碧图 WebVTT
+	ThumbnailURL *string `gorm:"type:varchar(1000)" json:"thumbnail_url,omitempty"`
+	SpriteVTTURL *string `gorm:"type:varchar(1000)" json:"sprite_vtt_url,omitempty"`
+
+	// 自动质检：黑场、冻帧、时长、画幅和音轨，QCFlags 为发现的问题；未通过时可自动重新生成，
+	// 重新生成的记录通过 QCRetryOfID 指向原记录，QCRetries 为累计重新生成次数
+	QCStatus    *string        `gorm:"type:varchar(20);index" json:"qc_status,omitempty"`
+	QCFlags     datatypes.JSON `gorm:"type:json" json:"qc_flags,omitempty"`
+	QCCheckedAt *time.Time     `json:"qc_checked_at,omitempty"`
+	QCRetryOfID *uint          `gorm:"index" json:"qc_retry_of_id,omitempty"`
+	QCRetries   int            `gorm:"default:0" json:"qc_retries"`
+
+	// 口型同步：有对白的分镜在视频完成后按配音同步口型，结果保存为该分镜的视频素材
+	LipSyncStatus  *LipSyncStatus `gorm:"type:varchar(20);index" json:"lip_sync_status,omitempty"`
+	LipSyncTaskID  *string        `gorm:"type:varchar(200)" json:"lip_sync_task_id,omitempty"`
+	LipSyncAssetID *uint          `json:"lip_sync_asset_id,omitempty"`
+	LipSyncError   *string        `gorm:"type:text" json:"lip_sync_error,omitempty"`
 }
 
 type VideoStatus string
@@ -63,6 +87,17 @@ const (
 	VideoStatusProcessing VideoStatus = "processing"
 	VideoStatusCompleted  VideoStatus = "completed"
 	VideoStatusFailed     VideoStatus = "failed"
+	VideoStatusCancelled  VideoStatus = "cancelled"
+)
+
+type LipSyncStatus string
+
+const (
+	LipSyncStatusPending    LipSyncStatus = "pending"
+	LipSyncStatusProcessing LipSyncStatus = "processing"
+	LipSyncStatusCompleted  LipSyncStatus = "completed"
+	LipSyncStatusFailed     LipSyncStatus = "failed"
+	LipSyncStatusSkipped    LipSyncStatus = "skipped" // 分镜没有可用的配音
 )
 
 type VideoProvider string
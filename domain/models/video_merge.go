@@ -33,12 +33,30 @@ type VideoMerge struct {
 	CompletedAt *time.Time       `json:"completed_at,omitempty"`
 	DeletedAt   gorm.DeletedAt   `gorm:"index" json:"-"`
 
+	// 响度标准化：输出档位、是否降噪，以及母带处理前后的响度测量
+	LoudnessProfile *string        `gorm:"type:varchar(50)" json:"loudness_profile,omitempty"`
+	Denoise         bool           `gorm:"default:false" json:"denoise"`
+	Loudness        datatypes.JSON `json:"loudness,omitempty"`
+
+	// 合成完成后需要转码的输出规格和 HLS 设置，为空时不生成其他规格
+	OutputSettings datatypes.JSON `json:"output_settings,omitempty"`
+
+	// 品牌包装：创建时按剧本设置解析的水印、片头片尾和标题卡，合成后记录各段时长
+	Branding datatypes.JSON `json:"branding,omitempty"`
+
+	// 统一帧率：创建时解析的目标帧率、转换方式和最大放慢倍数，为空时保留各片段原帧率
+	FrameRate datatypes.JSON `json:"frame_rate,omitempty"`
+
+	// 配色匹配：创建时解析的参考镜头和 LUT，合成时记录各片段的统计和校正
+	ColorMatch datatypes.JSON `json:"color_match,omitempty"`
+
 	Episode Episode `gorm:"foreignKey:EpisodeID" json:"episode,omitempty"`
 	Drama   Drama   `gorm:"foreignKey:DramaID" json:"drama,omitempty"`
 }
 
 type SceneClip struct {
 	SceneID    uint                   `json:"scene_id"`
+	AssetID    *uint                  `json:"asset_id,omitempty"` // 片段对应的素材，用于复用响度测量
 	VideoURL   string                 `json:"video_url"`
 	StartTime  float64                `json:"start_time"`
 	EndTime    float64                `json:"end_time"`
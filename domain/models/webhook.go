@@ -0,0 +1,98 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// WebhookSubscription 外部系统订阅的事件回调
+type WebhookSubscription struct {
+	ID          uint           `gorm:"primaryKey;autoIncrement" json:"id"`
+	Name        string         `gorm:"type:varchar(100)" json:"name"`
+	URL         string         `gorm:"type:varchar(500);not null" json:"url"`
+	Secret      string         `gorm:"type:varchar(255);not null" json:"-"` // 签名密钥，只在创建时返回
+	Events      StringList     `gorm:"type:text" json:"events"`             // 订阅的事件类型
+	DramaID     *uint          `gorm:"index" json:"drama_id,omitempty"`     // 只接收该剧目的事件，为空时接收全部
+	IsActive    bool           `gorm:"default:true" json:"is_active"`
+	Description *string        `gorm:"type:text" json:"description,omitempty"`
+	CreatedAt   time.Time      `gorm:"not null;autoCreateTime" json:"created_at"`
+	UpdatedAt   time.Time      `gorm:"not null;autoUpdateTime" json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (w *WebhookSubscription) TableName() string {
+	return "webhook_subscriptions"
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending WebhookDeliveryStatus = "pending"
+	WebhookDeliverySuccess WebhookDeliveryStatus = "success"
+	WebhookDeliveryFailed  WebhookDeliveryStatus = "failed"
+)
+
+// WebhookDelivery 事件投递记录，每次重新投递生成一条新记录
+type WebhookDelivery struct {
+	ID             uint                  `gorm:"primaryKey;autoIncrement" json:"id"`
+	SubscriptionID uint                  `gorm:"not null;index" json:"subscription_id"`
+	EventID        string                `gorm:"type:varchar(36);not null;index" json:"event_id"` // 同一事件的重新投递共用事件ID，接收方可据此去重
+	EventType      string                `gorm:"type:varchar(50);not null;index" json:"event_type"`
+	Payload        string                `gorm:"type:text;not null" json:"payload"`
+	Status         WebhookDeliveryStatus `gorm:"type:varchar(20);not null;default:'pending';index" json:"status"`
+	Attempts       int                   `gorm:"default:0" json:"attempts"`
+	ResponseStatus *int                  `json:"response_status,omitempty"`
+	ResponseBody   *string               `gorm:"type:text" json:"response_body,omitempty"`
+	ErrorMsg       *string               `gorm:"type:text" json:"error_msg,omitempty"`
+	DurationMs     int64                 `gorm:"default:0" json:"duration_ms"` // 最近一次请求耗时
+	RedeliveryOf   *uint                 `gorm:"index" json:"redelivery_of,omitempty"`
+	CreatedAt      time.Time             `gorm:"not null;autoCreateTime" json:"created_at"`
+	UpdatedAt      time.Time             `gorm:"not null;autoUpdateTime" json:"updated_at"`
+	DeliveredAt    *time.Time            `json:"delivered_at,omitempty"`
+}
+
+func (w *WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+// StringList 以JSON数组形式存储的字符串列表
+type StringList []string
+
+// Value 实现 driver.Valuer 接口
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal([]string(l))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan 实现 sql.Scanner 接口
+func (l *StringList) Scan(value interface{}) error {
+	if value == nil {
+		*l = StringList{}
+		return nil
+	}
+
+	var data []byte
+	switch v := value.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return errors.New("unsupported type for StringList")
+	}
+	if len(data) == 0 {
+		*l = StringList{}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(l))
+}
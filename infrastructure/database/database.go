@@ -84,6 +84,8 @@ func AutoMigrate(db *gorm.DB) error {
 		&models.ImageGeneration{},
 		&models.VideoGeneration{},
 		&models.VideoMerge{},
+		&models.Reframe{},
+		&models.DialogueLine{},
 
 		// AI配置
 		&models.AIServiceConfig{},
@@ -95,5 +97,16 @@ func AutoMigrate(db *gorm.DB) error {
 
 		// 任务管理
 		&models.AsyncTask{},
+
+		// 时间线
+		&models.Timeline{},
+		&models.TimelineTrack{},
+		&models.TimelineClip{},
+		&models.ClipTransition{},
+		&models.ClipEffect{},
+
+		// Webhook
+		&models.WebhookSubscription{},
+		&models.WebhookDelivery{},
 	)
 }
@@ -0,0 +1,367 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// 水印位置
+const (
+	WatermarkTopLeft     = "top_left"
+	WatermarkTopRight    = "top_right"
+	WatermarkBottomLeft  = "bottom_left"
+	WatermarkBottomRight = "bottom_right"
+	WatermarkCenter      = "center"
+)
+
+// 品牌包装的默认值
+const (
+	defaultWatermarkOpacity = 0.8
+	defaultWatermarkScale   = 0.12 // 水印宽度占画面宽度的比例
+	defaultTitleCardSeconds = 3
+	defaultTitleCardFont    = "Noto Sans CJK SC"
+	titleCardFadeSeconds    = 0.5
+	defaultBrandingFPS      = 30
+)
+
+// Watermark 正片上叠加的水印图片
+type Watermark struct {
+	Source   string  `json:"source"`             // 本地路径或远程 URL
+	Position string  `json:"position,omitempty"` // 为空时在右上角
+	Opacity  float64 `json:"opacity,omitempty"`  // 0-1，为空时使用 0.8
+	Scale    float64 `json:"scale,omitempty"`    // 水印宽度占画面宽度的比例，为空时使用 0.12
+}
+
+// TitleCard 正片前的标题卡：在背景上居中绘制标题文字
+type TitleCard struct {
+	Text       string  `json:"text"`
+	Duration   float64 `json:"duration,omitempty"`   // 为空时使用 3 秒
+	Background string  `json:"background,omitempty"` // 背景图片或视频，本地路径或远程 URL，为空时使用纯色背景
+	IsImage    bool    `json:"is_image,omitempty"`   // 背景为图片
+	Color      string  `json:"color,omitempty"`      // 纯色背景的颜色，为空时使用黑色
+	Font       string  `json:"font,omitempty"`       // fontconfig 字体名称，为空时使用 Noto Sans CJK SC
+	FontColor  string  `json:"font_color,omitempty"` // 为空时使用白色
+	FontSize   int     `json:"font_size,omitempty"`  // 为空时按画面高度计算
+}
+
+// BrandingOptions 成片品牌包装参数：正片叠加水印，前后依次拼接片头、标题卡和片尾
+type BrandingOptions struct {
+	InputPath  string
+	OutputPath string
+	Intro      string // 片头视频，本地路径或远程 URL，为空时不拼接
+	Outro      string // 片尾视频
+	TitleCard  *TitleCard
+	Watermark  *Watermark
+}
+
+// BrandingResult 包装后各段的时长（秒）
+type BrandingResult struct {
+	IntroDuration float64 `json:"intro_duration,omitempty"`
+	TitleDuration float64 `json:"title_duration,omitempty"`
+	OutroDuration float64 `json:"outro_duration,omitempty"`
+	Duration      float64 `json:"duration"`
+}
+
+// LeadIn 正片之前片头和标题卡的总时长
+func (r *BrandingResult) LeadIn() float64 {
+	return r.IntroDuration + r.TitleDuration
+}
+
+// brandingSegment 拼接的一段：视频和音频的滤镜输出标签及时长
+type brandingSegment struct {
+	video    string
+	audio    string
+	duration float64
+}
+
+// ApplyBranding 为成片加上品牌包装，所有处理在一个 filter_complex 中完成：
+// 片头、片尾和标题卡缩放到正片分辨率和帧率，没有音轨的段落补静音，再用 concat 滤镜拼接
+func (f *FFmpeg) ApplyBranding(ctx context.Context, opts *BrandingOptions) (*BrandingResult, error) {
+	bodyDuration, err := f.GetVideoDuration(opts.InputPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to probe video duration: %w", err)
+	}
+
+	workDir, err := os.MkdirTemp(f.tempDir, "branding_")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create work directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	if err := os.MkdirAll(filepath.Dir(opts.OutputPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	width, height := f.getVideoResolution(opts.InputPath)
+	fps := f.probeFrameRate(opts.InputPath)
+	normalize := fmt.Sprintf("fps=%d,scale=%d:%d:force_original_aspect_ratio=decrease,pad=%d:%d:(ow-iw)/2:(oh-ih)/2,setsar=1,format=yuv420p",
+		fps, width, height, width, height)
+
+	var inputs [][]string
+	var filters []string
+	addInput := func(args ...string) int {
+		inputs = append(inputs, args)
+		return len(inputs) - 1
+	}
+	fetch := func(source string) (string, error) {
+		return f.fetchRenderSource(source, workDir, len(inputs))
+	}
+	// segmentAudio 段落音频统一采样格式并补齐到段落时长，没有音轨时生成静音
+	segmentAudio := func(index int, hasAudio bool, duration float64, label string) {
+		if hasAudio {
+			filters = append(filters, fmt.Sprintf("[%d:a]aresample=%d,aformat=sample_fmts=fltp:channel_layouts=%s,apad,atrim=duration=%s[%s]",
+				index, renderSampleRate, renderChannelLayout, formatSeconds(duration), label))
+			return
+		}
+		filters = append(filters, fmt.Sprintf("anullsrc=r=%d:cl=%s,atrim=duration=%s[%s]",
+			renderSampleRate, renderChannelLayout, formatSeconds(duration), label))
+	}
+	// clipSegment 片头、片尾视频按正片规格处理
+	clipSegment := func(source, name string) (*brandingSegment, error) {
+		localPath, err := fetch(source)
+		if err != nil {
+			return nil, err
+		}
+		duration, err := f.GetVideoDuration(localPath)
+		if err != nil || duration <= 0 {
+			return nil, fmt.Errorf("failed to probe %s duration: %v", name, err)
+		}
+		index := addInput("-i", localPath)
+		filters = append(filters, fmt.Sprintf("[%d:v]%s[%sv]", index, normalize, name))
+		segmentAudio(index, f.hasAudioStream(localPath), duration, name+"a")
+		return &brandingSegment{video: "[" + name + "v]", audio: "[" + name + "a]", duration: duration}, nil
+	}
+
+	result := &BrandingResult{}
+	var segments []*brandingSegment
+
+	bodyIndex := addInput("-i", opts.InputPath)
+	bodyVideo := fmt.Sprintf("[%d:v]%s", bodyIndex, normalize)
+	if wm := opts.Watermark; wm != nil && wm.Source != "" {
+		localPath, err := fetch(wm.Source)
+		if err != nil {
+			return nil, err
+		}
+		wmIndex := addInput("-i", localPath)
+		filters = append(filters,
+			fmt.Sprintf("%s[bodybase]", bodyVideo),
+			fmt.Sprintf("[%d:v]%s[wm]", wmIndex, watermarkFilter(wm, width)),
+			fmt.Sprintf("[bodybase][wm]overlay=%s:format=auto,format=yuv420p[bodyv]", watermarkPosition(wm.Position, width, height)))
+	} else {
+		filters = append(filters, bodyVideo+"[bodyv]")
+	}
+	segmentAudio(bodyIndex, f.hasAudioStream(opts.InputPath), bodyDuration, "bodya")
+	body := &brandingSegment{video: "[bodyv]", audio: "[bodya]", duration: bodyDuration}
+
+	if opts.Intro != "" {
+		intro, err := clipSegment(opts.Intro, "intro")
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, intro)
+		result.IntroDuration = intro.duration
+	}
+
+	if card := opts.TitleCard; card != nil && strings.TrimSpace(card.Text) != "" {
+		title, err := f.titleCardSegment(card, workDir, width, height, fps, fetch, addInput, &filters)
+		if err != nil {
+			return nil, err
+		}
+		segmentAudio(0, false, title.duration, "titlea")
+		title.audio = "[titlea]"
+		segments = append(segments, title)
+		result.TitleDuration = title.duration
+	}
+
+	segments = append(segments, body)
+
+	if opts.Outro != "" {
+		outro, err := clipSegment(opts.Outro, "outro")
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, outro)
+		result.OutroDuration = outro.duration
+	}
+
+	var concat strings.Builder
+	for _, segment := range segments {
+		concat.WriteString(segment.video + segment.audio)
+		result.Duration += segment.duration
+	}
+	filters = append(filters, fmt.Sprintf("%sconcat=n=%d:v=1:a=1[vout][aout]", concat.String(), len(segments)))
+
+	args := []string{"-hide_banner", "-nostats", "-progress", "pipe:1"}
+	for _, input := range inputs {
+		args = append(args, input...)
+	}
+	args = append(args,
+		"-filter_complex", strings.Join(filters, ";"),
+		"-map", "[vout]",
+		"-map", "[aout]",
+		"-c:v", "libx264",
+		"-preset", "medium",
+		"-crf", "20",
+		"-pix_fmt", "yuv420p",
+		"-c:a", "aac",
+		"-b:a", "192k",
+		"-movflags", "+faststart",
+		"-y",
+		opts.OutputPath,
+	)
+
+	f.log.Infow("Applying branding",
+		"input", opts.InputPath,
+		"intro", opts.Intro != "",
+		"title_card", result.TitleDuration > 0,
+		"outro", opts.Outro != "",
+		"watermark", opts.Watermark != nil,
+		"output", opts.OutputPath)
+
+	if err := f.runWithProgress(ctx, args, result.Duration, nil); err != nil {
+		os.Remove(opts.OutputPath)
+		return nil, err
+	}
+
+	f.log.Infow("Branding applied", "output", opts.OutputPath, "duration", result.Duration)
+	return result, nil
+}
+
+// titleCardSegment 生成标题卡：背景铺满画面后居中绘制文字，首尾淡入淡出
+func (f *FFmpeg) titleCardSegment(card *TitleCard, workDir string, width, height, fps int,
+	fetch func(string) (string, error), addInput func(...string) int, filters *[]string) (*brandingSegment, error) {
+	duration := card.Duration
+	if duration <= 0 {
+		duration = defaultTitleCardSeconds
+	}
+
+	var index int
+	switch {
+	case card.Background == "":
+		color := card.Color
+		if color == "" {
+			color = "black"
+		}
+		index = addInput("-f", "lavfi", "-t", formatSeconds(duration),
+			"-i", fmt.Sprintf("color=c=%s:s=%dx%d:r=%d", color, width, height, fps))
+	case card.IsImage:
+		localPath, err := fetch(card.Background)
+		if err != nil {
+			return nil, err
+		}
+		index = addInput("-loop", "1", "-framerate", strconv.Itoa(fps), "-t", formatSeconds(duration), "-i", localPath)
+	default:
+		localPath, err := fetch(card.Background)
+		if err != nil {
+			return nil, err
+		}
+		index = addInput("-stream_loop", "-1", "-t", formatSeconds(duration), "-i", localPath)
+	}
+
+	// 文字写入临时文件以避免滤镜转义问题
+	textFile := filepath.Join(workDir, "title.txt")
+	if err := os.WriteFile(textFile, []byte(card.Text), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write text file: %w", err)
+	}
+	font := card.Font
+	if font == "" {
+		font = defaultTitleCardFont
+	}
+	fontColor := card.FontColor
+	if fontColor == "" {
+		fontColor = "white"
+	}
+	fontSize := card.FontSize
+	if fontSize <= 0 {
+		fontSize = height / 12
+	}
+
+	fade := math.Min(titleCardFadeSeconds, duration/4)
+	chain := []string{
+		fmt.Sprintf("fps=%d", fps),
+		fmt.Sprintf("scale=%d:%d:force_original_aspect_ratio=increase", width, height),
+		fmt.Sprintf("crop=%d:%d", width, height),
+		"setsar=1",
+		fmt.Sprintf("drawtext=textfile='%s':font='%s':fontsize=%d:fontcolor=%s:shadowcolor=black@0.6:shadowx=2:shadowy=2:x=(w-text_w)/2:y=(h-text_h)/2",
+			escapeFilterPath(textFile), escapeFilterPath(font), fontSize, fontColor),
+		fmt.Sprintf("trim=duration=%s", formatSeconds(duration)),
+		fmt.Sprintf("fade=t=in:st=0:d=%s", formatSeconds(fade)),
+		fmt.Sprintf("fade=t=out:st=%s:d=%s", formatSeconds(duration-fade), formatSeconds(fade)),
+		"format=yuv420p",
+	}
+	*filters = append(*filters, fmt.Sprintf("[%d:v]%s[titlev]", index, strings.Join(chain, ",")))
+	return &brandingSegment{video: "[titlev]", duration: duration}, nil
+}
+
+// watermarkFilter 按画面宽度缩放水印并设置透明度
+func watermarkFilter(wm *Watermark, frameWidth int) string {
+	scale := wm.Scale
+	if scale <= 0 || scale > 1 {
+		scale = defaultWatermarkScale
+	}
+	opacity := wm.Opacity
+	if opacity <= 0 || opacity > 1 {
+		opacity = defaultWatermarkOpacity
+	}
+	width := int(float64(frameWidth)*scale) / 2 * 2
+	return fmt.Sprintf("scale=%d:-2,format=rgba,colorchannelmixer=aa=%s", width, formatFloat(opacity))
+}
+
+// watermarkPosition overlay 的坐标表达式，边距为画面短边的 3%
+func watermarkPosition(position string, width, height int) string {
+	margin := int(float64(min(width, height)) * 0.03)
+	switch position {
+	case WatermarkTopLeft:
+		return fmt.Sprintf("x=%d:y=%d", margin, margin)
+	case WatermarkBottomLeft:
+		return fmt.Sprintf("x=%d:y=H-h-%d", margin, margin)
+	case WatermarkBottomRight:
+		return fmt.Sprintf("x=W-w-%d:y=H-h-%d", margin, margin)
+	case WatermarkCenter:
+		return "x=(W-w)/2:y=(H-h)/2"
+	default:
+		return fmt.Sprintf("x=W-w-%d:y=%d", margin, margin)
+	}
+}
+
+// probeFrameRate 获取视频帧率（取整），获取失败时返回 30
+func (f *FFmpeg) probeFrameRate(videoPath string) int {
+	output, err := exec.Command("ffprobe",
+		"-v", "error",
+		"-select_streams", "v:0",
+		"-show_entries", "stream=r_frame_rate",
+		"-of", "default=noprint_wrappers=1:nokey=1",
+		videoPath,
+	).Output()
+	if err != nil {
+		f.log.Warnw("Failed to get video frame rate", "path", videoPath, "error", err)
+		return defaultBrandingFPS
+	}
+	if fps := parseFrameRate(strings.TrimSpace(string(output))); fps > 0 {
+		return int(math.Round(fps))
+	}
+	return defaultBrandingFPS
+}
+
+// parseFrameRate 解析 ffprobe 的帧率，如 30000/1001
+func parseFrameRate(value string) float64 {
+	num, den, ok := strings.Cut(value, "/")
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0
+	}
+	if !ok {
+		return n
+	}
+	d, err := strconv.ParseFloat(den, 64)
+	if err != nil || d == 0 {
+		return 0
+	}
+	return n / d
+}
@@ -0,0 +1,40 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+
+	"github.com/drama-generator/backend/pkg/colormatch"
+)
+
+// 配色统计的取样帧率和缩略图宽度，统计值为均值，不需要全分辨率
+const (
+	colorSampleFPS   = 2
+	colorSampleWidth = 320
+)
+
+// MeasureColor 用 signalstats 统计片段 [start, end) 的平均亮度、亮度分布、色差和饱和度
+// end 不大于 start 时统计整个片段
+func (f *FFmpeg) MeasureColor(ctx context.Context, path string, start, end float64) (colormatch.Stats, error) {
+	args := []string{"-hide_banner", "-nostats"}
+	if end > start {
+		args = append(args, "-ss", formatSeconds(start), "-t", formatSeconds(end-start))
+	}
+	args = append(args,
+		"-i", path,
+		"-vf", fmt.Sprintf("fps=%d,scale=%d:-2,signalstats,metadata=print", colorSampleFPS, colorSampleWidth),
+		"-an", "-f", "null", "-",
+	)
+	output, err := exec.CommandContext(ctx, "ffmpeg", args...).CombinedOutput()
+	if err != nil {
+		return colormatch.Stats{}, fmt.Errorf("ffmpeg color measurement failed: %w, output: %s", err, lastLines(string(output), 5))
+	}
+
+	stats, err := colormatch.ParseSignalStats(string(output))
+	if err != nil {
+		return colormatch.Stats{}, err
+	}
+	f.log.Infow("Color measured", "path", path, "luma", stats.Luma, "u", stats.U, "v", stats.V, "saturation", stats.Saturation)
+	return stats, nil
+}
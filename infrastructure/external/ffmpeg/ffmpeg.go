@@ -34,11 +34,19 @@ type VideoClip struct {
 	StartTime  float64
 	EndTime    float64
 	Transition map[string]interface{}
+	Gain       float64 // 裁剪时对音频施加的增益（dB），用于统一不同厂商片段的响度
+
+	// TargetDuration 片段应占的时长（秒），比片段短且 FrameRate 允许拉伸时放慢填满
+	TargetDuration float64
+
+	// Effects 裁剪时施加的画面特效，用于镜头间的配色校正
+	Effects []RenderEffect
 }
 
 type MergeOptions struct {
 	OutputPath string
 	Clips      []VideoClip
+	FrameRate  *FrameRate // 统一的帧率，为空时保留各片段的原帧率
 }
 
 func (f *FFmpeg) MergeVideos(opts *MergeOptions) (string, error) {
@@ -46,11 +54,14 @@ func (f *FFmpeg) MergeVideos(opts *MergeOptions) (string, error) {
 		return "", fmt.Errorf("no video clips to merge")
 	}
 
-	f.log.Infow("Starting video merge with trimming", "clips_count", len(opts.Clips))
+	f.log.Infow("Starting video merge with trimming", "clips_count", len(opts.Clips), "frame_rate", opts.FrameRate.String())
 
 	// 下载并裁剪所有视频片段
 	trimmedPaths := make([]string, 0, len(opts.Clips))
 	downloadedPaths := make([]string, 0, len(opts.Clips))
+	// 放慢的片段在转场计算中使用放慢后的时长
+	trimmedClips := make([]VideoClip, len(opts.Clips))
+	copy(trimmedClips, opts.Clips)
 
 	for i, clip := range opts.Clips {
 		// 下载原始视频
@@ -65,19 +76,25 @@ func (f *FFmpeg) MergeVideos(opts *MergeOptions) (string, error) {
 
 		// 裁剪视频片段（根据StartTime和EndTime）
 		trimmedPath := filepath.Join(f.tempDir, fmt.Sprintf("trimmed_%d_%d.mp4", time.Now().Unix(), i))
-		err = f.trimVideo(localPath, trimmedPath, clip.StartTime, clip.EndTime)
+		stretchedDuration, err := f.trimVideo(localPath, trimmedPath, &opts.Clips[i], opts.FrameRate)
 		if err != nil {
 			f.cleanup(downloadedPaths)
 			f.cleanup(trimmedPaths)
 			return "", fmt.Errorf("failed to trim clip %d: %w", i, err)
 		}
 		trimmedPaths = append(trimmedPaths, trimmedPath)
+		if stretchedDuration > 0 {
+			trimmedClips[i].StartTime = 0
+			trimmedClips[i].EndTime = stretchedDuration
+			trimmedClips[i].Duration = stretchedDuration
+		}
 
 		f.log.Infow("Clip trimmed",
 			"index", i,
 			"start", clip.StartTime,
 			"end", clip.EndTime,
-			"duration", clip.EndTime-clip.StartTime)
+			"duration", clip.EndTime-clip.StartTime,
+			"gain_db", clip.Gain)
 	}
 
 	// 清理下载的原始文件
@@ -91,7 +108,7 @@ func (f *FFmpeg) MergeVideos(opts *MergeOptions) (string, error) {
 	}
 
 	// 合并裁剪后的视频片段（支持转场效果）
-	err := f.concatenateVideosWithTransitions(trimmedPaths, opts.Clips, opts.OutputPath)
+	err := f.concatenateVideosWithTransitions(trimmedPaths, trimmedClips, opts.OutputPath)
 
 	// 清理裁剪后的临时文件
 	f.cleanup(trimmedPaths)
@@ -161,84 +178,100 @@ func (f *FFmpeg) downloadVideo(url, destPath string) (string, error) {
 	return destPath, nil
 }
 
-func (f *FFmpeg) trimVideo(inputPath, outputPath string, startTime, endTime float64) error {
+// trimVideo 裁剪并重新编码片段；指定帧率时转换到目标帧率，需要放慢时返回放慢后的时长，否则返回 0
+func (f *FFmpeg) trimVideo(inputPath, outputPath string, clip *VideoClip, rate *FrameRate) (float64, error) {
+	startTime, endTime := clip.StartTime, clip.EndTime
 	f.log.Infow("Trimming video",
 		"input", inputPath,
 		"output", outputPath,
 		"start", startTime,
 		"end", endTime)
 
-	// 如果startTime和endTime都为0，或者endTime <= startTime，复制整个视频
-	// 使用重新编码而非-c copy以确保输出文件完整性
-	if (startTime == 0 && endTime == 0) || endTime <= startTime {
-		f.log.Infow("No valid trim range, re-encoding entire video")
-
-		cmd := exec.Command("ffmpeg",
-			"-i", inputPath,
-			"-c:v", "libx264",
-			"-preset", "fast",
-			"-crf", "23",
-			"-c:a", "aac",
-			"-b:a", "128k",
-			"-movflags", "+faststart",
-			"-y",
-			outputPath,
-		)
-
-		output, err := cmd.CombinedOutput()
-		if err != nil {
-			f.log.Errorw("FFmpeg re-encode failed", "error", err, "output", string(output))
-			return fmt.Errorf("ffmpeg re-encode failed: %w, output: %s", err, string(output))
+	// 使用重新编码而非-c copy以确保输出文件完整性，避免Windows环境下流信息丢失
+	encodeArgs := []string{
+		"-c:v", "libx264",
+		"-preset", "fast",
+		"-crf", "23",
+		"-c:a", "aac",
+		"-b:a", "128k",
+		"-movflags", "+faststart",
+	}
+	hasAudio := f.hasAudioStream(inputPath)
+	// 如果startTime和endTime都为0，或者endTime <= startTime，使用整个视频
+	wholeClip := (startTime == 0 && endTime == 0) || endTime <= startTime
+
+	// 先做配色校正，再统一帧率，比目标时长短的片段按设置放慢
+	videoFilters := effectFilters(clip.Effects)
+	stretch := 1.0
+	if rate != nil && rate.FPS > 0 {
+		sourceDuration := endTime - startTime
+		if wholeClip {
+			sourceDuration = 0
+			if clip.TargetDuration > 0 && rate.MaxStretch > 1 {
+				if probed, err := f.GetVideoDuration(inputPath); err == nil {
+					sourceDuration = probed
+				}
+			}
 		}
+		stretch = rate.stretchFactor(sourceDuration, clip.TargetDuration)
+		videoFilters = append(videoFilters, rate.conformFilters(float64(f.probeFrameRate(inputPath)), stretch)...)
+		if stretch > 1 {
+			f.log.Infow("Stretching clip to fill target duration",
+				"input", inputPath,
+				"source_duration", sourceDuration,
+				"target_duration", clip.TargetDuration,
+				"factor", stretch)
+		}
+	}
 
-		f.log.Infow("Video re-encoded successfully", "output", outputPath)
-		return nil
+	// 片段响度增益，没有音频流时 -af 会导致失败；放慢的片段音频按相同倍数减速（atempo 低于 0.5 时拆分为多级），
+	// 取整误差留下的空缺补静音
+	var audioFilters []string
+	if clip.Gain != 0 && hasAudio {
+		audioFilters = append(audioFilters, fmt.Sprintf("volume=%sdB", formatFloat(clip.Gain)))
+	}
+	if stretch > 1 && hasAudio {
+		audioFilters = append(audioFilters, atempoFilters(1/stretch)...)
+		audioFilters = append(audioFilters, "apad")
 	}
 
-	// 使用FFmpeg裁剪视频
-	// -ss: 开始时间（秒）
-	// -to/-t: 结束时间或持续时间
-	// 使用重新编码而非-c copy以确保输出文件完整性，避免Windows环境下流信息丢失
-	var cmd *exec.Cmd
-	if endTime > 0 {
-		// 有明确的结束时间
-		cmd = exec.Command("ffmpeg",
-			"-i", inputPath,
-			"-ss", fmt.Sprintf("%.2f", startTime),
-			"-to", fmt.Sprintf("%.2f", endTime),
-			"-c:v", "libx264",
-			"-preset", "fast",
-			"-crf", "23",
-			"-c:a", "aac",
-			"-b:a", "128k",
-			"-movflags", "+faststart",
-			"-y",
-			outputPath,
-		)
+	var args []string
+	var stretchedDuration float64
+	if stretch > 1 {
+		// 放慢时在输入端截取，输出端的 -ss/-to 按放慢后的时间戳计算
+		sourceDuration := clip.TargetDuration / stretch
+		if !wholeClip {
+			sourceDuration = endTime - startTime
+			args = append(args, "-ss", fmt.Sprintf("%.2f", startTime), "-t", fmt.Sprintf("%.2f", sourceDuration))
+		}
+		stretchedDuration = sourceDuration * stretch
+		args = append(args, "-i", inputPath, "-t", formatSeconds(stretchedDuration))
 	} else {
-		// 只有开始时间，裁剪到视频末尾
-		cmd = exec.Command("ffmpeg",
-			"-i", inputPath,
-			"-ss", fmt.Sprintf("%.2f", startTime),
-			"-c:v", "libx264",
-			"-preset", "fast",
-			"-crf", "23",
-			"-c:a", "aac",
-			"-b:a", "128k",
-			"-movflags", "+faststart",
-			"-y",
-			outputPath,
-		)
+		args = append(args, "-i", inputPath)
+		if !wholeClip {
+			// -ss: 开始时间（秒），-to: 结束时间
+			args = append(args, "-ss", fmt.Sprintf("%.2f", startTime), "-to", fmt.Sprintf("%.2f", endTime))
+		} else {
+			f.log.Infow("No valid trim range, re-encoding entire video")
+		}
+	}
+	if len(videoFilters) > 0 {
+		args = append(args, "-vf", strings.Join(videoFilters, ","))
 	}
+	if len(audioFilters) > 0 {
+		args = append(args, "-af", strings.Join(audioFilters, ","))
+	}
+	args = append(args, encodeArgs...)
+	args = append(args, "-y", outputPath)
 
-	output, err := cmd.CombinedOutput()
+	output, err := exec.Command("ffmpeg", args...).CombinedOutput()
 	if err != nil {
 		f.log.Errorw("FFmpeg trim failed", "error", err, "output", string(output))
-		return fmt.Errorf("ffmpeg trim failed: %w, output: %s", err, string(output))
+		return 0, fmt.Errorf("ffmpeg trim failed: %w, output: %s", err, string(output))
 	}
 
-	f.log.Infow("Video trimmed successfully", "output", outputPath)
-	return nil
+	f.log.Infow("Video trimmed successfully", "output", outputPath, "filters", videoFilters)
+	return stretchedDuration, nil
 }
 
 func (f *FFmpeg) concatenateVideosWithTransitions(inputPaths []string, clips []VideoClip, outputPath string) error {
@@ -0,0 +1,84 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// 帧率转换方式
+const (
+	InterpolationMotion = "minterpolate" // 运动补偿插帧，画面最平滑，耗时最长
+	InterpolationBlend  = "blend"        // 相邻帧混合
+	InterpolationDrop   = "drop"         // 丢弃或重复帧
+)
+
+// minStretchRatio 片段时长不足目标时长的比例超过该值才拉伸，避免为取整误差重新插帧
+const minStretchRatio = 1.02
+
+// FrameRate 合成时统一的帧率：各片段按转换方式转换到目标帧率，
+// MaxStretch 大于 1 时比目标时长短的片段（如厂商生成的慢动作）放慢填满目标时长，最多放慢到该倍数
+type FrameRate struct {
+	FPS           int     `json:"fps"`
+	Interpolation string  `json:"interpolation,omitempty"`
+	MaxStretch    float64 `json:"max_stretch,omitempty"`
+}
+
+// ValidInterpolation 是否为支持的帧率转换方式
+func ValidInterpolation(interpolation string) bool {
+	switch interpolation {
+	case InterpolationMotion, InterpolationBlend, InterpolationDrop:
+		return true
+	}
+	return false
+}
+
+// stretchFactor 片段放慢的倍数，不需要或无法拉伸时返回 1
+func (r *FrameRate) stretchFactor(sourceDuration, targetDuration float64) float64 {
+	if r == nil || r.MaxStretch <= 1 || sourceDuration <= 0 || targetDuration <= 0 {
+		return 1
+	}
+	factor := targetDuration / sourceDuration
+	if factor < minStretchRatio {
+		return 1
+	}
+	return math.Min(factor, r.MaxStretch)
+}
+
+// conformFilters 将片段转换到目标帧率的滤镜：帧率相同且未放慢时只锁定帧率；
+// 放慢的片段先拉长时间戳，再按转换方式补出中间帧
+func (r *FrameRate) conformFilters(sourceFPS, stretch float64) []string {
+	var filters []string
+	if stretch > 1 {
+		filters = append(filters, fmt.Sprintf("setpts=%s*PTS", formatFloat(stretch)))
+		sourceFPS /= stretch
+	}
+	if sourceFPS > 0 && math.Abs(sourceFPS-float64(r.FPS)) < 0.01 {
+		return append(filters, fmt.Sprintf("fps=%d", r.FPS))
+	}
+
+	switch r.Interpolation {
+	case InterpolationMotion:
+		filters = append(filters, fmt.Sprintf("minterpolate=fps=%d:mi_mode=mci:mc_mode=aobmc:me_mode=bidir:vsbmc=1", r.FPS))
+	case InterpolationBlend:
+		filters = append(filters, fmt.Sprintf("framerate=fps=%d", r.FPS))
+	default:
+		filters = append(filters, fmt.Sprintf("fps=%d", r.FPS))
+	}
+	return filters
+}
+
+// String 日志中显示的帧率设置
+func (r *FrameRate) String() string {
+	if r == nil {
+		return "source"
+	}
+	parts := []string{fmt.Sprintf("%dfps", r.FPS)}
+	if r.Interpolation != "" {
+		parts = append(parts, r.Interpolation)
+	}
+	if r.MaxStretch > 1 {
+		parts = append(parts, fmt.Sprintf("stretch<=%sx", formatFloat(r.MaxStretch)))
+	}
+	return strings.Join(parts, ",")
+}
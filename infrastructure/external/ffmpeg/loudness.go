@@ -0,0 +1,266 @@
+package ffmpeg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// 内置的输出档位响度目标
+const (
+	LoudnessProfileStreaming = "streaming" // 短视频平台和网页播放器
+	LoudnessProfileBroadcast = "broadcast" // EBU R128 广播标准
+	LoudnessProfileMobile    = "mobile"    // 手机外放，响度更高、动态更小
+)
+
+// 母带处理的默认参数
+const (
+	defaultNoiseFloor  = -25.0 // afftdn 噪声底（dB）
+	masterSampleRate   = 48000
+	masterAudioBitrate = "192k"
+)
+
+// LoudnessTarget EBU R128 响度目标
+type LoudnessTarget struct {
+	Integrated float64 `json:"integrated"` // 综合响度（LUFS）
+	TruePeak   float64 `json:"true_peak"`  // 真峰值上限（dBTP）
+	Range      float64 `json:"range"`      // 响度范围（LU）
+}
+
+// LoudnessProfiles 内置输出档位的响度目标
+var LoudnessProfiles = map[string]LoudnessTarget{
+	LoudnessProfileStreaming: {Integrated: -14, TruePeak: -1, Range: 11},
+	LoudnessProfileBroadcast: {Integrated: -23, TruePeak: -1, Range: 15},
+	LoudnessProfileMobile:    {Integrated: -12, TruePeak: -1, Range: 7},
+}
+
+// Validate 检查响度目标是否在 loudnorm 支持的范围内
+func (t LoudnessTarget) Validate() error {
+	if t.Integrated < -70 || t.Integrated > -5 {
+		return fmt.Errorf("integrated loudness must be between -70 and -5 LUFS")
+	}
+	if t.TruePeak < -9 || t.TruePeak > 0 {
+		return fmt.Errorf("true peak must be between -9 and 0 dBTP")
+	}
+	if t.Range < 1 || t.Range > 50 {
+		return fmt.Errorf("loudness range must be between 1 and 50 LU")
+	}
+	return nil
+}
+
+// silentLoudness 静音素材的响度，loudnorm 输出 -inf，存储时用该值代替以便 JSON 序列化
+const silentLoudness = -99.0
+
+// LoudnessMeasurement loudnorm 测量结果
+type LoudnessMeasurement struct {
+	Integrated float64 `json:"integrated"` // LUFS
+	TruePeak   float64 `json:"true_peak"`  // dBTP
+	Range      float64 `json:"range"`      // LU
+	Threshold  float64 `json:"threshold"`  // 门限（LUFS），第二遍标准化需要
+}
+
+// Silent 素材没有可测量的声音
+func (m *LoudnessMeasurement) Silent() bool {
+	return m.Integrated <= -70
+}
+
+// MasteringOptions 母带处理参数
+type MasteringOptions struct {
+	InputPath  string
+	OutputPath string
+	Target     LoudnessTarget
+	Denoise    bool    // 标准化前使用 afftdn 降噪
+	NoiseFloor float64 // 降噪的噪声底（dB），为 0 时使用默认值
+}
+
+// MasteringResult 母带处理前后的响度
+type MasteringResult struct {
+	Input  *LoudnessMeasurement `json:"input"`
+	Output *LoudnessMeasurement `json:"output,omitempty"`
+	Target LoudnessTarget       `json:"target"`
+}
+
+// loudnormStats loudnorm 以 print_format=json 输出的统计，数值均为字符串
+type loudnormStats struct {
+	InputI       string `json:"input_i"`
+	InputTP      string `json:"input_tp"`
+	InputLRA     string `json:"input_lra"`
+	InputThresh  string `json:"input_thresh"`
+	OutputI      string `json:"output_i"`
+	OutputTP     string `json:"output_tp"`
+	OutputLRA    string `json:"output_lra"`
+	OutputThresh string `json:"output_thresh"`
+	TargetOffset string `json:"target_offset"`
+}
+
+// MeasureLoudness 测量文件音频的 EBU R128 响度（loudnorm 第一遍），没有音频流时返回错误
+// start、end 为测量范围（秒），end <= start 时测量整个文件
+func (f *FFmpeg) MeasureLoudness(ctx context.Context, path string, start, end float64) (*LoudnessMeasurement, error) {
+	var inputArgs []string
+	if end > start {
+		if start > 0 {
+			inputArgs = append(inputArgs, "-ss", formatFloat(start))
+		}
+		inputArgs = append(inputArgs, "-to", formatFloat(end))
+	}
+	stats, err := f.measureLoudnorm(ctx, path, inputArgs, "", LoudnessProfiles[LoudnessProfileStreaming])
+	if err != nil {
+		return nil, err
+	}
+	return stats.input(), nil
+}
+
+// MasterAudio 对视频的音频做两遍 loudnorm 标准化：第一遍测量，第二遍按测量值线性调整到目标响度，
+// 线性调整会超出真峰值上限时 loudnorm 自动切换为带真峰值限幅的动态模式。
+// 视频流直接复制；没有音频流或音频为静音时原样复制文件
+func (f *FFmpeg) MasterAudio(ctx context.Context, opts *MasteringOptions) (*MasteringResult, error) {
+	if err := opts.Target.Validate(); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(opts.OutputPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	if !f.hasAudioStream(opts.InputPath) {
+		f.log.Infow("No audio stream, skipping mastering", "input", opts.InputPath)
+		return nil, f.copyFile(opts.InputPath, opts.OutputPath)
+	}
+
+	prefilter := ""
+	if opts.Denoise {
+		noiseFloor := opts.NoiseFloor
+		if noiseFloor == 0 {
+			noiseFloor = defaultNoiseFloor
+		}
+		prefilter = fmt.Sprintf("afftdn=nf=%s", formatFloat(noiseFloor))
+	}
+
+	stats, err := f.measureLoudnorm(ctx, opts.InputPath, nil, prefilter, opts.Target)
+	if err != nil {
+		return nil, err
+	}
+	result := &MasteringResult{Input: stats.input(), Target: opts.Target}
+	if result.Input.Silent() {
+		f.log.Infow("Audio is silent, skipping mastering", "input", opts.InputPath)
+		return result, f.copyFile(opts.InputPath, opts.OutputPath)
+	}
+
+	// loudnorm 内部以 192kHz 处理，输出前重采样；重采样后再用 alimiter 兜底，保证采样峰值不超过真峰值上限
+	filter := fmt.Sprintf("loudnorm=%s:measured_I=%s:measured_TP=%s:measured_LRA=%s:measured_thresh=%s:offset=%s:linear=true:print_format=json,"+
+		"aresample=%d,alimiter=limit=%.4f:level=0",
+		loudnormTargetArgs(opts.Target), stats.InputI, stats.InputTP, stats.InputLRA, stats.InputThresh, stats.TargetOffset,
+		masterSampleRate, math.Pow(10, opts.Target.TruePeak/20))
+	if prefilter != "" {
+		filter = prefilter + "," + filter
+	}
+
+	args := []string{"-hide_banner", "-nostats",
+		"-i", opts.InputPath,
+		"-map", "0:v?", "-map", "0:a:0",
+		"-af", filter,
+		"-c:v", "copy",
+		"-c:a", "aac", "-b:a", masterAudioBitrate, "-ar", strconv.Itoa(masterSampleRate),
+		"-movflags", "+faststart",
+		"-y", opts.OutputPath,
+	}
+	output, err := exec.CommandContext(ctx, "ffmpeg", args...).CombinedOutput()
+	if err != nil {
+		f.log.Errorw("FFmpeg mastering failed", "error", err, "output", string(output))
+		return nil, fmt.Errorf("ffmpeg mastering failed: %w", err)
+	}
+	if outputStats, err := parseLoudnormStats(string(output)); err == nil {
+		result.Output = outputStats.output()
+	}
+
+	f.log.Infow("Audio mastered",
+		"output", opts.OutputPath,
+		"input_i", result.Input.Integrated,
+		"target_i", opts.Target.Integrated,
+		"denoise", opts.Denoise)
+	return result, nil
+}
+
+// measureLoudnorm 运行 loudnorm 第一遍测量，prefilter 为测量前的处理（如降噪）
+func (f *FFmpeg) measureLoudnorm(ctx context.Context, path string, inputArgs []string, prefilter string, target LoudnessTarget) (*loudnormStats, error) {
+	filter := fmt.Sprintf("loudnorm=%s:print_format=json", loudnormTargetArgs(target))
+	if prefilter != "" {
+		filter = prefilter + "," + filter
+	}
+
+	args := append([]string{"-hide_banner", "-nostats"}, inputArgs...)
+	args = append(args,
+		"-i", path,
+		"-map", "0:a:0",
+		"-af", filter,
+		"-f", "null", "-",
+	)
+	output, err := exec.CommandContext(ctx, "ffmpeg", args...).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg loudness measurement failed: %w, output: %s", err, lastLines(string(output), 5))
+	}
+	return parseLoudnormStats(string(output))
+}
+
+func loudnormTargetArgs(target LoudnessTarget) string {
+	return fmt.Sprintf("I=%s:TP=%s:LRA=%s",
+		formatFloat(target.Integrated), formatFloat(target.TruePeak), formatFloat(target.Range))
+}
+
+// parseLoudnormStats 解析 FFmpeg 日志末尾 loudnorm 输出的 JSON
+func parseLoudnormStats(output string) (*loudnormStats, error) {
+	start := strings.LastIndex(output, "{")
+	end := strings.LastIndex(output, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("loudnorm statistics not found in ffmpeg output")
+	}
+
+	var stats loudnormStats
+	if err := json.Unmarshal([]byte(output[start:end+1]), &stats); err != nil {
+		return nil, fmt.Errorf("failed to parse loudnorm statistics: %w", err)
+	}
+	if stats.InputI == "" {
+		return nil, fmt.Errorf("loudnorm statistics not found in ffmpeg output")
+	}
+	return &stats, nil
+}
+
+func (s *loudnormStats) input() *LoudnessMeasurement {
+	return &LoudnessMeasurement{
+		Integrated: parseLoudnormValue(s.InputI),
+		TruePeak:   parseLoudnormValue(s.InputTP),
+		Range:      parseLoudnormValue(s.InputLRA),
+		Threshold:  parseLoudnormValue(s.InputThresh),
+	}
+}
+
+func (s *loudnormStats) output() *LoudnessMeasurement {
+	return &LoudnessMeasurement{
+		Integrated: parseLoudnormValue(s.OutputI),
+		TruePeak:   parseLoudnormValue(s.OutputTP),
+		Range:      parseLoudnormValue(s.OutputLRA),
+		Threshold:  parseLoudnormValue(s.OutputThresh),
+	}
+}
+
+// parseLoudnormValue 静音时 loudnorm 输出 "-inf"，解析失败同样视为静音
+func parseLoudnormValue(value string) float64 {
+	v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || math.IsInf(v, 0) || math.IsNaN(v) {
+		return silentLoudness
+	}
+	return v
+}
+
+// lastLines 返回输出的最后几行，用于错误信息
+func lastLines(output string, n int) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,123 @@
+package ffmpeg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+
+	"github.com/drama-generator/backend/pkg/qc"
+)
+
+// 质检滤镜：黑场至少 0.5 秒且像素亮度低于 10%，冻帧至少 2 秒且帧间差异低于 -60dB
+const (
+	qcBlackDetect  = "blackdetect=d=0.5:pix_th=0.10"
+	qcFreezeDetect = "freezedetect=n=-60dB:d=2"
+)
+
+// ffprobeStreams ffprobe -of json 输出中质检需要的字段
+type ffprobeStreams struct {
+	Streams []struct {
+		CodecType string `json:"codec_type"`
+		Width     int    `json:"width"`
+		Height    int    `json:"height"`
+	} `json:"streams"`
+	Format struct {
+		Duration string `json:"duration"`
+	} `json:"format"`
+}
+
+// ProbeVideoQC 探测视频的时长、分辨率和音轨，并用 blackdetect/freezedetect 扫描黑场和冻帧
+func (f *FFmpeg) ProbeVideoQC(ctx context.Context, path string) (*qc.VideoProbe, error) {
+	streams, err := probeStreams(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	probe := &qc.VideoProbe{}
+	probe.Duration, _ = strconv.ParseFloat(streams.Format.Duration, 64)
+	for _, stream := range streams.Streams {
+		switch stream.CodecType {
+		case "video":
+			if probe.Width == 0 {
+				probe.Width, probe.Height = stream.Width, stream.Height
+			}
+		case "audio":
+			probe.HasAudio = true
+		}
+	}
+	if probe.Width == 0 || probe.Height == 0 {
+		return nil, fmt.Errorf("no video stream")
+	}
+
+	args := []string{"-hide_banner", "-nostats",
+		"-i", path,
+		"-vf", qcBlackDetect + "," + qcFreezeDetect,
+		"-an", "-f", "null", "-",
+	}
+	output, err := exec.CommandContext(ctx, "ffmpeg", args...).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg qc scan failed: %w, output: %s", err, lastLines(string(output), 5))
+	}
+	probe.Black = qc.ParseBlackDetect(string(output))
+	probe.Frozen = qc.ParseFreezeDetect(string(output), probe.Duration)
+
+	f.log.Infow("Video QC probed",
+		"path", path,
+		"duration", probe.Duration,
+		"resolution", fmt.Sprintf("%dx%d", probe.Width, probe.Height),
+		"audio", probe.HasAudio,
+		"black_segments", len(probe.Black),
+		"frozen_segments", len(probe.Frozen))
+	return probe, nil
+}
+
+// ProbeImageQC 探测图片尺寸，并按封面分析的缩略图宽度计算亮度和对比度
+func (f *FFmpeg) ProbeImageQC(ctx context.Context, path string) (*qc.ImageProbe, error) {
+	streams, err := probeStreams(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	if len(streams.Streams) == 0 || streams.Streams[0].Width == 0 || streams.Streams[0].Height == 0 {
+		return nil, fmt.Errorf("no image stream")
+	}
+
+	workDir, err := os.MkdirTemp("", "drama-qc-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create work directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	preview := filepath.Join(workDir, "preview.jpg")
+	if err := f.ExtractFrame(ctx, path, preview, 0, posterAnalysisWidth); err != nil {
+		return nil, err
+	}
+	stats, err := analyzeFrame(preview)
+	if err != nil {
+		return nil, fmt.Errorf("failed to analyze image: %w", err)
+	}
+
+	return &qc.ImageProbe{Width: streams.Streams[0].Width, Height: streams.Streams[0].Height, Stats: stats}, nil
+}
+
+// probeStreams 用 ffprobe 读取流类型、分辨率和时长
+func probeStreams(ctx context.Context, path string) (*ffprobeStreams, error) {
+	output, err := exec.CommandContext(ctx, "ffprobe",
+		"-v", "error",
+		"-show_entries", "stream=codec_type,width,height:format=duration",
+		"-of", "json",
+		path,
+	).Output()
+	if err != nil {
+		return nil, fmt.Errorf("ffprobe failed: %w", err)
+	}
+
+	var streams ffprobeStreams
+	if err := json.Unmarshal(output, &streams); err != nil {
+		return nil, fmt.Errorf("failed to parse ffprobe output: %w", err)
+	}
+	return &streams, nil
+}
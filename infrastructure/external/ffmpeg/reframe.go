@@ -0,0 +1,187 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"image/jpeg"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/drama-generator/backend/pkg/reframe"
+)
+
+// 画面主体分析的缩略图宽度、取样间隔和最多分析的画面数
+const (
+	reframeAnalysisWidth  = 160
+	reframeSampleInterval = 0.5 // 秒
+	reframeMaxSamples     = 300
+)
+
+// ReframeAnalysisOptions 画面主体分析参数
+type ReframeAnalysisOptions struct {
+	InputPath string  // 本地路径或远程 URL
+	Aspect    float64 // 目标宽高比
+	Duration  float64 // 视频时长（秒），图片为 0
+	IsImage   bool
+}
+
+// AnalyzeReframe 按间隔截取缩略图，逐帧寻找显著度（人脸、细节）最高的裁剪位置，平滑后返回裁剪关键帧
+// 图片只返回一个关键帧
+func (f *FFmpeg) AnalyzeReframe(ctx context.Context, opts *ReframeAnalysisOptions) ([]reframe.Keyframe, error) {
+	workDir, err := os.MkdirTemp(f.tempDir, "reframe_")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create work directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	interval := reframeSampleInterval
+	if opts.Duration/interval > reframeMaxSamples {
+		interval = opts.Duration / reframeMaxSamples
+	}
+	scale := fmt.Sprintf("scale=%d:-2", reframeAnalysisWidth)
+	args := []string{"-hide_banner", "-nostats", "-i", opts.InputPath}
+	if opts.IsImage {
+		args = append(args, "-vf", scale, "-frames:v", "1")
+	} else {
+		args = append(args, "-vf", fmt.Sprintf("fps=1/%s,%s", formatFloat(interval), scale))
+	}
+	args = append(args, "-q:v", "5", "-y", filepath.Join(workDir, "frame_%05d.jpg"))
+	if err := f.runWithProgress(ctx, args, 0, nil); err != nil {
+		return nil, fmt.Errorf("failed to sample frames: %w", err)
+	}
+
+	frames, err := filepath.Glob(filepath.Join(workDir, "frame_*.jpg"))
+	if err != nil || len(frames) == 0 {
+		return nil, fmt.Errorf("no frames sampled from %s", opts.InputPath)
+	}
+	sort.Strings(frames)
+
+	keyframes := make([]reframe.Keyframe, 0, len(frames))
+	for i, path := range frames {
+		t := math.Round(float64(i)*interval*1000) / 1000
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		img, err := jpeg.Decode(file)
+		file.Close()
+		if err != nil {
+			f.log.Warnw("Failed to decode reframe sample", "path", path, "error", err)
+			continue
+		}
+		keyframes = append(keyframes, reframe.Focus(img, opts.Aspect, t))
+	}
+	if len(keyframes) == 0 {
+		return nil, fmt.Errorf("no frames could be analyzed")
+	}
+
+	if opts.IsImage {
+		return keyframes[:1], nil
+	}
+	smoothed := reframe.Smooth(keyframes)
+	f.log.Infow("Reframe analyzed", "input", opts.InputPath, "samples", len(keyframes), "keyframes", len(smoothed))
+	return smoothed, nil
+}
+
+// ReframeOptions 画面比例转换参数
+type ReframeOptions struct {
+	InputPath  string // 本地路径或远程 URL
+	OutputPath string // 视频输出 mp4，图片输出 jpg
+	Width      int
+	Height     int
+	Fit        string             // crop、letterbox、blur_pad 或 smart
+	Keyframes  []reframe.Keyframe // Fit 为 smart 时的裁剪关键帧，为空时居中裁剪
+	IsImage    bool
+	Duration   float64 // 视频时长（秒），用于计算进度
+	Progress   func(percent int)
+}
+
+// Reframe 将视频或图片转换到目标尺寸，画面适配方式与输出规格相同
+func (f *FFmpeg) Reframe(ctx context.Context, opts *ReframeOptions) error {
+	if err := os.MkdirAll(filepath.Dir(opts.OutputPath), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	args := []string{"-hide_banner", "-nostats", "-progress", "pipe:1", "-i", opts.InputPath,
+		"-filter_complex", fitFilter(opts.Width, opts.Height, opts.Fit, opts.Keyframes) + "[v]",
+		"-map", "[v]",
+	}
+	if opts.IsImage {
+		args = append(args, "-frames:v", "1", "-q:v", "2")
+	} else {
+		args = append(args,
+			"-map", "0:a?",
+			"-c:v", "libx264",
+			"-preset", "fast",
+			"-crf", "20",
+			"-pix_fmt", "yuv420p",
+			"-c:a", "aac",
+			"-b:a", "128k",
+			"-movflags", "+faststart",
+		)
+	}
+	args = append(args, "-y", opts.OutputPath)
+
+	f.log.Infow("Reframing",
+		"input", opts.InputPath,
+		"resolution", fmt.Sprintf("%dx%d", opts.Width, opts.Height),
+		"fit", opts.Fit,
+		"keyframes", len(opts.Keyframes),
+		"output", opts.OutputPath)
+
+	progress := opts.Progress
+	if opts.Duration <= 0 {
+		progress = nil
+	}
+	if err := f.runWithProgress(ctx, args, opts.Duration, progress); err != nil {
+		os.Remove(opts.OutputPath)
+		return err
+	}
+	return nil
+}
+
+// smartCropFilter 按关键帧移动的裁剪：等比放大铺满后，裁剪窗口位置随时间在关键帧之间线性插值
+func smartCropFilter(w, h int, keyframes []reframe.Keyframe) string {
+	x, y := "(iw-ow)/2", "(ih-oh)/2"
+	if len(keyframes) > 0 {
+		x = fmt.Sprintf("'(iw-ow)*(%s)'", keyframeExpr(keyframes, func(kf reframe.Keyframe) float64 { return kf.X }))
+		y = fmt.Sprintf("'(ih-oh)*(%s)'", keyframeExpr(keyframes, func(kf reframe.Keyframe) float64 { return kf.Y }))
+	}
+	return fmt.Sprintf("[0:v]scale=%d:%d:force_original_aspect_ratio=increase,crop=%d:%d:x=%s:y=%s,setsar=1", w, h, w, h, x, y)
+}
+
+// keyframeExpr 关键帧之间线性插值的 ffmpeg 表达式（变量 t 为秒），位置不变的片段直接使用常量
+func keyframeExpr(keyframes []reframe.Keyframe, value func(reframe.Keyframe) float64) string {
+	last := keyframes[len(keyframes)-1]
+	expr := formatPosition(value(last))
+	constant := true
+	for _, kf := range keyframes {
+		constant = constant && math.Abs(value(kf)-value(last)) <= 1e-4
+	}
+	if constant {
+		return expr
+	}
+	for i := len(keyframes) - 2; i >= 0; i-- {
+		prev, next := keyframes[i], keyframes[i+1]
+		from, to := value(prev), value(next)
+		segment := formatPosition(from)
+		if math.Abs(to-from) > 1e-4 {
+			segment = fmt.Sprintf("%s+(%s)*(t-%s)/%s", formatPosition(from), formatPosition(to-from),
+				formatSeconds(prev.Time), formatSeconds(next.Time-prev.Time))
+		}
+		expr = fmt.Sprintf("if(lt(t,%s),%s,%s)", formatSeconds(next.Time), segment, expr)
+	}
+	if first := keyframes[0]; first.Time > 0 {
+		expr = fmt.Sprintf("if(lt(t,%s),%s,%s)", formatSeconds(first.Time), formatPosition(value(first)), expr)
+	}
+	return expr
+}
+
+// formatPosition 裁剪位置保留 4 位小数，避免表达式过长
+func formatPosition(v float64) string {
+	return strings.TrimRight(strings.TrimRight(strconv.FormatFloat(v, 'f', 4, 64), "0"), ".")
+}
@@ -0,0 +1,322 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/drama-generator/backend/pkg/reframe"
+)
+
+// 画面适配方式：源视频与输出比例不一致时的处理
+const (
+	FitCrop      = "crop"      // 等比放大后居中裁剪，画面铺满
+	FitLetterbox = "letterbox" // 等比缩放后补黑边，保留完整画面
+	FitBlurPad   = "blur_pad"  // 等比缩放，空白处用放大模糊的原画面填充
+	FitSmart     = "smart"     // 等比放大后按画面主体（人脸、细节）跟踪裁剪，裁剪位置由关键帧决定
+)
+
+// 输出编码
+const (
+	CodecH264 = "h264"
+	CodecH265 = "h265"
+)
+
+// 内置输出规格
+const (
+	OutputProfileVertical1080  = "vertical_1080"
+	OutputProfileVertical720   = "vertical_720"
+	OutputProfileLandscape1080 = "landscape_1080"
+	OutputProfileLandscape720  = "landscape_720"
+)
+
+// 默认编码参数
+const (
+	defaultRenditionFPS     = 30
+	defaultAudioBitrate     = "128k"
+	defaultKeyframeInterval = 2 // 秒，HLS 切片时长需为其整数倍
+)
+
+// OutputProfile 成片的输出规格
+type OutputProfile struct {
+	Name         string `json:"name"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	VideoBitrate string `json:"video_bitrate"`           // 如 8M、2500k
+	AudioBitrate string `json:"audio_bitrate,omitempty"` // 为空时使用 128k
+	Codec        string `json:"codec,omitempty"`         // h264（默认）或 h265
+	FPS          int    `json:"fps,omitempty"`           // 为空时使用 30
+	Fit          string `json:"fit,omitempty"`           // crop、letterbox（默认）、blur_pad 或 smart
+}
+
+// OutputProfiles 内置输出规格：竖屏用于短视频平台，横屏用于网页播放器
+var OutputProfiles = map[string]OutputProfile{
+	OutputProfileVertical1080:  {Name: OutputProfileVertical1080, Width: 1080, Height: 1920, VideoBitrate: "8M", Codec: CodecH264, FPS: 30, Fit: FitBlurPad},
+	OutputProfileVertical720:   {Name: OutputProfileVertical720, Width: 720, Height: 1280, VideoBitrate: "4M", Codec: CodecH264, FPS: 30, Fit: FitBlurPad},
+	OutputProfileLandscape1080: {Name: OutputProfileLandscape1080, Width: 1920, Height: 1080, VideoBitrate: "6M", Codec: CodecH264, FPS: 30, Fit: FitLetterbox},
+	OutputProfileLandscape720:  {Name: OutputProfileLandscape720, Width: 1280, Height: 720, VideoBitrate: "3M", Codec: CodecH264, FPS: 30, Fit: FitLetterbox},
+}
+
+// Normalize 补全默认值并校验规格；只填写名称时使用同名的内置规格
+func (p OutputProfile) Normalize() (OutputProfile, error) {
+	p.Name = strings.ToLower(strings.TrimSpace(p.Name))
+	if p.Name == "" {
+		return p, fmt.Errorf("output profile name is required")
+	}
+	if p.Width == 0 && p.Height == 0 && p.VideoBitrate == "" {
+		preset, ok := OutputProfiles[p.Name]
+		if !ok {
+			return p, fmt.Errorf("unknown output profile %q", p.Name)
+		}
+		p = preset
+	}
+
+	if p.Width <= 0 || p.Height <= 0 || p.Width > 4096 || p.Height > 4096 {
+		return p, fmt.Errorf("output profile %q: invalid resolution %dx%d", p.Name, p.Width, p.Height)
+	}
+	if p.Width%2 != 0 || p.Height%2 != 0 {
+		return p, fmt.Errorf("output profile %q: width and height must be even", p.Name)
+	}
+	if _, err := parseBitrate(p.VideoBitrate); err != nil {
+		return p, fmt.Errorf("output profile %q: invalid video bitrate: %w", p.Name, err)
+	}
+	if p.AudioBitrate == "" {
+		p.AudioBitrate = defaultAudioBitrate
+	}
+	if _, err := parseBitrate(p.AudioBitrate); err != nil {
+		return p, fmt.Errorf("output profile %q: invalid audio bitrate: %w", p.Name, err)
+	}
+	switch p.Codec {
+	case "":
+		p.Codec = CodecH264
+	case CodecH264, CodecH265:
+	default:
+		return p, fmt.Errorf("output profile %q: unsupported codec %q", p.Name, p.Codec)
+	}
+	if p.FPS == 0 {
+		p.FPS = defaultRenditionFPS
+	}
+	if p.FPS < 1 || p.FPS > 120 {
+		return p, fmt.Errorf("output profile %q: invalid fps %d", p.Name, p.FPS)
+	}
+	switch p.Fit {
+	case "":
+		p.Fit = FitLetterbox
+	case FitCrop, FitLetterbox, FitBlurPad, FitSmart:
+	default:
+		return p, fmt.Errorf("output profile %q: unsupported fit %q", p.Name, p.Fit)
+	}
+	return p, nil
+}
+
+// Bandwidth 视频与音频的总码率（bit/s），用于 HLS 主播放列表
+func (p OutputProfile) Bandwidth() int {
+	videoBitrate, _ := parseBitrate(p.VideoBitrate)
+	audioBitrate, _ := parseBitrate(p.AudioBitrate)
+	if audioBitrate == 0 {
+		audioBitrate, _ = parseBitrate(defaultAudioBitrate)
+	}
+	return videoBitrate + audioBitrate
+}
+
+// RenditionOptions 输出规格转码参数
+type RenditionOptions struct {
+	InputPath  string // 本地路径或远程 URL
+	OutputPath string
+	Profile    OutputProfile      // 需已经过 Normalize
+	Keyframes  []reframe.Keyframe // Fit 为 smart 时的裁剪关键帧，为空时居中裁剪
+	Duration   float64            // 视频时长（秒），用于计算进度
+	Progress   func(percent int)
+}
+
+// TranscodeRendition 按输出规格转码成片：缩放适配画面、固定帧率和码率，
+// 关键帧按固定间隔插入，便于后续切分 HLS 时直接复制码流
+func (f *FFmpeg) TranscodeRendition(ctx context.Context, opts *RenditionOptions) error {
+	profile := opts.Profile
+	if err := os.MkdirAll(filepath.Dir(opts.OutputPath), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	videoBitrate, err := parseBitrate(profile.VideoBitrate)
+	if err != nil {
+		return fmt.Errorf("invalid video bitrate: %w", err)
+	}
+	gop := profile.FPS * defaultKeyframeInterval
+
+	args := []string{"-hide_banner", "-nostats", "-progress", "pipe:1", "-i", opts.InputPath,
+		"-filter_complex", fitFilter(profile.Width, profile.Height, profile.Fit, opts.Keyframes) + "[v]",
+		"-map", "[v]",
+		"-map", "0:a?",
+	}
+	switch profile.Codec {
+	case CodecH265:
+		args = append(args, "-c:v", "libx265", "-tag:v", "hvc1", "-x265-params", "log-level=error")
+	default:
+		args = append(args, "-c:v", "libx264", "-profile:v", "high")
+	}
+	args = append(args,
+		"-preset", "medium",
+		"-b:v", strconv.Itoa(videoBitrate),
+		"-maxrate", strconv.Itoa(videoBitrate*3/2),
+		"-bufsize", strconv.Itoa(videoBitrate*2),
+		"-pix_fmt", "yuv420p",
+		"-r", strconv.Itoa(profile.FPS),
+		"-g", strconv.Itoa(gop),
+		"-keyint_min", strconv.Itoa(gop),
+		"-sc_threshold", "0",
+		"-force_key_frames", fmt.Sprintf("expr:gte(t,n_forced*%d)", defaultKeyframeInterval),
+		"-c:a", "aac",
+		"-b:a", profile.AudioBitrate,
+		"-ar", strconv.Itoa(masterSampleRate),
+		"-movflags", "+faststart",
+		"-y", opts.OutputPath,
+	)
+
+	f.log.Infow("Transcoding rendition",
+		"input", opts.InputPath,
+		"profile", profile.Name,
+		"resolution", fmt.Sprintf("%dx%d", profile.Width, profile.Height),
+		"fit", profile.Fit,
+		"output", opts.OutputPath)
+
+	progress := opts.Progress
+	if opts.Duration <= 0 {
+		progress = nil
+	}
+	if err := f.runWithProgress(ctx, args, opts.Duration, progress); err != nil {
+		os.Remove(opts.OutputPath)
+		return err
+	}
+
+	f.log.Infow("Rendition transcoded", "profile", profile.Name, "output", opts.OutputPath)
+	return nil
+}
+
+// fitFilter 缩放适配到输出分辨率的滤镜链，输入为 [0:v]
+func fitFilter(w, h int, fit string, keyframes []reframe.Keyframe) string {
+	switch fit {
+	case FitSmart:
+		return smartCropFilter(w, h, keyframes)
+	case FitCrop:
+		return fmt.Sprintf("[0:v]scale=%d:%d:force_original_aspect_ratio=increase,crop=%d:%d,setsar=1", w, h, w, h)
+	case FitBlurPad:
+		// 背景铺满后模糊，前景完整缩放后居中叠加
+		return fmt.Sprintf("[0:v]split=2[bg][fg];"+
+			"[bg]scale=%d:%d:force_original_aspect_ratio=increase,crop=%d:%d,boxblur=20:2[blur];"+
+			"[fg]scale=%d:%d:force_original_aspect_ratio=decrease:force_divisible_by=2[front];"+
+			"[blur][front]overlay=(W-w)/2:(H-h)/2,setsar=1", w, h, w, h, w, h)
+	default:
+		return fmt.Sprintf("[0:v]scale=%d:%d:force_original_aspect_ratio=decrease:force_divisible_by=2,"+
+			"pad=%d:%d:(ow-iw)/2:(oh-ih)/2:black,setsar=1", w, h, w, h)
+	}
+}
+
+// HLSVariant HLS 码率阶梯中的一路，输入为已按输出规格转码的视频
+type HLSVariant struct {
+	Profile   OutputProfile
+	InputPath string
+}
+
+// HLSOptions HLS 打包参数
+type HLSOptions struct {
+	Variants       []HLSVariant
+	OutputDir      string // 主播放列表写入 master.m3u8，各路写入同名子目录
+	SegmentSeconds int    // 切片时长（秒），为空时使用 6
+}
+
+// HLSMasterPlaylist 主播放列表文件名
+const HLSMasterPlaylist = "master.m3u8"
+
+// PackageHLS 将各输出规格的视频切分为 HLS 点播切片并生成主播放列表，码流直接复制不再编码
+// 返回主播放列表的路径
+func (f *FFmpeg) PackageHLS(ctx context.Context, opts *HLSOptions) (string, error) {
+	if len(opts.Variants) == 0 {
+		return "", fmt.Errorf("no hls variants")
+	}
+	segmentSeconds := opts.SegmentSeconds
+	if segmentSeconds <= 0 {
+		segmentSeconds = 6
+	}
+
+	for _, variant := range opts.Variants {
+		variantDir := filepath.Join(opts.OutputDir, variant.Profile.Name)
+		if err := os.MkdirAll(variantDir, 0755); err != nil {
+			return "", fmt.Errorf("failed to create hls directory: %w", err)
+		}
+		args := []string{"-hide_banner", "-nostats", "-i", variant.InputPath,
+			"-map", "0:v:0",
+			"-map", "0:a?",
+			"-c", "copy",
+			"-f", "hls",
+			"-hls_time", strconv.Itoa(segmentSeconds),
+			"-hls_playlist_type", "vod",
+			"-hls_flags", "independent_segments",
+			"-hls_segment_filename", filepath.Join(variantDir, "segment_%04d.ts"),
+			"-y", filepath.Join(variantDir, "index.m3u8"),
+		}
+		if err := f.runWithProgress(ctx, args, 0, nil); err != nil {
+			return "", fmt.Errorf("failed to package hls variant %s: %w", variant.Profile.Name, err)
+		}
+	}
+
+	masterPath := filepath.Join(opts.OutputDir, HLSMasterPlaylist)
+	if err := os.WriteFile(masterPath, []byte(hlsMasterPlaylist(opts.Variants)), 0644); err != nil {
+		return "", fmt.Errorf("failed to write master playlist: %w", err)
+	}
+
+	f.log.Infow("HLS packaged", "output", masterPath, "variants", len(opts.Variants), "segment_seconds", segmentSeconds)
+	return masterPath, nil
+}
+
+// hlsMasterPlaylist 主播放列表，各路按码率从高到低排列
+func hlsMasterPlaylist(variants []HLSVariant) string {
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-INDEPENDENT-SEGMENTS\n")
+	ordered := make([]HLSVariant, len(variants))
+	copy(ordered, variants)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].Profile.Bandwidth() > ordered[j].Profile.Bandwidth()
+	})
+	for _, variant := range ordered {
+		p := variant.Profile
+		codecs := "avc1.640028,mp4a.40.2"
+		if p.Codec == CodecH265 {
+			codecs = "hvc1.1.6.L120.90,mp4a.40.2"
+		}
+		fmt.Fprintf(&b, "#EXT-X-STREAM-INF:BANDWIDTH=%d,RESOLUTION=%dx%d,FRAME-RATE=%d,CODECS=\"%s\",NAME=\"%s\"\n",
+			p.Bandwidth(), p.Width, p.Height, p.FPS, codecs, p.Name)
+		fmt.Fprintf(&b, "%s/index.m3u8\n", p.Name)
+	}
+	return b.String()
+}
+
+// parseBitrate 解析码率，支持 k/M 后缀，返回 bit/s
+func parseBitrate(value string) (int, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, fmt.Errorf("bitrate is required")
+	}
+	multiplier := 1.0
+	switch value[len(value)-1] {
+	case 'k', 'K':
+		multiplier = 1e3
+		value = value[:len(value)-1]
+	case 'm', 'M':
+		multiplier = 1e6
+		value = value[:len(value)-1]
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || number <= 0 {
+		return 0, fmt.Errorf("invalid bitrate %q", value)
+	}
+	bitrate := int(math.Round(number * multiplier))
+	if bitrate < 16000 {
+		return 0, fmt.Errorf("bitrate %d is too low", bitrate)
+	}
+	return bitrate, nil
+}
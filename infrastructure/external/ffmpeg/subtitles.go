@@ -0,0 +1,93 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SubtitleOptions 字幕嵌入参数
+type SubtitleOptions struct {
+	VideoPath    string // 本地路径或远程 URL
+	SubtitlePath string // 软字幕使用 SRT，烧录字幕使用 ASS 以保留样式
+	OutputPath   string
+	Burn         bool
+	Language     string  // ISO 639-2 语言代码，软字幕轨道使用
+	FontsDir     string  // 烧录字幕时额外的字体目录
+	Duration     float64 // 视频时长（秒），用于计算进度
+	Progress     func(percent int)
+}
+
+// AddSubtitles 为视频添加字幕：软字幕作为 mov_text 轨道封装，音视频直接复制；
+// 烧录字幕通过 subtitles 滤镜渲染到画面，需要重新编码视频
+func (f *FFmpeg) AddSubtitles(ctx context.Context, opts *SubtitleOptions) (string, error) {
+	if _, err := os.Stat(opts.SubtitlePath); err != nil {
+		return "", fmt.Errorf("subtitle file not found: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(opts.OutputPath), 0755); err != nil {
+		return "", fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	args := []string{"-hide_banner", "-nostats", "-progress", "pipe:1", "-i", opts.VideoPath}
+	if opts.Burn {
+		filter := fmt.Sprintf("subtitles=filename='%s'", escapeFilterPath(opts.SubtitlePath))
+		if opts.FontsDir != "" {
+			filter += fmt.Sprintf(":fontsdir='%s'", escapeFilterPath(opts.FontsDir))
+		}
+		args = append(args,
+			"-map", "0:v:0",
+			"-map", "0:a?",
+			"-vf", filter,
+			"-c:v", "libx264",
+			"-preset", "medium",
+			"-crf", "20",
+			"-pix_fmt", "yuv420p",
+			"-c:a", "copy",
+		)
+	} else {
+		language := opts.Language
+		if language == "" {
+			language = "chi"
+		}
+		args = append(args,
+			"-i", opts.SubtitlePath,
+			"-map", "0:v",
+			"-map", "0:a?",
+			"-map", "1:0",
+			"-c:v", "copy",
+			"-c:a", "copy",
+			"-c:s", "mov_text",
+			"-metadata:s:s:0", "language="+language,
+			"-disposition:s:0", "default",
+		)
+	}
+	args = append(args, "-movflags", "+faststart", "-y", opts.OutputPath)
+
+	f.log.Infow("Adding subtitles",
+		"video", opts.VideoPath,
+		"subtitle", opts.SubtitlePath,
+		"burn", opts.Burn,
+		"output", opts.OutputPath)
+
+	progress := opts.Progress
+	if opts.Duration <= 0 {
+		progress = nil
+	}
+	if err := f.runWithProgress(ctx, args, opts.Duration, progress); err != nil {
+		os.Remove(opts.OutputPath)
+		return "", err
+	}
+
+	f.log.Infow("Subtitles added", "output", opts.OutputPath)
+	return opts.OutputPath, nil
+}
+
+// GetVideoResolution 获取视频分辨率，获取失败时返回 1920x1080
+func (f *FFmpeg) GetVideoResolution(videoPath string) (int, int) {
+	if strings.TrimSpace(videoPath) == "" {
+		return 1920, 1080
+	}
+	return f.getVideoResolution(videoPath)
+}
@@ -0,0 +1,183 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"image/jpeg"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/drama-generator/backend/pkg/thumbnail"
+)
+
+// 封面候选画面的数量和分析用的缩略图宽度
+const (
+	defaultPosterCandidates = 6
+	posterAnalysisWidth     = 320
+)
+
+// 雪碧图输出文件名，WebVTT 中按相对路径引用图片
+const (
+	SpriteImageName = "sprite.jpg"
+	SpriteVTTName   = "sprite.vtt"
+)
+
+// PosterOptions 封面提取参数
+type PosterOptions struct {
+	InputPath  string // 本地路径或远程 URL
+	OutputPath string // JPEG 文件
+	Duration   float64
+	Candidates int // 候选画面数量，为空时使用 6
+}
+
+// PosterResult 选中的封面画面
+type PosterResult struct {
+	Timestamp float64              `json:"timestamp"`
+	Stats     thumbnail.FrameStats `json:"stats"`
+}
+
+// ExtractPoster 在视频中均匀截取候选画面，跳过黑场和模糊画面，选择最清晰的一帧保存为封面
+func (f *FFmpeg) ExtractPoster(ctx context.Context, opts *PosterOptions) (*PosterResult, error) {
+	n := opts.Candidates
+	if n <= 0 {
+		n = defaultPosterCandidates
+	}
+
+	workDir, err := os.MkdirTemp("", "drama-poster-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create work directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	var times []float64
+	var stats []thumbnail.FrameStats
+	for i, t := range thumbnail.CandidateTimes(opts.Duration, n) {
+		candidate := filepath.Join(workDir, fmt.Sprintf("candidate_%d.jpg", i))
+		if err := f.ExtractFrame(ctx, opts.InputPath, candidate, t, posterAnalysisWidth); err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			f.log.Warnw("Failed to extract poster candidate", "error", err, "input", opts.InputPath, "timestamp", t)
+			continue
+		}
+		frameStats, err := analyzeFrame(candidate)
+		if err != nil {
+			f.log.Warnw("Failed to analyze poster candidate", "error", err, "timestamp", t)
+			continue
+		}
+		times = append(times, t)
+		stats = append(stats, frameStats)
+	}
+
+	best := thumbnail.SelectPoster(stats)
+	if best < 0 {
+		return nil, fmt.Errorf("no frame could be extracted from video")
+	}
+	if err := f.ExtractFrame(ctx, opts.InputPath, opts.OutputPath, times[best], 0); err != nil {
+		return nil, err
+	}
+
+	f.log.Infow("Poster extracted",
+		"input", opts.InputPath,
+		"timestamp", times[best],
+		"usable", stats[best].Usable(),
+		"output", opts.OutputPath)
+	return &PosterResult{Timestamp: times[best], Stats: stats[best]}, nil
+}
+
+// ExtractFrame 截取指定时间点的画面保存为 JPEG，width 为 0 时保持原始分辨率
+func (f *FFmpeg) ExtractFrame(ctx context.Context, inputPath, outputPath string, timestamp float64, width int) error {
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	args := []string{"-hide_banner", "-nostats",
+		"-ss", formatSeconds(timestamp),
+		"-i", inputPath,
+		"-frames:v", "1",
+	}
+	if width > 0 {
+		args = append(args, "-vf", fmt.Sprintf("scale=%d:-2", width))
+	}
+	args = append(args, "-q:v", "2", "-y", outputPath)
+
+	output, err := exec.CommandContext(ctx, "ffmpeg", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("ffmpeg frame extraction failed: %w, output: %s", err, lastLines(string(output), 5))
+	}
+	// 时间点超出视频时长时 ffmpeg 不报错但不输出画面
+	if info, err := os.Stat(outputPath); err != nil || info.Size() == 0 {
+		return fmt.Errorf("no frame at %ss", formatSeconds(timestamp))
+	}
+	return nil
+}
+
+func analyzeFrame(path string) (thumbnail.FrameStats, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return thumbnail.FrameStats{}, err
+	}
+	defer file.Close()
+
+	img, err := jpeg.Decode(file)
+	if err != nil {
+		return thumbnail.FrameStats{}, err
+	}
+	return thumbnail.Analyze(img), nil
+}
+
+// SpriteOptions 拖动预览雪碧图参数
+type SpriteOptions struct {
+	InputPath string
+	OutputDir string // 写入 sprite.jpg 和 sprite.vtt
+	Duration  float64
+	Width     int // 源视频分辨率，用于计算缩略图高度
+	Height    int
+	MaxTiles  int // 缩略图数量上限，为空时为 100
+}
+
+// SpriteResult 生成的雪碧图
+type SpriteResult struct {
+	ImagePath string
+	VTTPath   string
+	Layout    thumbnail.SpriteLayout
+}
+
+// BuildSprite 按固定间隔截取缩略图拼接为一张雪碧图，并生成对应的 WebVTT 供播放器拖动预览
+func (f *FFmpeg) BuildSprite(ctx context.Context, opts *SpriteOptions) (*SpriteResult, error) {
+	layout, err := thumbnail.PlanSprite(opts.Duration, opts.Width, opts.Height, opts.MaxTiles)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(opts.OutputDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	imagePath := filepath.Join(opts.OutputDir, SpriteImageName)
+	filter := fmt.Sprintf("fps=1/%s,scale=%d:%d,setsar=1,tile=%dx%d",
+		formatFloat(layout.Interval), layout.TileWidth, layout.TileHeight, layout.Columns, layout.Rows)
+	args := []string{"-hide_banner", "-nostats",
+		"-i", opts.InputPath,
+		"-vf", filter,
+		"-frames:v", "1",
+		"-q:v", "4",
+		"-y", imagePath,
+	}
+	output, err := exec.CommandContext(ctx, "ffmpeg", args...).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg sprite failed: %w, output: %s", err, lastLines(string(output), 5))
+	}
+
+	vttPath := filepath.Join(opts.OutputDir, SpriteVTTName)
+	if err := os.WriteFile(vttPath, thumbnail.WriteSpriteVTT(layout, opts.Duration, SpriteImageName), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write sprite vtt: %w", err)
+	}
+
+	f.log.Infow("Sprite sheet built",
+		"input", opts.InputPath,
+		"tiles", layout.Count,
+		"grid", fmt.Sprintf("%dx%d", layout.Columns, layout.Rows),
+		"output", opts.OutputDir)
+	return &SpriteResult{ImagePath: imagePath, VTTPath: vttPath, Layout: layout}, nil
+}
@@ -0,0 +1,629 @@
+package ffmpeg
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 渲染轨道类型，与 models.TrackType 取值一致
+const (
+	RenderTrackVideo = "video"
+	RenderTrackAudio = "audio"
+	RenderTrackText  = "text"
+)
+
+// 音频轨道用途，与 models.TrackAudioRole 取值一致
+const (
+	RenderAudioDialogue = "dialogue"
+	RenderAudioMusic    = "music"
+	RenderAudioSFX      = "sfx"
+)
+
+// 对白出现时压低配乐和音效的侧链压缩参数
+const (
+	duckingThreshold = 0.02 // 约 -34dB，对白超过该电平时开始压缩
+	duckingRatio     = 8
+	duckingAttackMs  = 20
+	duckingReleaseMs = 400
+)
+
+// 混音统一的采样格式
+const (
+	renderSampleRate    = 44100
+	renderChannelLayout = "stereo"
+)
+
+// RenderOptions 多轨时间线渲染参数，时间单位均为秒
+type RenderOptions struct {
+	OutputPath string
+	Width      int
+	Height     int
+	FPS        int
+	Duration   float64
+	Tracks     []RenderTrack
+	FontFile   string // 文字轨道使用的字体文件，为空时由 fontconfig 选择
+
+	// Progress 渲染进度回调，percent 取值 0-100
+	Progress func(percent int)
+}
+
+// RenderTrack 渲染轨道，Order 小的视频/文字轨道在下层
+type RenderTrack struct {
+	Type   string
+	Role   string // 音频用途，music/sfx 轨道在 dialogue 轨道有声音时自动压低
+	Order  int
+	Muted  bool
+	Volume float64 // 1.0 为原始音量
+	Clips  []RenderClip
+}
+
+// RenderClip 轨道上的片段
+type RenderClip struct {
+	Source    string // 本地路径或远程 URL，文字片段为空
+	IsImage   bool
+	Text      string // 文字片段内容
+	Start     float64
+	Duration  float64 // 片段在时间线上的时长（已按速度换算）
+	TrimStart float64
+	Speed     float64
+	Volume    float64 // 1.0 为原始音量
+	Muted     bool
+	FadeIn    float64
+	FadeOut   float64
+	Effects   []RenderEffect
+
+	// CrossfadeIn 音频片段与同轨道前一片段的交叉淡化时长：片段提前开始并淡入，前一片段同时淡出
+	CrossfadeIn float64
+}
+
+// RenderEffect 片段特效
+type RenderEffect struct {
+	Type   string
+	Config map[string]interface{}
+}
+
+// renderInput 渲染命令的一路输入
+type renderInput struct {
+	args     []string
+	hasAudio bool
+}
+
+// renderGraph 构建中的滤镜图
+type renderGraph struct {
+	opts     *RenderOptions
+	inputs   []renderInput
+	filters  []string
+	audio    []string
+	dialogue []string // 对白音频，作为压低配乐的侧链信号
+	ducked   []string // 需要在对白时压低的配乐和音效
+	tempDir  string
+	videoOut string
+	labelSeq int
+}
+
+// RenderTimeline 将多轨时间线渲染为单个视频文件
+// 所有片段在一个 filter_complex 中完成：视频按轨道顺序叠加，音频按轨道/片段音量混音，
+// 变速使用 setpts/atempo，特效映射为 eq/gblur/colorbalance 等滤镜，文字轨道使用 drawtext 叠加，
+// 配乐和音效轨道通过 sidechaincompress 在对白出现时自动压低
+func (f *FFmpeg) RenderTimeline(ctx context.Context, opts *RenderOptions) (string, error) {
+	if opts.Duration <= 0 {
+		return "", fmt.Errorf("timeline is empty")
+	}
+	if opts.Width <= 0 || opts.Height <= 0 {
+		opts.Width, opts.Height = 1920, 1080
+	}
+	if opts.FPS <= 0 {
+		opts.FPS = 30
+	}
+
+	workDir, err := os.MkdirTemp(f.tempDir, "render_")
+	if err != nil {
+		return "", fmt.Errorf("failed to create work directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	if err := os.MkdirAll(filepath.Dir(opts.OutputPath), 0755); err != nil {
+		return "", fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	graph := &renderGraph{opts: opts, tempDir: workDir}
+	if err := f.buildRenderGraph(graph); err != nil {
+		return "", err
+	}
+
+	args := []string{"-hide_banner", "-nostats", "-progress", "pipe:1"}
+	for _, input := range graph.inputs {
+		args = append(args, input.args...)
+	}
+	args = append(args,
+		"-filter_complex", strings.Join(graph.filters, ";"),
+		"-map", "["+graph.videoOut+"]",
+		"-map", "[aout]",
+		"-c:v", "libx264",
+		"-preset", "medium",
+		"-crf", "20",
+		"-pix_fmt", "yuv420p",
+		"-r", strconv.Itoa(opts.FPS),
+		"-c:a", "aac",
+		"-b:a", "192k",
+		"-t", formatSeconds(opts.Duration),
+		"-movflags", "+faststart",
+		"-y",
+		opts.OutputPath,
+	)
+
+	f.log.Infow("Rendering timeline",
+		"inputs", len(graph.inputs),
+		"duration", opts.Duration,
+		"resolution", fmt.Sprintf("%dx%d", opts.Width, opts.Height),
+		"output", opts.OutputPath)
+
+	if err := f.runWithProgress(ctx, args, opts.Duration, opts.Progress); err != nil {
+		os.Remove(opts.OutputPath)
+		return "", err
+	}
+
+	f.log.Infow("Timeline rendered", "output", opts.OutputPath)
+	return opts.OutputPath, nil
+}
+
+// buildRenderGraph 下载素材并生成滤镜图
+func (f *FFmpeg) buildRenderGraph(g *renderGraph) error {
+	opts := g.opts
+	tracks := make([]RenderTrack, len(opts.Tracks))
+	copy(tracks, opts.Tracks)
+	sort.SliceStable(tracks, func(i, j int) bool {
+		return tracks[i].Order < tracks[j].Order
+	})
+
+	g.filters = append(g.filters,
+		fmt.Sprintf("color=c=black:s=%dx%d:r=%d:d=%s,format=yuv420p[base]",
+			opts.Width, opts.Height, opts.FPS, formatSeconds(opts.Duration)),
+		fmt.Sprintf("anullsrc=r=%d:cl=%s,atrim=duration=%s[abase]",
+			renderSampleRate, renderChannelLayout, formatSeconds(opts.Duration)))
+	g.videoOut = "base"
+
+	sources := make(map[string]string)
+	for _, track := range tracks {
+		if track.Type == RenderTrackAudio {
+			track.Clips = applyAudioCrossfades(track.Clips)
+		}
+		for _, clip := range track.Clips {
+			if clip.Duration <= 0 || clip.Start >= opts.Duration {
+				continue
+			}
+
+			if track.Type == RenderTrackText {
+				if err := g.addText(&clip); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if clip.Source == "" {
+				continue
+			}
+			localPath, ok := sources[clip.Source]
+			if !ok {
+				var err error
+				localPath, err = f.fetchRenderSource(clip.Source, g.tempDir, len(sources))
+				if err != nil {
+					return err
+				}
+				sources[clip.Source] = localPath
+			}
+
+			index := g.addInput(&clip, localPath, f.hasAudioStream(localPath) && !clip.IsImage)
+			if track.Type == RenderTrackVideo {
+				g.addVideo(&clip, index)
+			}
+			if g.inputs[index].hasAudio && !track.Muted && !clip.Muted {
+				label := g.addAudio(&clip, index, track.Volume*clip.Volume)
+				switch track.Role {
+				case RenderAudioDialogue:
+					g.dialogue = append(g.dialogue, label)
+				case RenderAudioMusic, RenderAudioSFX:
+					g.ducked = append(g.ducked, label)
+				default:
+					g.audio = append(g.audio, label)
+				}
+			}
+		}
+	}
+
+	g.mixAudio()
+	return nil
+}
+
+// mixAudio 混合所有音频：有对白时先将配乐和音效以对白为侧链压缩，再与其他音频混合
+// 对白和配乐各自与整段静音混合，保证侧链压缩的两路输入与时间线等长
+func (g *renderGraph) mixAudio() {
+	base := "[abase]"
+	if len(g.dialogue) > 0 && len(g.ducked) > 0 {
+		base = "[amixbase]"
+		g.filters = append(g.filters, "[abase]asplit=3[amixbase][adlgbase][abedbase]",
+			fmt.Sprintf("[adlgbase]%samix=inputs=%d:duration=first:dropout_transition=0:normalize=0,asplit=2[adlg][akey]",
+				strings.Join(g.dialogue, ""), len(g.dialogue)+1),
+			fmt.Sprintf("[abedbase]%samix=inputs=%d:duration=first:dropout_transition=0:normalize=0[abed]",
+				strings.Join(g.ducked, ""), len(g.ducked)+1),
+			fmt.Sprintf("[abed][akey]sidechaincompress=threshold=%s:ratio=%d:attack=%d:release=%d[aducked]",
+				formatFloat(duckingThreshold), duckingRatio, duckingAttackMs, duckingReleaseMs))
+		g.audio = append(g.audio, "[adlg]", "[aducked]")
+	} else {
+		g.audio = append(g.audio, g.dialogue...)
+		g.audio = append(g.audio, g.ducked...)
+	}
+
+	// 整段静音放在第一路，duration=first 使混音结果与时间线等长
+	// normalize=0 保持各路原始音量（需要 FFmpeg 4.4 及以上）
+	g.audio = append([]string{base}, g.audio...)
+	g.filters = append(g.filters, fmt.Sprintf("%samix=inputs=%d:duration=first:dropout_transition=0:normalize=0[aout]",
+		strings.Join(g.audio, ""), len(g.audio)))
+}
+
+// applyAudioCrossfades 将交叉淡化换算为片段的提前开始和淡入淡出，素材入点不足时从素材开头播放
+func applyAudioCrossfades(clips []RenderClip) []RenderClip {
+	result := make([]RenderClip, len(clips))
+	copy(result, clips)
+	for i := range result {
+		clip := &result[i]
+		if clip.CrossfadeIn <= 0 || i == 0 {
+			continue
+		}
+		crossfade := math.Min(clip.CrossfadeIn, clip.Start)
+		clip.Start -= crossfade
+		clip.Duration += crossfade
+		clip.TrimStart = math.Max(0, clip.TrimStart-crossfade*clipSpeed(clip))
+		clip.FadeIn = math.Max(clip.FadeIn, crossfade)
+
+		prev := &result[i-1]
+		prev.FadeOut = math.Min(math.Max(prev.FadeOut, crossfade), prev.Duration)
+	}
+	return result
+}
+
+// fetchRenderSource 远程素材下载到工作目录，本地文件直接使用
+func (f *FFmpeg) fetchRenderSource(source, workDir string, index int) (string, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		if _, err := os.Stat(source); err != nil {
+			return "", fmt.Errorf("local file not found: %s", source)
+		}
+		return source, nil
+	}
+
+	ext := filepath.Ext(strings.SplitN(source, "?", 2)[0])
+	if ext == "" || len(ext) > 5 {
+		ext = ".bin"
+	}
+	localPath, err := f.downloadVideo(source, filepath.Join(workDir, fmt.Sprintf("source_%d%s", index, ext)))
+	if err != nil {
+		return "", fmt.Errorf("failed to download %s: %w", source, err)
+	}
+	return localPath, nil
+}
+
+// addInput 添加输入并返回输入序号，视频通过输入端 -ss/-t 截取素材区间，图片循环为静帧
+func (g *renderGraph) addInput(clip *RenderClip, path string, hasAudio bool) int {
+	var args []string
+	if clip.IsImage {
+		args = []string{"-loop", "1", "-framerate", strconv.Itoa(g.opts.FPS), "-t", formatSeconds(clip.Duration), "-i", path}
+	} else {
+		sourceDuration := clip.Duration * clipSpeed(clip)
+		if clip.TrimStart > 0 {
+			args = append(args, "-ss", formatSeconds(clip.TrimStart))
+		}
+		args = append(args, "-t", formatSeconds(sourceDuration), "-i", path)
+	}
+
+	g.inputs = append(g.inputs, renderInput{args: args, hasAudio: hasAudio})
+	return len(g.inputs) - 1
+}
+
+// addVideo 处理片段画面并叠加到当前画面上
+func (g *renderGraph) addVideo(clip *RenderClip, index int) {
+	opts := g.opts
+
+	chain := []string{
+		fmt.Sprintf("setpts=(PTS-STARTPTS)/%s", formatFloat(clipSpeed(clip))),
+		fmt.Sprintf("fps=%d", opts.FPS),
+		fmt.Sprintf("scale=%d:%d:force_original_aspect_ratio=decrease", opts.Width, opts.Height),
+		fmt.Sprintf("pad=%d:%d:(ow-iw)/2:(oh-ih)/2", opts.Width, opts.Height),
+		"setsar=1",
+	}
+	chain = append(chain, effectFilters(clip.Effects)...)
+	chain = append(chain, "format=yuva420p")
+	if clip.FadeIn > 0 {
+		chain = append(chain, fmt.Sprintf("fade=t=in:st=0:d=%s:alpha=1", formatSeconds(clip.FadeIn)))
+	}
+	if clip.FadeOut > 0 {
+		chain = append(chain, fmt.Sprintf("fade=t=out:st=%s:d=%s:alpha=1",
+			formatSeconds(clip.Duration-clip.FadeOut), formatSeconds(clip.FadeOut)))
+	}
+	chain = append(chain,
+		fmt.Sprintf("trim=duration=%s", formatSeconds(clip.Duration)),
+		fmt.Sprintf("setpts=PTS-STARTPTS+%s/TB", formatSeconds(clip.Start)))
+
+	clipLabel := g.nextLabel("v")
+	outLabel := g.nextLabel("vo")
+	g.filters = append(g.filters,
+		fmt.Sprintf("[%d:v]%s[%s]", index, strings.Join(chain, ","), clipLabel),
+		fmt.Sprintf("[%s][%s]overlay=eof_action=pass:enable='between(t,%s,%s)'[%s]",
+			g.videoOut, clipLabel, formatSeconds(clip.Start), formatSeconds(clip.Start+clip.Duration), outLabel))
+	g.videoOut = outLabel
+}
+
+// addAudio 处理片段音频：变速、音量、淡入淡出，再延迟到片段在时间线上的位置，返回输出标签
+func (g *renderGraph) addAudio(clip *RenderClip, index int, volume float64) string {
+	chain := []string{"asetpts=PTS-STARTPTS"}
+	chain = append(chain, atempoFilters(clipSpeed(clip))...)
+	chain = append(chain,
+		fmt.Sprintf("atrim=duration=%s", formatSeconds(clip.Duration)),
+		fmt.Sprintf("aresample=%d", renderSampleRate),
+		fmt.Sprintf("aformat=sample_fmts=fltp:channel_layouts=%s", renderChannelLayout))
+	if volume != 1 {
+		chain = append(chain, fmt.Sprintf("volume=%s", formatFloat(volume)))
+	}
+	if clip.FadeIn > 0 {
+		chain = append(chain, fmt.Sprintf("afade=t=in:st=0:d=%s", formatSeconds(clip.FadeIn)))
+	}
+	if clip.FadeOut > 0 {
+		chain = append(chain, fmt.Sprintf("afade=t=out:st=%s:d=%s",
+			formatSeconds(clip.Duration-clip.FadeOut), formatSeconds(clip.FadeOut)))
+	}
+	if delay := int(clip.Start * 1000); delay > 0 {
+		chain = append(chain, fmt.Sprintf("adelay=%d|%d", delay, delay))
+	}
+
+	label := g.nextLabel("a")
+	g.filters = append(g.filters, fmt.Sprintf("[%d:a]%s[%s]", index, strings.Join(chain, ","), label))
+	return "[" + label + "]"
+}
+
+// addText 文字片段通过 drawtext 叠加，文字写入临时文件以避免滤镜转义问题
+func (g *renderGraph) addText(clip *RenderClip) error {
+	if strings.TrimSpace(clip.Text) == "" {
+		return nil
+	}
+
+	textFile := filepath.Join(g.tempDir, fmt.Sprintf("text_%d.txt", g.labelSeq))
+	if err := os.WriteFile(textFile, []byte(clip.Text), 0644); err != nil {
+		return fmt.Errorf("failed to write text file: %w", err)
+	}
+
+	start := clip.Start
+	end := clip.Start + clip.Duration
+	alpha := "1"
+	if clip.FadeIn > 0 || clip.FadeOut > 0 {
+		fadeIn, fadeOut := "1", "1"
+		if clip.FadeIn > 0 {
+			fadeIn = fmt.Sprintf("min(1,(t-%s)/%s)", formatSeconds(start), formatSeconds(clip.FadeIn))
+		}
+		if clip.FadeOut > 0 {
+			fadeOut = fmt.Sprintf("min(1,(%s-t)/%s)", formatSeconds(end), formatSeconds(clip.FadeOut))
+		}
+		alpha = fmt.Sprintf("max(0,min(%s,%s))", fadeIn, fadeOut)
+	}
+
+	fontSize := g.opts.Height / 18
+	params := []string{
+		fmt.Sprintf("textfile='%s'", escapeFilterPath(textFile)),
+		fmt.Sprintf("fontsize=%d", fontSize),
+		"fontcolor=white",
+		"box=1",
+		"boxcolor=black@0.4",
+		fmt.Sprintf("boxborderw=%d", fontSize/4),
+		"x=(w-text_w)/2",
+		fmt.Sprintf("y=h-text_h-%d", g.opts.Height/12),
+		fmt.Sprintf("alpha='%s'", alpha),
+		fmt.Sprintf("enable='between(t,%s,%s)'", formatSeconds(start), formatSeconds(end)),
+	}
+	if g.opts.FontFile != "" {
+		params = append([]string{fmt.Sprintf("fontfile='%s'", escapeFilterPath(g.opts.FontFile))}, params...)
+	}
+
+	outLabel := g.nextLabel("t")
+	g.filters = append(g.filters, fmt.Sprintf("[%s]drawtext=%s[%s]", g.videoOut, strings.Join(params, ":"), outLabel))
+	g.videoOut = outLabel
+	return nil
+}
+
+func (g *renderGraph) nextLabel(prefix string) string {
+	g.labelSeq++
+	return fmt.Sprintf("%s%d", prefix, g.labelSeq)
+}
+
+// effectFilters 将片段特效映射为 FFmpeg 滤镜，未启用或不支持的特效会被忽略
+// brightness/contrast/saturation 读取 value，blur 读取 sigma（或 value），
+// color 读取 colorbalance 参数（rs/gs/bs/rm/gm/bm/rh/gh/bh），filter 读取预设名 preset
+func effectFilters(effects []RenderEffect) []string {
+	var filters []string
+	for _, effect := range effects {
+		switch effect.Type {
+		case "brightness":
+			filters = append(filters, fmt.Sprintf("eq=brightness=%s", formatFloat(clampFloat(configFloat(effect.Config, "value", 0), -1, 1))))
+		case "contrast":
+			filters = append(filters, fmt.Sprintf("eq=contrast=%s", formatFloat(clampFloat(configFloat(effect.Config, "value", 1), -2, 2))))
+		case "saturation":
+			filters = append(filters, fmt.Sprintf("eq=saturation=%s", formatFloat(clampFloat(configFloat(effect.Config, "value", 1), 0, 3))))
+		case "blur":
+			sigma := configFloat(effect.Config, "sigma", configFloat(effect.Config, "value", 5))
+			if sigma > 0 {
+				filters = append(filters, fmt.Sprintf("gblur=sigma=%s", formatFloat(clampFloat(sigma, 0, 100))))
+			}
+		case "color":
+			// 顺序：亮度对比度饱和度、色彩平衡、LUT（lut 为调用方校验并解析到存储目录下的 .cube/.3dl 文件路径）
+			var eq []string
+			if v := configFloat(effect.Config, "brightness", 0); v != 0 {
+				eq = append(eq, "brightness="+formatFloat(clampFloat(v, -1, 1)))
+			}
+			if v := configFloat(effect.Config, "contrast", 1); v != 1 {
+				eq = append(eq, "contrast="+formatFloat(clampFloat(v, -2, 2)))
+			}
+			if v := configFloat(effect.Config, "saturation", 1); v != 1 {
+				eq = append(eq, "saturation="+formatFloat(clampFloat(v, 0, 3)))
+			}
+			if len(eq) > 0 {
+				filters = append(filters, "eq="+strings.Join(eq, ":"))
+			}
+			var params []string
+			for _, key := range []string{"rs", "gs", "bs", "rm", "gm", "bm", "rh", "gh", "bh"} {
+				if v := configFloat(effect.Config, key, 0); v != 0 {
+					params = append(params, fmt.Sprintf("%s=%s", key, formatFloat(clampFloat(v, -1, 1))))
+				}
+			}
+			if len(params) > 0 {
+				filters = append(filters, "colorbalance="+strings.Join(params, ":"))
+			}
+			if lut, _ := effect.Config["lut"].(string); lut != "" {
+				filters = append(filters, fmt.Sprintf("lut3d=file='%s'", escapeFilterPath(lut)))
+			}
+		case "filter":
+			preset, _ := effect.Config["preset"].(string)
+			if filter, ok := filterPresets[preset]; ok {
+				filters = append(filters, filter)
+			}
+		}
+	}
+	return filters
+}
+
+// filterPresets filter 类型特效支持的预设
+var filterPresets = map[string]string{
+	"grayscale": "hue=s=0",
+	"sepia":     "colorchannelmixer=.393:.769:.189:0:.349:.686:.168:0:.272:.534:.131",
+	"invert":    "negate",
+	"vignette":  "vignette",
+	"sharpen":   "unsharp=5:5:1.0",
+}
+
+// atempoFilters 单个 atempo 只支持 0.5-2.0 倍，超出范围时拆分为多级
+func atempoFilters(speed float64) []string {
+	if speed == 1 {
+		return nil
+	}
+	var filters []string
+	for speed > 2 {
+		filters = append(filters, "atempo=2.0")
+		speed /= 2
+	}
+	for speed < 0.5 {
+		filters = append(filters, "atempo=0.5")
+		speed /= 0.5
+	}
+	return append(filters, fmt.Sprintf("atempo=%s", formatFloat(speed)))
+}
+
+// runWithProgress 执行 ffmpeg 并解析 -progress 输出回调进度，context 取消时终止进程
+func (f *FFmpeg) runWithProgress(ctx context.Context, args []string, duration float64, progress func(int)) error {
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to open ffmpeg output: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start ffmpeg: %w", err)
+	}
+
+	lastPercent := -1
+	lastReport := time.Time{}
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if !ok || progress == nil {
+			continue
+		}
+		// out_time_ms 实际单位为微秒
+		if key != "out_time_us" && key != "out_time_ms" {
+			continue
+		}
+		us, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || us < 0 {
+			continue
+		}
+		percent := int(float64(us) / 1e6 / duration * 100)
+		if percent > 99 {
+			percent = 99
+		}
+		if percent > lastPercent && time.Since(lastReport) >= time.Second {
+			lastPercent = percent
+			lastReport = time.Now()
+			progress(percent)
+		}
+	}
+
+	if err := cmd.Wait(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		output := stderr.String()
+		if len(output) > 4000 {
+			output = output[len(output)-4000:]
+		}
+		f.log.Errorw("FFmpeg render failed", "error", err, "output", output)
+		return fmt.Errorf("ffmpeg render failed: %w, output: %s", err, output)
+	}
+	if progress != nil {
+		progress(100)
+	}
+	return nil
+}
+
+// clipSpeed 图片片段不变速
+func clipSpeed(clip *RenderClip) float64 {
+	if clip.IsImage || clip.Speed <= 0 {
+		return 1
+	}
+	return clip.Speed
+}
+
+func configFloat(config map[string]interface{}, key string, fallback float64) float64 {
+	switch v := config[key].(type) {
+	case float64:
+		return v
+	case int:
+		return float64(v)
+	case string:
+		if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+			return parsed
+		}
+	}
+	return fallback
+}
+
+func clampFloat(v, min, max float64) float64 {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
+
+func formatSeconds(seconds float64) string {
+	return strconv.FormatFloat(seconds, 'f', 3, 64)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// escapeFilterPath 转义滤镜参数中单引号包裹的路径
+func escapeFilterPath(path string) string {
+	path = filepath.ToSlash(path)
+	path = strings.ReplaceAll(path, `\`, `\\`)
+	path = strings.ReplaceAll(path, `'`, `'\''`)
+	return strings.ReplaceAll(path, ":", `\:`)
+}
@@ -0,0 +1,60 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// VoiceTrackOptions 分镜对白音轨：台词从起点依次排列，与时间线上配音的摆放方式一致
+type VoiceTrackOptions struct {
+	Inputs     []string // 台词音频，按台词顺序
+	OutputPath string   // 输出 WAV 文件
+	Duration   float64  // 输出时长（秒），超出部分截掉，不足部分补静音；为 0 时保持台词总长
+}
+
+// BuildVoiceTrack 将分镜的多句台词拼接为一条单声道音轨，供口型同步使用
+func (f *FFmpeg) BuildVoiceTrack(ctx context.Context, opts *VoiceTrackOptions) error {
+	if len(opts.Inputs) == 0 {
+		return fmt.Errorf("no voice lines")
+	}
+	if err := os.MkdirAll(filepath.Dir(opts.OutputPath), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	args := []string{"-hide_banner", "-nostats"}
+	for _, input := range opts.Inputs {
+		args = append(args, "-i", input)
+	}
+
+	// concat 要求各输入格式一致，先统一采样率和声道
+	var filters []string
+	var labels strings.Builder
+	for i := range opts.Inputs {
+		filters = append(filters, fmt.Sprintf("[%d:a]aresample=%d,aformat=sample_fmts=fltp:channel_layouts=mono[v%d]", i, masterSampleRate, i))
+		labels.WriteString(fmt.Sprintf("[v%d]", i))
+	}
+	concat := fmt.Sprintf("%sconcat=n=%d:v=0:a=1", labels.String(), len(opts.Inputs))
+	if opts.Duration > 0 {
+		concat += fmt.Sprintf(",apad,atrim=0:%s", formatFloat(opts.Duration))
+	}
+	filters = append(filters, concat+"[voice]")
+
+	args = append(args,
+		"-filter_complex", strings.Join(filters, ";"),
+		"-map", "[voice]",
+		"-c:a", "pcm_s16le",
+		"-y", opts.OutputPath,
+	)
+	output, err := exec.CommandContext(ctx, "ffmpeg", args...).CombinedOutput()
+	if err != nil {
+		f.log.Errorw("FFmpeg voice track failed", "error", err, "output", string(output))
+		return fmt.Errorf("ffmpeg voice track failed: %w, output: %s", err, lastLines(string(output), 5))
+	}
+
+	f.log.Infow("Voice track built", "output", opts.OutputPath, "lines", len(opts.Inputs), "duration", opts.Duration)
+	return nil
+}
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/drama-generator/backend/api/routes"
+	"github.com/drama-generator/backend/application/services"
 	"github.com/drama-generator/backend/infrastructure/database"
 	"github.com/drama-generator/backend/infrastructure/storage"
 	"github.com/drama-generator/backend/pkg/config"
@@ -41,6 +42,14 @@ func main() {
 	}
 	logr.Info("Database tables migrated successfully")
 
+	// 校验配置生成业务设置，创建事件总线和任务队列（各业务服务创建时向队列注册任务处理函数）
+	settings, err := services.NewSettings(cfg)
+	if err != nil {
+		logr.Fatal("Invalid config", "error", err)
+	}
+	eventBus := services.NewEventBus()
+	jobQueue := services.NewJobQueue(db, eventBus, cfg.Queue, logr)
+
 	// 初始化本地存储
 	var localStorage *storage.LocalStorage
 	if cfg.Storage.Type == "local" {
@@ -57,7 +66,10 @@ func main() {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
-	router := routes.SetupRouter(cfg, db, logr, localStorage)
+	router := routes.SetupRouter(cfg, db, logr, localStorage, eventBus, jobQueue, settings)
+
+	// 路由初始化完成后所有任务处理函数均已注册，开始调度
+	jobQueue.Start()
 
 	srv := &http.Server{
 		Addr:         fmt.Sprintf(":%d", cfg.Server.Port),
@@ -90,6 +102,9 @@ func main() {
 
 	logr.Info("Shutting down server...")
 
+	// 停止任务队列，将执行中的任务释放回队列，重启后继续执行
+	jobQueue.Stop()
+
 	// 清理资源
 	// CRITICAL FIX: Properly close database connection to prevent resource leaks
 	// SQLite connections should be closed gracefully to avoid database lock issues
@@ -0,0 +1,166 @@
+package audio
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Candidate 参与匹配的音频库素材
+type Candidate struct {
+	ID          uint
+	Name        string
+	Description string
+	Tags        []string
+}
+
+// Match 匹配结果，Score 为每个关键词的平均得分，取值 0-3
+type Match struct {
+	ID    uint
+	Score float64
+}
+
+// 关键词命中标签、名称、描述的得分
+const (
+	tagScore         = 3
+	nameScore        = 2
+	descriptionScore = 1
+)
+
+// 描述配乐和音效时的通用词，不参与匹配
+var stopWords = map[string]bool{
+	"背景": true, "音乐": true, "配乐": true, "音效": true, "声音": true, "效果": true,
+	"景音": true, "乐声": true, "bgm": true, "music": true, "sfx": true, "sound": true,
+	"sounds": true, "effect": true, "effects": true, "background": true, "the": true,
+	"and": true, "with": true, "of": true, "in": true, "a": true, "an": true,
+}
+
+// Keywords 从配乐提示词或音效描述中提取关键词：英文按单词，中文按连续汉字的二元组
+// 例如 "紧张的弦乐，心跳声" 提取为 紧张、弦乐、心跳、跳声
+func Keywords(text string) []string {
+	var keywords []string
+	seen := make(map[string]bool)
+	add := func(word string) {
+		if word == "" || stopWords[word] || seen[word] {
+			return
+		}
+		seen[word] = true
+		keywords = append(keywords, word)
+	}
+
+	var latin []rune
+	var han []rune
+	flush := func() {
+		if len(latin) > 1 {
+			add(string(latin))
+		}
+		latin = latin[:0]
+
+		switch {
+		case len(han) == 1:
+			add(string(han))
+		case len(han) > 1:
+			for i := 0; i+1 < len(han); i++ {
+				add(string(han[i : i+2]))
+			}
+		}
+		han = han[:0]
+	}
+
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			if len(latin) > 0 {
+				flush()
+			}
+			// "的" 等虚词断开短语
+			if strings.ContainsRune("的地得和与及或在着了", r) {
+				flush()
+				continue
+			}
+			han = append(han, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if len(han) > 0 {
+				flush()
+			}
+			latin = append(latin, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return keywords
+}
+
+// Rank 按关键词命中情况为候选素材打分，返回得分大于 0 的结果，得分高的在前
+func Rank(query string, candidates []Candidate) []Match {
+	keywords := Keywords(query)
+	if len(keywords) == 0 {
+		return nil
+	}
+
+	var matches []Match
+	for _, candidate := range candidates {
+		name := strings.ToLower(candidate.Name)
+		description := strings.ToLower(candidate.Description)
+		tags := make([]string, 0, len(candidate.Tags))
+		for _, tag := range candidate.Tags {
+			if tag = strings.ToLower(strings.TrimSpace(tag)); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+
+		total := 0
+		for _, keyword := range keywords {
+			switch {
+			case matchesTag(keyword, tags):
+				total += tagScore
+			case strings.Contains(name, keyword):
+				total += nameScore
+			case strings.Contains(description, keyword):
+				total += descriptionScore
+			}
+		}
+		if total > 0 {
+			matches = append(matches, Match{ID: candidate.ID, Score: float64(total) / float64(len(keywords))})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	return matches
+}
+
+// matchesTag 关键词与标签互相包含即算命中，如 "弦乐" 命中标签 "弦乐四重奏"
+func matchesTag(keyword string, tags []string) bool {
+	for _, tag := range tags {
+		if strings.Contains(tag, keyword) || strings.Contains(keyword, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseTags 解析逗号分隔的标签，兼容中文逗号和顿号
+func ParseTags(tags string) []string {
+	fields := strings.FieldsFunc(tags, func(r rune) bool {
+		return r == ',' || r == '，' || r == '、' || r == ';' || r == '；'
+	})
+	var result []string
+	seen := make(map[string]bool)
+	for _, field := range fields {
+		field = strings.TrimSpace(field)
+		if field == "" || seen[strings.ToLower(field)] {
+			continue
+		}
+		seen[strings.ToLower(field)] = true
+		result = append(result, field)
+	}
+	return result
+}
+
+// JoinTags 将标签拼接为逗号分隔的字符串
+func JoinTags(tags []string) string {
+	return strings.Join(ParseTags(strings.Join(tags, ",")), ",")
+}
@@ -0,0 +1,56 @@
+package audio
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKeywords(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"紧张的弦乐，心跳声", []string{"紧张", "弦乐", "心跳", "跳声"}},
+		{"Soft piano music with rain", []string{"soft", "piano", "rain"}},
+		{"雷声", []string{"雷声"}},
+		{"背景音乐", nil},
+	}
+
+	for _, tt := range tests {
+		if got := Keywords(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Keywords(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestRank(t *testing.T) {
+	candidates := []Candidate{
+		{ID: 1, Name: "欢快钢琴", Tags: []string{"欢快", "钢琴"}},
+		{ID: 2, Name: "悬疑配乐", Description: "低沉的弦乐铺底", Tags: []string{"紧张", "悬疑"}},
+		{ID: 3, Name: "心跳声", Tags: []string{"心跳"}},
+		{ID: 4, Name: "雨声"},
+	}
+
+	matches := Rank("紧张的弦乐", candidates)
+	// 紧张命中标签、弦乐命中描述
+	if len(matches) != 1 || matches[0].ID != 2 || matches[0].Score != 2 {
+		t.Errorf("Rank() = %+v, want [{2 2}]", matches)
+	}
+
+	matches = Rank("心跳声，雨声", candidates)
+	if len(matches) != 2 || matches[0].ID != 3 || matches[1].ID != 4 {
+		t.Errorf("Rank() = %+v, want [3 4]", matches)
+	}
+
+	if matches := Rank("背景音乐", candidates); matches != nil {
+		t.Errorf("Rank() with only stop words = %+v, want nil", matches)
+	}
+}
+
+func TestParseTags(t *testing.T) {
+	got := ParseTags("紧张， 悬疑、Suspense,suspense,,")
+	want := []string{"紧张", "悬疑", "Suspense"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseTags() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,139 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// MinimaxMusicClient MiniMax 音乐生成
+type MinimaxMusicClient struct {
+	BaseURL    string
+	APIKey     string
+	Model      string
+	HTTPClient *http.Client
+}
+
+type MinimaxMusicAudioSetting struct {
+	SampleRate int    `json:"sample_rate"`
+	Bitrate    int    `json:"bitrate"`
+	Format     string `json:"format"`
+}
+
+type MinimaxMusicRequest struct {
+	Model          string                   `json:"model"`
+	Prompt         string                   `json:"prompt"`
+	Lyrics         string                   `json:"lyrics,omitempty"`
+	IsInstrumental bool                     `json:"is_instrumental,omitempty"`
+	AudioSetting   MinimaxMusicAudioSetting `json:"audio_setting"`
+	OutputFormat   string                   `json:"output_format"`
+}
+
+type MinimaxMusicResponse struct {
+	Data struct {
+		Audio  string `json:"audio"` // hex 编码的音频
+		Status int    `json:"status"`
+	} `json:"data"`
+	ExtraInfo struct {
+		MusicDuration int    `json:"music_duration"` // 毫秒
+		AudioFormat   string `json:"audio_format"`
+	} `json:"extra_info"`
+	BaseResp struct {
+		StatusCode int    `json:"status_code"`
+		StatusMsg  string `json:"status_msg"`
+	} `json:"base_resp"`
+}
+
+func NewMinimaxMusicClient(baseURL, apiKey, model string) *MinimaxMusicClient {
+	if baseURL == "" {
+		baseURL = "https://api.minimaxi.com"
+	}
+	if model == "" {
+		model = "music-2.0"
+	}
+	return &MinimaxMusicClient{
+		BaseURL: baseURL,
+		APIKey:  apiKey,
+		Model:   model,
+		HTTPClient: &http.Client{
+			// 音乐生成为同步接口，耗时较长
+			Timeout: 10 * time.Minute,
+		},
+	}
+}
+
+func (c *MinimaxMusicClient) GenerateMusic(prompt string, opts ...MusicOption) (*MusicResult, error) {
+	options := applyMusicOptions(MusicOptions{
+		Model:  c.Model,
+		Format: "mp3",
+	}, opts)
+
+	reqBody := MinimaxMusicRequest{
+		Model:          options.Model,
+		Prompt:         prompt,
+		Lyrics:         options.Lyrics,
+		IsInstrumental: options.Lyrics == "",
+		AudioSetting: MinimaxMusicAudioSetting{
+			SampleRate: 44100,
+			Bitrate:    256000,
+			Format:     options.Format,
+		},
+		OutputFormat: "hex",
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", c.BaseURL+"/v1/music_generation", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.APIKey)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var result MinimaxMusicResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("parse response: %w, body: %s", err, string(body))
+	}
+	if result.BaseResp.StatusCode != 0 {
+		return nil, fmt.Errorf("minimax error: %d - %s", result.BaseResp.StatusCode, result.BaseResp.StatusMsg)
+	}
+
+	audio, err := hex.DecodeString(result.Data.Audio)
+	if err != nil {
+		return nil, fmt.Errorf("decode audio: %w", err)
+	}
+	if len(audio) == 0 {
+		return nil, fmt.Errorf("empty audio response")
+	}
+
+	format := options.Format
+	if result.ExtraInfo.AudioFormat != "" {
+		format = result.ExtraInfo.AudioFormat
+	}
+	return &MusicResult{
+		Audio:    audio,
+		Format:   format,
+		Duration: result.ExtraInfo.MusicDuration,
+	}, nil
+}
@@ -0,0 +1,53 @@
+package audio
+
+// MusicClient 音乐生成接口，用于音频库中没有合适配乐时按提示词生成
+type MusicClient interface {
+	GenerateMusic(prompt string, opts ...MusicOption) (*MusicResult, error)
+}
+
+type MusicResult struct {
+	Audio    []byte // 音频数据
+	Format   string // mp3, wav 等
+	Duration int    // 厂商返回的时长（毫秒），未返回时为 0
+}
+
+type MusicOptions struct {
+	Model    string
+	Lyrics   string // 为空时生成纯音乐
+	Format   string
+	Duration int // 期望时长（秒），仅部分厂商支持
+}
+
+type MusicOption func(*MusicOptions)
+
+func WithMusicModel(model string) MusicOption {
+	return func(o *MusicOptions) {
+		o.Model = model
+	}
+}
+
+func WithLyrics(lyrics string) MusicOption {
+	return func(o *MusicOptions) {
+		o.Lyrics = lyrics
+	}
+}
+
+func WithMusicFormat(format string) MusicOption {
+	return func(o *MusicOptions) {
+		o.Format = format
+	}
+}
+
+func WithMusicDuration(seconds int) MusicOption {
+	return func(o *MusicOptions) {
+		o.Duration = seconds
+	}
+}
+
+func applyMusicOptions(defaults MusicOptions, opts []MusicOption) *MusicOptions {
+	options := defaults
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return &options
+}
@@ -0,0 +1,165 @@
+package colormatch
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// 单个片段校正幅度的上限，超出部分多为内容差异（夜景、特写）而非色偏，不做强行匹配
+const (
+	maxContrastRatio = 1.33
+	maxBrightness    = 0.15 // eq 亮度，-1 到 1
+	maxSaturation    = 1.33
+	maxBalance       = 0.3 // colorbalance 中间调，-1 到 1
+)
+
+// balanceGain RGB 偏移（0-1）换算为 colorbalance 中间调调整值的经验系数
+const balanceGain = 2.0
+
+// Stats 片段画面的平均统计，取值范围 0-255（8 位）
+type Stats struct {
+	Luma       float64 `json:"luma"`       // 平均亮度 YAVG
+	Low        float64 `json:"low"`        // 亮度 10% 分位 YLOW
+	High       float64 `json:"high"`       // 亮度 90% 分位 YHIGH
+	U          float64 `json:"u"`          // 平均蓝色色差 UAVG，128 为中性
+	V          float64 `json:"v"`          // 平均红色色差 VAVG，128 为中性
+	Saturation float64 `json:"saturation"` // 平均饱和度 SATAVG
+}
+
+// Spread 亮度分布范围，用于比较对比度
+func (s Stats) Spread() float64 {
+	return math.Max(s.High-s.Low, 1)
+}
+
+var signalStatsPattern = regexp.MustCompile(`lavfi\.signalstats\.(YAVG|YLOW|YHIGH|UAVG|VAVG|SATAVG)=([\d.]+)`)
+
+// ParseSignalStats 解析 signalstats 经 metadata=print 输出的逐帧统计，返回各帧的平均值
+func ParseSignalStats(output string) (Stats, error) {
+	sums := make(map[string]float64)
+	counts := make(map[string]int)
+	for _, match := range signalStatsPattern.FindAllStringSubmatch(output, -1) {
+		value, err := strconv.ParseFloat(match[2], 64)
+		if err != nil {
+			continue
+		}
+		sums[match[1]] += value
+		counts[match[1]]++
+	}
+	if counts["YAVG"] == 0 {
+		return Stats{}, fmt.Errorf("no signalstats in output")
+	}
+
+	avg := func(key string, fallback float64) float64 {
+		if counts[key] == 0 {
+			return fallback
+		}
+		return sums[key] / float64(counts[key])
+	}
+	return Stats{
+		Luma:       avg("YAVG", 0),
+		Low:        avg("YLOW", 16),
+		High:       avg("YHIGH", 235),
+		U:          avg("UAVG", 128),
+		V:          avg("VAVG", 128),
+		Saturation: avg("SATAVG", 0),
+	}, nil
+}
+
+// Median 各项分别取中位数，作为没有参考镜头时的匹配目标
+func Median(stats []Stats) Stats {
+	if len(stats) == 0 {
+		return Stats{}
+	}
+	pick := func(value func(Stats) float64) float64 {
+		values := make([]float64, len(stats))
+		for i, s := range stats {
+			values[i] = value(s)
+		}
+		sort.Float64s(values)
+		n := len(values)
+		if n%2 == 1 {
+			return values[n/2]
+		}
+		return (values[n/2-1] + values[n/2]) / 2
+	}
+	return Stats{
+		Luma:       pick(func(s Stats) float64 { return s.Luma }),
+		Low:        pick(func(s Stats) float64 { return s.Low }),
+		High:       pick(func(s Stats) float64 { return s.High }),
+		U:          pick(func(s Stats) float64 { return s.U }),
+		V:          pick(func(s Stats) float64 { return s.V }),
+		Saturation: pick(func(s Stats) float64 { return s.Saturation }),
+	}
+}
+
+// Correction 片段的配色校正：Brightness、Contrast、Saturation 对应 eq 滤镜，
+// RM、GM、BM 对应 colorbalance 的中间调
+type Correction struct {
+	Brightness float64 `json:"brightness"`
+	Contrast   float64 `json:"contrast"`
+	Saturation float64 `json:"saturation"`
+	RM         float64 `json:"rm"`
+	GM         float64 `json:"gm"`
+	BM         float64 `json:"bm"`
+}
+
+// Identity 不做任何调整
+func (c Correction) Identity() bool {
+	return c.Brightness == 0 && c.Contrast == 1 && c.Saturation == 1 && c.RM == 0 && c.GM == 0 && c.BM == 0
+}
+
+// EffectConfig 转换为 color 类型片段特效的配置，不需要调整的项不写入
+func (c Correction) EffectConfig() map[string]interface{} {
+	config := make(map[string]interface{})
+	if c.Brightness != 0 {
+		config["brightness"] = c.Brightness
+	}
+	if c.Contrast != 1 {
+		config["contrast"] = c.Contrast
+	}
+	if c.Saturation != 1 {
+		config["saturation"] = c.Saturation
+	}
+	for key, value := range map[string]float64{"rm": c.RM, "gm": c.GM, "bm": c.BM} {
+		if value != 0 {
+			config[key] = value
+		}
+	}
+	return config
+}
+
+// Derive 计算把片段调整到目标统计的校正：对比度按亮度分布范围之比，亮度使平均亮度一致，
+// 饱和度按平均饱和度之比，色温和色调按色差均值之差换算为 RGB 中间调偏移；变化很小的项保持不变
+func Derive(source, target Stats) Correction {
+	c := Correction{Contrast: 1, Saturation: 1}
+
+	c.Contrast = neutral(clamp(target.Spread()/source.Spread(), 1/maxContrastRatio, maxContrastRatio), 1, 0.02)
+	// eq 以 0.5 为中心调整对比度：out = (in - 0.5) * contrast + 0.5 + brightness
+	brightness := (target.Luma - 127.5 - c.Contrast*(source.Luma-127.5)) / 255
+	c.Brightness = neutral(clamp(brightness, -maxBrightness, maxBrightness), 0, 0.01)
+	if source.Saturation > 1 && target.Saturation > 1 {
+		c.Saturation = neutral(clamp(target.Saturation/source.Saturation, 1/maxSaturation, maxSaturation), 1, 0.03)
+	}
+
+	du := (target.U - source.U) / 255
+	dv := (target.V - source.V) / 255
+	c.RM = neutral(clamp(1.402*dv*balanceGain, -maxBalance, maxBalance), 0, 0.01)
+	c.GM = neutral(clamp((-0.344*du-0.714*dv)*balanceGain, -maxBalance, maxBalance), 0, 0.01)
+	c.BM = neutral(clamp(1.772*du*balanceGain, -maxBalance, maxBalance), 0, 0.01)
+	return c
+}
+
+// neutral 与中性值相差不超过 tolerance 时取中性值，否则保留三位小数
+func neutral(value, identity, tolerance float64) float64 {
+	if math.Abs(value-identity) <= tolerance {
+		return identity
+	}
+	return math.Round(value*1000) / 1000
+}
+
+func clamp(value, min, max float64) float64 {
+	return math.Max(min, math.Min(max, value))
+}
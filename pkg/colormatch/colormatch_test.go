@@ -0,0 +1,78 @@
+package colormatch
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseSignalStats(t *testing.T) {
+	output := `[Parsed_metadata_3 @ 0x1] frame:0    pts:0       pts_time:0
+[Parsed_metadata_3 @ 0x1] lavfi.signalstats.YAVG=100.5
+[Parsed_metadata_3 @ 0x1] lavfi.signalstats.YLOW=20
+[Parsed_metadata_3 @ 0x1] lavfi.signalstats.YHIGH=200
+[Parsed_metadata_3 @ 0x1] lavfi.signalstats.UAVG=120
+[Parsed_metadata_3 @ 0x1] lavfi.signalstats.VAVG=136
+[Parsed_metadata_3 @ 0x1] lavfi.signalstats.SATAVG=30
+[Parsed_metadata_3 @ 0x1] frame:1    pts:1       pts_time:0.5
+[Parsed_metadata_3 @ 0x1] lavfi.signalstats.YAVG=110.5
+[Parsed_metadata_3 @ 0x1] lavfi.signalstats.YLOW=30
+[Parsed_metadata_3 @ 0x1] lavfi.signalstats.YHIGH=210
+[Parsed_metadata_3 @ 0x1] lavfi.signalstats.UAVG=124
+[Parsed_metadata_3 @ 0x1] lavfi.signalstats.VAVG=132
+[Parsed_metadata_3 @ 0x1] lavfi.signalstats.SATAVG=34`
+
+	stats, err := ParseSignalStats(output)
+	if err != nil {
+		t.Fatalf("ParseSignalStats() error = %v", err)
+	}
+	want := Stats{Luma: 105.5, Low: 25, High: 205, U: 122, V: 134, Saturation: 32}
+	if stats != want {
+		t.Errorf("ParseSignalStats() = %+v, want %+v", stats, want)
+	}
+
+	if _, err := ParseSignalStats("no stats here"); err == nil {
+		t.Error("ParseSignalStats() without stats should fail")
+	}
+}
+
+func TestDerive(t *testing.T) {
+	target := Stats{Luma: 120, Low: 30, High: 210, U: 128, V: 128, Saturation: 40}
+
+	if c := Derive(target, target); !c.Identity() {
+		t.Errorf("Derive() of identical stats = %+v, want identity", c)
+	}
+
+	// 偏暗、偏暖（V 高 U 低）、对比度偏低的片段
+	source := Stats{Luma: 100, Low: 40, High: 180, U: 122, V: 136, Saturation: 40}
+	c := Derive(source, target)
+	if c.Contrast <= 1 {
+		t.Errorf("Contrast = %v, want > 1", c.Contrast)
+	}
+	if c.RM >= 0 || c.BM <= 0 {
+		t.Errorf("RM = %v, BM = %v, want cooler correction", c.RM, c.BM)
+	}
+	if c.Saturation != 1 {
+		t.Errorf("Saturation = %v, want unchanged", c.Saturation)
+	}
+	// 校正后平均亮度接近目标
+	corrected := (source.Luma/255-0.5)*c.Contrast + 0.5 + c.Brightness
+	if math.Abs(corrected*255-target.Luma) > 3 {
+		t.Errorf("corrected luma = %.1f, want about %.1f", corrected*255, target.Luma)
+	}
+
+	// 差异过大时限制校正幅度
+	extreme := Derive(Stats{Luma: 20, Low: 5, High: 40, U: 128, V: 128}, target)
+	if extreme.Contrast > maxContrastRatio || extreme.Brightness > maxBrightness {
+		t.Errorf("Derive() not clamped: %+v", extreme)
+	}
+}
+
+func TestMedian(t *testing.T) {
+	stats := []Stats{{Luma: 90, U: 120}, {Luma: 130, U: 128}, {Luma: 100, U: 140}}
+	if m := Median(stats); m.Luma != 100 || m.U != 128 {
+		t.Errorf("Median() = %+v", m)
+	}
+	if m := Median(stats[:2]); m.Luma != 110 {
+		t.Errorf("Median() of two = %+v", m)
+	}
+}
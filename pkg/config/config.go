@@ -7,11 +7,17 @@ import (
 )
 
 type Config struct {
-	App      AppConfig      `mapstructure:"app"`
-	Server   ServerConfig   `mapstructure:"server"`
-	Database DatabaseConfig `mapstructure:"database"`
-	Storage  StorageConfig  `mapstructure:"storage"`
-	AI       AIConfig       `mapstructure:"ai"`
+	App       AppConfig       `mapstructure:"app"`
+	Server    ServerConfig    `mapstructure:"server"`
+	Database  DatabaseConfig  `mapstructure:"database"`
+	Storage   StorageConfig   `mapstructure:"storage"`
+	AI        AIConfig        `mapstructure:"ai"`
+	Queue     QueueConfig     `mapstructure:"queue"`
+	Callback  CallbackConfig  `mapstructure:"callback"`
+	Mastering MasteringConfig `mapstructure:"mastering"`
+	QC        QCConfig        `mapstructure:"qc"`
+	FrameRate FrameRateConfig `mapstructure:"frame_rate"`
+	Webhook   WebhookConfig   `mapstructure:"webhook"`
 }
 
 type AppConfig struct {
@@ -52,6 +58,60 @@ type AIConfig struct {
 	DefaultTextProvider  string `mapstructure:"default_text_provider"`
 	DefaultImageProvider string `mapstructure:"default_image_provider"`
 	DefaultVideoProvider string `mapstructure:"default_video_provider"`
+	MaxRetries           int    `mapstructure:"max_retries"`      // 单个配置上临时错误的最大重试次数
+	RetryBaseDelay       int    `mapstructure:"retry_base_delay"` // 首次重试等待时间（秒），之后指数退避
+	RetryMaxDelay        int    `mapstructure:"retry_max_delay"`  // 单次重试等待时间上限（秒）
+}
+
+type QueueConfig struct {
+	PollInterval        int                       `mapstructure:"poll_interval"`        // 调度间隔（秒）
+	LeaseSeconds        int                       `mapstructure:"lease_seconds"`        // 租约时长（秒），超时未心跳的任务会被重新领取
+	MaxAttempts         int                       `mapstructure:"max_attempts"`         // 默认最大执行次数
+	Concurrency         map[string]int            `mapstructure:"concurrency"`          // 按工作池限制并发：text, image, video, audio, ffmpeg, default
+	ProviderConcurrency map[string]map[string]int `mapstructure:"provider_concurrency"` // 按工作池+厂商限制并发
+}
+
+type CallbackConfig struct {
+	PublicURL            string `mapstructure:"public_url"`             // 厂商可访问的服务地址，为空时不注册回调，仅轮询
+	Secret               string `mapstructure:"secret"`                 // 回调地址签名密钥
+	FallbackPollInterval int    `mapstructure:"fallback_poll_interval"` // 已注册回调时的兜底轮询间隔（秒）
+}
+
+// MasteringConfig 成片合成后的响度标准化
+type MasteringConfig struct {
+	Disabled   bool                             `mapstructure:"disabled"`    // 关闭响度标准化
+	Profile    string                           `mapstructure:"profile"`     // 默认输出档位：streaming、broadcast、mobile 或 profiles 中自定义的档位
+	Profiles   map[string]LoudnessProfileConfig `mapstructure:"profiles"`    // 自定义档位，或覆盖内置档位的响度目标
+	Denoise    bool                             `mapstructure:"denoise"`     // 默认是否在标准化前降噪
+	NoiseFloor float64                          `mapstructure:"noise_floor"` // 降噪的噪声底（dB），默认 -25
+}
+
+// QCConfig 生成结果的自动质检
+type QCConfig struct {
+	Disabled         bool `mapstructure:"disabled"`          // 关闭自动质检
+	AutoRegenerate   bool `mapstructure:"auto_regenerate"`   // 质检未通过时自动重新生成
+	MaxRegenerations int  `mapstructure:"max_regenerations"` // 同一镜头自动重新生成的次数上限，默认 1
+	RequireAudio     bool `mapstructure:"require_audio"`     // 视频没有音轨视为未通过（视频厂商生成带声音的视频时开启）
+}
+
+// FrameRateConfig 合成时统一各片段的帧率
+type FrameRateConfig struct {
+	Enabled       bool    `mapstructure:"enabled"`       // 开启后合成时统一帧率，默认保留各片段原帧率（合成请求仍可指定帧率）
+	FPS           int     `mapstructure:"fps"`           // 时间线和输出规格都未指定帧率时使用，默认 30
+	Interpolation string  `mapstructure:"interpolation"` // 帧率转换方式：minterpolate、blend（默认）或 drop
+	Stretch       bool    `mapstructure:"stretch"`       // 片段比分镜时长短时放慢填满
+	MaxStretch    float64 `mapstructure:"max_stretch"`   // 最多放慢的倍数，默认 2
+}
+
+// WebhookConfig webhook 投递，默认拒绝回环、内网和链路本地地址
+type WebhookConfig struct {
+	AllowedHosts []string `mapstructure:"allowed_hosts"` // 允许投递的内网主机名、IP 或 CIDR 网段
+}
+
+type LoudnessProfileConfig struct {
+	TargetLUFS float64 `mapstructure:"target_lufs"` // 综合响度（LUFS）
+	TruePeak   float64 `mapstructure:"true_peak"`   // 真峰值上限（dBTP），默认 -1
+	LRA        float64 `mapstructure:"lra"`         // 响度范围（LU），默认 11
 }
 
 func LoadConfig() (*Config, error) {
@@ -0,0 +1,120 @@
+package interchange
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// EDL 的录制时间码从 01:00:00:00 开始
+const edlRecordStartHours = 1
+
+// EncodeEDL 生成 CMX3600 EDL
+// EDL 只支持一条视频轨道，导出最下层的视频轨道和第一条音频轨道，文字轨道不导出；
+// 片段间转场导出为叠化（D），从空隙/黑场进入的转场导出为从 BL 叠化，变速导出为 M2
+func EncodeEDL(timeline *Timeline) ([]byte, error) {
+	var video, audio *frameTrack
+	for i := range timeline.Tracks {
+		track := &timeline.Tracks[i]
+		switch {
+		case track.Kind == TrackVideo && video == nil:
+			video = buildFrameTrack(track, timeline.FPS)
+		case track.Kind == TrackAudio && audio == nil:
+			audio = buildFrameTrack(track, timeline.FPS)
+		}
+	}
+
+	w := &edlWriter{fps: timeline.FPS, recordOffset: edlRecordStartHours * 3600 * timeline.FPS}
+	fmt.Fprintf(&w.buf, "TITLE: %s\n", edlText(timeline.Name))
+	w.buf.WriteString("FCM: NON-DROP FRAME\n\n")
+
+	if video != nil {
+		for _, clip := range video.clips {
+			w.writeClip(video, clip, "V")
+		}
+	}
+	if audio != nil {
+		for _, clip := range audio.clips {
+			w.writeClip(audio, clip, "A")
+		}
+	}
+	return w.buf.Bytes(), nil
+}
+
+type edlWriter struct {
+	buf          bytes.Buffer
+	fps          int
+	recordOffset int
+	event        int
+}
+
+func (w *edlWriter) writeClip(track *frameTrack, clip *frameClip, channel string) {
+	w.event++
+	srcIn := clip.sourceIn
+	srcOut := clip.sourceIn + clip.sourceDuration
+	recIn := w.recordOffset + clip.start
+	recOut := w.recordOffset + clip.end()
+
+	if c := track.cutBefore(clip); c != nil && channel == "V" {
+		if c.From != nil {
+			fromOut := c.From.sourceIn + c.From.sourceDuration
+			w.writeEvent("AX", channel, "C", "", fromOut, fromOut, recIn, recIn)
+		} else {
+			w.writeEvent("BL", channel, "C", "", 0, 0, recIn, recIn)
+		}
+		w.writeEvent("AX", channel, "D", fmt.Sprintf("%03d", c.Duration), srcIn, srcOut, recIn, recOut)
+		w.buf.WriteString("* EFFECT NAME: CROSS DISSOLVE\n")
+		if c.From != nil {
+			fmt.Fprintf(&w.buf, "* FROM CLIP NAME: %s\n", edlText(clipDisplayName(c.From)))
+			fmt.Fprintf(&w.buf, "* TO CLIP NAME: %s\n", edlText(clipDisplayName(clip)))
+		} else {
+			fmt.Fprintf(&w.buf, "* FROM CLIP NAME: %s\n", edlText(clipDisplayName(clip)))
+		}
+	} else {
+		w.writeEvent("AX", channel, "C", "", srcIn, srcOut, recIn, recOut)
+		fmt.Fprintf(&w.buf, "* FROM CLIP NAME: %s\n", edlText(clipDisplayName(clip)))
+	}
+
+	if clip.speed() != 1 {
+		fmt.Fprintf(&w.buf, "M2   %-8s %05.1f                %s\n", "AX", float64(w.fps)*clip.speed(), w.timecode(srcIn))
+	}
+	if clip.MediaPath != "" {
+		fmt.Fprintf(&w.buf, "* SOURCE FILE: %s\n", clip.MediaPath)
+	}
+	for _, marker := range clip.Markers {
+		fmt.Fprintf(&w.buf, "* LOC: %s YELLOW  %s\n", w.timecode(recIn+toFrames(marker.Offset, w.fps)), edlText(marker.Name))
+	}
+	w.buf.WriteString("\n")
+}
+
+func (w *edlWriter) writeEvent(reel, channel, kind, duration string, srcIn, srcOut, recIn, recOut int) {
+	fmt.Fprintf(&w.buf, "%03d  %-8s %-5s %-4s %-3s %s %s %s %s\n",
+		w.event, reel, channel, kind, duration,
+		w.timecode(srcIn), w.timecode(srcOut), w.timecode(recIn), w.timecode(recOut))
+}
+
+// timecode 帧数转换为非丢帧时间码
+func (w *edlWriter) timecode(frames int) string {
+	if frames < 0 {
+		frames = 0
+	}
+	fps := w.fps
+	return fmt.Sprintf("%02d:%02d:%02d:%02d",
+		frames/(3600*fps), frames/(60*fps)%60, frames/fps%60, frames%fps)
+}
+
+// clipDisplayName 片段名称，未命名时使用素材文件名或文字内容
+func clipDisplayName(clip *frameClip) string {
+	if clip.Name != "" {
+		return clip.Name
+	}
+	if clip.MediaPath == "" {
+		return clip.Text
+	}
+	return mediaName(clip.MediaPath)
+}
+
+// edlText EDL 按行解析，去掉文本中的换行
+func edlText(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
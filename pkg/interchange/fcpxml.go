@@ -0,0 +1,447 @@
+package interchange
+
+import (
+	"encoding/xml"
+	"fmt"
+	"math"
+)
+
+// Final Cut Pro 内置效果的标识
+const (
+	fcpCrossDissolveUID = "FxPlug:4731E73A-8DAC-4113-9A30-AE85B1761265"
+	fcpBasicTitleUID    = ".../Titles.localized/Bumper:Opener.localized/Basic Title.localized/Basic Title.moti"
+)
+
+type fcpxmlDocument struct {
+	XMLName   xml.Name        `xml:"fcpxml"`
+	Version   string          `xml:"version,attr"`
+	Resources fcpxmlResources `xml:"resources"`
+	Library   fcpxmlLibrary   `xml:"library"`
+}
+
+type fcpxmlResources struct {
+	Formats []fcpxmlFormat `xml:"format"`
+	Assets  []*fcpxmlAsset `xml:"asset"`
+	Effects []fcpxmlEffect `xml:"effect"`
+}
+
+type fcpxmlFormat struct {
+	ID            string `xml:"id,attr"`
+	FrameDuration string `xml:"frameDuration,attr"`
+	Width         int    `xml:"width,attr"`
+	Height        int    `xml:"height,attr"`
+}
+
+type fcpxmlAsset struct {
+	ID       string         `xml:"id,attr"`
+	Name     string         `xml:"name,attr"`
+	Start    string         `xml:"start,attr"`
+	Duration string         `xml:"duration,attr"`
+	HasVideo string         `xml:"hasVideo,attr,omitempty"`
+	HasAudio string         `xml:"hasAudio,attr,omitempty"`
+	Format   string         `xml:"format,attr,omitempty"`
+	MediaRep fcpxmlMediaRep `xml:"media-rep"`
+
+	frames int
+}
+
+type fcpxmlMediaRep struct {
+	Kind string `xml:"kind,attr"`
+	Src  string `xml:"src,attr"`
+}
+
+type fcpxmlEffect struct {
+	ID   string `xml:"id,attr"`
+	Name string `xml:"name,attr"`
+	UID  string `xml:"uid,attr"`
+}
+
+type fcpxmlLibrary struct {
+	Event fcpxmlEvent `xml:"event"`
+}
+
+type fcpxmlEvent struct {
+	Name    string        `xml:"name,attr"`
+	Project fcpxmlProject `xml:"project"`
+}
+
+type fcpxmlProject struct {
+	Name     string         `xml:"name,attr"`
+	Sequence fcpxmlSequence `xml:"sequence"`
+}
+
+type fcpxmlSequence struct {
+	Format   string      `xml:"format,attr"`
+	Duration string      `xml:"duration,attr"`
+	TCStart  string      `xml:"tcStart,attr"`
+	TCFormat string      `xml:"tcFormat,attr"`
+	Spine    fcpxmlSpine `xml:"spine"`
+}
+
+type fcpxmlSpine struct {
+	Items []interface{}
+}
+
+type fcpxmlGap struct {
+	XMLName   xml.Name `xml:"gap"`
+	Name      string   `xml:"name,attr"`
+	Offset    string   `xml:"offset,attr"`
+	Start     string   `xml:"start,attr"`
+	Duration  string   `xml:"duration,attr"`
+	Connected []interface{}
+}
+
+type fcpxmlAssetClip struct {
+	XMLName   xml.Name `xml:"asset-clip"`
+	Ref       string   `xml:"ref,attr"`
+	Lane      int      `xml:"lane,attr,omitempty"`
+	Name      string   `xml:"name,attr"`
+	Offset    string   `xml:"offset,attr"`
+	Start     string   `xml:"start,attr"`
+	Duration  string   `xml:"duration,attr"`
+	TimeMap   *fcpxmlTimeMap
+	Connected []interface{}
+	Markers   []fcpxmlMarker `xml:"marker"`
+}
+
+type fcpxmlTimeMap struct {
+	XMLName xml.Name       `xml:"timeMap"`
+	Points  []fcpxmlTimept `xml:"timept"`
+}
+
+type fcpxmlTimept struct {
+	Time   string `xml:"time,attr"`
+	Value  string `xml:"value,attr"`
+	Interp string `xml:"interp,attr"`
+}
+
+type fcpxmlMarker struct {
+	Start    string `xml:"start,attr"`
+	Duration string `xml:"duration,attr"`
+	Value    string `xml:"value,attr"`
+	Note     string `xml:"note,attr,omitempty"`
+}
+
+type fcpxmlTransition struct {
+	XMLName  xml.Name          `xml:"transition"`
+	Name     string            `xml:"name,attr"`
+	Offset   string            `xml:"offset,attr"`
+	Duration string            `xml:"duration,attr"`
+	Filter   fcpxmlFilterVideo `xml:"filter-video"`
+}
+
+type fcpxmlFilterVideo struct {
+	Ref  string `xml:"ref,attr"`
+	Name string `xml:"name,attr"`
+}
+
+type fcpxmlTitle struct {
+	XMLName      xml.Name `xml:"title"`
+	Ref          string   `xml:"ref,attr"`
+	Lane         int      `xml:"lane,attr,omitempty"`
+	Name         string   `xml:"name,attr"`
+	Offset       string   `xml:"offset,attr"`
+	Start        string   `xml:"start,attr"`
+	Duration     string   `xml:"duration,attr"`
+	Text         fcpxmlText
+	TextStyleDef fcpxmlTextStyleDef
+}
+
+type fcpxmlText struct {
+	XMLName xml.Name `xml:"text"`
+	Style   struct {
+		Ref  string `xml:"ref,attr"`
+		Text string `xml:",chardata"`
+	} `xml:"text-style"`
+}
+
+type fcpxmlTextStyleDef struct {
+	XMLName xml.Name `xml:"text-style-def"`
+	ID      string   `xml:"id,attr"`
+	Style   struct {
+		Font      string `xml:"font,attr"`
+		FontSize  int    `xml:"fontSize,attr"`
+		FontColor string `xml:"fontColor,attr"`
+		Alignment string `xml:"alignment,attr"`
+	} `xml:"text-style"`
+}
+
+// fcpxmlSpineElement 主故事情节中的片段或空隙，用于挂接其他轨道的连接片段
+type fcpxmlSpineElement struct {
+	offset    int
+	start     int
+	duration  int
+	connected *[]interface{}
+}
+
+// fcpxmlBuilder 生成 FCPXML 时的状态
+type fcpxmlBuilder struct {
+	fps       int
+	doc       *fcpxmlDocument
+	assets    map[string]*fcpxmlAsset
+	elements  []fcpxmlSpineElement
+	resources int
+	styles    int
+	dissolve  string
+	title     string
+}
+
+// EncodeFCPXML 生成 FCPXML 1.9
+// 最下层的视频轨道作为主故事情节（含转场），其余视频/文字轨道作为上方 lane 的连接片段，音频轨道作为下方 lane 的连接片段；
+// 变速导出为 timeMap，分镜标记导出为片段上的 marker
+func EncodeFCPXML(timeline *Timeline) ([]byte, error) {
+	fps := timeline.FPS
+	width, height := timeline.Width, timeline.Height
+	if width <= 0 || height <= 0 {
+		width, height = 1920, 1080
+	}
+
+	b := &fcpxmlBuilder{
+		fps:    fps,
+		assets: make(map[string]*fcpxmlAsset),
+		doc: &fcpxmlDocument{
+			Version: "1.9",
+			Resources: fcpxmlResources{
+				Formats: []fcpxmlFormat{{ID: "r1", FrameDuration: fmt.Sprintf("1/%ds", fps), Width: width, Height: height}},
+			},
+		},
+		resources: 1,
+	}
+
+	var primary *frameTrack
+	var others []*frameTrack
+	for i := range timeline.Tracks {
+		track := buildFrameTrack(&timeline.Tracks[i], fps)
+		if primary == nil && track.Kind == TrackVideo {
+			primary = track
+			continue
+		}
+		others = append(others, track)
+	}
+
+	all := append([]*frameTrack{}, others...)
+	if primary != nil {
+		all = append(all, primary)
+	}
+	total := timelineFrames(all)
+
+	spine := &b.doc.Library.Event.Project.Sequence.Spine
+	cursor := 0
+	if primary != nil {
+		for _, clip := range primary.clips {
+			if clip.start > cursor {
+				b.appendGap(spine, cursor, clip.start-cursor)
+			}
+			if c := primary.cutBefore(clip); c != nil {
+				offset := c.At
+				if c.From != nil {
+					offset -= c.Duration / 2
+				}
+				spine.Items = append(spine.Items, b.transition(c, offset))
+			}
+			b.appendClip(spine, clip)
+			if c := primary.cutAfter(clip); c != nil {
+				spine.Items = append(spine.Items, b.transition(c, c.At-c.Duration))
+			}
+			cursor = clip.end()
+		}
+	}
+	if cursor < total || len(b.elements) == 0 {
+		b.appendGap(spine, cursor, maxInt(total-cursor, 1))
+	}
+
+	// 连接片段的 lane：视频/文字轨道向上递增，音频轨道向下递减
+	upper, lower := 0, 0
+	for _, track := range others {
+		lane := 0
+		if track.Kind == TrackAudio {
+			lower--
+			lane = lower
+		} else {
+			upper++
+			lane = upper
+		}
+		for _, clip := range track.clips {
+			b.connect(clip, track.Kind, lane)
+		}
+	}
+
+	sequence := &b.doc.Library.Event.Project.Sequence
+	sequence.Format = "r1"
+	sequence.Duration = b.time(maxInt(total, 1))
+	sequence.TCStart = "0s"
+	sequence.TCFormat = "NDF"
+	b.doc.Library.Event.Name = timeline.Name
+	b.doc.Library.Event.Project.Name = timeline.Name
+
+	for _, asset := range b.doc.Resources.Assets {
+		asset.Duration = b.time(asset.frames)
+	}
+
+	data, err := xml.MarshalIndent(b.doc, "", "    ")
+	if err != nil {
+		return nil, err
+	}
+	output := []byte(xml.Header + "<!DOCTYPE fcpxml>\n\n")
+	output = append(output, data...)
+	return append(output, '\n'), nil
+}
+
+func (b *fcpxmlBuilder) appendGap(spine *fcpxmlSpine, offset, duration int) {
+	gap := &fcpxmlGap{Name: "Gap", Offset: b.time(offset), Start: "0s", Duration: b.time(duration)}
+	spine.Items = append(spine.Items, gap)
+	b.elements = append(b.elements, fcpxmlSpineElement{offset: offset, duration: duration, connected: &gap.Connected})
+}
+
+func (b *fcpxmlBuilder) appendClip(spine *fcpxmlSpine, clip *frameClip) {
+	element := b.assetClip(clip, 0, clip.start)
+	spine.Items = append(spine.Items, element)
+	b.elements = append(b.elements, fcpxmlSpineElement{
+		offset:    clip.start,
+		start:     b.clipStart(clip),
+		duration:  clip.duration,
+		connected: &element.Connected,
+	})
+}
+
+// connect 将片段挂接到所在时间的主故事情节元素上，offset 使用父元素的本地时间
+func (b *fcpxmlBuilder) connect(clip *frameClip, kind TrackKind, lane int) {
+	parent := &b.elements[len(b.elements)-1]
+	for i := range b.elements {
+		if clip.start < b.elements[i].offset+b.elements[i].duration {
+			parent = &b.elements[i]
+			break
+		}
+	}
+	offset := parent.start + clip.start - parent.offset
+
+	if kind == TrackText {
+		*parent.connected = append(*parent.connected, b.titleClip(clip, lane, offset))
+		return
+	}
+	if clip.MediaPath == "" {
+		return
+	}
+	*parent.connected = append(*parent.connected, b.assetClip(clip, lane, offset))
+}
+
+func (b *fcpxmlBuilder) assetClip(clip *frameClip, lane, offset int) *fcpxmlAssetClip {
+	asset := b.asset(clip, lane < 0)
+	start := b.clipStart(clip)
+	element := &fcpxmlAssetClip{
+		Ref:      asset.ID,
+		Lane:     lane,
+		Name:     clipDisplayName(clip),
+		Offset:   b.time(offset),
+		Start:    b.time(start),
+		Duration: b.time(clip.duration),
+	}
+
+	if clip.speed() != 1 {
+		mediaFrames := asset.frames
+		element.TimeMap = &fcpxmlTimeMap{Points: []fcpxmlTimept{
+			{Time: "0s", Value: "0s", Interp: "linear"},
+			{Time: b.time(int(math.Round(float64(mediaFrames) / clip.speed()))), Value: b.time(mediaFrames), Interp: "linear"},
+		}}
+	}
+
+	for _, marker := range clip.Markers {
+		element.Markers = append(element.Markers, fcpxmlMarker{
+			Start:    b.time(start + toFrames(marker.Offset, b.fps)),
+			Duration: b.time(1),
+			Value:    marker.Name,
+			Note:     marker.Note,
+		})
+	}
+	return element
+}
+
+func (b *fcpxmlBuilder) titleClip(clip *frameClip, lane, offset int) *fcpxmlTitle {
+	if b.title == "" {
+		b.title = b.effect("Basic Title", fcpBasicTitleUID)
+	}
+	b.styles++
+	styleID := fmt.Sprintf("ts%d", b.styles)
+
+	title := &fcpxmlTitle{
+		Ref:      b.title,
+		Lane:     lane,
+		Name:     clipDisplayName(clip),
+		Offset:   b.time(offset),
+		Start:    "0s",
+		Duration: b.time(clip.duration),
+	}
+	title.Text.Style.Ref = styleID
+	title.Text.Style.Text = clip.Text
+	title.TextStyleDef.ID = styleID
+	title.TextStyleDef.Style.Font = "PingFang SC"
+	title.TextStyleDef.Style.FontSize = 60
+	title.TextStyleDef.Style.FontColor = "1 1 1 1"
+	title.TextStyleDef.Style.Alignment = "center"
+	return title
+}
+
+func (b *fcpxmlBuilder) transition(c *cut, offset int) *fcpxmlTransition {
+	if b.dissolve == "" {
+		b.dissolve = b.effect("Cross Dissolve", fcpCrossDissolveUID)
+	}
+	return &fcpxmlTransition{
+		Name:     "Cross Dissolve",
+		Offset:   b.time(maxInt(offset, 0)),
+		Duration: b.time(c.Duration),
+		Filter:   fcpxmlFilterVideo{Ref: b.dissolve, Name: "Cross Dissolve"},
+	}
+}
+
+// asset 按素材路径复用资源，时长取素材时长与所有引用出点的最大值
+func (b *fcpxmlBuilder) asset(clip *frameClip, audioOnly bool) *fcpxmlAsset {
+	sourceOut := clip.sourceIn + clip.sourceDuration
+	if asset, ok := b.assets[clip.MediaPath]; ok {
+		asset.frames = maxInt(asset.frames, sourceOut)
+		return asset
+	}
+
+	b.resources++
+	asset := &fcpxmlAsset{
+		ID:       fmt.Sprintf("r%d", b.resources),
+		Name:     mediaName(clip.MediaPath),
+		Start:    "0s",
+		HasAudio: "1",
+		MediaRep: fcpxmlMediaRep{Kind: "original-media", Src: mediaURL(clip.MediaPath)},
+		frames:   maxInt(toFrames(clip.MediaDuration, b.fps), sourceOut),
+	}
+	if !audioOnly {
+		asset.HasVideo = "1"
+		asset.Format = "r1"
+	}
+	b.assets[clip.MediaPath] = asset
+	b.doc.Resources.Assets = append(b.doc.Resources.Assets, asset)
+	return asset
+}
+
+func (b *fcpxmlBuilder) effect(name, uid string) string {
+	b.resources++
+	id := fmt.Sprintf("r%d", b.resources)
+	b.doc.Resources.Effects = append(b.doc.Resources.Effects, fcpxmlEffect{ID: id, Name: name, UID: uid})
+	return id
+}
+
+// clipStart 片段的本地起始时间，变速片段为换算后的时间
+func (b *fcpxmlBuilder) clipStart(clip *frameClip) int {
+	return int(math.Round(float64(clip.sourceIn) / clip.speed()))
+}
+
+// time 帧数转换为 FCPXML 有理数时间
+func (b *fcpxmlBuilder) time(frames int) string {
+	if frames == 0 {
+		return "0s"
+	}
+	return fmt.Sprintf("%d/%ds", frames, b.fps)
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,266 @@
+package interchange
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Format 剪辑交换格式
+type Format string
+
+const (
+	FormatEDL    Format = "edl"
+	FormatFCPXML Format = "fcpxml"
+	FormatOTIO   Format = "otio"
+)
+
+// TrackKind 轨道类型
+type TrackKind string
+
+const (
+	TrackVideo TrackKind = "video"
+	TrackAudio TrackKind = "audio"
+	TrackText  TrackKind = "text"
+)
+
+// Timeline 导出用的时间线，时间单位均为毫秒
+type Timeline struct {
+	Name   string
+	FPS    int
+	Width  int
+	Height int
+	Tracks []Track // 视频/文字轨道按从下到上排列
+}
+
+// Track 轨道，片段之间不重叠
+type Track struct {
+	Name  string
+	Kind  TrackKind
+	Clips []Clip
+}
+
+// Clip 片段
+type Clip struct {
+	Name          string
+	MediaPath     string // 本地绝对路径或远程 URL，文字片段为空
+	MediaDuration int    // 素材总时长，未知时为 0
+	Text          string // 文字片段内容
+	Start         int    // 在时间线上的位置
+	Duration      int    // 在时间线上的时长
+	SourceIn      int    // 素材入点
+	Speed         float64
+
+	TransitionIn  *Transition
+	TransitionOut *Transition
+	Markers       []Marker
+}
+
+// Transition 转场
+type Transition struct {
+	Name     string
+	Duration int
+}
+
+// Marker 标记点，Offset 为相对片段起点的偏移
+type Marker struct {
+	Name   string
+	Offset int
+	Note   string
+}
+
+// ParseFormat 解析导出格式
+func ParseFormat(format string) (Format, error) {
+	switch Format(strings.ToLower(strings.TrimSpace(format))) {
+	case FormatEDL:
+		return FormatEDL, nil
+	case FormatFCPXML, "xml":
+		return FormatFCPXML, nil
+	case FormatOTIO, "opentimelineio":
+		return FormatOTIO, nil
+	default:
+		return "", fmt.Errorf("unsupported export format: %s", format)
+	}
+}
+
+// Encode 将时间线编码为指定格式
+func Encode(timeline *Timeline, format Format) ([]byte, error) {
+	if timeline.FPS <= 0 {
+		timeline.FPS = 30
+	}
+	switch format {
+	case FormatEDL:
+		return EncodeEDL(timeline)
+	case FormatFCPXML:
+		return EncodeFCPXML(timeline)
+	case FormatOTIO:
+		return EncodeOTIO(timeline)
+	default:
+		return nil, fmt.Errorf("unsupported export format: %s", format)
+	}
+}
+
+// ContentType 返回格式对应的 MIME 类型
+func (f Format) ContentType() string {
+	switch f {
+	case FormatFCPXML:
+		return "application/xml; charset=utf-8"
+	case FormatOTIO:
+		return "application/json; charset=utf-8"
+	default:
+		return "text/plain; charset=utf-8"
+	}
+}
+
+// Extension 返回格式对应的文件扩展名
+func (f Format) Extension() string {
+	return "." + string(f)
+}
+
+// frameClip 换算为帧的片段
+type frameClip struct {
+	*Clip
+	start          int
+	duration       int
+	sourceIn       int
+	sourceDuration int
+}
+
+func (c *frameClip) end() int {
+	return c.start + c.duration
+}
+
+func (c *frameClip) speed() float64 {
+	if c.Speed <= 0 {
+		return 1
+	}
+	return c.Speed
+}
+
+// cut 轨道上一处转场：From/To 为转场两侧的片段，为空表示黑场或空隙
+type cut struct {
+	From     *frameClip
+	To       *frameClip
+	Name     string
+	At       int // 剪辑点所在帧
+	Duration int
+}
+
+// frameTrack 按帧排列的轨道片段及转场
+type frameTrack struct {
+	*Track
+	clips []*frameClip
+	cuts  []cut
+}
+
+// toFrames 毫秒换算为帧
+func toFrames(ms, fps int) int {
+	return int(math.Round(float64(ms) * float64(fps) / 1000))
+}
+
+// buildFrameTrack 将片段换算为帧并整理转场：
+// 相邻片段之间的转场优先使用后一片段的入场转场；与空隙相邻的入场/出场转场视为淡入淡出
+func buildFrameTrack(track *Track, fps int) *frameTrack {
+	ft := &frameTrack{Track: track}
+	for i := range track.Clips {
+		clip := &track.Clips[i]
+		fc := &frameClip{
+			Clip:     clip,
+			start:    toFrames(clip.Start, fps),
+			duration: toFrames(clip.Duration, fps),
+			sourceIn: toFrames(clip.SourceIn, fps),
+		}
+		if fc.duration <= 0 {
+			continue
+		}
+		fc.sourceDuration = int(math.Round(float64(fc.duration) * fc.speed()))
+		ft.clips = append(ft.clips, fc)
+	}
+	sort.SliceStable(ft.clips, func(i, j int) bool {
+		return ft.clips[i].start < ft.clips[j].start
+	})
+
+	for i, clip := range ft.clips {
+		var prev *frameClip
+		if i > 0 && ft.clips[i-1].end() == clip.start {
+			prev = ft.clips[i-1]
+		}
+
+		transition := clip.TransitionIn
+		if transition == nil && prev != nil {
+			transition = prev.TransitionOut
+		}
+		if transition != nil {
+			if d := toFrames(transition.Duration, fps); d > 0 {
+				ft.cuts = append(ft.cuts, cut{From: prev, To: clip, Name: transition.Name, At: clip.start, Duration: d})
+			}
+		}
+
+		nextAdjacent := i+1 < len(ft.clips) && ft.clips[i+1].start == clip.end()
+		if clip.TransitionOut != nil && !nextAdjacent {
+			if d := toFrames(clip.TransitionOut.Duration, fps); d > 0 {
+				ft.cuts = append(ft.cuts, cut{From: clip, Name: clip.TransitionOut.Name, At: clip.end(), Duration: d})
+			}
+		}
+	}
+	return ft
+}
+
+// cutBefore 返回片段入点处的转场
+func (ft *frameTrack) cutBefore(clip *frameClip) *cut {
+	for i := range ft.cuts {
+		if ft.cuts[i].To == clip {
+			return &ft.cuts[i]
+		}
+	}
+	return nil
+}
+
+// cutAfter 返回片段出点处的淡出转场（不含与下一片段之间的转场）
+func (ft *frameTrack) cutAfter(clip *frameClip) *cut {
+	for i := range ft.cuts {
+		if ft.cuts[i].From == clip && ft.cuts[i].To == nil {
+			return &ft.cuts[i]
+		}
+	}
+	return nil
+}
+
+// timelineFrames 返回时间线总帧数
+func timelineFrames(tracks []*frameTrack) int {
+	total := 0
+	for _, track := range tracks {
+		for _, clip := range track.clips {
+			if clip.end() > total {
+				total = clip.end()
+			}
+		}
+	}
+	return total
+}
+
+// mediaURL 本地路径转换为 file:// URL，远程地址保持不变
+func mediaURL(path string) string {
+	if path == "" || strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") || strings.HasPrefix(path, "file://") {
+		return path
+	}
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
+
+// mediaName 返回素材的文件名
+func mediaName(path string) string {
+	if path == "" {
+		return ""
+	}
+	if u, err := url.Parse(path); err == nil && u.Path != "" {
+		path = u.Path
+	}
+	return filepath.Base(filepath.FromSlash(path))
+}
@@ -0,0 +1,150 @@
+package interchange
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func testTimeline() *Timeline {
+	return &Timeline{
+		Name: "Episode 1",
+		FPS:  30,
+		Tracks: []Track{
+			{Name: "V1", Kind: TrackVideo, Clips: []Clip{
+				{Name: "SB1", MediaPath: "/data/a.mp4", MediaDuration: 5000, Start: 0, Duration: 4000, SourceIn: 500,
+					Markers: []Marker{{Name: "Storyboard 1"}}},
+				{Name: "SB2", MediaPath: "/data/b.mp4", MediaDuration: 6000, Start: 4000, Duration: 2000, Speed: 2,
+					TransitionIn: &Transition{Name: "dissolve", Duration: 500}},
+			}},
+			{Name: "T1", Kind: TrackText, Clips: []Clip{{Text: "hello", Start: 1000, Duration: 1500}}},
+			{Name: "A1", Kind: TrackAudio, Clips: []Clip{{Name: "bgm", MediaPath: "/data/bgm.mp3", Start: 0, Duration: 6000}}},
+		},
+	}
+}
+
+func TestEncodeEDL(t *testing.T) {
+	data, err := Encode(testTimeline(), FormatEDL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edl := string(data)
+
+	for _, want := range []string{
+		"001  AX       V     C        00:00:00:15 00:00:04:15 01:00:00:00 01:00:04:00",
+		"002  AX       V     C        00:00:04:15 00:00:04:15 01:00:04:00 01:00:04:00",
+		"002  AX       V     D    015 00:00:00:00 00:00:04:00 01:00:04:00 01:00:06:00",
+		"M2   AX       060.0                00:00:00:00",
+		"* LOC: 01:00:00:00 YELLOW  Storyboard 1",
+		"003  AX       A     C        00:00:00:00 00:00:06:00 01:00:00:00 01:00:06:00",
+	} {
+		if !strings.Contains(edl, want) {
+			t.Errorf("EDL missing line %q\n%s", want, edl)
+		}
+	}
+}
+
+func TestEncodeFCPXML(t *testing.T) {
+	data, err := Encode(testTimeline(), FormatFCPXML)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc struct {
+		Spine struct {
+			Clips []struct {
+				Name   string `xml:"name,attr"`
+				Offset string `xml:"offset,attr"`
+				Start  string `xml:"start,attr"`
+				Titles []struct {
+					Offset string `xml:"offset,attr"`
+				} `xml:"title"`
+				Audio []struct {
+					Lane int `xml:"lane,attr"`
+				} `xml:"asset-clip"`
+				Markers []struct {
+					Value string `xml:"value,attr"`
+				} `xml:"marker"`
+			} `xml:"asset-clip"`
+			Transitions []struct {
+				Offset   string `xml:"offset,attr"`
+				Duration string `xml:"duration,attr"`
+			} `xml:"transition"`
+		} `xml:"library>event>project>sequence>spine"`
+	}
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("invalid xml: %v", err)
+	}
+
+	clips := doc.Spine.Clips
+	if len(clips) != 2 || clips[0].Start != "15/30s" || clips[1].Offset != "120/30s" {
+		t.Fatalf("unexpected spine clips: %+v", clips)
+	}
+	if len(clips[0].Titles) != 1 || clips[0].Titles[0].Offset != "45/30s" {
+		t.Errorf("title should connect to first clip at its local time, got %+v", clips[0].Titles)
+	}
+	if len(clips[0].Audio) != 1 || clips[0].Audio[0].Lane != -1 {
+		t.Errorf("audio should connect below the storyline, got %+v", clips[0].Audio)
+	}
+	if len(clips[0].Markers) != 1 || clips[0].Markers[0].Value != "Storyboard 1" {
+		t.Errorf("unexpected markers: %+v", clips[0].Markers)
+	}
+	if len(doc.Spine.Transitions) != 1 || doc.Spine.Transitions[0].Offset != "113/30s" {
+		t.Errorf("transition should be centered on the cut, got %+v", doc.Spine.Transitions)
+	}
+}
+
+func TestEncodeOTIO(t *testing.T) {
+	data, err := Encode(testTimeline(), FormatOTIO)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var doc struct {
+		Tracks struct {
+			Children []struct {
+				Kind     string `json:"kind"`
+				Children []struct {
+					Schema    string                  `json:"OTIO_SCHEMA"`
+					InOffset  struct{ Value float64 } `json:"in_offset"`
+					OutOffset struct{ Value float64 } `json:"out_offset"`
+					Reference struct {
+						TargetURL string `json:"target_url"`
+					} `json:"media_reference"`
+				} `json:"children"`
+			} `json:"children"`
+		} `json:"tracks"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+
+	tracks := doc.Tracks.Children
+	if len(tracks) != 3 || tracks[2].Kind != "Audio" {
+		t.Fatalf("unexpected tracks: %+v", tracks)
+	}
+	video := tracks[0].Children
+	if len(video) != 3 || video[1].Schema != "Transition.1" {
+		t.Fatalf("expected clip, transition, clip: %+v", video)
+	}
+	if video[1].InOffset.Value+video[1].OutOffset.Value != 15 {
+		t.Errorf("transition offsets should cover 15 frames, got %+v", video[1])
+	}
+	if video[0].Reference.TargetURL != "file:///data/a.mp4" {
+		t.Errorf("unexpected media url: %s", video[0].Reference.TargetURL)
+	}
+	text := tracks[1].Children
+	if len(text) != 2 || text[0].Schema != "Gap.1" {
+		t.Errorf("text track should start with a gap: %+v", text)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	if f, err := ParseFormat("FCPXML"); err != nil || f != FormatFCPXML {
+		t.Errorf("ParseFormat(FCPXML) = %v, %v", f, err)
+	}
+	if _, err := ParseFormat("aaf"); err == nil {
+		t.Error("expected error for unsupported format")
+	}
+}
@@ -0,0 +1,173 @@
+package interchange
+
+import (
+	"encoding/json"
+)
+
+// otioObject OTIO 对象按 schema 序列化为 JSON 对象
+type otioObject map[string]interface{}
+
+// EncodeOTIO 生成 OpenTimelineIO（.otio）JSON
+// 每条轨道对应一个 Track，变速导出为 LinearTimeWarp，文字片段使用 text 类型的 GeneratorReference
+func EncodeOTIO(timeline *Timeline) ([]byte, error) {
+	fps := timeline.FPS
+	children := make([]interface{}, 0, len(timeline.Tracks))
+	for i := range timeline.Tracks {
+		children = append(children, otioTrack(buildFrameTrack(&timeline.Tracks[i], fps), fps))
+	}
+
+	document := otioObject{
+		"OTIO_SCHEMA":       "Timeline.1",
+		"name":              timeline.Name,
+		"global_start_time": otioTime(0, fps),
+		"metadata":          otioObject{},
+		"tracks": otioObject{
+			"OTIO_SCHEMA":  "Stack.1",
+			"name":         "tracks",
+			"children":     children,
+			"source_range": nil,
+			"effects":      []interface{}{},
+			"markers":      []interface{}{},
+			"metadata":     otioObject{},
+		},
+	}
+
+	data, err := json.MarshalIndent(document, "", "    ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+func otioTrack(track *frameTrack, fps int) otioObject {
+	kind := "Video"
+	if track.Kind == TrackAudio {
+		kind = "Audio"
+	}
+
+	children := []interface{}{}
+	cursor := 0
+	for _, clip := range track.clips {
+		if clip.start > cursor {
+			children = append(children, otioObject{
+				"OTIO_SCHEMA":  "Gap.1",
+				"name":         "",
+				"source_range": otioRange(0, clip.start-cursor, fps),
+				"effects":      []interface{}{},
+				"markers":      []interface{}{},
+				"metadata":     otioObject{},
+			})
+		}
+
+		// 转场位于两个相邻元素之间，in_offset/out_offset 为剪辑点前后占用的长度
+		if c := track.cutBefore(clip); c != nil {
+			if c.From != nil {
+				children = append(children, otioTransition(c.Name, c.Duration/2, c.Duration-c.Duration/2, fps))
+			} else {
+				children = append(children, otioTransition(c.Name, 0, c.Duration, fps))
+			}
+		}
+		children = append(children, otioClip(clip, track.Kind, fps))
+		if c := track.cutAfter(clip); c != nil {
+			children = append(children, otioTransition(c.Name, c.Duration, 0, fps))
+		}
+		cursor = clip.end()
+	}
+
+	return otioObject{
+		"OTIO_SCHEMA":  "Track.1",
+		"name":         track.Name,
+		"kind":         kind,
+		"children":     children,
+		"source_range": nil,
+		"effects":      []interface{}{},
+		"markers":      []interface{}{},
+		"metadata":     otioObject{},
+	}
+}
+
+func otioClip(clip *frameClip, kind TrackKind, fps int) otioObject {
+	var reference otioObject
+	if kind == TrackText {
+		reference = otioObject{
+			"OTIO_SCHEMA":     "GeneratorReference.1",
+			"name":            "",
+			"generator_kind":  "text",
+			"parameters":      otioObject{"text": clip.Text},
+			"available_range": nil,
+			"metadata":        otioObject{},
+		}
+	} else {
+		var available interface{}
+		if clip.MediaDuration > 0 {
+			available = otioRange(0, toFrames(clip.MediaDuration, fps), fps)
+		}
+		reference = otioObject{
+			"OTIO_SCHEMA":     "ExternalReference.1",
+			"name":            mediaName(clip.MediaPath),
+			"target_url":      mediaURL(clip.MediaPath),
+			"available_range": available,
+			"metadata":        otioObject{},
+		}
+	}
+
+	effects := []interface{}{}
+	if clip.speed() != 1 {
+		effects = append(effects, otioObject{
+			"OTIO_SCHEMA": "LinearTimeWarp.1",
+			"name":        "",
+			"effect_name": "LinearTimeWarp",
+			"time_scalar": clip.speed(),
+			"metadata":    otioObject{},
+		})
+	}
+
+	markers := []interface{}{}
+	for _, marker := range clip.Markers {
+		markers = append(markers, otioObject{
+			"OTIO_SCHEMA":  "Marker.2",
+			"name":         marker.Name,
+			"color":        "RED",
+			"comment":      marker.Note,
+			"marked_range": otioRange(clip.sourceIn+toFrames(marker.Offset, fps), 0, fps),
+			"metadata":     otioObject{},
+		})
+	}
+
+	return otioObject{
+		"OTIO_SCHEMA":     "Clip.1",
+		"name":            clipDisplayName(clip),
+		"source_range":    otioRange(clip.sourceIn, clip.duration, fps),
+		"media_reference": reference,
+		"effects":         effects,
+		"markers":         markers,
+		"metadata":        otioObject{},
+	}
+}
+
+func otioTransition(name string, inOffset, outOffset, fps int) otioObject {
+	return otioObject{
+		"OTIO_SCHEMA":     "Transition.1",
+		"name":            name,
+		"transition_type": "SMPTE_Dissolve",
+		"in_offset":       otioTime(inOffset, fps),
+		"out_offset":      otioTime(outOffset, fps),
+		"metadata":        otioObject{},
+	}
+}
+
+func otioTime(frames, fps int) otioObject {
+	return otioObject{
+		"OTIO_SCHEMA": "RationalTime.1",
+		"rate":        float64(fps),
+		"value":       float64(frames),
+	}
+}
+
+func otioRange(start, duration, fps int) otioObject {
+	return otioObject{
+		"OTIO_SCHEMA": "TimeRange.1",
+		"start_time":  otioTime(start, fps),
+		"duration":    otioTime(duration, fps),
+	}
+}
@@ -0,0 +1,312 @@
+package qc
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/drama-generator/backend/pkg/thumbnail"
+)
+
+// 质检结论
+const (
+	StatusPassed  = "passed"  // 没有发现问题
+	StatusWarning = "warning" // 只有不影响使用的问题
+	StatusFailed  = "failed"  // 存在需要重新生成的问题
+	StatusError   = "error"   // 文件无法探测，未能完成质检
+)
+
+// 问题严重程度
+const (
+	SeverityWarning = "warning"
+	SeverityError   = "error"
+)
+
+// 质检问题标记
+const (
+	FlagBlack         = "black"          // 黑场
+	FlagFrozen        = "frozen"         // 冻帧
+	FlagDurationShort = "duration_short" // 时长不足
+	FlagDurationLong  = "duration_long"  // 时长明显超出
+	FlagAspectRatio   = "aspect_ratio"   // 画幅比例与请求不符
+	FlagResolution    = "resolution"     // 分辨率低于请求
+	FlagNoAudio       = "no_audio"       // 没有音轨
+	FlagBlank         = "blank"          // 图片为纯色或接近纯色
+	FlagDimensions    = "dimensions"     // 图片尺寸与请求不符或过小
+	FlagUnreadable    = "unreadable"     // 文件无法探测
+)
+
+// 判定阈值
+const (
+	badRatio          = 0.5  // 黑场/冻帧占时长的比例达到该值视为不可用
+	noticeSeconds     = 1.0  // 黑场/冻帧累计达到该秒数时提示
+	aspectTolerance   = 0.03 // 画幅比例允许的相对误差
+	resolutionMargin  = 0.9  // 短边低于请求的 90% 视为分辨率不足
+	minImageShortSide = 256  // 图片短边低于该像素数视为尺寸过小
+)
+
+// Segment 黑场或冻帧片段（秒）
+type Segment struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+}
+
+// Duration 片段时长
+func (s Segment) Duration() float64 {
+	return math.Max(s.End-s.Start, 0)
+}
+
+// VideoProbe 视频的探测结果
+type VideoProbe struct {
+	Duration float64   `json:"duration"`
+	Width    int       `json:"width"`
+	Height   int       `json:"height"`
+	HasAudio bool      `json:"has_audio"`
+	Black    []Segment `json:"black,omitempty"`
+	Frozen   []Segment `json:"frozen,omitempty"`
+}
+
+// VideoSpec 视频生成时请求的规格，为零的项不检查
+type VideoSpec struct {
+	Duration     float64 `json:"duration,omitempty"`       // 请求时长（秒）
+	AspectRatio  float64 `json:"aspect_ratio,omitempty"`   // 宽高比
+	MinShortSide int     `json:"min_short_side,omitempty"` // 请求分辨率的短边
+	RequireAudio bool    `json:"require_audio,omitempty"`
+}
+
+// ImageProbe 图片的探测结果
+type ImageProbe struct {
+	Width  int                  `json:"width"`
+	Height int                  `json:"height"`
+	Stats  thumbnail.FrameStats `json:"stats"`
+}
+
+// ImageSpec 图片生成时请求的规格，为零的项不检查
+type ImageSpec struct {
+	Width       int     `json:"width,omitempty"` // 明确请求的尺寸
+	Height      int     `json:"height,omitempty"`
+	AspectRatio float64 `json:"aspect_ratio,omitempty"` // 仅通过提示词要求的宽高比，不符时只提示
+}
+
+// Issue 质检发现的问题
+type Issue struct {
+	Flag     string `json:"flag"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// Report 质检结论和问题列表
+type Report struct {
+	Status string  `json:"status"`
+	Issues []Issue `json:"issues,omitempty"`
+}
+
+// Flags 问题标记列表
+func (r Report) Flags() []string {
+	flags := make([]string, 0, len(r.Issues))
+	for _, issue := range r.Issues {
+		flags = append(flags, issue.Flag)
+	}
+	return flags
+}
+
+// NewReport 根据问题的严重程度得出质检结论
+func NewReport(issues []Issue) Report {
+	status := StatusPassed
+	for _, issue := range issues {
+		if issue.Severity == SeverityError {
+			status = StatusFailed
+			break
+		}
+		status = StatusWarning
+	}
+	return Report{Status: status, Issues: issues}
+}
+
+// Unreadable 文件无法探测时的质检结论
+func Unreadable(err error) Report {
+	return Report{Status: StatusError, Issues: []Issue{{FlagUnreadable, SeverityError, err.Error()}}}
+}
+
+// CheckVideo 检查黑场、冻帧、时长、画幅、分辨率和音轨
+func CheckVideo(probe VideoProbe, spec VideoSpec) Report {
+	var issues []Issue
+	issues = append(issues, checkSegments(FlagBlack, "黑场", probe.Black, probe.Duration)...)
+	issues = append(issues, checkSegments(FlagFrozen, "冻帧", probe.Frozen, probe.Duration)...)
+
+	if spec.Duration > 0 && probe.Duration > 0 {
+		diff := probe.Duration - spec.Duration
+		switch {
+		case diff < -math.Max(0.5, spec.Duration*0.15):
+			issues = append(issues, Issue{FlagDurationShort, SeverityError,
+				fmt.Sprintf("时长 %.1fs，短于请求的 %.1fs", probe.Duration, spec.Duration)})
+		case diff > math.Max(1, spec.Duration*0.5):
+			issues = append(issues, Issue{FlagDurationLong, SeverityWarning,
+				fmt.Sprintf("时长 %.1fs，明显长于请求的 %.1fs", probe.Duration, spec.Duration)})
+		}
+	}
+
+	if spec.AspectRatio > 0 && probe.Width > 0 && probe.Height > 0 && !aspectMatches(probe.Width, probe.Height, spec.AspectRatio) {
+		issues = append(issues, Issue{FlagAspectRatio, SeverityError,
+			fmt.Sprintf("画面 %dx%d 与请求的宽高比 %.3f 不符", probe.Width, probe.Height, spec.AspectRatio)})
+	}
+
+	if spec.MinShortSide > 0 && probe.Width > 0 && probe.Height > 0 {
+		if shortSide := minInt(probe.Width, probe.Height); float64(shortSide) < float64(spec.MinShortSide)*resolutionMargin {
+			issues = append(issues, Issue{FlagResolution, SeverityWarning,
+				fmt.Sprintf("分辨率 %dx%d 低于请求的 %dp", probe.Width, probe.Height, spec.MinShortSide)})
+		}
+	}
+
+	if spec.RequireAudio && !probe.HasAudio {
+		issues = append(issues, Issue{FlagNoAudio, SeverityError, "没有音轨"})
+	}
+
+	return NewReport(issues)
+}
+
+// checkSegments 黑场/冻帧占时长一半以上视为不可用，累计超过 1 秒时提示
+func checkSegments(flag, label string, segments []Segment, duration float64) []Issue {
+	var total float64
+	for _, segment := range segments {
+		total += segment.Duration()
+	}
+	switch {
+	case total <= 0:
+		return nil
+	case duration > 0 && total >= duration*badRatio:
+		return []Issue{{flag, SeverityError, fmt.Sprintf("%s %.1fs，占时长 %.0f%%", label, total, total/duration*100)}}
+	case total >= noticeSeconds:
+		return []Issue{{flag, SeverityWarning, fmt.Sprintf("%s %.1fs", label, total)}}
+	}
+	return nil
+}
+
+// CheckImage 检查纯色画面和尺寸
+func CheckImage(probe ImageProbe, spec ImageSpec) Report {
+	var issues []Issue
+	if probe.Stats.Blank() {
+		issues = append(issues, Issue{FlagBlank, SeverityError,
+			fmt.Sprintf("画面接近纯色（平均亮度 %.0f，对比度 %.1f）", probe.Stats.MeanLuma, probe.Stats.Contrast)})
+	}
+
+	if probe.Width > 0 && probe.Height > 0 {
+		switch {
+		case minInt(probe.Width, probe.Height) < minImageShortSide:
+			issues = append(issues, Issue{FlagDimensions, SeverityError,
+				fmt.Sprintf("尺寸 %dx%d 过小", probe.Width, probe.Height)})
+		case spec.Width > 0 && spec.Height > 0 && !aspectMatches(probe.Width, probe.Height, float64(spec.Width)/float64(spec.Height)):
+			issues = append(issues, Issue{FlagDimensions, SeverityError,
+				fmt.Sprintf("尺寸 %dx%d 与请求的 %dx%d 比例不符", probe.Width, probe.Height, spec.Width, spec.Height)})
+		case spec.Width > 0 && spec.Height > 0 && (probe.Width < spec.Width || probe.Height < spec.Height):
+			issues = append(issues, Issue{FlagDimensions, SeverityWarning,
+				fmt.Sprintf("尺寸 %dx%d 小于请求的 %dx%d", probe.Width, probe.Height, spec.Width, spec.Height)})
+		case spec.AspectRatio > 0 && !aspectMatches(probe.Width, probe.Height, spec.AspectRatio):
+			issues = append(issues, Issue{FlagAspectRatio, SeverityWarning,
+				fmt.Sprintf("画面 %dx%d 与要求的宽高比 %.3f 不符", probe.Width, probe.Height, spec.AspectRatio)})
+		}
+	}
+
+	return NewReport(issues)
+}
+
+func aspectMatches(width, height int, ratio float64) bool {
+	return math.Abs(float64(width)/float64(height)/ratio-1) <= aspectTolerance
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// ParseAspectRatio 解析 "16:9"、"1920x1080" 或 "1.78" 形式的宽高比，无法解析时返回 0
+func ParseAspectRatio(value string) float64 {
+	value = strings.ToLower(strings.TrimSpace(value))
+	for _, sep := range []string{":", "x", "*"} {
+		if parts := strings.SplitN(value, sep, 2); len(parts) == 2 {
+			w, errW := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+			h, errH := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+			if errW != nil || errH != nil || w <= 0 || h <= 0 {
+				return 0
+			}
+			return w / h
+		}
+	}
+	ratio, err := strconv.ParseFloat(value, 64)
+	if err != nil || ratio <= 0 {
+		return 0
+	}
+	return ratio
+}
+
+// ParseSize 解析 "1920x1080" 形式的尺寸，无法解析时返回 0, 0
+func ParseSize(value string) (int, int) {
+	parts := strings.SplitN(strings.ToLower(strings.TrimSpace(value)), "x", 2)
+	if len(parts) != 2 {
+		return 0, 0
+	}
+	w, errW := strconv.Atoi(strings.TrimSpace(parts[0]))
+	h, errH := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if errW != nil || errH != nil || w <= 0 || h <= 0 {
+		return 0, 0
+	}
+	return w, h
+}
+
+// ParseResolution 解析 "720p" 或 "1280x720" 形式的分辨率，返回短边像素数，无法解析时返回 0
+func ParseResolution(value string) int {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if w, h := ParseSize(value); w > 0 {
+		return minInt(w, h)
+	}
+	switch value {
+	case "4k":
+		return 2160
+	case "2k":
+		return 1440
+	}
+	if p, err := strconv.Atoi(strings.TrimSuffix(value, "p")); err == nil && p > 0 {
+		return p
+	}
+	return 0
+}
+
+var (
+	blackDetectPattern  = regexp.MustCompile(`black_start:\s*([\d.]+)\s+black_end:\s*([\d.]+)`)
+	freezeDetectPattern = regexp.MustCompile(`lavfi\.freezedetect\.freeze_(start|end):\s*([\d.]+)`)
+)
+
+// ParseBlackDetect 解析 blackdetect 滤镜日志中的黑场片段
+func ParseBlackDetect(output string) []Segment {
+	var segments []Segment
+	for _, match := range blackDetectPattern.FindAllStringSubmatch(output, -1) {
+		start, _ := strconv.ParseFloat(match[1], 64)
+		end, _ := strconv.ParseFloat(match[2], 64)
+		segments = append(segments, Segment{Start: start, End: end})
+	}
+	return segments
+}
+
+// ParseFreezeDetect 解析 freezedetect 滤镜日志中的冻帧片段，持续到结尾的冻帧没有结束时间，以 duration 结束
+func ParseFreezeDetect(output string, duration float64) []Segment {
+	var segments []Segment
+	open := -1.0
+	for _, match := range freezeDetectPattern.FindAllStringSubmatch(output, -1) {
+		value, _ := strconv.ParseFloat(match[2], 64)
+		switch {
+		case match[1] == "start":
+			open = value
+		case open >= 0:
+			segments = append(segments, Segment{Start: open, End: value})
+			open = -1
+		}
+	}
+	if open >= 0 && duration > open {
+		segments = append(segments, Segment{Start: open, End: duration})
+	}
+	return segments
+}
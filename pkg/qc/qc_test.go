@@ -0,0 +1,91 @@
+package qc
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/drama-generator/backend/pkg/thumbnail"
+)
+
+func TestParseDetectors(t *testing.T) {
+	output := `[blackdetect @ 0x5581] black_start:0 black_end:1.5 black_duration:1.5
+[freezedetect @ 0x5582] lavfi.freezedetect.freeze_start: 2.002
+[freezedetect @ 0x5582] lavfi.freezedetect.freeze_duration: 2.5
+[freezedetect @ 0x5582] lavfi.freezedetect.freeze_end: 4.502
+[freezedetect @ 0x5582] lavfi.freezedetect.freeze_start: 7
+[blackdetect @ 0x5581] black_start:9.2 black_end:10 black_duration:0.8`
+
+	black := ParseBlackDetect(output)
+	if want := []Segment{{0, 1.5}, {9.2, 10}}; !reflect.DeepEqual(black, want) {
+		t.Errorf("ParseBlackDetect() = %v, want %v", black, want)
+	}
+	frozen := ParseFreezeDetect(output, 10)
+	if want := []Segment{{2.002, 4.502}, {7, 10}}; !reflect.DeepEqual(frozen, want) {
+		t.Errorf("ParseFreezeDetect() = %v, want %v", frozen, want)
+	}
+}
+
+func TestCheckVideo(t *testing.T) {
+	good := VideoProbe{Duration: 5, Width: 1280, Height: 720, HasAudio: true}
+	spec := VideoSpec{Duration: 5, AspectRatio: 16.0 / 9, MinShortSide: 720, RequireAudio: true}
+
+	tests := []struct {
+		name   string
+		probe  VideoProbe
+		status string
+		flags  []string
+	}{
+		{"clean", good, StatusPassed, []string{}},
+		{"mostly black", VideoProbe{Duration: 5, Width: 1280, Height: 720, HasAudio: true, Black: []Segment{{0, 3}}}, StatusFailed, []string{FlagBlack}},
+		{"short freeze", VideoProbe{Duration: 5, Width: 1280, Height: 720, HasAudio: true, Frozen: []Segment{{3, 4.2}}}, StatusWarning, []string{FlagFrozen}},
+		{"truncated", VideoProbe{Duration: 3, Width: 1280, Height: 720, HasAudio: true}, StatusFailed, []string{FlagDurationShort}},
+		{"vertical and silent", VideoProbe{Duration: 5, Width: 720, Height: 1280}, StatusFailed, []string{FlagAspectRatio, FlagNoAudio}},
+		{"low resolution", VideoProbe{Duration: 5.4, Width: 854, Height: 480, HasAudio: true}, StatusWarning, []string{FlagResolution}},
+	}
+	for _, tt := range tests {
+		report := CheckVideo(tt.probe, spec)
+		if report.Status != tt.status || !reflect.DeepEqual(report.Flags(), tt.flags) {
+			t.Errorf("%s: CheckVideo() = %s %v, want %s %v", tt.name, report.Status, report.Flags(), tt.status, tt.flags)
+		}
+	}
+}
+
+func TestCheckImage(t *testing.T) {
+	sharp := thumbnail.FrameStats{MeanLuma: 110, Contrast: 40, Sharpness: 200}
+
+	tests := []struct {
+		name   string
+		probe  ImageProbe
+		spec   ImageSpec
+		status string
+	}{
+		{"matches size", ImageProbe{1920, 1080, sharp}, ImageSpec{Width: 1920, Height: 1080}, StatusPassed},
+		{"blank", ImageProbe{1920, 1080, thumbnail.FrameStats{MeanLuma: 250, Contrast: 2}}, ImageSpec{}, StatusFailed},
+		{"wrong ratio", ImageProbe{1024, 1024, sharp}, ImageSpec{Width: 1920, Height: 1080}, StatusFailed},
+		{"smaller", ImageProbe{1280, 720, sharp}, ImageSpec{Width: 1920, Height: 1080}, StatusWarning},
+		{"hinted ratio", ImageProbe{1024, 1024, sharp}, ImageSpec{AspectRatio: 16.0 / 9}, StatusWarning},
+		{"tiny", ImageProbe{128, 72, sharp}, ImageSpec{}, StatusFailed},
+	}
+	for _, tt := range tests {
+		if report := CheckImage(tt.probe, tt.spec); report.Status != tt.status {
+			t.Errorf("%s: CheckImage() = %s %v, want %s", tt.name, report.Status, report.Issues, tt.status)
+		}
+	}
+}
+
+func TestParseSpecs(t *testing.T) {
+	if got := ParseAspectRatio("9:16"); got != 9.0/16 {
+		t.Errorf("ParseAspectRatio(9:16) = %v", got)
+	}
+	if got := ParseAspectRatio("1920x1080"); got != 1920.0/1080 {
+		t.Errorf("ParseAspectRatio(1920x1080) = %v", got)
+	}
+	if got := ParseAspectRatio("wide"); got != 0 {
+		t.Errorf("ParseAspectRatio(wide) = %v, want 0", got)
+	}
+	for value, want := range map[string]int{"720p": 720, "1920x1080": 1080, "4K": 2160, "hd": 0} {
+		if got := ParseResolution(value); got != want {
+			t.Errorf("ParseResolution(%q) = %d, want %d", value, got, want)
+		}
+	}
+}
@@ -0,0 +1,302 @@
+package reframe
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 显著度与关键帧平滑参数，画面按分析用的缩略图计算
+const (
+	skinWeight    = 2.0  // 肤色像素（人脸、人物）相对于平均细节强度的额外权重
+	centerBias    = 0.15 // 越靠近画面中心权重越高，显著度接近时优先居中
+	maxPanSpeed   = 0.25 // 裁剪窗口每秒最多移动可移动范围的比例，避免镜头乱晃
+	deadZone      = 0.04 // 位置变化小于该值时保持不动
+	simplifyError = 0.01 // 线性插值误差小于该值的关键帧会被合并
+)
+
+// MaxKeyframes 单个画面转换允许的关键帧数量上限，关键帧过多时裁剪表达式过长
+const MaxKeyframes = 500
+
+// Keyframe 裁剪窗口在某一时刻的位置：X、Y 为窗口在可移动范围内的比例，0 为最左（上），0.5 居中，1 为最右（下）
+// 关键帧之间线性插值，第一个关键帧之前和最后一个之后保持不动
+type Keyframe struct {
+	Time float64 `json:"time"` // 秒
+	X    float64 `json:"x"`
+	Y    float64 `json:"y"`
+}
+
+// Center 居中的关键帧
+func Center(t float64) Keyframe {
+	return Keyframe{Time: t, X: 0.5, Y: 0.5}
+}
+
+// Normalize 按时间排序并校验关键帧，同一时刻只保留最后一个
+func Normalize(keyframes []Keyframe) ([]Keyframe, error) {
+	if len(keyframes) > MaxKeyframes {
+		return nil, fmt.Errorf("too many keyframes (max %d)", MaxKeyframes)
+	}
+	sorted := make([]Keyframe, 0, len(keyframes))
+	for i, kf := range keyframes {
+		if math.IsNaN(kf.Time) || math.IsInf(kf.Time, 0) || kf.Time < 0 {
+			return nil, fmt.Errorf("keyframe %d: invalid time %v", i+1, kf.Time)
+		}
+		if !(kf.X >= 0 && kf.X <= 1) || !(kf.Y >= 0 && kf.Y <= 1) {
+			return nil, fmt.Errorf("keyframe %d: position must be between 0 and 1", i+1)
+		}
+		sorted = append(sorted, kf)
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Time < sorted[j].Time })
+
+	result := sorted[:0]
+	for _, kf := range sorted {
+		if n := len(result); n > 0 && math.Abs(result[n-1].Time-kf.Time) < 1e-3 {
+			result[n-1] = kf
+			continue
+		}
+		result = append(result, kf)
+	}
+	return result, nil
+}
+
+// At 某一时刻的裁剪位置
+func At(keyframes []Keyframe, t float64) (float64, float64) {
+	if len(keyframes) == 0 {
+		return 0.5, 0.5
+	}
+	if t <= keyframes[0].Time {
+		return keyframes[0].X, keyframes[0].Y
+	}
+	for i := 1; i < len(keyframes); i++ {
+		prev, next := keyframes[i-1], keyframes[i]
+		if t < next.Time {
+			ratio := (t - prev.Time) / (next.Time - prev.Time)
+			return prev.X + (next.X-prev.X)*ratio, prev.Y + (next.Y-prev.Y)*ratio
+		}
+	}
+	last := keyframes[len(keyframes)-1]
+	return last.X, last.Y
+}
+
+// Saliency 画面各像素的显著度：亮度梯度（轮廓和细节）按平均值归一化，肤色区域额外加权，
+// 再乘以中心权重；返回按行排列的显著度和宽高
+func Saliency(img image.Image) ([]float64, int, int) {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w < 3 || h < 3 {
+		return nil, w, h
+	}
+
+	luma := make([]float64, w*h)
+	skin := make([]bool, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			r, g, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			yy, cb, cr := color.RGBToYCbCr(uint8(r>>8), uint8(g>>8), uint8(b>>8))
+			luma[y*w+x] = float64(yy)
+			skin[y*w+x] = isSkin(yy, cb, cr)
+		}
+	}
+
+	gradient := make([]float64, w*h)
+	var total float64
+	for y := 1; y < h-1; y++ {
+		for x := 1; x < w-1; x++ {
+			i := y*w + x
+			gx := luma[i+1] - luma[i-1]
+			gy := luma[i+w] - luma[i-w]
+			gradient[i] = math.Sqrt(gx*gx + gy*gy)
+			total += gradient[i]
+		}
+	}
+	mean := total / float64((w-2)*(h-2))
+	if mean <= 0 {
+		mean = 1
+	}
+
+	weights := make([]float64, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			i := y*w + x
+			value := gradient[i] / mean
+			if skin[i] {
+				value += skinWeight
+			}
+			dx := (float64(x)+0.5)/float64(w) - 0.5
+			dy := (float64(y)+0.5)/float64(h) - 0.5
+			weights[i] = value * (1 - centerBias*math.Sqrt(dx*dx+dy*dy)*2)
+		}
+	}
+	return weights, w, h
+}
+
+// isSkin YCbCr 空间的肤色范围，过暗的像素不计入
+func isSkin(y, cb, cr uint8) bool {
+	return y > 40 && cb >= 77 && cb <= 127 && cr >= 133 && cr <= 173
+}
+
+// Focus 在画面中放置目标宽高比的裁剪窗口，使窗口内显著度之和最大；返回时间为 t 的关键帧
+// 目标比源画面更窄时水平移动窗口，更宽时垂直移动，另一方向保持居中
+func Focus(img image.Image, aspect float64, t float64) Keyframe {
+	kf := Center(t)
+	weights, w, h := Saliency(img)
+	if weights == nil || aspect <= 0 {
+		return kf
+	}
+
+	source := float64(w) / float64(h)
+	switch {
+	case aspect < source*0.99:
+		profile := make([]float64, w)
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				profile[x] += weights[y*w+x]
+			}
+		}
+		kf.X = bestWindow(profile, int(math.Round(float64(w)*aspect/source)))
+	case aspect > source*1.01:
+		profile := make([]float64, h)
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				profile[y] += weights[y*w+x]
+			}
+		}
+		kf.Y = bestWindow(profile, int(math.Round(float64(h)*source/aspect)))
+	}
+	return kf
+}
+
+// bestWindow 显著度之和最大的窗口起点在可移动范围内的比例，相同时取更靠近中间的位置
+func bestWindow(profile []float64, window int) float64 {
+	n := len(profile)
+	if window <= 0 || window >= n {
+		return 0.5
+	}
+	var sum float64
+	for i := 0; i < window; i++ {
+		sum += profile[i]
+	}
+	slack := n - window
+	best, bestSum := 0, sum
+	for start := 1; start <= slack; start++ {
+		sum += profile[start+window-1] - profile[start-1]
+		if sum > bestSum+1e-9 || (math.Abs(sum-bestSum) <= 1e-9 && math.Abs(float64(start)-float64(slack)/2) < math.Abs(float64(best)-float64(slack)/2)) {
+			best, bestSum = start, sum
+		}
+	}
+	return float64(best) / float64(slack)
+}
+
+// Smooth 平滑逐帧分析得到的关键帧：中值滤波去除单帧误判，小幅变化保持不动，
+// 限制移动速度避免镜头乱晃，最后合并可以线性插值得到的关键帧
+func Smooth(keyframes []Keyframe) []Keyframe {
+	n := len(keyframes)
+	if n < 2 {
+		return keyframes
+	}
+
+	smoothed := make([]Keyframe, n)
+	for i := range keyframes {
+		smoothed[i] = keyframes[i]
+		if i > 0 && i < n-1 {
+			smoothed[i].X = median3(keyframes[i-1].X, keyframes[i].X, keyframes[i+1].X)
+			smoothed[i].Y = median3(keyframes[i-1].Y, keyframes[i].Y, keyframes[i+1].Y)
+		}
+	}
+	for i := 1; i < n; i++ {
+		limit := maxPanSpeed * (smoothed[i].Time - smoothed[i-1].Time)
+		smoothed[i].X = follow(smoothed[i-1].X, smoothed[i].X, limit)
+		smoothed[i].Y = follow(smoothed[i-1].Y, smoothed[i].Y, limit)
+	}
+	return Simplify(smoothed, simplifyError)
+}
+
+// follow 从上一个位置向目标移动，变化小于死区时不动，超过速度限制时只移动到限制处
+func follow(prev, target, limit float64) float64 {
+	delta := target - prev
+	if math.Abs(delta) < deadZone {
+		return prev
+	}
+	if math.Abs(delta) > limit {
+		delta = math.Copysign(limit, delta)
+	}
+	return prev + delta
+}
+
+func median3(a, b, c float64) float64 {
+	return math.Max(math.Min(a, b), math.Min(math.Max(a, b), c))
+}
+
+// Simplify 去掉与前后关键帧线性插值结果相差不超过 tolerance 的关键帧，保留首尾
+func Simplify(keyframes []Keyframe, tolerance float64) []Keyframe {
+	if len(keyframes) < 3 {
+		return keyframes
+	}
+	result := []Keyframe{keyframes[0]}
+	for i := 1; i < len(keyframes)-1; i++ {
+		prev, next := result[len(result)-1], keyframes[i+1]
+		x, y := At([]Keyframe{prev, next}, keyframes[i].Time)
+		if math.Abs(x-keyframes[i].X) > tolerance || math.Abs(y-keyframes[i].Y) > tolerance {
+			result = append(result, keyframes[i])
+		}
+	}
+	return append(result, keyframes[len(keyframes)-1])
+}
+
+// ParseAspect 解析 "9:16" 或 "1080x1920" 形式的宽高比，返回约分后的 "9:16" 形式和比值
+func ParseAspect(value string) (string, float64, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	for _, sep := range []string{":", "x"} {
+		parts := strings.SplitN(value, sep, 2)
+		if len(parts) != 2 {
+			continue
+		}
+		w, errW := strconv.Atoi(strings.TrimSpace(parts[0]))
+		h, errH := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if errW != nil || errH != nil || w <= 0 || h <= 0 {
+			break
+		}
+		ratio := float64(w) / float64(h)
+		if ratio < 0.25 || ratio > 4 {
+			return "", 0, fmt.Errorf("aspect ratio %q is out of range", value)
+		}
+		return FormatAspect(w, h), ratio, nil
+	}
+	return "", 0, fmt.Errorf("invalid aspect ratio %q", value)
+}
+
+// FormatAspect 约分后的宽高比，如 1080x1920 为 "9:16"
+func FormatAspect(width, height int) string {
+	a, b := width, height
+	for b != 0 {
+		a, b = b, a%b
+	}
+	if a == 0 {
+		return fmt.Sprintf("%d:%d", width, height)
+	}
+	return fmt.Sprintf("%d:%d", width/a, height/a)
+}
+
+// OutputSize 转换到目标宽高比后的输出尺寸：短边与源画面短边相同，宽高取偶数
+func OutputSize(width, height int, aspect float64) (int, int) {
+	short := width
+	if height < short {
+		short = height
+	}
+	if aspect >= 1 {
+		return even(float64(short) * aspect), even(float64(short))
+	}
+	return even(float64(short)), even(float64(short) / aspect)
+}
+
+func even(value float64) int {
+	n := int(math.Round(value/2)) * 2
+	if n < 2 {
+		return 2
+	}
+	return n
+}
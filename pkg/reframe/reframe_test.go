@@ -0,0 +1,104 @@
+package reframe
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestFocusFollowsSubject(t *testing.T) {
+	// 16:9 灰色画面，右侧三分之一处有一块肤色区域
+	img := image.NewRGBA(image.Rect(0, 0, 160, 90))
+	for y := 0; y < 90; y++ {
+		for x := 0; x < 160; x++ {
+			img.Set(x, y, color.RGBA{90, 90, 90, 255})
+		}
+	}
+	for y := 20; y < 60; y++ {
+		for x := 110; x < 135; x++ {
+			img.Set(x, y, color.RGBA{224, 172, 140, 255})
+		}
+	}
+
+	kf := Focus(img, 9.0/16, 2)
+	if kf.Time != 2 || kf.Y != 0.5 {
+		t.Fatalf("Focus() = %+v, want time 2 and centered Y", kf)
+	}
+	// 窗口宽约 51 像素，需要包含 110-135 的主体
+	window := 51.0
+	start := kf.X * (160 - window)
+	if start > 110 || start+window < 135 {
+		t.Errorf("Focus() window starts at %.1f, subject not inside", start)
+	}
+
+	if kf := Focus(img, 16.0/9, 0); kf.X != 0.5 || kf.Y != 0.5 {
+		t.Errorf("Focus() with same aspect = %+v, want centered", kf)
+	}
+}
+
+func TestSmooth(t *testing.T) {
+	raw := []Keyframe{{0, 0.5, 0.5}, {1, 0.52, 0.5}, {2, 1, 0.5}, {3, 0.5, 0.5}, {4, 0.9, 0.5}, {5, 0.9, 0.5}, {6, 0.9, 0.5}}
+	smoothed := Smooth(raw)
+	if smoothed[0] != raw[0] {
+		t.Errorf("first keyframe changed: %+v", smoothed[0])
+	}
+	for i := 1; i < len(smoothed); i++ {
+		speed := math.Abs(smoothed[i].X-smoothed[i-1].X) / (smoothed[i].Time - smoothed[i-1].Time)
+		if speed > maxPanSpeed+1e-9 {
+			t.Errorf("pan speed %.3f between keyframes %d and %d exceeds limit", speed, i-1, i)
+		}
+	}
+	// 单帧跳到 1 的误判被中值滤波去掉，1-3 秒保持不动
+	if x, _ := At(smoothed, 2); x != 0.5 {
+		t.Errorf("At(2) = %.3f, want 0.5", x)
+	}
+	if x, _ := At(smoothed, 6); x <= 0.5 {
+		t.Errorf("At(6) = %.3f, want window moving towards 0.9", x)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	got, err := Normalize([]Keyframe{{2, 0.1, 0.5}, {0, 0.5, 0.5}, {2, 0.2, 0.5}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Time != 0 || got[1].X != 0.2 {
+		t.Errorf("Normalize() = %+v", got)
+	}
+	if _, err := Normalize([]Keyframe{{0, 1.5, 0.5}}); err == nil {
+		t.Error("Normalize() accepted position outside 0-1")
+	}
+	if _, err := Normalize([]Keyframe{{-1, 0.5, 0.5}}); err == nil {
+		t.Error("Normalize() accepted negative time")
+	}
+}
+
+func TestAspect(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"9:16", "9:16"},
+		{"1080x1920", "9:16"},
+		{" 16:9 ", "16:9"},
+		{"4:3", "4:3"},
+	}
+	for _, tt := range tests {
+		if got, _, err := ParseAspect(tt.value); err != nil || got != tt.want {
+			t.Errorf("ParseAspect(%q) = %q, %v, want %q", tt.value, got, err, tt.want)
+		}
+	}
+	for _, value := range []string{"", "abc", "1:10", "0:1"} {
+		if _, _, err := ParseAspect(value); err == nil {
+			t.Errorf("ParseAspect(%q) succeeded, want error", value)
+		}
+	}
+
+	if w, h := OutputSize(1920, 1080, 9.0/16); w != 1080 || h != 1920 {
+		t.Errorf("OutputSize(16:9 -> 9:16) = %dx%d", w, h)
+	}
+	if w, h := OutputSize(720, 1280, 16.0/9); w != 1280 || h != 720 {
+		t.Errorf("OutputSize(9:16 -> 16:9) = %dx%d", w, h)
+	}
+}
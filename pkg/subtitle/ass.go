@@ -0,0 +1,105 @@
+package subtitle
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+)
+
+var assEscaper = strings.NewReplacer(`\`, `\\`, "{", `\{`, "}", `\}`)
+
+// writeASS 输出 ASS 字幕，样式按视频分辨率换算，烧录字幕时使用
+func writeASS(doc *Document) []byte {
+	style := doc.Style
+	scale := float64(min(doc.Width, doc.Height)) / 1080
+
+	title := doc.Title
+	if title == "" {
+		title = "Subtitles"
+	}
+
+	bold := 0
+	if style.Bold {
+		bold = -1
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("[Script Info]\n")
+	fmt.Fprintf(&buf, "Title: %s\n", strings.ReplaceAll(title, "\n", " "))
+	buf.WriteString("ScriptType: v4.00+\n")
+	fmt.Fprintf(&buf, "PlayResX: %d\n", doc.Width)
+	fmt.Fprintf(&buf, "PlayResY: %d\n", doc.Height)
+	buf.WriteString("WrapStyle: 0\n")
+	buf.WriteString("ScaledBorderAndShadow: yes\n\n")
+
+	buf.WriteString("[V4+ Styles]\n")
+	buf.WriteString("Format: Name, Fontname, Fontsize, PrimaryColour, SecondaryColour, OutlineColour, BackColour, Bold, Italic, Underline, StrikeOut, ScaleX, ScaleY, Spacing, Angle, BorderStyle, Outline, Shadow, Alignment, MarginL, MarginR, MarginV, Encoding\n")
+	fmt.Fprintf(&buf, "Style: Default,%s,%d,%s,%s,%s,%s,%d,0,0,0,100,100,0,0,1,%s,%s,%d,%d,%d,%d,1\n",
+		style.FontName,
+		scaleInt(style.FontSize, scale),
+		assColor(style.PrimaryColor, 0),
+		assColor(style.PrimaryColor, 0),
+		assColor(style.OutlineColor, 0),
+		assColor(style.OutlineColor, 0x80),
+		bold,
+		formatASSNumber(style.Outline*scale),
+		formatASSNumber(style.Shadow*scale),
+		assAlignment(style.Position),
+		scaleInt(40, scale),
+		scaleInt(40, scale),
+		scaleInt(style.MarginV, scale),
+	)
+	buf.WriteString("\n")
+
+	buf.WriteString("[Events]\n")
+	buf.WriteString("Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text\n")
+	for _, cue := range doc.Cues {
+		lines := wrapText(cue.Text, style.MaxLineLength)
+		for i := range lines {
+			lines[i] = assEscaper.Replace(lines[i])
+		}
+		fmt.Fprintf(&buf, "Dialogue: 0,%s,%s,Default,%s,0,0,0,,%s\n",
+			formatASSTimestamp(cue.Start),
+			formatASSTimestamp(cue.End),
+			strings.ReplaceAll(cue.Speaker, ",", "，"),
+			strings.Join(lines, `\N`),
+		)
+	}
+	return buf.Bytes()
+}
+
+// assColor 将 #RRGGBB 转换为 ASS 的 &HAABBGGRR，alpha 0 为不透明
+func assColor(color string, alpha int) string {
+	hex := strings.TrimPrefix(color, "#")
+	return fmt.Sprintf("&H%02X%s%s%s", alpha, hex[4:6], hex[2:4], hex[0:2])
+}
+
+// assAlignment 小键盘布局的对齐方式：2 底部居中，5 居中，8 顶部居中
+func assAlignment(position string) int {
+	switch position {
+	case PositionTop:
+		return 8
+	case PositionMiddle:
+		return 5
+	default:
+		return 2
+	}
+}
+
+// formatASSTimestamp 格式化为 H:MM:SS.cc
+func formatASSTimestamp(ms int) string {
+	if ms < 0 {
+		ms = 0
+	}
+	cs := ms / 10
+	return fmt.Sprintf("%d:%02d:%02d.%02d", cs/360000, cs/6000%60, cs/100%60, cs%100)
+}
+
+func scaleInt(v int, scale float64) int {
+	return int(math.Round(float64(v) * scale))
+}
+
+func formatASSNumber(v float64) string {
+	return strings.TrimRight(strings.TrimRight(fmt.Sprintf("%.2f", v), "0"), ".")
+}
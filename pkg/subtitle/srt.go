@@ -0,0 +1,19 @@
+package subtitle
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// writeSRT 输出 SubRip 字幕
+func writeSRT(doc *Document) []byte {
+	var buf bytes.Buffer
+	for i, cue := range doc.Cues {
+		fmt.Fprintf(&buf, "%d\n", i+1)
+		fmt.Fprintf(&buf, "%s --> %s\n", formatTimestamp(cue.Start, ","), formatTimestamp(cue.End, ","))
+		buf.WriteString(strings.Join(wrapText(cue.Text, doc.Style.MaxLineLength), "\n"))
+		buf.WriteString("\n\n")
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,249 @@
+package subtitle
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Format 字幕格式
+type Format string
+
+const (
+	FormatSRT Format = "srt"
+	FormatVTT Format = "vtt"
+	FormatASS Format = "ass"
+)
+
+// Formats 支持的全部字幕格式
+var Formats = []Format{FormatSRT, FormatVTT, FormatASS}
+
+// ParseFormat 解析字幕格式
+func ParseFormat(format string) (Format, error) {
+	switch Format(strings.ToLower(strings.TrimSpace(format))) {
+	case FormatSRT:
+		return FormatSRT, nil
+	case FormatVTT, "webvtt":
+		return FormatVTT, nil
+	case FormatASS, "ssa":
+		return FormatASS, nil
+	default:
+		return "", fmt.Errorf("unsupported subtitle format: %s", format)
+	}
+}
+
+// Extension 文件扩展名
+func (f Format) Extension() string {
+	return string(f)
+}
+
+// ContentType 下载时使用的 MIME 类型
+func (f Format) ContentType() string {
+	switch f {
+	case FormatVTT:
+		return "text/vtt; charset=utf-8"
+	case FormatASS:
+		return "text/x-ssa; charset=utf-8"
+	default:
+		return "application/x-subrip; charset=utf-8"
+	}
+}
+
+// Cue 一条字幕，时间单位为毫秒
+type Cue struct {
+	Start   int
+	End     int
+	Speaker string
+	Text    string
+}
+
+// Document 一集的字幕，Width/Height 为视频分辨率，ASS 按此换算字号和边距
+type Document struct {
+	Title  string
+	Width  int
+	Height int
+	Style  Style
+	Cues   []Cue
+}
+
+// 字幕位置
+const (
+	PositionBottom = "bottom"
+	PositionMiddle = "middle"
+	PositionTop    = "top"
+)
+
+// Style 字幕样式，字号、描边和边距以 1080 像素的画面短边为基准，颜色为 #RRGGBB
+type Style struct {
+	FontName      string  `json:"font_name"`
+	FontSize      int     `json:"font_size"`
+	PrimaryColor  string  `json:"primary_color"`
+	OutlineColor  string  `json:"outline_color"`
+	Outline       float64 `json:"outline"`
+	Shadow        float64 `json:"shadow"`
+	Bold          bool    `json:"bold"`
+	Position      string  `json:"position"`
+	MarginV       int     `json:"margin_v"`
+	MaxLineLength int     `json:"max_line_length"` // 每行最多字数，超出时自动换行，0 表示不换行
+}
+
+// DefaultStyle 默认样式：底部居中的白字黑边
+func DefaultStyle() Style {
+	return Style{
+		FontName:      "Noto Sans CJK SC",
+		FontSize:      56,
+		PrimaryColor:  "#FFFFFF",
+		OutlineColor:  "#000000",
+		Outline:       3,
+		Position:      PositionBottom,
+		MarginV:       60,
+		MaxLineLength: 18,
+	}
+}
+
+var colorPattern = regexp.MustCompile(`^#?[0-9A-Fa-f]{6}$`)
+
+// Normalize 未设置的字段使用默认值，并校验取值范围
+func (s Style) Normalize() (Style, error) {
+	defaults := DefaultStyle()
+	if strings.TrimSpace(s.FontName) == "" {
+		s.FontName = defaults.FontName
+	}
+	if s.FontSize == 0 {
+		s.FontSize = defaults.FontSize
+	}
+	if s.PrimaryColor == "" {
+		s.PrimaryColor = defaults.PrimaryColor
+	}
+	if s.OutlineColor == "" {
+		s.OutlineColor = defaults.OutlineColor
+	}
+	if s.Position == "" {
+		s.Position = defaults.Position
+	}
+
+	s.FontName = strings.TrimSpace(s.FontName)
+	if strings.ContainsAny(s.FontName, ",\n") {
+		return s, fmt.Errorf("font_name must not contain commas or line breaks")
+	}
+	if s.FontSize < 12 || s.FontSize > 200 {
+		return s, fmt.Errorf("font_size must be between 12 and 200")
+	}
+	for _, color := range []*string{&s.PrimaryColor, &s.OutlineColor} {
+		if !colorPattern.MatchString(*color) {
+			return s, fmt.Errorf("invalid color %q, expected #RRGGBB", *color)
+		}
+		*color = "#" + strings.ToUpper(strings.TrimPrefix(*color, "#"))
+	}
+	if s.Outline < 0 || s.Outline > 20 {
+		return s, fmt.Errorf("outline must be between 0 and 20")
+	}
+	if s.Shadow < 0 || s.Shadow > 20 {
+		return s, fmt.Errorf("shadow must be between 0 and 20")
+	}
+	switch s.Position {
+	case PositionBottom, PositionMiddle, PositionTop:
+	default:
+		return s, fmt.Errorf("position must be one of bottom, middle, top")
+	}
+	if s.MarginV < 0 || s.MarginV > 500 {
+		return s, fmt.Errorf("margin_v must be between 0 and 500")
+	}
+	if s.MaxLineLength < 0 {
+		return s, fmt.Errorf("max_line_length must not be negative")
+	}
+	return s, nil
+}
+
+// Write 按指定格式输出字幕文件内容
+func Write(doc *Document, format Format) ([]byte, error) {
+	style, err := doc.Style.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	normalized := *doc
+	normalized.Style = style
+	normalized.Cues = normalizeCues(doc.Cues)
+	if normalized.Width <= 0 || normalized.Height <= 0 {
+		normalized.Width, normalized.Height = 1920, 1080
+	}
+
+	switch format {
+	case FormatSRT:
+		return writeSRT(&normalized), nil
+	case FormatVTT:
+		return writeVTT(&normalized), nil
+	case FormatASS:
+		return writeASS(&normalized), nil
+	default:
+		return nil, fmt.Errorf("unsupported subtitle format: %s", format)
+	}
+}
+
+// normalizeCues 去掉空字幕和无效时间，按开始时间排序
+func normalizeCues(cues []Cue) []Cue {
+	result := make([]Cue, 0, len(cues))
+	for _, cue := range cues {
+		cue.Text = strings.TrimSpace(cue.Text)
+		if cue.Text == "" || cue.End <= cue.Start {
+			continue
+		}
+		if cue.Start < 0 {
+			cue.Start = 0
+		}
+		result = append(result, cue)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Start < result[j].Start
+	})
+	return result
+}
+
+// wrapText 按每行最多字数换行，各行长度尽量均衡，优先在标点或空格后断行
+func wrapText(text string, maxLength int) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		runes := []rune(strings.TrimSpace(paragraph))
+		for maxLength > 0 && len(runes) > maxLength {
+			count := (len(runes) + maxLength - 1) / maxLength
+			target := (len(runes) + count - 1) / count
+			cut := target
+			best := -1
+			for i := 1; i <= maxLength; i++ {
+				if isBreakRune(runes[i-1]) && (best < 0 || absInt(i-target) < absInt(best-target)) {
+					best = i
+				}
+			}
+			if best >= target/2 {
+				cut = best
+			}
+			lines = append(lines, strings.TrimSpace(string(runes[:cut])))
+			runes = []rune(strings.TrimSpace(string(runes[cut:])))
+		}
+		if len(runes) > 0 {
+			lines = append(lines, string(runes))
+		}
+	}
+	return lines
+}
+
+func isBreakRune(r rune) bool {
+	return unicode.IsPunct(r) || unicode.IsSpace(r)
+}
+
+func absInt(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// formatTimestamp 格式化为 HH:MM:SS<sep>mmm
+func formatTimestamp(ms int, sep string) string {
+	if ms < 0 {
+		ms = 0
+	}
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", ms/3600000, ms/60000%60, ms/1000%60, sep, ms%1000)
+}
@@ -0,0 +1,119 @@
+package subtitle
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testDocument() *Document {
+	return &Document{
+		Title: "第1集",
+		Style: DefaultStyle(),
+		Cues: []Cue{
+			{Start: 3500, End: 5000, Speaker: "李芳", Text: "现在怎么办？"},
+			{Start: 0, End: 3333, Speaker: "陈峥", Text: "我们被耍了，这里根本没有我们要找的东西。"},
+			{Start: 6000, End: 6000, Text: "无效"},
+		},
+	}
+}
+
+func TestWriteSRT(t *testing.T) {
+	data, err := Write(testDocument(), FormatSRT)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "1\n00:00:00,000 --> 00:00:03,333\n我们被耍了，\n这里根本没有我们要找的东西。\n\n" +
+		"2\n00:00:03,500 --> 00:00:05,000\n现在怎么办？\n\n"
+	if string(data) != want {
+		t.Errorf("Write(srt) =\n%s\nwant\n%s", data, want)
+	}
+}
+
+func TestWriteVTT(t *testing.T) {
+	doc := testDocument()
+	doc.Style.Position = PositionTop
+	doc.Cues = append(doc.Cues, Cue{Start: 7000, End: 8000, Text: "A < B & C"})
+
+	data, err := Write(doc, FormatVTT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := string(data)
+
+	for _, want := range []string{
+		"WEBVTT\n\n",
+		"00:00:00.000 --> 00:00:03.333 line:5% align:center\n<v 陈峥>我们被耍了，\n这里根本没有我们要找的东西。\n",
+		"A &lt; B &amp; C\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Write(vtt) missing %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestWriteASS(t *testing.T) {
+	doc := testDocument()
+	doc.Width, doc.Height = 1080, 1920
+	doc.Style.PrimaryColor = "#FFCC00"
+	doc.Style.Position = PositionMiddle
+	doc.Cues = append(doc.Cues, Cue{Start: 3723450, End: 3725000, Text: "{重要}"})
+
+	data, err := Write(doc, FormatASS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := string(data)
+
+	for _, want := range []string{
+		"PlayResX: 1080\nPlayResY: 1920\n",
+		"Style: Default,Noto Sans CJK SC,56,&H0000CCFF,&H0000CCFF,&H00000000,&H80000000,0,0,0,0,100,100,0,0,1,3,0,5,40,40,60,1\n",
+		"Dialogue: 0,0:00:00.00,0:00:03.33,Default,陈峥,0,0,0,,我们被耍了，\\N这里根本没有我们要找的东西。\n",
+		"Dialogue: 0,1:02:03.45,1:02:05.00,Default,,0,0,0,,\\{重要\\}\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Write(ass) missing %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestStyleNormalize(t *testing.T) {
+	style, err := Style{PrimaryColor: "ffcc00"}.Normalize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if style.PrimaryColor != "#FFCC00" || style.FontSize != DefaultStyle().FontSize || style.Position != PositionBottom {
+		t.Errorf("Normalize() = %+v", style)
+	}
+
+	for _, invalid := range []Style{
+		{FontSize: 500},
+		{PrimaryColor: "red"},
+		{Position: "left"},
+		{Outline: -1},
+	} {
+		if _, err := invalid.Normalize(); err == nil {
+			t.Errorf("Normalize(%+v) expected error", invalid)
+		}
+	}
+}
+
+func TestWrapText(t *testing.T) {
+	got := wrapText("这是一句没有标点但是非常非常长的台词内容", 8)
+	want := []string{"这是一句没有标", "点但是非常非常", "长的台词内容"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrapText() = %q, want %q", got, want)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for input, want := range map[string]Format{"SRT": FormatSRT, "webvtt": FormatVTT, "ssa": FormatASS} {
+		if got, err := ParseFormat(input); err != nil || got != want {
+			t.Errorf("ParseFormat(%q) = %v, %v", input, got, err)
+		}
+	}
+	if _, err := ParseFormat("txt"); err == nil {
+		t.Error("ParseFormat(txt) expected error")
+	}
+}
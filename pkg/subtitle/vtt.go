@@ -0,0 +1,40 @@
+package subtitle
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+var vttEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// writeVTT 输出 WebVTT 字幕，说话人写入 <v> 标签，位置通过 line 设置
+func writeVTT(doc *Document) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("WEBVTT\n\n")
+
+	settings := ""
+	switch doc.Style.Position {
+	case PositionTop:
+		settings = " line:5% align:center"
+	case PositionMiddle:
+		settings = " line:50% align:center"
+	}
+
+	for i, cue := range doc.Cues {
+		fmt.Fprintf(&buf, "%d\n", i+1)
+		fmt.Fprintf(&buf, "%s --> %s%s\n", formatTimestamp(cue.Start, "."), formatTimestamp(cue.End, "."), settings)
+
+		lines := wrapText(cue.Text, doc.Style.MaxLineLength)
+		for j := range lines {
+			lines[j] = vttEscaper.Replace(lines[j])
+		}
+		text := strings.Join(lines, "\n")
+		if speaker := strings.TrimSpace(cue.Speaker); speaker != "" {
+			text = fmt.Sprintf("<v %s>%s", vttEscaper.Replace(speaker), text)
+		}
+		buf.WriteString(text)
+		buf.WriteString("\n\n")
+	}
+	return buf.Bytes()
+}
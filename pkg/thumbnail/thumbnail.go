@@ -0,0 +1,201 @@
+package thumbnail
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"math"
+)
+
+// 判断画面是否可用作封面的阈值，亮度取值 0-255，清晰度按 320 像素宽的缩略图计算
+const (
+	BlackLuma     = 20.0 // 平均亮度低于该值视为黑场
+	FlatContrast  = 8.0  // 亮度标准差低于该值视为纯色画面（黑场、白场、转场）
+	BlurSharpness = 40.0 // 拉普拉斯方差低于该值视为模糊
+)
+
+// FrameStats 画面统计
+type FrameStats struct {
+	MeanLuma  float64 `json:"mean_luma"` // 平均亮度
+	Contrast  float64 `json:"contrast"`  // 亮度标准差
+	Sharpness float64 `json:"sharpness"` // 亮度拉普拉斯方差，越大越清晰
+}
+
+// Blank 黑场或纯色画面
+func (s FrameStats) Blank() bool {
+	return s.MeanLuma < BlackLuma || s.Contrast < FlatContrast
+}
+
+// Blurry 画面模糊
+func (s FrameStats) Blurry() bool {
+	return s.Sharpness < BlurSharpness
+}
+
+// Usable 可以用作封面
+func (s FrameStats) Usable() bool {
+	return !s.Blank() && !s.Blurry()
+}
+
+// Analyze 计算画面的亮度、对比度和清晰度
+func Analyze(img image.Image) FrameStats {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w == 0 || h == 0 {
+		return FrameStats{}
+	}
+
+	luma := make([]float64, w*h)
+	if ycc, ok := img.(*image.YCbCr); ok {
+		// JPEG 解码结果直接读取亮度平面
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				luma[y*w+x] = float64(ycc.Y[ycc.YOffset(bounds.Min.X+x, bounds.Min.Y+y)])
+			}
+		}
+	} else {
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				r, g, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+				luma[y*w+x] = (0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)) / 257
+			}
+		}
+	}
+
+	var sum, sumSq float64
+	for _, v := range luma {
+		sum += v
+		sumSq += v * v
+	}
+	n := float64(len(luma))
+	mean := sum / n
+	stats := FrameStats{
+		MeanLuma: mean,
+		Contrast: math.Sqrt(math.Max(sumSq/n-mean*mean, 0)),
+	}
+
+	if w < 3 || h < 3 {
+		return stats
+	}
+	var lapSum, lapSumSq float64
+	for y := 1; y < h-1; y++ {
+		for x := 1; x < w-1; x++ {
+			i := y*w + x
+			lap := 4*luma[i] - luma[i-1] - luma[i+1] - luma[i-w] - luma[i+w]
+			lapSum += lap
+			lapSumSq += lap * lap
+		}
+	}
+	count := float64((w - 2) * (h - 2))
+	lapMean := lapSum / count
+	stats.Sharpness = math.Max(lapSumSq/count-lapMean*lapMean, 0)
+	return stats
+}
+
+// SelectPoster 从候选画面中选择封面，返回下标：优先选择可用画面中最清晰的，
+// 都模糊时选择非黑场中最清晰的，都是黑场时选择对比度最高的；没有候选时返回 -1
+func SelectPoster(candidates []FrameStats) int {
+	best := -1
+	for _, accept := range []func(FrameStats) bool{FrameStats.Usable, func(s FrameStats) bool { return !s.Blank() }} {
+		for i, stats := range candidates {
+			if accept(stats) && (best < 0 || stats.Sharpness > candidates[best].Sharpness) {
+				best = i
+			}
+		}
+		if best >= 0 {
+			return best
+		}
+	}
+	for i, stats := range candidates {
+		if best < 0 || stats.Contrast > candidates[best].Contrast {
+			best = i
+		}
+	}
+	return best
+}
+
+// CandidateTimes 在视频中均匀取 n 个候选时间点，避开片头片尾的淡入淡出
+func CandidateTimes(duration float64, n int) []float64 {
+	if n <= 0 {
+		return nil
+	}
+	if duration <= 0 {
+		return []float64{0}
+	}
+	times := make([]float64, n)
+	for i := range times {
+		times[i] = math.Round(duration*float64(i+1)/float64(n+1)*1000) / 1000
+	}
+	return times
+}
+
+// SpriteLayout 拖动预览的雪碧图布局，每隔 Interval 秒截取一帧，按行排列
+type SpriteLayout struct {
+	Interval   float64 `json:"interval"`
+	Count      int     `json:"count"`
+	Columns    int     `json:"columns"`
+	Rows       int     `json:"rows"`
+	TileWidth  int     `json:"tile_width"`
+	TileHeight int     `json:"tile_height"`
+}
+
+// 雪碧图默认参数
+const (
+	maxSpriteColumns = 10
+	spriteTileWidth  = 160
+)
+
+// PlanSprite 按视频时长和分辨率规划雪碧图，截图间隔至少 1 秒且总数不超过 maxTiles
+func PlanSprite(duration float64, width, height, maxTiles int) (SpriteLayout, error) {
+	if duration <= 0 {
+		return SpriteLayout{}, fmt.Errorf("invalid duration %v", duration)
+	}
+	if width <= 0 || height <= 0 {
+		return SpriteLayout{}, fmt.Errorf("invalid resolution %dx%d", width, height)
+	}
+	if maxTiles <= 0 {
+		maxTiles = 100
+	}
+
+	interval := math.Max(1, math.Ceil(duration/float64(maxTiles)))
+	count := int(math.Ceil(duration / interval))
+	columns := count
+	if columns > maxSpriteColumns {
+		columns = maxSpriteColumns
+	}
+	tileHeight := int(math.Round(float64(spriteTileWidth)*float64(height)/float64(width)/2)) * 2
+	if tileHeight < 2 {
+		tileHeight = 2
+	}
+	return SpriteLayout{
+		Interval:   interval,
+		Count:      count,
+		Columns:    columns,
+		Rows:       (count + columns - 1) / columns,
+		TileWidth:  spriteTileWidth,
+		TileHeight: tileHeight,
+	}, nil
+}
+
+// WriteSpriteVTT 输出拖动预览的 WebVTT，每个时间段指向雪碧图中对应的区域（媒体片段 #xywh）
+func WriteSpriteVTT(layout SpriteLayout, duration float64, imageURL string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("WEBVTT\n\n")
+	for i := 0; i < layout.Count; i++ {
+		start := float64(i) * layout.Interval
+		end := math.Min(start+layout.Interval, duration)
+		x := (i % layout.Columns) * layout.TileWidth
+		y := (i / layout.Columns) * layout.TileHeight
+		fmt.Fprintf(&buf, "%s --> %s\n%s#xywh=%d,%d,%d,%d\n\n",
+			formatTimestamp(start), formatTimestamp(end), imageURL, x, y, layout.TileWidth, layout.TileHeight)
+	}
+	return buf.Bytes()
+}
+
+// formatTimestamp 格式化为 HH:MM:SS.mmm
+func formatTimestamp(seconds float64) string {
+	ms := int(math.Round(seconds * 1000))
+	if ms < 0 {
+		ms = 0
+	}
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
@@ -0,0 +1,90 @@
+package thumbnail
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func filledImage(fill func(x, y int) uint8) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, 64, 36))
+	for y := 0; y < 36; y++ {
+		for x := 0; x < 64; x++ {
+			img.SetGray(x, y, color.Gray{Y: fill(x, y)})
+		}
+	}
+	return img
+}
+
+func TestAnalyze(t *testing.T) {
+	black := Analyze(filledImage(func(x, y int) uint8 { return 4 }))
+	if !black.Blank() {
+		t.Errorf("black frame stats = %+v, want blank", black)
+	}
+
+	// 平滑渐变：有对比度但没有细节
+	gradient := Analyze(filledImage(func(x, y int) uint8 { return uint8(40 + x*2) }))
+	if gradient.Blank() || !gradient.Blurry() {
+		t.Errorf("gradient stats = %+v, want blurry but not blank", gradient)
+	}
+
+	checker := Analyze(filledImage(func(x, y int) uint8 {
+		if (x/2+y/2)%2 == 0 {
+			return 40
+		}
+		return 220
+	}))
+	if !checker.Usable() {
+		t.Errorf("checkerboard stats = %+v, want usable", checker)
+	}
+}
+
+func TestSelectPoster(t *testing.T) {
+	black := FrameStats{MeanLuma: 5, Contrast: 2}
+	blurry := FrameStats{MeanLuma: 100, Contrast: 30, Sharpness: 10}
+	sharp := FrameStats{MeanLuma: 100, Contrast: 30, Sharpness: 200}
+	sharper := FrameStats{MeanLuma: 100, Contrast: 30, Sharpness: 300}
+
+	tests := []struct {
+		name       string
+		candidates []FrameStats
+		want       int
+	}{
+		{"prefers sharpest usable", []FrameStats{black, sharp, blurry, sharper}, 3},
+		{"falls back to blurry", []FrameStats{black, blurry}, 1},
+		{"all black", []FrameStats{black, {MeanLuma: 10, Contrast: 5}}, 1},
+		{"empty", nil, -1},
+	}
+	for _, tt := range tests {
+		if got := SelectPoster(tt.candidates); got != tt.want {
+			t.Errorf("%s: SelectPoster() = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPlanSprite(t *testing.T) {
+	layout, err := PlanSprite(25, 1080, 1920, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if layout.Interval != 1 || layout.Count != 25 || layout.Columns != 10 || layout.Rows != 3 || layout.TileHeight != 284 {
+		t.Errorf("PlanSprite(25s) = %+v", layout)
+	}
+
+	layout, _ = PlanSprite(250, 1920, 1080, 100)
+	if layout.Interval != 3 || layout.Count != 84 {
+		t.Errorf("PlanSprite(250s) = %+v, want 3s interval and 84 tiles", layout)
+	}
+}
+
+func TestWriteSpriteVTT(t *testing.T) {
+	layout := SpriteLayout{Interval: 2, Count: 3, Columns: 2, Rows: 2, TileWidth: 160, TileHeight: 90}
+	got := string(WriteSpriteVTT(layout, 5.5, "sprite.jpg"))
+	want := "WEBVTT\n\n" +
+		"00:00:00.000 --> 00:00:02.000\nsprite.jpg#xywh=0,0,160,90\n\n" +
+		"00:00:02.000 --> 00:00:04.000\nsprite.jpg#xywh=160,0,160,90\n\n" +
+		"00:00:04.000 --> 00:00:05.500\nsprite.jpg#xywh=0,90,160,90\n\n"
+	if got != want {
+		t.Errorf("WriteSpriteVTT() =\n%s\nwant\n%s", got, want)
+	}
+}
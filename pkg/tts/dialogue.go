@@ -0,0 +1,125 @@
+package tts
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// NarratorSpeaker 旁白的说话人名称
+const NarratorSpeaker = "旁白"
+
+// DialogueLine 拆分后的一句台词，Speaker 为空表示未标注说话人（如独白）
+type DialogueLine struct {
+	Speaker string
+	Text    string
+}
+
+var (
+	// 未提供角色名时，按 "名字：" 识别说话人
+	genericSpeakerPattern = regexp.MustCompile(`([\p{Han}A-Za-z0-9·]{1,12})\s*[：:]`)
+	// 括号中的动作、语气提示，如（冷笑）、(OS)
+	parentheticalPattern = regexp.MustCompile(`（[^（）]*）|\([^()]*\)`)
+	// 表示旁白的括号提示
+	narratorHintPattern = regexp.MustCompile(`^\s*[（(]\s*(旁白|画外音|narrator|narration)\s*[）)]`)
+)
+
+// ParseDialogue 将分镜对白按说话人拆分为多句台词
+// 对白格式如：陈峥："我们被耍了。" 李芳："现在怎么办？"；speakers 为已知角色名，优先按角色名识别说话人
+func ParseDialogue(text string, speakers []string) []DialogueLine {
+	text = strings.TrimSpace(text)
+	switch text {
+	case "", "无", "（无）", "(无)":
+		return nil
+	}
+
+	markers := findSpeakerMarkers(text, knownSpeakerPattern(speakers))
+	if len(markers) == 0 {
+		markers = findSpeakerMarkers(text, genericSpeakerPattern)
+	}
+
+	var lines []DialogueLine
+	cursor := 0
+	speaker := ""
+	for _, marker := range markers {
+		lines = appendDialogueLine(lines, speaker, text[cursor:marker.start])
+		speaker = marker.name
+		cursor = marker.end
+	}
+	return appendDialogueLine(lines, speaker, text[cursor:])
+}
+
+type speakerMarker struct {
+	name       string
+	start, end int
+}
+
+// findSpeakerMarkers 查找说话人标记，标记必须位于开头或在空白、标点、引号之后
+func findSpeakerMarkers(text string, pattern *regexp.Regexp) []speakerMarker {
+	if pattern == nil {
+		return nil
+	}
+
+	var markers []speakerMarker
+	for _, match := range pattern.FindAllStringSubmatchIndex(text, -1) {
+		if match[0] > 0 {
+			prev, _ := utf8.DecodeLastRuneInString(text[:match[0]])
+			if !unicode.IsSpace(prev) && !unicode.IsPunct(prev) {
+				continue
+			}
+		}
+		markers = append(markers, speakerMarker{
+			name:  text[match[2]:match[3]],
+			start: match[0],
+			end:   match[1],
+		})
+	}
+	return markers
+}
+
+// knownSpeakerPattern 按角色名构造说话人标记的正则，长名字优先匹配
+func knownSpeakerPattern(speakers []string) *regexp.Regexp {
+	var names []string
+	for _, speaker := range speakers {
+		if speaker = strings.TrimSpace(speaker); speaker != "" {
+			names = append(names, regexp.QuoteMeta(speaker))
+		}
+	}
+	names = append(names, NarratorSpeaker)
+	sort.SliceStable(names, func(i, j int) bool {
+		return len(names[i]) > len(names[j])
+	})
+	return regexp.MustCompile(`(` + strings.Join(names, "|") + `)\s*[：:]`)
+}
+
+// appendDialogueLine 清理台词文本：识别旁白提示，去掉括号中的提示和两侧的引号，空台词忽略
+func appendDialogueLine(lines []DialogueLine, speaker, text string) []DialogueLine {
+	if narratorHintPattern.MatchString(text) {
+		speaker = NarratorSpeaker
+	}
+	text = parentheticalPattern.ReplaceAllString(text, "")
+	text = strings.TrimLeftFunc(text, isDialogueQuote)
+	// 句间的分隔符不属于台词
+	text = strings.TrimRightFunc(text, func(r rune) bool {
+		return isDialogueQuote(r) || strings.ContainsRune("；;，,、", r)
+	})
+	if !containsSpeakable(text) {
+		return lines
+	}
+	return append(lines, DialogueLine{Speaker: speaker, Text: text})
+}
+
+func isDialogueQuote(r rune) bool {
+	return unicode.IsSpace(r) || strings.ContainsRune(`"'“”‘’「」『』`, r)
+}
+
+func containsSpeakable(text string) bool {
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package tts
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDialogue(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		speakers []string
+		want     []DialogueLine
+	}{
+		{
+			name:     "multiple speakers",
+			text:     `陈峥："我们被耍了，这里根本没有我们要找的东西。" 李芳："现在怎么办？"`,
+			speakers: []string{"陈峥", "李芳"},
+			want: []DialogueLine{
+				{Speaker: "陈峥", Text: "我们被耍了，这里根本没有我们要找的东西。"},
+				{Speaker: "李芳", Text: "现在怎么办？"},
+			},
+		},
+		{
+			name: "unknown speakers fall back to name markers",
+			text: "张三：“快走！”；李四：“等等我。”",
+			want: []DialogueLine{
+				{Speaker: "张三", Text: "快走！"},
+				{Speaker: "李四", Text: "等等我。"},
+			},
+		},
+		{
+			name: "monologue without speaker",
+			text: "（独白）这么多年了，里面到底藏着什么秘密？",
+			want: []DialogueLine{{Text: "这么多年了，里面到底藏着什么秘密？"}},
+		},
+		{
+			name:     "narrator and stage directions",
+			text:     `（旁白）三年后。林雪：（冷笑）"你终于来了。"`,
+			speakers: []string{"林雪"},
+			want: []DialogueLine{
+				{Speaker: NarratorSpeaker, Text: "三年后。"},
+				{Speaker: "林雪", Text: "你终于来了。"},
+			},
+		},
+		{
+			name:     "colon inside speech is not a speaker",
+			text:     `王队："记住：不要开枪。"`,
+			speakers: []string{"王队"},
+			want:     []DialogueLine{{Speaker: "王队", Text: "记住：不要开枪。"}},
+		},
+		{
+			name: "no dialogue",
+			text: "无",
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseDialogue(tt.text, tt.speakers)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseDialogue() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseVoiceStyle(t *testing.T) {
+	tests := []struct {
+		style string
+		want  VoiceProfile
+	}{
+		{"低沉沙哑的中年男声", VoiceProfile{Gender: "male", Age: "adult"}},
+		{"清脆活泼的少女音", VoiceProfile{Gender: "female", Age: "young"}},
+		{"苍老的男声", VoiceProfile{Gender: "male", Age: "elder"}},
+		{"a warm female voice", VoiceProfile{Gender: "female", Age: "adult"}},
+		{"", VoiceProfile{Gender: "female", Age: "adult"}},
+	}
+
+	for _, tt := range tests {
+		if got := ParseVoiceStyle(tt.style); got != tt.want {
+			t.Errorf("ParseVoiceStyle(%q) = %+v, want %+v", tt.style, got, tt.want)
+		}
+	}
+}
+
+func TestLocalTTSClientDuration(t *testing.T) {
+	result, err := NewLocalTTSClient().Synthesize("我们被耍了，这里根本没有我们要找的东西。")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Duration <= 0 {
+		t.Fatalf("expected positive duration, got %d", result.Duration)
+	}
+	if got := WAVDuration(result.Audio); got != result.Duration {
+		t.Errorf("WAVDuration() = %d, want %d", got, result.Duration)
+	}
+}
@@ -0,0 +1,128 @@
+package tts
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// 本地替身的语速：中文约每秒 4.5 个字，其他语言约每秒 2.5 个词
+const (
+	localCJKCharsPerSecond = 4.5
+	localWordsPerSecond    = 2.5
+	localMinDurationMs     = 500
+	localSampleRate        = 16000
+)
+
+// LocalTTSClient 本地替身，不调用任何接口，按文本长度估算时长生成静音 WAV
+// 用于在未配置语音厂商时跑通配音、时间线和字幕流程
+type LocalTTSClient struct{}
+
+func NewLocalTTSClient() *LocalTTSClient {
+	return &LocalTTSClient{}
+}
+
+func (c *LocalTTSClient) Synthesize(text string, opts ...TTSOption) (*TTSResult, error) {
+	options := applyOptions(TTSOptions{SampleRate: localSampleRate}, opts)
+	if strings.TrimSpace(text) == "" {
+		return nil, fmt.Errorf("text is empty")
+	}
+
+	duration := EstimateSpeechDuration(text, options.Speed)
+	return &TTSResult{
+		Audio:    silentWAV(duration, options.SampleRate),
+		Format:   "wav",
+		Duration: duration,
+	}, nil
+}
+
+// EstimateSpeechDuration 按正常语速估算朗读文本的时长（毫秒）
+func EstimateSpeechDuration(text string, speed float64) int {
+	if speed <= 0 {
+		speed = 1
+	}
+
+	cjk, words := 0, 0
+	inWord := false
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r):
+			cjk++
+			inWord = false
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if !inWord {
+				words++
+			}
+			inWord = true
+		default:
+			inWord = false
+		}
+	}
+
+	seconds := float64(cjk)/localCJKCharsPerSecond + float64(words)/localWordsPerSecond
+	duration := int(math.Round(seconds / speed * 1000))
+	if duration < localMinDurationMs {
+		duration = localMinDurationMs
+	}
+	return duration
+}
+
+// silentWAV 生成指定时长的 16 位单声道静音 WAV
+func silentWAV(durationMs, sampleRate int) []byte {
+	if sampleRate <= 0 {
+		sampleRate = localSampleRate
+	}
+	samples := sampleRate * durationMs / 1000
+	dataSize := samples * 2
+
+	buf := bytes.NewBuffer(make([]byte, 0, 44+dataSize))
+	buf.WriteString("RIFF")
+	binary.Write(buf, binary.LittleEndian, uint32(36+dataSize))
+	buf.WriteString("WAVE")
+	buf.WriteString("fmt ")
+	binary.Write(buf, binary.LittleEndian, uint32(16))
+	binary.Write(buf, binary.LittleEndian, uint16(1)) // PCM
+	binary.Write(buf, binary.LittleEndian, uint16(1)) // 单声道
+	binary.Write(buf, binary.LittleEndian, uint32(sampleRate))
+	binary.Write(buf, binary.LittleEndian, uint32(sampleRate*2))
+	binary.Write(buf, binary.LittleEndian, uint16(2))
+	binary.Write(buf, binary.LittleEndian, uint16(16))
+	buf.WriteString("data")
+	binary.Write(buf, binary.LittleEndian, uint32(dataSize))
+	buf.Write(make([]byte, dataSize))
+	return buf.Bytes()
+}
+
+// WAVDuration 读取 PCM WAV 数据的时长（毫秒），无法解析时返回 0
+func WAVDuration(data []byte) int {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return 0
+	}
+
+	var byteRate uint32
+	for offset := 12; offset+8 <= len(data); {
+		chunkID := string(data[offset : offset+4])
+		chunkSize := int(binary.LittleEndian.Uint32(data[offset+4 : offset+8]))
+		body := offset + 8
+		switch chunkID {
+		case "fmt ":
+			if body+12 <= len(data) {
+				byteRate = binary.LittleEndian.Uint32(data[body+8 : body+12])
+			}
+		case "data":
+			if byteRate == 0 {
+				return 0
+			}
+			if body+chunkSize > len(data) {
+				chunkSize = len(data) - body
+			}
+			return int(int64(chunkSize) * 1000 / int64(byteRate))
+		}
+		// chunk 按偶数字节对齐
+		offset = body + chunkSize + chunkSize%2
+	}
+	return 0
+}
@@ -0,0 +1,162 @@
+package tts
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// MinimaxTTSClient MiniMax T2A v2 同步语音合成
+type MinimaxTTSClient struct {
+	BaseURL    string
+	APIKey     string
+	Model      string
+	HTTPClient *http.Client
+}
+
+type MinimaxVoiceSetting struct {
+	VoiceID string  `json:"voice_id"`
+	Speed   float64 `json:"speed"`
+	Vol     float64 `json:"vol"`
+	Pitch   int     `json:"pitch"`
+	Emotion string  `json:"emotion,omitempty"`
+}
+
+type MinimaxAudioSetting struct {
+	SampleRate int    `json:"sample_rate"`
+	Bitrate    int    `json:"bitrate"`
+	Format     string `json:"format"`
+	Channel    int    `json:"channel"`
+}
+
+type MinimaxTTSRequest struct {
+	Model        string              `json:"model"`
+	Text         string              `json:"text"`
+	Stream       bool                `json:"stream"`
+	VoiceSetting MinimaxVoiceSetting `json:"voice_setting"`
+	AudioSetting MinimaxAudioSetting `json:"audio_setting"`
+	OutputFormat string              `json:"output_format"`
+}
+
+type MinimaxTTSResponse struct {
+	Data struct {
+		Audio  string `json:"audio"` // hex 编码的音频
+		Status int    `json:"status"`
+	} `json:"data"`
+	ExtraInfo struct {
+		AudioLength int    `json:"audio_length"` // 毫秒
+		AudioFormat string `json:"audio_format"`
+	} `json:"extra_info"`
+	BaseResp struct {
+		StatusCode int    `json:"status_code"`
+		StatusMsg  string `json:"status_msg"`
+	} `json:"base_resp"`
+}
+
+// MiniMax 支持的情绪
+var minimaxEmotions = map[string]bool{
+	"happy": true, "sad": true, "angry": true, "fearful": true,
+	"disgusted": true, "surprised": true, "calm": true,
+}
+
+func NewMinimaxTTSClient(baseURL, apiKey, model string) *MinimaxTTSClient {
+	if baseURL == "" {
+		baseURL = "https://api.minimaxi.com"
+	}
+	if model == "" {
+		model = "speech-02-hd"
+	}
+	return &MinimaxTTSClient{
+		BaseURL: baseURL,
+		APIKey:  apiKey,
+		Model:   model,
+		HTTPClient: &http.Client{
+			Timeout: 2 * time.Minute,
+		},
+	}
+}
+
+func (c *MinimaxTTSClient) Synthesize(text string, opts ...TTSOption) (*TTSResult, error) {
+	options := applyOptions(TTSOptions{
+		Model:      c.Model,
+		Voice:      "female-shaonv",
+		Format:     "mp3",
+		SampleRate: 32000,
+	}, opts)
+
+	reqBody := MinimaxTTSRequest{
+		Model: options.Model,
+		Text:  text,
+		VoiceSetting: MinimaxVoiceSetting{
+			VoiceID: options.Voice,
+			Speed:   options.Speed,
+			Vol:     1,
+		},
+		AudioSetting: MinimaxAudioSetting{
+			SampleRate: options.SampleRate,
+			Bitrate:    128000,
+			Format:     options.Format,
+			Channel:    1,
+		},
+		OutputFormat: "hex",
+	}
+	if minimaxEmotions[options.Emotion] {
+		reqBody.VoiceSetting.Emotion = options.Emotion
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", c.BaseURL+"/v1/t2a_v2", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.APIKey)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var result MinimaxTTSResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("parse response: %w, body: %s", err, string(body))
+	}
+	if result.BaseResp.StatusCode != 0 {
+		return nil, fmt.Errorf("minimax error: %d - %s", result.BaseResp.StatusCode, result.BaseResp.StatusMsg)
+	}
+
+	audio, err := hex.DecodeString(result.Data.Audio)
+	if err != nil {
+		return nil, fmt.Errorf("decode audio: %w", err)
+	}
+	if len(audio) == 0 {
+		return nil, fmt.Errorf("empty audio response")
+	}
+
+	format := options.Format
+	if result.ExtraInfo.AudioFormat != "" {
+		format = result.ExtraInfo.AudioFormat
+	}
+	return &TTSResult{
+		Audio:    audio,
+		Format:   format,
+		Duration: result.ExtraInfo.AudioLength,
+	}, nil
+}
@@ -0,0 +1,70 @@
+package tts
+
+type TTSClient interface {
+	Synthesize(text string, opts ...TTSOption) (*TTSResult, error)
+}
+
+type TTSResult struct {
+	Audio    []byte // 音频数据
+	Format   string // mp3, wav 等
+	Duration int    // 厂商返回的时长（毫秒），未返回时为 0
+}
+
+type TTSOptions struct {
+	Model      string
+	Voice      string  // 厂商音色 ID
+	Speed      float64 // 语速倍数，1 为正常语速
+	Emotion    string  // 情绪，仅部分厂商支持
+	Format     string
+	SampleRate int
+}
+
+type TTSOption func(*TTSOptions)
+
+func WithModel(model string) TTSOption {
+	return func(o *TTSOptions) {
+		o.Model = model
+	}
+}
+
+func WithVoice(voice string) TTSOption {
+	return func(o *TTSOptions) {
+		o.Voice = voice
+	}
+}
+
+func WithSpeed(speed float64) TTSOption {
+	return func(o *TTSOptions) {
+		o.Speed = speed
+	}
+}
+
+func WithEmotion(emotion string) TTSOption {
+	return func(o *TTSOptions) {
+		o.Emotion = emotion
+	}
+}
+
+func WithFormat(format string) TTSOption {
+	return func(o *TTSOptions) {
+		o.Format = format
+	}
+}
+
+func WithSampleRate(sampleRate int) TTSOption {
+	return func(o *TTSOptions) {
+		o.SampleRate = sampleRate
+	}
+}
+
+// applyOptions 合并默认参数和调用方参数
+func applyOptions(defaults TTSOptions, opts []TTSOption) *TTSOptions {
+	options := defaults
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.Speed <= 0 {
+		options.Speed = 1
+	}
+	return &options
+}
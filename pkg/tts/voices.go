@@ -0,0 +1,123 @@
+package tts
+
+import "strings"
+
+// VoiceProfile 从角色声音描述中识别出的性别和年龄段
+type VoiceProfile struct {
+	Gender string // male, female
+	Age    string // child, young, adult, elder
+}
+
+// 旁白使用的音色 key
+const narratorKey = "narrator"
+
+// 各厂商按 性别/年龄段 预设的音色，角色未指定音色时按声音描述选择
+var voicePresets = map[string]map[string]string{
+	"openai": {
+		"male/child":   "echo",
+		"male/young":   "echo",
+		"male/adult":   "onyx",
+		"male/elder":   "ash",
+		"female/child": "coral",
+		"female/young": "shimmer",
+		"female/adult": "nova",
+		"female/elder": "sage",
+		narratorKey:    "fable",
+	},
+	"volcengine": {
+		"male/child":   "BV061_streaming",
+		"male/young":   "BV102_streaming",
+		"male/adult":   "BV002_streaming",
+		"male/elder":   "BV002_streaming",
+		"female/child": "BV061_streaming",
+		"female/young": "BV700_streaming",
+		"female/adult": "BV001_streaming",
+		"female/elder": "BV001_streaming",
+		narratorKey:    "BV701_streaming",
+	},
+	"minimax": {
+		"male/child":   "clever_boy",
+		"male/young":   "male-qn-qingse",
+		"male/adult":   "male-qn-jingying",
+		"male/elder":   "male-qn-jingying",
+		"female/child": "lovely_girl",
+		"female/young": "female-shaonv",
+		"female/adult": "female-yujie",
+		"female/elder": "female-chengshu",
+		narratorKey:    "presenter_male",
+	},
+}
+
+// 声音描述中的关键词，按顺序匹配
+var (
+	femaleKeywords = []string{"女", "female", "woman", "girl"}
+	maleKeywords   = []string{"男", "male", "man", "boy"}
+	childKeywords  = []string{"童", "孩", "幼", "稚", "child", "kid"}
+	elderKeywords  = []string{"老", "年迈", "苍老", "沧桑", "elder", "old"}
+	youngKeywords  = []string{"少女", "少年", "青年", "年轻", "清脆", "活泼", "young"}
+)
+
+// ParseVoiceStyle 从角色的声音描述（如"低沉的中年男声"）中识别性别和年龄段，识别不出时为成年女声
+func ParseVoiceStyle(style string) VoiceProfile {
+	style = strings.ToLower(style)
+	profile := VoiceProfile{Gender: "female", Age: "adult"}
+
+	// 先匹配女性关键词，避免 "female" 被 "male" 误判
+	if containsAny(style, femaleKeywords) {
+		profile.Gender = "female"
+	} else if containsAny(style, maleKeywords) {
+		profile.Gender = "male"
+	}
+
+	switch {
+	case containsAny(style, childKeywords):
+		profile.Age = "child"
+	case containsAny(style, elderKeywords):
+		profile.Age = "elder"
+	case containsAny(style, youngKeywords):
+		profile.Age = "young"
+	}
+	return profile
+}
+
+// DefaultVoice 根据角色声音描述选择厂商的预设音色
+func DefaultVoice(provider, voiceStyle string) string {
+	presets := providerPresets(provider)
+	if presets == nil {
+		return ""
+	}
+	profile := ParseVoiceStyle(voiceStyle)
+	return presets[profile.Gender+"/"+profile.Age]
+}
+
+// NarratorVoice 旁白和无法识别说话人的台词使用的音色
+func NarratorVoice(provider string) string {
+	presets := providerPresets(provider)
+	if presets == nil {
+		return ""
+	}
+	return presets[narratorKey]
+}
+
+func providerPresets(provider string) map[string]string {
+	switch provider {
+	case "doubao", "volcengine", "volces":
+		return voicePresets["volcengine"]
+	case "minimax":
+		return voicePresets["minimax"]
+	case "local":
+		return nil
+	default:
+		// 其他厂商按 OpenAI 兼容接口处理
+		return voicePresets["openai"]
+	}
+}
+
+func containsAny(s string, keywords []string) bool {
+	for _, keyword := range keywords {
+		if strings.Contains(s, keyword) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+package tts
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// VolcengineTTSClient 火山引擎（豆包语音）HTTP 非流式语音合成
+type VolcengineTTSClient struct {
+	BaseURL     string
+	AppID       string
+	AccessToken string
+	Cluster     string
+	HTTPClient  *http.Client
+}
+
+type VolcengineTTSRequest struct {
+	App struct {
+		AppID   string `json:"appid"`
+		Token   string `json:"token"`
+		Cluster string `json:"cluster"`
+	} `json:"app"`
+	User struct {
+		UID string `json:"uid"`
+	} `json:"user"`
+	Audio struct {
+		VoiceType  string  `json:"voice_type"`
+		Encoding   string  `json:"encoding"`
+		SpeedRatio float64 `json:"speed_ratio"`
+		Rate       int     `json:"rate,omitempty"`
+		Emotion    string  `json:"emotion,omitempty"`
+	} `json:"audio"`
+	Request struct {
+		ReqID     string `json:"reqid"`
+		Text      string `json:"text"`
+		Operation string `json:"operation"`
+	} `json:"request"`
+}
+
+type VolcengineTTSResponse struct {
+	ReqID    string `json:"reqid"`
+	Code     int    `json:"code"`
+	Message  string `json:"message"`
+	Data     string `json:"data"` // base64 编码的音频
+	Addition struct {
+		Duration string `json:"duration"` // 毫秒
+	} `json:"addition"`
+}
+
+// volcengineSuccessCode 合成成功的返回码
+const volcengineSuccessCode = 3000
+
+func NewVolcengineTTSClient(baseURL, appID, accessToken, cluster string) *VolcengineTTSClient {
+	if baseURL == "" {
+		baseURL = "https://openspeech.bytedance.com"
+	}
+	if cluster == "" {
+		cluster = "volcano_tts"
+	}
+	return &VolcengineTTSClient{
+		BaseURL:     baseURL,
+		AppID:       appID,
+		AccessToken: accessToken,
+		Cluster:     cluster,
+		HTTPClient: &http.Client{
+			Timeout: 2 * time.Minute,
+		},
+	}
+}
+
+func (c *VolcengineTTSClient) Synthesize(text string, opts ...TTSOption) (*TTSResult, error) {
+	options := applyOptions(TTSOptions{
+		Voice:  "BV001_streaming",
+		Format: "mp3",
+	}, opts)
+
+	var reqBody VolcengineTTSRequest
+	reqBody.App.AppID = c.AppID
+	reqBody.App.Token = c.AccessToken
+	reqBody.App.Cluster = c.Cluster
+	reqBody.User.UID = "drama-generator"
+	reqBody.Audio.VoiceType = options.Voice
+	reqBody.Audio.Encoding = options.Format
+	reqBody.Audio.SpeedRatio = options.Speed
+	reqBody.Audio.Rate = options.SampleRate
+	reqBody.Audio.Emotion = options.Emotion
+	reqBody.Request.ReqID = uuid.New().String()
+	reqBody.Request.Text = text
+	reqBody.Request.Operation = "query"
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", c.BaseURL+"/api/v1/tts", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// 火山语音的鉴权头格式为 "Bearer;{token}"
+	req.Header.Set("Authorization", "Bearer;"+c.AccessToken)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+
+	var result VolcengineTTSResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("parse response: %w, body: %s", err, string(body))
+	}
+	if resp.StatusCode != http.StatusOK || result.Code != volcengineSuccessCode {
+		return nil, fmt.Errorf("API error (status %d, code %d): %s", resp.StatusCode, result.Code, result.Message)
+	}
+
+	audio, err := base64.StdEncoding.DecodeString(result.Data)
+	if err != nil {
+		return nil, fmt.Errorf("decode audio: %w", err)
+	}
+	duration, _ := strconv.Atoi(result.Addition.Duration)
+
+	return &TTSResult{
+		Audio:    audio,
+		Format:   options.Format,
+		Duration: duration,
+	}, nil
+}
@@ -0,0 +1,126 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// blockedNetworks IP 自带方法判断不到的保留网段：0.0.0.0/8 和运营商级 NAT 共享地址
+var blockedNetworks = mustParseCIDRs("0.0.0.0/8", "100.64.0.0/10")
+
+// HostGuard 限制服务端主动请求的目标地址，拒绝回环、内网、链路本地（包括云厂商元数据地址 169.254.169.254）等非公网地址
+// allow-list 中的主机名、IP 或 CIDR 网段不受限制，用于投递到内网服务
+type HostGuard struct {
+	hosts    map[string]bool
+	networks []*net.IPNet
+}
+
+// NewHostGuard 创建地址校验器，allowed 中每项可以是主机名、IP 或 CIDR 网段
+func NewHostGuard(allowed []string) *HostGuard {
+	g := &HostGuard{hosts: make(map[string]bool)}
+	for _, entry := range allowed {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			g.networks = append(g.networks, network)
+			continue
+		}
+		if ip := net.ParseIP(entry); ip != nil {
+			g.networks = append(g.networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)})
+			continue
+		}
+		g.hosts[entry] = true
+	}
+	return g
+}
+
+// CheckHost 解析主机名并校验所有解析结果，返回可以连接的 IP
+func (g *HostGuard) CheckHost(ctx context.Context, host string) ([]net.IP, error) {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if ip := net.ParseIP(host); ip != nil {
+		if !g.allowIP(ip) {
+			return nil, fmt.Errorf("address %s is not a public address", ip)
+		}
+		return []net.IP{ip}, nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, fmt.Errorf("resolve %s: %w", host, err)
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("resolve %s: no addresses", host)
+	}
+	ips := make([]net.IP, 0, len(addrs))
+	for _, addr := range addrs {
+		// 任何一个解析结果指向内网都拒绝，避免轮询解析时绕过校验
+		if !g.hosts[host] && !g.allowIP(addr.IP) {
+			return nil, fmt.Errorf("host %s resolves to non-public address %s", host, addr.IP)
+		}
+		ips = append(ips, addr.IP)
+	}
+	return ips, nil
+}
+
+// DialContext 校验目标地址后直接连接解析出的 IP，避免校验和连接之间 DNS 记录被替换（DNS rebinding）
+// 用作 http.Transport.DialContext，重定向后的请求同样经过校验
+func (g *HostGuard) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	ips, err := g.CheckHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var dialer net.Dialer
+	var lastErr error
+	for _, ip := range ips {
+		conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+func (g *HostGuard) allowIP(ip net.IP) bool {
+	for _, network := range g.networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return IsPublicIP(ip)
+}
+
+// IsPublicIP 判断是否为公网地址，回环、内网（RFC1918、fc00::/7）、链路本地、组播和未指定地址都不是
+func IsPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
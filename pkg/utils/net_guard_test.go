@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"context"
+	"net"
+	"testing"
+)
+
+func TestIsPublicIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{ip: "8.8.8.8", want: true},
+		{ip: "2606:4700:4700::1111", want: true},
+		{ip: "127.0.0.1", want: false},
+		{ip: "::1", want: false},
+		{ip: "10.1.2.3", want: false},
+		{ip: "172.16.0.1", want: false},
+		{ip: "192.168.1.1", want: false},
+		{ip: "169.254.169.254", want: false},
+		{ip: "100.64.0.1", want: false},
+		{ip: "0.0.0.0", want: false},
+		{ip: "fd00::1", want: false},
+		{ip: "fe80::1", want: false},
+		{ip: "::ffff:127.0.0.1", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := IsPublicIP(net.ParseIP(tt.ip)); got != tt.want {
+			t.Errorf("IsPublicIP(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestHostGuardCheckHost(t *testing.T) {
+	ctx := context.Background()
+	guard := NewHostGuard([]string{"10.0.0.0/8", "192.168.1.20", "hooks.internal"})
+
+	tests := []struct {
+		host    string
+		wantErr bool
+	}{
+		{host: "8.8.8.8", wantErr: false},
+		{host: "127.0.0.1", wantErr: true},
+		{host: "169.254.169.254", wantErr: true},
+		{host: "10.20.30.40", wantErr: false},
+		{host: "192.168.1.20", wantErr: false},
+		{host: "192.168.1.21", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		if _, err := guard.CheckHost(ctx, tt.host); (err != nil) != tt.wantErr {
+			t.Errorf("CheckHost(%s) error = %v, wantErr %v", tt.host, err, tt.wantErr)
+		}
+	}
+
+	if _, err := NewHostGuard(nil).CheckHost(ctx, "localhost"); err == nil {
+		t.Error("CheckHost(localhost) should be rejected")
+	}
+}
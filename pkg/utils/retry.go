@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 匹配各客户端统一的错误格式："API error (status 429): ..."
+var apiStatusPattern = regexp.MustCompile(`status (\d{3})`)
+
+// HTTPStatusFromError 从AI客户端返回的错误中提取HTTP状态码，无法识别时返回 0
+func HTTPStatusFromError(err error) int {
+	if err == nil {
+		return 0
+	}
+	match := apiStatusPattern.FindStringSubmatch(err.Error())
+	if len(match) != 2 {
+		return 0
+	}
+	status, _ := strconv.Atoi(match[1])
+	return status
+}
+
+// IsTransientError 判断错误是否为可重试的临时错误
+// 临时错误：超时、限流(429)、服务端错误(5xx)、网络连接错误
+// 永久错误：其余 4xx（参数错误、鉴权失败、内容审核拒绝等）以及无法识别的业务错误
+func IsTransientError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if status := HTTPStatusFromError(err); status > 0 {
+		return status == 408 || status == 425 || status == 429 || status >= 500
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, keyword := range []string{
+		"timeout",
+		"deadline exceeded",
+		"connection reset",
+		"connection refused",
+		"broken pipe",
+		"eof",
+		"send request",
+		"read response",
+		"too many requests",
+		"rate limit",
+		"temporarily unavailable",
+	} {
+		if strings.Contains(msg, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// Backoff 计算第 attempt 次（从 1 开始）重试前的指数退避等待时间
+func Backoff(attempt int, base, max time.Duration) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	delay := base << uint(attempt-1)
+	if delay > max || delay <= 0 {
+		delay = max
+	}
+	return delay
+}
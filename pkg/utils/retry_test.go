@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestIsTransientError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil, want: false},
+		{name: "rate limited", err: fmt.Errorf("API error (status 429): too many requests"), want: true},
+		{name: "server error", err: fmt.Errorf("API error (status 503): service unavailable"), want: true},
+		{name: "content policy", err: fmt.Errorf("API error (status 400): content violates policy"), want: false},
+		{name: "unauthorized", err: fmt.Errorf("API error (status 401): invalid api key"), want: false},
+		{name: "network", err: fmt.Errorf("send request: %w", errors.New("connection reset by peer")), want: true},
+		{name: "deadline", err: fmt.Errorf("send request: %w", context.DeadlineExceeded), want: true},
+		{name: "business error", err: errors.New("no image generated"), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTransientError(tt.err); got != tt.want {
+				t.Errorf("IsTransientError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	base := 2 * time.Second
+	max := 30 * time.Second
+
+	if got := Backoff(1, base, max); got != 2*time.Second {
+		t.Errorf("Backoff(1) = %v, want 2s", got)
+	}
+	if got := Backoff(3, base, max); got != 8*time.Second {
+		t.Errorf("Backoff(3) = %v, want 8s", got)
+	}
+	if got := Backoff(10, base, max); got != max {
+		t.Errorf("Backoff(10) = %v, want %v", got, max)
+	}
+}
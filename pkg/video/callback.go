@@ -0,0 +1,102 @@
+package video
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// 支持任务回调的厂商标识，用于回调地址 /api/v1/videos/callbacks/:provider
+const (
+	CallbackProviderVolces  = "volces"
+	CallbackProviderMinimax = "minimax"
+)
+
+// CallbackEvent 厂商推送的任务状态
+type CallbackEvent struct {
+	Result    *VideoResult
+	Challenge string // MiniMax 校验回调地址时下发，需原样返回
+}
+
+// CallbackProvider 返回厂商在回调地址中的标识，不支持回调的厂商返回空字符串
+func CallbackProvider(provider string) string {
+	switch provider {
+	case "doubao", "volcengine", "volces":
+		return CallbackProviderVolces
+	case "minimax":
+		return CallbackProviderMinimax
+	default:
+		return ""
+	}
+}
+
+// ParseCallback 解析厂商推送的任务回调
+func ParseCallback(provider string, body []byte) (*CallbackEvent, error) {
+	switch provider {
+	case CallbackProviderVolces:
+		return parseVolcesArkCallback(body)
+	case CallbackProviderMinimax:
+		return parseMinimaxCallback(body)
+	default:
+		return nil, fmt.Errorf("unsupported callback provider: %s", provider)
+	}
+}
+
+// parseVolcesArkCallback 火山方舟在任务状态变化时推送与查询接口相同的任务对象
+func parseVolcesArkCallback(body []byte) (*CallbackEvent, error) {
+	var result VolcesArkResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("parse callback: %w", err)
+	}
+	if result.ID == "" {
+		return nil, fmt.Errorf("callback missing task id")
+	}
+
+	videoResult := result.toVideoResult()
+	if videoResult.Error == "" && (result.Status == "failed" || result.Status == "expired") {
+		videoResult.Error = "task " + result.Status
+	}
+	return &CallbackEvent{Result: videoResult}, nil
+}
+
+// MinimaxCallback MiniMax 任务回调，首次配置回调地址时只包含 challenge
+type MinimaxCallback struct {
+	Challenge string `json:"challenge"`
+	TaskID    string `json:"task_id"`
+	Status    string `json:"status"` // processing, success, failed
+	FileID    string `json:"file_id"`
+	BaseResp  struct {
+		StatusCode int    `json:"status_code"`
+		StatusMsg  string `json:"status_msg"`
+	} `json:"base_resp"`
+}
+
+// parseMinimaxCallback 回调只包含 file_id，下载地址需再通过查询接口获取
+func parseMinimaxCallback(body []byte) (*CallbackEvent, error) {
+	var callback MinimaxCallback
+	if err := json.Unmarshal(body, &callback); err != nil {
+		return nil, fmt.Errorf("parse callback: %w", err)
+	}
+	if callback.Challenge != "" {
+		return &CallbackEvent{Challenge: callback.Challenge}, nil
+	}
+	if callback.TaskID == "" {
+		return nil, fmt.Errorf("callback missing task id")
+	}
+
+	videoResult := &VideoResult{
+		TaskID: callback.TaskID,
+		Status: callback.Status,
+	}
+	switch {
+	case strings.EqualFold(callback.Status, "success"):
+		videoResult.Completed = true
+	case strings.EqualFold(callback.Status, "failed"):
+		videoResult.Completed = true
+		videoResult.Error = "Video generation failed"
+		if callback.BaseResp.StatusMsg != "" {
+			videoResult.Error = callback.BaseResp.StatusMsg
+		}
+	}
+	return &CallbackEvent{Result: videoResult}, nil
+}
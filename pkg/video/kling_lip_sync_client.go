@@ -0,0 +1,183 @@
+package video
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// KlingLipSyncClient 可灵对口型客户端
+// APIKey 为 "AccessKey:SecretKey" 时按官方要求签发 JWT，否则直接作为 Bearer Token（适用于中转服务）
+type KlingLipSyncClient struct {
+	BaseURL    string
+	APIKey     string
+	HTTPClient *http.Client
+}
+
+type KlingLipSyncRequest struct {
+	Input struct {
+		VideoURL  string `json:"video_url"`
+		Mode      string `json:"mode"`       // audio2video: 使用提供的音频驱动口型
+		AudioType string `json:"audio_type"` // url: 通过地址提供音频
+		AudioURL  string `json:"audio_url"`
+	} `json:"input"`
+}
+
+type KlingLipSyncResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    struct {
+		TaskID        string `json:"task_id"`
+		TaskStatus    string `json:"task_status"` // submitted, processing, succeed, failed
+		TaskStatusMsg string `json:"task_status_msg"`
+		TaskResult    struct {
+			Videos []struct {
+				URL      string `json:"url"`
+				Duration string `json:"duration"`
+			} `json:"videos"`
+		} `json:"task_result"`
+	} `json:"data"`
+}
+
+func NewKlingLipSyncClient(baseURL, apiKey string) *KlingLipSyncClient {
+	if baseURL == "" {
+		baseURL = "https://api-beijing.klingai.com"
+	}
+	return &KlingLipSyncClient{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		APIKey:  apiKey,
+		HTTPClient: &http.Client{
+			Timeout: 60 * time.Second,
+		},
+	}
+}
+
+func (c *KlingLipSyncClient) SyncLips(source *VideoResult, audioURL string, opts ...LipSyncOption) (*VideoResult, error) {
+	var reqBody KlingLipSyncRequest
+	reqBody.Input.VideoURL = source.VideoURL
+	reqBody.Input.Mode = "audio2video"
+	reqBody.Input.AudioType = "url"
+	reqBody.Input.AudioURL = audioURL
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	body, err := c.do("POST", c.BaseURL+"/v1/videos/lip-sync", jsonData)
+	if err != nil {
+		return nil, err
+	}
+	return c.parseResult(body)
+}
+
+func (c *KlingLipSyncClient) GetTaskStatus(taskID string) (*VideoResult, error) {
+	body, err := c.do("GET", c.BaseURL+"/v1/videos/lip-sync/"+taskID, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.parseResult(body)
+}
+
+func (c *KlingLipSyncClient) do(method, endpoint string, payload []byte) ([]byte, error) {
+	token, err := c.authToken()
+	if err != nil {
+		return nil, err
+	}
+
+	var reader io.Reader
+	if payload != nil {
+		reader = bytes.NewBuffer(payload)
+	}
+	req, err := http.NewRequest(method, endpoint, reader)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+	}
+	return body, nil
+}
+
+func (c *KlingLipSyncClient) parseResult(body []byte) (*VideoResult, error) {
+	var result KlingLipSyncResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("parse response: %w", err)
+	}
+	if result.Code != 0 {
+		return nil, fmt.Errorf("kling error (code %d): %s", result.Code, result.Message)
+	}
+
+	videoResult := &VideoResult{
+		TaskID: result.Data.TaskID,
+		Status: result.Data.TaskStatus,
+	}
+
+	switch result.Data.TaskStatus {
+	case "succeed":
+		if len(result.Data.TaskResult.Videos) > 0 {
+			output := result.Data.TaskResult.Videos[0]
+			videoResult.VideoURL = output.URL
+			if seconds, err := strconv.ParseFloat(output.Duration, 64); err == nil {
+				videoResult.Duration = int(math.Round(seconds))
+			}
+		}
+		videoResult.Completed = true
+	case "failed":
+		videoResult.Error = result.Data.TaskStatusMsg
+		if videoResult.Error == "" {
+			videoResult.Error = "lip sync failed"
+		}
+	}
+
+	return videoResult, nil
+}
+
+// authToken 使用 AccessKey/SecretKey 签发 30 分钟有效的 HS256 JWT
+func (c *KlingLipSyncClient) authToken() (string, error) {
+	accessKey, secretKey, ok := strings.Cut(c.APIKey, ":")
+	if !ok {
+		return c.APIKey, nil
+	}
+
+	now := time.Now()
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss": accessKey,
+		"exp": now.Add(30 * time.Minute).Unix(),
+		"nbf": now.Add(-5 * time.Second).Unix(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("marshal token claims: %w", err)
+	}
+	signingInput := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	mac := hmac.New(sha256.New, []byte(secretKey))
+	mac.Write([]byte(signingInput))
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
@@ -0,0 +1,35 @@
+package video
+
+// LipSyncClient 口型同步客户端：按配音驱动视频中人物的口型，返回同步后的视频
+// 厂商均为异步任务，SyncLips 返回任务ID，通过 GetTaskStatus 轮询结果
+type LipSyncClient interface {
+	SyncLips(source *VideoResult, audioURL string, opts ...LipSyncOption) (*VideoResult, error)
+	GetTaskStatus(taskID string) (*VideoResult, error)
+}
+
+// 配音与视频时长不一致时的处理方式
+const (
+	LipSyncModeCutOff  = "cut_off" // 截断到较短的一方
+	LipSyncModeLoop    = "loop"    // 循环较短的视频
+	LipSyncModeBounce  = "bounce"  // 较短的视频来回播放
+	LipSyncModeSilence = "silence" // 配音较短时补静音
+)
+
+type LipSyncOptions struct {
+	Model    string
+	SyncMode string
+}
+
+type LipSyncOption func(*LipSyncOptions)
+
+func WithLipSyncModel(model string) LipSyncOption {
+	return func(o *LipSyncOptions) {
+		o.Model = model
+	}
+}
+
+func WithSyncMode(mode string) LipSyncOption {
+	return func(o *LipSyncOptions) {
+		o.SyncMode = mode
+	}
+}
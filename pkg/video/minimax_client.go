@@ -60,6 +60,7 @@ type MinimaxRequest struct {
 	Model            string                    `json:"model"`
 	Duration         int                       `json:"duration,omitempty"`
 	Resolution       string                    `json:"resolution,omitempty"`
+	CallbackURL      string                    `json:"callback_url,omitempty"`
 }
 
 // MinimaxCreateResponse 创建任务的响应
@@ -129,9 +130,10 @@ func (c *MinimaxClient) GenerateVideo(imageURL, prompt string, opts ...VideoOpti
 	}
 
 	reqBody := MinimaxRequest{
-		Prompt:   prompt,
-		Model:    model,
-		Duration: options.Duration,
+		Prompt:      prompt,
+		Model:       model,
+		Duration:    options.Duration,
+		CallbackURL: options.CallbackURL,
 	}
 
 	// 设置分辨率
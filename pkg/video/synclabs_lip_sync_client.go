@@ -0,0 +1,151 @@
+package video
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SyncLabsLipSyncClient Sync Labs (sync.so) 口型同步客户端
+type SyncLabsLipSyncClient struct {
+	BaseURL    string
+	APIKey     string
+	Model      string
+	HTTPClient *http.Client
+}
+
+type SyncLabsInput struct {
+	Type string `json:"type"` // video, audio
+	URL  string `json:"url"`
+}
+
+type SyncLabsRequest struct {
+	Model   string          `json:"model"`
+	Input   []SyncLabsInput `json:"input"`
+	Options struct {
+		SyncMode string `json:"sync_mode,omitempty"`
+	} `json:"options"`
+}
+
+type SyncLabsResponse struct {
+	ID        string `json:"id"`
+	Status    string `json:"status"` // PENDING, PROCESSING, COMPLETED, FAILED, REJECTED, CANCELED
+	OutputURL string `json:"outputUrl"`
+	Error     string `json:"error"`
+}
+
+func NewSyncLabsLipSyncClient(baseURL, apiKey, model string) *SyncLabsLipSyncClient {
+	if baseURL == "" {
+		baseURL = "https://api.sync.so"
+	}
+	if model == "" {
+		model = "lipsync-2"
+	}
+	return &SyncLabsLipSyncClient{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		APIKey:  apiKey,
+		Model:   model,
+		HTTPClient: &http.Client{
+			Timeout: 60 * time.Second,
+		},
+	}
+}
+
+func (c *SyncLabsLipSyncClient) SyncLips(source *VideoResult, audioURL string, opts ...LipSyncOption) (*VideoResult, error) {
+	options := &LipSyncOptions{SyncMode: LipSyncModeCutOff}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	model := c.Model
+	if options.Model != "" {
+		model = options.Model
+	}
+
+	reqBody := SyncLabsRequest{
+		Model: model,
+		Input: []SyncLabsInput{
+			{Type: "video", URL: source.VideoURL},
+			{Type: "audio", URL: audioURL},
+		},
+	}
+	reqBody.Options.SyncMode = options.SyncMode
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	body, err := c.do("POST", c.BaseURL+"/v2/generate", jsonData)
+	if err != nil {
+		return nil, err
+	}
+	return c.parseResult(body)
+}
+
+func (c *SyncLabsLipSyncClient) GetTaskStatus(taskID string) (*VideoResult, error) {
+	body, err := c.do("GET", c.BaseURL+"/v2/generate/"+taskID, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.parseResult(body)
+}
+
+func (c *SyncLabsLipSyncClient) do(method, endpoint string, payload []byte) ([]byte, error) {
+	var reader io.Reader
+	if payload != nil {
+		reader = bytes.NewBuffer(payload)
+	}
+	req, err := http.NewRequest(method, endpoint, reader)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("x-api-key", c.APIKey)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+	}
+	return body, nil
+}
+
+func (c *SyncLabsLipSyncClient) parseResult(body []byte) (*VideoResult, error) {
+	var result SyncLabsResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("parse response: %w", err)
+	}
+
+	videoResult := &VideoResult{
+		TaskID:    result.ID,
+		Status:    result.Status,
+		VideoURL:  result.OutputURL,
+		Completed: result.Status == "COMPLETED",
+	}
+
+	switch result.Status {
+	case "FAILED", "REJECTED", "CANCELED":
+		videoResult.Error = result.Error
+		if videoResult.Error == "" {
+			videoResult.Error = fmt.Sprintf("lip sync %s", strings.ToLower(result.Status))
+		}
+	}
+
+	return videoResult, nil
+}
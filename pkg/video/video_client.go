@@ -14,6 +14,11 @@ type VideoClient interface {
 	GetTaskStatus(taskID string) (*VideoResult, error)
 }
 
+// TaskCanceler 支持取消远程任务的客户端可选实现该接口
+type TaskCanceler interface {
+	CancelTask(taskID string) error
+}
+
 type VideoResult struct {
 	TaskID       string
 	Status       string
@@ -39,6 +44,7 @@ type VideoOptions struct {
 	FirstFrameURL      string
 	LastFrameURL       string
 	ReferenceImageURLs []string
+	CallbackURL        string // 任务状态变化时厂商回调的地址，仅部分厂商支持
 }
 
 type VideoOption func(*VideoOptions)
@@ -115,6 +121,13 @@ func WithReferenceImages(urls []string) VideoOption {
 	}
 }
 
+// WithCallbackURL 设置任务回调地址，不支持回调的客户端忽略该选项
+func WithCallbackURL(url string) VideoOption {
+	return func(o *VideoOptions) {
+		o.CallbackURL = url
+	}
+}
+
 type RunwayClient struct {
 	BaseURL    string
 	APIKey     string
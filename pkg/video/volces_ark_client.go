@@ -31,6 +31,7 @@ type VolcesArkRequest struct {
 	Model         string             `json:"model"`
 	Content       []VolcesArkContent `json:"content"`
 	GenerateAudio bool               `json:"generate_audio,omitempty"`
+	CallbackURL   string             `json:"callback_url,omitempty"`
 }
 
 type VolcesArkResponse struct {
@@ -166,6 +167,7 @@ func (c *VolcesArkClient) GenerateVideo(imageURL, prompt string, opts ...VideoOp
 		Model:         model,
 		Content:       content,
 		GenerateAudio: generateAudio,
+		CallbackURL:   options.CallbackURL,
 	}
 
 	jsonData, err := json.Marshal(reqBody)
@@ -230,15 +232,7 @@ func (c *VolcesArkClient) GenerateVideo(imageURL, prompt string, opts ...VideoOp
 }
 
 func (c *VolcesArkClient) GetTaskStatus(taskID string) (*VideoResult, error) {
-	// 替换占位符{taskId}、{task_id}或直接拼接
-	queryPath := c.QueryEndpoint
-	if strings.Contains(queryPath, "{taskId}") {
-		queryPath = strings.ReplaceAll(queryPath, "{taskId}", taskID)
-	} else if strings.Contains(queryPath, "{task_id}") {
-		queryPath = strings.ReplaceAll(queryPath, "{task_id}", taskID)
-	} else {
-		queryPath = queryPath + "/" + taskID
-	}
+	queryPath := c.taskPath(taskID)
 
 	endpoint := c.BaseURL + queryPath
 	fmt.Printf("[VolcesARK] Querying task status - TaskID: %s, QueryEndpoint: %s, FullURL: %s\n", taskID, c.QueryEndpoint, endpoint)
@@ -270,21 +264,61 @@ func (c *VolcesArkClient) GetTaskStatus(taskID string) (*VideoResult, error) {
 
 	fmt.Printf("[VolcesARK] Parsed result - ID: %s, Status: %s, VideoURL: %s\n", result.ID, result.Status, result.Content.VideoURL)
 
+	return result.toVideoResult(), nil
+}
+
+// toVideoResult 将任务查询（或回调）结果转换为通用结果
+func (r *VolcesArkResponse) toVideoResult() *VideoResult {
 	videoResult := &VideoResult{
-		TaskID:    result.ID,
-		Status:    result.Status,
-		Completed: result.Status == "completed" || result.Status == "succeeded",
-		Duration:  result.Duration,
+		TaskID:    r.ID,
+		Status:    r.Status,
+		Completed: r.Status == "completed" || r.Status == "succeeded",
+		Duration:  r.Duration,
 	}
 
-	if result.Error != nil {
-		videoResult.Error = fmt.Sprintf("%v", result.Error)
+	if r.Error != nil {
+		videoResult.Error = fmt.Sprintf("%v", r.Error)
 	}
 
-	if result.Content.VideoURL != "" {
-		videoResult.VideoURL = result.Content.VideoURL
+	if r.Content.VideoURL != "" {
+		videoResult.VideoURL = r.Content.VideoURL
 		videoResult.Completed = true
 	}
 
-	return videoResult, nil
+	return videoResult
+}
+
+// CancelTask 取消排队中的任务（火山方舟仅支持取消 queued 状态的任务）
+func (c *VolcesArkClient) CancelTask(taskID string) error {
+	req, err := http.NewRequest("DELETE", c.BaseURL+c.taskPath(taskID), nil)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+c.APIKey)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
+
+// taskPath 替换占位符{taskId}、{task_id}或直接拼接任务ID
+func (c *VolcesArkClient) taskPath(taskID string) string {
+	queryPath := c.QueryEndpoint
+	if strings.Contains(queryPath, "{taskId}") {
+		return strings.ReplaceAll(queryPath, "{taskId}", taskID)
+	}
+	if strings.Contains(queryPath, "{task_id}") {
+		return strings.ReplaceAll(queryPath, "{task_id}", taskID)
+	}
+	return queryPath + "/" + taskID
 }